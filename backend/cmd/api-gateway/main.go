@@ -4,32 +4,51 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 
 	"github.com/bifshteksex/hertz-board/internal/config"
 	"github.com/bifshteksex/hertz-board/internal/database"
+	"github.com/bifshteksex/hertz-board/internal/debug"
+	"github.com/bifshteksex/hertz-board/internal/encryption"
+	"github.com/bifshteksex/hertz-board/internal/graphql"
 	"github.com/bifshteksex/hertz-board/internal/handler"
+	"github.com/bifshteksex/hertz-board/internal/logger"
+	"github.com/bifshteksex/hertz-board/internal/metrics"
+	"github.com/bifshteksex/hertz-board/internal/middleware"
 	"github.com/bifshteksex/hertz-board/internal/repository"
 	"github.com/bifshteksex/hertz-board/internal/router"
+	"github.com/bifshteksex/hertz-board/internal/rpc"
 	"github.com/bifshteksex/hertz-board/internal/service"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 )
 
 const (
-	maxRequestBodySizeMB   = 10
+	bufferedBodySizeMB     = 1 // larger request bodies are streamed to the handler
 	shutdownTimeoutSeconds = 5
 	bytesInMB              = 1024 * 1024
+	day                    = 24 * time.Hour
 	defaultConfigPath      = "configs/config.yaml"
+	migrationsPath         = "migrations"
 )
 
-func main() {
-	// Initialize logger
-	log.Println("Starting HertzBoard API Gateway...")
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.4 init -d ../.. -g cmd/api-gateway/main.go -o ../../api/openapi --outputTypes json,yaml --parseInternal
 
+// @title HertzBoard API
+// @version 1.0
+// @description REST API of the HertzBoard collaborative whiteboard. Realtime updates use the WebSocket at /ws or the Server-Sent Events fallback.
+// @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token as "Bearer <token>"
+func main() {
 	// Load configuration
 	configPath := getEnv("CONFIG_PATH", defaultConfigPath)
 	cfg, err := config.Load(configPath)
@@ -37,157 +56,800 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	log.Printf("Loaded configuration: %s environment", cfg.App.Env)
+	// Initialize logger
+	logCloser, err := logger.Setup(&cfg.Logging, "api-gateway")
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logCloser.Close()
+
+	hlog.Infof("Starting HertzBoard API Gateway (%s environment)...", cfg.App.Env)
+
+	shutdownTracing, err := tracing.Init(&cfg.Tracing, "api-gateway")
+	if err != nil {
+		hlog.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeoutSeconds*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+	}()
+
+	// Pick up rotated secrets of vault: and aws-sm: references
+	go cfg.SecretManager().Run(context.Background())
+
+	// Apply changes of the reloadable settings without a restart
+	configReloader := config.NewReloader(configPath, cfg)
+	configReloader.OnReload(func(current *config.Config) {
+		if levelErr := logger.SetLevel(current.Logging.Level); levelErr != nil {
+			hlog.Warnf("Keeping the current log level: %v", levelErr)
+		}
+	})
 
 	// Connect to databases
-	log.Println("Connecting to PostgreSQL...")
+	hlog.Info("Connecting to PostgreSQL...")
 	dbPool, err := database.NewPostgresPool(&cfg.Database)
 	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		hlog.Fatalf("Failed to connect to PostgreSQL: %v", err)
 	}
 	defer database.ClosePostgresPool(dbPool)
-	log.Println("Connected to PostgreSQL")
+	hlog.Info("Connected to PostgreSQL")
+
+	// Listings and searches are read from the replica when one is configured
+	replicaPool, err := database.NewReplicaPool(&cfg.Database)
+	if err != nil {
+		database.ClosePostgresPool(dbPool)
+		hlog.Fatalf("Failed to create read replica pool: %v", err) //nolint:gocritic // cleanup is done before exit
+	}
+	defer database.ClosePostgresPool(replicaPool)
 
-	log.Println("Connecting to Redis...")
+	hlog.Info("Connecting to Redis...")
 	redisClient, err := database.NewRedisClient(&cfg.Redis)
 	if err != nil {
 		database.ClosePostgresPool(dbPool)
-		log.Fatalf("Failed to connect to Redis: %v", err) //nolint:gocritic // cleanup is done before exit
+		database.ClosePostgresPool(replicaPool)
+		hlog.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	defer func() {
 		_ = database.CloseRedisClient(redisClient)
 	}()
-	log.Println("Connected to Redis")
+	hlog.Info("Connected to Redis")
 
-	log.Println("Connecting to NATS...")
+	hlog.Info("Connecting to NATS...")
 	natsConn, err := database.NewNATSConnection(&cfg.NATS)
 	if err != nil {
 		database.ClosePostgresPool(dbPool)
+		database.ClosePostgresPool(replicaPool)
 		_ = database.CloseRedisClient(redisClient)
-		log.Fatalf("Failed to connect to NATS: %v", err)
+		hlog.Fatalf("Failed to connect to NATS: %v", err)
 	}
 	defer database.CloseNATSConnection(natsConn)
-	log.Println("Connected to NATS")
+	hlog.Info("Connected to NATS")
+
+	// Usage analytics go through NATS to ClickHouse
+	var analyticsService *service.AnalyticsService
+	var analyticsRepo *repository.AnalyticsRepository
+	if cfg.ClickHouse.Enabled {
+		analyticsService = service.NewAnalyticsService(natsConn)
+		analyticsRepo = repository.NewAnalyticsRepository(database.NewClickHouse(&cfg.ClickHouse), "migrations/clickhouse")
+	}
 
 	// Run migrations
-	log.Println("Running database migrations...")
-	if migrateErr := database.Migrate(dbPool, "migrations"); migrateErr != nil {
-		log.Fatalf("Failed to run migrations: %v", migrateErr)
+	if cfg.Database.AutoMigrate {
+		hlog.Info("Running database migrations...")
+		if migrateErr := database.Migrate(dbPool, migrationsPath); migrateErr != nil {
+			hlog.Fatalf("Failed to run migrations: %v", migrateErr)
+		}
+		hlog.Info("Migrations completed")
+	} else {
+		hlog.Info("Automatic migrations are disabled, apply them with cmd/migrate")
+	}
+
+	// Element and snapshot content is encrypted at rest when configured
+	var keyring *encryption.Keyring
+	if cfg.Encryption.Enabled {
+		keyWrapper, wrapperErr := encryption.NewKeyWrapper(&cfg.Encryption)
+		if wrapperErr != nil {
+			hlog.Fatalf("Failed to initialize encryption: %v", wrapperErr)
+		}
+		keyring = encryption.NewKeyring(keyWrapper, repository.NewDataKeyRepository(dbPool))
+		hlog.Infof("Content encryption enabled with master key %s", keyWrapper.KeyID())
 	}
-	log.Println("Migrations completed")
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(dbPool)
-	workspaceRepo := repository.NewWorkspaceRepository(dbPool)
-	canvasRepo := repository.NewCanvasRepository(dbPool)
-	assetRepo := repository.NewAssetRepository(dbPool)
-	snapshotRepo := repository.NewSnapshotRepository(dbPool)
+	workspaceRepo := repository.NewWorkspaceRepository(dbPool, replicaPool)
+	canvasRepo := repository.NewCanvasRepository(dbPool, replicaPool, keyring)
+	assetRepo := repository.NewAssetRepository(dbPool, replicaPool)
+	snapshotRepo := repository.NewSnapshotRepository(dbPool, replicaPool, keyring)
 	elementRepo := repository.NewElementRepository(dbPool)
 	operationRepo := repository.NewOperationRepository(dbPool)
+	webhookRepo := repository.NewWebhookRepository(dbPool)
+	inboundWebhookRepo := repository.NewInboundWebhookRepository(dbPool)
+	apiKeyRepo := repository.NewAPIKeyRepository(dbPool)
+	botRepo := repository.NewBotRepository(dbPool)
+	exportRepo := repository.NewExportRepository(dbPool)
+	commentRepo := repository.NewCommentRepository(dbPool)
+	reactionRepo := repository.NewReactionRepository(dbPool)
+	embedTokenRepo := repository.NewEmbedTokenRepository(dbPool)
+	scimRepo := repository.NewSCIMRepository(dbPool)
+	notificationRepo := repository.NewNotificationRepository(dbPool)
 
 	// Initialize services
 	jwtService, err := service.NewJWTService(&cfg.JWT)
 	if err != nil {
-		log.Fatalf("Failed to create JWT service: %v", err)
+		hlog.Fatalf("Failed to create JWT service: %v", err)
 	}
 
-	emailService := service.NewEmailService(&cfg.Email, natsConn)
-	authService := service.NewAuthService(userRepo, jwtService)
+	// Events and emails are written to the outbox and published by the relay
+	outboxRepo := repository.NewOutboxRepository(dbPool)
+	emailService, err := service.NewEmailService(
+		&cfg.Email, natsConn, outboxRepo, notificationRepo, repository.NewEmailSuppressionRepository(dbPool), redisClient,
+	)
+	if err != nil {
+		hlog.Fatalf("Failed to create email service: %v", err)
+	}
+	eventPublisher := service.NewEventPublisher(outboxRepo)
+	webhookService, err := service.NewWebhookService(
+		webhookRepo, workspaceRepo, canvasRepo, userRepo, notificationRepo, natsConn, cfg.App.FrontendURL,
+	)
+	if err != nil {
+		hlog.Fatalf("Failed to create webhook service: %v", err)
+	}
+	var ldapAuthenticator *service.LDAPAuthenticator
+	if cfg.LDAP.Enabled {
+		ldapAuthenticator, err = service.NewLDAPAuthenticator(&cfg.LDAP)
+		if err != nil {
+			hlog.Fatalf("Failed to configure LDAP: %v", err)
+		}
+	}
+	// Audit log, shipped to the SIEM sinks of the deployment
+	auditRepo := repository.NewAuditRepository(dbPool)
+	var auditService *service.AuditService
+	if cfg.Audit.Enabled {
+		auditService = service.NewAuditService(auditRepo)
+	}
+	consentService := service.NewConsentService(repository.NewConsentRepository(dbPool), &cfg.Legal)
+	authService := service.NewAuthService(userRepo, workspaceRepo, jwtService, ldapAuthenticator, consentService, auditService)
+	emailVerification := service.NewEmailVerificationPolicy(userRepo, cfg.Auth.RequireVerifiedEmail)
+	configReloader.OnReload(func(current *config.Config) {
+		emailVerification.SetRequired(current.Auth.RequireVerifiedEmail)
+	})
 	oauthService := service.NewOAuthService(&cfg.OAuth, userRepo, jwtService)
-	workspaceService := service.NewWorkspaceService(workspaceRepo, userRepo, emailService)
+
+	// Realtime hub and notifications
+	broker, err := service.NewBroker(cfg, redisClient, natsConn)
+	if err != nil {
+		hlog.Fatalf("Failed to create realtime broker: %v", err)
+	}
+	defer func() {
+		_ = broker.Close()
+	}()
+	recorder := service.NewSessionRecorder(redisClient)
+	defer func() {
+		_ = recorder.Close()
+	}()
+	hub := service.NewHub(broker, service.NewOnlineUsers(redisClient), analyticsService, recorder)
+	roomRegistry := service.NewRoomRegistry(redisClient, hub)
+	defer func() {
+		_ = roomRegistry.Close()
+	}()
+
+	// REST-originated changes reach live rooms through the internal API of
+	// the ws-server when it is configured, otherwise through the local hub
+	var rooms service.RoomBroadcaster = hub
+	if cfg.GRPC.Enabled && cfg.GRPC.Addr != "" {
+		realtimeClient, clientErr := rpc.NewClient(&cfg.GRPC, hub)
+		if clientErr != nil {
+			hlog.Fatalf("Failed to create ws-server client: %v", clientErr)
+		}
+		defer func() {
+			_ = realtimeClient.Close()
+		}()
+		rooms = realtimeClient
+	}
+	webPushService, err := service.NewWebPushService(
+		&cfg.Notifications.WebPush, repository.NewPushSubscriptionRepository(dbPool), hub,
+	)
+	if err != nil {
+		hlog.Fatalf("Failed to create web push service: %v", err)
+	}
+	notificationService := service.NewNotificationService(
+		notificationRepo, userRepo, emailService, hub, webPushService, cfg.App.FrontendURL,
+	)
+
+	// Plans and their entitlements, workspaces are unlimited without billing
+	var billingService *service.BillingService
+	if cfg.Billing.Enabled {
+		billingService, err = service.NewBillingService(
+			&cfg.Billing, repository.NewSubscriptionRepository(dbPool), userRepo, cfg.App.FrontendURL,
+		)
+		if err != nil {
+			hlog.Fatalf("Failed to create billing service: %v", err)
+		}
+	}
+
+	// Monthly usage of workspaces, reported to billing for overage
+	var meteringService *service.MeteringService
+	if cfg.Metering.Enabled {
+		meteringService = service.NewMeteringService(repository.NewUsageRepository(dbPool), billingService)
+	}
+
+	workspaceVisits := service.NewWorkspaceVisits(redisClient, workspaceRepo)
+	workspaceViews := service.NewWorkspaceViews(redisClient, workspaceRepo, analyticsService)
+	workspaceService := service.NewWorkspaceService(
+		workspaceRepo, userRepo, emailService, eventPublisher, webPushService, billingService, auditService, workspaceVisits,
+		notificationService, rooms, workspaceViews,
+	)
 
 	// Canvas and asset services
 	cacheService := service.NewCanvasCacheService(redisClient)
-	canvasService := service.NewCanvasService(canvasRepo, workspaceRepo, cacheService)
+	canvasService := service.NewCanvasService(
+		canvasRepo, workspaceRepo, cacheService, eventPublisher, notificationService, analyticsService, meteringService,
+	)
+
+	objectStorage, err := service.NewObjectStorage(&cfg.Storage, &cfg.MinIO)
+	if err != nil {
+		hlog.Fatalf("Failed to initialize object storage: %v", err)
+	}
 
 	assetService, err := service.NewAssetService(
 		assetRepo,
 		workspaceRepo,
-		cfg.MinIO.Endpoint,
-		cfg.MinIO.AccessKey,
-		cfg.MinIO.SecretKey,
-		cfg.MinIO.UseSSL,
+		natsConn,
+		objectStorage,
+		&cfg.MinIO,
+		&cfg.Upload,
+		billingService,
 	)
 	if err != nil {
-		log.Fatalf("Failed to create asset service: %v", err)
+		hlog.Fatalf("Failed to create asset service: %v", err)
 	}
 
-	snapshotService := service.NewSnapshotService(snapshotRepo, canvasRepo, workspaceRepo)
+	stockMediaService := service.NewStockMediaService(&cfg.Integrations, assetService)
+	exportStorage, err := service.NewExportStorage(&cfg.Storage, &cfg.MinIO)
+	if err != nil {
+		hlog.Fatalf("Failed to initialize export storage: %v", err)
+	}
+	exportService := service.NewExportService(
+		exportRepo, canvasService, workspaceService, objectStorage, natsConn, meteringService,
+		operationRepo, exportStorage,
+	)
 
 	// Initialize CRDT and WebSocket services
-	crdt := service.NewCRDTService(elementRepo, operationRepo)
-	hub := service.NewHub(redisClient)
+	crdt := service.NewCRDTService(
+		elementRepo, operationRepo, eventPublisher, notificationService, analyticsService, meteringService,
+	)
+
+	backupStorage, err := service.NewBackupStorage(&cfg.Storage, &cfg.MinIO)
+	if err != nil {
+		hlog.Fatalf("Failed to initialize backup storage: %v", err)
+	}
+
+	inboundWebhookService := service.NewInboundWebhookService(inboundWebhookRepo, canvasService, workspaceService, rooms)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, workspaceRepo, auditService)
+	botService := service.NewBotService(botRepo, workspaceRepo, auditService)
+	commentService := service.NewCommentService(commentRepo, workspaceRepo, rooms, reactionRepo)
+	presentationService := service.NewPresentationService(
+		repository.NewPresentationRepository(dbPool), workspaceRepo, rooms,
+	)
+	viewStateService := service.NewViewStateService(redisClient, repository.NewViewStateRepository(dbPool))
+	recordingService := service.NewRecordingService(
+		repository.NewRecordingRepository(dbPool), redisClient, backupStorage,
+	)
+	searchService := service.NewSearchService(canvasService, commentRepo, assetRepo)
+	ipAllowlistService := service.NewIPAllowlistService(workspaceRepo, auditService)
+	triggerService := service.NewTriggerService(
+		canvasRepo, commentRepo, workspaceRepo, userRepo, workspaceService, webhookService, cfg.App.FrontendURL,
+	)
+
+	snapshotService := service.NewSnapshotService(
+		snapshotRepo, canvasRepo, workspaceRepo, cacheService, rooms, assetService, eventPublisher, backupStorage,
+		billingService,
+	)
+
+	// Move payloads of snapshots created before object storage was used
+	go func() {
+		moved, moveErr := snapshotService.MigrateInlineSnapshots(context.Background())
+		if moveErr != nil {
+			hlog.Errorf("Failed to move snapshot data to object storage: %v", moveErr)
+		}
+		if moved > 0 {
+			hlog.Infof("Moved %d snapshots to object storage", moved)
+		}
+	}()
+
+	// Encrypt elements stored before encryption was enabled
+	if keyring != nil {
+		go func() {
+			encrypted, encryptErr := canvasService.EncryptExistingElements(context.Background())
+			if encryptErr != nil {
+				hlog.Errorf("Failed to encrypt existing elements: %v", encryptErr)
+			}
+			if encrypted > 0 {
+				hlog.Infof("Encrypted %d existing elements", encrypted)
+			}
+		}()
+	}
+
+	// Start outbox relay, it publishes the events and emails of both services
+	outboxInterval, err := cfg.Outbox.GetPollIntervalDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid outbox poll interval: %v", err)
+	}
+	outboxRetention, err := cfg.Outbox.GetRetentionDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid outbox retention: %v", err)
+	}
+	outboxRelay, err := service.NewOutboxRelay(outboxRepo, natsConn, outboxInterval, cfg.Outbox.BatchSize, outboxRetention)
+	if err != nil {
+		hlog.Fatalf("Failed to start outbox relay: %v", err)
+	}
+	defer outboxRelay.Close()
+	hlog.Info("Outbox relay started")
+
+	var auditExporters []*service.AuditExporter
+	if cfg.Audit.Enabled {
+		auditExporters, err = service.NewAuditExporters(auditRepo, &cfg.Audit)
+		if err != nil {
+			hlog.Fatalf("Failed to start audit sinks: %v", err)
+		}
+		defer service.CloseAuditExporters(auditExporters)
+		hlog.Infof("Audit log shipped to %d sinks", len(auditExporters))
+	}
 
 	// Start email worker
-	log.Println("Starting email worker...")
+	hlog.Info("Starting email worker...")
 	emailWorker, err := service.NewEmailWorker(&cfg.Email, natsConn)
 	if err != nil {
-		log.Fatalf("Failed to start email worker: %v", err)
+		hlog.Fatalf("Failed to start email worker: %v", err)
 	}
 	defer emailWorker.Close()
-	log.Println("Email worker started")
+	hlog.Info("Email worker started")
+
+	// Start notification digest worker
+	digestInterval, err := cfg.Notifications.GetDigestIntervalDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid notification digest interval: %v", err)
+	}
+	digestDelay, err := cfg.Notifications.GetDigestDelayDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid notification digest delay: %v", err)
+	}
+	digestWorker, err := service.NewNotificationDigestWorker(notificationService, digestInterval, digestDelay)
+	if err != nil {
+		hlog.Fatalf("Failed to start notification digest worker: %v", err)
+	}
+	defer digestWorker.Close()
+	hlog.Info("Notification digest worker started")
+
+	// Start webhook worker
+	hlog.Info("Starting webhook worker...")
+	webhookWorker, err := service.NewWebhookWorker(natsConn, webhookService)
+	if err != nil {
+		hlog.Fatalf("Failed to start webhook worker: %v", err)
+	}
+	defer webhookWorker.Close()
+	hlog.Info("Webhook worker started")
+
+	// Start PDF render worker
+	hlog.Info("Starting PDF render worker...")
+	pdfRenderWorker, err := service.NewPDFRenderWorker(natsConn, assetService)
+	if err != nil {
+		hlog.Fatalf("Failed to start PDF render worker: %v", err)
+	}
+	defer pdfRenderWorker.Close()
+	hlog.Info("PDF render worker started")
+
+	// Start export worker
+	hlog.Info("Starting export worker...")
+	exportWorker, err := service.NewExportWorker(natsConn, exportService)
+	if err != nil {
+		hlog.Fatalf("Failed to start export worker: %v", err)
+	}
+	defer exportWorker.Close()
+	hlog.Info("Export worker started")
+
+	// Start media transcode worker
+	hlog.Info("Starting media transcode worker...")
+	mediaTranscodeWorker, err := service.NewMediaTranscodeWorker(natsConn, assetService)
+	if err != nil {
+		hlog.Fatalf("Failed to start media transcode worker: %v", err)
+	}
+	defer mediaTranscodeWorker.Close()
+	hlog.Info("Media transcode worker started")
+
+	// Start image optimize worker
+	hlog.Info("Starting image optimize worker...")
+	imageOptimizeWorker, err := service.NewImageOptimizeWorker(natsConn, assetService)
+	if err != nil {
+		hlog.Fatalf("Failed to start image optimize worker: %v", err)
+	}
+	defer imageOptimizeWorker.Close()
+	hlog.Info("Image optimize worker started")
+
+	// Start asset scan worker
+	virusScanner, err := service.NewVirusScanner(&cfg.Upload.Antivirus)
+	if err != nil {
+		hlog.Fatalf("Failed to create virus scanner: %v", err)
+	}
+	assetScanWorker, err := service.NewAssetScanWorker(natsConn, assetService, virusScanner, emailService, userRepo)
+	if err != nil {
+		hlog.Fatalf("Failed to start asset scan worker: %v", err)
+	}
+	defer assetScanWorker.Close()
+	hlog.Info("Asset scan worker started")
+
+	// Start asset OCR worker, images are only queued when OCR is enabled
+	ocrProvider, err := service.NewOCRProvider(&cfg.Upload.OCR)
+	if err != nil {
+		hlog.Fatalf("Failed to create OCR provider: %v", err)
+	}
+	if ocrProvider != nil {
+		assetOCRWorker, workerErr := service.NewAssetOCRWorker(natsConn, assetService, ocrProvider)
+		if workerErr != nil {
+			hlog.Fatalf("Failed to start asset OCR worker: %v", workerErr)
+		}
+		defer assetOCRWorker.Close()
+		hlog.Info("Asset OCR worker started")
+	}
+
+	// Start asset purge worker
+	purgeInterval, err := cfg.Upload.GetPurgeIntervalDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid asset purge interval: %v", err)
+	}
+	assetRetention := time.Duration(cfg.Upload.DeletedRetentionDays) * day
+	assetPurgeWorker, err := service.NewAssetPurgeWorker(assetService, assetRetention, purgeInterval)
+	if err != nil {
+		hlog.Fatalf("Failed to start asset purge worker: %v", err)
+	}
+	defer assetPurgeWorker.Close()
+	hlog.Info("Asset purge worker started")
+
+	// Start snapshot retention worker
+	retentionInterval, err := cfg.Snapshots.GetRetentionIntervalDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid snapshot retention interval: %v", err)
+	}
+	snapshotRetentionWorker, err := service.NewSnapshotRetentionWorker(snapshotService, retentionInterval)
+	if err != nil {
+		hlog.Fatalf("Failed to start snapshot retention worker: %v", err)
+	}
+	defer snapshotRetentionWorker.Close()
+	hlog.Info("Snapshot retention worker started")
+
+	// Start operation partition worker
+	operationsInterval, err := cfg.Operations.GetMaintenanceIntervalDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid operations maintenance interval: %v", err)
+	}
+	operationsRetention, err := cfg.Operations.GetRetentionDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid operations retention: %v", err)
+	}
+	operationPartitionWorker, err := service.NewOperationPartitionWorker(
+		operationRepo, operationsInterval, cfg.Operations.PartitionsAhead, operationsRetention,
+	)
+	if err != nil {
+		hlog.Fatalf("Failed to start operation partition worker: %v", err)
+	}
+	defer operationPartitionWorker.Close()
+	hlog.Info("Operation partition worker started")
+
+	// Start workspace visit worker
+	workspaceVisitWorker, err := service.NewWorkspaceVisitWorker(workspaceVisits, service.WorkspaceVisitFlushInterval)
+	if err != nil {
+		hlog.Fatalf("Failed to start workspace visit worker: %v", err)
+	}
+	defer workspaceVisitWorker.Close()
+	hlog.Info("Workspace visit worker started")
+
+	// Start view state worker
+	viewStateWorker, err := service.NewViewStateWorker(viewStateService, service.ViewStateFlushInterval)
+	if err != nil {
+		hlog.Fatalf("Failed to start view state worker: %v", err)
+	}
+	defer viewStateWorker.Close()
+	hlog.Info("View state worker started")
+
+	var meteringWorker *service.MeteringWorker
+	if meteringService != nil {
+		meteringInterval, intervalErr := cfg.Metering.GetIntervalDuration()
+		if intervalErr != nil {
+			hlog.Fatalf("Invalid metering interval: %v", intervalErr)
+		}
+		meteringWorker, err = service.NewMeteringWorker(meteringService, meteringInterval)
+		if err != nil {
+			hlog.Fatalf("Failed to start metering worker: %v", err)
+		}
+		defer meteringWorker.Close()
+		hlog.Info("Metering worker started")
+	}
+
+	// Start analytics worker, it keeps running while ClickHouse is down
+	if cfg.ClickHouse.Enabled {
+		analyticsFlushInterval, intervalErr := cfg.ClickHouse.GetFlushIntervalDuration()
+		if intervalErr != nil {
+			hlog.Fatalf("Invalid analytics flush interval: %v", intervalErr)
+		}
+		analyticsWorker, workerErr := service.NewAnalyticsWorker(
+			natsConn, analyticsRepo, cfg.ClickHouse.BatchSize, cfg.ClickHouse.MaxBuffered, analyticsFlushInterval,
+		)
+		if workerErr != nil {
+			hlog.Fatalf("Failed to start analytics worker: %v", workerErr)
+		}
+		defer analyticsWorker.Close()
+		hlog.Info("Analytics worker started")
+	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService)
 	userHandler := handler.NewUserHandler(userRepo, authService)
+	consentHandler := handler.NewConsentHandler(consentService)
+	ipAllowlistHandler := handler.NewIPAllowlistHandler(ipAllowlistService)
 	oauthHandler := handler.NewOAuthHandler(oauthService)
-	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
-	canvasHandler := handler.NewCanvasHandler(canvasService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService, assetService, emailVerification)
+	healthHandler := handler.NewWorkspaceHealthHandler(
+		service.NewWorkspaceHealthService(workspaceRepo, canvasRepo, snapshotRepo),
+	)
+	canvasHandler := handler.NewCanvasHandler(canvasService, commentService)
 	assetHandler := handler.NewAssetHandler(assetService)
+	integrationHandler := handler.NewIntegrationHandler(stockMediaService, assetService)
 	snapshotHandler := handler.NewSnapshotHandler(snapshotService)
-	wsHandler := handler.NewWebSocketHandler(hub, jwtService)
+	exportHandler := handler.NewExportHandler(exportService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	presentationHandler := handler.NewPresentationHandler(presentationService)
+	viewStateHandler := handler.NewViewStateHandler(viewStateService)
+	recordingHandler := handler.NewRecordingHandler(recordingService)
+	searchHandler := handler.NewSearchHandler(searchService)
+	adminService := service.NewAdminService(
+		workspaceRepo, canvasService, assetService, crdt, rooms, hub, roomRegistry, auditService,
+	)
+	adminService.RegisterJob(service.JobAssetPurge, "Purge assets deleted longer than the retention period ago", assetPurgeWorker)
+	adminService.RegisterJob(service.JobSnapshotRetention, "Apply the snapshot retention policies", snapshotRetentionWorker)
+	adminService.RegisterJob(service.JobNotificationDigest, "Email due notification digests", digestWorker)
+	adminService.RegisterJob(
+		service.JobOperationPartitions, "Create upcoming operation log partitions and drop expired operations", operationPartitionWorker,
+	)
+	adminService.RegisterJob(service.JobWorkspaceVisits, "Persist the buffered workspace visits of users", workspaceVisitWorker)
+	adminService.RegisterJob(service.JobViewStates, "Persist the changed view states of users", viewStateWorker)
+	if meteringWorker != nil {
+		adminService.RegisterJob(service.JobUsageMetering, "Sample storage usage and report closed months to billing", meteringWorker)
+	}
+	configReloadService := service.NewConfigReloadService(configReloader, redisClient, auditService)
+	go configReloader.Watch(context.Background())
+	go configReloadService.Run(context.Background())
+	adminHandler := handler.NewAdminHandler(emailService, adminService, configReloadService)
+	emailWebhookHandler := handler.NewEmailWebhookHandler(emailService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	inboundWebhookHandler := handler.NewInboundWebhookHandler(inboundWebhookService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	botHandler := handler.NewBotHandler(botService)
+	embedCacheMaxAge, err := cfg.Embed.GetCacheMaxAgeDuration()
+	if err != nil {
+		hlog.Fatalf("Invalid embed cache max age: %v", err)
+	}
+	embedHandler := handler.NewEmbedHandler(
+		service.NewEmbedService(embedTokenRepo, workspaceRepo, canvasService, ipAllowlistService), embedCacheMaxAge,
+	)
+	triggerHandler := handler.NewTriggerHandler(triggerService)
+	var scimHandler *handler.SCIMHandler
+	if cfg.SCIM.Enabled {
+		if cfg.SCIM.Token == "" {
+			hlog.Fatal("SCIM is enabled without a token")
+		}
+		scimHandler = handler.NewSCIMHandler(service.NewSCIMService(scimRepo, userRepo, workspaceRepo, auditService))
+	}
+
+	var usageHandler *handler.UsageHandler
+	if meteringService != nil {
+		usageHandler = handler.NewUsageHandler(meteringService)
+	}
+
+	var billingHandler *handler.BillingHandler
+	if billingService != nil {
+		billingHandler = handler.NewBillingHandler(billingService)
+	}
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	pushHandler := handler.NewPushHandler(webPushService)
+	var workspaceAnalytics *service.WorkspaceAnalyticsService
+	if analyticsRepo != nil {
+		workspaceAnalytics = service.NewWorkspaceAnalyticsService(analyticsRepo, canvasRepo)
+	}
+	analyticsHandler := handler.NewAnalyticsHandler(workspaceAnalytics)
+
+	// Filesystem storage serves presigned URLs through the API
+	var storageHandler *handler.StorageHandler
+	if fsStorage, ok := objectStorage.(*service.FilesystemStorage); ok {
+		storageHandler = handler.NewStorageHandler(fsStorage)
+	}
+	operationHandler := handler.NewOperationHandler(crdt)
+	wsHandler := handler.NewWebSocketHandler(
+		hub, jwtService, crdt, workspaceService, analyticsService, ipAllowlistService, botService, presentationService,
+	)
+	sseHandler := handler.NewSSEHandler(hub, wsHandler, workspaceService)
+
+	var docsHandler *handler.DocsHandler
+	if cfg.Docs.Enabled {
+		docsHandler = handler.NewDocsHandler(cfg.Docs.SpecPath)
+	}
+
+	var aiHandler *handler.AIHandler
+	if cfg.AI.Provider != "" {
+		aiService, err := service.NewAIService(&cfg.AI, canvasService, rooms)
+		if err != nil {
+			hlog.Fatalf("Failed to create AI service: %v", err)
+		}
+		aiHandler = handler.NewAIHandler(aiService)
+	}
 
-	// Initialize Hertz server
+	var translationHandler *handler.TranslationHandler
+	if cfg.Translation.Provider != "" {
+		translationService, err := service.NewTranslationService(
+			&cfg.Translation, &cfg.AI, canvasService, workspaceService, rooms,
+		)
+		if err != nil {
+			hlog.Fatalf("Failed to create translation service: %v", err)
+		}
+		translationHandler = handler.NewTranslationHandler(translationService)
+	}
+
+	var graphqlHandler *handler.GraphQLHandler
+	if cfg.GraphQL.Enabled {
+		graphqlResolver := graphql.NewResolver(
+			workspaceService, canvasService, snapshotService, assetService, userRepo, workspaceRepo, hub,
+			ipAllowlistService,
+		)
+		graphqlServer, graphqlErr := graphql.NewServer(&cfg.GraphQL, graphqlResolver)
+		if graphqlErr != nil {
+			hlog.Fatalf("Failed to initialize GraphQL: %v", graphqlErr)
+		}
+		graphqlHandler = handler.NewGraphQLHandler(graphqlServer)
+	}
+
+	// Prometheus metrics, served on their own port
+	var httpMetrics *metrics.HTTPMetrics
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled {
+		registry := metrics.NewServiceRegistry(dbPool, redisClient, natsConn, hub)
+		if replicaPool != nil {
+			metrics.RegisterDBReplicaPool(registry, replicaPool)
+		}
+		metrics.RegisterOutboxRelay(registry, outboxRelay)
+		metrics.RegisterAuditExporters(registry, auditExporters)
+		httpMetrics = metrics.NewHTTPMetrics(registry)
+		metricsServer = metrics.NewServer(cfg.Metrics.Port, registry)
+	}
+
+	// Per user and client rate limits, shared by all instances through
+	// Redis. The middleware is always installed, so a reload can turn them on.
+	rateLimit, err := middleware.RateLimit(service.NewRateLimiter(redisClient), configReloader, jwtService, httpMetrics)
+	if err != nil {
+		hlog.Fatalf("Failed to configure rate limiting: %v", err)
+	}
+
+	// Profiling and runtime endpoints, served on an internal port
+	var debugServer *http.Server
+	if cfg.Debug.Enabled {
+		debug.PublishHub(hub)
+		debugServer = debug.NewServer(&cfg.Debug, cfg.Debug.Port)
+	}
+
+	// Initialize Hertz server. Size limits are enforced per route by the
+	// body limit middleware, uploads are parsed as they stream in.
 	addr := fmt.Sprintf(":%d", cfg.App.Port)
 	h := server.Default(
 		server.WithHostPorts(addr),
-		server.WithMaxRequestBodySize(maxRequestBodySizeMB*bytesInMB),
+		server.WithMaxRequestBodySize(bufferedBodySizeMB*bytesInMB),
+		server.WithStreamBody(true),
+		server.WithDisablePreParseMultipartForm(true),
 	)
 
+	// Forwarded client addresses are only taken from trusted proxies
+	trustedProxies, err := cfg.App.GetTrustedProxies()
+	if err != nil {
+		hlog.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	h.SetClientIPFunc(middleware.ClientIP(trustedProxies))
+
 	// Setup routes and middleware
 	deps := &router.Dependencies{
-		JWTService:       jwtService,
-		WorkspaceService: workspaceService,
-		AuthHandler:      authHandler,
-		UserHandler:      userHandler,
-		OAuthHandler:     oauthHandler,
-		WorkspaceHandler: workspaceHandler,
-		CanvasHandler:    canvasHandler,
-		AssetHandler:     assetHandler,
-		SnapshotHandler:  snapshotHandler,
-		WSHandler:        wsHandler,
-		Hub:              hub,
-		CRDTService:      crdt,
+		JWTService:            jwtService,
+		WorkspaceService:      workspaceService,
+		IPAllowlistService:    ipAllowlistService,
+		AuthHandler:           authHandler,
+		UserHandler:           userHandler,
+		ConsentHandler:        consentHandler,
+		OAuthHandler:          oauthHandler,
+		WorkspaceHandler:      workspaceHandler,
+		HealthHandler:         healthHandler,
+		IPAllowlistHandler:    ipAllowlistHandler,
+		CanvasHandler:         canvasHandler,
+		AssetHandler:          assetHandler,
+		IntegrationHandler:    integrationHandler,
+		StorageHandler:        storageHandler,
+		SnapshotHandler:       snapshotHandler,
+		ExportHandler:         exportHandler,
+		CommentHandler:        commentHandler,
+		PresentationHandler:   presentationHandler,
+		ViewStateHandler:      viewStateHandler,
+		RecordingHandler:      recordingHandler,
+		SearchHandler:         searchHandler,
+		OperationHandler:      operationHandler,
+		WSHandler:             wsHandler,
+		SSEHandler:            sseHandler,
+		AdminHandler:          adminHandler,
+		EmailWebhookHandler:   emailWebhookHandler,
+		WebhookHandler:        webhookHandler,
+		InboundWebhookHandler: inboundWebhookHandler,
+		APIKeyHandler:         apiKeyHandler,
+		BotHandler:            botHandler,
+		EmbedHandler:          embedHandler,
+		TriggerHandler:        triggerHandler,
+		SCIMHandler:           scimHandler,
+		BillingHandler:        billingHandler,
+		UsageHandler:          usageHandler,
+		NotificationHandler:   notificationHandler,
+		PushHandler:           pushHandler,
+		AnalyticsHandler:      analyticsHandler,
+		DocsHandler:           docsHandler,
+		GraphQLHandler:        graphqlHandler,
+		AIHandler:             aiHandler,
+		TranslationHandler:    translationHandler,
+		EmailVerification:     emailVerification,
+		Hub:                   hub,
+		APIKeyService:         apiKeyService,
+		BotService:            botService,
+		CRDTService:           crdt,
+		HTTPMetrics:           httpMetrics,
+		RateLimit:             rateLimit,
+		ConfigReloader:        configReloader,
+		Analytics:             analyticsService,
 	}
 	router.Setup(h, cfg, deps)
 
-	log.Printf("API Gateway is starting on %s", addr)
+	hlog.Infof("API Gateway is starting on %s", addr)
 
 	// Graceful shutdown
 	go func() {
 		if err := h.Run(); err != nil {
-			log.Fatalf("Failed to run server: %v", err)
+			hlog.Fatalf("Failed to run server: %v", err)
 		}
 	}()
 
-	log.Printf("API Gateway is running on %s", addr)
+	hlog.Infof("API Gateway is running on %s", addr)
+
+	if metricsServer != nil {
+		metrics.Start(metricsServer)
+	}
+	if debugServer != nil {
+		debug.Start(debugServer)
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	hlog.Info("Shutting down server...")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeoutSeconds*time.Second)
 	defer cancel()
 
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(ctx)
+	}
+	if debugServer != nil {
+		_ = debugServer.Shutdown(ctx)
+	}
+
 	if err := h.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		hlog.Fatalf("Server forced to shutdown: %v", err)
 	}
 
-	fmt.Println("Server exited gracefully")
+	hlog.Info("Server exited gracefully")
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,158 @@
+// Command migrate manages the PostgreSQL schema migrations of HertzBoard.
+//
+//	migrate up              apply all pending migrations
+//	migrate down [N]        revert the last N applied migrations, 1 by default
+//	migrate status          list migrations and when they were applied
+//	migrate force VERSION   mark migrations up to VERSION as applied, later ones as not
+//	migrate create NAME     write empty up and down files for the next version
+//
+// It reads the database settings from CONFIG_PATH like the services do.
+// Production deployments set database.auto_migrate to false and run it
+// explicitly before rolling out a release.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/database"
+)
+
+const (
+	defaultConfigPath     = "configs/config.yaml"
+	defaultMigrationsPath = "migrations"
+	statusTimeFormat      = "2006-01-02 15:04:05"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), `Usage: migrate [-path DIR] COMMAND
+
+Commands:
+  up              apply all pending migrations
+  down [N]        revert the last N applied migrations, 1 by default
+  status          list migrations and when they were applied
+  force VERSION   mark migrations up to VERSION as applied, later ones as not
+  create NAME     write empty up and down files for the next version
+
+Flags:
+`)
+		flag.PrintDefaults()
+	}
+	path := flag.String("path", defaultMigrationsPath, "directory of the migration files")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*path, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, command string, args []string) error {
+	switch command {
+	case "up", "down", "status", "force":
+	case "create":
+		if len(args) != 1 {
+			return fmt.Errorf("create needs a migration name")
+		}
+		files, err := database.CreateMigration(path, args[0])
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			fmt.Println("Created", file)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	pool, err := connect()
+	if err != nil {
+		return err
+	}
+	defer database.ClosePostgresPool(pool)
+
+	switch command {
+	case "up":
+		return database.Migrate(pool, path)
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+		}
+		return database.MigrateDown(pool, path, steps)
+	case "status":
+		return printStatus(pool, path)
+	case "force":
+		if len(args) != 1 {
+			return fmt.Errorf("force needs a version")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		return database.ForceMigrationVersion(pool, path, version)
+	}
+	return nil
+}
+
+func connect() (*pgxpool.Pool, error) {
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = defaultConfigPath
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	pool, err := database.NewPostgresPool(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return pool, nil
+}
+
+func printStatus(pool *pgxpool.Pool, path string) error {
+	states, err := database.GetMigrationStatus(pool, path)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	pending := 0
+	for _, state := range states {
+		applied := "pending"
+		switch {
+		case state.Missing:
+			applied = state.AppliedAt.Format(statusTimeFormat) + " (file missing)"
+		case state.AppliedAt != nil:
+			applied = state.AppliedAt.Format(statusTimeFormat)
+		default:
+			pending++
+		}
+		_, _ = fmt.Fprintf(w, "%03d\t%s\t%s\n", state.Version, state.Name, applied)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d migrations, %d pending\n", len(states), pending)
+	return nil
+}
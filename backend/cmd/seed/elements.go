@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const drawingPoints = 40
+
+// demoElements lays out an element of every type. The video element is
+// left out when there is no video asset.
+func demoElements(workspaceID, userID uuid.UUID, img, video *models.Asset) []models.CanvasElement {
+	var elements []models.CanvasElement
+	add := func(elementType models.ElementType, parentID *uuid.UUID, data any) uuid.UUID {
+		id := uuid.New()
+		elements = append(elements, models.CanvasElement{
+			ID:          id,
+			WorkspaceID: workspaceID,
+			ElementType: elementType,
+			ElementData: toElementData(data),
+			ZIndex:      len(elements),
+			ParentID:    parentID,
+			CreatedBy:   userID,
+			UpdatedBy:   &userID,
+		})
+		return id
+	}
+	base := func(x, y, width, height float64, style models.Style) models.BaseElementData {
+		return models.BaseElementData{
+			Position: models.Position{X: x, Y: y},
+			Size:     models.Size{Width: width, Height: height},
+			Style:    style,
+		}
+	}
+
+	// The group is created first so its children can point to it
+	groupID := add(models.ElementTypeGroup, nil, nil)
+
+	titleID := add(models.ElementTypeText, &groupID, models.TextElementData{
+		Content:         "<h1>Welcome to HertzBoard</h1>",
+		PlainText:       "Welcome to HertzBoard",
+		BaseElementData: base(40, 40, 480, 60, models.Style{FontSize: 32, FontWeight: "bold", Fill: "#1f2937"}),
+	})
+	welcomeID := add(models.ElementTypeSticky, &groupID, models.StickyNoteData{
+		Content:         "Drag me around, double click to edit",
+		Color:           "#fde68a",
+		BaseElementData: base(40, 120, 200, 200, models.Style{FontSize: 16}),
+	})
+
+	startID := add(models.ElementTypeShape, nil, models.ShapeElementData{
+		ShapeType:       "rectangle",
+		BaseElementData: base(320, 160, 160, 90, models.Style{Fill: "#bfdbfe", Stroke: "#1d4ed8", StrokeWidth: 2}),
+	})
+	endID := add(models.ElementTypeShape, nil, models.ShapeElementData{
+		ShapeType:       "ellipse",
+		BaseElementData: base(600, 160, 140, 90, models.Style{Fill: "#bbf7d0", Stroke: "#15803d", StrokeWidth: 2}),
+	})
+	add(models.ElementTypeConnector, nil, models.ConnectorElementData{
+		StartElementID:  &startID,
+		EndElementID:    &endID,
+		LineType:        "straight",
+		ArrowEnd:        true,
+		BaseElementData: base(480, 205, 120, 0, models.Style{Stroke: "#374151", StrokeWidth: 2}),
+	})
+
+	add(models.ElementTypeSticky, nil, models.StickyNoteData{
+		Content:         "Ideas go here",
+		Color:           "#fbcfe8",
+		BaseElementData: base(800, 140, 200, 200, models.Style{FontSize: 16}),
+	})
+
+	add(models.ElementTypeList, nil, models.ListElementData{
+		ListType: "checkbox",
+		Items: []models.ListItem{
+			{ID: uuid.New(), Content: "Invite the team", Checked: true},
+			{ID: uuid.New(), Content: "Sketch the first flow"},
+			{ID: uuid.New(), Content: "Save a snapshot"},
+		},
+		BaseElementData: base(40, 360, 260, 140, models.Style{FontSize: 14}),
+	})
+
+	// A sine wave drawn freehand
+	points := make([]models.Point, 0, drawingPoints)
+	for i := 0; i < drawingPoints; i++ {
+		x := float64(i) * 8
+		points = append(points, models.Point{X: x, Y: 30 * math.Sin(x/40), Pressure: 0.5})
+	}
+	add(models.ElementTypeDrawing, nil, models.DrawingElementData{
+		Points:          points,
+		Smooth:          true,
+		BaseElementData: base(340, 420, 320, 60, models.Style{Stroke: "#7c3aed", StrokeWidth: 3}),
+	})
+
+	imageData := models.ImageElementData{
+		URL:             img.URL,
+		AssetID:         img.ID,
+		BaseElementData: base(40, 540, demoImageWidth/2, demoImageHeight/2, models.Style{}),
+	}
+	if img.ThumbnailURL != nil {
+		imageData.ThumbnailURL = *img.ThumbnailURL
+	}
+	add(models.ElementTypeImage, nil, imageData)
+
+	if video != nil {
+		add(models.ElementTypeVideo, nil, map[string]any{
+			"url":        video.URL,
+			"asset_id":   video.ID,
+			"poster_url": img.URL,
+			"position":   models.Position{X: 400, Y: 540},
+			"size":       models.Size{Width: 320, Height: 180},
+		})
+	}
+
+	// Fill in the group now that its children exist
+	elements[0].ElementData = toElementData(models.GroupElementData{
+		ChildIDs:        []uuid.UUID{titleID, welcomeID},
+		BaseElementData: base(40, 40, 480, 280, models.Style{}),
+	})
+
+	return elements
+}
+
+// toElementData converts typed element data to the generic map stored in
+// element_data
+func toElementData(data any) models.ElementData {
+	result := make(models.ElementData)
+	if data == nil {
+		return result
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		panic(err) // only fixed, encodable values are seeded
+	}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		panic(err)
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+// Command seed fills a development database with demo data: three users, a
+// workspace shared between them with an element of every type, an image
+// asset and two snapshots. It is meant for local development, e2e tests
+// and product demos, never for production.
+//
+//	seed [-password PASSWORD] [-video FILE] [-force]
+//
+// Users that already exist are reused. The workspace is only created once
+// unless -force is given. The repo ships no video, pass an mp4 or webm file
+// with -video to also seed a video element.
+//
+// It reads CONFIG_PATH like the services and needs PostgreSQL, NATS and the
+// object storage to be up, with migrations applied.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/database"
+	"github.com/bifshteksex/hertz-board/internal/encryption"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	defaultConfigPath = "configs/config.yaml"
+	defaultPassword   = "demo-password"
+)
+
+func main() {
+	password := flag.String("password", defaultPassword, "password of the demo users")
+	video := flag.String("video", "", "mp4 or webm file for the video element, skipped when empty")
+	force := flag.Bool("force", false, "create another demo workspace when one exists")
+	flag.Parse()
+
+	if err := run(*password, *video, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(password, videoPath string, force bool) error {
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = defaultConfigPath
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	pool, err := database.NewPostgresPool(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer database.ClosePostgresPool(pool)
+
+	// Uploaded assets queue their processing jobs on NATS
+	nc, err := database.NewNATSConnection(&cfg.NATS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	defer database.CloseNATSConnection(nc)
+
+	s, err := newSeeder(cfg, pool, nc)
+	if err != nil {
+		return err
+	}
+	return s.seed(context.Background(), password, videoPath, force)
+}
+
+func newSeeder(cfg *config.Config, pool *pgxpool.Pool, nc *nats.Conn) (*seeder, error) {
+	storage, err := service.NewObjectStorage(&cfg.Storage, &cfg.MinIO)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize object storage: %w", err)
+	}
+
+	// Seeded boards are encrypted like the ones the API gateway writes
+	var keyring *encryption.Keyring
+	if cfg.Encryption.Enabled {
+		keyWrapper, err := encryption.NewKeyWrapper(&cfg.Encryption)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize encryption: %w", err)
+		}
+		keyring = encryption.NewKeyring(keyWrapper, repository.NewDataKeyRepository(pool))
+	}
+
+	workspaceRepo := repository.NewWorkspaceRepository(pool, nil)
+	canvasRepo := repository.NewCanvasRepository(pool, nil, keyring)
+	// Seeded boards use the configured quota, not plan limits
+	assetService, err := service.NewAssetService(
+		repository.NewAssetRepository(pool, nil), workspaceRepo, nc, storage, &cfg.MinIO, &cfg.Upload, nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asset service: %w", err)
+	}
+
+	// No realtime clients or event consumers to notify while seeding
+	snapshotService := service.NewSnapshotService(
+		repository.NewSnapshotRepository(pool, nil, keyring), canvasRepo, workspaceRepo, nil, nil, assetService, nil, storage, nil,
+	)
+
+	return &seeder{
+		userRepo:      repository.NewUserRepository(pool),
+		workspaceRepo: workspaceRepo,
+		canvasRepo:    canvasRepo,
+		assets:        assetService,
+		snapshots:     snapshotService,
+	}, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"maps"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	demoWorkspaceName = "Demo board"
+	demoImageName     = "hertzboard-demo.png"
+	demoImageWidth    = 640
+	demoImageHeight   = 400
+)
+
+// demoUsers are the seeded accounts, the first one owns the workspace
+var demoUsers = []struct {
+	email string
+	name  string
+	role  models.WorkspaceRole
+}{
+	{"alice@example.com", "Alice Demo", models.WorkspaceRoleOwner},
+	{"bob@example.com", "Bob Demo", models.WorkspaceRoleEditor},
+	{"carol@example.com", "Carol Demo", models.WorkspaceRoleViewer},
+}
+
+type seeder struct {
+	userRepo      *repository.UserRepository
+	workspaceRepo *repository.WorkspaceRepository
+	canvasRepo    *repository.CanvasRepository
+	assets        *service.AssetService
+	snapshots     *service.SnapshotService
+}
+
+func (s *seeder) seed(ctx context.Context, password, videoPath string, force bool) error {
+	users := make([]*models.User, 0, len(demoUsers))
+	for _, demo := range demoUsers {
+		user, err := s.ensureUser(ctx, demo.email, demo.name, password)
+		if err != nil {
+			return err
+		}
+		users = append(users, user)
+	}
+	owner := users[0]
+
+	if !force {
+		existing, _, err := s.workspaceRepo.ListWorkspacesByUser(ctx, owner.ID, models.WorkspaceListFilter{
+			Query:     demoWorkspaceName,
+			OwnedOnly: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to look up demo workspace: %w", err)
+		}
+		if len(existing) > 0 {
+			fmt.Printf("Demo workspace %s already exists, use -force to create another one\n", existing[0].ID)
+			return nil
+		}
+	}
+
+	description := "Sample board with an element of every type"
+	workspace := &models.Workspace{
+		ID:          uuid.New(),
+		Name:        demoWorkspaceName,
+		Description: &description,
+		OwnerID:     owner.ID,
+		Settings:    make(map[string]interface{}),
+	}
+	if err := s.workspaceRepo.CreateWorkspace(ctx, workspace); err != nil {
+		return fmt.Errorf("failed to create demo workspace: %w", err)
+	}
+	for i := 1; i < len(users); i++ {
+		member := &models.WorkspaceMember{
+			ID:          uuid.New(),
+			WorkspaceID: workspace.ID,
+			UserID:      users[i].ID,
+			Role:        demoUsers[i].role,
+			InvitedBy:   &owner.ID,
+		}
+		if err := s.workspaceRepo.AddMember(ctx, member); err != nil {
+			return fmt.Errorf("failed to add %s to the demo workspace: %w", users[i].Email, err)
+		}
+	}
+
+	imageAsset, err := s.uploadDemoImage(ctx, workspace.ID, owner.ID)
+	if err != nil {
+		return err
+	}
+	var videoAsset *models.Asset
+	if videoPath != "" {
+		if videoAsset, err = s.uploadVideo(ctx, workspace.ID, owner.ID, videoPath); err != nil {
+			return err
+		}
+	}
+
+	elements := demoElements(workspace.ID, owner.ID, imageAsset, videoAsset)
+	if err := s.canvasRepo.BatchCreateElements(ctx, elements); err != nil {
+		return fmt.Errorf("failed to create demo elements: %w", err)
+	}
+
+	if err := s.createSnapshots(ctx, workspace.ID, owner.ID, elements); err != nil {
+		return err
+	}
+
+	fmt.Printf("Seeded workspace %q (%s) with %d elements\n", workspace.Name, workspace.ID, len(elements))
+	for i, user := range users {
+		fmt.Printf("  %-20s %-6s password %s\n", user.Email, demoUsers[i].role, password)
+	}
+	if videoAsset == nil {
+		fmt.Println("No -video file given, the board has no video element")
+	}
+	return nil
+}
+
+// ensureUser returns the user with the email, creating a verified account
+// when there is none
+func (s *seeder) ensureUser(ctx context.Context, email, name, password string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", email, err)
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	passwordHash := string(hash)
+
+	user = &models.User{
+		Email:         email,
+		PasswordHash:  &passwordHash,
+		Name:          name,
+		Provider:      "email",
+		EmailVerified: true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", email, err)
+	}
+	return user, nil
+}
+
+// uploadDemoImage uploads a generated gradient, so the seed needs no files
+func (s *seeder) uploadDemoImage(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Asset, error) {
+	img := image.NewRGBA(image.Rect(0, 0, demoImageWidth, demoImageHeight))
+	for y := 0; y < demoImageHeight; y++ {
+		for x := 0; x < demoImageWidth; x++ {
+			img.Set(x, y, color.RGBA{
+				R: uint8(x * 255 / demoImageWidth),  //nolint:gosec // below 256
+				G: uint8(y * 255 / demoImageHeight), //nolint:gosec // below 256
+				B: 200,
+				A: 255,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode demo image: %w", err)
+	}
+
+	asset, err := s.assets.UploadAsset(
+		ctx, workspaceID, userID, demoImageName, "image/png", int64(buf.Len()), &buf,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload demo image: %w", err)
+	}
+	return asset, nil
+}
+
+func (s *seeder) uploadVideo(ctx context.Context, workspaceID, userID uuid.UUID, path string) (*models.Asset, error) {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if !service.AllowedVideoTypes[contentType] {
+		return nil, fmt.Errorf("%s is not an mp4 or webm video", path)
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // path is given by the developer running the seed
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video: %w", err)
+	}
+
+	asset, err := s.assets.UploadAsset(
+		ctx, workspaceID, userID, filepath.Base(path), contentType, int64(len(data)), bytes.NewReader(data),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload video: %w", err)
+	}
+	return asset, nil
+}
+
+// createSnapshots saves a pinned snapshot of the seeded board, then moves a
+// sticky note and saves an automatic one, so history and diffs have
+// something to show
+func (s *seeder) createSnapshots(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	elements []models.CanvasElement,
+) error {
+	name := "Seeded board"
+	if _, _, err := s.snapshots.CreateSnapshot(ctx, workspaceID, userID, &models.CreateSnapshotRequest{
+		Name:   &name,
+		Tags:   []string{"demo"},
+		Pinned: true,
+	}); err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	for i := range elements {
+		if elements[i].ElementType != models.ElementTypeSticky {
+			continue
+		}
+		moved := elements[i]
+		moved.ElementData = maps.Clone(moved.ElementData)
+		moved.ElementData["position"] = models.Position{X: 720, Y: 420}
+		moved.UpdatedBy = &userID
+		if err := s.canvasRepo.BatchUpdateElements(ctx, []models.CanvasElement{moved}); err != nil {
+			return fmt.Errorf("failed to move sticky note: %w", err)
+		}
+		break
+	}
+
+	if _, _, err := s.snapshots.CreateSnapshot(ctx, workspaceID, userID, &models.CreateSnapshotRequest{}); err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	return nil
+}
@@ -12,29 +12,246 @@ import (
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"google.golang.org/grpc"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/database"
+	"github.com/bifshteksex/hertz-board/internal/debug"
+	"github.com/bifshteksex/hertz-board/internal/handler"
+	"github.com/bifshteksex/hertz-board/internal/logger"
+	"github.com/bifshteksex/hertz-board/internal/metrics"
+	"github.com/bifshteksex/hertz-board/internal/middleware"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/router"
+	"github.com/bifshteksex/hertz-board/internal/rpc"
+	"github.com/bifshteksex/hertz-board/internal/service"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 )
 
 const (
-	defaultPort            = ":8081"
 	shutdownTimeoutSeconds = 5
+	defaultConfigPath      = "configs/config.yaml"
 )
 
 func main() {
+	// Load configuration
+	configPath := getEnv("CONFIG_PATH", defaultConfigPath)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	// Initialize logger
-	log.Println("Starting HertzBoard WebSocket Server...")
+	logCloser, err := logger.Setup(&cfg.Logging, "ws-server")
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logCloser.Close()
 
-	// Load configuration
-	// TODO: Implement config loading
+	hlog.Infof("Starting HertzBoard WebSocket Server (%s environment)...", cfg.App.Env)
+
+	shutdownTracing, err := tracing.Init(&cfg.Tracing, "ws-server")
+	if err != nil {
+		hlog.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeoutSeconds*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+	}()
+
+	// Pick up rotated secrets of vault: and aws-sm: references
+	go cfg.SecretManager().Run(context.Background())
+
+	// Apply changes of the reloadable settings without a restart
+	configReloader := config.NewReloader(configPath, cfg)
+	configReloader.OnReload(func(current *config.Config) {
+		if levelErr := logger.SetLevel(current.Logging.Level); levelErr != nil {
+			hlog.Warnf("Keeping the current log level: %v", levelErr)
+		}
+	})
+
+	// Connect to databases
+	hlog.Info("Connecting to PostgreSQL...")
+	dbPool, err := database.NewPostgresPool(&cfg.Database)
+	if err != nil {
+		hlog.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer database.ClosePostgresPool(dbPool)
+	hlog.Info("Connected to PostgreSQL")
+
+	hlog.Info("Connecting to Redis...")
+	redisClient, err := database.NewRedisClient(&cfg.Redis)
+	if err != nil {
+		database.ClosePostgresPool(dbPool)
+		hlog.Fatalf("Failed to connect to Redis: %v", err) //nolint:gocritic // cleanup is done before exit
+	}
+	defer func() {
+		_ = database.CloseRedisClient(redisClient)
+	}()
+	hlog.Info("Connected to Redis")
+
+	hlog.Info("Connecting to NATS...")
+	natsConn, err := database.NewNATSConnection(&cfg.NATS)
+	if err != nil {
+		database.ClosePostgresPool(dbPool)
+		_ = database.CloseRedisClient(redisClient)
+		hlog.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer database.CloseNATSConnection(natsConn)
+	hlog.Info("Connected to NATS")
+
+	// Usage analytics go through NATS to ClickHouse
+	var analyticsService *service.AnalyticsService
+	if cfg.ClickHouse.Enabled {
+		analyticsService = service.NewAnalyticsService(natsConn)
+	}
+
+	// Editors of boards are metered here, the API gateway aggregates and bills
+	var meteringService *service.MeteringService
+	if cfg.Metering.Enabled {
+		meteringService = service.NewMeteringService(repository.NewUsageRepository(dbPool), nil)
+	}
+
+	// Initialize services
+	jwtService, err := service.NewJWTService(&cfg.JWT)
+	if err != nil {
+		hlog.Fatalf("Failed to create JWT service: %v", err)
+	}
+
+	// Hub fans messages out through the configured broker, so clients connected
+	// here and clients connected to the api-gateway share the same rooms
+	broker, err := service.NewBroker(cfg, redisClient, natsConn)
+	if err != nil {
+		hlog.Fatalf("Failed to create realtime broker: %v", err)
+	}
+	defer func() {
+		_ = broker.Close()
+	}()
+	recorder := service.NewSessionRecorder(redisClient)
+	defer func() {
+		_ = recorder.Close()
+	}()
+	hub := service.NewHub(broker, service.NewOnlineUsers(redisClient), analyticsService, recorder)
+	roomRegistry := service.NewRoomRegistry(redisClient, hub)
+	defer func() {
+		_ = roomRegistry.Close()
+	}()
+
+	userRepo := repository.NewUserRepository(dbPool)
+	notificationRepo := repository.NewNotificationRepository(dbPool)
+	// The API gateway relays the outbox this service writes events and emails to
+	outboxRepo := repository.NewOutboxRepository(dbPool)
+	emailService, err := service.NewEmailService(
+		&cfg.Email, natsConn, outboxRepo, notificationRepo, repository.NewEmailSuppressionRepository(dbPool), redisClient,
+	)
+	if err != nil {
+		hlog.Fatalf("Failed to create email service: %v", err)
+	}
+
+	// Operations received over WebSocket are persisted through the CRDT
+	// service, which also notifies users mentioned in sticky notes
+	eventPublisher := service.NewEventPublisher(outboxRepo)
+	webPushService, err := service.NewWebPushService(
+		&cfg.Notifications.WebPush, repository.NewPushSubscriptionRepository(dbPool), hub,
+	)
+	if err != nil {
+		hlog.Fatalf("Failed to create web push service: %v", err)
+	}
+	notificationService := service.NewNotificationService(
+		notificationRepo, userRepo, emailService, hub, webPushService, cfg.App.FrontendURL,
+	)
+	crdt := service.NewCRDTService(
+		repository.NewElementRepository(dbPool),
+		repository.NewOperationRepository(dbPool),
+		eventPublisher,
+		notificationService,
+		analyticsService,
+		meteringService,
+	)
+
+	// Workspace service resolves the user's role on join
+	workspaceRepo := repository.NewWorkspaceRepository(dbPool, nil)
+	workspaceService := service.NewWorkspaceService(
+		workspaceRepo,
+		userRepo,
+		emailService,
+		eventPublisher,
+		webPushService,
+		nil, // invitations are handled by the API gateway
+		nil, // and so are the membership changes that are audited
+		nil, // and the visits of workspaces
+		nil, // and access requests
+		nil, // and freezing boards
+		nil, // and counting views
+	)
+
+	// Joins from outside a workspace's allowlist are refused and audited,
+	// the API gateway ships the audit log
+	var auditService *service.AuditService
+	if cfg.Audit.Enabled {
+		auditService = service.NewAuditService(repository.NewAuditRepository(dbPool))
+	}
+	ipAllowlistService := service.NewIPAllowlistService(workspaceRepo, auditService)
+	botService := service.NewBotService(repository.NewBotRepository(dbPool), workspaceRepo, auditService)
+
+	// Presenters change slides over WebSocket, the API gateway manages the rest
+	presentationService := service.NewPresentationService(
+		repository.NewPresentationRepository(dbPool), workspaceRepo, hub,
+	)
+
+	// Reload requests of admins arrive through the api-gateway
+	go configReloader.Watch(context.Background())
+	go service.NewConfigReloadService(configReloader, redisClient, auditService).Run(context.Background())
+
+	wsHandler := handler.NewWebSocketHandler(
+		hub, jwtService, crdt, workspaceService, analyticsService, ipAllowlistService, botService, presentationService,
+	)
+
+	// Prometheus metrics, served on their own port
+	var httpMetrics *metrics.HTTPMetrics
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled {
+		registry := metrics.NewServiceRegistry(dbPool, redisClient, natsConn, hub)
+		httpMetrics = metrics.NewHTTPMetrics(registry)
+		metricsServer = metrics.NewServer(cfg.Metrics.WSPort, registry)
+	}
+
+	// Internal API for the api-gateway and admin tooling
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = rpc.NewServer(&cfg.GRPC, hub, crdt)
+	}
+
+	// Profiling and runtime endpoints, served on an internal port
+	var debugServer *http.Server
+	if cfg.Debug.Enabled {
+		debug.PublishHub(hub)
+		debugServer = debug.NewServer(&cfg.Debug, cfg.Debug.WSPort)
+	}
 
 	// Initialize Hertz server for WebSocket
+	addr := fmt.Sprintf(":%d", cfg.WebSocket.Port)
 	h := server.Default(
-		server.WithHostPorts(defaultPort),
+		server.WithHostPorts(addr),
 	)
 
-	// TODO: Initialize WebSocket hub
-	// TODO: Register WebSocket handlers
-	// TODO: Connect to Redis for pub/sub
-	// TODO: Initialize CRDT sync engine
+	// Forwarded client addresses are only taken from trusted proxies
+	trustedProxies, err := cfg.App.GetTrustedProxies()
+	if err != nil {
+		hlog.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	h.SetClientIPFunc(middleware.ClientIP(trustedProxies))
+
+	h.Use(middleware.Recovery())
+	h.Use(middleware.RequestID())
+	h.Use(middleware.Tracing())
+	h.Use(middleware.Logger())
+	if httpMetrics != nil {
+		h.Use(middleware.Metrics(httpMetrics))
+	}
+	h.Use(middleware.CORS(configReloader))
 
 	// Register health check endpoint
 	h.GET("/health", func(c context.Context, ctx *app.RequestContext) {
@@ -45,30 +262,61 @@ func main() {
 		})
 	})
 
+	// Register WebSocket and metrics endpoints
+	router.SetupRealtime(h, wsHandler, hub)
+
 	// Graceful shutdown
 	go func() {
 		if err := h.Run(); err != nil {
-			log.Fatalf("Failed to run server: %v", err)
+			hlog.Fatalf("Failed to run server: %v", err)
 		}
 	}()
 
-	log.Printf("WebSocket Server is running on %s", defaultPort)
+	hlog.Infof("WebSocket Server is running on %s", addr)
+
+	if metricsServer != nil {
+		metrics.Start(metricsServer)
+	}
+	if debugServer != nil {
+		debug.Start(debugServer)
+	}
+	if grpcServer != nil {
+		if err := rpc.Start(grpcServer, &cfg.GRPC); err != nil {
+			hlog.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	hlog.Info("Shutting down server...")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeoutSeconds*time.Second)
+	defer cancel()
+
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(ctx)
+	}
+	if debugServer != nil {
+		_ = debugServer.Shutdown(ctx)
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
 
 	if err := h.Shutdown(ctx); err != nil {
-		cancel()
-		log.Fatalf("Server forced to shutdown: %v", err)
+		hlog.Fatalf("Server forced to shutdown: %v", err)
 	}
 
-	cancel()
-	fmt.Println("Server exited")
+	hlog.Info("Server exited")
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
 }
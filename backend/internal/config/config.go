@@ -2,36 +2,68 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	App        AppConfig        `yaml:"app"`
-	Database   DatabaseConfig   `yaml:"database"`
-	Redis      RedisConfig      `yaml:"redis"`
-	MinIO      MinIOConfig      `yaml:"minio"`
-	ClickHouse ClickHouseConfig `yaml:"clickhouse"`
-	NATS       NATSConfig       `yaml:"nats"`
-	JWT        JWTConfig        `yaml:"jwt"`
-	OAuth      OAuthConfig      `yaml:"oauth"`
-	Email      EmailConfig      `yaml:"email"`
-	CORS       CORSConfig       `yaml:"cors"`
-	WebSocket  WebSocketConfig  `yaml:"websocket"`
-	Upload     UploadConfig     `yaml:"upload"`
-	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
-	Logging    LoggingConfig    `yaml:"logging"`
-	Metrics    MetricsConfig    `yaml:"metrics"`
-	Tracing    TracingConfig    `yaml:"tracing"`
+	App           AppConfig           `yaml:"app"`
+	Database      DatabaseConfig      `yaml:"database"`
+	Redis         RedisConfig         `yaml:"redis"`
+	MinIO         MinIOConfig         `yaml:"minio"`
+	Storage       StorageConfig       `yaml:"storage"`
+	ClickHouse    ClickHouseConfig    `yaml:"clickhouse"`
+	NATS          NATSConfig          `yaml:"nats"`
+	Outbox        OutboxConfig        `yaml:"outbox"`
+	JWT           JWTConfig           `yaml:"jwt"`
+	Auth          AuthConfig          `yaml:"auth"`
+	OAuth         OAuthConfig         `yaml:"oauth"`
+	SCIM          SCIMConfig          `yaml:"scim"`
+	LDAP          LDAPConfig          `yaml:"ldap"`
+	Email         EmailConfig         `yaml:"email"`
+	Admin         AdminConfig         `yaml:"admin"`
+	Legal         LegalConfig         `yaml:"legal"`
+	Audit         AuditConfig         `yaml:"audit"`
+	Encryption    EncryptionConfig    `yaml:"encryption"`
+	Secrets       SecretsConfig       `yaml:"secrets"`
+	Billing       BillingConfig       `yaml:"billing"`
+	Metering      MeteringConfig      `yaml:"metering"`
+	CORS          CORSConfig          `yaml:"cors"`
+	Embed         EmbedConfig         `yaml:"embed"`
+	WebSocket     WebSocketConfig     `yaml:"websocket"`
+	Upload        UploadConfig        `yaml:"upload"`
+	Snapshots     SnapshotsConfig     `yaml:"snapshots"`
+	Operations    OperationsConfig    `yaml:"operations"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Integrations  IntegrationsConfig  `yaml:"integrations"`
+	AI            AIConfig            `yaml:"ai"`
+	Translation   TranslationConfig   `yaml:"translation"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit"`
+	Logging       LoggingConfig       `yaml:"logging"`
+	Metrics       MetricsConfig       `yaml:"metrics"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+	Debug         DebugConfig         `yaml:"debug"`
+	Docs          DocsConfig          `yaml:"docs"`
+	GraphQL       GraphQLConfig       `yaml:"graphql"`
+	GRPC          GRPCConfig          `yaml:"grpc"`
+	Reload        ReloadConfig        `yaml:"reload"`
+	Features      FeaturesConfig      `yaml:"features"`
+
+	secrets *SecretManager
 }
 
 type AppConfig struct {
-	Name  string `yaml:"name"`
-	Env   string `yaml:"env"`
-	Port  int    `yaml:"port"`
-	Debug bool   `yaml:"debug"`
+	Name           string   `yaml:"name"`
+	Env            string   `yaml:"env"`
+	FrontendURL    string   `yaml:"frontend_url"` // base URL of links to the app, e.g. in chat messages
+	Port           int      `yaml:"port"`
+	Debug          bool     `yaml:"debug"`
+	MaxBodySize    int64    `yaml:"max_body_size"`   // bytes of request bodies other than uploads
+	TrustedProxies []string `yaml:"trusted_proxies"` // CIDRs or addresses of proxies whose X-Forwarded-For is used, none when empty
 }
 
 type DatabaseConfig struct {
@@ -41,9 +73,14 @@ type DatabaseConfig struct {
 	User                  string `yaml:"user"`
 	Password              string `yaml:"password"`
 	SSLMode               string `yaml:"ssl_mode"`
-	MaxConnections        int    `yaml:"max_connections"`
-	MaxIdleConnections    int    `yaml:"max_idle_connections"`
-	ConnectionMaxLifetime int    `yaml:"connection_max_lifetime"`
+	MaxConnections        int    `yaml:"max_connections"`         // pool size, 25 when zero
+	MaxIdleConnections    int    `yaml:"max_idle_connections"`    // connections kept open when idle
+	ConnectionMaxLifetime int    `yaml:"connection_max_lifetime"` // seconds, an hour when zero
+	ReplicaHost           string `yaml:"replica_host"`            // read-only replica for listings and searches, empty for none
+	ReplicaPort           int    `yaml:"replica_port"`            // defaults to port
+	AutoMigrate           bool   `yaml:"auto_migrate"`            // apply pending migrations when the api-gateway starts
+
+	secrets *SecretManager
 }
 
 type RedisConfig struct {
@@ -51,8 +88,8 @@ type RedisConfig struct {
 	Port       int    `yaml:"port"`
 	Password   string `yaml:"password"`
 	DB         int    `yaml:"db"`
-	MaxRetries int    `yaml:"max_retries"`
-	PoolSize   int    `yaml:"pool_size"`
+	MaxRetries int    `yaml:"max_retries"` // 3 when zero, negative disables retries
+	PoolSize   int    `yaml:"pool_size"`   // 10 per CPU when zero
 }
 
 type MinIOConfig struct {
@@ -63,32 +100,113 @@ type MinIOConfig struct {
 	BucketAssets  string `yaml:"bucket_assets"`
 	BucketExports string `yaml:"bucket_exports"`
 	BucketBackups string `yaml:"bucket_backups"`
+	URLExpiry     string `yaml:"url_expiry"`
+}
+
+type StorageConfig struct {
+	Provider     string `yaml:"provider"`      // minio (default), s3, gcs or filesystem
+	Bucket       string `yaml:"bucket"`        // asset bucket, also the directory name for filesystem
+	BackupBucket string `yaml:"backup_bucket"` // snapshot bucket, defaults to minio.bucket_backups for minio
+	ExportBucket string `yaml:"export_bucket"` // report bucket, defaults to minio.bucket_exports for minio
+	Endpoint     string `yaml:"endpoint"`      // s3/gcs endpoint override, e.g. for S3-compatible services
+	Region       string `yaml:"region"`        // s3 region
+	AccessKey    string `yaml:"access_key"`    // s3 access key or GCS HMAC key
+	SecretKey    string `yaml:"secret_key"`    // s3 secret key or GCS HMAC secret
+	Path         string `yaml:"path"`          // filesystem root directory
+	PublicURL    string `yaml:"public_url"`    // filesystem: base URL of the API for presigned links
+	SigningKey   string `yaml:"signing_key"`   // filesystem: HMAC key for presigned links
 }
 
 type ClickHouseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	Database string `yaml:"database"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Enabled       bool   `yaml:"enabled"`
+	Host          string `yaml:"host"`
+	Port          int    `yaml:"port"` // HTTP interface
+	Database      string `yaml:"database"`
+	User          string `yaml:"user"`
+	Password      string `yaml:"password"`
+	BatchSize     int    `yaml:"batch_size"`     // analytics events per insert
+	FlushInterval string `yaml:"flush_interval"` // longest wait before buffered events are inserted
+	MaxBuffered   int    `yaml:"max_buffered"`   // events kept while ClickHouse is down, newer ones are dropped
 }
 
 type NATSConfig struct {
-	URL           string `yaml:"url"`
-	MaxReconnect  int    `yaml:"max_reconnect"`
-	ReconnectWait int    `yaml:"reconnect_wait"`
+	JetStream     JetStreamConfig `yaml:"jetstream"`
+	URL           string          `yaml:"url"`
+	MaxReconnect  int             `yaml:"max_reconnect"`
+	ReconnectWait int             `yaml:"reconnect_wait"`
+}
+
+// OutboxConfig tunes the relay that publishes the transactional outbox
+type OutboxConfig struct {
+	PollInterval string `yaml:"poll_interval"` // how often new messages are looked for, bounds the delivery delay
+	BatchSize    int    `yaml:"batch_size"`    // messages published per transaction
+	Retention    string `yaml:"retention"`     // how long published messages are kept for debugging
+}
+
+type JetStreamConfig struct {
+	StreamName   string `yaml:"stream_name"`
+	MaxAge       string `yaml:"max_age"`
+	ConsumerName string `yaml:"consumer_name"` // durable consumer prefix of this instance, defaults to the hostname
 }
 
 type JWTConfig struct {
 	Secret             string `yaml:"secret"`
 	AccessTokenExpiry  string `yaml:"access_token_expiry"`
 	RefreshTokenExpiry string `yaml:"refresh_token_expiry"`
+
+	secrets *SecretManager
+}
+
+// AuthConfig holds account policies of a deployment
+type AuthConfig struct {
+	// RequireVerifiedEmail blocks invites, public sharing and asset uploads
+	// until the user has verified their email address
+	RequireVerifiedEmail bool `yaml:"require_verified_email"`
+}
+
+// SCIMConfig serves SCIM 2.0 provisioning at /scim/v2 for an identity
+// provider such as Okta or Azure AD
+type SCIMConfig struct {
+	Token   string `yaml:"token"` // bearer token the identity provider authenticates with
+	Enabled bool   `yaml:"enabled"`
+}
+
+// LDAPConfig authenticates the email and password login against an LDAP or
+// Active Directory server. Users are looked up with the service account,
+// then bound with their own password.
+type LDAPConfig struct {
+	URL            string `yaml:"url"`     // ldap://host:389 or ldaps://host:636
+	BindDN         string `yaml:"bind_dn"` // service account that searches users, empty to search anonymously
+	BindPassword   string `yaml:"bind_password"`
+	BaseDN         string `yaml:"base_dn"`
+	UserFilter     string `yaml:"user_filter"` // %s is replaced by the escaped login email
+	EmailAttribute string `yaml:"email_attribute"`
+	NameAttribute  string `yaml:"name_attribute"`
+	GroupAttribute string `yaml:"group_attribute"` // group DNs of the user, memberOf in Active Directory
+	Timeout        string `yaml:"timeout"`
+	// GroupRoles grants the members of a group a role in a workspace at login
+	GroupRoles         []LDAPGroupRoleConfig `yaml:"group_roles"`
+	StartTLS           bool                  `yaml:"start_tls"`
+	InsecureSkipVerify bool                  `yaml:"insecure_skip_verify"`
+	// LocalFallback lets users that aren't in the directory log in with a
+	// local password, e.g. the admins of the deployment
+	LocalFallback bool `yaml:"local_fallback"`
+	Enabled       bool `yaml:"enabled"`
+}
+
+type LDAPGroupRoleConfig struct {
+	GroupDN     string `yaml:"group_dn"`
+	WorkspaceID string `yaml:"workspace_id"`
+	Role        string `yaml:"role"` // editor or viewer
 }
 
 type OAuthProviderConfig struct {
 	ClientID     string `yaml:"client_id"`
 	ClientSecret string `yaml:"client_secret"`
 	RedirectURL  string `yaml:"redirect_url"`
+
+	secrets    *SecretManager
+	secretName string
 }
 
 type OAuthConfig struct {
@@ -97,11 +215,170 @@ type OAuthConfig struct {
 }
 
 type EmailConfig struct {
+	Provider     string `yaml:"provider"`    // smtp (default), sendgrid, ses or mailgun
+	APIKey       string `yaml:"api_key"`     // sendgrid/mailgun API key
+	Domain       string `yaml:"domain"`      // mailgun sending domain
+	Region       string `yaml:"region"`      // ses region, or "eu" for mailgun EU domains
+	AccessKey    string `yaml:"access_key"`  // ses access key
+	SecretKey    string `yaml:"secret_key"`  // ses secret key
+	WebhookKey   string `yaml:"webhook_key"` // sendgrid verification key, mailgun signing key or ses token
 	SMTPHost     string `yaml:"smtp_host"`
 	SMTPPort     int    `yaml:"smtp_port"`
 	SMTPUser     string `yaml:"smtp_user"`
 	SMTPPassword string `yaml:"smtp_password"`
 	From         string `yaml:"from"`
+	// TemplatesDir overrides the embedded email templates file by file
+	TemplatesDir string `yaml:"templates_dir"`
+	// RetryBackoff is the delay before the first retry, doubled per attempt
+	RetryBackoff    string `yaml:"retry_backoff"`
+	MaxRetryBackoff string `yaml:"max_retry_backoff"`
+	// MaxAttempts is how often an email is tried before it is dead-lettered
+	MaxAttempts int `yaml:"max_attempts"`
+	// MaxInvitesPerRecipientPerHour and MaxInvitesPerWorkspacePerHour limit
+	// invitation emails, 0 disables a limit
+	MaxInvitesPerRecipientPerHour int `yaml:"max_invites_per_recipient_per_hour"`
+	MaxInvitesPerWorkspacePerHour int `yaml:"max_invites_per_workspace_per_hour"`
+	// ReloadTemplates re-reads the templates for every email (development)
+	ReloadTemplates bool `yaml:"reload_templates"`
+
+	secrets *SecretManager
+}
+
+// AdminConfig lists the users allowed to use the admin endpoints
+type AdminConfig struct {
+	UserIDs []string `yaml:"user_ids"`
+}
+
+// LegalConfig holds the current versions of the terms of service and the
+// privacy policy. Users accept them at registration and again after a
+// version changes. Empty versions require no consent.
+type LegalConfig struct {
+	TermsVersion   string `yaml:"terms_version"`
+	TermsURL       string `yaml:"terms_url"`
+	PrivacyVersion string `yaml:"privacy_version"`
+	PrivacyURL     string `yaml:"privacy_url"`
+}
+
+// AuditConfig records security relevant actions in the audit log and ships
+// it to the SIEM sinks of the deployment
+type AuditConfig struct {
+	Sinks   []AuditSinkConfig `yaml:"sinks"`
+	Enabled bool              `yaml:"enabled"`
+}
+
+// AuditSinkConfig is one destination of the audit log. Each sink keeps its
+// own position in the log, a sink that is down catches up when it is back.
+type AuditSinkConfig struct {
+	Name       string            `yaml:"name"`        // identifies the position and the metrics of the sink, must not change
+	Type       string            `yaml:"type"`        // syslog, splunk or s3
+	Interval   string            `yaml:"interval"`    // how often new events are looked for
+	MaxBackoff string            `yaml:"max_backoff"` // longest wait between retries of a failing sink
+	BatchSize  int               `yaml:"batch_size"`  // events shipped per request, or per object for s3
+	Syslog     AuditSyslogConfig `yaml:"syslog"`
+	Splunk     AuditSplunkConfig `yaml:"splunk"`
+	S3         AuditS3SinkConfig `yaml:"s3"`
+}
+
+// AuditSyslogConfig sends RFC 5424 messages to a syslog server
+type AuditSyslogConfig struct {
+	Network            string `yaml:"network"` // udp, tcp or tls
+	Address            string `yaml:"address"` // host:port
+	AppName            string `yaml:"app_name"`
+	Facility           int    `yaml:"facility"` // 0-23, 13 (log audit) by default
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// AuditSplunkConfig sends events to a Splunk HTTP Event Collector
+type AuditSplunkConfig struct {
+	URL                string `yaml:"url"` // e.g. https://splunk:8088
+	Token              string `yaml:"token"`
+	Index              string `yaml:"index"`
+	Source             string `yaml:"source"`
+	SourceType         string `yaml:"source_type"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// AuditS3SinkConfig writes batches of events as gzipped JSON lines to an
+// S3-compatible bucket
+type AuditS3SinkConfig struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"` // objects are named prefix/YYYY/MM/DD/<first>-<last>.jsonl.gz
+	UseSSL    bool   `yaml:"use_ssl"`
+}
+
+// EncryptionConfig encrypts the content of elements and snapshots in
+// Postgres with a data key per workspace. Data keys are wrapped by a master
+// key held in the configuration or in Vault's transit engine.
+type EncryptionConfig struct {
+	Provider string                `yaml:"provider"` // local or vault
+	Local    EncryptionLocalConfig `yaml:"local"`
+	Vault    EncryptionVaultConfig `yaml:"vault"`
+	Enabled  bool                  `yaml:"enabled"`
+}
+
+// EncryptionLocalConfig holds master keys in the configuration. Rotated
+// keys stay in PreviousKeys until every data key was rewrapped, which
+// happens the first time a workspace is used after the rotation.
+type EncryptionLocalConfig struct {
+	KeyID        string               `yaml:"key_id"` // recorded with the data keys, must change with the key
+	Key          string               `yaml:"key"`    // base64 encoded 32 random bytes
+	PreviousKeys []EncryptionLocalKey `yaml:"previous_keys"`
+}
+
+// EncryptionLocalKey is a master key that was rotated out
+type EncryptionLocalKey struct {
+	KeyID string `yaml:"key_id"`
+	Key   string `yaml:"key"`
+}
+
+// EncryptionVaultConfig wraps data keys with a key of Vault's transit
+// secrets engine, so the master key never leaves Vault
+type EncryptionVaultConfig struct {
+	Address   string `yaml:"address"`   // e.g. https://vault:8200
+	Token     string `yaml:"token"`     // needs encrypt and decrypt on the key
+	Namespace string `yaml:"namespace"` // Vault Enterprise namespace, optional
+	Mount     string `yaml:"mount"`     // defaults to transit
+	KeyName   string `yaml:"key_name"`
+}
+
+// BillingConfig sells the pro and team plans through Stripe Checkout. When
+// billing is disabled no plan limits apply beyond the upload quota.
+type BillingConfig struct {
+	Plans         map[string]PlanConfig `yaml:"plans"`          // entitlements of the free, pro and team plans
+	Meters        BillingMetersConfig   `yaml:"meters"`         // Stripe meters overage is reported to
+	SecretKey     string                `yaml:"secret_key"`     // Stripe API key
+	WebhookSecret string                `yaml:"webhook_secret"` // signing secret of the Stripe webhook endpoint
+	APIURL        string                `yaml:"api_url"`        // https://api.stripe.com when empty
+	Enabled       bool                  `yaml:"enabled"`
+}
+
+// PlanConfig holds the Stripe price and the entitlements of a plan. Zero
+// limits are unlimited.
+type PlanConfig struct {
+	PriceID         string `yaml:"price_id"`         // Stripe price of paid plans
+	StorageQuotaMB  int64  `yaml:"storage_quota_mb"` // asset storage per workspace
+	MaxMembers      int    `yaml:"max_members"`      // members per workspace, including the owner
+	MaxSnapshots    int    `yaml:"max_snapshots"`    // version history depth per workspace
+	IncludedEditors int    `yaml:"included_editors"` // active editors per workspace and month before overage
+	IncludedExports int    `yaml:"included_exports"` // rendered exports per workspace and month before overage
+}
+
+// BillingMetersConfig names the Stripe billing meters overage of paid plans
+// is reported to. Dimensions without a meter are not billed.
+type BillingMetersConfig struct {
+	ActiveEditors string `yaml:"active_editors"`
+	StorageGB     string `yaml:"storage_gb"`
+	Exports       string `yaml:"exports"`
+}
+
+// MeteringConfig aggregates the billable usage of workspaces per month
+type MeteringConfig struct {
+	Interval string `yaml:"interval"` // how often storage is sampled and closed months are reported to billing
+	Enabled  bool   `yaml:"enabled"`
 }
 
 type CORSConfig struct {
@@ -112,25 +389,121 @@ type CORSConfig struct {
 	MaxAge           int      `yaml:"max_age"`
 }
 
+// EmbedConfig serves read-only boards to iframes in wikis and docs at
+// /embed/:token. Embeds are fetched from other sites, so they have their
+// own CORS origins.
+type EmbedConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"` // origins that may fetch embeds, "*" for any
+	FrameAncestors []string `yaml:"frame_ancestors"` // sent as CSP frame-ancestors, e.g. https://wiki.example.com
+	CacheMaxAge    string   `yaml:"cache_max_age"`   // how long browsers and CDNs may reuse a board, also after revocation
+}
+
 type WebSocketConfig struct {
-	Port            int `yaml:"port"`
-	ReadBufferSize  int `yaml:"read_buffer_size"`
-	WriteBufferSize int `yaml:"write_buffer_size"`
-	MaxMessageSize  int `yaml:"max_message_size"`
-	PingPeriod      int `yaml:"ping_period"`
-	PongWait        int `yaml:"pong_wait"`
-	WriteWait       int `yaml:"write_wait"`
+	Transport       string `yaml:"transport"` // redis or jetstream
+	Port            int    `yaml:"port"`
+	ReadBufferSize  int    `yaml:"read_buffer_size"`
+	WriteBufferSize int    `yaml:"write_buffer_size"`
+	MaxMessageSize  int    `yaml:"max_message_size"`
+	PingPeriod      int    `yaml:"ping_period"`
+	PongWait        int    `yaml:"pong_wait"`
+	WriteWait       int    `yaml:"write_wait"`
 }
 
 type UploadConfig struct {
-	MaxSize      int64    `yaml:"max_size"`
-	AllowedTypes []string `yaml:"allowed_types"`
+	Antivirus            AntivirusConfig `yaml:"antivirus"`
+	OCR                  OCRConfig       `yaml:"ocr"`
+	AllowedTypes         []string        `yaml:"allowed_types"`
+	PurgeInterval        string          `yaml:"purge_interval"`
+	MaxSize              int64           `yaml:"max_size"`               // bytes per uploaded file, images are capped at 10MB
+	WorkspaceQuota       int64           `yaml:"workspace_quota"`        // bytes per workspace, 0 means unlimited
+	DeletedRetentionDays int             `yaml:"deleted_retention_days"` // days before deleted assets are purged
+	StripMetadata        bool            `yaml:"strip_metadata"`         // remove EXIF data such as GPS from JPEGs
+}
+
+type SnapshotsConfig struct {
+	RetentionInterval string `yaml:"retention_interval"` // how often retention policies are enforced
+}
+
+// OperationsConfig manages the monthly partitions of the CRDT operation log
+type OperationsConfig struct {
+	Retention           string `yaml:"retention"`            // age after which operations are dropped, empty keeps them forever
+	PartitionsAhead     int    `yaml:"partitions_ahead"`     // monthly partitions created before they are needed
+	MaintenanceInterval string `yaml:"maintenance_interval"` // how often partitions are created and expired ones dropped
+}
+
+type NotificationsConfig struct {
+	DigestInterval string        `yaml:"digest_interval"` // how often email digests of unread notifications are sent
+	DigestDelay    string        `yaml:"digest_delay"`    // how long a notification stays unread before it's emailed
+	WebPush        WebPushConfig `yaml:"web_push"`
+}
+
+// WebPushConfig holds the VAPID key browser push notifications are signed with
+type WebPushConfig struct {
+	VAPIDPrivateKey string `yaml:"vapid_private_key"` // base64url P-256 private key, empty disables web push
+	Subject         string `yaml:"subject"`           // mailto: or https: contact of the push service operator
+}
+
+type AntivirusConfig struct {
+	Provider string `yaml:"provider"` // "clamav" or empty to skip scanning
+	Address  string `yaml:"address"`  // clamd TCP address
+	Timeout  string `yaml:"timeout"`
+}
+
+// OCRConfig selects how text is recognized in uploaded images
+type OCRConfig struct {
+	Provider  string   `yaml:"provider"`  // "tesseract", "google" or empty to disable OCR
+	Command   string   `yaml:"command"`   // tesseract binary
+	APIKey    string   `yaml:"api_key"`   // Google Cloud Vision API key
+	Timeout   string   `yaml:"timeout"`   // per image
+	Languages []string `yaml:"languages"` // tesseract language packs or Vision language hints, e.g. eng, deu
+}
+
+type IntegrationsConfig struct {
+	Unsplash UnsplashConfig `yaml:"unsplash"`
+	Giphy    GiphyConfig    `yaml:"giphy"`
+}
+
+type UnsplashConfig struct {
+	AccessKey string `yaml:"access_key"` // empty disables the integration
+	AppName   string `yaml:"app_name"`   // used in attribution links
+}
+
+type GiphyConfig struct {
+	APIKey string `yaml:"api_key"` // empty disables the integration
+	Rating string `yaml:"rating"`  // maximum content rating, e.g. "g" or "pg"
+}
+
+// AIConfig selects the language model behind the AI assistance endpoints
+type AIConfig struct {
+	Provider    string `yaml:"provider"`     // openai or anthropic, empty disables AI assistance
+	APIKey      string `yaml:"api_key"`      // key of the provider
+	Model       string `yaml:"model"`        // model of the provider, its default when empty
+	BaseURL     string `yaml:"base_url"`     // OpenAI-compatible endpoint, e.g. a self-hosted model
+	MaxElements int    `yaml:"max_elements"` // elements sent to the model per request, 200 when zero
+}
+
+// TranslationConfig selects the backend element content is translated with
+type TranslationConfig struct {
+	Provider    string `yaml:"provider"`     // deepl, google or ai, empty disables translation
+	APIKey      string `yaml:"api_key"`      // DeepL or Google Cloud API key, the ai provider uses ai config
+	BaseURL     string `yaml:"base_url"`     // DeepL endpoint, derived from the key when empty
+	MaxElements int    `yaml:"max_elements"` // elements translated per request, 100 when zero
 }
 
 type RateLimitConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	Requests int    `yaml:"requests"`
-	Duration string `yaml:"duration"`
+	Enabled  bool                   `yaml:"enabled"`
+	Requests int                    `yaml:"requests"`
+	Duration string                 `yaml:"duration"`
+	Routes   []RouteRateLimitConfig `yaml:"routes"` // tighter limits of sensitive routes
+}
+
+// RouteRateLimitConfig replaces the default limit for the routes whose
+// pattern starts with Route. The longest matching route wins.
+type RouteRateLimitConfig struct {
+	Route    string   `yaml:"route"`   // e.g. /api/v1/workspaces/:workspace_id/invites
+	Methods  []string `yaml:"methods"` // empty for every method
+	Requests int      `yaml:"requests"`
+	Duration string   `yaml:"duration"`
 }
 
 type LoggingConfig struct {
@@ -141,12 +514,48 @@ type LoggingConfig struct {
 
 type MetricsConfig struct {
 	Enabled bool `yaml:"enabled"`
-	Port    int  `yaml:"port"`
+	Port    int  `yaml:"port"`    // Prometheus /metrics port of the api-gateway
+	WSPort  int  `yaml:"ws_port"` // Prometheus /metrics port of the ws-server
+}
+
+// DebugConfig is the internal listener of the pprof and expvar endpoints.
+// Keep it bound to a private interface or set a token.
+type DebugConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`    // interface to listen on, e.g. 127.0.0.1
+	Port    int    `yaml:"port"`    // pprof/expvar port of the api-gateway
+	WSPort  int    `yaml:"ws_port"` // pprof/expvar port of the ws-server
+	Token   string `yaml:"token"`   // bearer token required by the endpoints, empty for none
+}
+
+// GRPCConfig is the internal gRPC API of the ws-server. The api-gateway
+// dials it to push changes into live rooms when an address is set.
+type GRPCConfig struct {
+	Host    string `yaml:"host"`  // interface the ws-server listens on, e.g. 127.0.0.1
+	Addr    string `yaml:"addr"`  // ws-server address dialed by the api-gateway, empty to use its own hub
+	Token   string `yaml:"token"` // shared bearer token required by the server, empty for none
+	Port    int    `yaml:"port"`
+	Enabled bool   `yaml:"enabled"`
+}
+
+// DocsConfig serves the generated OpenAPI spec and Swagger UI from the
+// api-gateway. Turn it off in production unless the API is public.
+type DocsConfig struct {
+	SpecPath string `yaml:"spec_path"` // spec written by make backend-docs
+	Enabled  bool   `yaml:"enabled"`
+}
+
+// GraphQLConfig serves a GraphQL API next to the REST API at
+// /api/v1/graphql
+type GraphQLConfig struct {
+	MaxDepth int  `yaml:"max_depth"` // deepest selection a query may have, 0 for the default
+	Enabled  bool `yaml:"enabled"`
 }
 
 type TracingConfig struct {
-	Enabled        bool   `yaml:"enabled"`
-	JaegerEndpoint string `yaml:"jaeger_endpoint"`
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP traces URL, e.g. Jaeger's http://localhost:4318/v1/traces
+	SampleRatio float64 `yaml:"sample_ratio"` // share of new traces that are recorded, 1 records all
 }
 
 // Load reads configuration from a YAML file
@@ -164,9 +573,44 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Replace vault: and aws-sm: references by the secrets they point to
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// GetTrustedProxies parses the networks of the trusted proxies, single
+// addresses are taken as networks of one address
+func (c *AppConfig) GetTrustedProxies() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
 // GetDSN returns PostgreSQL connection string
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf(
@@ -175,11 +619,54 @@ func (c *DatabaseConfig) GetDSN() string {
 	)
 }
 
+// GetReplicaDSN returns the connection string of the read replica. It uses
+// the credentials and database of the primary.
+func (c *DatabaseConfig) GetReplicaDSN() string {
+	port := c.ReplicaPort
+	if port == 0 {
+		port = c.Port
+	}
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.ReplicaHost, port, c.User, c.Password, c.Name, c.SSLMode,
+	)
+}
+
+// CurrentPassword returns the database password, the rotated one when it
+// is resolved from a secret reference
+func (c *DatabaseConfig) CurrentPassword() string {
+	return c.secrets.Get(SecretDatabasePassword, c.Password)
+}
+
 // GetRedisAddr returns Redis address
 func (c *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// CurrentSecret returns the signing secret, the rotated one when it is
+// resolved from a secret reference
+func (c *JWTConfig) CurrentSecret() string {
+	return c.secrets.Get(SecretJWT, c.Secret)
+}
+
+// PreviousSecret returns the signing secret before its last rotation, empty
+// when it wasn't rotated. Tokens signed with it are still accepted.
+func (c *JWTConfig) PreviousSecret() string {
+	return c.secrets.Previous(SecretJWT)
+}
+
+// CurrentClientSecret returns the client secret, the rotated one when it
+// is resolved from a secret reference
+func (c *OAuthProviderConfig) CurrentClientSecret() string {
+	return c.secrets.Get(c.secretName, c.ClientSecret)
+}
+
+// CurrentSMTPCredentials returns the SMTP user and password, the rotated
+// ones when they are resolved from secret references
+func (c *EmailConfig) CurrentSMTPCredentials() (user, password string) {
+	return c.secrets.Get(SecretSMTPUser, c.SMTPUser), c.secrets.Get(SecretSMTPPassword, c.SMTPPassword)
+}
+
 // GetAccessTokenDuration parses access token expiry duration
 func (c *JWTConfig) GetAccessTokenDuration() (time.Duration, error) {
 	return time.ParseDuration(c.AccessTokenExpiry)
@@ -189,3 +676,133 @@ func (c *JWTConfig) GetAccessTokenDuration() (time.Duration, error) {
 func (c *JWTConfig) GetRefreshTokenDuration() (time.Duration, error) {
 	return time.ParseDuration(c.RefreshTokenExpiry)
 }
+
+// GetTimeoutDuration parses the timeout of LDAP connections and requests
+func (c *LDAPConfig) GetTimeoutDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Timeout)
+}
+
+// GetCacheMaxAgeDuration parses how long embedded boards may be cached
+func (c *EmbedConfig) GetCacheMaxAgeDuration() (time.Duration, error) {
+	return time.ParseDuration(c.CacheMaxAge)
+}
+
+// GetURLExpiryDuration parses presigned asset URL expiry duration
+func (c *MinIOConfig) GetURLExpiryDuration() (time.Duration, error) {
+	return time.ParseDuration(c.URLExpiry)
+}
+
+// GetPurgeIntervalDuration parses how often deleted assets are purged
+func (c *UploadConfig) GetPurgeIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.PurgeInterval)
+}
+
+// GetRetentionIntervalDuration parses how often snapshot retention runs
+func (c *SnapshotsConfig) GetRetentionIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.RetentionInterval)
+}
+
+// GetPollIntervalDuration parses how often the outbox relay polls
+func (c *OutboxConfig) GetPollIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.PollInterval)
+}
+
+// GetRetentionDuration parses how long published outbox messages are kept
+func (c *OutboxConfig) GetRetentionDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Retention)
+}
+
+// GetRetentionDuration parses how long operations are kept, 0 when they are
+// kept forever
+func (c *OperationsConfig) GetRetentionDuration() (time.Duration, error) {
+	if c.Retention == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.Retention)
+}
+
+// GetMaintenanceIntervalDuration parses how often operation partitions are maintained
+func (c *OperationsConfig) GetMaintenanceIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.MaintenanceInterval)
+}
+
+// GetIntervalDuration parses how often usage is aggregated
+// GetIntervalDuration parses how often an audit sink looks for new events
+func (c *AuditSinkConfig) GetIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Interval)
+}
+
+// GetMaxBackoffDuration parses the longest retry wait of an audit sink
+func (c *AuditSinkConfig) GetMaxBackoffDuration() (time.Duration, error) {
+	return time.ParseDuration(c.MaxBackoff)
+}
+
+func (c *MeteringConfig) GetIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Interval)
+}
+
+// GetDigestIntervalDuration parses how often notification digests are sent
+func (c *NotificationsConfig) GetDigestIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.DigestInterval)
+}
+
+// GetDigestDelayDuration parses how long notifications wait before being emailed
+func (c *NotificationsConfig) GetDigestDelayDuration() (time.Duration, error) {
+	return time.ParseDuration(c.DigestDelay)
+}
+
+// GetRetryBackoffDuration parses the delay before the first email retry
+func (c *EmailConfig) GetRetryBackoffDuration() (time.Duration, error) {
+	return time.ParseDuration(c.RetryBackoff)
+}
+
+// GetMaxRetryBackoffDuration parses the longest delay between email retries
+func (c *EmailConfig) GetMaxRetryBackoffDuration() (time.Duration, error) {
+	return time.ParseDuration(c.MaxRetryBackoff)
+}
+
+// GetWindowDuration parses the window of the default rate limit
+func (c *RateLimitConfig) GetWindowDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Duration)
+}
+
+// GetWindowDuration parses the window of a route rate limit
+func (c *RouteRateLimitConfig) GetWindowDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Duration)
+}
+
+// GetFlushIntervalDuration parses how often analytics events are inserted
+func (c *ClickHouseConfig) GetFlushIntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.FlushInterval)
+}
+
+// GetTimeoutDuration parses antivirus scan timeout
+func (c *AntivirusConfig) GetTimeoutDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Timeout)
+}
+
+// GetTimeoutDuration parses how long text recognition of an image may take
+func (c *OCRConfig) GetTimeoutDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Timeout)
+}
+
+// GetMaxAgeDuration parses stream message retention duration
+func (c *JetStreamConfig) GetMaxAgeDuration() (time.Duration, error) {
+	return time.ParseDuration(c.MaxAge)
+}
+
+// GetConsumerName returns the durable consumer prefix of this instance. It
+// has to stay the same across restarts and differ between instances, so
+// the hostname is used unless one is configured.
+func (c *JetStreamConfig) GetConsumerName() (string, error) {
+	name := c.ConsumerName
+	if name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("failed to get hostname: %w", err)
+		}
+		name = hostname
+	}
+	// Consumer names can't contain subject tokens or wildcards
+	return strings.NewReplacer(".", "-", "*", "-", ">", "-", " ", "-").Replace(name), nil
+}
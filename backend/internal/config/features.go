@@ -0,0 +1,29 @@
+package config
+
+import "slices"
+
+// Features that can be switched off at runtime
+const (
+	FeatureAI           = "ai"
+	FeatureTranslation  = "translation"
+	FeatureEmbed        = "embed"
+	FeatureGraphQL      = "graphql"
+	FeatureIntegrations = "integrations"
+	FeatureAutomation   = "automation"
+)
+
+var knownFeatures = []string{
+	FeatureAI, FeatureTranslation, FeatureEmbed, FeatureGraphQL, FeatureIntegrations, FeatureAutomation,
+}
+
+// FeaturesConfig turns features off without a restart, e.g. when an AI
+// provider misbehaves. Features are on unless listed in disabled; ones that
+// need a section of their own, such as ai.provider, still need it.
+type FeaturesConfig struct {
+	Disabled []string `yaml:"disabled"`
+}
+
+// Enabled reports whether a feature isn't disabled
+func (c *FeaturesConfig) Enabled(feature string) bool {
+	return !slices.Contains(c.Disabled, feature)
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+// ReloadConfig watches the config file for changes of the settings that
+// can be applied without a restart
+type ReloadConfig struct {
+	Interval string `yaml:"interval"` // how often the file is checked, 10s when empty
+	Watch    bool   `yaml:"watch"`
+}
+
+// ReloadResult lists the settings a reload changed
+type ReloadResult struct {
+	Applied         []string `json:"applied"`          // reloadable settings that were applied
+	RestartRequired []string `json:"restart_required"` // changed sections that only apply after a restart
+}
+
+// Reloader holds the current configuration and applies the reloadable
+// settings of a changed config file: rate limits, CORS, the log level, the
+// auth policies and the feature flags. Other changes are reported but need a
+// restart.
+// Consumers of reloadable settings read them from Current on every use.
+type Reloader struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex // serializes reloads
+	hash     [sha256.Size]byte
+	onReload []func(cfg *Config)
+}
+
+// NewReloader creates a reloader of the config loaded from path
+func NewReloader(path string, cfg *Config) *Reloader {
+	r := &Reloader{path: path}
+	r.current.Store(cfg)
+	if data, err := os.ReadFile(path); err == nil {
+		r.hash = sha256.Sum256(data)
+	}
+	return r
+}
+
+// Current returns the current configuration. It must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers a function called with the new configuration after
+// reloadable settings changed. It must be called before Watch.
+func (r *Reloader) OnReload(fn func(cfg *Config)) {
+	r.mu.Lock()
+	r.onReload = append(r.onReload, fn)
+	r.mu.Unlock()
+}
+
+// Reload reads the config file again and applies its reloadable settings.
+// An invalid file is rejected as a whole and the current settings kept.
+func (r *Reloader) Reload() (*ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	loaded, err := Load(r.path)
+	if err != nil {
+		return nil, err
+	}
+	r.hash = sha256.Sum256(data)
+
+	current := r.current.Load()
+	next := *current
+	result := &ReloadResult{
+		Applied:         applyReloadable(&next, loaded),
+		RestartRequired: changedSections(&next, loaded),
+	}
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	r.current.Store(&next)
+	for _, fn := range r.onReload {
+		fn(&next)
+	}
+	return result, nil
+}
+
+// Watch reloads the configuration when the config file changes, until ctx
+// is done. It does nothing unless reload.watch is set.
+func (r *Reloader) Watch(ctx context.Context) {
+	cfg := r.Current().Reload
+	if !cfg.Watch {
+		return
+	}
+	interval := defaultReloadInterval
+	if cfg.Interval != "" {
+		if parsed, err := time.ParseDuration(cfg.Interval); err == nil && parsed > 0 {
+			interval = parsed
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			result, err := r.Reload()
+			if err != nil {
+				hlog.CtxErrorf(ctx, "Config file changed but wasn't reloaded: %v", err)
+				continue
+			}
+			LogReload(ctx, result)
+		}
+	}
+}
+
+// changed reports whether the config file differs from the one last loaded
+func (r *Reloader) changed() bool {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return false
+	}
+	hash := sha256.Sum256(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return hash != r.hash
+}
+
+// LogReload logs the outcome of a reload
+func LogReload(ctx context.Context, result *ReloadResult) {
+	if len(result.Applied) > 0 {
+		hlog.CtxInfof(ctx, "Reloaded config settings: %v", result.Applied)
+	}
+	if len(result.RestartRequired) > 0 {
+		hlog.CtxWarnf(ctx, "Config changes of %v need a restart to apply", result.RestartRequired)
+	}
+}
+
+// applyReloadable copies the reloadable settings from src to dst and
+// returns the names of those that changed
+func applyReloadable(dst, src *Config) []string {
+	applied := []string{}
+	if !sameSetting(dst.RateLimit, src.RateLimit) {
+		dst.RateLimit = src.RateLimit
+		applied = append(applied, "rate_limit")
+	}
+	if !sameSetting(dst.CORS, src.CORS) {
+		dst.CORS = src.CORS
+		applied = append(applied, "cors")
+	}
+	if dst.Logging.Level != src.Logging.Level {
+		dst.Logging.Level = src.Logging.Level
+		applied = append(applied, "logging.level")
+	}
+	if !sameSetting(dst.Auth, src.Auth) {
+		dst.Auth = src.Auth
+		applied = append(applied, "auth")
+	}
+	if !sameSetting(dst.Features, src.Features) {
+		dst.Features = src.Features
+		applied = append(applied, "features")
+	}
+	return applied
+}
+
+// changedSections returns the sections of the config that differ between a
+// and b, by their YAML names
+func changedSections(a, b *Config) []string {
+	changed := []string{}
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+		name := field.Tag.Get("yaml")
+		if name == "" {
+			continue
+		}
+		if !sameSetting(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// sameSetting compares settings by their YAML form, which leaves out the
+// runtime state of the config sections
+func sameSetting(a, b interface{}) bool {
+	encodedA, errA := yaml.Marshal(a)
+	encodedB, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
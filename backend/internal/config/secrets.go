@@ -0,0 +1,448 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/awssig"
+)
+
+const (
+	vaultSecretPrefix = "vault:"
+	awsSecretPrefix   = "aws-sm:"
+
+	defaultSecretsRefreshInterval = 5 * time.Minute
+	defaultVaultKVMount           = "secret"
+	secretsResolveTimeout         = 30 * time.Second
+	secretsRequestTimeout         = 10 * time.Second
+	secretsMaxErrorBody           = 512
+
+	awsSecretsService   = "secretsmanager"
+	awsSecretsURLFormat = "https://secretsmanager.%s.amazonaws.com/"
+	awsSecretsTarget    = "secretsmanager.GetSecretValue"
+)
+
+// Names of the settings that may hold a secret reference
+const (
+	SecretJWT                = "jwt.secret"
+	SecretDatabasePassword   = "database.password"
+	SecretGoogleClientSecret = "oauth.google.client_secret"
+	SecretGitHubClientSecret = "oauth.github.client_secret"
+	SecretSMTPUser           = "email.smtp_user"
+	SecretSMTPPassword       = "email.smtp_password"
+)
+
+// SecretsConfig configures the secret stores that settings can reference
+// instead of holding the secret. A reference is vault:<path>#<field> for a
+// field of a Vault KV v2 secret, or aws-sm:<secret id>[#<key>] for an AWS
+// Secrets Manager secret, optionally a key of its JSON value.
+type SecretsConfig struct {
+	Vault VaultSecretsConfig `yaml:"vault"`
+	AWS   AWSSecretsConfig   `yaml:"aws"`
+	// RefreshInterval is how often references are resolved again to pick up
+	// rotated secrets, 5m when empty and "0" to resolve them at startup only
+	RefreshInterval string `yaml:"refresh_interval"`
+}
+
+type VaultSecretsConfig struct {
+	Address   string `yaml:"address"`   // e.g. https://vault.internal:8200
+	Token     string `yaml:"token"`     // token allowed to read the referenced paths
+	Namespace string `yaml:"namespace"` // Vault Enterprise namespace, empty for none
+	Mount     string `yaml:"mount"`     // KV v2 mount, "secret" when empty
+}
+
+// AWSSecretsConfig holds the credentials of Secrets Manager. Empty keys
+// fall back to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+type AWSSecretsConfig struct {
+	Region       string `yaml:"region"`
+	AccessKey    string `yaml:"access_key"`
+	SecretKey    string `yaml:"secret_key"`
+	SessionToken string `yaml:"session_token"`
+	Endpoint     string `yaml:"endpoint"` // overrides the regional endpoint, e.g. for a VPC endpoint
+}
+
+// SecretManager holds the settings resolved from secret references and
+// resolves them again every refresh interval. Rotated values are returned
+// by the Current methods of the config sections, existing fields keep the
+// value resolved at startup.
+type SecretManager struct {
+	cfg        SecretsConfig
+	httpClient *http.Client
+	interval   time.Duration
+
+	mu        sync.RWMutex
+	refs      map[string]*secretRef
+	documents map[string]secretDocument
+}
+
+type secretRef struct {
+	ref      string
+	value    string
+	previous string
+}
+
+// secretDocument is a fetched Vault secret or Secrets Manager value. The
+// fields of a document are shared by the references to it, so each is
+// fetched once per refresh.
+type secretDocument struct {
+	expiresAt time.Time
+	raw       string
+	fields    map[string]string
+}
+
+// IsSecretRef reports whether a setting is a secret reference
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, vaultSecretPrefix) || strings.HasPrefix(value, awsSecretPrefix)
+}
+
+// resolveSecrets replaces the secret references of cfg by their values.
+// The manager is kept on cfg when there were any, nil otherwise.
+func (c *Config) resolveSecrets() error {
+	fields := c.secretFields()
+
+	refs := make(map[string]*secretRef)
+	for name, field := range fields {
+		if IsSecretRef(*field) {
+			refs[name] = &secretRef{ref: *field}
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	interval := defaultSecretsRefreshInterval
+	if c.Secrets.RefreshInterval != "" {
+		parsed, err := time.ParseDuration(c.Secrets.RefreshInterval)
+		if err != nil {
+			return fmt.Errorf("invalid secrets.refresh_interval: %w", err)
+		}
+		interval = parsed
+	}
+
+	manager := &SecretManager{
+		cfg:        c.Secrets,
+		httpClient: &http.Client{Timeout: secretsRequestTimeout},
+		interval:   interval,
+		refs:       refs,
+		documents:  make(map[string]secretDocument),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretsResolveTimeout)
+	defer cancel()
+
+	for name, ref := range refs {
+		value, err := manager.resolve(ctx, ref.ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		ref.value = value
+		*fields[name] = value
+	}
+
+	c.secrets = manager
+	c.Database.secrets = manager
+	c.JWT.secrets = manager
+	c.Email.secrets = manager
+	c.OAuth.Google.secrets, c.OAuth.Google.secretName = manager, SecretGoogleClientSecret
+	c.OAuth.GitHub.secrets, c.OAuth.GitHub.secretName = manager, SecretGitHubClientSecret
+	return nil
+}
+
+// secretFields returns the settings that may hold a secret reference by name
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		SecretJWT:                &c.JWT.Secret,
+		SecretDatabasePassword:   &c.Database.Password,
+		SecretGoogleClientSecret: &c.OAuth.Google.ClientSecret,
+		SecretGitHubClientSecret: &c.OAuth.GitHub.ClientSecret,
+		SecretSMTPUser:           &c.Email.SMTPUser,
+		SecretSMTPPassword:       &c.Email.SMTPPassword,
+	}
+}
+
+// SecretManager returns the manager of the resolved secret references, nil
+// when the config has none
+func (c *Config) SecretManager() *SecretManager {
+	return c.secrets
+}
+
+// Get returns the current value of a setting resolved from a reference,
+// fallback when the setting isn't one
+func (m *SecretManager) Get(name, fallback string) string {
+	if m == nil {
+		return fallback
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ref, ok := m.refs[name]; ok {
+		return ref.value
+	}
+	return fallback
+}
+
+// Previous returns the value a setting had before its last rotation, empty
+// when it wasn't rotated
+func (m *SecretManager) Previous(name string) string {
+	if m == nil {
+		return ""
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ref, ok := m.refs[name]; ok {
+		return ref.previous
+	}
+	return ""
+}
+
+// Run resolves the references again every refresh interval until ctx is
+// done. Failures are logged and the last values kept.
+func (m *SecretManager) Run(ctx context.Context) {
+	if m == nil || m.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.refresh(ctx)
+		}
+	}
+}
+
+// refresh resolves every reference and keeps the replaced value of the
+// rotated ones
+func (m *SecretManager) refresh(ctx context.Context) {
+	m.mu.RLock()
+	refs := make(map[string]string, len(m.refs))
+	for name, ref := range m.refs {
+		refs[name] = ref.ref
+	}
+	m.mu.RUnlock()
+
+	for name, ref := range refs {
+		value, err := m.resolve(ctx, ref)
+		if err != nil {
+			hlog.CtxWarnf(ctx, "Failed to refresh secret %s, keeping its last value: %v", name, err)
+			continue
+		}
+
+		m.mu.Lock()
+		current := m.refs[name]
+		changed := current.value != value
+		if changed {
+			current.previous, current.value = current.value, value
+		}
+		m.mu.Unlock()
+
+		if changed {
+			hlog.CtxInfof(ctx, "Secret %s was rotated", name)
+		}
+	}
+}
+
+// resolve returns the value a reference points to
+func (m *SecretManager) resolve(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, vaultSecretPrefix):
+		path, field, ok := strings.Cut(strings.TrimPrefix(ref, vaultSecretPrefix), "#")
+		if !ok || path == "" || field == "" {
+			return "", fmt.Errorf("vault references must look like vault:<path>#<field>")
+		}
+		doc, err := m.document(ctx, ref[:len(vaultSecretPrefix)+len(path)], func() (secretDocument, error) {
+			return m.fetchVault(ctx, path)
+		})
+		if err != nil {
+			return "", err
+		}
+		value, ok := doc.fields[field]
+		if !ok {
+			return "", fmt.Errorf("vault secret %s has no string field %q", path, field)
+		}
+		return value, nil
+
+	case strings.HasPrefix(ref, awsSecretPrefix):
+		secretID, key, hasKey := strings.Cut(strings.TrimPrefix(ref, awsSecretPrefix), "#")
+		if secretID == "" || (hasKey && key == "") {
+			return "", fmt.Errorf("aws secrets manager references must look like aws-sm:<secret id>[#<key>]")
+		}
+		doc, err := m.document(ctx, ref[:len(awsSecretPrefix)+len(secretID)], func() (secretDocument, error) {
+			return m.fetchAWS(ctx, secretID)
+		})
+		if err != nil {
+			return "", err
+		}
+		if !hasKey {
+			return doc.raw, nil
+		}
+		value, ok := doc.fields[key]
+		if !ok {
+			return "", fmt.Errorf("aws secret %s has no string key %q", secretID, key)
+		}
+		return value, nil
+	}
+
+	return "", fmt.Errorf("unsupported secret reference")
+}
+
+// document returns a cached document, fetching it when it's missing or
+// older than the refresh interval
+func (m *SecretManager) document(ctx context.Context, key string, fetch func() (secretDocument, error)) (secretDocument, error) {
+	now := time.Now()
+
+	m.mu.RLock()
+	doc, ok := m.documents[key]
+	m.mu.RUnlock()
+	if ok && now.Before(doc.expiresAt) {
+		return doc, nil
+	}
+
+	doc, err := fetch()
+	if err != nil {
+		return secretDocument{}, err
+	}
+	// Expire a little early so the next refresh fetches the document again
+	doc.expiresAt = now.Add(m.interval / 2)
+
+	m.mu.Lock()
+	m.documents[key] = doc
+	m.mu.Unlock()
+
+	return doc, nil
+}
+
+// fetchVault reads a KV v2 secret
+func (m *SecretManager) fetchVault(ctx context.Context, path string) (secretDocument, error) {
+	cfg := m.cfg.Vault
+	if cfg.Address == "" || cfg.Token == "" {
+		return secretDocument{}, fmt.Errorf("secrets.vault.address and secrets.vault.token are required")
+	}
+	mount := strings.Trim(cfg.Mount, "/")
+	if mount == "" {
+		mount = defaultVaultKVMount
+	}
+
+	endpoint := strings.TrimSuffix(cfg.Address, "/") + "/v1/" + mount + "/data/" + strings.Trim(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return secretDocument{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", cfg.Token)
+	if cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", cfg.Namespace)
+	}
+
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := m.call(req, &resp); err != nil {
+		return secretDocument{}, fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+
+	return secretDocument{fields: stringFields(resp.Data.Data)}, nil
+}
+
+// fetchAWS reads the current value of a Secrets Manager secret
+func (m *SecretManager) fetchAWS(ctx context.Context, secretID string) (secretDocument, error) {
+	cfg := m.cfg.AWS
+	creds := awssig.Credentials{
+		AccessKey:    firstNonEmpty(cfg.AccessKey, os.Getenv("AWS_ACCESS_KEY_ID")),
+		SecretKey:    firstNonEmpty(cfg.SecretKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		SessionToken: firstNonEmpty(cfg.SessionToken, os.Getenv("AWS_SESSION_TOKEN")),
+	}
+	region := firstNonEmpty(cfg.Region, os.Getenv("AWS_REGION"))
+	if region == "" || creds.AccessKey == "" || creds.SecretKey == "" {
+		return secretDocument{}, fmt.Errorf("secrets.aws requires a region, access_key and secret_key")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(awsSecretsURLFormat, url.PathEscape(region))
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return secretDocument{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return secretDocument{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", awsSecretsTarget)
+	awssig.SignRequest(req, body, creds, region, awsSecretsService, time.Now())
+
+	var resp struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := m.call(req, &resp); err != nil {
+		return secretDocument{}, fmt.Errorf("failed to read aws secret %s: %w", secretID, err)
+	}
+	if resp.SecretString == nil {
+		return secretDocument{}, fmt.Errorf("aws secret %s has no string value", secretID)
+	}
+
+	doc := secretDocument{raw: *resp.SecretString}
+	var fields map[string]interface{}
+	if json.Unmarshal([]byte(doc.raw), &fields) == nil {
+		doc.fields = stringFields(fields)
+	}
+	return doc, nil
+}
+
+// call sends a request and decodes the JSON response into out
+func (m *SecretManager) call(req *http.Request, out interface{}) error {
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, secretsMaxErrorBody))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// stringFields keeps the string values of a secret
+func stringFields(values map[string]interface{}) map[string]string {
+	fields := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			fields[key] = s
+		}
+	}
+	return fields
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	minJWTSecretLength = 32
+	encryptionKeySize  = 32 // AES-256
+	maxPort            = 65535
+	defaultBodySize    = 10 << 20 // 10MB
+
+	// sampleJWTSecret is the secret of configs/config.yaml, refused in
+	// production
+	sampleJWTSecret = "your-super-secret-jwt-key-change-this-in-production"
+)
+
+// ValidationError lists every problem found in a configuration, so they can
+// be fixed in one go
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate applies the defaults of settings left empty and checks the
+// required ones. It returns a *ValidationError naming each invalid setting
+// and how to fix it.
+func (c *Config) Validate() error {
+	c.applyDefaults()
+
+	v := &validator{}
+	c.validateApp(v)
+	c.validateDatabase(v)
+	c.validateRedis(v)
+	c.validateJWT(v)
+	c.validateStorage(v)
+	c.validateWebSocket(v)
+	c.validateEncryption(v)
+	c.validateRateLimit(v)
+	c.validateAI(v)
+	c.validateOCR(v)
+	c.validateTranslation(v)
+	c.validateFeatures(v)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// applyDefaults fills in settings whose zero value can't work
+func (c *Config) applyDefaults() {
+	setDefault(&c.App.Name, "HertzBoard")
+	setDefault(&c.App.Env, "development")
+	setDefault(&c.App.Port, 8080)
+	setDefault(&c.App.MaxBodySize, defaultBodySize)
+
+	setDefault(&c.Database.Port, 5432)
+	setDefault(&c.Database.SSLMode, "prefer")
+
+	setDefault(&c.Redis.Port, 6379)
+
+	setDefault(&c.NATS.URL, "nats://localhost:4222")
+
+	setDefault(&c.JWT.AccessTokenExpiry, "15m")
+	setDefault(&c.JWT.RefreshTokenExpiry, "168h")
+
+	setDefault(&c.MinIO.URLExpiry, "1h")
+
+	setDefault(&c.Storage.Provider, "minio")
+	setDefault(&c.Upload.MaxSize, defaultBodySize)
+
+	setDefault(&c.WebSocket.Port, 8081)
+	setDefault(&c.WebSocket.Transport, "redis")
+
+	setDefault(&c.AI.MaxElements, 200)
+	setDefault(&c.Translation.MaxElements, 100)
+
+	setDefault(&c.Upload.OCR.Command, "tesseract")
+	setDefault(&c.Upload.OCR.Timeout, "1m")
+}
+
+func setDefault[T comparable](field *T, value T) {
+	var zero T
+	if *field == zero {
+		*field = value
+	}
+}
+
+func (c *Config) validateApp(v *validator) {
+	v.port("app.port", c.App.Port)
+	if c.App.MaxBodySize < 0 {
+		v.add("app.max_body_size must be a positive number of bytes, got %d", c.App.MaxBodySize)
+	}
+	if _, err := c.App.GetTrustedProxies(); err != nil {
+		v.add("app.trusted_proxies must list CIDRs or addresses such as 10.0.0.0/8: %v", err)
+	}
+}
+
+func (c *Config) validateDatabase(v *validator) {
+	v.required("database.host", c.Database.Host, "the address of the PostgreSQL server, e.g. localhost")
+	v.required("database.name", c.Database.Name, "the database to connect to, e.g. hertzboard")
+	v.required("database.user", c.Database.User, "the role to connect as")
+	v.port("database.port", c.Database.Port)
+	if c.Database.ReplicaHost != "" && c.Database.ReplicaPort != 0 {
+		v.port("database.replica_port", c.Database.ReplicaPort)
+	}
+	v.oneOf("database.ssl_mode", c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+}
+
+func (c *Config) validateRedis(v *validator) {
+	v.required("redis.host", c.Redis.Host, "the address of the Redis server, e.g. localhost")
+	v.port("redis.port", c.Redis.Port)
+}
+
+func (c *Config) validateJWT(v *validator) {
+	switch {
+	case c.JWT.Secret == "":
+		v.add("jwt.secret is required, generate one with `openssl rand -base64 48`")
+	case len(c.JWT.Secret) < minJWTSecretLength:
+		v.add("jwt.secret must be at least %d characters long, it has %d; generate one with `openssl rand -base64 48`",
+			minJWTSecretLength, len(c.JWT.Secret))
+	case c.JWT.Secret == sampleJWTSecret && c.App.Env == "production":
+		v.add("jwt.secret is still the sample secret, set a random one with `openssl rand -base64 48`")
+	}
+
+	access := v.duration("jwt.access_token_expiry", c.JWT.AccessTokenExpiry)
+	refresh := v.duration("jwt.refresh_token_expiry", c.JWT.RefreshTokenExpiry)
+	if access > 0 && refresh > 0 && refresh <= access {
+		v.add("jwt.refresh_token_expiry (%s) must be longer than jwt.access_token_expiry (%s)",
+			c.JWT.RefreshTokenExpiry, c.JWT.AccessTokenExpiry)
+	}
+}
+
+func (c *Config) validateStorage(v *validator) {
+	switch c.Storage.Provider {
+	case "minio":
+		v.required("minio.endpoint", c.MinIO.Endpoint, "the host:port of the MinIO server, e.g. localhost:9000")
+		v.required("minio.access_key", c.MinIO.AccessKey, "the MinIO access key (MINIO_ROOT_USER of a development server)")
+		v.required("minio.secret_key", c.MinIO.SecretKey, "the MinIO secret key (MINIO_ROOT_PASSWORD of a development server)")
+		v.duration("minio.url_expiry", c.MinIO.URLExpiry)
+	case "s3", "gcs":
+		v.required("storage.access_key", c.Storage.AccessKey, "the access key, or the HMAC key for gcs")
+		v.required("storage.secret_key", c.Storage.SecretKey, "the secret key, or the HMAC secret for gcs")
+		if c.Storage.Provider == "s3" {
+			v.required("storage.region", c.Storage.Region, "the region of the bucket, e.g. eu-central-1")
+		}
+	case "filesystem":
+		v.required("storage.path", c.Storage.Path, "the directory assets are stored in, e.g. ./data/storage")
+		v.required("storage.signing_key", c.Storage.SigningKey, "a random key for presigned links, e.g. from `openssl rand -hex 32`")
+	default:
+		v.oneOf("storage.provider", c.Storage.Provider, "minio", "s3", "gcs", "filesystem")
+	}
+
+	if c.Upload.MaxSize < 0 {
+		v.add("upload.max_size must be a positive number of bytes, got %d", c.Upload.MaxSize)
+	}
+}
+
+func (c *Config) validateWebSocket(v *validator) {
+	v.port("websocket.port", c.WebSocket.Port)
+	v.oneOf("websocket.transport", c.WebSocket.Transport, "redis", "jetstream")
+}
+
+func (c *Config) validateEncryption(v *validator) {
+	if !c.Encryption.Enabled {
+		return
+	}
+
+	switch c.Encryption.Provider {
+	case "", "local":
+		v.required("encryption.local.key_id", c.Encryption.Local.KeyID, "a name of the master key, e.g. local-1")
+		key, err := base64.StdEncoding.DecodeString(c.Encryption.Local.Key)
+		if c.Encryption.Local.Key == "" || err != nil || len(key) != encryptionKeySize {
+			v.add("encryption.local.key must be 32 random bytes in base64, generate one with `openssl rand -base64 32`")
+		}
+	case "vault":
+		v.required("encryption.vault.address", c.Encryption.Vault.Address, "the URL of Vault, e.g. https://vault:8200")
+		v.required("encryption.vault.token", c.Encryption.Vault.Token, "a token allowed to encrypt and decrypt with the key")
+		v.required("encryption.vault.key_name", c.Encryption.Vault.KeyName, "the name of the transit key")
+	default:
+		v.oneOf("encryption.provider", c.Encryption.Provider, "local", "vault")
+	}
+}
+
+func (c *Config) validateAI(v *validator) {
+	switch c.AI.Provider {
+	case "":
+	case "openai":
+		if c.AI.BaseURL == "" {
+			v.required("ai.api_key", c.AI.APIKey, "the OpenAI API key, or set ai.base_url to a compatible endpoint")
+		}
+	case "anthropic":
+		v.required("ai.api_key", c.AI.APIKey, "the Anthropic API key")
+	default:
+		v.add("ai.provider must be openai or anthropic, or empty to disable AI assistance, got %q", c.AI.Provider)
+	}
+	if c.AI.MaxElements < 0 {
+		v.add("ai.max_elements must be a positive number, got %d", c.AI.MaxElements)
+	}
+}
+
+func (c *Config) validateTranslation(v *validator) {
+	switch c.Translation.Provider {
+	case "":
+	case "deepl":
+		v.required("translation.api_key", c.Translation.APIKey, "the DeepL authentication key")
+	case "google":
+		v.required("translation.api_key", c.Translation.APIKey, "a Google Cloud API key with the Translation API enabled")
+	case "ai":
+		if c.AI.Provider == "" {
+			v.add("translation.provider ai translates with the language model of ai.provider, set it or use deepl or google")
+		}
+	default:
+		v.add("translation.provider must be deepl, google or ai, or empty to disable translation, got %q",
+			c.Translation.Provider)
+	}
+	if c.Translation.MaxElements < 0 {
+		v.add("translation.max_elements must be a positive number, got %d", c.Translation.MaxElements)
+	}
+}
+
+func (c *Config) validateFeatures(v *validator) {
+	for _, feature := range c.Features.Disabled {
+		v.oneOf("features.disabled", feature, knownFeatures...)
+	}
+}
+
+func (c *Config) validateOCR(v *validator) {
+	switch c.Upload.OCR.Provider {
+	case "":
+		return
+	case "tesseract":
+	case "google":
+		v.required("upload.ocr.api_key", c.Upload.OCR.APIKey, "a Google Cloud API key with the Vision API enabled")
+	default:
+		v.add("upload.ocr.provider must be tesseract or google, or empty to disable OCR, got %q", c.Upload.OCR.Provider)
+	}
+	v.duration("upload.ocr.timeout", c.Upload.OCR.Timeout)
+}
+
+func (c *Config) validateRateLimit(v *validator) {
+	if !c.RateLimit.Enabled {
+		return
+	}
+	v.duration("rate_limit.duration", c.RateLimit.Duration)
+	for i := range c.RateLimit.Routes {
+		v.duration(fmt.Sprintf("rate_limit.routes[%d].duration", i), c.RateLimit.Routes[i].Duration)
+	}
+}
+
+// validator collects the problems of a configuration
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(name, value, hint string) {
+	if strings.TrimSpace(value) == "" {
+		v.add("%s is required, set it to %s", name, hint)
+	}
+}
+
+func (v *validator) port(name string, value int) {
+	if value < 1 || value > maxPort {
+		v.add("%s must be a port between 1 and %d, got %d", name, maxPort, value)
+	}
+}
+
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	for _, option := range allowed {
+		if value == option {
+			return
+		}
+	}
+	v.add("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value)
+}
+
+// duration checks a positive duration and returns it, 0 when it's invalid
+func (v *validator) duration(name, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		v.add("%s must be a positive duration such as 15m or 24h, got %q", name, value)
+		return 0
+	}
+	return d
+}
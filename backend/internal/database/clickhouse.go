@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	clickHouseTimeout = 30 * time.Second
+	// clickHouseErrorLimit caps the error text read from failed responses
+	clickHouseErrorLimit = 4096
+)
+
+// ClickHouse is a client of the ClickHouse HTTP interface
+type ClickHouse struct {
+	client   *http.Client
+	url      string
+	database string
+	user     string
+	password string
+}
+
+// NewClickHouse creates a ClickHouse client. It doesn't connect, use Ping to
+// check the server.
+func NewClickHouse(cfg *config.ClickHouseConfig) *ClickHouse {
+	return &ClickHouse{
+		client: &http.Client{
+			Timeout:   clickHouseTimeout,
+			Transport: tracing.NewTransport(http.DefaultTransport),
+		},
+		url:      "http://" + cfg.Host + ":" + strconv.Itoa(cfg.Port) + "/",
+		database: cfg.Database,
+		user:     cfg.User,
+		password: cfg.Password,
+	}
+}
+
+// Ping checks that the server answers
+func (c *ClickHouse) Ping(ctx context.Context) error {
+	return c.Exec(ctx, "SELECT 1")
+}
+
+// Exec runs a statement and discards its result
+func (c *ClickHouse) Exec(ctx context.Context, query string) error {
+	return c.exec(ctx, query, nil)
+}
+
+// Insert writes rows into table. rows holds one JSON object per line, fields
+// unknown to the table are skipped.
+func (c *ClickHouse) Insert(ctx context.Context, table string, rows io.Reader) error {
+	return c.exec(ctx, "INSERT INTO "+table+" FORMAT JSONEachRow", rows)
+}
+
+func (c *ClickHouse) exec(ctx context.Context, query string, body io.Reader) error {
+	resp, err := c.do(ctx, query, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Query runs a SELECT and returns its rows, one JSON object per line. The
+// query refers to params as {name:Type}. The caller closes the reader.
+func (c *ClickHouse) Query(ctx context.Context, query string, params map[string]string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, query+" FORMAT JSONEachRow", nil, params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// MigrateClickHouse runs the statements of every migration file. There is no
+// record of applied migrations, statements must be idempotent, e.g. CREATE
+// TABLE IF NOT EXISTS.
+func MigrateClickHouse(ctx context.Context, ch *ClickHouse, migrationsPath string) error {
+	migrations, err := readMigrationFiles(migrationsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read migration files: %w", err)
+	}
+
+	for _, migration := range migrations {
+		// The HTTP interface runs a single statement per request
+		for _, statement := range strings.Split(migration.SQL, ";") {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			if err := ch.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("failed to execute migration %s: %w", migration.Name, err)
+			}
+		}
+		hlog.CtxDebugf(ctx, "ClickHouse migration applied: %s", migration.Name)
+	}
+
+	return nil
+}
+
+func (c *ClickHouse) do(ctx context.Context, query string, body io.Reader, params map[string]string) (*http.Response, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("database", c.database)
+	values.Set("date_time_input_format", "best_effort")
+	values.Set("input_format_skip_unknown_fields", "1")
+	values.Set("output_format_json_quote_64bit_integers", "0")
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"?"+values.Encode(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-ClickHouse-User", c.user)
+	req.Header.Set("X-ClickHouse-Key", c.password)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach clickhouse: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, clickHouseErrorLimit))
+		return nil, fmt.Errorf("clickhouse returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return resp, nil
+}
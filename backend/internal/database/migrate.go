@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -38,7 +42,7 @@ func Migrate(pool *pgxpool.Pool, migrationsPath string) error {
 			continue
 		}
 
-		fmt.Printf("Applying migration: %s\n", migration.Name)
+		hlog.CtxInfof(ctx, "Applying migration: %s", migration.Name)
 
 		// Start transaction
 		tx, err := pool.Begin(ctx)
@@ -64,16 +68,221 @@ func Migrate(pool *pgxpool.Pool, migrationsPath string) error {
 			return fmt.Errorf("failed to commit migration %s: %w", migration.Name, err)
 		}
 
-		fmt.Printf("Migration applied: %s\n", migration.Name)
+		hlog.CtxInfof(ctx, "Migration applied: %s", migration.Name)
 	}
 
 	return nil
 }
 
+const (
+	// downSuffix marks the file that reverts a migration, e.g.
+	// 028_create_foo.down.sql reverts 028_create_foo.sql
+	downSuffix        = ".down.sql"
+	migrationFileMode = 0o644
+)
+
+// migrationNameCleaner matches the runs of characters replaced by an
+// underscore in the names of new migrations
+var migrationNameCleaner = regexp.MustCompile(`[^a-z0-9]+`)
+
 type Migration struct {
 	Version int
 	Name    string
 	SQL     string
+	DownSQL string // empty when the migration has no down file
+}
+
+// MigrationState is a migration file and when it was applied
+type MigrationState struct {
+	AppliedAt *time.Time
+	Name      string
+	Version   int
+	Missing   bool // applied but its file no longer exists
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first. Each
+// one needs a down file, which all migrations from 010 on have, so rollback
+// stops at the base schema of 001-009.
+func MigrateDown(pool *pgxpool.Pool, migrationsPath string, steps int) error {
+	ctx := context.Background()
+
+	if err := createMigrationsTable(ctx, pool); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := readMigrationFiles(migrationsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read migration files: %w", err)
+	}
+	byName := make(map[string]Migration, len(migrations))
+	for _, migration := range migrations {
+		byName[migration.Name] = migration
+	}
+
+	rows, err := pool.Query(ctx, "SELECT name FROM schema_migrations ORDER BY version DESC, id DESC LIMIT $1", steps)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	for _, name := range names {
+		migration, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("migration %s is applied but its file is missing", name)
+		}
+		if migration.DownSQL == "" {
+			return fmt.Errorf("migration %s has no %s file", name, downSuffix)
+		}
+
+		hlog.CtxInfof(ctx, "Reverting migration: %s", name)
+
+		err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, migration.DownSQL); err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", name, err)
+			}
+			if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE name = $1", name); err != nil {
+				return fmt.Errorf("failed to unrecord migration %s: %w", name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		hlog.CtxInfof(ctx, "Migration reverted: %s", name)
+	}
+
+	return nil
+}
+
+// GetMigrationStatus lists the migration files and the applied migrations
+// whose file is missing, by version
+func GetMigrationStatus(pool *pgxpool.Pool, migrationsPath string) ([]MigrationState, error) {
+	ctx := context.Background()
+
+	if err := createMigrationsTable(ctx, pool); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := readMigrationFiles(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration files: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]MigrationState)
+	for rows.Next() {
+		var state MigrationState
+		var appliedAt time.Time
+		if err := rows.Scan(&state.Version, &state.Name, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		state.AppliedAt = &appliedAt
+		applied[state.Name] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Name]; ok {
+			state.AppliedAt = record.AppliedAt
+			delete(applied, migration.Name)
+		}
+		states = append(states, state)
+	}
+	for _, record := range applied {
+		record.Missing = true
+		states = append(states, record)
+	}
+
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Version < states[j].Version
+	})
+	return states, nil
+}
+
+// ForceMigrationVersion records the migrations up to version as applied and
+// the later ones as not applied, without running any SQL. It repairs the
+// table after a migration was applied or reverted by hand.
+func ForceMigrationVersion(pool *pgxpool.Pool, migrationsPath string, version int) error {
+	ctx := context.Background()
+
+	if err := createMigrationsTable(ctx, pool); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := readMigrationFiles(migrationsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read migration files: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version > $1", version); err != nil {
+			return fmt.Errorf("failed to unrecord migrations: %w", err)
+		}
+
+		query := `
+			INSERT INTO schema_migrations (version, name)
+			SELECT $1, $2
+			WHERE NOT EXISTS (SELECT 1 FROM schema_migrations WHERE name = $2)
+		`
+		for _, migration := range migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, err := tx.Exec(ctx, query, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateMigration writes empty up and down files for the next version and
+// returns their paths. name is turned into snake case.
+func CreateMigration(migrationsPath, name string) ([]string, error) {
+	slug := strings.Trim(migrationNameCleaner.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return nil, fmt.Errorf("invalid migration name %q", name)
+	}
+
+	migrations, err := readMigrationFiles(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration files: %w", err)
+	}
+	version := 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := filepath.Join(migrationsPath, fmt.Sprintf("%03d_%s", version, slug))
+	files := []struct {
+		path    string
+		content string
+	}{
+		{base + ".sql", fmt.Sprintf("-- Migration: %s\n\n", name)},
+		{base + downSuffix, fmt.Sprintf("-- Revert migration: %s\n\n", name)},
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := os.WriteFile(file.path, []byte(file.content), migrationFileMode); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.path, err)
+		}
+		paths = append(paths, file.path)
+	}
+	return paths, nil
 }
 
 func createMigrationsTable(ctx context.Context, pool *pgxpool.Pool) error {
@@ -121,7 +330,7 @@ func readMigrationFiles(path string) ([]Migration, error) {
 	}
 
 	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".sql") {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".sql") || strings.HasSuffix(file.Name(), downSuffix) {
 			continue
 		}
 
@@ -138,11 +347,21 @@ func readMigrationFiles(path string) ([]Migration, error) {
 			return nil, err
 		}
 
-		migrations = append(migrations, Migration{
+		migration := Migration{
 			Version: version,
 			Name:    strings.TrimSuffix(file.Name(), ".sql"),
 			SQL:     string(content),
-		})
+		}
+
+		downContent, err := os.ReadFile(filepath.Join(path, migration.Name+downSuffix))
+		switch {
+		case err == nil:
+			migration.DownSQL = string(downContent)
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+
+		migrations = append(migrations, migration)
 	}
 
 	// Sort by version
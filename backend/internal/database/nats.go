@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/nats-io/nats.go"
 
 	"github.com/bifshteksex/hertz-board/internal/config"
@@ -16,14 +17,14 @@ func NewNATSConnection(cfg *config.NATSConfig) (*nats.Conn, error) {
 		nats.ReconnectWait(time.Duration(cfg.ReconnectWait) * time.Second),
 		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
 			if err != nil {
-				fmt.Printf("NATS disconnected: %v\n", err)
+				hlog.Warnf("NATS disconnected: %v", err)
 			}
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
-			fmt.Printf("NATS reconnected to %s\n", nc.ConnectedUrl())
+			hlog.Infof("NATS reconnected to %s", nc.ConnectedUrl())
 		}),
 		nats.ClosedHandler(func(nc *nats.Conn) {
-			fmt.Println("NATS connection closed")
+			hlog.Info("NATS connection closed")
 		}),
 	}
 
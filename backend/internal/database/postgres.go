@@ -3,41 +3,88 @@ package database
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 )
 
 const (
-	defaultPingTimeout = 5 * time.Second
+	defaultPingTimeout       = 5 * time.Second
+	defaultMaxConns          = 25
+	defaultConnMaxLifetime   = time.Hour
+	defaultConnMaxIdleTime   = 30 * time.Minute
+	defaultHealthCheckPeriod = time.Minute
+
+	// connLifetimeJitterDivisor spreads reconnects over a tenth of the
+	// lifetime so connections opened together aren't all closed together
+	connLifetimeJitterDivisor = 10
 )
 
 // NewPostgresPool creates a new PostgreSQL connection pool
 func NewPostgresPool(cfg *config.DatabaseConfig) (*pgxpool.Pool, error) {
-	poolConfig, err := pgxpool.ParseConfig(cfg.GetDSN())
+	pool, err := newPool(cfg, cfg.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), defaultPingTimeout)
+	defer cancel()
+
+	if err := pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return pool, nil
+}
+
+// NewReplicaPool creates the connection pool of the read replica, nil when
+// none is configured. It is not pinged: repositories fall back to the primary
+// while the replica is unreachable, so it must not block startup.
+func NewReplicaPool(cfg *config.DatabaseConfig) (*pgxpool.Pool, error) {
+	if cfg.ReplicaHost == "" {
+		return nil, nil
+	}
+	return newPool(cfg, cfg.GetReplicaDSN())
+}
+
+func newPool(cfg *config.DatabaseConfig, dsn string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
 
-	// Configure pool settings
-	// #nosec G115 -- MaxConnections is validated to be within int32 range
-	maxConns := cfg.MaxConnections
-	if maxConns > 0x7FFFFFFF || maxConns < 0 {
-		maxConns = 100 // default safe value
+	// Configure pool settings. Zero values fall back to the defaults, pgx
+	// rejects an empty pool and closes connections with a zero lifetime right
+	// after use.
+	maxConns := defaultMaxConns
+	if cfg.MaxConnections > 0 {
+		maxConns = min(cfg.MaxConnections, math.MaxInt32)
+	}
+	minConns := min(max(cfg.MaxIdleConnections, 0), maxConns)
+	lifetime := defaultConnMaxLifetime
+	if cfg.ConnectionMaxLifetime > 0 {
+		lifetime = time.Duration(cfg.ConnectionMaxLifetime) * time.Second
 	}
-	poolConfig.MaxConns = int32(maxConns) // #nosec G115
 
-	// #nosec G115 -- MaxIdleConnections is validated to be within int32 range
-	minConns := cfg.MaxIdleConnections
-	if minConns > 0x7FFFFFFF || minConns < 0 {
-		minConns = 10 // default safe value
+	poolConfig.MaxConns = int32(maxConns) //nolint:gosec // capped at math.MaxInt32
+	poolConfig.MinConns = int32(minConns) //nolint:gosec // capped at maxConns
+	poolConfig.MaxConnLifetime = lifetime
+	poolConfig.MaxConnLifetimeJitter = lifetime / connLifetimeJitterDivisor
+	poolConfig.MaxConnIdleTime = defaultConnMaxIdleTime
+	poolConfig.HealthCheckPeriod = defaultHealthCheckPeriod
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	// New connections use the current password, so a rotated one is picked
+	// up without restarting
+	poolConfig.BeforeConnect = func(_ context.Context, connConfig *pgx.ConnConfig) error {
+		connConfig.Password = cfg.CurrentPassword()
+		return nil
 	}
-	poolConfig.MinConns = int32(minConns) // #nosec G115
-	poolConfig.MaxConnLifetime = time.Duration(cfg.ConnectionMaxLifetime) * time.Second
-	poolConfig.MaxConnIdleTime = 30 * time.Minute
-	poolConfig.HealthCheckPeriod = 1 * time.Minute
 
 	// Create pool
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
@@ -45,14 +92,6 @@ func NewPostgresPool(cfg *config.DatabaseConfig) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), defaultPingTimeout)
-	defer cancel()
-
-	if err := pool.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
 	return pool, nil
 }
 
@@ -3,11 +3,13 @@ package database
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
 	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 )
 
 const (
@@ -16,21 +18,45 @@ const (
 	redisReadTimeout  = 3 * time.Second
 	redisWriteTimeout = 3 * time.Second
 	redisMinIdleConns = 2
+
+	// redisPoolSizePerCPU is the go-redis default pool size per GOMAXPROCS
+	redisPoolSizePerCPU  = 10
+	redisDefaultRetries  = 3
+	redisNoRetries       = -1
+	redisConnMaxIdleTime = 30 * time.Minute
+	redisConnMaxLifetime = time.Hour
 )
 
-// NewRedisClient creates a new Redis client
+// NewRedisClient creates a new Redis client. A zero pool size uses ten
+// connections per CPU, zero retries uses the default of three and a negative
+// value disables retries.
 func NewRedisClient(cfg *config.RedisConfig) (*redis.Client, error) {
+	poolSize := cfg.PoolSize
+	if poolSize <= 0 {
+		poolSize = redisPoolSizePerCPU * runtime.GOMAXPROCS(0)
+	}
+	maxRetries := cfg.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = redisDefaultRetries
+	case maxRetries < 0:
+		maxRetries = redisNoRetries
+	}
+
 	client := redis.NewClient(&redis.Options{
-		Addr:         cfg.GetRedisAddr(),
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		MaxRetries:   cfg.MaxRetries,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: redisMinIdleConns,
-		DialTimeout:  redisDialTimeout,
-		ReadTimeout:  redisReadTimeout,
-		WriteTimeout: redisWriteTimeout,
+		Addr:            cfg.GetRedisAddr(),
+		Password:        cfg.Password,
+		DB:              cfg.DB,
+		MaxRetries:      maxRetries,
+		PoolSize:        poolSize,
+		MinIdleConns:    min(redisMinIdleConns, poolSize),
+		ConnMaxIdleTime: redisConnMaxIdleTime,
+		ConnMaxLifetime: redisConnMaxLifetime,
+		DialTimeout:     redisDialTimeout,
+		ReadTimeout:     redisReadTimeout,
+		WriteTimeout:    redisWriteTimeout,
 	})
+	client.AddHook(tracing.RedisHook{})
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), redisPingTimeout)
@@ -0,0 +1,93 @@
+// Package debug serves the Go profiling and runtime endpoints, net/http/pprof
+// and expvar, on an internal port for operators.
+package debug
+
+import (
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const debugReadHeaderTimeout = 5 * time.Second
+
+var publishRuntime sync.Once
+
+// NewServer returns the HTTP server of /debug/pprof/ and /debug/vars on port.
+// It has no write timeout, CPU profiles and traces stream for as long as the
+// operator asks for. When a token is configured every request must send it
+// as a bearer token.
+func NewServer(cfg *config.DebugConfig, port int) *http.Server {
+	publishRuntime.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() any {
+			return runtime.NumGoroutine()
+		}))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	var handler http.Handler = mux
+	if cfg.Token != "" {
+		handler = requireToken(cfg.Token, mux)
+	}
+
+	return &http.Server{
+		Addr:              net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		Handler:           handler,
+		ReadHeaderTimeout: debugReadHeaderTimeout,
+	}
+}
+
+// PublishHub exposes the clients of every room of the hub under hub_rooms,
+// to spot rooms that outlive their clients
+func PublishHub(hub *service.Hub) {
+	expvar.Publish("hub_rooms", expvar.Func(func() any {
+		stats := hub.GetAllRoomStats()
+		rooms := make(map[string]int, len(stats))
+		for workspaceID, clients := range stats {
+			rooms[workspaceID.String()] = clients
+		}
+		return rooms
+	}))
+}
+
+// Start serves the debug endpoints in the background. A failing debug server
+// is logged and doesn't stop the application.
+func Start(srv *http.Server) {
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			hlog.Errorf("Debug server failed: %v", err)
+		}
+	}()
+	hlog.Infof("Profiling endpoints are served on %s/debug/pprof/", srv.Addr)
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
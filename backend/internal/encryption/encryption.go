@@ -0,0 +1,95 @@
+// Package encryption encrypts workspace content at rest with envelope
+// encryption: content is sealed with AES-256-GCM under a data key per
+// workspace, and data keys are only stored wrapped by a master key that
+// lives outside the database.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+const (
+	// keySize is the size of data and local master keys, AES-256
+	keySize = 32
+
+	// sealVersion prefixes sealed values, so the format can change later
+	sealVersion byte = 1
+)
+
+var (
+	// ErrUnknownMasterKey is returned for data keys wrapped by a master key
+	// that isn't configured, e.g. a rotated key removed too early
+	ErrUnknownMasterKey = errors.New("data key is wrapped by an unknown master key")
+	// ErrInvalidCiphertext is returned for values that were not sealed by
+	// this package or were tampered with
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// KeyWrapper protects data keys with a master key
+type KeyWrapper interface {
+	// KeyID identifies the master key new data keys are wrapped with
+	KeyID() string
+	// Wrap encrypts a data key with the current master key
+	Wrap(ctx context.Context, key []byte) ([]byte, error)
+	// Unwrap decrypts a data key wrapped by the master key keyID
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// NewKeyWrapper creates the key wrapper of the configured provider
+func NewKeyWrapper(cfg *config.EncryptionConfig) (KeyWrapper, error) {
+	switch cfg.Provider {
+	case "", "local":
+		return NewLocalKeyWrapper(&cfg.Local)
+	case "vault":
+		return NewVaultKeyWrapper(&cfg.Vault)
+	default:
+		return nil, fmt.Errorf("unknown encryption provider %q", cfg.Provider)
+	}
+}
+
+// newAEAD creates AES-256-GCM for a key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// seal encrypts plaintext as version, nonce and ciphertext. The additional
+// data is authenticated but not stored.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out[0] = sealVersion
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(out, out[1:], plaintext, additionalData), nil
+}
+
+// open decrypts a value produced by seal with the same additional data
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < 1+aead.NonceSize()+aead.Overhead() || sealed[0] != sealVersion {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce := sealed[1 : 1+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, sealed[1+aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
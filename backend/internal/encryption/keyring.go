@@ -0,0 +1,164 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// maxCachedDataKeys bounds the unwrapped data keys held in memory
+const maxCachedDataKeys = 10000
+
+// KeyStore persists the wrapped data keys of workspaces
+type KeyStore interface {
+	// GetDataKey returns the data key of a workspace, nil when it has none
+	GetDataKey(ctx context.Context, workspaceID uuid.UUID) (*models.WorkspaceDataKey, error)
+	// CreateDataKey stores a data key unless the workspace has one already
+	// and returns the stored key
+	CreateDataKey(ctx context.Context, key *models.WorkspaceDataKey) (*models.WorkspaceDataKey, error)
+	// RewrapDataKey replaces the wrapped form of a data key
+	RewrapDataKey(ctx context.Context, workspaceID uuid.UUID, masterKeyID string, wrappedKey []byte) error
+}
+
+// Keyring seals and opens workspace content with the data key of the
+// workspace. Data keys are created on first use and kept unwrapped in
+// memory, so the master key is only needed once per workspace and process.
+type Keyring struct {
+	wrapper KeyWrapper
+	store   KeyStore
+
+	mu   sync.Mutex
+	keys map[uuid.UUID]cipher.AEAD
+}
+
+// NewKeyring creates a keyring
+func NewKeyring(wrapper KeyWrapper, store KeyStore) *Keyring {
+	return &Keyring{
+		wrapper: wrapper,
+		store:   store,
+		keys:    make(map[uuid.UUID]cipher.AEAD),
+	}
+}
+
+// Seal encrypts plaintext for a workspace. The additional data, usually
+// the ID of the row, must be passed to Open again, so sealed values can't
+// be moved between rows.
+func (k *Keyring) Seal(ctx context.Context, workspaceID uuid.UUID, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := k.dataKey(ctx, workspaceID, true)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, plaintext, additionalData)
+}
+
+// Open decrypts a value sealed for a workspace
+func (k *Keyring) Open(ctx context.Context, workspaceID uuid.UUID, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := k.dataKey(ctx, workspaceID, false)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, sealed, additionalData)
+}
+
+// dataKey returns the data key of a workspace, creating it when create is
+// set and the workspace has none
+func (k *Keyring) dataKey(ctx context.Context, workspaceID uuid.UUID, create bool) (cipher.AEAD, error) {
+	k.mu.Lock()
+	aead, ok := k.keys[workspaceID]
+	k.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	stored, err := k.store.GetDataKey(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	switch {
+	case stored != nil:
+		key, err = k.wrapper.Unwrap(ctx, stored.MasterKeyID, stored.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key of workspace %s: %w", workspaceID, err)
+		}
+		if stored.MasterKeyID != k.wrapper.KeyID() {
+			k.rewrap(ctx, workspaceID, key)
+		}
+	case create:
+		key, err = k.createDataKey(ctx, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("workspace %s has no data key", workspaceID)
+	}
+
+	aead, err = newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	if len(k.keys) >= maxCachedDataKeys {
+		for id := range k.keys {
+			delete(k.keys, id)
+			break
+		}
+	}
+	k.keys[workspaceID] = aead
+	k.mu.Unlock()
+
+	return aead, nil
+}
+
+// createDataKey generates and stores a data key. When another instance
+// stored one first, that key is used instead.
+func (k *Keyring) createDataKey(ctx context.Context, workspaceID uuid.UUID) ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := k.wrapper.Wrap(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	stored, err := k.store.CreateDataKey(ctx, &models.WorkspaceDataKey{
+		WorkspaceID: workspaceID,
+		MasterKeyID: k.wrapper.KeyID(),
+		WrappedKey:  wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stored.MasterKeyID == k.wrapper.KeyID() && string(stored.WrappedKey) == string(wrapped) {
+		return key, nil
+	}
+
+	key, err = k.wrapper.Unwrap(ctx, stored.MasterKeyID, stored.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of workspace %s: %w", workspaceID, err)
+	}
+	return key, nil
+}
+
+// rewrap wraps a data key with the current master key after a rotation.
+// Failures are logged, the key is rewrapped on a later use.
+func (k *Keyring) rewrap(ctx context.Context, workspaceID uuid.UUID, key []byte) {
+	wrapped, err := k.wrapper.Wrap(ctx, key)
+	if err == nil {
+		err = k.store.RewrapDataKey(ctx, workspaceID, k.wrapper.KeyID(), wrapped)
+	}
+	if err != nil {
+		hlog.CtxWarnf(ctx, "Failed to rewrap data key of workspace %s: %v", workspaceID, err)
+	}
+}
@@ -0,0 +1,71 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// LocalKeyWrapper wraps data keys with master keys from the configuration.
+// Previous keys are kept to unwrap data keys that weren't rewrapped yet.
+type LocalKeyWrapper struct {
+	keys  map[string]cipher.AEAD
+	keyID string
+}
+
+// NewLocalKeyWrapper decodes the master keys of cfg
+func NewLocalKeyWrapper(cfg *config.EncryptionLocalConfig) (*LocalKeyWrapper, error) {
+	if cfg.KeyID == "" {
+		return nil, fmt.Errorf("encryption key_id is required")
+	}
+
+	w := &LocalKeyWrapper{keys: make(map[string]cipher.AEAD), keyID: cfg.KeyID}
+	if err := w.addKey(cfg.KeyID, cfg.Key); err != nil {
+		return nil, err
+	}
+	for _, previous := range cfg.PreviousKeys {
+		if _, exists := w.keys[previous.KeyID]; exists {
+			return nil, fmt.Errorf("encryption key_id %q is used more than once", previous.KeyID)
+		}
+		if err := w.addKey(previous.KeyID, previous.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (w *LocalKeyWrapper) addKey(keyID, encoded string) error {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("encryption key %q is not valid base64: %w", keyID, err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return fmt.Errorf("encryption key %q: %w", keyID, err)
+	}
+	w.keys[keyID] = aead
+	return nil
+}
+
+// KeyID returns the ID of the current master key
+func (w *LocalKeyWrapper) KeyID() string {
+	return w.keyID
+}
+
+// Wrap seals a data key with the current master key
+func (w *LocalKeyWrapper) Wrap(_ context.Context, key []byte) ([]byte, error) {
+	return seal(w.keys[w.keyID], key, []byte(w.keyID))
+}
+
+// Unwrap opens a data key with the master key it was wrapped with
+func (w *LocalKeyWrapper) Unwrap(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := w.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMasterKey, keyID)
+	}
+	return open(aead, wrapped, []byte(keyID))
+}
@@ -0,0 +1,139 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	defaultVaultMount    = "transit"
+	vaultTimeout         = 10 * time.Second
+	vaultMaxErrorBody    = 512
+	vaultTokenHeader     = "X-Vault-Token"
+	vaultNamespaceHeader = "X-Vault-Namespace"
+)
+
+// VaultKeyWrapper wraps data keys with a key of Vault's transit secrets
+// engine. Vault records the key version in the ciphertext, so rotating the
+// key in Vault needs no configuration change.
+type VaultKeyWrapper struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	namespace  string
+	keyName    string
+	keyID      string
+}
+
+// vaultResponse is the envelope of transit responses
+type vaultResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"`
+		Plaintext  string `json:"plaintext"`
+	} `json:"data"`
+}
+
+// NewVaultKeyWrapper creates a wrapper for the transit key of cfg
+func NewVaultKeyWrapper(cfg *config.EncryptionVaultConfig) (*VaultKeyWrapper, error) {
+	if cfg.Address == "" || cfg.Token == "" || cfg.KeyName == "" {
+		return nil, fmt.Errorf("vault address, token and key_name are required")
+	}
+
+	mount := strings.Trim(cfg.Mount, "/")
+	if mount == "" {
+		mount = defaultVaultMount
+	}
+
+	return &VaultKeyWrapper{
+		httpClient: &http.Client{Timeout: vaultTimeout, Transport: tracing.NewTransport(http.DefaultTransport)},
+		baseURL:    strings.TrimSuffix(cfg.Address, "/") + "/v1/" + mount,
+		token:      cfg.Token,
+		namespace:  cfg.Namespace,
+		keyName:    cfg.KeyName,
+		keyID:      "vault:" + mount + "/" + cfg.KeyName,
+	}, nil
+}
+
+// KeyID identifies the transit key
+func (w *VaultKeyWrapper) KeyID() string {
+	return w.keyID
+}
+
+// Wrap encrypts a data key in Vault
+func (w *VaultKeyWrapper) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	resp, err := w.call(ctx, "encrypt", map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("vault returned no ciphertext")
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// Unwrap decrypts a data key in Vault
+func (w *VaultKeyWrapper) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != w.keyID {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMasterKey, keyID)
+	}
+
+	resp, err := w.call(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)})
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vault returned an invalid plaintext: %w", err)
+	}
+	return key, nil
+}
+
+// call posts to an operation of the transit key
+func (w *VaultKeyWrapper) call(ctx context.Context, operation string, body map[string]string) (*vaultResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal vault request: %w", err)
+	}
+
+	endpoint := w.baseURL + "/" + operation + "/" + url.PathEscape(w.keyName)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set(vaultTokenHeader, w.token)
+	req.Header.Set("Content-Type", "application/json")
+	if w.namespace != "" {
+		req.Header.Set(vaultNamespaceHeader, w.namespace)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, vaultMaxErrorBody))
+		return nil, fmt.Errorf("vault %s responded with status %d: %s", operation, resp.StatusCode, bytes.TrimSpace(errBody))
+	}
+
+	var result vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return &result, nil
+}
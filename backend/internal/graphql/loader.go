@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	// loaderWait is how long a loader collects keys before it fetches them.
+	// Sibling fields resolve concurrently, so the keys of a list arrive
+	// together.
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch caps the keys of one fetch
+	loaderMaxBatch = 500
+)
+
+// loaders batch the lookups of one request, so a list of elements loads
+// its authors with one query instead of one per element, and a list of
+// workspaces loads their elements, snapshots, assets and IP allowlists with
+// one query each
+type loaders struct {
+	users     *loader[uuid.UUID, *models.User]
+	members   *loader[uuid.UUID, []models.WorkspaceMemberWithUser]
+	elements  *loader[uuid.UUID, []models.CanvasElement]
+	snapshots *loader[pageKey[snapshotFilter], *models.SnapshotPage]
+	assets    *loader[pageKey[models.AssetListFilter], *models.AssetPage]
+	// ipAccess tells whether the client of the request may access a workspace
+	ipAccess *loader[uuid.UUID, bool]
+}
+
+func (r *Resolver) newLoaders(userID uuid.UUID, clientIP string) *loaders {
+	return &loaders{
+		users: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.User, error) {
+			users, err := r.userRepo.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.User, len(users))
+			for i := range users {
+				byID[users[i].ID] = &users[i]
+			}
+			return byID, nil
+		}),
+		members:  newLoader(r.workspaceRepo.ListMembersByWorkspaces),
+		elements: newLoader(r.canvasService.GetElementsByWorkspaces),
+		snapshots: newPageLoader(func(ctx context.Context, ids []uuid.UUID, filter snapshotFilter) (map[uuid.UUID]*models.SnapshotPage, error) {
+			return r.snapshotService.ListSnapshotsByWorkspaces(ctx, ids, filter.listFilter())
+		}),
+		assets: newPageLoader(r.assetService.GetAssetsByWorkspaces),
+		ipAccess: newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+			return r.ipAllowlist.CheckAccessMany(ctx, ids, userID, clientIP)
+		}),
+	}
+}
+
+// pageKey identifies a page of a workspace list. The workspaces of a list
+// usually ask for the same page, those are fetched together.
+type pageKey[F comparable] struct {
+	filter      F
+	workspaceID uuid.UUID
+}
+
+// newPageLoader creates a loader that fetches the pages of all workspaces
+// with the same filter with one call
+func newPageLoader[F comparable, V any](
+	fetch func(ctx context.Context, workspaceIDs []uuid.UUID, filter F) (map[uuid.UUID]V, error),
+) *loader[pageKey[F], V] {
+	return newLoader(func(ctx context.Context, keys []pageKey[F]) (map[pageKey[F]]V, error) {
+		byFilter := make(map[F][]uuid.UUID)
+		for _, key := range keys {
+			byFilter[key.filter] = append(byFilter[key.filter], key.workspaceID)
+		}
+
+		values := make(map[pageKey[F]]V, len(keys))
+		for filter, workspaceIDs := range byFilter {
+			pages, err := fetch(ctx, workspaceIDs, filter)
+			if err != nil {
+				return nil, err
+			}
+			for workspaceID, page := range pages {
+				values[pageKey[F]{filter: filter, workspaceID: workspaceID}] = page
+			}
+		}
+		return values, nil
+	})
+}
+
+// loader collects the keys requested within loaderWait and fetches them
+// with a single call. Results are cached for the rest of the request.
+type loader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	cache   map[K]*loaderResult[V]
+	pending []K
+	mu      sync.Mutex
+}
+
+type loaderResult[V any] struct {
+	value V
+	err   error
+	done  chan struct{}
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch: fetch,
+		cache: make(map[K]*loaderResult[V]),
+	}
+}
+
+// Load returns the value of a key, the zero value when the fetch didn't
+// return it
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	result, cached := l.cache[key]
+	if !cached {
+		result = &loaderResult[V]{done: make(chan struct{})}
+		l.cache[key] = result
+		l.pending = append(l.pending, key)
+
+		switch {
+		case len(l.pending) >= loaderMaxBatch:
+			keys := l.pending
+			l.pending = nil
+			go l.dispatch(ctx, keys)
+		case len(l.pending) == 1:
+			go l.dispatchAfterWait(ctx)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-result.done:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (l *loader[K, V]) dispatchAfterWait(ctx context.Context) {
+	time.Sleep(loaderWait)
+
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(keys) > 0 {
+		l.dispatch(ctx, keys)
+	}
+}
+
+func (l *loader[K, V]) dispatch(ctx context.Context, keys []K) {
+	values, err := l.fetch(ctx, keys)
+
+	l.mu.Lock()
+	results := make([]*loaderResult[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.cache[key]
+	}
+	l.mu.Unlock()
+
+	for i, key := range keys {
+		results[i].value = values[key]
+		results[i].err = err
+		close(results[i].done)
+	}
+}
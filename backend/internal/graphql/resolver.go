@@ -0,0 +1,211 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	maxWorkspaceLimit = 100
+	maxSnapshotLimit  = 100
+	maxAssetLimit     = 200
+)
+
+var (
+	errUnauthenticated = errors.New("unauthenticated")
+	errAccessDenied    = errors.New("access denied")
+	errInvalidID       = errors.New("invalid id")
+	// errInternal hides failures of the storage layer, they are logged
+	errInternal = errors.New("internal error")
+)
+
+// Resolver is the root resolver of the schema
+type Resolver struct {
+	workspaceService *service.WorkspaceService
+	canvasService    *service.CanvasService
+	snapshotService  *service.SnapshotService
+	assetService     *service.AssetService
+	userRepo         *repository.UserRepository
+	workspaceRepo    *repository.WorkspaceRepository
+	hub              *service.Hub
+	ipAllowlist      *service.IPAllowlistService
+}
+
+// NewResolver creates the root resolver
+func NewResolver(
+	workspaceService *service.WorkspaceService,
+	canvasService *service.CanvasService,
+	snapshotService *service.SnapshotService,
+	assetService *service.AssetService,
+	userRepo *repository.UserRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	hub *service.Hub,
+	ipAllowlist *service.IPAllowlistService,
+) *Resolver {
+	return &Resolver{
+		workspaceService: workspaceService,
+		canvasService:    canvasService,
+		snapshotService:  snapshotService,
+		assetService:     assetService,
+		userRepo:         userRepo,
+		workspaceRepo:    workspaceRepo,
+		hub:              hub,
+		ipAllowlist:      ipAllowlist,
+	}
+}
+
+// Me resolves the authenticated user
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := v.loaders.users.Load(ctx, v.userID)
+	if err != nil {
+		return nil, internalError(ctx, "load user", err)
+	}
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+type workspacesArgs struct {
+	Query  *string
+	Limit  int32
+	Offset int32
+}
+
+// Workspaces resolves the workspaces the authenticated user is a member of
+func (r *Resolver) Workspaces(ctx context.Context, args workspacesArgs) (*workspacePageResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.WorkspaceListFilter{
+		Limit:     clampLimit(args.Limit, maxWorkspaceLimit),
+		Offset:    clampOffset(args.Offset),
+		SortBy:    "updated_at",
+		SortOrder: "desc",
+	}
+	if args.Query != nil {
+		filter.Query = *args.Query
+	}
+
+	list, err := r.workspaceService.ListUserWorkspaces(ctx, v.userID, filter)
+	if err != nil {
+		return nil, internalError(ctx, "list workspaces", err)
+	}
+
+	items := make([]*workspaceResolver, 0, len(list.Workspaces))
+	for i := range list.Workspaces {
+		ws := &list.Workspaces[i]
+		role := models.WorkspaceRoleViewer
+		if ws.UserRole != nil {
+			role = *ws.UserRole
+		}
+		items = append(items, &workspaceResolver{
+			r:    r,
+			role: role,
+			workspace: models.Workspace{
+				ID:           ws.ID,
+				Name:         ws.Name,
+				Description:  ws.Description,
+				OwnerID:      ws.OwnerID,
+				ThumbnailURL: ws.ThumbnailURL,
+				IsPublic:     ws.IsPublic,
+				Settings:     ws.Settings,
+				CreatedAt:    ws.CreatedAt,
+				UpdatedAt:    ws.UpdatedAt,
+			},
+		})
+	}
+
+	return &workspacePageResolver{total: list.Total, items: items}, nil
+}
+
+// Workspace resolves a workspace the authenticated user can access. Missing
+// workspaces and denied access both resolve to null, like the 404 of REST.
+func (r *Resolver) Workspace(ctx context.Context, args struct{ ID graphqlgo.ID }) (*workspaceResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := r.workspaceService.GetWorkspaceWithRole(ctx, workspaceID, v.userID)
+	if err != nil {
+		// GetWorkspaceWithRole doesn't tell a missing workspace from a
+		// failed lookup, both are reported as not found
+		hlog.CtxDebugf(ctx, "GraphQL workspace %s not resolved: %v", workspaceID, err)
+		return nil, nil
+	}
+
+	if err := r.checkIPAllowlist(ctx, v, workspaceID); err != nil {
+		return nil, err
+	}
+
+	return &workspaceResolver{r: r, workspace: ws.Workspace, role: ws.UserRole}, nil
+}
+
+// checkIPAllowlist returns an error when the workspace restricts access to
+// networks the client of the request isn't in, like the REST routes do. The
+// allowlists of a list of workspaces are loaded together.
+func (r *Resolver) checkIPAllowlist(ctx context.Context, v *viewer, workspaceID uuid.UUID) error {
+	if r.ipAllowlist == nil {
+		return nil
+	}
+
+	allowed, err := v.loaders.ipAccess.Load(ctx, workspaceID)
+	if err != nil {
+		return internalError(ctx, "check IP allowlist", err)
+	}
+	if !allowed {
+		return service.ErrIPNotAllowed
+	}
+	return nil
+}
+
+func parseID(id graphqlgo.ID) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(string(id))
+	if err != nil {
+		return uuid.Nil, errInvalidID
+	}
+	return parsed, nil
+}
+
+func clampLimit(limit, maxLimit int32) int {
+	if limit <= 0 || limit > maxLimit {
+		return int(maxLimit)
+	}
+	return int(limit)
+}
+
+func clampOffset(offset int32) int {
+	if offset < 0 {
+		return 0
+	}
+	return int(offset)
+}
+
+// internalError logs a storage failure and returns the error shown to the
+// client in its place
+func internalError(ctx context.Context, action string, err error) error {
+	hlog.CtxErrorf(ctx, "GraphQL failed to %s: %v", action, err)
+	return errInternal
+}
@@ -0,0 +1,109 @@
+// Package graphql serves a GraphQL API over workspaces, members, elements,
+// snapshots and assets next to the REST API. Subscriptions stream the
+// realtime messages of the hub.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/google/uuid"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	defaultMaxDepth = 10
+	maxParallelism  = 10
+	maxQueryLength  = 16 * 1024
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type viewerKey struct{}
+
+// viewer is the authenticated user of a request with the loaders that
+// batch its lookups
+type viewer struct {
+	loaders  *loaders
+	userName string
+	clientIP string
+	userID   uuid.UUID
+}
+
+// Server executes GraphQL operations for authenticated users
+type Server struct {
+	schema   *graphqlgo.Schema
+	resolver *Resolver
+}
+
+// NewServer parses the schema against the resolver, so a resolver that
+// doesn't match the schema fails at startup
+func NewServer(cfg *config.GraphQLConfig, resolver *Resolver) (*Server, error) {
+	maxDepth := cfg.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+
+	schema, err := graphqlgo.ParseSchema(schemaSDL, resolver,
+		graphqlgo.MaxDepth(maxDepth),
+		graphqlgo.MaxParallelism(maxParallelism),
+		graphqlgo.MaxQueryLength(maxQueryLength),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse graphql schema: %w", err)
+	}
+
+	return &Server{schema: schema, resolver: resolver}, nil
+}
+
+// Exec runs a query for a user. clientIP is checked against the IP
+// allowlists of the workspaces the query reads.
+func (s *Server) Exec(
+	ctx context.Context,
+	userID uuid.UUID,
+	userName, clientIP string,
+	req *models.GraphQLRequest,
+) *graphqlgo.Response {
+	ctx = s.withViewer(ctx, userID, userName, clientIP)
+	return s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+// Subscribe runs an operation for a user and returns its responses. Queries
+// produce a single response, subscriptions one per event until the context
+// is cancelled.
+func (s *Server) Subscribe(
+	ctx context.Context,
+	userID uuid.UUID,
+	userName, clientIP string,
+	req *models.GraphQLRequest,
+) (<-chan interface{}, error) {
+	ctx = s.withViewer(ctx, userID, userName, clientIP)
+	responses, err := s.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	return responses, nil
+}
+
+func (s *Server) withViewer(ctx context.Context, userID uuid.UUID, userName, clientIP string) context.Context {
+	return context.WithValue(ctx, viewerKey{}, &viewer{
+		userID:   userID,
+		userName: userName,
+		clientIP: clientIP,
+		loaders:  s.resolver.newLoaders(userID, clientIP),
+	})
+}
+
+func viewerFromContext(ctx context.Context) (*viewer, error) {
+	v, ok := ctx.Value(viewerKey{}).(*viewer)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	return v, nil
+}
@@ -0,0 +1,155 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	// subscriptionPingInterval keeps subscribers from expiring as stale
+	// presences in the hub
+	subscriptionPingInterval = 15 * time.Second
+	// subscriptionBufferSize matches the send buffer of WebSocket clients
+	subscriptionBufferSize = 256
+)
+
+var (
+	elementEventTypes = map[models.MessageType]bool{
+		models.MessageTypeOperation:        true,
+		models.MessageTypeBatch:            true,
+		models.MessageTypeElementsAdded:    true,
+		models.MessageTypeElementsRestored: true,
+		models.MessageTypeBoardReloaded:    true,
+	}
+	presenceEventTypes = map[models.MessageType]bool{
+		models.MessageTypeUserJoined:      true,
+		models.MessageTypeUserLeft:        true,
+		models.MessageTypeCursorMove:      true,
+		models.MessageTypeSelectionChange: true,
+		models.MessageTypePresenceUpdate:  true,
+	}
+)
+
+type subscriptionArgs struct {
+	WorkspaceID graphqlgo.ID
+}
+
+// ElementUpdates streams the element changes of a workspace
+func (r *Resolver) ElementUpdates(ctx context.Context, args subscriptionArgs) (<-chan *eventResolver, error) {
+	return r.subscribe(ctx, args.WorkspaceID, elementEventTypes)
+}
+
+// PresenceUpdates streams the presence changes of a workspace
+func (r *Resolver) PresenceUpdates(ctx context.Context, args subscriptionArgs) (<-chan *eventResolver, error) {
+	return r.subscribe(ctx, args.WorkspaceID, presenceEventTypes)
+}
+
+// subscribe joins the room of a workspace like a WebSocket client and
+// forwards the messages of the given types until the context is cancelled
+func (r *Resolver) subscribe(
+	ctx context.Context,
+	id graphqlgo.ID,
+	types map[models.MessageType]bool,
+) (<-chan *eventResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := r.workspaceService.GetUserRole(ctx, workspaceID, v.userID)
+	if err != nil {
+		return nil, errAccessDenied
+	}
+	if err := r.checkIPAllowlist(ctx, v, workspaceID); err != nil {
+		return nil, err
+	}
+
+	userColor := models.UserColor(v.userID)
+	client := &models.Client{
+		ID:          uuid.New(),
+		UserID:      v.userID,
+		WorkspaceID: workspaceID,
+		UserName:    v.userName,
+		UserColor:   userColor,
+		Role:        role,
+		Send:        make(chan *models.WSMessage, subscriptionBufferSize),
+		Presence: &models.UserPresence{
+			UserID:    v.userID,
+			UserName:  v.userName,
+			UserColor: userColor,
+			LastSeen:  time.Now(),
+		},
+	}
+	client.Touch()
+	r.hub.Register(client)
+
+	events := make(chan *eventResolver)
+	go func() {
+		defer close(events)
+		defer r.hub.Unregister(client)
+
+		ticker := time.NewTicker(subscriptionPingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				client.Touch()
+
+			case message, ok := <-client.Send:
+				if !ok {
+					return
+				}
+				if !types[message.Type] {
+					continue
+				}
+
+				select {
+				case events <- &eventResolver{message: message}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+type eventResolver struct {
+	message *models.WSMessage
+}
+
+func (e *eventResolver) Type() string { return string(e.message.Type) }
+
+func (e *eventResolver) UserID() *graphqlgo.ID {
+	if e.message.UserID == uuid.Nil {
+		return nil
+	}
+	id := graphqlgo.ID(e.message.UserID.String())
+	return &id
+}
+
+func (e *eventResolver) Timestamp() graphqlgo.Time {
+	return graphqlgo.Time{Time: e.message.Timestamp}
+}
+
+func (e *eventResolver) Payload() *JSON {
+	if e.message.Payload == nil {
+		return nil
+	}
+	return &JSON{Value: e.message.Payload}
+}
@@ -0,0 +1,386 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+
+	"github.com/google/uuid"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// JSON is the JSON scalar, any value that encodes to JSON
+type JSON struct {
+	Value interface{}
+}
+
+// ImplementsGraphQLType maps the type to the JSON scalar of the schema
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL accepts any input value
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	j.Value = input
+	return nil
+}
+
+// MarshalJSON writes the value itself
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
+func roleEnum(role models.WorkspaceRole) string {
+	return strings.ToUpper(string(role))
+}
+
+func optionalID(id *uuid.UUID) *graphqlgo.ID {
+	if id == nil {
+		return nil
+	}
+	value := graphqlgo.ID(id.String())
+	return &value
+}
+
+// loadUser resolves a user reference through the request's user loader
+func loadUser(ctx context.Context, userID uuid.UUID) (*userResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := v.loaders.users.Load(ctx, userID)
+	if err != nil {
+		return nil, internalError(ctx, "load user", err)
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+type userResolver struct {
+	user *models.User
+}
+
+func (u *userResolver) ID() graphqlgo.ID   { return graphqlgo.ID(u.user.ID.String()) }
+func (u *userResolver) Name() string       { return u.user.Name }
+func (u *userResolver) Username() string   { return u.user.Username }
+func (u *userResolver) Email() string      { return u.user.Email }
+func (u *userResolver) AvatarURL() *string { return u.user.AvatarURL }
+
+type workspaceResolver struct {
+	r         *Resolver
+	role      models.WorkspaceRole
+	workspace models.Workspace
+}
+
+func (w *workspaceResolver) ID() graphqlgo.ID      { return graphqlgo.ID(w.workspace.ID.String()) }
+func (w *workspaceResolver) Name() string          { return w.workspace.Name }
+func (w *workspaceResolver) Description() *string  { return w.workspace.Description }
+func (w *workspaceResolver) ThumbnailURL() *string { return w.workspace.ThumbnailURL }
+func (w *workspaceResolver) IsPublic() bool        { return w.workspace.IsPublic }
+func (w *workspaceResolver) Role() string          { return roleEnum(w.role) }
+
+func (w *workspaceResolver) Settings() *JSON {
+	if w.workspace.Settings == nil {
+		return nil
+	}
+	return &JSON{Value: w.workspace.Settings}
+}
+
+func (w *workspaceResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: w.workspace.CreatedAt}
+}
+
+func (w *workspaceResolver) UpdatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: w.workspace.UpdatedAt}
+}
+
+func (w *workspaceResolver) Owner(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, w.workspace.OwnerID)
+}
+
+// Members loads the members of all workspaces of a list with one query
+func (w *workspaceResolver) Members(ctx context.Context) ([]*memberResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.r.checkIPAllowlist(ctx, v, w.workspace.ID); err != nil {
+		return nil, err
+	}
+
+	members, err := v.loaders.members.Load(ctx, w.workspace.ID)
+	if err != nil {
+		return nil, internalError(ctx, "list members", err)
+	}
+
+	resolvers := make([]*memberResolver, len(members))
+	for i := range members {
+		resolvers[i] = &memberResolver{member: &members[i]}
+	}
+	return resolvers, nil
+}
+
+// Elements loads the elements of all workspaces of a list together
+func (w *workspaceResolver) Elements(ctx context.Context, args struct{ Type *string }) ([]*elementResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.r.checkIPAllowlist(ctx, v, w.workspace.ID); err != nil {
+		return nil, err
+	}
+
+	elements, err := v.loaders.elements.Load(ctx, w.workspace.ID)
+	if err != nil {
+		return nil, internalError(ctx, "list elements", err)
+	}
+
+	resolvers := make([]*elementResolver, 0, len(elements))
+	for i := range elements {
+		if args.Type != nil && string(elements[i].ElementType) != *args.Type {
+			continue
+		}
+		resolvers = append(resolvers, &elementResolver{element: &elements[i]})
+	}
+	return resolvers, nil
+}
+
+type snapshotsArgs struct {
+	Tag    *string
+	Pinned *bool
+	Limit  int32
+	Offset int32
+}
+
+// snapshotFilter is a snapshot list filter without pointers, so the
+// workspaces of a list that ask for the same page share a loader key
+type snapshotFilter struct {
+	tag       string
+	limit     int
+	offset    int
+	pinned    bool
+	pinnedSet bool
+}
+
+func (f snapshotFilter) listFilter() models.SnapshotListFilter {
+	filter := models.SnapshotListFilter{Tag: f.tag, Limit: f.limit, Offset: f.offset}
+	if f.pinnedSet {
+		filter.Pinned = &f.pinned
+	}
+	return filter
+}
+
+// Snapshots loads the snapshot pages of all workspaces of a list together
+func (w *workspaceResolver) Snapshots(ctx context.Context, args snapshotsArgs) (*snapshotPageResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.r.checkIPAllowlist(ctx, v, w.workspace.ID); err != nil {
+		return nil, err
+	}
+
+	filter := snapshotFilter{
+		limit:  clampLimit(args.Limit, maxSnapshotLimit),
+		offset: clampOffset(args.Offset),
+	}
+	if args.Tag != nil {
+		filter.tag = *args.Tag
+	}
+	if args.Pinned != nil {
+		filter.pinned, filter.pinnedSet = *args.Pinned, true
+	}
+
+	page, err := v.loaders.snapshots.Load(ctx, pageKey[snapshotFilter]{filter: filter, workspaceID: w.workspace.ID})
+	if err != nil {
+		return nil, internalError(ctx, "list snapshots", err)
+	}
+	if page == nil {
+		page = &models.SnapshotPage{}
+	}
+
+	items := make([]*snapshotResolver, len(page.Snapshots))
+	for i := range page.Snapshots {
+		items[i] = &snapshotResolver{snapshot: &page.Snapshots[i]}
+	}
+	return &snapshotPageResolver{total: page.Total, items: items}, nil
+}
+
+type assetsArgs struct {
+	Query       *string
+	ContentType *string
+	Limit       int32
+	Offset      int32
+}
+
+// Assets loads the asset pages of all workspaces of a list together
+func (w *workspaceResolver) Assets(ctx context.Context, args assetsArgs) (*assetPageResolver, error) {
+	v, err := viewerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.r.checkIPAllowlist(ctx, v, w.workspace.ID); err != nil {
+		return nil, err
+	}
+
+	filter := models.AssetListFilter{
+		Limit:  clampLimit(args.Limit, maxAssetLimit),
+		Offset: clampOffset(args.Offset),
+	}
+	if args.Query != nil {
+		filter.Query = *args.Query
+	}
+	if args.ContentType != nil {
+		filter.ContentType = *args.ContentType
+	}
+
+	page, err := v.loaders.assets.Load(ctx, pageKey[models.AssetListFilter]{filter: filter, workspaceID: w.workspace.ID})
+	if err != nil {
+		return nil, internalError(ctx, "list assets", err)
+	}
+	if page == nil {
+		page = &models.AssetPage{}
+	}
+
+	// The loader caches the page for the rest of the request, so the URLs
+	// are signed on copies
+	items := make([]*assetResolver, len(page.Assets))
+	for i := range page.Assets {
+		asset := page.Assets[i]
+		asset.Variants = maps.Clone(asset.Variants)
+		if err := w.r.assetService.SignAsset(ctx, &asset); err != nil {
+			return nil, internalError(ctx, "sign asset URLs", err)
+		}
+		items[i] = &assetResolver{asset: &asset}
+	}
+	return &assetPageResolver{total: page.Total, items: items}, nil
+}
+
+type workspacePageResolver struct {
+	items []*workspaceResolver
+	total int
+}
+
+func (p *workspacePageResolver) Total() int32                { return int32(p.total) } //nolint:gosec // page totals fit
+func (p *workspacePageResolver) Items() []*workspaceResolver { return p.items }
+
+type memberResolver struct {
+	member *models.WorkspaceMemberWithUser
+}
+
+func (m *memberResolver) ID() graphqlgo.ID    { return graphqlgo.ID(m.member.ID.String()) }
+func (m *memberResolver) User() *userResolver { return &userResolver{user: &m.member.User} }
+func (m *memberResolver) Role() string        { return roleEnum(m.member.Role) }
+
+func (m *memberResolver) JoinedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: m.member.JoinedAt}
+}
+
+type elementResolver struct {
+	element *models.CanvasElement
+}
+
+func (e *elementResolver) ID() graphqlgo.ID        { return graphqlgo.ID(e.element.ID.String()) }
+func (e *elementResolver) Type() string            { return string(e.element.ElementType) }
+func (e *elementResolver) Data() JSON              { return JSON{Value: e.element.ElementData} }
+func (e *elementResolver) ZIndex() int32           { return int32(e.element.ZIndex) } //nolint:gosec // z-indexes fit
+func (e *elementResolver) ParentID() *graphqlgo.ID { return optionalID(e.element.ParentID) }
+
+func (e *elementResolver) CreatedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, e.element.CreatedBy)
+}
+
+func (e *elementResolver) UpdatedBy(ctx context.Context) (*userResolver, error) {
+	if e.element.UpdatedBy == nil {
+		return nil, nil
+	}
+	return loadUser(ctx, *e.element.UpdatedBy)
+}
+
+func (e *elementResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: e.element.CreatedAt}
+}
+
+func (e *elementResolver) UpdatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: e.element.UpdatedAt}
+}
+
+type snapshotResolver struct {
+	snapshot *models.CanvasSnapshot
+}
+
+func (s *snapshotResolver) ID() graphqlgo.ID     { return graphqlgo.ID(s.snapshot.ID.String()) }
+func (s *snapshotResolver) Version() int32       { return int32(s.snapshot.Version) } //nolint:gosec // versions fit
+func (s *snapshotResolver) Name() *string        { return s.snapshot.Name }
+func (s *snapshotResolver) Description() *string { return s.snapshot.Description }
+func (s *snapshotResolver) Pinned() bool         { return s.snapshot.Pinned }
+func (s *snapshotResolver) ElementCount() int32  { return int32(s.snapshot.ElementCount) } //nolint:gosec // counts fit
+
+func (s *snapshotResolver) Tags() []string {
+	if s.snapshot.Tags == nil {
+		return []string{}
+	}
+	return s.snapshot.Tags
+}
+
+func (s *snapshotResolver) CreatedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, s.snapshot.CreatedBy)
+}
+
+func (s *snapshotResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: s.snapshot.CreatedAt}
+}
+
+type snapshotPageResolver struct {
+	items []*snapshotResolver
+	total int
+}
+
+func (p *snapshotPageResolver) Total() int32               { return int32(p.total) } //nolint:gosec // page totals fit
+func (p *snapshotPageResolver) Items() []*snapshotResolver { return p.items }
+
+type assetResolver struct {
+	asset *models.Asset
+}
+
+func (a *assetResolver) ID() graphqlgo.ID      { return graphqlgo.ID(a.asset.ID.String()) }
+func (a *assetResolver) Filename() string      { return a.asset.Filename }
+func (a *assetResolver) ContentType() string   { return a.asset.ContentType }
+func (a *assetResolver) URL() string           { return a.asset.URL }
+func (a *assetResolver) ThumbnailURL() *string { return a.asset.ThumbnailURL }
+func (a *assetResolver) Size() float64         { return float64(a.asset.Size) }
+func (a *assetResolver) Width() *int32         { return optionalInt(a.asset.Width) }
+func (a *assetResolver) Height() *int32        { return optionalInt(a.asset.Height) }
+func (a *assetResolver) Status() string        { return a.asset.Status }
+func (a *assetResolver) UsageCount() int32     { return int32(a.asset.UsageCount) } //nolint:gosec // counts fit
+
+func (a *assetResolver) UploadedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, a.asset.UploadedBy)
+}
+
+func (a *assetResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: a.asset.CreatedAt}
+}
+
+type assetPageResolver struct {
+	items []*assetResolver
+	total int
+}
+
+func (p *assetPageResolver) Total() int32            { return int32(p.total) } //nolint:gosec // page totals fit
+func (p *assetPageResolver) Items() []*assetResolver { return p.items }
+
+func optionalInt(value *int) *int32 {
+	if value == nil {
+		return nil
+	}
+	converted := int32(*value) //nolint:gosec // image dimensions fit
+	return &converted
+}
@@ -0,0 +1,444 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 200
+
+	defaultSuppressionLimit = 50
+	maxSuppressionLimit     = 200
+)
+
+type AdminHandler struct {
+	emailService *service.EmailService
+	adminService *service.AdminService
+	configReload *service.ConfigReloadService
+}
+
+func NewAdminHandler(
+	emailService *service.EmailService,
+	adminService *service.AdminService,
+	configReload *service.ConfigReloadService,
+) *AdminHandler {
+	return &AdminHandler{
+		emailService: emailService,
+		adminService: adminService,
+		configReload: configReload,
+	}
+}
+
+// ListDeadLetterEmails godoc
+// @Summary List dead-lettered emails
+// @Description Returns emails that failed every delivery attempt, newest first
+// @Tags admin
+// @Produce json
+// @Param before query int false "Only return dead letters with a lower sequence"
+// @Param limit query int false "Maximum number of dead letters (default 50, max 200)"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/emails/dead-letters [get]
+func (h *AdminHandler) ListDeadLetterEmails(ctx context.Context, c *app.RequestContext) {
+	before, _ := strconv.ParseUint(c.Query("before"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+	if limit > maxDeadLetterLimit {
+		limit = maxDeadLetterLimit
+	}
+
+	deadLetters, err := h.emailService.ListDeadLetters(before, limit)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list dead letter emails: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list dead letter emails"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"dead_letters": deadLetters})
+}
+
+// RequeueDeadLetterEmail godoc
+// @Summary Requeue a dead-lettered email
+// @Description Puts the email back on the queue with a fresh set of attempts
+// @Tags admin
+// @Produce json
+// @Param sequence path int true "Dead letter sequence"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/emails/dead-letters/{sequence}/requeue [post]
+func (h *AdminHandler) RequeueDeadLetterEmail(ctx context.Context, c *app.RequestContext) {
+	sequence, err := strconv.ParseUint(c.Param("sequence"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid sequence"})
+		return
+	}
+
+	if err := h.emailService.RequeueDeadLetter(sequence); err != nil {
+		respondDeadLetterError(ctx, c, "Failed to requeue dead letter email", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Email requeued successfully"})
+}
+
+// DeleteDeadLetterEmail godoc
+// @Summary Discard a dead-lettered email
+// @Tags admin
+// @Produce json
+// @Param sequence path int true "Dead letter sequence"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/emails/dead-letters/{sequence} [delete]
+func (h *AdminHandler) DeleteDeadLetterEmail(ctx context.Context, c *app.RequestContext) {
+	sequence, err := strconv.ParseUint(c.Param("sequence"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid sequence"})
+		return
+	}
+
+	if err := h.emailService.DeleteDeadLetter(sequence); err != nil {
+		respondDeadLetterError(ctx, c, "Failed to delete dead letter email", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Dead letter deleted successfully"})
+}
+
+func respondDeadLetterError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	if errors.Is(err, service.ErrDeadLetterNotFound) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Dead letter not found"})
+		return
+	}
+
+	hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+	c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": msg})
+}
+
+// ListEmailSuppressions godoc
+// @Summary List suppressed email addresses
+// @Description Returns addresses that hard bounced or complained, most recently updated first
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum number of suppressions (default 50, max 200)"
+// @Param offset query int false "Number of suppressions to skip"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/emails/suppressions [get]
+func (h *AdminHandler) ListEmailSuppressions(ctx context.Context, c *app.RequestContext) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	if limit <= 0 {
+		limit = defaultSuppressionLimit
+	}
+	if limit > maxSuppressionLimit {
+		limit = maxSuppressionLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	suppressions, err := h.emailService.ListSuppressions(ctx, limit, offset)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list email suppressions: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list email suppressions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"suppressions": suppressions})
+}
+
+// DeleteEmailSuppression godoc
+// @Summary Lift an email suppression
+// @Description Allows emails to the address again
+// @Tags admin
+// @Produce json
+// @Param email path string true "Email address"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/emails/suppressions/{email} [delete]
+func (h *AdminHandler) DeleteEmailSuppression(ctx context.Context, c *app.RequestContext) {
+	if err := h.emailService.Unsuppress(ctx, c.Param("email")); err != nil {
+		if errors.Is(err, service.ErrEmailSuppressionNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Email suppression not found"})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to delete email suppression: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete email suppression"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Email suppression lifted"})
+}
+
+// ListWorkspaces godoc
+// @Summary List all workspaces
+// @Description Returns the workspaces of all users with their owner, counts and storage usage
+// @Tags admin
+// @Produce json
+// @Param q query string false "Search workspace names and owner emails"
+// @Param sort_by query string false "created_at (default), updated_at or storage_used_bytes"
+// @Param limit query int false "Maximum number of workspaces (default 50, max 200)"
+// @Param offset query int false "Number of workspaces to skip"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/workspaces [get]
+func (h *AdminHandler) ListWorkspaces(ctx context.Context, c *app.RequestContext) {
+	var filter models.AdminWorkspaceListFilter
+	if err := c.BindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid query parameters"})
+		return
+	}
+
+	workspaces, total, err := h.adminService.ListWorkspaces(ctx, filter)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list workspaces: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list workspaces"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"workspaces": workspaces, "total": total})
+}
+
+// DeleteWorkspace godoc
+// @Summary Force-delete a workspace
+// @Description Deletes a workspace of any user, e.g. for abusive content
+// @Tags admin
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/workspaces/{workspace_id} [delete]
+func (h *AdminHandler) DeleteWorkspace(ctx context.Context, c *app.RequestContext) {
+	adminID, workspaceID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.DeleteWorkspace(ctx, adminID, workspaceID); err != nil {
+		respondAdminError(ctx, c, "Failed to delete workspace", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Workspace deleted successfully"})
+}
+
+// DeleteElement godoc
+// @Summary Force-delete an element
+// @Description Deletes an element and its children from any board and removes it from connected clients
+// @Tags admin
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param element_id path string true "Element ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/workspaces/{workspace_id}/elements/{element_id} [delete]
+func (h *AdminHandler) DeleteElement(ctx context.Context, c *app.RequestContext) {
+	adminID, workspaceID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	elementID, err := uuid.Parse(c.Param("element_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid element ID"})
+		return
+	}
+
+	if err := h.adminService.DeleteElement(ctx, adminID, workspaceID, elementID); err != nil {
+		respondAdminError(ctx, c, "Failed to delete element", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Element deleted successfully"})
+}
+
+// PurgeAsset godoc
+// @Summary Force-delete an asset
+// @Description Permanently deletes an asset and its stored files right away, even if it is on the canvas
+// @Tags admin
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param asset_id path string true "Asset ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/workspaces/{workspace_id}/assets/{asset_id} [delete]
+func (h *AdminHandler) PurgeAsset(ctx context.Context, c *app.RequestContext) {
+	adminID, workspaceID, ok := adminTarget(c)
+	if !ok {
+		return
+	}
+
+	assetID, err := uuid.Parse(c.Param("asset_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid asset ID"})
+		return
+	}
+
+	if err := h.adminService.PurgeAsset(ctx, adminID, workspaceID, assetID); err != nil {
+		respondAdminError(ctx, c, "Failed to purge asset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Asset purged successfully"})
+}
+
+// ListRealtimeRooms godoc
+// @Summary List realtime rooms
+// @Description Returns the rooms and connected clients of every realtime instance. Instances report every 30 seconds.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.RealtimeRoomsResponse
+//
+// @Router /api/v1/admin/realtime/rooms [get]
+func (h *AdminHandler) ListRealtimeRooms(ctx context.Context, c *app.RequestContext) {
+	rooms, err := h.adminService.ListRealtimeRooms(ctx)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list realtime rooms: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list realtime rooms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rooms)
+}
+
+// BroadcastMaintenance godoc
+// @Summary Broadcast a maintenance notice
+// @Description Sends a maintenance message to every client connected to any realtime instance
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body models.MaintenanceNoticeRequest true "Notice"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/realtime/maintenance [post]
+func (h *AdminHandler) BroadcastMaintenance(ctx context.Context, c *app.RequestContext) {
+	adminID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.MaintenanceNoticeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.adminService.BroadcastMaintenance(ctx, adminID, &req); err != nil {
+		respondAdminError(ctx, c, "Failed to broadcast maintenance notice", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Maintenance notice sent"})
+}
+
+// ListJobs godoc
+// @Summary List background jobs
+// @Description Returns the background jobs that can be run on demand
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/jobs [get]
+func (h *AdminHandler) ListJobs(_ context.Context, c *app.RequestContext) {
+	c.JSON(http.StatusOK, map[string]interface{}{"jobs": h.adminService.ListJobs()})
+}
+
+// RunJob godoc
+// @Summary Run a background job
+// @Description Starts a background job now instead of at its next scheduled run. The job runs in the background.
+// @Tags admin
+// @Produce json
+// @Param job path string true "Job name"
+// @Success 202 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/jobs/{job}/run [post]
+func (h *AdminHandler) RunJob(ctx context.Context, c *app.RequestContext) {
+	adminID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.adminService.RunJob(ctx, adminID, c.Param("job")); err != nil {
+		respondAdminError(ctx, c, "Failed to run job", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]interface{}{"message": "Job started"})
+}
+
+// ReloadConfig godoc
+// @Summary Reload the configuration
+// @Description Reads the config file again on every instance and applies the settings that can change at runtime: rate limits, CORS, the log level and the auth policies. Other changed sections are listed as needing a restart. An invalid file is rejected and the current settings kept.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} config.ReloadResult
+// @Failure 422 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/config/reload [post]
+func (h *AdminHandler) ReloadConfig(ctx context.Context, c *app.RequestContext) {
+	adminID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	result, err := h.configReload.Reload(ctx, adminID)
+	if err != nil {
+		hlog.CtxWarnf(ctx, "Failed to reload config: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Failed to reload config",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// adminTarget returns the admin and the workspace of a moderation request
+func adminTarget(c *app.RequestContext) (adminID, workspaceID uuid.UUID, ok bool) {
+	adminID, ok = getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return adminID, workspaceID, true
+}
+
+func respondAdminError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrAdminContentNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Not found"})
+	case errors.Is(err, service.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Job not found"})
+	case errors.Is(err, service.ErrJobPending):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
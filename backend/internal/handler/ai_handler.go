@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type AIHandler struct {
+	aiService *service.AIService
+}
+
+func NewAIHandler(aiService *service.AIService) *AIHandler {
+	return &AIHandler{
+		aiService: aiService,
+	}
+}
+
+// Summarize godoc
+// @Summary Summarize a board
+// @Description Summarizes the text of the board or of the selected elements with the configured language model.
+// @Description With create the summary is added to the board as a text element marked ai_generated.
+// @Tags ai
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.AISummarizeRequest true "Selection"
+// @Success 200 {object} models.AISummarizeResponse
+// @Failure 423 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/ai/summarize [post]
+func (h *AIHandler) Summarize(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.AISummarizeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	resp, err := h.aiService.Summarize(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondAIError(ctx, c, "Failed to summarize board", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Cluster godoc
+// @Summary Cluster sticky notes
+// @Description Groups the sticky notes of the board or of the selection by theme. With create a text element
+// @Description listing the notes of each cluster is added to the board, marked ai_generated.
+// @Tags ai
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.AIClusterRequest true "Selection"
+// @Success 200 {object} models.AIClusterResponse
+// @Failure 423 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/ai/cluster [post]
+func (h *AIHandler) Cluster(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.AIClusterRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	resp, err := h.aiService.Cluster(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondAIError(ctx, c, "Failed to cluster sticky notes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GenerateIdeas godoc
+// @Summary Generate ideas
+// @Description Suggests new ideas about a topic, or the content of the board or selection. With create each idea
+// @Description is added to the board as a sticky note marked ai_generated.
+// @Tags ai
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.AIIdeasRequest true "Topic and selection"
+// @Success 200 {object} models.AIIdeasResponse
+// @Failure 423 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/ai/ideas [post]
+func (h *AIHandler) GenerateIdeas(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.AIIdeasRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	resp, err := h.aiService.GenerateIdeas(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondAIError(ctx, c, "Failed to generate ideas", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// aiRequestIDs returns the workspace and user of a request and responds
+// when they are missing
+func aiRequestIDs(c *app.RequestContext) (workspaceID, userID uuid.UUID, ok bool) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	value, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok = value.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return workspaceID, userID, true
+}
+
+func respondAIError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrBoardFrozen):
+		c.JSON(http.StatusLocked, map[string]interface{}{"error": err.Error(), "code": "board_frozen"})
+	case errors.Is(err, service.ErrAINoContent):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrAIProvider):
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadGateway, map[string]interface{}{"error": "The AI provider is unavailable, try again later"})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
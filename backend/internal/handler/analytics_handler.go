@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *service.WorkspaceAnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *service.WorkspaceAnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+// GetWorkspaceAnalytics godoc
+// @Summary Get workspace analytics
+// @Description Returns daily active collaborators, edits per day, edits by hour of the day, element counts per day
+// @Description and views and unique viewers per day of a workspace, in UTC
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param days query int false "Number of days up to today (default 30, max 365)"
+// @Success 200 {object} models.WorkspaceAnalytics
+// @Failure 503 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/analytics [get]
+func (h *AnalyticsHandler) GetWorkspaceAnalytics(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	days := service.DefaultAnalyticsDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxAnalyticsDays {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "days must be between 1 and " + strconv.Itoa(service.MaxAnalyticsDays),
+			})
+			return
+		}
+		days = parsed
+	}
+
+	var analytics *models.WorkspaceAnalytics
+	analytics, err := h.analyticsService.GetWorkspaceAnalytics(ctx, workspaceID, days)
+	if err != nil {
+		if errors.Is(err, service.ErrAnalyticsDisabled) {
+			c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"error": "Analytics are not enabled"})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to get workspace analytics: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get workspace analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
+
+// GetWorkspaceHeatmap godoc
+// @Summary Get the cursor heatmap of a workspace
+// @Description Returns how many sampled cursor positions fell in each cell of a grid over the area of the board
+// @Description they covered, showing which areas got attention. Selections count at the cursor they were made with.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param from query string false "Start of the range, RFC3339 (default 7 days before to)"
+// @Param to query string false "End of the range, RFC3339 (default now)"
+// @Param kind query string false "Only cursor moves or selections" Enums(cursor, selection)
+// @Param resolution query int false "Cells along the longer side of the grid (default 64, max 256)"
+// @Success 200 {object} models.Heatmap
+// @Failure 400 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/analytics/heatmap [get]
+func (h *AnalyticsHandler) GetWorkspaceHeatmap(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid time range"})
+		return
+	}
+	if c.Query("from") == "" {
+		from = to.AddDate(0, 0, -service.DefaultHeatmapDays)
+	}
+	if to.Sub(from) > service.MaxAnalyticsDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "time range must be at most " + strconv.Itoa(service.MaxAnalyticsDays) + " days",
+		})
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && kind != models.CursorSampleMove && kind != models.CursorSampleSelection {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "kind must be cursor or selection"})
+		return
+	}
+
+	resolution := service.DefaultHeatmapResolution
+	if value := c.Query("resolution"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxHeatmapResolution {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "resolution must be between 1 and " + strconv.Itoa(service.MaxHeatmapResolution),
+			})
+			return
+		}
+		resolution = parsed
+	}
+
+	heatmap, err := h.analyticsService.GetHeatmap(ctx, workspaceID, from, to, kind, resolution)
+	if err != nil {
+		if errors.Is(err, service.ErrAnalyticsDisabled) {
+			c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"error": "Analytics are not enabled"})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to get workspace heatmap: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get workspace heatmap"})
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates a key automation platforms such as Zapier and Make use to poll triggers and subscribe REST hooks.
+// @Description The key acts as the calling user in this workspace and is only returned here.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateAPIKeyRequest true "Key name"
+// @Success 201 {object} models.APIKeyWithSecret
+//
+// @Router /api/v1/workspaces/{workspace_id}/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		respondAPIKeyError(ctx, c, "Failed to create API key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Tags api-keys
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(ctx, workspaceID)
+	if err != nil {
+		respondAPIKeyError(ctx, c, "Failed to list API keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"api_keys": keys})
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an API key
+// @Tags api-keys
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param key_id path string true "API key ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/api-keys/{key_id} [delete]
+func (h *APIKeyHandler) DeleteAPIKey(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid API key ID"})
+		return
+	}
+
+	if err := h.apiKeyService.DeleteAPIKey(ctx, workspaceID, keyID); err != nil {
+		respondAPIKeyError(ctx, c, "Failed to delete API key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "API key revoked successfully"})
+}
+
+func respondAPIKeyError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "API key not found"})
+	case errors.Is(err, service.ErrAPIKeyLimitReached):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -9,9 +11,15 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
 	"github.com/bifshteksex/hertz-board/internal/service"
 )
 
+const (
+	defaultAssetListLimit = 50
+	maxAssetListLimit     = 200
+)
+
 type AssetHandler struct {
 	assetService *service.AssetService
 }
@@ -47,56 +55,198 @@ func (h *AssetHandler) UploadAsset(ctx context.Context, c *app.RequestContext) {
 		return
 	}
 
-	// Get uploaded file
-	fileHeader, err := c.FormFile("file")
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	// The file is read from the request as it arrives and never held in
+	// memory as a whole
+	part, err := multipartFile(c, "file")
 	if err != nil {
+		hlog.CtxWarnf(ctx, "Invalid asset upload: %v", err)
 		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "No file uploaded"})
 		return
 	}
+	defer part.Close()
 
 	// Validate content type
-	contentType := fileHeader.Header.Get("Content-Type")
+	contentType := part.Header.Get("Content-Type")
 	if !h.assetService.ValidateContentType(contentType) {
-		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Unsupported file type. Only images are allowed."})
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Unsupported file type. Only images, videos and PDFs are allowed."})
 		return
 	}
 
-	// Open file
-	file, err := fileHeader.Open()
+	asset, err := h.assetService.UploadAssetStream(ctx, workspaceID, userUUID, part.FileName(), contentType, part)
 	if err != nil {
-		hlog.CtxErrorf(ctx, "Failed to open uploaded file: %v", err)
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to process file"})
+		hlog.CtxErrorf(ctx, "Failed to upload asset: %v", err)
+		switch {
+		case errors.Is(err, repository.ErrStorageQuotaExceeded):
+			respondQuotaExceeded(c)
+		case errors.Is(err, service.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		}
+		return
+	}
+
+	h.respondWithAsset(ctx, c, http.StatusCreated, asset)
+}
+
+// PresignUpload godoc
+// @Summary Get a presigned upload URL
+// @Description Validates file metadata and returns a presigned URL to upload the file directly to storage
+// @Tags assets
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.UploadAssetRequest true "File metadata"
+// @Success 200 {object} models.PresignedUploadResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/assets/presign [post]
+func (h *AssetHandler) PresignUpload(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	var req models.UploadAssetRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	presigned, err := h.assetService.PresignUpload(ctx, workspaceID, &req)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to presign upload: %v", err)
+		if errors.Is(err, repository.ErrStorageQuotaExceeded) {
+			respondQuotaExceeded(c)
+			return
+		}
+		if errors.Is(err, service.ErrFileTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, presigned)
+}
+
+// ConfirmUpload godoc
+// @Summary Confirm a presigned upload
+// @Description Validates a file uploaded through a presigned URL and creates the asset
+// @Tags assets
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.ConfirmUploadRequest true "Uploaded object"
+// @Success 201 {object} models.AssetResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/assets/confirm [post]
+func (h *AssetHandler) ConfirmUpload(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
 		return
 	}
-	defer file.Close()
 
-	// Upload asset
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
 		return
 	}
 
-	asset, err := h.assetService.UploadAsset(
-		ctx,
-		workspaceID,
-		userUUID,
-		fileHeader.Filename,
-		contentType,
-		fileHeader.Size,
-		file,
-	)
+	var req models.ConfirmUploadRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil || req.ObjectKey == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "object_key is required"})
+		return
+	}
+
+	asset, err := h.assetService.ConfirmUpload(ctx, workspaceID, userUUID, &req)
 	if err != nil {
-		hlog.CtxErrorf(ctx, "Failed to upload asset: %v", err)
-		if fileHeader.Size > 10*1024*1024 {
-			c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": "File too large. Maximum size is 10MB."})
-		} else {
+		hlog.CtxErrorf(ctx, "Failed to confirm upload: %v", err)
+		if errors.Is(err, repository.ErrStorageQuotaExceeded) {
+			respondQuotaExceeded(c)
+			return
+		}
+		if errors.Is(err, service.ErrFileTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	h.respondWithAsset(ctx, c, http.StatusCreated, asset)
+}
+
+// ImportAsset godoc
+// @Summary Import an image from a URL
+// @Description Downloads a remote image on the server and stores it as a workspace asset
+// @Tags assets
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.ImportAssetRequest true "Image URL"
+// @Success 201 {object} models.AssetResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/assets/from-url [post]
+func (h *AssetHandler) ImportAsset(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.ImportAssetRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil || req.URL == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "url is required"})
+		return
+	}
+
+	asset, err := h.assetService.ImportAssetFromURL(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to import asset from %s: %v", req.URL, err)
+		switch {
+		case errors.Is(err, repository.ErrStorageQuotaExceeded):
+			respondQuotaExceeded(c)
+		case errors.Is(err, service.ErrBlockedAddress):
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "URL points to a non-public address"})
+		case errors.Is(err, service.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": err.Error()})
+		default:
 			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
 		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, asset.ToResponse())
+	h.respondWithAsset(ctx, c, http.StatusCreated, asset)
 }
 
 // GetAsset godoc
@@ -111,23 +261,62 @@ func (h *AssetHandler) UploadAsset(ctx context.Context, c *app.RequestContext) {
 //
 // @Router /api/v1/workspaces/{workspace_id}/assets/{asset_id} [get]
 func (h *AssetHandler) GetAsset(ctx context.Context, c *app.RequestContext) {
-	handleGetByID(ctx, c, "asset_id", func(ctx context.Context, id uuid.UUID) (interface{}, error) {
-		asset, err := h.assetService.GetAsset(ctx, id)
-		if err != nil {
-			return nil, err
-		}
-		return asset.ToResponse(), nil
-	}, "Failed to get asset")
+	asset, ok := h.getWorkspaceAsset(ctx, c)
+	if !ok {
+		return
+	}
+
+	h.respondWithAsset(ctx, c, http.StatusOK, asset)
+}
+
+// GetAssetContent godoc
+// @Summary Download asset content
+// @Description Redirects to a short-lived presigned URL for the asset file, its thumbnail or an optimized variant.
+// @Description Accepts the access token as a query parameter so it can be used directly in img/video tags.
+// @Tags assets
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param asset_id path string true "Asset ID"
+// @Param variant query string false "thumbnail or a variant key such as webp_640"
+// @Param token query string false "Access token"
+// @Success 302
+//
+// @Router /api/v1/workspaces/{workspace_id}/assets/{asset_id}/content [get]
+func (h *AssetHandler) GetAssetContent(ctx context.Context, c *app.RequestContext) {
+	asset, ok := h.getWorkspaceAsset(ctx, c)
+	if !ok {
+		return
+	}
+
+	contentURL, err := h.assetService.ContentURL(ctx, asset, c.Query("variant"))
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get asset content URL: %v", err)
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Asset content not found"})
+		return
+	}
+
+	// Let the browser reuse the redirect while the target URL is still valid
+	const cacheFraction = 2
+	maxAge := int(h.assetService.SignedURLExpiry().Seconds()) / cacheFraction
+	c.Response.Header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	c.Redirect(http.StatusFound, []byte(contentURL))
 }
 
 // GetWorkspaceAssets godoc
-// @Summary Get all assets in a workspace
-// @Description Retrieves all assets for a workspace
+// @Summary List workspace assets
+// @Description Retrieves a page of workspace assets with optional filtering, search and sorting
 // @Tags assets
 // @Accept json
 // @Produce json
 // @Param workspace_id path string true "Workspace ID"
-// @Success 200 {object} map[string][]models.AssetResponse
+// @Param q query string false "Search filenames and the text recognized in images"
+// @Param content_type query string false "Content type, or a family such as image/*"
+// @Param uploaded_by query string false "Uploader user ID"
+// @Param sort_by query string false "created_at, size or filename" default(created_at)
+// @Param sort_order query string false "asc or desc" default(desc)
+// @Param limit query int false "Number of results" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} models.AssetListResponse
 //
 // @Router /api/v1/workspaces/{workspace_id}/assets [get]
 func (h *AssetHandler) GetWorkspaceAssets(ctx context.Context, c *app.RequestContext) {
@@ -138,7 +327,25 @@ func (h *AssetHandler) GetWorkspaceAssets(ctx context.Context, c *app.RequestCon
 		return
 	}
 
-	assets, err := h.assetService.GetWorkspaceAssets(ctx, workspaceID)
+	var filter models.AssetListFilter
+	if bindErr := c.BindQuery(&filter); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid query parameters"})
+		return
+	}
+	if filter.Limit <= 0 || filter.Limit > maxAssetListLimit {
+		filter.Limit = defaultAssetListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.UploadedBy != "" {
+		if _, parseErr := uuid.Parse(filter.UploadedBy); parseErr != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid uploaded_by"})
+			return
+		}
+	}
+
+	assets, total, err := h.assetService.GetWorkspaceAssets(ctx, workspaceID, filter)
 	if err != nil {
 		hlog.CtxErrorf(ctx, "Failed to get workspace assets: %v", err)
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get assets"})
@@ -148,32 +355,71 @@ func (h *AssetHandler) GetWorkspaceAssets(ctx context.Context, c *app.RequestCon
 	// Convert to response
 	responses := make([]models.AssetResponse, len(assets))
 	for i := range assets {
+		if err := h.assetService.SignAsset(ctx, &assets[i]); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to sign asset URLs: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get assets"})
+			return
+		}
 		responses[i] = assets[i].ToResponse()
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"assets": responses,
-		"total":  len(responses),
+	c.JSON(http.StatusOK, models.AssetListResponse{
+		Assets: responses,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
 	})
 }
 
 // DeleteAsset godoc
 // @Summary Delete an asset
-// @Description Soft deletes an asset
+// @Description Soft deletes an asset. Assets still used on the canvas are only deleted with force=true.
 // @Tags assets
 // @Accept json
 // @Produce json
 // @Param workspace_id path string true "Workspace ID"
 // @Param asset_id path string true "Asset ID"
+// @Param force query bool false "Delete even if canvas elements still use the asset"
+// @Failure 409 {object} map[string]interface{}
 //
 // @Router /api/v1/workspaces/{workspace_id}/assets/{asset_id} [delete]
 func (h *AssetHandler) DeleteAsset(ctx context.Context, c *app.RequestContext) {
-	handleDeleteByID(ctx, c, "asset_id", h.assetService.DeleteAsset, "Failed to delete asset", "Asset deleted successfully")
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	assetID, err := uuid.Parse(c.Param("asset_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid asset_id"})
+		return
+	}
+
+	force := c.Query("force") == "true"
+	if err := h.assetService.DeleteAsset(ctx, workspaceID, assetID, force); err != nil {
+		var inUse *service.AssetInUseError
+		if errors.As(err, &inUse) {
+			c.JSON(http.StatusConflict, map[string]interface{}{
+				"error":       "Asset is still used on the canvas. Pass force=true to delete it anyway.",
+				"code":        "asset_in_use",
+				"usage_count": len(inUse.ElementIDs),
+				"used_by":     inUse.ElementIDs,
+			})
+			return
+		}
+
+		hlog.CtxErrorf(ctx, "Failed to delete asset: %v", err)
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Failed to delete asset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Asset deleted successfully"})
 }
 
 // CleanupOrphanedAssets godoc
 // @Summary Cleanup orphaned assets
-// @Description Deletes assets not referenced by any canvas element
+// @Description Deletes assets that no canvas element references
 // @Tags assets
 // @Accept json
 // @Produce json
@@ -201,3 +447,65 @@ func (h *AssetHandler) CleanupOrphanedAssets(ctx context.Context, c *app.Request
 		"count":   count,
 	})
 }
+
+// getWorkspaceAsset loads the asset from the path, making sure it belongs to
+// the workspace the caller was authorized for
+func (h *AssetHandler) getWorkspaceAsset(ctx context.Context, c *app.RequestContext) (*models.Asset, bool) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return nil, false
+	}
+
+	assetID, err := uuid.Parse(c.Param("asset_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid asset_id"})
+		return nil, false
+	}
+
+	asset, err := h.assetService.GetWorkspaceAsset(ctx, workspaceID, assetID)
+	if errors.Is(err, service.ErrAssetQuarantined) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Asset was flagged by the malware scanner and is quarantined",
+			"code":  "asset_quarantined",
+		})
+		return nil, false
+	}
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get asset: %v", err)
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Failed to get asset"})
+		return nil, false
+	}
+
+	return asset, true
+}
+
+// respondWithAsset signs the asset URLs and writes the asset response
+func (h *AssetHandler) respondWithAsset(ctx context.Context, c *app.RequestContext, status int, asset *models.Asset) {
+	respondWithSignedAsset(ctx, c, h.assetService, status, asset)
+}
+
+// respondWithSignedAsset signs the asset URLs and writes the asset response
+func respondWithSignedAsset(
+	ctx context.Context,
+	c *app.RequestContext,
+	assetService *service.AssetService,
+	status int,
+	asset *models.Asset,
+) {
+	if err := assetService.SignAsset(ctx, asset); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to sign asset URLs: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to sign asset URLs"})
+		return
+	}
+
+	c.JSON(status, asset.ToResponse())
+}
+
+// respondQuotaExceeded reports that an upload does not fit in the workspace quota
+func respondQuotaExceeded(c *app.RequestContext) {
+	c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+		"error": "Workspace storage quota exceeded. Delete unused assets to free up space.",
+		"code":  "storage_quota_exceeded",
+	})
+}
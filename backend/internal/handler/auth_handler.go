@@ -22,11 +22,22 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 	}
 }
 
-// Register handles user registration
+// Register godoc
+// @Summary Register a user
+// @Description Creates an account with email and password and returns the user with a token pair.
+// @Description accept_terms must be true when terms of service or a privacy policy are configured.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateUserRequest true "Account details"
+// @Success 201 {object} models.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/register [post]
 func (h *AuthHandler) Register(c context.Context, ctx *app.RequestContext) {
 	var req models.CreateUserRequest
 	resp, statusCode, err := h.bindValidateAndExecute(ctx, &req, func() (interface{}, error) {
-		return h.authService.Register(c, &req)
+		return h.authService.Register(c, &req, ctx.ClientIP())
 	})
 
 	if err != nil {
@@ -39,7 +50,17 @@ func (h *AuthHandler) Register(c context.Context, ctx *app.RequestContext) {
 	ctx.JSON(consts.StatusCreated, resp)
 }
 
-// Login handles user login
+// Login godoc
+// @Summary Log in
+// @Description Checks email and password and returns the user with a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginRequest true "Credentials"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c context.Context, ctx *app.RequestContext) {
 	var req models.LoginRequest
 	resp, statusCode, err := h.bindValidateAndExecute(ctx, &req, func() (interface{}, error) {
@@ -77,7 +98,17 @@ func (h *AuthHandler) bindValidateAndExecute(
 	return resp, consts.StatusOK, nil
 }
 
-// RefreshToken handles token refresh
+// RefreshToken godoc
+// @Summary Refresh tokens
+// @Description Exchanges a refresh token for a new token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Refresh token" SchemaExample({"refresh_token": "..."})
+// @Success 200 {object} models.TokenPair
+// @Failure 401 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c context.Context, ctx *app.RequestContext) {
 	type RefreshRequest struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
@@ -103,7 +134,16 @@ func (h *AuthHandler) RefreshToken(c context.Context, ctx *app.RequestContext) {
 	ctx.JSON(consts.StatusOK, tokens)
 }
 
-// Logout handles user logout
+// Logout godoc
+// @Summary Log out
+// @Description Revokes a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Refresh token" SchemaExample({"refresh_token": "..."})
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/logout [post]
 func (h *AuthHandler) Logout(c context.Context, ctx *app.RequestContext) {
 	type LogoutRequest struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
@@ -130,7 +170,16 @@ func (h *AuthHandler) Logout(c context.Context, ctx *app.RequestContext) {
 	})
 }
 
-// ForgotPassword handles forgot password requests
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Emails a password reset link. The response is the same whether the email exists or not.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/forgot-password [post]
 func (h *AuthHandler) ForgotPassword(c context.Context, ctx *app.RequestContext) {
 	var req models.ForgotPasswordRequest
 	if err := ctx.BindAndValidate(&req); err != nil {
@@ -156,7 +205,17 @@ func (h *AuthHandler) ForgotPassword(c context.Context, ctx *app.RequestContext)
 	})
 }
 
-// ResetPassword handles password reset
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Sets a new password with the token from the reset email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/reset-password [post]
 func (h *AuthHandler) ResetPassword(c context.Context, ctx *app.RequestContext) {
 	var req models.ResetPasswordRequest
 	if err := ctx.BindAndValidate(&req); err != nil {
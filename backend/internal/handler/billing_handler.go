@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type BillingHandler struct {
+	billingService *service.BillingService
+}
+
+func NewBillingHandler(billingService *service.BillingService) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+	}
+}
+
+// GetBilling godoc
+// @Summary Get the current plan
+// @Description Returns the subscription of the current user and the entitlements of their plan.
+// @Description Workspaces get the entitlements of their owner's plan.
+// @Tags billing
+// @Produce json
+// @Success 200 {object} models.BillingResponse
+//
+// @Router /api/v1/billing [get]
+func (h *BillingHandler) GetBilling(ctx context.Context, c *app.RequestContext) {
+	userID, ok := billingUser(c)
+	if !ok {
+		return
+	}
+
+	billing, err := h.billingService.GetBilling(ctx, userID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get billing: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get billing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, billing)
+}
+
+// CreateCheckoutSession godoc
+// @Summary Buy a plan
+// @Description Starts a Stripe Checkout for the pro or team plan and returns the page to redirect to.
+// @Description The plan applies once Stripe confirms the subscription.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param request body models.CheckoutRequest true "Plan"
+// @Success 200 {object} models.BillingSessionResponse
+//
+// @Router /api/v1/billing/checkout [post]
+func (h *BillingHandler) CreateCheckoutSession(ctx context.Context, c *app.RequestContext) {
+	userID, ok := billingUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.CheckoutRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	session, err := h.billingService.CreateCheckoutSession(ctx, userID, &req)
+	if err != nil {
+		respondBillingError(ctx, c, "Failed to create checkout session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// CreatePortalSession godoc
+// @Summary Manage the subscription
+// @Description Opens the Stripe billing portal, where the plan is changed or canceled
+// @Tags billing
+// @Produce json
+// @Success 200 {object} models.BillingSessionResponse
+//
+// @Router /api/v1/billing/portal [post]
+func (h *BillingHandler) CreatePortalSession(ctx context.Context, c *app.RequestContext) {
+	userID, ok := billingUser(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.billingService.CreatePortalSession(ctx, userID)
+	if err != nil {
+		respondBillingError(ctx, c, "Failed to create billing portal session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// HandleStripeWebhook godoc
+// @Summary Receive Stripe events
+// @Description Subscription webhook of Stripe, authenticated with the Stripe-Signature header
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/webhooks/stripe [post]
+func (h *BillingHandler) HandleStripeWebhook(ctx context.Context, c *app.RequestContext) {
+	signature := string(c.GetHeader("Stripe-Signature"))
+
+	if err := h.billingService.HandleWebhook(ctx, c.Request.Body(), signature); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Invalid signature"})
+		case errors.Is(err, service.ErrInvalidStripeEvent):
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid payload"})
+		default:
+			hlog.CtxErrorf(ctx, "Failed to handle stripe webhook: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to handle stripe webhook"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "ok"})
+}
+
+// billingUser returns the authenticated user, or responds with an error
+func billingUser(c *app.RequestContext) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return uuid.Nil, false
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return uuid.Nil, false
+	}
+
+	return userUUID, true
+}
+
+func respondBillingError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPlan):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrAlreadySubscribed):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrNoSubscription):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "No subscription, buy a plan first"})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": msg})
+	}
+}
+
+// respondPlanLimit reports that an action exceeds the plan of the workspace
+// owner. It returns false for other errors, which the caller reports.
+func respondPlanLimit(c *app.RequestContext, err error) bool {
+	if !errors.Is(err, service.ErrPlanLimitReached) {
+		return false
+	}
+
+	c.JSON(http.StatusPaymentRequired, map[string]interface{}{
+		"error": err.Error(),
+		"code":  "plan_limit_reached",
+	})
+	return true
+}
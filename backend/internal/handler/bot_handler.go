@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type BotHandler struct {
+	botService *service.BotService
+}
+
+func NewBotHandler(botService *service.BotService) *BotHandler {
+	return &BotHandler{
+		botService: botService,
+	}
+}
+
+// CreateBot godoc
+// @Summary Create a bot
+// @Description Creates a bot account for CI jobs and alert integrations. The bot is a member of the workspace
+// @Description and acts through tokens created for it.
+// @Tags bots
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateBotRequest true "Bot name and role"
+// @Success 201 {object} models.Bot
+//
+// @Router /api/v1/workspaces/{workspace_id}/bots [post]
+func (h *BotHandler) CreateBot(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.CreateBotRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	bot, err := h.botService.CreateBot(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		respondBotError(ctx, c, "Failed to create bot", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, bot)
+}
+
+// ListBots godoc
+// @Summary List bots
+// @Tags bots
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/bots [get]
+func (h *BotHandler) ListBots(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	bots, err := h.botService.ListBots(ctx, workspaceID)
+	if err != nil {
+		respondBotError(ctx, c, "Failed to list bots", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"bots": bots})
+}
+
+// DeleteBot godoc
+// @Summary Delete a bot
+// @Description Removes the bot from the workspace and revokes its tokens. Elements it created are kept.
+// @Tags bots
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/bots/{bot_id} [delete]
+func (h *BotHandler) DeleteBot(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	botID, err := uuid.Parse(c.Param("bot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid bot ID"})
+		return
+	}
+
+	if err := h.botService.DeleteBot(ctx, workspaceID, botID); err != nil {
+		respondBotError(ctx, c, "Failed to delete bot", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Bot deleted successfully"})
+}
+
+// CreateBotToken godoc
+// @Summary Create a bot token
+// @Description Creates a token of a bot, limited to its scopes and to this workspace. element_types further limits
+// @Description the elements it may create, e.g. only stickies. The token is only returned here.
+// @Tags bots
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param bot_id path string true "Bot ID"
+// @Param request body models.CreateBotTokenRequest true "Token name, scopes and element types"
+// @Success 201 {object} models.BotTokenWithSecret
+//
+// @Router /api/v1/workspaces/{workspace_id}/bots/{bot_id}/tokens [post]
+func (h *BotHandler) CreateBotToken(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	botID, err := uuid.Parse(c.Param("bot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid bot ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.CreateBotTokenRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	token, err := h.botService.CreateToken(ctx, workspaceID, botID, userUUID, &req)
+	if err != nil {
+		respondBotError(ctx, c, "Failed to create bot token", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// ListBotTokens godoc
+// @Summary List bot tokens
+// @Tags bots
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/bots/{bot_id}/tokens [get]
+func (h *BotHandler) ListBotTokens(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	botID, err := uuid.Parse(c.Param("bot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid bot ID"})
+		return
+	}
+
+	tokens, err := h.botService.ListTokens(ctx, workspaceID, botID)
+	if err != nil {
+		respondBotError(ctx, c, "Failed to list bot tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// DeleteBotToken godoc
+// @Summary Revoke a bot token
+// @Tags bots
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param bot_id path string true "Bot ID"
+// @Param token_id path string true "Token ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/bots/{bot_id}/tokens/{token_id} [delete]
+func (h *BotHandler) DeleteBotToken(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	botID, err := uuid.Parse(c.Param("bot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid bot ID"})
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("token_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := h.botService.DeleteToken(ctx, workspaceID, botID, tokenID); err != nil {
+		respondBotError(ctx, c, "Failed to delete bot token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Bot token revoked successfully"})
+}
+
+func respondBotError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrBotNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Bot not found"})
+	case errors.Is(err, service.ErrBotTokenNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Bot token not found"})
+	case errors.Is(err, service.ErrBotLimitReached):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
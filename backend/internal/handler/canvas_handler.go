@@ -16,12 +16,30 @@ import (
 var ErrInvalidRequestType = errors.New("invalid request type")
 
 type CanvasHandler struct {
-	canvasService *service.CanvasService
+	canvasService  *service.CanvasService
+	commentService *service.CommentService
 }
 
-func NewCanvasHandler(canvasService *service.CanvasService) *CanvasHandler {
+func NewCanvasHandler(canvasService *service.CanvasService, commentService *service.CommentService) *CanvasHandler {
 	return &CanvasHandler{
-		canvasService: canvasService,
+		canvasService:  canvasService,
+		commentService: commentService,
+	}
+}
+
+// addCommentCounts fills in the comment threads of elements. Counts are
+// informational, so a failure leaves them out rather than failing the read.
+func (h *CanvasHandler) addCommentCounts(ctx context.Context, workspaceID uuid.UUID, responses []models.ElementResponse) {
+	counts, err := h.commentService.CountsByElement(ctx, workspaceID)
+	if err != nil {
+		hlog.CtxWarnf(ctx, "Failed to count comments of workspace %s: %v", workspaceID, err)
+		return
+	}
+
+	for i := range responses {
+		if count, ok := counts[responses[i].ID]; ok {
+			responses[i].Comments = &count
+		}
 	}
 }
 
@@ -61,7 +79,7 @@ func (h *CanvasHandler) processBatchElementRequest(
 
 // GetWorkspaceElements godoc
 // @Summary Get all elements in a workspace
-// @Description Retrieves all canvas elements for a workspace
+// @Description Retrieves all canvas elements for a workspace. Public workspaces that allow anonymous viewing can be read without a token.
 // @Tags canvas
 // @Accept json
 // @Produce json
@@ -89,6 +107,7 @@ func (h *CanvasHandler) GetWorkspaceElements(ctx context.Context, c *app.Request
 	for i := range elements {
 		responses[i] = elements[i].ToResponse()
 	}
+	h.addCommentCounts(ctx, workspaceID, responses)
 
 	c.JSON(http.StatusOK, models.ElementListResponse{
 		Elements: responses,
@@ -130,7 +149,7 @@ func (h *CanvasHandler) CreateElement(ctx context.Context, c *app.RequestContext
 
 // GetElement godoc
 // @Summary Get a canvas element by ID
-// @Description Retrieves a specific canvas element
+// @Description Retrieves a specific canvas element. Public workspaces that allow anonymous viewing can be read without a token.
 // @Tags canvas
 // @Accept json
 // @Produce json
@@ -145,7 +164,9 @@ func (h *CanvasHandler) GetElement(ctx context.Context, c *app.RequestContext) {
 		if err != nil {
 			return nil, err
 		}
-		return element.ToResponse(), nil
+		responses := []models.ElementResponse{element.ToResponse()}
+		h.addCommentCounts(ctx, element.WorkspaceID, responses)
+		return responses[0], nil
 	}, "Failed to get element")
 }
 
@@ -290,13 +311,19 @@ func (h *CanvasHandler) BatchDeleteElements(ctx context.Context, c *app.RequestC
 		return
 	}
 
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
 	var req models.BatchDeleteRequest
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
 		return
 	}
 
-	if err := h.canvasService.BatchDeleteElements(ctx, workspaceID, req); err != nil {
+	if err := h.canvasService.BatchDeleteElements(ctx, workspaceID, userID, req); err != nil {
 		hlog.CtxErrorf(ctx, "Failed to batch delete elements: %v", err)
 		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
 		return
@@ -307,7 +334,7 @@ func (h *CanvasHandler) BatchDeleteElements(ctx context.Context, c *app.RequestC
 
 // GetElementsByType godoc
 // @Summary Get elements by type
-// @Description Retrieves all elements of a specific type in a workspace
+// @Description Retrieves all elements of a specific type in a workspace. Public workspaces that allow anonymous viewing can be read without a token.
 // @Tags canvas
 // @Accept json
 // @Produce json
@@ -348,6 +375,7 @@ func (h *CanvasHandler) GetElementsByType(ctx context.Context, c *app.RequestCon
 	for i := range elements {
 		responses[i] = elements[i].ToResponse()
 	}
+	h.addCommentCounts(ctx, workspaceID, responses)
 
 	c.JSON(http.StatusOK, models.ElementListResponse{
 		Elements: responses,
@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type CommentHandler struct {
+	commentService *service.CommentService
+}
+
+func NewCommentHandler(commentService *service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+	}
+}
+
+// ListComments godoc
+// @Summary List comment threads
+// @Description Returns the threads of the board oldest first, each with its replies.
+// @Tags comments
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param element_id query string false "Threads of one element only"
+// @Param resolved query bool false "Resolved (true) or open (false) threads only"
+// @Success 200 {object} models.CommentListResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments [get]
+func (h *CommentHandler) ListComments(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	var filter models.CommentFilter
+	if raw := c.Query("element_id"); raw != "" {
+		elementID, parseErr := uuid.Parse(raw)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid element ID"})
+			return
+		}
+		filter.ElementID = &elementID
+	}
+	if raw := c.Query("resolved"); raw != "" {
+		resolved, parseErr := strconv.ParseBool(raw)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "resolved must be true or false"})
+			return
+		}
+		filter.Resolved = &resolved
+	}
+
+	resp, err := h.commentService.ListThreads(ctx, workspaceID, filter)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to list comments", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateComment godoc
+// @Summary Start a comment thread
+// @Description Starts a thread on an element, or on the board without element_id. Only members can comment, and visitors of public boards that allow commenting.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateCommentRequest true "Thread"
+// @Success 201 {object} models.Comment
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments [post]
+func (h *CommentHandler) CreateComment(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	comment, err := h.commentService.CreateThread(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to create comment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// ReplyToComment godoc
+// @Summary Reply to a comment thread
+// @Description Replying to a reply adds to its thread.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Param request body models.CreateReplyRequest true "Reply"
+// @Success 201 {object} models.Comment
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id}/replies [post]
+func (h *CommentHandler) ReplyToComment(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateReplyRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	reply, err := h.commentService.Reply(ctx, workspaceID, userID, commentID, &req)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to reply to comment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Only the author can edit a comment.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Param request body models.UpdateCommentRequest true "Body"
+// @Success 200 {object} models.Comment
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id} [put]
+func (h *CommentHandler) UpdateComment(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	comment, err := h.commentService.Update(ctx, workspaceID, userID, commentID, &req)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to update comment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Deleting a thread deletes its replies. Authors can delete their comments, owners any comment.
+// @Tags comments
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id} [delete]
+func (h *CommentHandler) DeleteComment(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.Delete(ctx, workspaceID, userID, commentID); err != nil {
+		respondCommentError(ctx, c, "Failed to delete comment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Comment deleted successfully"})
+}
+
+// ResolveComment godoc
+// @Summary Resolve a comment thread
+// @Description Resolves the thread of the comment, recording who resolved it.
+// @Tags comments
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Success 200 {object} models.Comment
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id}/resolve [post]
+func (h *CommentHandler) ResolveComment(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	thread, err := h.commentService.Resolve(ctx, workspaceID, userID, commentID)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to resolve comment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// ReopenComment godoc
+// @Summary Reopen a comment thread
+// @Tags comments
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Success 200 {object} models.Comment
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id}/reopen [post]
+func (h *CommentHandler) ReopenComment(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	thread, err := h.commentService.Reopen(ctx, workspaceID, userID, commentID)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to reopen comment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// AddCommentReaction godoc
+// @Summary React to a comment
+// @Description Adds a reaction of the user with an emoji. Reacting twice with the same emoji is a no-op.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Param request body models.ReactionRequest true "Emoji"
+// @Success 200 {object} models.ReactionsResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id}/reactions [post]
+func (h *CommentHandler) AddCommentReaction(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReactionRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	reactions, err := h.commentService.AddReaction(ctx, workspaceID, userID, commentID, req.Emoji)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to add reaction", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReactionsResponse{Reactions: reactions})
+}
+
+// RemoveCommentReaction godoc
+// @Summary Remove a reaction from a comment
+// @Tags comments
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param comment_id path string true "Comment ID"
+// @Param emoji query string true "Emoji of the reaction"
+// @Success 200 {object} models.ReactionsResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/comments/{comment_id}/reactions [delete]
+func (h *CommentHandler) RemoveCommentReaction(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, commentID, ok := commentRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	emoji := c.Query("emoji")
+	if emoji == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "emoji is required"})
+		return
+	}
+
+	reactions, err := h.commentService.RemoveReaction(ctx, workspaceID, userID, commentID, emoji)
+	if err != nil {
+		respondCommentError(ctx, c, "Failed to remove reaction", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReactionsResponse{Reactions: reactions})
+}
+
+func commentRequestIDs(c *app.RequestContext) (workspaceID, userID, commentID uuid.UUID, ok bool) {
+	workspaceID, userID, ok = aiRequestIDs(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	commentID, err := uuid.Parse(c.Param("comment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid comment ID"})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return workspaceID, userID, commentID, true
+}
+
+func respondCommentError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Comment not found"})
+	case errors.Is(err, service.ErrCommentForbidden):
+		c.JSON(http.StatusForbidden, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type ConsentHandler struct {
+	consentService *service.ConsentService
+}
+
+func NewConsentHandler(consentService *service.ConsentService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: consentService,
+	}
+}
+
+// GetConsentStatus godoc
+// @Summary Check consent
+// @Description Returns the current versions of the terms of service and privacy policy and the versions the user accepted.
+// @Description consent_required is true when the user has to accept an updated version.
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.ConsentStatus
+//
+// @Router /api/v1/users/me/consent [get]
+func (h *ConsentHandler) GetConsentStatus(ctx context.Context, c *app.RequestContext) {
+	userID, ok := consentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.consentService.GetStatus(ctx, userID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get consent status: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get consent status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// AcceptConsent godoc
+// @Summary Accept the terms
+// @Description Records that the user accepted the current versions of the terms of service and privacy policy
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptConsentRequest true "Accepted versions"
+// @Success 200 {object} models.ConsentStatus
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/users/me/consent [post]
+func (h *ConsentHandler) AcceptConsent(ctx context.Context, c *app.RequestContext) {
+	userID, ok := consentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.AcceptConsentRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	status, err := h.consentService.Accept(ctx, userID, &req, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, service.ErrConsentVersionMismatch) {
+			c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to record consent: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to record consent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// consentUser returns the authenticated user, or responds with an error
+func consentUser(c *app.RequestContext) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return uuid.Nil, false
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return uuid.Nil, false
+	}
+
+	return userUUID, true
+}
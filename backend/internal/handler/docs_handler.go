@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// swaggerUIPage loads Swagger UI from a CDN, so the gateway doesn't have to
+// bundle its assets
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>HertzBoard API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: "/api/openapi.json",
+      dom_id: "#swagger-ui",
+      persistAuthorization: true
+    });
+  </script>
+</body>
+</html>
+`
+
+// DocsHandler serves the OpenAPI spec generated from the handler annotations
+type DocsHandler struct {
+	specPath string
+}
+
+// NewDocsHandler creates a docs handler for the spec at specPath
+func NewDocsHandler(specPath string) *DocsHandler {
+	return &DocsHandler{
+		specPath: specPath,
+	}
+}
+
+// GetSpec returns the OpenAPI spec. It is read on every request, so a
+// regenerated spec is served without a restart.
+// GET /api/openapi.json
+func (h *DocsHandler) GetSpec(ctx context.Context, c *app.RequestContext) {
+	spec, err := os.ReadFile(h.specPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+				"error": "API spec has not been generated, run make backend-docs",
+			})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to read API spec: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to read API spec",
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// GetUI returns the Swagger UI page
+// GET /api/docs
+func (h *DocsHandler) GetUI(ctx context.Context, c *app.RequestContext) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
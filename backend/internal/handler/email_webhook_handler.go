@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type EmailWebhookHandler struct {
+	emailService *service.EmailService
+}
+
+func NewEmailWebhookHandler(emailService *service.EmailService) *EmailWebhookHandler {
+	return &EmailWebhookHandler{
+		emailService: emailService,
+	}
+}
+
+// HandleFeedback godoc
+// @Summary Receive email provider feedback
+// @Description Delivery, bounce and complaint webhook of the configured email provider (sendgrid, ses or mailgun).
+// @Description Requests are authenticated with the provider signature, or the token query parameter for SES.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param provider path string true "Email provider"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/webhooks/email/{provider} [post]
+func (h *EmailWebhookHandler) HandleFeedback(ctx context.Context, c *app.RequestContext) {
+	header := http.Header{}
+	c.Request.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	query, err := url.ParseQuery(string(c.URI().QueryString()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid query string"})
+		return
+	}
+
+	req := &service.EmailWebhookRequest{
+		Header: header,
+		Query:  query,
+		Body:   c.Request.Body(),
+	}
+
+	if err := h.emailService.HandleWebhook(ctx, c.Param("provider"), req); err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmailWebhookDisabled):
+			c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Email webhook is not configured"})
+		case errors.Is(err, service.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Invalid signature"})
+		case errors.Is(err, service.ErrInvalidEmailWebhook):
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid payload"})
+		default:
+			hlog.CtxErrorf(ctx, "Failed to handle email webhook: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to handle email webhook"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "ok"})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// embedStaleFactor is how many times the max age a cache may keep serving
+// a board while it revalidates it
+const embedStaleFactor = 10
+
+type EmbedHandler struct {
+	embedService *service.EmbedService
+	cacheControl string
+}
+
+func NewEmbedHandler(embedService *service.EmbedService, cacheMaxAge time.Duration) *EmbedHandler {
+	maxAge := int(cacheMaxAge.Seconds())
+	return &EmbedHandler{
+		embedService: embedService,
+		cacheControl: fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d", maxAge, maxAge*embedStaleFactor),
+	}
+}
+
+// GetEmbedElements godoc
+// @Summary Get an embedded board
+// @Description Returns the elements of the board an embed token gives read-only access to. Responses may be cached by browsers and CDNs and carry an ETag for conditional requests.
+// @Description Boards of workspaces with an IP allowlist are only served to viewers inside of it and aren't cached by CDNs.
+// @Tags embed
+// @Produce json
+// @Param token path string true "Embed token"
+// @Param If-None-Match header string false "ETag of a cached board"
+// @Success 200 {object} models.EmbedBoard
+// @Success 304 "Board not modified"
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /embed/{token}/elements [get]
+func (h *EmbedHandler) GetEmbedElements(ctx context.Context, c *app.RequestContext) {
+	board, err := h.embedService.GetBoard(ctx, c.Param("token"), c.ClientIP())
+	if err != nil {
+		c.Response.Header.Set("Cache-Control", "no-store")
+		if errors.Is(err, service.ErrInvalidEmbedToken) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Embed not found"})
+			return
+		}
+		if errors.Is(err, service.ErrIPNotAllowed) {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"error": err.Error(),
+				"code":  "ip_not_allowed",
+			})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to get embedded board: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get board"})
+		return
+	}
+
+	body, err := json.Marshal(board)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to encode embedded board: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get board"})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// A shared cache would hand boards of allowlisted workspaces to viewers
+	// outside of the allowlist, so those are only revalidated privately
+	if board.Restricted {
+		c.Response.Header.Set("Cache-Control", "private, no-cache")
+	} else {
+		c.Response.Header.Set("Cache-Control", h.cacheControl)
+	}
+	c.Response.Header.Set("ETag", etag)
+
+	if string(c.GetHeader("If-None-Match")) == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// CreateEmbedToken godoc
+// @Summary Create an embed token
+// @Description Creates a token that gives read-only access to the board through the embed API, for iframes in wikis and docs.
+// @Description The token is only returned here. It stops working when it expires or its creator leaves the workspace.
+// @Tags embed
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateEmbedTokenRequest true "Token name and expiry"
+// @Success 201 {object} models.EmbedTokenWithSecret
+//
+// @Router /api/v1/workspaces/{workspace_id}/embed-tokens [post]
+func (h *EmbedHandler) CreateEmbedToken(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateEmbedTokenRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	token, err := h.embedService.CreateEmbedToken(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondEmbedTokenError(ctx, c, "Failed to create embed token", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// ListEmbedTokens godoc
+// @Summary List embed tokens
+// @Tags embed
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/embed-tokens [get]
+func (h *EmbedHandler) ListEmbedTokens(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	tokens, err := h.embedService.ListEmbedTokens(ctx, workspaceID)
+	if err != nil {
+		respondEmbedTokenError(ctx, c, "Failed to list embed tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"embed_tokens": tokens})
+}
+
+// DeleteEmbedToken godoc
+// @Summary Revoke an embed token
+// @Description Cached copies of the board may still be served for the configured cache max age.
+// @Tags embed
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param token_id path string true "Embed token ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/embed-tokens/{token_id} [delete]
+func (h *EmbedHandler) DeleteEmbedToken(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("token_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid embed token ID"})
+		return
+	}
+
+	if err := h.embedService.DeleteEmbedToken(ctx, workspaceID, tokenID); err != nil {
+		respondEmbedTokenError(ctx, c, "Failed to delete embed token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Embed token revoked successfully"})
+}
+
+func respondEmbedTokenError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmbedTokenNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Embed token not found"})
+	case errors.Is(err, service.ErrEmbedTokenLimitReached):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// CreateExport godoc
+// @Summary Export a board as a document
+// @Description Queues an export of the text, sticky notes and lists of the board in reading order, with groups
+// @Description and shapes drawn around elements as sections. markdown is GitHub-flavored and imported by Notion,
+// @Description confluence is wiki markup. Poll the export until it is completed to get its download link.
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateBoardExportRequest true "Format"
+// @Success 202 {object} models.BoardExport
+//
+// @Router /api/v1/workspaces/{workspace_id}/exports [post]
+func (h *ExportHandler) CreateExport(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.CreateBoardExportRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	export, err := h.exportService.CreateExport(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		respondExportError(ctx, c, "Failed to create export", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// ListExports godoc
+// @Summary List board exports
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/exports [get]
+func (h *ExportHandler) ListExports(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	exports, err := h.exportService.ListExports(ctx, workspaceID)
+	if err != nil {
+		respondExportError(ctx, c, "Failed to list exports", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"exports": exports})
+}
+
+// GetExport godoc
+// @Summary Get a board export
+// @Description Returns the status of an export and, once completed, a download link valid for an hour.
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param export_id path string true "Export ID"
+// @Success 200 {object} models.BoardExport
+//
+// @Router /api/v1/workspaces/{workspace_id}/exports/{export_id} [get]
+func (h *ExportHandler) GetExport(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid export ID"})
+		return
+	}
+
+	export, err := h.exportService.GetExport(ctx, workspaceID, exportID)
+	if err != nil {
+		respondExportError(ctx, c, "Failed to get export", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// DeleteExport godoc
+// @Summary Delete a board export
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param export_id path string true "Export ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/exports/{export_id} [delete]
+func (h *ExportHandler) DeleteExport(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid export ID"})
+		return
+	}
+
+	if err := h.exportService.DeleteExport(ctx, workspaceID, exportID); err != nil {
+		respondExportError(ctx, c, "Failed to delete export", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Export deleted successfully"})
+}
+
+// ExportOperations godoc
+// @Summary Export the operation history of a board
+// @Description Queues a report of who changed which element how and when, for compliance reviews. The report
+// @Description covers operations created from from up to to, the whole stored history without from, and at most
+// @Description 200000 of them. Poll the report until it is completed to get its download link.
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param from query string false "Start time (RFC3339)"
+// @Param to query string false "End time (RFC3339), defaults to now"
+// @Param format query string false "Report format" Enums(csv, json) default(csv)
+// @Success 202 {object} models.BoardExport
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/operations/export [get]
+func (h *ExportHandler) ExportOperations(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid time range, use RFC3339 timestamps"})
+		return
+	}
+
+	format := models.ExportFormatOperationsCSV
+	switch c.Query("format") {
+	case "", "csv":
+	case "json":
+		format = models.ExportFormatOperationsJSON
+	default:
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "format must be csv or json"})
+		return
+	}
+
+	report, err := h.exportService.CreateOperationReport(ctx, workspaceID, userID, format, from, to)
+	if err != nil {
+		respondExportError(ctx, c, "Failed to create operation report", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, report)
+}
+
+// ListOperationReports godoc
+// @Summary List operation history reports of a board
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/operations/exports [get]
+func (h *ExportHandler) ListOperationReports(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	reports, err := h.exportService.ListOperationReports(ctx, workspaceID)
+	if err != nil {
+		respondExportError(ctx, c, "Failed to list operation reports", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"exports": reports})
+}
+
+// GetOperationReport godoc
+// @Summary Get an operation history report
+// @Description Returns the status of a report and, once completed, a download link valid for an hour.
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param export_id path string true "Export ID"
+// @Success 200 {object} models.BoardExport
+//
+// @Router /api/v1/workspaces/{workspace_id}/operations/exports/{export_id} [get]
+func (h *ExportHandler) GetOperationReport(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid export ID"})
+		return
+	}
+
+	report, err := h.exportService.GetOperationReport(ctx, workspaceID, exportID)
+	if err != nil {
+		respondExportError(ctx, c, "Failed to get operation report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// DeleteOperationReport godoc
+// @Summary Delete an operation history report
+// @Tags exports
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param export_id path string true "Export ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/operations/exports/{export_id} [delete]
+func (h *ExportHandler) DeleteOperationReport(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid export ID"})
+		return
+	}
+
+	if err := h.exportService.DeleteOperationReport(ctx, workspaceID, exportID); err != nil {
+		respondExportError(ctx, c, "Failed to delete operation report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Report deleted successfully"})
+}
+
+func respondExportError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrExportNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Export not found"})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+
+	"github.com/bifshteksex/hertz-board/internal/graphql"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// GraphQLHandler serves the GraphQL API. Subscriptions are streamed over
+// Server-Sent Events, following the distinct connections mode of GraphQL
+// over SSE.
+type GraphQLHandler struct {
+	server *graphql.Server
+}
+
+func NewGraphQLHandler(server *graphql.Server) *GraphQLHandler {
+	return &GraphQLHandler{
+		server: server,
+	}
+}
+
+// Query godoc
+// @Summary Run a GraphQL operation
+// @Description Runs a query and returns its result. With Accept: text/event-stream the operation is streamed instead: every result is a "next" event and a "complete" event ends the stream, which is how subscriptions are consumed.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Produce text/event-stream
+// @Param request body models.GraphQLRequest true "Operation"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/graphql [post]
+func (h *GraphQLHandler) Query(ctx context.Context, c *app.RequestContext) {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	var req models.GraphQLRequest
+	if err := c.BindJSON(&req); err != nil || req.Query == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	username := c.GetString("username")
+
+	if !strings.Contains(string(c.GetHeader("Accept")), "text/event-stream") {
+		c.JSON(http.StatusOK, h.server.Exec(ctx, userID, username, c.ClientIP(), &req))
+		return
+	}
+
+	// Hertz doesn't cancel the request context when the client goes away,
+	// the subscription is stopped when writing to the stream fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses, err := h.server.Subscribe(ctx, userID, username, c.ClientIP(), &req)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to start GraphQL subscription: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.SetStatusCode(http.StatusOK)
+	c.Response.Header.Set("Content-Type", "text/event-stream")
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("Connection", "keep-alive")
+	c.Response.Header.Set("X-Accel-Buffering", "no")
+	c.Response.HijackWriter(resp.NewChunkedBodyWriter(&c.Response, c.GetWriter()))
+
+	h.streamResponses(c, responses)
+}
+
+// streamResponses writes every response as a next event until the
+// operation ends or the client goes away
+func (h *GraphQLHandler) streamResponses(c *app.RequestContext, responses <-chan interface{}) {
+	ticker := time.NewTicker(sseHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case response, ok := <-responses:
+			if !ok {
+				_ = writeSSEEvent(c, "complete", struct{}{})
+				return
+			}
+
+			if err := writeSSEEvent(c, "next", response); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			if _, err := c.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			if err := c.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
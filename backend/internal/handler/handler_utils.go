@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -9,6 +13,39 @@ import (
 	"github.com/google/uuid"
 )
 
+// requestBody returns the request body as a reader. Bodies above the
+// server's buffer size are read from the connection as they arrive.
+func requestBody(c *app.RequestContext) io.Reader {
+	if c.Request.IsBodyStream() {
+		return c.Request.BodyStream()
+	}
+	return bytes.NewReader(c.Request.Body())
+}
+
+// multipartFile returns the file part named field of a multipart request,
+// without reading the file itself
+func multipartFile(c *app.RequestContext, field string) (*multipart.Part, error) {
+	boundary := c.Request.Header.MultipartFormBoundary()
+	if len(boundary) == 0 {
+		return nil, fmt.Errorf("request is not multipart/form-data")
+	}
+
+	reader := multipart.NewReader(requestBody(c), string(boundary))
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no %s file in the request", field)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart body: %w", err)
+		}
+		if part.FormName() == field && part.FileName() != "" {
+			return part, nil
+		}
+		_ = part.Close()
+	}
+}
+
 // parseIDParam parses a UUID from a request parameter
 func parseIDParam(c *app.RequestContext, paramName string) (uuid.UUID, error) {
 	idStr := c.Param(paramName)
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type InboundWebhookHandler struct {
+	inboundWebhookService *service.InboundWebhookService
+}
+
+func NewInboundWebhookHandler(inboundWebhookService *service.InboundWebhookService) *InboundWebhookHandler {
+	return &InboundWebhookHandler{
+		inboundWebhookService: inboundWebhookService,
+	}
+}
+
+// CreateInboundWebhook godoc
+// @Summary Create an inbound webhook
+// @Description Creates a URL that turns posted text into sticky notes on the board.
+// @Description The token is only returned here and when it is rotated.
+// @Tags inbound-webhooks
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateInboundWebhookRequest true "Webhook name and the column notes go to by default"
+// @Success 201 {object} models.InboundWebhookWithToken
+//
+// @Router /api/v1/workspaces/{workspace_id}/inbound-webhooks [post]
+func (h *InboundWebhookHandler) CreateInboundWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.CreateInboundWebhookRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := h.inboundWebhookService.CreateInboundWebhook(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		respondInboundWebhookError(ctx, c, "Failed to create inbound webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListInboundWebhooks godoc
+// @Summary List inbound webhooks
+// @Tags inbound-webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/inbound-webhooks [get]
+func (h *InboundWebhookHandler) ListInboundWebhooks(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	webhooks, err := h.inboundWebhookService.ListInboundWebhooks(ctx, workspaceID)
+	if err != nil {
+		respondInboundWebhookError(ctx, c, "Failed to list inbound webhooks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"inbound_webhooks": webhooks})
+}
+
+// GetInboundWebhook godoc
+// @Summary Get an inbound webhook
+// @Tags inbound-webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Inbound webhook ID"
+// @Success 200 {object} models.InboundWebhook
+//
+// @Router /api/v1/workspaces/{workspace_id}/inbound-webhooks/{webhook_id} [get]
+func (h *InboundWebhookHandler) GetInboundWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.inboundWebhookService.GetInboundWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		respondInboundWebhookError(ctx, c, "Failed to get inbound webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateInboundWebhook godoc
+// @Summary Update an inbound webhook
+// @Description Changes the name, default column or active flag. With rotate_token the new token is returned.
+// @Tags inbound-webhooks
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Inbound webhook ID"
+// @Param request body models.UpdateInboundWebhookRequest true "Fields to change"
+// @Success 200 {object} models.InboundWebhookWithToken
+//
+// @Router /api/v1/workspaces/{workspace_id}/inbound-webhooks/{webhook_id} [put]
+func (h *InboundWebhookHandler) UpdateInboundWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateInboundWebhookRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := h.inboundWebhookService.UpdateInboundWebhook(ctx, workspaceID, webhookID, &req)
+	if err != nil {
+		respondInboundWebhookError(ctx, c, "Failed to update inbound webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteInboundWebhook godoc
+// @Summary Delete an inbound webhook
+// @Tags inbound-webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Inbound webhook ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/inbound-webhooks/{webhook_id} [delete]
+func (h *InboundWebhookHandler) DeleteInboundWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.inboundWebhookService.DeleteInboundWebhook(ctx, workspaceID, webhookID); err != nil {
+		respondInboundWebhookError(ctx, c, "Failed to delete inbound webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Inbound webhook deleted successfully"})
+}
+
+// ReceiveInboundWebhook godoc
+// @Summary Post to an inbound webhook
+// @Description Creates a sticky note with the text under the column heading of the board, the webhook's
+// @Description default column when none is given. A missing heading is added next to the board.
+// @Description The token goes in the Authorization header as a bearer token, or in the token query parameter.
+// @Tags inbound-webhooks
+// @Accept json
+// @Produce json
+// @Param hook_id path string true "Inbound webhook ID"
+// @Param token query string false "Webhook token, when it can't be sent as a header"
+// @Param request body models.InboundWebhookPayload true "Note text, column and color"
+// @Success 201 {object} models.InboundWebhookResult
+//
+// @Router /api/v1/hooks/{hook_id} [post]
+func (h *InboundWebhookHandler) ReceiveInboundWebhook(ctx context.Context, c *app.RequestContext) {
+	webhookID, err := uuid.Parse(c.Param("hook_id"))
+	if err != nil {
+		// Unknown webhooks look the same whatever the ID
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Invalid webhook or token"})
+		return
+	}
+
+	token := c.Query("token")
+	if header := string(c.GetHeader("Authorization")); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	var payload models.InboundWebhookPayload
+	if bindErr := c.BindJSON(&payload); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := h.inboundWebhookService.Receive(ctx, webhookID, token, &payload)
+	if err != nil {
+		respondInboundWebhookError(ctx, c, "Failed to handle inbound webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+func respondInboundWebhookError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrBoardFrozen):
+		c.JSON(http.StatusLocked, map[string]interface{}{"error": err.Error(), "code": "board_frozen"})
+	case errors.Is(err, service.ErrInboundWebhookNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Inbound webhook not found"})
+	case errors.Is(err, service.ErrInboundWebhookLimitReached):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrInboundWebhookUnauthorized):
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Invalid webhook or token"})
+	case errors.Is(err, service.ErrInboundWebhookForbidden):
+		c.JSON(http.StatusForbidden, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidInboundPayload):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type IntegrationHandler struct {
+	stockMediaService *service.StockMediaService
+	assetService      *service.AssetService
+}
+
+func NewIntegrationHandler(stockMediaService *service.StockMediaService, assetService *service.AssetService) *IntegrationHandler {
+	return &IntegrationHandler{
+		stockMediaService: stockMediaService,
+		assetService:      assetService,
+	}
+}
+
+// SearchUnsplash godoc
+// @Summary Search Unsplash photos
+// @Description Searches Unsplash through the server so the API key stays private
+// @Tags integrations
+// @Produce json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Results per page"
+// @Success 200 {object} models.StockSearchResponse
+//
+// @Router /api/v1/integrations/unsplash/search [get]
+func (h *IntegrationHandler) SearchUnsplash(ctx context.Context, c *app.RequestContext) {
+	h.search(ctx, c, models.StockProviderUnsplash)
+}
+
+// SearchGiphy godoc
+// @Summary Search GIPHY GIFs
+// @Description Searches GIPHY through the server so the API key stays private
+// @Tags integrations
+// @Produce json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number"
+// @Param per_page query int false "Results per page"
+// @Success 200 {object} models.StockSearchResponse
+//
+// @Router /api/v1/integrations/giphy/search [get]
+func (h *IntegrationHandler) SearchGiphy(ctx context.Context, c *app.RequestContext) {
+	h.search(ctx, c, models.StockProviderGiphy)
+}
+
+func (h *IntegrationHandler) search(ctx context.Context, c *app.RequestContext, provider string) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "q is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "0"))
+
+	result, err := h.stockMediaService.Search(ctx, provider, query, page, perPage)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to search %s: %v", provider, err)
+		respondStockMediaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// InsertStockMedia godoc
+// @Summary Insert a stock media item
+// @Description Imports an Unsplash photo or GIPHY GIF into the workspace with attribution
+// @Tags integrations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param provider path string true "Provider (unsplash or giphy)"
+// @Param request body models.InsertStockMediaRequest true "Item to insert"
+// @Success 201 {object} models.AssetResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/integrations/{provider}/insert [post]
+func (h *IntegrationHandler) InsertStockMedia(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.InsertStockMediaRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil || req.ID == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "id is required"})
+		return
+	}
+
+	provider := c.Param("provider")
+	asset, err := h.stockMediaService.Insert(ctx, workspaceID, userUUID, provider, req.ID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to insert %s item %s: %v", provider, req.ID, err)
+		respondStockMediaError(c, err)
+		return
+	}
+
+	respondWithSignedAsset(ctx, c, h.assetService, http.StatusCreated, asset)
+}
+
+// respondStockMediaError maps stock media errors to HTTP responses
+func respondStockMediaError(c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownStockProvider):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Unknown provider"})
+	case errors.Is(err, service.ErrStockProviderDisabled):
+		c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"error": "Provider is not configured"})
+	case errors.Is(err, repository.ErrStorageQuotaExceeded):
+		respondQuotaExceeded(c)
+	default:
+		c.JSON(http.StatusBadGateway, map[string]interface{}{"error": err.Error()})
+	}
+}
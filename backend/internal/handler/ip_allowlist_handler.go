@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type IPAllowlistHandler struct {
+	ipAllowlist *service.IPAllowlistService
+}
+
+func NewIPAllowlistHandler(ipAllowlist *service.IPAllowlistService) *IPAllowlistHandler {
+	return &IPAllowlistHandler{
+		ipAllowlist: ipAllowlist,
+	}
+}
+
+// GetIPAllowlist godoc
+// @Summary Get the IP allowlist
+// @Description Returns the networks the workspace is restricted to and the address the caller is seen from.
+// @Description An empty list means the workspace can be reached from anywhere.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} models.IPAllowlistResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/ip-allowlist [get]
+func (h *IPAllowlistHandler) GetIPAllowlist(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	allowlist, err := h.ipAllowlist.GetAllowlist(ctx, workspaceID, c.ClientIP())
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get IP allowlist: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get IP allowlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, allowlist)
+}
+
+// UpdateIPAllowlist godoc
+// @Summary Replace the IP allowlist
+// @Description Restricts the workspace, its REST routes and realtime connections, to the given CIDR ranges or addresses.
+// @Description The list must include the caller's address so owners can't lock themselves out. An empty list lifts the restriction.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.UpdateIPAllowlistRequest true "Allowed ranges"
+// @Success 200 {object} models.IPAllowlistResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/ip-allowlist [put]
+func (h *IPAllowlistHandler) UpdateIPAllowlist(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateIPAllowlistRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	allowlist, err := h.ipAllowlist.UpdateAllowlist(ctx, workspaceID, userID, c.ClientIP(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidIPAllowlist):
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		case errors.Is(err, service.ErrAllowlistExcludesCaller):
+			c.JSON(http.StatusConflict, map[string]interface{}{
+				"error":     err.Error(),
+				"client_ip": c.ClientIP(),
+			})
+		default:
+			hlog.CtxErrorf(ctx, "Failed to update IP allowlist: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to update IP allowlist"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, allowlist)
+}
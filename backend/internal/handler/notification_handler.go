@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 100
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// ListNotifications godoc
+// @Summary List notifications
+// @Description Returns the notifications of the current user, newest first, with the unread count
+// @Tags notifications
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Maximum number of notifications (default 50, max 100)"
+// @Param offset query int false "Number of notifications to skip"
+// @Success 200 {object} models.NotificationListResponse
+//
+// @Router /api/v1/notifications [get]
+func (h *NotificationHandler) ListNotifications(ctx context.Context, c *app.RequestContext) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	if limit > maxNotificationLimit {
+		limit = maxNotificationLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.notificationService.ListNotifications(ctx, userUUID, unreadOnly, limit, offset)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list notifications: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification as read
+// @Tags notifications
+// @Produce json
+// @Param notification_id path string true "Notification ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/notifications/{notification_id}/read [post]
+func (h *NotificationHandler) MarkNotificationRead(ctx context.Context, c *app.RequestContext) {
+	notificationID, err := uuid.Parse(c.Param("notification_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid notification ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	if err := h.notificationService.MarkRead(ctx, userUUID, notificationID); err != nil {
+		if errors.Is(err, service.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Notification not found"})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to mark notification as read: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to mark notification as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications as read
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/notifications/read-all [post]
+func (h *NotificationHandler) MarkAllNotificationsRead(ctx context.Context, c *app.RequestContext) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	marked, err := h.notificationService.MarkAllRead(ctx, userUUID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to mark notifications as read: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to mark notifications as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"marked": marked})
+}
+
+// GetNotificationPreferences godoc
+// @Summary Get notification preferences
+// @Description Returns the notification settings of the current user, the defaults if they were never changed
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} models.NotificationPreferences
+//
+// @Router /api/v1/notifications/preferences [get]
+func (h *NotificationHandler) GetNotificationPreferences(ctx context.Context, c *app.RequestContext) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	prefs, err := h.notificationService.GetPreferences(ctx, userUUID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get notification preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary Update notification preferences
+// @Description Changes the given notification settings of the current user
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param request body models.UpdateNotificationPreferencesRequest true "Settings to change"
+// @Success 200 {object} models.NotificationPreferences
+//
+// @Router /api/v1/notifications/preferences [put]
+func (h *NotificationHandler) UpdateNotificationPreferences(ctx context.Context, c *app.RequestContext) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	prefs, err := h.notificationService.UpdatePreferences(ctx, userUUID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDigestFrequency) {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		hlog.CtxErrorf(ctx, "Failed to update notification preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
@@ -32,7 +33,13 @@ func NewOAuthHandler(oauthService *service.OAuthService) *OAuthHandler {
 	}
 }
 
-// GoogleAuth redirects to Google OAuth
+// GoogleAuth godoc
+// @Summary Log in with Google
+// @Description Redirects to the Google consent screen
+// @Tags auth
+// @Success 307
+//
+// @Router /api/v1/auth/google [get]
 func (h *OAuthHandler) GoogleAuth(c context.Context, ctx *app.RequestContext) {
 	state := h.generateState()
 	h.states[state] = time.Now().Add(stateExpiration)
@@ -41,12 +48,27 @@ func (h *OAuthHandler) GoogleAuth(c context.Context, ctx *app.RequestContext) {
 	ctx.Redirect(consts.StatusTemporaryRedirect, []byte(url))
 }
 
-// GoogleCallback handles Google OAuth callback
+// GoogleCallback godoc
+// @Summary Google OAuth callback
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the authorization request"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/google/callback [get]
 func (h *OAuthHandler) GoogleCallback(c context.Context, ctx *app.RequestContext) {
 	h.handleOAuthCallback(c, ctx, h.oauthService.GoogleCallback)
 }
 
-// GitHubAuth redirects to GitHub OAuth
+// GitHubAuth godoc
+// @Summary Log in with GitHub
+// @Description Redirects to the GitHub authorization page
+// @Tags auth
+// @Success 307
+//
+// @Router /api/v1/auth/github [get]
 func (h *OAuthHandler) GitHubAuth(c context.Context, ctx *app.RequestContext) {
 	state := h.generateState()
 	h.states[state] = time.Now().Add(stateExpiration)
@@ -55,7 +77,16 @@ func (h *OAuthHandler) GitHubAuth(c context.Context, ctx *app.RequestContext) {
 	ctx.Redirect(consts.StatusTemporaryRedirect, []byte(url))
 }
 
-// GitHubCallback handles GitHub OAuth callback
+// GitHubCallback godoc
+// @Summary GitHub OAuth callback
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the authorization request"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/auth/github/callback [get]
 func (h *OAuthHandler) GitHubCallback(c context.Context, ctx *app.RequestContext) {
 	h.handleOAuthCallback(c, ctx, h.oauthService.GitHubCallback)
 }
@@ -79,6 +110,12 @@ func (h *OAuthHandler) handleOAuthCallback(
 
 	// Handle OAuth callback
 	resp, err := callbackFunc(c, code)
+	if errors.Is(err, service.ErrUserDeactivated) {
+		ctx.JSON(consts.StatusForbidden, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(consts.StatusInternalServerError, map[string]interface{}{
 			"error": err.Error(),
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	defaultReplayLimit = 500
+	maxReplayLimit     = 1000
+)
+
+type OperationHandler struct {
+	crdtService *service.CRDTService
+}
+
+func NewOperationHandler(crdtService *service.CRDTService) *OperationHandler {
+	return &OperationHandler{
+		crdtService: crdtService,
+	}
+}
+
+// Replay godoc
+// @Summary Replay workspace operations
+// @Description Returns stored operations in the order they were applied, for animating board history
+// @Tags operations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param from query string false "Start time (RFC3339)"
+// @Param to query string false "End time (RFC3339), defaults to now"
+// @Param limit query int false "Number of results" default(500)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} models.ReplayResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/replay [get]
+func (h *OperationHandler) Replay(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid time range, use RFC3339 timestamps"})
+		return
+	}
+
+	// Parse pagination parameters
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	if limit <= 0 {
+		limit = defaultReplayLimit
+	}
+	if limit > maxReplayLimit {
+		limit = maxReplayLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var replay *models.ReplayResponse
+	replay, err = h.crdtService.GetReplay(ctx, workspaceID, from, to, limit, offset)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get replay: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get operations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, replay)
+}
+
+// parseTimeRange parses optional from/to query parameters.
+// Missing from means the beginning of history, missing to means now.
+func parseTimeRange(c *app.RequestContext) (time.Time, time.Time, bool) {
+	var from time.Time
+	to := time.Now()
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return from, to, false
+		}
+		from = parsed
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return from, to, false
+		}
+		to = parsed
+	}
+
+	return from, to, !to.Before(from)
+}
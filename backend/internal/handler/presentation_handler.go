@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type PresentationHandler struct {
+	presentationService *service.PresentationService
+}
+
+func NewPresentationHandler(presentationService *service.PresentationService) *PresentationHandler {
+	return &PresentationHandler{
+		presentationService: presentationService,
+	}
+}
+
+// GetPresentation godoc
+// @Summary Get the presentation of a board
+// @Description Returns the slide order and the running session with the slide shown. Followers fetch it when they
+// @Description reconnect, presentation_event messages over WebSocket carry the changes after.
+// @Tags presentations
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} models.Presentation
+//
+// @Router /api/v1/workspaces/{workspace_id}/presentation [get]
+func (h *PresentationHandler) GetPresentation(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	presentation, err := h.presentationService.Get(ctx, workspaceID)
+	if err != nil {
+		respondPresentationError(ctx, c, "Failed to get presentation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, presentation)
+}
+
+// SetPresentationSlides godoc
+// @Summary Set the slides of a board
+// @Description Replaces the slide order with frames of the board. A running session stays on the last slide if its
+// @Description slide was dropped, and ends when no slides are left.
+// @Tags presentations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.SetSlidesRequest true "Frames in order"
+// @Success 200 {object} models.Presentation
+//
+// @Router /api/v1/workspaces/{workspace_id}/presentation/slides [put]
+func (h *PresentationHandler) SetPresentationSlides(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	var req models.SetSlidesRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	presentation, err := h.presentationService.SetSlides(ctx, workspaceID, &req)
+	if err != nil {
+		respondPresentationError(ctx, c, "Failed to set presentation slides", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, presentation)
+}
+
+// StartPresentation godoc
+// @Summary Start presenting a board
+// @Description The current user presents, unless presenter_id designates another member. The presenter changes
+// @Description slides with presentation_goto messages over WebSocket.
+// @Tags presentations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.StartPresentationRequest false "Presenter and first slide"
+// @Success 200 {object} models.Presentation
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/presentation/start [post]
+func (h *PresentationHandler) StartPresentation(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.StartPresentationRequest
+	if len(c.Request.Body()) > 0 {
+		if bindErr := c.BindJSON(&req); bindErr != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	presentation, err := h.presentationService.Start(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondPresentationError(ctx, c, "Failed to start presentation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, presentation)
+}
+
+// StopPresentation godoc
+// @Summary Stop presenting a board
+// @Description The presenter and editors can stop the session.
+// @Tags presentations
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} models.Presentation
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/presentation/stop [post]
+func (h *PresentationHandler) StopPresentation(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	presentation, err := h.presentationService.Stop(ctx, workspaceID, userID)
+	if err != nil {
+		respondPresentationError(ctx, c, "Failed to stop presentation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, presentation)
+}
+
+// ChangePresenter godoc
+// @Summary Hand the presentation to another member
+// @Description The presenter and editors can hand the running session over.
+// @Tags presentations
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.ChangePresenterRequest true "New presenter"
+// @Success 200 {object} models.Presentation
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/presentation/presenter [put]
+func (h *PresentationHandler) ChangePresenter(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangePresenterRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	presentation, err := h.presentationService.ChangePresenter(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondPresentationError(ctx, c, "Failed to change presenter", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, presentation)
+}
+
+func respondPresentationError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrPresentationRunning), errors.Is(err, service.ErrPresentationNotRunning):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrNotPresenter):
+		c.JSON(http.StatusForbidden, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type PushHandler struct {
+	webPushService *service.WebPushService
+}
+
+func NewPushHandler(webPushService *service.WebPushService) *PushHandler {
+	return &PushHandler{
+		webPushService: webPushService,
+	}
+}
+
+// GetPushPublicKey godoc
+// @Summary Get the VAPID public key
+// @Description Returns the applicationServerKey browsers subscribe to push notifications with
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+//
+// @Router /api/v1/notifications/push/public-key [get]
+func (h *PushHandler) GetPushPublicKey(ctx context.Context, c *app.RequestContext) {
+	publicKey, err := h.webPushService.PublicKey()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"error": "Push notifications are not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"public_key": publicKey})
+}
+
+// RegisterPushSubscription godoc
+// @Summary Register a push subscription
+// @Description Stores the PushSubscription of a browser, mentions and invites are pushed to it while the user is offline
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param request body models.RegisterPushSubscriptionRequest true "Browser subscription"
+// @Success 201 {object} models.PushSubscription
+// @Failure 400 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+//
+// @Router /api/v1/notifications/push/subscriptions [post]
+func (h *PushHandler) RegisterPushSubscription(ctx context.Context, c *app.RequestContext) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.RegisterPushSubscriptionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	sub, err := h.webPushService.Subscribe(ctx, userUUID, &req, string(c.UserAgent()))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWebPushDisabled):
+			c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"error": "Push notifications are not enabled"})
+		case errors.Is(err, service.ErrInvalidPushSubscription):
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		default:
+			hlog.CtxErrorf(ctx, "Failed to register push subscription: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to register push subscription"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// UnregisterPushSubscription godoc
+// @Summary Unregister a push subscription
+// @Description Removes the subscription of a browser, for example when the user turns push notifications off
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param request body models.UnregisterPushSubscriptionRequest true "Subscription endpoint"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/notifications/push/subscriptions [delete]
+func (h *PushHandler) UnregisterPushSubscription(ctx context.Context, c *app.RequestContext) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.UnregisterPushSubscriptionRequest
+	if err := c.BindJSON(&req); err != nil || req.Endpoint == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.webPushService.Unsubscribe(ctx, userUUID, req.Endpoint); err != nil {
+		switch {
+		case errors.Is(err, service.ErrWebPushDisabled):
+			c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"error": "Push notifications are not enabled"})
+		case errors.Is(err, service.ErrPushSubscriptionNotFound):
+			c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Push subscription not found"})
+		default:
+			hlog.CtxErrorf(ctx, "Failed to unregister push subscription: %v", err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to unregister push subscription"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Push subscription removed"})
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type RecordingHandler struct {
+	recordingService *service.RecordingService
+}
+
+func NewRecordingHandler(recordingService *service.RecordingService) *RecordingHandler {
+	return &RecordingHandler{
+		recordingService: recordingService,
+	}
+}
+
+// StartRecording godoc
+// @Summary Start recording a session of a board
+// @Description Records the operations, cursors, selections and presence of everyone on the board until the
+// @Description recording is stopped, for at most 4 hours. A board records one session at a time.
+// @Tags recordings
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.StartRecordingRequest false "Name of the session"
+// @Success 201 {object} models.SessionRecording
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/recordings [post]
+func (h *RecordingHandler) StartRecording(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.StartRecordingRequest
+	if len(c.Request.Body()) > 0 {
+		if bindErr := c.BindJSON(&req); bindErr != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	rec, err := h.recordingService.Start(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondRecordingError(ctx, c, "Failed to start recording", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rec)
+}
+
+// StopRecording godoc
+// @Summary Stop recording a session
+// @Description Stops the recording and stores its events compressed, after which it can be played back.
+// @Tags recordings
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param recording_id path string true "Recording ID"
+// @Success 200 {object} models.SessionRecording
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/recordings/{recording_id}/stop [post]
+func (h *RecordingHandler) StopRecording(ctx context.Context, c *app.RequestContext) {
+	workspaceID, recordingID, ok := recordingRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	rec, err := h.recordingService.Stop(ctx, workspaceID, recordingID)
+	if err != nil {
+		respondRecordingError(ctx, c, "Failed to stop recording", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rec)
+}
+
+// ListRecordings godoc
+// @Summary List the recorded sessions of a board
+// @Tags recordings
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/recordings [get]
+func (h *RecordingHandler) ListRecordings(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	recordings, err := h.recordingService.List(ctx, workspaceID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list recordings: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list recordings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"recordings": recordings})
+}
+
+// GetRecording godoc
+// @Summary Get a recorded session
+// @Tags recordings
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param recording_id path string true "Recording ID"
+// @Success 200 {object} models.SessionRecording
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/recordings/{recording_id} [get]
+func (h *RecordingHandler) GetRecording(ctx context.Context, c *app.RequestContext) {
+	workspaceID, recordingID, ok := recordingRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	rec, err := h.recordingService.Get(ctx, workspaceID, recordingID)
+	if err != nil {
+		respondRecordingError(ctx, c, "Failed to get recording", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rec)
+}
+
+// PlayRecording godoc
+// @Summary Play a recorded session back
+// @Description Streams the recorded events as Server-Sent Events named after their message type, as far apart as
+// @Description they happened divided by speed. An end event follows the last one.
+// @Tags recordings
+// @Produce text/event-stream
+// @Param workspace_id path string true "Workspace ID"
+// @Param recording_id path string true "Recording ID"
+// @Param speed query number false "Playback speed, 1 to 32" default(1)
+// @Success 200 {string} string "Event stream"
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/recordings/{recording_id}/playback [get]
+func (h *RecordingHandler) PlayRecording(ctx context.Context, c *app.RequestContext) {
+	workspaceID, recordingID, ok := recordingRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	speed := 1.0
+	if raw := c.Query("speed"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed < 1 || parsed > service.MaxPlaybackSpeed {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid speed"})
+			return
+		}
+		speed = parsed
+	}
+
+	// Checked before streaming, errors can't change the status after
+	rec, err := h.recordingService.Get(ctx, workspaceID, recordingID)
+	if err != nil {
+		respondRecordingError(ctx, c, "Failed to play recording", err)
+		return
+	}
+	if rec.Status != models.RecordingStatusReady {
+		respondRecordingError(ctx, c, "Failed to play recording", service.ErrRecordingNotReady)
+		return
+	}
+
+	c.SetStatusCode(http.StatusOK)
+	c.Response.Header.Set("Content-Type", "text/event-stream")
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("Connection", "keep-alive")
+	c.Response.Header.Set("X-Accel-Buffering", "no")
+	c.Response.HijackWriter(resp.NewChunkedBodyWriter(&c.Response, c.GetWriter()))
+
+	err = h.recordingService.Play(ctx, workspaceID, recordingID, speed, func(event *models.RecordedEvent) error {
+		return writeSSEEvent(c, string(event.Type), event)
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			hlog.CtxWarnf(ctx, "Failed to play recording %s: %v", recordingID, err)
+			_ = writeSSEEvent(c, "error", map[string]interface{}{"error": "Playback failed"})
+		}
+		return
+	}
+
+	_ = writeSSEEvent(c, "end", map[string]interface{}{"recording_id": recordingID})
+}
+
+// recordingRequestIDs parses the workspace and recording IDs of a request,
+// responding with 400 if either is invalid
+func recordingRequestIDs(c *app.RequestContext) (workspaceID, recordingID uuid.UUID, ok bool) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	recordingID, err = uuid.Parse(c.Param("recording_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid recording ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return workspaceID, recordingID, true
+}
+
+func respondRecordingError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrRecordingNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrRecordingRunning), errors.Is(err, service.ErrRecordingNotRunning),
+		errors.Is(err, service.ErrRecordingNotReady):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
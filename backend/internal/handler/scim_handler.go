@@ -0,0 +1,667 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	// scimContentType is the media type of SCIM requests and responses
+	scimContentType = "application/scim+json"
+	// scimBasePath is where the SCIM endpoints are mounted
+	scimBasePath = "/scim/v2"
+
+	defaultSCIMGroupLimit = 50
+	maxSCIMGroupLimit     = 200
+)
+
+// SCIMHandler serves the SCIM 2.0 endpoints identity providers provision
+// users and groups with, and the admin endpoints that map SCIM groups to
+// workspaces
+type SCIMHandler struct {
+	scimService *service.SCIMService
+}
+
+func NewSCIMHandler(scimService *service.SCIMService) *SCIMHandler {
+	return &SCIMHandler{
+		scimService: scimService,
+	}
+}
+
+// GetServiceProviderConfig godoc
+// @Summary Describe the SCIM service provider
+// @Description Advertises PATCH and filtering support and bearer token authentication
+// @Tags scim
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) GetServiceProviderConfig(ctx context.Context, c *app.RequestContext) {
+	writeSCIM(ctx, c, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{models.SCIMSchemaServiceProviderConfig},
+		"patch":          map[string]interface{}{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": 200},
+		"changePassword": map[string]interface{}{"supported": false},
+		"sort":           map[string]interface{}{"supported": false},
+		"etag":           map[string]interface{}{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The token configured in scim.token",
+			"primary":     true,
+		}},
+		"meta": map[string]interface{}{"resourceType": "ServiceProviderConfig", "location": scimURL(c, "ServiceProviderConfig")},
+	})
+}
+
+// GetResourceTypes godoc
+// @Summary List SCIM resource types
+// @Tags scim
+// @Produce json
+// @Success 200 {object} models.SCIMListResponse
+//
+// @Router /scim/v2/ResourceTypes [get]
+func (h *SCIMHandler) GetResourceTypes(ctx context.Context, c *app.RequestContext) {
+	resourceTypes := []map[string]interface{}{
+		{
+			"schemas":  []string{models.SCIMSchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   models.SCIMSchemaUser,
+			"meta":     map[string]interface{}{"resourceType": "ResourceType", "location": scimURL(c, "ResourceTypes/User")},
+		},
+		{
+			"schemas":  []string{models.SCIMSchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   models.SCIMSchemaGroup,
+			"meta":     map[string]interface{}{"resourceType": "ResourceType", "location": scimURL(c, "ResourceTypes/Group")},
+		},
+	}
+
+	writeSCIM(ctx, c, http.StatusOK, &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMSchemaListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+// GetSchemas godoc
+// @Summary List SCIM schemas
+// @Description Lists the attributes of users and groups that are stored
+// @Tags scim
+// @Produce json
+// @Success 200 {object} models.SCIMListResponse
+//
+// @Router /scim/v2/Schemas [get]
+func (h *SCIMHandler) GetSchemas(ctx context.Context, c *app.RequestContext) {
+	attribute := func(name, attrType string, required bool, extra map[string]interface{}) map[string]interface{} {
+		attr := map[string]interface{}{
+			"name":        name,
+			"type":        attrType,
+			"multiValued": false,
+			"required":    required,
+			"mutability":  "readWrite",
+			"returned":    "default",
+			"uniqueness":  "none",
+		}
+		for key, value := range extra {
+			attr[key] = value
+		}
+		return attr
+	}
+
+	schemas := []map[string]interface{}{
+		{
+			"schemas": []string{models.SCIMSchemaSchema},
+			"id":      models.SCIMSchemaUser,
+			"name":    "User",
+			"attributes": []map[string]interface{}{
+				attribute("userName", "string", true, map[string]interface{}{"uniqueness": "server"}),
+				attribute("name", "complex", false, nil),
+				attribute("displayName", "string", false, nil),
+				attribute("emails", "complex", false, map[string]interface{}{"multiValued": true}),
+				attribute("active", "boolean", false, nil),
+				attribute("externalId", "string", false, nil),
+			},
+			"meta": map[string]interface{}{"resourceType": "Schema", "location": scimURL(c, "Schemas/"+models.SCIMSchemaUser)},
+		},
+		{
+			"schemas": []string{models.SCIMSchemaSchema},
+			"id":      models.SCIMSchemaGroup,
+			"name":    "Group",
+			"attributes": []map[string]interface{}{
+				attribute("displayName", "string", true, map[string]interface{}{"uniqueness": "server"}),
+				attribute("members", "complex", false, map[string]interface{}{"multiValued": true}),
+				attribute("externalId", "string", false, nil),
+			},
+			"meta": map[string]interface{}{"resourceType": "Schema", "location": scimURL(c, "Schemas/"+models.SCIMSchemaGroup)},
+		},
+	}
+
+	writeSCIM(ctx, c, http.StatusOK, &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMSchemaListResponse},
+		TotalResults: len(schemas),
+		StartIndex:   1,
+		ItemsPerPage: len(schemas),
+		Resources:    schemas,
+	})
+}
+
+// ListUsers godoc
+// @Summary List SCIM users
+// @Description Lists users, optionally filtered with userName, emails.value, externalId or id eq "value"
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param filter query string false "Filter, e.g. userName eq \"jane@example.com\""
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Maximum number of results (default 100, max 200)"
+// @Success 200 {object} models.SCIMListResponse
+//
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) ListUsers(ctx context.Context, c *app.RequestContext) {
+	startIndex, count := scimPageParams(c)
+
+	list, err := h.scimService.ListUsers(ctx, c.Query("filter"), startIndex, count)
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to list SCIM users", err)
+		return
+	}
+
+	if users, ok := list.Resources.([]*models.SCIMUserResource); ok {
+		for _, user := range users {
+			user.Meta.Location = scimURL(c, "Users/"+user.ID)
+		}
+	}
+	writeSCIM(ctx, c, http.StatusOK, list)
+}
+
+// GetUser godoc
+// @Summary Get a SCIM user
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.SCIMUserResource
+//
+// @Router /scim/v2/Users/{user_id} [get]
+func (h *SCIMHandler) GetUser(ctx context.Context, c *app.RequestContext) {
+	user, err := h.scimService.GetUser(ctx, c.Param("user_id"))
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to get SCIM user", err)
+		return
+	}
+
+	writeSCIMUser(ctx, c, http.StatusOK, user)
+}
+
+// CreateUser godoc
+// @Summary Provision a user
+// @Description Creates a user with the email address in userName. Users that already exist are answered with 409.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.SCIMUserResource true "User"
+// @Success 201 {object} models.SCIMUserResource
+//
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) CreateUser(ctx context.Context, c *app.RequestContext) {
+	var resource models.SCIMUserResource
+	if !bindSCIM(ctx, c, &resource) {
+		return
+	}
+
+	user, err := h.scimService.CreateUser(ctx, &resource)
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to create SCIM user", err)
+		return
+	}
+
+	writeSCIMUser(ctx, c, http.StatusCreated, user)
+}
+
+// ReplaceUser godoc
+// @Summary Replace a SCIM user
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param user_id path string true "User ID"
+// @Param request body models.SCIMUserResource true "User"
+// @Success 200 {object} models.SCIMUserResource
+//
+// @Router /scim/v2/Users/{user_id} [put]
+func (h *SCIMHandler) ReplaceUser(ctx context.Context, c *app.RequestContext) {
+	var resource models.SCIMUserResource
+	if !bindSCIM(ctx, c, &resource) {
+		return
+	}
+
+	user, err := h.scimService.ReplaceUser(ctx, c.Param("user_id"), &resource)
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to replace SCIM user", err)
+		return
+	}
+
+	writeSCIMUser(ctx, c, http.StatusOK, user)
+}
+
+// PatchUser godoc
+// @Summary Patch a SCIM user
+// @Description Updates attributes of a user. Setting active to false deactivates the user, who can no longer
+// @Description sign in and loses the workspace memberships of their groups.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param user_id path string true "User ID"
+// @Param request body models.SCIMPatchRequest true "Patch operations"
+// @Success 200 {object} models.SCIMUserResource
+//
+// @Router /scim/v2/Users/{user_id} [patch]
+func (h *SCIMHandler) PatchUser(ctx context.Context, c *app.RequestContext) {
+	var req models.SCIMPatchRequest
+	if !bindSCIM(ctx, c, &req) {
+		return
+	}
+
+	user, err := h.scimService.PatchUser(ctx, c.Param("user_id"), &req)
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to patch SCIM user", err)
+		return
+	}
+
+	writeSCIMUser(ctx, c, http.StatusOK, user)
+}
+
+// DeleteUser godoc
+// @Summary Deprovision a user
+// @Description Deactivates the user. Users own workspaces and content, so they are never deleted.
+// @Tags scim
+// @Param Authorization header string true "Bearer token"
+// @Param user_id path string true "User ID"
+// @Success 204
+//
+// @Router /scim/v2/Users/{user_id} [delete]
+func (h *SCIMHandler) DeleteUser(ctx context.Context, c *app.RequestContext) {
+	if err := h.scimService.DeleteUser(ctx, c.Param("user_id")); err != nil {
+		respondSCIMError(ctx, c, "Failed to delete SCIM user", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups godoc
+// @Summary List SCIM groups
+// @Description Lists groups, optionally filtered with displayName, externalId or id eq "value"
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param filter query string false "Filter, e.g. displayName eq \"Design\""
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Maximum number of results (default 100, max 200)"
+// @Param excludedAttributes query string false "members to leave out the members"
+// @Success 200 {object} models.SCIMListResponse
+//
+// @Router /scim/v2/Groups [get]
+func (h *SCIMHandler) ListGroups(ctx context.Context, c *app.RequestContext) {
+	startIndex, count := scimPageParams(c)
+
+	list, err := h.scimService.ListGroups(ctx, c.Query("filter"), startIndex, count, scimWithMembers(c))
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to list SCIM groups", err)
+		return
+	}
+
+	if groups, ok := list.Resources.([]*models.SCIMGroupResource); ok {
+		for _, group := range groups {
+			setSCIMGroupLocations(c, group)
+		}
+	}
+	writeSCIM(ctx, c, http.StatusOK, list)
+}
+
+// GetGroup godoc
+// @Summary Get a SCIM group
+// @Tags scim
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param group_id path string true "Group ID"
+// @Param excludedAttributes query string false "members to leave out the members"
+// @Success 200 {object} models.SCIMGroupResource
+//
+// @Router /scim/v2/Groups/{group_id} [get]
+func (h *SCIMHandler) GetGroup(ctx context.Context, c *app.RequestContext) {
+	group, err := h.scimService.GetGroup(ctx, c.Param("group_id"), scimWithMembers(c))
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to get SCIM group", err)
+		return
+	}
+
+	writeSCIMGroup(ctx, c, http.StatusOK, group)
+}
+
+// CreateGroup godoc
+// @Summary Provision a group
+// @Description Creates a group with its members. Map the group to workspaces with the admin API to grant its
+// @Description members workspace roles.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.SCIMGroupResource true "Group"
+// @Success 201 {object} models.SCIMGroupResource
+//
+// @Router /scim/v2/Groups [post]
+func (h *SCIMHandler) CreateGroup(ctx context.Context, c *app.RequestContext) {
+	var resource models.SCIMGroupResource
+	if !bindSCIM(ctx, c, &resource) {
+		return
+	}
+
+	group, err := h.scimService.CreateGroup(ctx, &resource)
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to create SCIM group", err)
+		return
+	}
+
+	writeSCIMGroup(ctx, c, http.StatusCreated, group)
+}
+
+// ReplaceGroup godoc
+// @Summary Replace a SCIM group
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param group_id path string true "Group ID"
+// @Param request body models.SCIMGroupResource true "Group"
+// @Success 200 {object} models.SCIMGroupResource
+//
+// @Router /scim/v2/Groups/{group_id} [put]
+func (h *SCIMHandler) ReplaceGroup(ctx context.Context, c *app.RequestContext) {
+	var resource models.SCIMGroupResource
+	if !bindSCIM(ctx, c, &resource) {
+		return
+	}
+
+	group, err := h.scimService.ReplaceGroup(ctx, c.Param("group_id"), &resource)
+	if err != nil {
+		respondSCIMError(ctx, c, "Failed to replace SCIM group", err)
+		return
+	}
+
+	writeSCIMGroup(ctx, c, http.StatusOK, group)
+}
+
+// PatchGroup godoc
+// @Summary Patch a SCIM group
+// @Description Renames a group or adds, removes and replaces its members
+// @Tags scim
+// @Accept json
+// @Param Authorization header string true "Bearer token"
+// @Param group_id path string true "Group ID"
+// @Param request body models.SCIMPatchRequest true "Patch operations"
+// @Success 204
+//
+// @Router /scim/v2/Groups/{group_id} [patch]
+func (h *SCIMHandler) PatchGroup(ctx context.Context, c *app.RequestContext) {
+	var req models.SCIMPatchRequest
+	if !bindSCIM(ctx, c, &req) {
+		return
+	}
+
+	if err := h.scimService.PatchGroup(ctx, c.Param("group_id"), &req); err != nil {
+		respondSCIMError(ctx, c, "Failed to patch SCIM group", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a SCIM group
+// @Description Deletes a group. Its members lose the workspace memberships it granted.
+// @Tags scim
+// @Param Authorization header string true "Bearer token"
+// @Param group_id path string true "Group ID"
+// @Success 204
+//
+// @Router /scim/v2/Groups/{group_id} [delete]
+func (h *SCIMHandler) DeleteGroup(ctx context.Context, c *app.RequestContext) {
+	if err := h.scimService.DeleteGroup(ctx, c.Param("group_id")); err != nil {
+		respondSCIMError(ctx, c, "Failed to delete SCIM group", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroupWorkspaces godoc
+// @Summary List SCIM groups with their workspaces
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum number of groups (default 50, max 200)"
+// @Param offset query int false "Number of groups to skip"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/scim/groups [get]
+func (h *SCIMHandler) ListGroupWorkspaces(ctx context.Context, c *app.RequestContext) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	if limit <= 0 {
+		limit = defaultSCIMGroupLimit
+	}
+	if limit > maxSCIMGroupLimit {
+		limit = maxSCIMGroupLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	groups, err := h.scimService.ListGroupWorkspaces(ctx, limit, offset)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to list SCIM groups: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to list SCIM groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"groups": groups})
+}
+
+// SetGroupWorkspace godoc
+// @Summary Map a SCIM group to a workspace
+// @Description Grants the members of the group the role in the workspace, or changes the role of the mapping.
+// @Description Members that are in several mapped groups get the highest role. Owners and invited members keep
+// @Description their membership.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param group_id path string true "SCIM group ID"
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.SetSCIMGroupWorkspaceRequest true "Role (editor or viewer)"
+// @Success 200 {object} models.SCIMGroupWorkspace
+//
+// @Router /api/v1/admin/scim/groups/{group_id}/workspaces/{workspace_id} [put]
+func (h *SCIMHandler) SetGroupWorkspace(ctx context.Context, c *app.RequestContext) {
+	groupID, workspaceID, ok := scimMappingParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetSCIMGroupWorkspaceRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	mapping, err := h.scimService.SetGroupWorkspace(ctx, groupID, workspaceID, req.Role)
+	if err != nil {
+		respondSCIMMappingError(ctx, c, "Failed to map SCIM group", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// DeleteGroupWorkspace godoc
+// @Summary Unmap a SCIM group from a workspace
+// @Description Members of the group lose the membership it granted, unless another group grants one
+// @Tags admin
+// @Produce json
+// @Param group_id path string true "SCIM group ID"
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/admin/scim/groups/{group_id}/workspaces/{workspace_id} [delete]
+func (h *SCIMHandler) DeleteGroupWorkspace(ctx context.Context, c *app.RequestContext) {
+	groupID, workspaceID, ok := scimMappingParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.scimService.DeleteGroupWorkspace(ctx, groupID, workspaceID); err != nil {
+		respondSCIMMappingError(ctx, c, "Failed to unmap SCIM group", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "SCIM group unmapped successfully"})
+}
+
+func scimMappingParams(c *app.RequestContext) (groupID, workspaceID uuid.UUID, ok bool) {
+	groupID, err := uuid.Parse(c.Param("group_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid group ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	workspaceID, err = uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return groupID, workspaceID, true
+}
+
+// scimPageParams reads startIndex and count, count is -1 when missing
+func scimPageParams(c *app.RequestContext) (startIndex, count int) {
+	startIndex, _ = strconv.Atoi(c.Query("startIndex"))
+	count = -1
+	if value := c.Query("count"); value != "" {
+		count, _ = strconv.Atoi(value)
+	}
+	return startIndex, count
+}
+
+// scimWithMembers reports whether excludedAttributes keeps group members
+func scimWithMembers(c *app.RequestContext) bool {
+	for _, attribute := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attribute), "members") {
+			return false
+		}
+	}
+	return true
+}
+
+// scimURL returns the absolute URL of a path below the SCIM base path
+func scimURL(c *app.RequestContext, path string) string {
+	scheme := string(c.Request.Header.Peek("X-Forwarded-Proto"))
+	if scheme == "" {
+		scheme = string(c.URI().Scheme())
+	}
+	return scheme + "://" + string(c.Host()) + scimBasePath + "/" + path
+}
+
+func setSCIMGroupLocations(c *app.RequestContext, group *models.SCIMGroupResource) {
+	group.Meta.Location = scimURL(c, "Groups/"+group.ID)
+	for i := range group.Members {
+		group.Members[i].Ref = scimURL(c, "Users/"+group.Members[i].Value)
+	}
+}
+
+func writeSCIMUser(ctx context.Context, c *app.RequestContext, status int, user *models.SCIMUserResource) {
+	user.Meta.Location = scimURL(c, "Users/"+user.ID)
+	c.Header("Location", user.Meta.Location)
+	writeSCIM(ctx, c, status, user)
+}
+
+func writeSCIMGroup(ctx context.Context, c *app.RequestContext, status int, group *models.SCIMGroupResource) {
+	setSCIMGroupLocations(c, group)
+	c.Header("Location", group.Meta.Location)
+	writeSCIM(ctx, c, status, group)
+}
+
+// bindSCIM decodes a SCIM request body. Identity providers send
+// application/scim+json, which the JSON binding doesn't accept.
+func bindSCIM(ctx context.Context, c *app.RequestContext, v any) bool {
+	if err := json.Unmarshal(c.Request.Body(), v); err != nil {
+		writeSCIMError(ctx, c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return false
+	}
+	return true
+}
+
+func writeSCIM(ctx context.Context, c *app.RequestContext, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to encode SCIM response: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, scimContentType, data)
+}
+
+func writeSCIMError(ctx context.Context, c *app.RequestContext, status int, scimType, detail string) {
+	writeSCIM(ctx, c, status, &models.SCIMErrorResponse{
+		Schemas:  []string{models.SCIMSchemaError},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
+
+func respondSCIMError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	var scimErr *service.SCIMError
+	if errors.As(err, &scimErr) {
+		writeSCIMError(ctx, c, scimErr.Status, scimErr.Type, scimErr.Detail)
+		return
+	}
+
+	hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+	writeSCIMError(ctx, c, http.StatusInternalServerError, "", msg)
+}
+
+func respondSCIMMappingError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrSCIMGroupNotFound),
+		errors.Is(err, service.ErrSCIMWorkspaceNotFound),
+		errors.Is(err, service.ErrSCIMGroupWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSCIMRole):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type SearchHandler struct {
+	searchService *service.SearchService
+}
+
+func NewSearchHandler(searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+// Search godoc
+// @Summary Search a workspace
+// @Description Searches the text of elements, comments, and the filenames and recognized text of assets at once.
+// @Description Results of all types are ranked together: every word of q must occur, words at the start of a
+// @Description word, the whole query as a phrase and shorter texts rank higher.
+// @Tags search
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param q query string true "Search query"
+// @Param types query string false "Comma separated result types: element, comment, asset"
+// @Param limit query int false "Page size, 20 by default and at most 50"
+// @Param offset query int false "Results to skip"
+// @Success 200 {object} models.SearchResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/search [get]
+func (h *SearchHandler) Search(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	req := models.SearchRequest{Query: c.Query("q")}
+	req.Limit, _ = strconv.Atoi(c.Query("limit"))
+	req.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	if raw := c.Query("types"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			switch t := models.SearchResultType(strings.TrimSpace(name)); t {
+			case models.SearchResultElement, models.SearchResultComment, models.SearchResultAsset:
+				req.Types = append(req.Types, t)
+			default:
+				c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid result type: " + name})
+				return
+			}
+		}
+	}
+
+	resp, err := h.searchService.Search(ctx, workspaceID, &req)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to search workspace: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -25,12 +25,13 @@ func NewSnapshotHandler(snapshotService *service.SnapshotService) *SnapshotHandl
 
 // CreateSnapshot godoc
 // @Summary Create a canvas snapshot
-// @Description Creates a new version snapshot of the current canvas state
+// @Description Creates a new version snapshot of the current canvas state, or returns the unchanged latest one with 200
 // @Tags snapshots
 // @Accept json
 // @Produce json
 // @Param workspace_id path string true "Workspace ID"
-// @Param request body models.CreateSnapshotRequest false "Snapshot description"
+// @Param request body models.CreateSnapshotRequest false "Snapshot name, description, tags and pinned flag"
+// @Success 200 {object} models.SnapshotResponse
 // @Success 201 {object} models.SnapshotResponse
 //
 // @Router /api/v1/workspaces/{workspace_id}/snapshots [post]
@@ -50,8 +51,8 @@ func (h *SnapshotHandler) CreateSnapshot(ctx context.Context, c *app.RequestCont
 
 	var req models.CreateSnapshotRequest
 	if bindErr := c.BindJSON(&req); bindErr != nil {
-		// Description is optional, so it's OK if body is empty
-		req.Description = nil
+		// All fields are optional, so it's OK if body is empty
+		req = models.CreateSnapshotRequest{}
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
@@ -60,13 +61,19 @@ func (h *SnapshotHandler) CreateSnapshot(ctx context.Context, c *app.RequestCont
 		return
 	}
 
-	snapshot, err := h.snapshotService.CreateSnapshot(ctx, workspaceID, userUUID, req.Description)
+	snapshot, created, err := h.snapshotService.CreateSnapshot(ctx, workspaceID, userUUID, &req)
 	if err != nil {
 		hlog.CtxErrorf(ctx, "Failed to create snapshot: %v", err)
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
 		return
 	}
 
+	// Nothing changed since the latest snapshot, which is returned instead
+	if !created {
+		c.JSON(http.StatusOK, snapshot.ToResponse())
+		return
+	}
+
 	c.JSON(http.StatusCreated, snapshot.ToResponse())
 }
 
@@ -77,6 +84,8 @@ func (h *SnapshotHandler) CreateSnapshot(ctx context.Context, c *app.RequestCont
 // @Accept json
 // @Produce json
 // @Param workspace_id path string true "Workspace ID"
+// @Param tag query string false "Only snapshots with this tag"
+// @Param pinned query bool false "Only pinned or unpinned snapshots"
 // @Param limit query int false "Number of results" default(20)
 // @Param offset query int false "Offset for pagination" default(0)
 // @Success 200 {object} models.SnapshotListResponse
@@ -90,15 +99,13 @@ func (h *SnapshotHandler) ListSnapshots(ctx context.Context, c *app.RequestConte
 		return
 	}
 
-	// Parse pagination parameters
-	limit, _ := strconv.Atoi(c.Query("limit"))
-	offset, _ := strconv.Atoi(c.Query("offset"))
-
-	if limit <= 0 {
-		limit = 20
+	var filter models.SnapshotListFilter
+	if bindErr := c.BindQuery(&filter); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid query parameters"})
+		return
 	}
 
-	snapshots, total, err := h.snapshotService.ListSnapshots(ctx, workspaceID, limit, offset)
+	snapshots, total, err := h.snapshotService.ListSnapshots(ctx, workspaceID, filter)
 	if err != nil {
 		hlog.CtxErrorf(ctx, "Failed to list snapshots: %v", err)
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get snapshots"})
@@ -220,6 +227,173 @@ func (h *SnapshotHandler) RestoreSnapshot(ctx context.Context, c *app.RequestCon
 	c.JSON(http.StatusOK, map[string]interface{}{"message": "Snapshot restored successfully"})
 }
 
+// RestoreSnapshotElements godoc
+// @Summary Restore elements from a snapshot
+// @Description Adds the given elements of a snapshot back to the board as new elements without rolling back anything else
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param snapshot_id path string true "Snapshot ID"
+// @Param request body models.RestoreSnapshotElementsRequest true "IDs of the elements in the snapshot"
+// @Success 201 {object} models.ElementListResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/snapshots/{snapshot_id}/restore-elements [post]
+func (h *SnapshotHandler) RestoreSnapshotElements(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	snapshotIDStr := c.Param("snapshot_id")
+	snapshotID, err := uuid.Parse(snapshotIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.RestoreSnapshotElementsRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	elements, err := h.snapshotService.RestoreElements(ctx, workspaceID, userUUID, snapshotID, req.ElementIDs)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to restore snapshot elements: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	responses := make([]models.ElementResponse, len(elements))
+	for i := range elements {
+		responses[i] = elements[i].ToResponse()
+	}
+
+	c.JSON(http.StatusCreated, models.ElementListResponse{
+		Elements: responses,
+		Total:    len(responses),
+	})
+}
+
+// RestoreSnapshotToNew godoc
+// @Summary Restore a snapshot into a new workspace
+// @Description Creates a new private workspace from a snapshot, copying referenced assets, instead of overwriting the board
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param snapshot_id path string true "Snapshot ID"
+// @Param request body models.RestoreSnapshotToNewRequest false "Name of the new workspace"
+// @Success 201 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/snapshots/{snapshot_id}/restore-to-new [post]
+func (h *SnapshotHandler) RestoreSnapshotToNew(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	snapshotIDStr := c.Param("snapshot_id")
+	snapshotID, err := uuid.Parse(snapshotIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.RestoreSnapshotToNewRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		// The name is optional, so it's OK if body is empty
+		req = models.RestoreSnapshotToNewRequest{}
+	}
+
+	workspace, err := h.snapshotService.RestoreToNewWorkspace(ctx, workspaceID, userUUID, snapshotID, req.Name)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to restore snapshot to new workspace: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	// The user who branches the board owns the new workspace
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"workspace": &models.WorkspaceWithRole{
+			Workspace: *workspace,
+			UserRole:  models.WorkspaceRoleOwner,
+		},
+	})
+}
+
+// UpdateSnapshot godoc
+// @Summary Update a snapshot
+// @Description Renames, tags, pins or unpins a snapshot. Pinned snapshots are never removed by cleanup.
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param snapshot_id path string true "Snapshot ID"
+// @Param request body models.UpdateSnapshotRequest true "Fields to update"
+// @Success 200 {object} models.SnapshotResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/snapshots/{snapshot_id} [put]
+func (h *SnapshotHandler) UpdateSnapshot(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	snapshotIDStr := c.Param("snapshot_id")
+	snapshotID, err := uuid.Parse(snapshotIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	var req models.UpdateSnapshotRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	snapshot, err := h.snapshotService.UpdateSnapshot(ctx, workspaceID, snapshotID, &req)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to update snapshot: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot.ToResponse())
+}
+
 // DeleteSnapshot godoc
 // @Summary Delete a snapshot
 // @Description Deletes a specific snapshot
@@ -245,7 +419,19 @@ func (h *SnapshotHandler) DeleteSnapshot(ctx context.Context, c *app.RequestCont
 		return
 	}
 
-	if err := h.snapshotService.DeleteSnapshot(ctx, workspaceID, snapshotID); err != nil {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	if err := h.snapshotService.DeleteSnapshot(ctx, workspaceID, userUUID, snapshotID); err != nil {
 		hlog.CtxErrorf(ctx, "Failed to delete snapshot: %v", err)
 		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
 		return
@@ -253,3 +439,80 @@ func (h *SnapshotHandler) DeleteSnapshot(ctx context.Context, c *app.RequestCont
 
 	c.JSON(http.StatusOK, map[string]interface{}{"message": "Snapshot deleted successfully"})
 }
+
+// GetRetentionPolicy godoc
+// @Summary Get the snapshot retention policy
+// @Description Returns which snapshots the cleanup job keeps, the default policy when none is configured
+// @Tags snapshots
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} models.SnapshotRetentionPolicy
+//
+// @Router /api/v1/workspaces/{workspace_id}/snapshots/retention [get]
+func (h *SnapshotHandler) GetRetentionPolicy(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	policy, err := h.snapshotService.GetRetentionPolicy(ctx, workspaceID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get retention policy: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get retention policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateRetentionPolicy godoc
+// @Summary Update the snapshot retention policy
+// @Description Replaces the retention policy of the workspace. Pinned snapshots are always kept.
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.UpdateSnapshotRetentionRequest true "Retention policy"
+// @Success 200 {object} models.SnapshotRetentionPolicy
+//
+// @Router /api/v1/workspaces/{workspace_id}/snapshots/retention [put]
+func (h *SnapshotHandler) UpdateRetentionPolicy(ctx context.Context, c *app.RequestContext) {
+	workspaceIDStr := c.Param("workspace_id")
+	workspaceID, err := uuid.Parse(workspaceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.UpdateSnapshotRetentionRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	policy, err := h.snapshotService.UpdateRetentionPolicy(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to update retention policy: %v", err)
+		if respondPlanLimit(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	// sseHeartbeatInterval keeps idle streams alive through proxies
+	sseHeartbeatInterval = 15 * time.Second
+)
+
+// SSEHandler streams realtime workspace events over Server-Sent Events for
+// networks that block WebSockets. Clients send their own updates over REST.
+type SSEHandler struct {
+	hub              *service.Hub
+	wsHandler        *WebSocketHandler
+	workspaceService *service.WorkspaceService
+
+	// Active stream clients indexed by client ID
+	clients sync.Map
+}
+
+func NewSSEHandler(hub *service.Hub, wsHandler *WebSocketHandler, workspaceService *service.WorkspaceService) *SSEHandler {
+	return &SSEHandler{
+		hub:              hub,
+		wsHandler:        wsHandler,
+		workspaceService: workspaceService,
+	}
+}
+
+// Events godoc
+// @Summary Stream workspace events
+// @Description Server-Sent Events fallback for the WebSocket. EventSource can't set headers, so the access token is passed as a query parameter.
+// @Tags realtime
+// @Produce text/event-stream
+// @Param workspace_id path string true "Workspace ID"
+// @Param token query string true "Access token"
+// @Success 200 {string} string "Event stream"
+//
+// @Router /api/v1/workspaces/{workspace_id}/events [get]
+func (h *SSEHandler) Events(ctx context.Context, c *app.RequestContext) {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	role, err := h.workspaceService.GetUserRole(ctx, workspaceID, userID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Access denied",
+		})
+		return
+	}
+
+	username := c.GetString("username")
+	userColor := models.UserColor(userID)
+
+	client := &models.Client{
+		ID:          uuid.New(),
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		UserName:    username,
+		UserColor:   userColor,
+		Role:        role,
+		Send:        make(chan *models.WSMessage, clientSendBufferSize),
+		Presence: &models.UserPresence{
+			UserID:    userID,
+			UserName:  username,
+			UserColor: userColor,
+			LastSeen:  time.Now(),
+		},
+	}
+	client.Touch()
+
+	c.SetStatusCode(http.StatusOK)
+	c.Response.Header.Set("Content-Type", "text/event-stream")
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("Connection", "keep-alive")
+	c.Response.Header.Set("X-Accel-Buffering", "no")
+	c.Response.HijackWriter(resp.NewChunkedBodyWriter(&c.Response, c.GetWriter()))
+
+	// Tell the client its ID so it can publish updates over REST
+	if err := writeSSEEvent(c, "connected", map[string]interface{}{"client_id": client.ID}); err != nil {
+		return
+	}
+
+	h.clients.Store(client.ID, client)
+	h.hub.Register(client)
+
+	startedAt := time.Now()
+	defer func() {
+		h.clients.Delete(client.ID)
+		h.hub.Unregister(client)
+		h.wsHandler.analytics.TrackSession(client, models.SessionTransportSSE, startedAt)
+	}()
+
+	hlog.Infof("User %s opened event stream for workspace %s", userID, workspaceID)
+
+	h.streamEvents(c, client)
+}
+
+// streamEvents writes hub messages to the stream until the client goes away
+func (h *SSEHandler) streamEvents(c *app.RequestContext, client *models.Client) {
+	ticker := time.NewTicker(sseHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.Send:
+			if !ok {
+				return
+			}
+
+			if err := writeSSEEvent(c, string(message.Type), message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			if _, err := c.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			if err := c.Flush(); err != nil {
+				return
+			}
+			client.Touch()
+		}
+	}
+}
+
+// Publish godoc
+// @Summary Publish a client event
+// @Description Sends a cursor, selection or operation message from an SSE client. Operations need the editor role.
+// @Tags realtime
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.EventPublishRequest true "Message"
+// @Success 202 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/events [post]
+func (h *SSEHandler) Publish(ctx context.Context, c *app.RequestContext) {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	var req models.EventPublishRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	value, exists := h.clients.Load(req.ClientID)
+	if !exists {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Event stream not found",
+		})
+		return
+	}
+
+	client, ok := value.(*models.Client)
+	if !ok || client.UserID != userID || client.WorkspaceID != workspaceID {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Event stream not found",
+		})
+		return
+	}
+
+	msg := &models.WSMessage{
+		Type:      req.Type,
+		Payload:   req.Payload,
+		UserID:    userID,
+		Timestamp: time.Now(),
+		RequestID: req.RequestID,
+	}
+
+	//nolint:exhaustive // only client-originated updates can be published
+	switch req.Type {
+	case models.MessageTypeCursorMove:
+		h.wsHandler.handleCursorMove(client, msg)
+
+	case models.MessageTypeSelectionChange:
+		h.wsHandler.handleSelectionChange(client, msg)
+
+	case models.MessageTypeOperation, models.MessageTypeBatch:
+		if err := h.workspaceService.CheckPermission(ctx, workspaceID, userID, models.WorkspaceRoleEditor); err != nil {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"error": "Access denied",
+			})
+			return
+		}
+
+		if req.Type == models.MessageTypeOperation {
+			h.wsHandler.handleOperation(ctx, client, msg)
+		} else {
+			h.wsHandler.handleBatch(ctx, client, msg)
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("Unsupported message type: %s", req.Type),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message": "Event published",
+	})
+}
+
+// writeSSEEvent writes a single named event and flushes it to the client
+func writeSSEEvent(c *app.RequestContext, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		hlog.Errorf("Failed to marshal SSE event: %v", err)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(c, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+
+	return c.Flush()
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// StorageHandler serves presigned downloads and uploads for the filesystem
+// storage backend. Cloud backends hand out URLs pointing at the object store
+// directly and don't need it.
+type StorageHandler struct {
+	storage *service.FilesystemStorage
+}
+
+func NewStorageHandler(storage *service.FilesystemStorage) *StorageHandler {
+	return &StorageHandler{
+		storage: storage,
+	}
+}
+
+// GetObject godoc
+// @Summary Download a stored object
+// @Description Serves a file from filesystem storage using a presigned URL
+// @Tags storage
+// @Produce octet-stream
+// @Param key path string true "Object key"
+// @Param expires query int true "Expiry timestamp"
+// @Param cache_control query string false "Cache-Control of the response, signed"
+// @Param signature query string true "URL signature"
+// @Success 200
+//
+// @Router /api/v1/storage/{key} [get]
+func (h *StorageHandler) GetObject(ctx context.Context, c *app.RequestContext) {
+	key, ok := h.verify(c, http.MethodGet, c.Query("cache_control"))
+	if !ok {
+		return
+	}
+
+	info, err := h.storage.Stat(ctx, key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Object not found"})
+		return
+	}
+
+	object, err := h.storage.Get(ctx, key)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to open stored object: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to read object"})
+		return
+	}
+
+	if cacheControl := c.Query("cache_control"); cacheControl != "" {
+		c.Response.Header.Set("Cache-Control", cacheControl)
+	}
+	c.Response.Header.Set("X-Content-Type-Options", "nosniff")
+	// Objects are served from the API origin, only raster images may render
+	// there. Anything else, SVG and HTML alike, can't run scripts.
+	if !service.AllowedImageTypes[info.ContentType] {
+		c.Response.Header.Set("Content-Security-Policy", "sandbox")
+		if info.ContentType != service.ContentTypePDF && !service.AllowedVideoTypes[info.ContentType] {
+			c.Response.Header.Set("Content-Disposition", "attachment")
+		}
+	}
+	c.SetContentType(info.ContentType)
+	c.SetBodyStream(object, int(info.Size))
+}
+
+// PutObject godoc
+// @Summary Upload a stored object
+// @Description Stores a file in filesystem storage using a presigned URL
+// @Tags storage
+// @Accept octet-stream
+// @Param key path string true "Object key"
+// @Param expires query int true "Expiry timestamp"
+// @Param content_type query string true "Content-Type of the upload, signed"
+// @Param signature query string true "URL signature"
+// @Success 200
+// @Failure 415 {object} map[string]interface{}
+//
+// @Router /api/v1/storage/{key} [put]
+func (h *StorageHandler) PutObject(ctx context.Context, c *app.RequestContext) {
+	contentType := c.Query("content_type")
+	key, ok := h.verify(c, http.MethodPut, contentType)
+	if !ok {
+		return
+	}
+
+	// The upload is stored with the type it was presigned for, nothing else
+	if string(c.ContentType()) != contentType {
+		c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{
+			"error": "Content-Type must be " + contentType,
+		})
+		return
+	}
+
+	size := int64(c.Request.Header.ContentLength())
+	if size < 0 {
+		size = -1 // chunked, stored until the body ends
+	}
+
+	// The body limit middleware caps the size, ConfirmUpload validates the
+	// file once it's stored
+	if err := h.storage.Put(ctx, key, requestBody(c), size, contentType); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to store object: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to store object"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// verify checks the presigned URL, including its signed header value, and
+// returns the object key
+func (h *StorageHandler) verify(c *app.RequestContext, method, header string) (string, bool) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	err := h.storage.Verify(method, key, c.Query("expires"), header, c.Query("signature"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, map[string]interface{}{"error": "Invalid or expired signature"})
+		return "", false
+	}
+	return key, true
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type TranslationHandler struct {
+	translationService *service.TranslationService
+}
+
+func NewTranslationHandler(translationService *service.TranslationService) *TranslationHandler {
+	return &TranslationHandler{
+		translationService: translationService,
+	}
+}
+
+// TranslateElements godoc
+// @Summary Translate elements
+// @Description Translates the text elements, sticky notes and lists among the given elements with the configured
+// @Description backend; rich text keeps its formatting. With create a translated copy of each element is added
+// @Description to the right of the board, marked with translated_from and translation_language, which needs
+// @Description editor access.
+// @Tags elements
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.TranslateElementsRequest true "Elements and target language"
+// @Success 200 {object} models.TranslateElementsResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 423 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/elements/translate [post]
+func (h *TranslationHandler) TranslateElements(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.TranslateElementsRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	resp, err := h.translationService.Translate(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondTranslationError(ctx, c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func respondTranslationError(ctx context.Context, c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, service.ErrBoardFrozen):
+		c.JSON(http.StatusLocked, map[string]interface{}{"error": err.Error(), "code": "board_frozen"})
+	case errors.Is(err, service.ErrTranslationForbidden):
+		c.JSON(http.StatusForbidden, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrTranslationRejected):
+		hlog.CtxWarnf(ctx, "Failed to translate elements: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "The translation provider rejected the request, check the target language",
+		})
+	case errors.Is(err, service.ErrTranslationProvider):
+		hlog.CtxErrorf(ctx, "Failed to translate elements: %v", err)
+		c.JSON(http.StatusBadGateway, map[string]interface{}{"error": "The translation provider is unavailable, try again later"})
+	default:
+		hlog.CtxErrorf(ctx, "Failed to translate elements: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
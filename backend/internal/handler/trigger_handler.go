@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// nextCursorHeader carries the cursor of the next page of a polling
+// trigger. The body stays a plain array, which is what Zapier expects.
+const nextCursorHeader = "X-Next-Cursor"
+
+// TriggerHandler serves the API key authenticated endpoints of automation
+// platforms such as Zapier and Make
+type TriggerHandler struct {
+	triggerService *service.TriggerService
+}
+
+func NewTriggerHandler(triggerService *service.TriggerService) *TriggerHandler {
+	return &TriggerHandler{
+		triggerService: triggerService,
+	}
+}
+
+// GetAccount godoc
+// @Summary Describe the API key
+// @Description Returns the workspace and user the API key acts as. Automation platforms call it to test the key.
+// @Tags automation
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Success 200 {object} models.AutomationAccount
+//
+// @Router /api/v1/automation/me [get]
+func (h *TriggerHandler) GetAccount(ctx context.Context, c *app.RequestContext) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.triggerService.Account(ctx, key)
+	if err != nil {
+		respondTriggerError(ctx, c, "Failed to get API key account", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// PollNewElements godoc
+// @Summary Poll new elements
+// @Description Lists the elements of the workspace newest first. The cursor of the next page is returned in
+// @Description the X-Next-Cursor header, which is missing on the last page.
+// @Tags automation
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param cursor query string false "Cursor of the page"
+// @Param limit query int false "Maximum number of items (default 50, max 100)"
+// @Success 200 {array} models.ElementTriggerItem
+//
+// @Router /api/v1/automation/triggers/new-element [get]
+func (h *TriggerHandler) PollNewElements(ctx context.Context, c *app.RequestContext) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, next, err := h.triggerService.PollNewElements(ctx, key.WorkspaceID, c.Query("cursor"), limit)
+	if err != nil {
+		respondTriggerError(ctx, c, "Failed to poll new elements", err)
+		return
+	}
+
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// PollNewComments godoc
+// @Summary Poll new comments
+// @Description Lists the comments of the workspace, threads and replies, newest first. The cursor of the next
+// @Description page is returned in the X-Next-Cursor header, which is missing on the last page.
+// @Tags automation
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param cursor query string false "Cursor of the page"
+// @Param limit query int false "Maximum number of items (default 50, max 100)"
+// @Success 200 {array} models.CommentTriggerItem
+//
+// @Router /api/v1/automation/triggers/new-comment [get]
+func (h *TriggerHandler) PollNewComments(ctx context.Context, c *app.RequestContext) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, next, err := h.triggerService.PollNewComments(ctx, key.WorkspaceID, c.Query("cursor"), limit)
+	if err != nil {
+		respondTriggerError(ctx, c, "Failed to poll new comments", err)
+		return
+	}
+
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// PollNewMembers godoc
+// @Summary Poll new members
+// @Description Lists the members of the workspace, most recently joined first. The cursor of the next page is
+// @Description returned in the X-Next-Cursor header, which is missing on the last page.
+// @Tags automation
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param cursor query string false "Cursor of the page"
+// @Param limit query int false "Maximum number of items (default 50, max 100)"
+// @Success 200 {array} models.MemberTriggerItem
+//
+// @Router /api/v1/automation/triggers/new-member [get]
+func (h *TriggerHandler) PollNewMembers(ctx context.Context, c *app.RequestContext) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, next, err := h.triggerService.PollNewMembers(ctx, key.WorkspaceID, c.Query("cursor"), limit)
+	if err != nil {
+		respondTriggerError(ctx, c, "Failed to poll new members", err)
+		return
+	}
+
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// Subscribe godoc
+// @Summary Subscribe a REST hook
+// @Description Registers a target URL that receives the items of a trigger as a JSON array when they are created.
+// @Description Requires an API key of a workspace owner.
+// @Tags automation
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param request body models.TriggerSubscriptionRequest true "Target URL and trigger (new_element or new_member)"
+// @Success 201 {object} models.TriggerSubscription
+//
+// @Router /api/v1/automation/subscriptions [post]
+func (h *TriggerHandler) Subscribe(ctx context.Context, c *app.RequestContext) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.TriggerSubscriptionRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	subscription, err := h.triggerService.Subscribe(ctx, key.WorkspaceID, key.UserID, &req)
+	if err != nil {
+		respondTriggerError(ctx, c, "Failed to subscribe REST hook", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// Unsubscribe godoc
+// @Summary Unsubscribe a REST hook
+// @Tags automation
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Param subscription_id path string true "Subscription ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/automation/subscriptions/{subscription_id} [delete]
+func (h *TriggerHandler) Unsubscribe(ctx context.Context, c *app.RequestContext) {
+	key, ok := apiKeyFromContext(c)
+	if !ok {
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(c.Param("subscription_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid subscription ID"})
+		return
+	}
+
+	if err := h.triggerService.Unsubscribe(ctx, key.WorkspaceID, key.UserID, subscriptionID); err != nil {
+		respondTriggerError(ctx, c, "Failed to unsubscribe REST hook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Unsubscribed successfully"})
+}
+
+// apiKeyFromContext returns the API key stored by the API key middleware
+// and responds with 401 when there is none
+func apiKeyFromContext(c *app.RequestContext) (*models.APIKey, bool) {
+	value, exists := c.Get("api_key")
+	key, ok := value.(*models.APIKey)
+	if !exists || !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "API key required"})
+		return nil, false
+	}
+	return key, true
+}
+
+func respondTriggerError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Invalid API key"})
+	case errors.Is(err, service.ErrTriggerForbidden):
+		c.JSON(http.StatusForbidden, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrTriggerSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Subscription not found"})
+	case errors.Is(err, service.ErrUnknownTrigger), errors.Is(err, service.ErrInvalidTriggerCursor):
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, service.ErrWebhookLimitReached):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type UsageHandler struct {
+	meteringService *service.MeteringService
+}
+
+func NewUsageHandler(meteringService *service.MeteringService) *UsageHandler {
+	return &UsageHandler{
+		meteringService: meteringService,
+	}
+}
+
+// GetWorkspaceUsage godoc
+// @Summary Get workspace usage
+// @Description Returns the billable usage of a workspace per calendar month (UTC), newest first: active editors,
+// @Description peak storage and rendered exports. With billing enabled every month carries its overage under the owner's plan.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param months query int false "Number of months (default 12, max 36)"
+// @Success 200 {object} models.UsageResponse
+//
+// @Router /api/v1/workspaces/{workspace_id}/usage [get]
+func (h *UsageHandler) GetWorkspaceUsage(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	months := service.DefaultUsageMonths
+	if value := c.Query("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxUsageMonths {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "months must be between 1 and " + strconv.Itoa(service.MaxUsageMonths),
+			})
+			return
+		}
+		months = parsed
+	}
+
+	usage, err := h.meteringService.GetUsage(ctx, workspaceID, months)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get workspace usage: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get workspace usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"strings"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -27,7 +28,14 @@ func NewUserHandler(userRepo *repository.UserRepository, authService *service.Au
 	}
 }
 
-// GetProfile returns the current user's profile
+// GetProfile godoc
+// @Summary Get the current user
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/users/me [get]
 func (h *UserHandler) GetProfile(c context.Context, ctx *app.RequestContext) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
@@ -63,7 +71,17 @@ func (h *UserHandler) GetProfile(c context.Context, ctx *app.RequestContext) {
 	ctx.JSON(consts.StatusOK, user)
 }
 
-// UpdateProfile updates the current user's profile
+// UpdateProfile godoc
+// @Summary Update the current user
+// @Description Changes the name, username or avatar of the current user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/users/me [put]
 func (h *UserHandler) UpdateProfile(c context.Context, ctx *app.RequestContext) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
@@ -106,6 +124,30 @@ func (h *UserHandler) UpdateProfile(c context.Context, ctx *app.RequestContext)
 	if req.AvatarURL != nil {
 		user.AvatarURL = req.AvatarURL
 	}
+	if req.Username != nil && strings.ToLower(*req.Username) != user.Username {
+		username := strings.ToLower(*req.Username)
+		if !models.ValidUsername(username) {
+			ctx.JSON(consts.StatusBadRequest, map[string]interface{}{
+				"error": "Username must be 2-40 letters, digits, '_', '.' or '-' and start and end with a letter, digit or '_'",
+			})
+			return
+		}
+
+		taken, err := h.userRepo.GetByUsername(c, username)
+		if err != nil {
+			ctx.JSON(consts.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to check username",
+			})
+			return
+		}
+		if taken != nil {
+			ctx.JSON(consts.StatusConflict, map[string]interface{}{
+				"error": "Username is already taken",
+			})
+			return
+		}
+		user.Username = username
+	}
 
 	if err := h.userRepo.Update(c, user); err != nil {
 		ctx.JSON(consts.StatusInternalServerError, map[string]interface{}{
@@ -117,7 +159,16 @@ func (h *UserHandler) UpdateProfile(c context.Context, ctx *app.RequestContext)
 	ctx.JSON(consts.StatusOK, user)
 }
 
-// ChangePassword changes the current user's password
+// ChangePassword godoc
+// @Summary Change the password
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/users/me/password [put]
 func (h *UserHandler) ChangePassword(c context.Context, ctx *app.RequestContext) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
@@ -188,6 +239,7 @@ func (h *UserHandler) ChangePassword(c context.Context, ctx *app.RequestContext)
 
 	// Delete all refresh tokens (logout all sessions)
 	_ = h.userRepo.DeleteUserRefreshTokens(c, uid)
+	h.authService.PasswordChanged(c, uid)
 
 	ctx.JSON(consts.StatusOK, map[string]interface{}{
 		"message": "Password changed successfully",
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type ViewStateHandler struct {
+	viewStateService *service.ViewStateService
+}
+
+func NewViewStateHandler(viewStateService *service.ViewStateService) *ViewStateHandler {
+	return &ViewStateHandler{
+		viewStateService: viewStateService,
+	}
+}
+
+// GetViewState godoc
+// @Summary Get where the current user left off on a board
+// @Description Returns the viewport, collapsed frames and hidden layers the current user saved last, on any
+// @Description device. Boards the user never saved a state for open at the default view.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} models.ViewState
+//
+// @Router /api/v1/workspaces/{workspace_id}/view-state [get]
+func (h *ViewStateHandler) GetViewState(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	state, err := h.viewStateService.Get(ctx, userID, workspaceID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get view state: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to get view state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// UpdateViewState godoc
+// @Summary Save where the current user is on a board
+// @Description Saves the viewport, collapsed frames and hidden layers of the current user. Fields left out keep
+// @Description their value, so the viewport can be saved on its own as the user pans and zooms.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.UpdateViewStateRequest true "Fields to change"
+// @Success 200 {object} models.ViewState
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/view-state [put]
+func (h *ViewStateHandler) UpdateViewState(ctx context.Context, c *app.RequestContext) {
+	workspaceID, userID, ok := aiRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateViewStateRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	state, err := h.viewStateService.Update(ctx, userID, workspaceID, &req)
+	if err != nil {
+		hlog.CtxWarnf(ctx, "Failed to save view state: %v", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+const (
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 200
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Registers a URL that receives signed event payloads, or chat messages for the teams format.
+// @Description The signing secret is only returned here.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateWebhookRequest true "Webhook URL, format and events, all events when empty"
+// @Success 201 {object} models.WebhookWithSecret
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(ctx, workspaceID, userUUID, &req)
+	if err != nil {
+		respondWebhookError(ctx, c, "Failed to create webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(ctx context.Context, c *app.RequestContext) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(ctx, workspaceID)
+	if err != nil {
+		respondWebhookError(ctx, c, "Failed to list webhooks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"webhooks":    webhooks,
+		"event_types": models.EventTypes,
+	})
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} models.Webhook
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks/{webhook_id} [get]
+func (h *WebhookHandler) GetWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		respondWebhookError(ctx, c, "Failed to get webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Changes the URL, events or active flag. With rotate_secret the new secret is returned.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param request body models.UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} models.WebhookWithSecret
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks/{webhook_id} [put]
+func (h *WebhookHandler) UpdateWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if bindErr := c.BindJSON(&req); bindErr != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(ctx, workspaceID, webhookID, &req)
+	if err != nil {
+		respondWebhookError(ctx, c, "Failed to update webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks/{webhook_id} [delete]
+func (h *WebhookHandler) DeleteWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(ctx, workspaceID, webhookID); err != nil {
+		respondWebhookError(ctx, c, "Failed to delete webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Returns the delivery log of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param limit query int false "Maximum number of deliveries (default 50, max 200)"
+// @Param offset query int false "Number of deliveries to skip"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks/{webhook_id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	if limit <= 0 {
+		limit = defaultWebhookDeliveryLimit
+	}
+	if limit > maxWebhookDeliveryLimit {
+		limit = maxWebhookDeliveryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(ctx, workspaceID, webhookID, limit, offset)
+	if err != nil {
+		respondWebhookError(ctx, c, "Failed to list webhook deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{"deliveries": deliveries})
+}
+
+// TestWebhook godoc
+// @Summary Test-fire a webhook
+// @Description Sends a webhook.test event right away and returns the delivery with the endpoint's response status
+// @Tags webhooks
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} models.WebhookDelivery
+//
+// @Router /api/v1/workspaces/{workspace_id}/webhooks/{webhook_id}/test [post]
+func (h *WebhookHandler) TestWebhook(ctx context.Context, c *app.RequestContext) {
+	workspaceID, webhookID, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Invalid user ID format"})
+		return
+	}
+
+	delivery, err := h.webhookService.TestWebhook(ctx, workspaceID, webhookID, userUUID)
+	if err != nil {
+		respondWebhookError(ctx, c, "Failed to test webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// parseWebhookParams parses the workspace and webhook IDs and responds with
+// 400 when one is invalid
+func parseWebhookParams(c *app.RequestContext) (workspaceID, webhookID uuid.UUID, ok bool) {
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid workspace ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	webhookID, err = uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid webhook ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return workspaceID, webhookID, true
+}
+
+func respondWebhookError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Webhook not found"})
+	case errors.Is(err, service.ErrWebhookLimitReached):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
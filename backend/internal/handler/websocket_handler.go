@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/bifshteksex/hertz-board/internal/logger"
 	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/service"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -37,85 +43,172 @@ const (
 
 	// clientSendBufferSize is the buffer size for client send channel
 	clientSendBufferSize = 256
+
+	// anonymousMessagesPerSecond limits messages from anonymous clients
+	anonymousMessagesPerSecond = 5
+
+	// Presence shown for anonymous viewers of public workspaces
+	anonymousUserName  = "Anonymous"
+	anonymousUserColor = "#9E9E9E"
 )
 
+// tracedMessageTypes are the messages that reach the database and get a
+// span. Cursor and presence updates are too frequent to trace.
+var tracedMessageTypes = map[models.MessageType]bool{
+	models.MessageTypeJoinRoom:  true,
+	models.MessageTypeOperation: true,
+	models.MessageTypeBatch:     true,
+}
+
 type WebSocketHandler struct {
-	hub        *service.Hub
-	jwtService *service.JWTService
+	hub              *service.Hub
+	jwtService       *service.JWTService
+	crdtService      *service.CRDTService
+	workspaceService *service.WorkspaceService
+	analytics        *service.AnalyticsService
+	ipAllowlist      *service.IPAllowlistService
+	botService       *service.BotService
+	presentations    *service.PresentationService
 }
 
-func NewWebSocketHandler(hub *service.Hub, jwtService *service.JWTService) *WebSocketHandler {
+func NewWebSocketHandler(
+	hub *service.Hub,
+	jwtService *service.JWTService,
+	crdtService *service.CRDTService,
+	workspaceService *service.WorkspaceService,
+	analytics *service.AnalyticsService,
+	ipAllowlist *service.IPAllowlistService,
+	botService *service.BotService,
+	presentations *service.PresentationService,
+) *WebSocketHandler {
 	return &WebSocketHandler{
-		hub:        hub,
-		jwtService: jwtService,
+		hub:              hub,
+		jwtService:       jwtService,
+		crdtService:      crdtService,
+		workspaceService: workspaceService,
+		analytics:        analytics,
+		ipAllowlist:      ipAllowlist,
+		botService:       botService,
+		presentations:    presentations,
 	}
 }
 
-// HandleWebSocket handles WebSocket connections using gorilla/websocket
+// HandleWebSocket handles WebSocket connections using gorilla/websocket.
+// Connections without a token are anonymous and may only watch public workspaces
+// that allow anonymous viewing.
+// Bot tokens with the board:read scope connect read-only to the bot's workspace.
 func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Create client
+	client := &models.Client{
+		ID:   uuid.New(),
+		Send: make(chan *models.WSMessage, clientSendBufferSize),
+		IP:   requestClientIP(r),
+	}
+	client.Touch()
+
+	var username string
+
 	// Get token from query parameter
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		http.Error(w, "Missing authentication token", http.StatusUnauthorized)
-		return
-	}
+		// Each anonymous connection gets its own identity for presence
+		client.UserID = uuid.New()
+		client.Anonymous = true
+		username = anonymousUserName
+	} else if service.IsBotToken(token) {
+		bot, err := h.authenticateBot(r.Context(), token)
+		if err != nil {
+			http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
+			return
+		}
+		client.UserID = bot.ID
+		client.Bot = true
+		username = bot.Name
+	} else {
+		// Validate JWT token
+		claims, err := h.jwtService.ValidateToken(token)
+		if err != nil {
+			http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
+			return
+		}
 
-	// Validate JWT token
-	claims, err := h.jwtService.ValidateToken(token)
-	if err != nil {
-		http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
-		return
+		client.UserID = claims.UserID
+		username = claims.Username
 	}
 
-	// Get user ID from claims
-	userID := claims.UserID
-
 	// Upgrade to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		hlog.Errorf("WebSocket upgrade failed: %v", err)
 		return
 	}
 
-	// Create client
-	client := &models.Client{
-		ID:       uuid.New(),
-		UserID:   userID,
-		Send:     make(chan *models.WSMessage, clientSendBufferSize),
-		LastPing: time.Now(),
+	// Messages of the connection continue the trace of the upgrade request
+	ctx := tracing.Extract(context.Background(), r.Header.Get(tracing.TraceparentHeader))
+	ctx = logger.With(ctx, logger.UserIDKey, client.UserID.String())
+	ctx = service.WithAuditRequest(ctx, client.IP, r.UserAgent(), "")
+	switch {
+	case client.Bot:
+		ctx = service.WithAuditActor(ctx, models.AuditActorBot, &client.UserID)
+	case !client.Anonymous:
+		ctx = service.WithAuditActor(ctx, models.AuditActorUser, &client.UserID)
 	}
 
 	// Handle the connection
-	h.handleConnection(conn, client, claims.Username)
+	h.handleConnection(ctx, conn, client, username)
+}
+
+// authenticateBot returns the bot of a bot token that may read its board
+func (h *WebSocketHandler) authenticateBot(ctx context.Context, secret string) (*models.Bot, error) {
+	if h.botService == nil {
+		return nil, service.ErrInvalidBotToken
+	}
+
+	token, err := h.botService.Authenticate(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !token.HasScope(models.BotScopeBoardRead) {
+		return nil, service.ErrInvalidBotToken
+	}
+
+	return h.botService.GetBot(ctx, token.WorkspaceID, token.BotID)
 }
 
 // handleConnection manages the WebSocket connection lifecycle
-func (h *WebSocketHandler) handleConnection(conn *websocket.Conn, client *models.Client, username string) {
+func (h *WebSocketHandler) handleConnection(
+	ctx context.Context,
+	conn *websocket.Conn,
+	client *models.Client,
+	username string,
+) {
+	startedAt := time.Now()
 	defer func() {
 		conn.Close()
+		h.analytics.TrackSession(client, models.SessionTransportWebSocket, startedAt)
 	}()
 
 	// Configure connection
 	conn.SetReadLimit(maxMessageSize)
 	if err := conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
-		log.Printf("Failed to set read deadline: %v", err)
+		hlog.CtxErrorf(ctx, "Failed to set read deadline: %v", err)
 		return
 	}
 	conn.SetPongHandler(func(string) error {
-		client.LastPing = time.Now()
+		client.Touch()
 		if err := conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
-			log.Printf("Failed to set read deadline in pong handler: %v", err)
+			hlog.CtxErrorf(ctx, "Failed to set read deadline in pong handler: %v", err)
 		}
 		return nil
 	})
 
 	// Start goroutines for read and write
 	go h.writePump(conn, client)
-	h.readPump(conn, client, username)
+	h.readPump(ctx, conn, client, username)
 }
 
 // readPump reads messages from the WebSocket connection
-func (h *WebSocketHandler) readPump(conn *websocket.Conn, client *models.Client, username string) {
+func (h *WebSocketHandler) readPump(ctx context.Context, conn *websocket.Conn, client *models.Client, username string) {
 	defer func() {
 		// Unregister client when connection closes
 		if client.WorkspaceID != uuid.Nil {
@@ -123,22 +216,32 @@ func (h *WebSocketHandler) readPump(conn *websocket.Conn, client *models.Client,
 		}
 	}()
 
+	limiter := &messageRateLimiter{limit: anonymousMessagesPerSecond}
+
 	for {
 		var msg models.WSMessage
 		err := conn.ReadJSON(&msg)
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				hlog.CtxErrorf(ctx, "WebSocket error: %v", err)
 			}
 			break
 		}
 
+		// Any inbound message proves the client is alive
+		client.Touch()
+
+		if client.Anonymous && !limiter.allow() {
+			h.sendError(client, "rate_limited", "Too many messages")
+			continue
+		}
+
 		// Set user ID from client
 		msg.UserID = client.UserID
 		msg.Timestamp = time.Now()
 
 		// Handle message based on type
-		h.handleMessage(client, username, &msg)
+		h.handleMessage(ctx, client, username, &msg)
 	}
 }
 
@@ -153,26 +256,26 @@ func (h *WebSocketHandler) writePump(conn *websocket.Conn, client *models.Client
 		select {
 		case message, ok := <-client.Send:
 			if err := conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
-				log.Printf("Failed to set write deadline: %v", err)
+				hlog.Errorf("Failed to set write deadline: %v", err)
 				return
 			}
 			if !ok {
 				// Channel closed
 				if err := conn.WriteMessage(websocket.CloseMessage, []byte{}); err != nil {
-					log.Printf("Failed to write close message: %v", err)
+					hlog.Errorf("Failed to write close message: %v", err)
 				}
 				return
 			}
 
 			err := conn.WriteJSON(message)
 			if err != nil {
-				log.Printf("Write error: %v", err)
+				hlog.Errorf("Write error: %v", err)
 				return
 			}
 
 		case <-ticker.C:
 			if err := conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
-				log.Printf("Failed to set write deadline: %v", err)
+				hlog.Errorf("Failed to set write deadline: %v", err)
 				return
 			}
 			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -183,10 +286,27 @@ func (h *WebSocketHandler) writePump(conn *websocket.Conn, client *models.Client
 }
 
 // handleMessage processes incoming WebSocket messages
-func (h *WebSocketHandler) handleMessage(client *models.Client, username string, msg *models.WSMessage) {
+func (h *WebSocketHandler) handleMessage(
+	ctx context.Context,
+	client *models.Client,
+	username string,
+	msg *models.WSMessage,
+) {
+	if client.WorkspaceID != uuid.Nil {
+		ctx = logger.With(ctx, logger.WorkspaceIDKey, client.WorkspaceID.String())
+	}
+
+	if tracedMessageTypes[msg.Type] {
+		var span *tracing.Span
+		ctx, span = tracing.Start(ctx, "ws "+string(msg.Type), tracing.SpanKindServer)
+		span.SetAttribute("ws.client_id", client.ID.String())
+		span.SetAttribute("workspace.id", client.WorkspaceID.String())
+		defer span.End()
+	}
+
 	switch msg.Type {
 	case models.MessageTypeJoinRoom:
-		h.handleJoinRoom(client, username, msg)
+		h.handleJoinRoom(ctx, client, username, msg)
 
 	case models.MessageTypeLeaveRoom:
 		h.handleLeaveRoom(client)
@@ -198,14 +318,17 @@ func (h *WebSocketHandler) handleMessage(client *models.Client, username string,
 		h.handleSelectionChange(client, msg)
 
 	case models.MessageTypeOperation:
-		h.handleOperation(client, msg)
+		h.handleOperation(ctx, client, msg)
 
 	case models.MessageTypeBatch:
-		h.handleBatch(client, msg)
+		h.handleBatch(ctx, client, msg)
 
 	case models.MessageTypeSyncRequest:
 		h.handleSyncRequest(client, msg)
 
+	case models.MessageTypePresentationGoto:
+		h.handlePresentationGoto(ctx, client, msg)
+
 	case models.MessageTypeHeartbeat:
 		// Respond with pong
 		client.Send <- &models.WSMessage{
@@ -213,60 +336,177 @@ func (h *WebSocketHandler) handleMessage(client *models.Client, username string,
 			Timestamp: time.Now(),
 		}
 
-	case models.MessageTypeUserJoined, models.MessageTypeUserLeft, models.MessageTypePresenceUpdate,
-		models.MessageTypeSyncResponse, models.MessageTypePong, models.MessageTypeError:
+	case models.MessageTypeJoinAck, models.MessageTypeUserJoined, models.MessageTypeUserLeft, models.MessageTypePresenceUpdate,
+		models.MessageTypeSyncResponse, models.MessageTypePong, models.MessageTypeError,
+		models.MessageTypeBoardReloaded, models.MessageTypeElementsRestored, models.MessageTypeElementsAdded,
+		models.MessageTypeSnapshotCreated, models.MessageTypeSnapshotRestored, models.MessageTypeSnapshotDeleted,
+		models.MessageTypeCommentEvent, models.MessageTypeReactionsUpdated, models.MessageTypeMaintenance,
+		models.MessageTypePresentationEvent, models.MessageTypeBoardFrozen:
 		// These message types are sent by the server, not received from clients
 		// Just log and ignore
-		log.Printf("Received server-only message type from client: %s", msg.Type)
+		hlog.CtxWarnf(ctx, "Received server-only message type from client: %s", msg.Type)
 
 	default:
-		log.Printf("Unknown message type: %s", msg.Type)
+		hlog.CtxWarnf(ctx, "Unknown message type: %s", msg.Type)
 		h.sendError(client, "unknown_message_type", fmt.Sprintf("Unknown message type: %s", msg.Type))
 	}
 }
 
-// handleJoinRoom handles join_room messages
-func (h *WebSocketHandler) handleJoinRoom(client *models.Client, username string, msg *models.WSMessage) {
-	// Parse payload
-	payload, ok := msg.Payload.(map[string]interface{})
-	if !ok {
+// handleJoinRoom handles join_room messages and negotiates the session
+func (h *WebSocketHandler) handleJoinRoom(
+	ctx context.Context,
+	client *models.Client,
+	username string,
+	msg *models.WSMessage,
+) {
+	var payload models.JoinRoomPayload
+	if err := decodePayload(msg.Payload, &payload); err != nil {
 		h.sendError(client, "invalid_payload", "Invalid join_room payload")
 		return
 	}
 
-	workspaceIDStr, ok := payload["workspace_id"].(string)
-	if !ok {
+	workspaceID := payload.WorkspaceID
+	if workspaceID == uuid.Nil {
 		h.sendError(client, "invalid_workspace_id", "Invalid workspace_id")
 		return
 	}
 
-	workspaceID, err := uuid.Parse(workspaceIDStr)
+	role, err := h.resolveRole(ctx, client, workspaceID)
 	if err != nil {
-		h.sendError(client, "invalid_workspace_id", "Invalid workspace_id format")
+		h.sendError(client, "access_denied", "Access denied")
 		return
 	}
 
+	if h.ipAllowlist != nil {
+		userID := client.UserID
+		if client.Anonymous {
+			userID = uuid.Nil
+		}
+		if err := h.ipAllowlist.CheckAccess(ctx, workspaceID, userID, client.IP); err != nil {
+			if errors.Is(err, service.ErrIPNotAllowed) {
+				h.sendError(client, "ip_not_allowed", err.Error())
+			} else {
+				h.sendError(client, "access_denied", "Failed to check workspace access")
+			}
+			return
+		}
+	}
+
 	// Get user color or generate one
-	userColor, _ := payload["user_color"].(string)
-	if userColor == "" {
-		userColor = generateUserColor(client.UserID)
+	userColor := payload.UserColor
+	if client.Anonymous {
+		userColor = anonymousUserColor
+	} else if userColor == "" {
+		userColor = models.UserColor(client.UserID)
 	}
 
 	// Update client info
 	client.WorkspaceID = workspaceID
 	client.UserName = username
 	client.UserColor = userColor
+	client.Role = role
+	client.ReplayAfter = payload.LastSeq
 	client.Presence = &models.UserPresence{
 		UserID:    client.UserID,
 		UserName:  username,
 		UserColor: userColor,
+		Anonymous: client.Anonymous,
+		Bot:       client.Bot,
 		LastSeen:  time.Now(),
 	}
 
+	// Clients that announce a protocol version get a join_ack from the room
+	if payload.ProtocolVersion >= models.ProtocolVersionCurrent {
+		boardVersion, err := h.crdtService.GetBoardVersion(ctx, workspaceID)
+		if err != nil {
+			hlog.CtxErrorf(ctx, "Failed to get board version for workspace %s: %v", workspaceID, err)
+		}
+
+		client.JoinAck = &models.WSMessage{
+			Type:      models.MessageTypeJoinAck,
+			Timestamp: time.Now(),
+			RequestID: msg.RequestID,
+			Payload: &models.JoinAckPayload{
+				ProtocolVersion: models.ProtocolVersionCurrent,
+				Encodings:       []string{models.EncodingJSON},
+				Encoding:        negotiateEncoding(payload.Encodings),
+				MaxMessageSize:  maxMessageSize,
+				BoardVersion:    boardVersion,
+				Role:            role,
+				ClientID:        client.ID,
+			},
+		}
+	}
+
 	// Register client to hub
 	h.hub.Register(client)
 
-	log.Printf("User %s joined workspace %s", client.UserID, workspaceID)
+	hlog.CtxInfof(ctx, "User %s joined workspace %s", client.UserID, workspaceID)
+}
+
+// resolveRole returns the client's role in a workspace.
+// Anonymous clients are read-only viewers of public workspaces. Bots only
+// watch their own workspace, read-only.
+func (h *WebSocketHandler) resolveRole(
+	ctx context.Context,
+	client *models.Client,
+	workspaceID uuid.UUID,
+) (models.WorkspaceRole, error) {
+	if client.Bot {
+		if _, err := h.botService.GetBot(ctx, workspaceID, client.UserID); err != nil {
+			return "", err
+		}
+		return models.WorkspaceRoleViewer, nil
+	}
+
+	if !client.Anonymous {
+		return h.workspaceService.GetUserRole(ctx, workspaceID, client.UserID)
+	}
+
+	workspace, err := h.workspaceService.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return "", err
+	}
+	if !workspace.IsPublic {
+		return "", fmt.Errorf("workspace is not public")
+	}
+	if !workspace.PublicAccess.AllowAnonymous {
+		return "", fmt.Errorf("workspace requires signing in")
+	}
+
+	return models.WorkspaceRoleViewer, nil
+}
+
+type clientIPKey struct{}
+
+// WithClientIP attaches the client address resolved by Hertz to the context
+// of a WebSocket upgrade
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// requestClientIP returns the address of the client as resolved by Hertz
+// through the trusted proxies, or the peer address. Forwarding headers are
+// never read here, any client could set them.
+func requestClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// negotiateEncoding picks the first client encoding supported by the server
+func negotiateEncoding(clientEncodings []string) string {
+	for _, encoding := range clientEncodings {
+		if encoding == models.EncodingJSON {
+			return encoding
+		}
+	}
+	return models.EncodingJSON
 }
 
 // handleLeaveRoom handles leave_room messages
@@ -302,6 +542,8 @@ func (h *WebSocketHandler) handleCursorMove(client *models.Client, msg *models.W
 		client.Presence.LastSeen = time.Now()
 	}
 
+	h.analytics.TrackCursor(client, models.CursorSampleMove, models.CursorPosition{X: x, Y: y})
+
 	// Broadcast to room
 	h.hub.BroadcastToRoom(client.WorkspaceID, &models.WSMessage{
 		Type:      models.MessageTypePresenceUpdate,
@@ -344,6 +586,11 @@ func (h *WebSocketHandler) handleSelectionChange(client *models.Client, msg *mod
 		client.Presence.LastSeen = time.Now()
 	}
 
+	// Heatmaps place selections at the cursor, clearing one isn't attention
+	if len(elementIDs) > 0 && client.Presence != nil && client.Presence.Cursor != nil {
+		h.analytics.TrackCursor(client, models.CursorSampleSelection, *client.Presence.Cursor)
+	}
+
 	// Broadcast to room
 	h.hub.BroadcastToRoom(client.WorkspaceID, &models.WSMessage{
 		Type:      models.MessageTypePresenceUpdate,
@@ -356,27 +603,104 @@ func (h *WebSocketHandler) handleSelectionChange(client *models.Client, msg *mod
 }
 
 // handleOperation handles CRDT operations
-func (h *WebSocketHandler) handleOperation(client *models.Client, msg *models.WSMessage) {
+func (h *WebSocketHandler) handleOperation(ctx context.Context, client *models.Client, msg *models.WSMessage) {
 	if client.WorkspaceID == uuid.Nil {
 		return
 	}
 
-	// Broadcast operation to other clients
-	h.hub.BroadcastToRoom(client.WorkspaceID, msg, client.ID)
+	if !canEdit(client) {
+		h.sendError(client, "read_only", "Operations are not allowed for this connection")
+		return
+	}
+	if !h.checkNotFrozen(ctx, client) {
+		return
+	}
 
-	// TODO: Store operation in database for persistence
+	var op models.OperationPayload
+	if err := decodePayload(msg.Payload, &op); err != nil {
+		h.sendError(client, "invalid_payload", "Invalid operation payload")
+		return
+	}
+
+	// Other clients only see the operation once it is stored, as the server
+	// normalized it
+	if !h.persistOperation(ctx, client, &op) {
+		return
+	}
+	h.broadcastOperations(client, []models.OperationPayload{op})
 }
 
 // handleBatch handles batch operations
-func (h *WebSocketHandler) handleBatch(client *models.Client, msg *models.WSMessage) {
+func (h *WebSocketHandler) handleBatch(ctx context.Context, client *models.Client, msg *models.WSMessage) {
 	if client.WorkspaceID == uuid.Nil {
 		return
 	}
 
-	// Broadcast batch to other clients
-	h.hub.BroadcastToRoom(client.WorkspaceID, msg, client.ID)
+	if !canEdit(client) {
+		h.sendError(client, "read_only", "Operations are not allowed for this connection")
+		return
+	}
+	if !h.checkNotFrozen(ctx, client) {
+		return
+	}
+
+	var batch models.BatchPayload
+	if err := decodePayload(msg.Payload, &batch); err != nil {
+		h.sendError(client, "invalid_payload", "Invalid batch payload")
+		return
+	}
+
+	// Only the operations that were stored are broadcast
+	applied := make([]models.OperationPayload, 0, len(batch.Operations))
+	for i := range batch.Operations {
+		if h.persistOperation(ctx, client, &batch.Operations[i]) {
+			applied = append(applied, batch.Operations[i])
+		}
+	}
+	if len(applied) > 0 {
+		h.broadcastOperations(client, applied)
+	}
+}
+
+// persistOperation stores a client operation through the CRDT service and
+// reports whether it was applied. Workspace and user are taken from the
+// connection, not the payload, and the service keeps the timestamp from
+// running ahead of the server clock.
+func (h *WebSocketHandler) persistOperation(ctx context.Context, client *models.Client, op *models.OperationPayload) bool {
+	op.WorkspaceID = client.WorkspaceID
+	op.UserID = client.UserID
+	if op.Timestamp == 0 {
+		op.Timestamp = h.crdtService.GenerateTimestamp()
+	}
+
+	err := h.crdtService.ApplyOperation(ctx, op)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrElementNotFound):
+		h.sendError(client, "element_not_found", "Element not found")
+	default:
+		hlog.CtxErrorf(ctx, "Failed to apply operation for element %s: %v", op.ElementID, err)
+		h.sendError(client, "internal_error", "Failed to apply the operation")
+	}
+	return false
+}
 
-	// TODO: Store operations in database for persistence
+// broadcastOperations sends applied operations of a client to the rest of
+// its room, a single operation as an operation message and several as a
+// batch
+func (h *WebSocketHandler) broadcastOperations(client *models.Client, ops []models.OperationPayload) {
+	msg := &models.WSMessage{
+		Type:      models.MessageTypeBatch,
+		UserID:    client.UserID,
+		Timestamp: time.Now(),
+		Payload:   models.BatchPayload{Operations: ops},
+	}
+	if len(ops) == 1 {
+		msg.Type = models.MessageTypeOperation
+		msg.Payload = ops[0]
+	}
+	h.hub.BroadcastToRoom(client.WorkspaceID, msg, client.ID)
 }
 
 // handleSyncRequest handles sync requests
@@ -398,6 +722,37 @@ func (h *WebSocketHandler) handleSyncRequest(client *models.Client, msg *models.
 	}
 }
 
+// handlePresentationGoto shows another slide of the presentation the client
+// presents. Followers, the presenter included, get the change from the room.
+func (h *WebSocketHandler) handlePresentationGoto(ctx context.Context, client *models.Client, msg *models.WSMessage) {
+	if client.WorkspaceID == uuid.Nil {
+		return
+	}
+
+	if client.Anonymous || client.Bot || h.presentations == nil {
+		h.sendError(client, "not_presenter", "Only the presenter can change slides")
+		return
+	}
+
+	var payload models.PresentationGotoPayload
+	if err := decodePayload(msg.Payload, &payload); err != nil {
+		h.sendError(client, "invalid_payload", "Invalid presentation_goto payload")
+		return
+	}
+
+	_, err := h.presentations.GoTo(ctx, client.WorkspaceID, client.UserID, payload.Slide)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrNotPresenter):
+		h.sendError(client, "not_presenter", "Only the presenter can change slides")
+	case errors.Is(err, service.ErrPresentationNotRunning):
+		h.sendError(client, "presentation_not_running", err.Error())
+	default:
+		hlog.CtxWarnf(ctx, "Failed to change slide: %v", err)
+		h.sendError(client, "invalid_slide", err.Error())
+	}
+}
+
 // sendError sends an error message to the client
 func (h *WebSocketHandler) sendError(client *models.Client, code, message string) {
 	client.Send <- &models.WSMessage{
@@ -410,16 +765,56 @@ func (h *WebSocketHandler) sendError(client *models.Client, code, message string
 	}
 }
 
-// generateUserColor generates a consistent color for a user based on their ID
-func generateUserColor(userID uuid.UUID) string {
-	colors := []string{
-		"#FF6B6B", "#4ECDC4", "#45B7D1", "#FFA07A",
-		"#98D8C8", "#F7DC6F", "#BB8FCE", "#85C1E2",
-		"#F8B739", "#52B788", "#E76F51", "#2A9D8F",
+// checkNotFrozen sends an error and returns false if the board of the
+// client is frozen. Operations are dropped instead of broadcast, the client
+// reverts them.
+func (h *WebSocketHandler) checkNotFrozen(ctx context.Context, client *models.Client) bool {
+	err := h.workspaceService.CheckNotFrozen(ctx, client.WorkspaceID)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, service.ErrBoardFrozen) {
+		h.sendError(client, "board_frozen", "The board is frozen, changes are not allowed")
+	} else {
+		hlog.CtxErrorf(ctx, "Failed to check board freeze: %v", err)
+		h.sendError(client, "internal_error", "Failed to apply the operation")
+	}
+	return false
+}
+
+// canEdit reports whether the client may send operations
+func canEdit(client *models.Client) bool {
+	if client.Anonymous || client.Bot {
+		return false
+	}
+	return client.Role == models.WorkspaceRoleOwner || client.Role == models.WorkspaceRoleEditor
+}
+
+// messageRateLimiter counts messages in fixed one-second windows
+type messageRateLimiter struct {
+	windowStart time.Time
+	count       int
+	limit       int
+}
+
+// allow reports whether another message fits into the current window
+func (l *messageRateLimiter) allow() bool {
+	now := time.Now()
+	if now.Sub(l.windowStart) >= time.Second {
+		l.windowStart = now
+		l.count = 0
 	}
 
-	// Use user ID bytes to select color
-	bytes := userID[:]
-	index := int(bytes[0]) % len(colors)
-	return colors[index]
+	l.count++
+	return l.count <= l.limit
+}
+
+// decodePayload converts a generic JSON payload into a typed struct
+func decodePayload(payload, target interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
 }
@@ -2,22 +2,33 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/service"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
 )
 
 type WorkspaceHandler struct {
-	workspaceService *service.WorkspaceService
+	workspaceService  *service.WorkspaceService
+	assetService      *service.AssetService
+	emailVerification *service.EmailVerificationPolicy
 }
 
-func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
+func NewWorkspaceHandler(
+	workspaceService *service.WorkspaceService,
+	assetService *service.AssetService,
+	emailVerification *service.EmailVerificationPolicy,
+) *WorkspaceHandler {
 	return &WorkspaceHandler{
-		workspaceService: workspaceService,
+		workspaceService:  workspaceService,
+		assetService:      assetService,
+		emailVerification: emailVerification,
 	}
 }
 
@@ -28,8 +39,16 @@ func getUUIDFromContext(c *app.RequestContext, key string) (uuid.UUID, bool) {
 	return id, ok
 }
 
-// CreateWorkspace creates a new workspace
-// POST /api/v1/workspaces
+// CreateWorkspace godoc
+// @Summary Create a workspace
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param request body models.CreateWorkspaceRequest true "Workspace"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces [post]
 func (h *WorkspaceHandler) CreateWorkspace(ctx context.Context, c *app.RequestContext) {
 	userID, ok := getUUIDFromContext(c, "user_id")
 	if !ok {
@@ -47,6 +66,10 @@ func (h *WorkspaceHandler) CreateWorkspace(ctx context.Context, c *app.RequestCo
 		return
 	}
 
+	if req.IsPublic && !h.checkCanPublish(ctx, c, userID) {
+		return
+	}
+
 	workspace, err := h.workspaceService.CreateWorkspace(ctx, &req, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -66,8 +89,23 @@ func (h *WorkspaceHandler) CreateWorkspace(ctx context.Context, c *app.RequestCo
 	})
 }
 
-// ListWorkspaces lists all workspaces accessible to user
-// GET /api/v1/workspaces
+// ListWorkspaces godoc
+// @Summary List workspaces
+// @Description Returns the workspaces the current user owns or is a member of
+// @Tags workspaces
+// @Produce json
+// @Param q query string false "Fuzzy search on name and description, tolerant of typos"
+// @Param search_content query bool false "Also match q in the text of elements, ranked below name matches"
+// @Param sort_by query string false "created_at, updated_at, name or relevance (default relevance with q, else updated_at)"
+// @Param sort_order query string false "asc or desc (default desc)"
+// @Param limit query int false "Maximum number of workspaces (default 20)"
+// @Param offset query int false "Number of workspaces to skip"
+// @Param owned_only query bool false "Only workspaces owned by the user"
+// @Param shared_only query bool false "Only workspaces shared with the user"
+// @Param recent query bool false "Only workspaces the user visited, last visited first"
+// @Success 200 {object} models.WorkspaceListResponse
+//
+// @Router /api/v1/workspaces [get]
 func (h *WorkspaceHandler) ListWorkspaces(ctx context.Context, c *app.RequestContext) {
 	userID, ok := getUUIDFromContext(c, "user_id")
 	if !ok {
@@ -91,6 +129,9 @@ func (h *WorkspaceHandler) ListWorkspaces(ctx context.Context, c *app.RequestCon
 	}
 	if filter.SortBy == "" {
 		filter.SortBy = "updated_at"
+		if filter.Query != "" {
+			filter.SortBy = "relevance"
+		}
 	}
 	if filter.SortOrder == "" {
 		filter.SortOrder = "desc"
@@ -107,8 +148,17 @@ func (h *WorkspaceHandler) ListWorkspaces(ctx context.Context, c *app.RequestCon
 	c.JSON(http.StatusOK, response)
 }
 
-// GetWorkspace retrieves a specific workspace
-// GET /api/v1/workspaces/:workspace_id
+// GetWorkspace godoc
+// @Summary Get a workspace
+// @Description Returns a workspace with the role of the current user. Public workspaces can be read without a token.
+// @Description Opening the workspace counts as a view, the owner gets the view counts.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id} [get]
 func (h *WorkspaceHandler) GetWorkspace(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -129,6 +179,8 @@ func (h *WorkspaceHandler) GetWorkspace(ctx context.Context, c *app.RequestConte
 			return
 		}
 
+		h.recordView(ctx, c, workspaceID, nil)
+
 		c.JSON(http.StatusOK, map[string]interface{}{
 			"workspace": workspace,
 		})
@@ -151,13 +203,50 @@ func (h *WorkspaceHandler) GetWorkspace(ctx context.Context, c *app.RequestConte
 		return
 	}
 
+	if err := h.workspaceService.RecordVisit(ctx, uid, workspaceID); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to record workspace visit: %v", err)
+	}
+	h.recordView(ctx, c, workspaceID, &uid)
+
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"workspace": workspace,
 	})
 }
 
-// UpdateWorkspace updates workspace information
-// PUT /api/v1/workspaces/:workspace_id
+// recordView counts the request as a view of the workspace. userID is nil
+// for anonymous visitors. Views are best effort and never fail the request.
+func (h *WorkspaceHandler) recordView(
+	ctx context.Context,
+	c *app.RequestContext,
+	workspaceID uuid.UUID,
+	userID *uuid.UUID,
+) {
+	err := h.workspaceService.RecordView(ctx, &models.WorkspaceView{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		IP:          c.ClientIP(),
+		UserAgent:   string(c.UserAgent()),
+		Referrer:    string(c.GetHeader("Referer")),
+	})
+	if err != nil {
+		hlog.CtxWarnf(ctx, "Failed to record workspace view: %v", err)
+	}
+}
+
+// UpdateWorkspace godoc
+// @Summary Update a workspace
+// @Description Only the owner can change public_access, what non-members of a public workspace may do, and
+// @Description freeze_role, the least role that may freeze the board.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.UpdateWorkspaceRequest true "Fields to change"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id} [put]
 func (h *WorkspaceHandler) UpdateWorkspace(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -175,7 +264,30 @@ func (h *WorkspaceHandler) UpdateWorkspace(ctx context.Context, c *app.RequestCo
 		return
 	}
 
+	if req.IsPublic != nil && *req.IsPublic {
+		userID, ok := getUUIDFromContext(c, "user_id")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "Invalid user ID",
+			})
+			return
+		}
+		if !h.checkCanPublish(ctx, c, userID) {
+			return
+		}
+	}
+
+	if (req.PublicAccess != nil || req.FreezeRole != nil) && !h.checkOwner(ctx, c, workspaceID) {
+		return
+	}
+
 	workspace, err := h.workspaceService.UpdateWorkspace(ctx, workspaceID, &req)
+	if errors.Is(err, service.ErrInvalidPublicRole) || errors.Is(err, service.ErrInvalidFreezeRole) {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": err.Error(),
@@ -188,8 +300,65 @@ func (h *WorkspaceHandler) UpdateWorkspace(ctx context.Context, c *app.RequestCo
 	})
 }
 
-// DeleteWorkspace deletes a workspace
-// DELETE /api/v1/workspaces/:workspace_id
+// checkOwner responds with an error and returns false if the user isn't the
+// owner of the workspace
+func (h *WorkspaceHandler) checkOwner(ctx context.Context, c *app.RequestContext, workspaceID uuid.UUID) bool {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return false
+	}
+
+	isOwner, err := h.workspaceService.IsOwner(ctx, workspaceID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to check ownership",
+		})
+		return false
+	}
+	if !isOwner {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "Only workspace owner can change these settings",
+		})
+		return false
+	}
+	return true
+}
+
+// checkCanPublish responds with an error and returns false if the user may
+// not make workspaces public because their email isn't verified
+func (h *WorkspaceHandler) checkCanPublish(ctx context.Context, c *app.RequestContext, userID uuid.UUID) bool {
+	err := h.emailVerification.Check(ctx, userID)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error":   "Email verification required",
+			"code":    service.EmailNotVerifiedCode,
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	hlog.CtxErrorf(ctx, "Failed to check email verification: %v", err)
+	c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"error": "Failed to check email verification",
+	})
+	return false
+}
+
+// DeleteWorkspace godoc
+// @Summary Delete a workspace
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id} [delete]
 func (h *WorkspaceHandler) DeleteWorkspace(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -211,8 +380,18 @@ func (h *WorkspaceHandler) DeleteWorkspace(ctx context.Context, c *app.RequestCo
 	})
 }
 
-// DuplicateWorkspace creates a copy of a workspace
-// POST /api/v1/workspaces/:workspace_id/duplicate
+// DuplicateWorkspace godoc
+// @Summary Duplicate a workspace
+// @Description Copies a workspace with its elements. The current user owns the copy. Non-members can duplicate public workspaces that allow it.
+// @Tags workspaces
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body object true "Name of the copy" SchemaExample({"name": "Copy of Roadmap"})
+// @Success 201 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/duplicate [post]
 func (h *WorkspaceHandler) DuplicateWorkspace(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -241,6 +420,12 @@ func (h *WorkspaceHandler) DuplicateWorkspace(ctx context.Context, c *app.Reques
 	}
 
 	workspace, err := h.workspaceService.DuplicateWorkspace(ctx, workspaceID, userID, req.Name)
+	if errors.Is(err, service.ErrDuplicateNotAllowed) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": err.Error(),
@@ -259,10 +444,176 @@ func (h *WorkspaceHandler) DuplicateWorkspace(ctx context.Context, c *app.Reques
 	})
 }
 
+// FreezeWorkspace godoc
+// @Summary Freeze a board
+// @Description Makes the board read-only for everyone: REST writes return 423 and WebSocket operations are
+// @Description rejected with the board_frozen code until it is unfrozen. The owner configures with freeze_role
+// @Description whether editors may freeze it too.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/freeze [post]
+func (h *WorkspaceHandler) FreezeWorkspace(ctx context.Context, c *app.RequestContext) {
+	h.setFrozen(ctx, c, h.workspaceService.FreezeBoard)
+}
+
+// UnfreezeWorkspace godoc
+// @Summary Unfreeze a board
+// @Description Makes a frozen board editable again. The roles that may freeze it may unfreeze it.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/unfreeze [post]
+func (h *WorkspaceHandler) UnfreezeWorkspace(ctx context.Context, c *app.RequestContext) {
+	h.setFrozen(ctx, c, h.workspaceService.UnfreezeBoard)
+}
+
+// setFrozen freezes or unfreezes the board of the request
+func (h *WorkspaceHandler) setFrozen(
+	ctx context.Context,
+	c *app.RequestContext,
+	set func(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Workspace, error),
+) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	workspace, err := set(ctx, workspaceID, userID)
+	if errors.Is(err, service.ErrFreezeForbidden) || errors.Is(err, service.ErrWorkspaceAccessDenied) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to change board freeze: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to change board freeze",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"workspace": workspace,
+	})
+}
+
+// GetWorkspaceStats godoc
+// @Summary Get workspace statistics
+// @Description Returns element, member and storage usage with the storage quota
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/stats [get]
+func (h *WorkspaceHandler) GetWorkspaceStats(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	stats, err := h.workspaceService.GetWorkspaceStats(ctx, workspaceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	stats.StorageQuotaBytes, err = h.assetService.StorageQuota(ctx, workspaceID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get storage quota: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get workspace stats",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"stats": stats,
+	})
+}
+
+// GetContributors godoc
+// @Summary Get the contributors of a workspace
+// @Description Returns the users that created or edited the elements on the board, with how many elements each
+// @Description created and edited and when they first and last contributed, most contributions first. With
+// @Description element_id only the contributors of that element are returned, for author chips.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param element_id query string false "Element ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/contributors [get]
+func (h *WorkspaceHandler) GetContributors(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	var elementID *uuid.UUID
+	if value := c.Query("element_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid element ID",
+			})
+			return
+		}
+		elementID = &parsed
+	}
+
+	contributors, err := h.workspaceService.GetContributors(ctx, workspaceID, elementID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get contributors: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get contributors",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"contributors": contributors,
+	})
+}
+
 // --- Member Management ---
 
-// ListMembers retrieves all members of a workspace
-// GET /api/v1/workspaces/:workspace_id/members
+// ListMembers godoc
+// @Summary List members
+// @Tags members
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/members [get]
 //
 //nolint:dupl // Similar handler pattern is intentional
 func (h *WorkspaceHandler) ListMembers(ctx context.Context, c *app.RequestContext) {
@@ -287,8 +638,64 @@ func (h *WorkspaceHandler) ListMembers(ctx context.Context, c *app.RequestContex
 	})
 }
 
-// UpdateMemberRole updates a member's role
-// PUT /api/v1/workspaces/:workspace_id/members/:user_id
+// SuggestMembers godoc
+// @Summary Suggest members to mention
+// @Description Members other than the caller whose name, a word of it, or username starts with q, for
+// @Description @-mention autocomplete. Members the caller recently talked with in comments come first,
+// @Description then those recently active on the board. Without q the most relevant members are returned.
+// @Tags members
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param q query string false "Prefix typed after @"
+// @Param limit query int false "Maximum number of members, 10 by default and at most 25"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/members/suggest [get]
+func (h *WorkspaceHandler) SuggestMembers(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	members, err := h.workspaceService.SuggestMembers(ctx, workspaceID, userID, c.Query("q"), limit)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to suggest members: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to suggest members",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"members": members,
+	})
+}
+
+// UpdateMemberRole godoc
+// @Summary Change the role of a member
+// @Tags members
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param user_id path string true "User ID of the member"
+// @Param request body models.UpdateMemberRoleRequest true "New role"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/members/{user_id} [put]
 func (h *WorkspaceHandler) UpdateMemberRole(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -327,8 +734,16 @@ func (h *WorkspaceHandler) UpdateMemberRole(ctx context.Context, c *app.RequestC
 	})
 }
 
-// RemoveMember removes a member from workspace
-// DELETE /api/v1/workspaces/:workspace_id/members/:user_id
+// RemoveMember godoc
+// @Summary Remove a member
+// @Tags members
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param user_id path string true "User ID of the member"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/members/{user_id} [delete]
 func (h *WorkspaceHandler) RemoveMember(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -361,8 +776,19 @@ func (h *WorkspaceHandler) RemoveMember(ctx context.Context, c *app.RequestConte
 
 // --- Invitations ---
 
-// CreateInvite creates a workspace invitation
-// POST /api/v1/workspaces/:workspace_id/invites
+// CreateInvite godoc
+// @Summary Invite to a workspace
+// @Description Creates an invitation and emails it when an email address is given
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.InviteToWorkspaceRequest true "Invitation"
+// @Success 201 {object} models.InviteTokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invites [post]
 func (h *WorkspaceHandler) CreateInvite(ctx context.Context, c *app.RequestContext) {
 	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
 	if !ok {
@@ -389,6 +815,19 @@ func (h *WorkspaceHandler) CreateInvite(ctx context.Context, c *app.RequestConte
 
 	tokenResponse, err := h.workspaceService.CreateInvite(ctx, workspaceID, userID, &req)
 	if err != nil {
+		var limitErr *service.EmailRateLimitError
+		if errors.As(err, &limitErr) {
+			c.Response.Header.Set("Retry-After", strconv.Itoa(limitErr.RetryAfterSeconds()))
+			c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+				"error":       "Too many invitations. Try again later.",
+				"code":        "email_rate_limited",
+				"retry_after": limitErr.RetryAfterSeconds(),
+			})
+			return
+		}
+		if respondPlanLimit(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -398,8 +837,14 @@ func (h *WorkspaceHandler) CreateInvite(ctx context.Context, c *app.RequestConte
 	c.JSON(http.StatusCreated, tokenResponse)
 }
 
-// ListInvites retrieves all pending invitations for a workspace
-// GET /api/v1/workspaces/:workspace_id/invites
+// ListInvites godoc
+// @Summary List pending invitations
+// @Tags invites
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invites [get]
 //
 //nolint:dupl // Similar handler pattern is intentional
 func (h *WorkspaceHandler) ListInvites(ctx context.Context, c *app.RequestContext) {
@@ -424,8 +869,16 @@ func (h *WorkspaceHandler) ListInvites(ctx context.Context, c *app.RequestContex
 	})
 }
 
-// RevokeInvite revokes a pending invitation
-// DELETE /api/v1/workspaces/:workspace_id/invites/:invite_id
+// RevokeInvite godoc
+// @Summary Revoke an invitation
+// @Tags invites
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param invite_id path string true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invites/{invite_id} [delete]
 func (h *WorkspaceHandler) RevokeInvite(ctx context.Context, c *app.RequestContext) {
 	inviteIDStr := c.Param("invite_id")
 	inviteID, err := uuid.Parse(inviteIDStr)
@@ -448,8 +901,17 @@ func (h *WorkspaceHandler) RevokeInvite(ctx context.Context, c *app.RequestConte
 	})
 }
 
-// AcceptInvite accepts a workspace invitation
-// POST /api/v1/workspaces/invites/accept
+// AcceptInvite godoc
+// @Summary Accept an invitation
+// @Description Adds the current user to the workspace of an invitation token
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptInviteRequest true "Invitation token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/invites/accept [post]
 func (h *WorkspaceHandler) AcceptInvite(ctx context.Context, c *app.RequestContext) {
 	userID, ok := getUUIDFromContext(c, "user_id")
 	if !ok {
@@ -469,6 +931,9 @@ func (h *WorkspaceHandler) AcceptInvite(ctx context.Context, c *app.RequestConte
 
 	workspace, err := h.workspaceService.AcceptInvite(ctx, req.Token, userID)
 	if err != nil {
+		if respondPlanLimit(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -480,3 +945,531 @@ func (h *WorkspaceHandler) AcceptInvite(ctx context.Context, c *app.RequestConte
 		"message":   "Invitation accepted successfully",
 	})
 }
+
+// CreateInviteLink godoc
+// @Summary Create an invite link
+// @Description Creates a link any authenticated user can join the workspace with, optionally a limited number of times or until it expires. The token is only returned once.
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.CreateInviteLinkRequest true "Invite link"
+// @Success 201 {object} models.InviteLinkTokenResponse
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invite-links [post]
+func (h *WorkspaceHandler) CreateInviteLink(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	var req models.CreateInviteLinkRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	link, err := h.workspaceService.CreateInviteLink(ctx, workspaceID, userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// ListInviteLinks godoc
+// @Summary List invite links
+// @Description Returns the invite links of the workspace that weren't revoked, with how often they were used
+// @Tags invites
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invite-links [get]
+func (h *WorkspaceHandler) ListInviteLinks(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	links, err := h.workspaceService.ListInviteLinks(ctx, workspaceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"links": links,
+	})
+}
+
+// ListInviteLinkRedemptions godoc
+// @Summary List who joined through an invite link
+// @Tags invites
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param link_id path string true "Invite link ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invite-links/{link_id}/redemptions [get]
+func (h *WorkspaceHandler) ListInviteLinkRedemptions(ctx context.Context, c *app.RequestContext) {
+	workspaceID, linkID, ok := inviteLinkIDs(c)
+	if !ok {
+		return
+	}
+
+	redemptions, err := h.workspaceService.ListInviteLinkRedemptions(ctx, workspaceID, linkID)
+	if err != nil {
+		respondInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"redemptions": redemptions,
+	})
+}
+
+// RevokeInviteLink godoc
+// @Summary Revoke an invite link
+// @Description The link stops working; members that joined through it stay.
+// @Tags invites
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param link_id path string true "Invite link ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/invite-links/{link_id} [delete]
+func (h *WorkspaceHandler) RevokeInviteLink(ctx context.Context, c *app.RequestContext) {
+	workspaceID, linkID, ok := inviteLinkIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.RevokeInviteLink(ctx, workspaceID, linkID); err != nil {
+		respondInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Invite link revoked successfully",
+	})
+}
+
+// RedeemInviteLink godoc
+// @Summary Join a workspace through an invite link
+// @Tags invites
+// @Accept json
+// @Produce json
+// @Param request body models.RedeemInviteLinkRequest true "Invite link token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/invite-links/redeem [post]
+func (h *WorkspaceHandler) RedeemInviteLink(ctx context.Context, c *app.RequestContext) {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	var req models.RedeemInviteLinkRequest
+	if err := c.BindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	workspace, err := h.workspaceService.RedeemInviteLink(ctx, req.Token, userID)
+	if err != nil {
+		if respondPlanLimit(c, err) {
+			return
+		}
+		respondInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"workspace": workspace,
+		"message":   "Joined workspace successfully",
+	})
+}
+
+func inviteLinkIDs(c *app.RequestContext) (workspaceID, linkID uuid.UUID, ok bool) {
+	workspaceID, ok = getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	linkID, err := uuid.Parse(c.Param("link_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid invite link ID",
+		})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return workspaceID, linkID, true
+}
+
+func respondInviteLinkError(c *app.RequestContext, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrInviteLinkNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrAlreadyMember):
+		status = http.StatusConflict
+	}
+	c.JSON(status, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+// ListUserInvites godoc
+// @Summary List my invitations
+// @Description Returns the pending invitations addressed to the email of the current user, with their workspace and inviter
+// @Tags invites
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{} "Email not verified"
+//
+// @Router /api/v1/users/me/invites [get]
+func (h *WorkspaceHandler) ListUserInvites(ctx context.Context, c *app.RequestContext) {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	invites, err := h.workspaceService.ListUserInvites(ctx, userID)
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		respondUserInviteError(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"invites": invites,
+	})
+}
+
+// AcceptUserInvite godoc
+// @Summary Accept one of my invitations
+// @Description Adds the current user to the workspace of an invitation addressed to their email, without the emailed token
+// @Tags invites
+// @Produce json
+// @Param invite_id path string true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{} "Email not verified"
+//
+// @Router /api/v1/users/me/invites/{invite_id}/accept [post]
+func (h *WorkspaceHandler) AcceptUserInvite(ctx context.Context, c *app.RequestContext) {
+	userID, inviteID, ok := userInviteIDs(c)
+	if !ok {
+		return
+	}
+
+	workspace, err := h.workspaceService.AcceptUserInvite(ctx, inviteID, userID)
+	if err != nil {
+		if respondPlanLimit(c, err) {
+			return
+		}
+		respondUserInviteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"workspace": workspace,
+		"message":   "Invitation accepted successfully",
+	})
+}
+
+// DeclineUserInvite godoc
+// @Summary Decline one of my invitations
+// @Tags invites
+// @Produce json
+// @Param invite_id path string true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{} "Email not verified"
+//
+// @Router /api/v1/users/me/invites/{invite_id}/decline [post]
+func (h *WorkspaceHandler) DeclineUserInvite(ctx context.Context, c *app.RequestContext) {
+	userID, inviteID, ok := userInviteIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.DeclineUserInvite(ctx, inviteID, userID); err != nil {
+		respondUserInviteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Invitation declined",
+	})
+}
+
+func userInviteIDs(c *app.RequestContext) (userID, inviteID uuid.UUID, ok bool) {
+	userID, ok = getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	inviteID, err := uuid.Parse(c.Param("invite_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid invite ID",
+		})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, inviteID, true
+}
+
+func respondUserInviteError(c *app.RequestContext, err error) {
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error":   "Email verification required",
+			"code":    service.EmailNotVerifiedCode,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if errors.Is(err, service.ErrInviteNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+// RequestAccess godoc
+// @Summary Request access to a workspace
+// @Description Asks the owners of a workspace the user isn't a member of to let them join, and notifies the owners. Users get a 403 with code access_required from a workspace they can request access to.
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request body models.RequestAccessRequest false "Role asked for and a note"
+// @Success 201 {object} models.WorkspaceAccessRequest
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/request-access [post]
+func (h *WorkspaceHandler) RequestAccess(ctx context.Context, c *app.RequestContext) {
+	userID, ok := getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+	// Not set by a workspace middleware, the user has no access yet
+	workspaceID, err := uuid.Parse(c.Param("workspace_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	var req models.RequestAccessRequest
+	if len(c.Request.Body()) > 0 {
+		if bindErr := c.BindJSON(&req); bindErr != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid request body",
+			})
+			return
+		}
+	}
+
+	request, err := h.workspaceService.RequestAccess(ctx, workspaceID, userID, &req)
+	if err != nil {
+		respondAccessRequestError(ctx, c, "Failed to request access", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// ListAccessRequests godoc
+// @Summary List pending access requests
+// @Tags access-requests
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/access-requests [get]
+func (h *WorkspaceHandler) ListAccessRequests(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	requests, err := h.workspaceService.ListAccessRequests(ctx, workspaceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"requests": requests,
+	})
+}
+
+// ApproveAccessRequest godoc
+// @Summary Approve an access request
+// @Description Adds the user as a member with the role they asked for, unless another role is given.
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request_id path string true "Access request ID"
+// @Param request body models.ApproveAccessRequest false "Role to grant"
+// @Success 200 {object} models.WorkspaceAccessRequest
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/access-requests/{request_id}/approve [post]
+func (h *WorkspaceHandler) ApproveAccessRequest(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ownerID, requestID, ok := accessRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.ApproveAccessRequest
+	if len(c.Request.Body()) > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid request body",
+			})
+			return
+		}
+	}
+
+	request, err := h.workspaceService.ApproveAccessRequest(ctx, workspaceID, requestID, ownerID, &req)
+	if err != nil {
+		respondAccessRequestError(ctx, c, "Failed to approve access request", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// DenyAccessRequest godoc
+// @Summary Deny an access request
+// @Tags access-requests
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Param request_id path string true "Access request ID"
+// @Success 200 {object} models.WorkspaceAccessRequest
+// @Failure 409 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/access-requests/{request_id}/deny [post]
+func (h *WorkspaceHandler) DenyAccessRequest(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ownerID, requestID, ok := accessRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.workspaceService.DenyAccessRequest(ctx, workspaceID, requestID, ownerID)
+	if err != nil {
+		respondAccessRequestError(ctx, c, "Failed to deny access request", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func accessRequestIDs(c *app.RequestContext) (workspaceID, userID, requestID uuid.UUID, ok bool) {
+	workspaceID, ok = getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	userID, ok = getUUIDFromContext(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid user ID",
+		})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	requestID, err := uuid.Parse(c.Param("request_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid access request ID",
+		})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return workspaceID, userID, requestID, true
+}
+
+func respondAccessRequestError(ctx context.Context, c *app.RequestContext, msg string, err error) {
+	if respondPlanLimit(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrWorkspaceNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Workspace not found"})
+	case errors.Is(err, service.ErrAccessRequestNotFound):
+		c.JSON(http.StatusNotFound, map[string]interface{}{"error": "Access request not found"})
+	case errors.Is(err, service.ErrAlreadyMember),
+		errors.Is(err, service.ErrAccessRequestPending),
+		errors.Is(err, service.ErrAccessRequestDecided):
+		c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	default:
+		hlog.CtxErrorf(ctx, "%s: %v", msg, err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+type WorkspaceHealthHandler struct {
+	healthService *service.WorkspaceHealthService
+}
+
+func NewWorkspaceHealthHandler(healthService *service.WorkspaceHealthService) *WorkspaceHealthHandler {
+	return &WorkspaceHealthHandler{
+		healthService: healthService,
+	}
+}
+
+// GetWorkspaceHealth godoc
+// @Summary Get the health of a workspace
+// @Description Returns how many elements, deleted elements, operations, snapshots and assets the board keeps and
+// @Description their size, its largest elements and how deep its groups nest, with recommendations such as
+// @Description compacting operations or purging the trash to keep giant boards performant.
+// @Tags workspaces
+// @Produce json
+// @Param workspace_id path string true "Workspace ID"
+// @Success 200 {object} models.WorkspaceHealth
+// @Failure 400 {object} map[string]interface{}
+//
+// @Router /api/v1/workspaces/{workspace_id}/health [get]
+func (h *WorkspaceHealthHandler) GetWorkspaceHealth(ctx context.Context, c *app.RequestContext) {
+	workspaceID, ok := getUUIDFromContext(c, "workspace_id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid workspace ID",
+		})
+		return
+	}
+
+	health, err := h.healthService.GetHealth(ctx, workspaceID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to get workspace health: %v", err)
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to get workspace health",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
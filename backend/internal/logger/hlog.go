@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// Levels of hlog that slog doesn't have
+const (
+	LevelTrace  = slog.LevelDebug - 4
+	LevelNotice = slog.LevelInfo + 2
+	LevelFatal  = slog.LevelError + 4
+)
+
+// callerDepth skips runtime.Callers, log and the hlog method, so records
+// point at the code that called hlog
+const callerDepth = 4
+
+// hertzLogger routes hlog, which Hertz and the handlers log with, to slog
+type hertzLogger struct {
+	logger *slog.Logger
+	level  *slog.LevelVar
+}
+
+func newHertzLogger(logger *slog.Logger, level *slog.LevelVar) *hertzLogger {
+	return &hertzLogger{logger: logger, level: level}
+}
+
+func (l *hertzLogger) log(ctx context.Context, level slog.Level, msg string) {
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(callerDepth, pcs[:])
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	_ = l.logger.Handler().Handle(ctx, record)
+
+	if level >= LevelFatal {
+		os.Exit(1)
+	}
+}
+
+func (l *hertzLogger) Trace(v ...interface{}) {
+	l.log(context.Background(), LevelTrace, fmt.Sprint(v...))
+}
+func (l *hertzLogger) Debug(v ...interface{}) {
+	l.log(context.Background(), slog.LevelDebug, fmt.Sprint(v...))
+}
+func (l *hertzLogger) Info(v ...interface{}) {
+	l.log(context.Background(), slog.LevelInfo, fmt.Sprint(v...))
+}
+func (l *hertzLogger) Notice(v ...interface{}) {
+	l.log(context.Background(), LevelNotice, fmt.Sprint(v...))
+}
+func (l *hertzLogger) Warn(v ...interface{}) {
+	l.log(context.Background(), slog.LevelWarn, fmt.Sprint(v...))
+}
+func (l *hertzLogger) Error(v ...interface{}) {
+	l.log(context.Background(), slog.LevelError, fmt.Sprint(v...))
+}
+func (l *hertzLogger) Fatal(v ...interface{}) {
+	l.log(context.Background(), LevelFatal, fmt.Sprint(v...))
+}
+
+func (l *hertzLogger) Tracef(format string, v ...interface{}) {
+	l.log(context.Background(), LevelTrace, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) Debugf(format string, v ...interface{}) {
+	l.log(context.Background(), slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) Infof(format string, v ...interface{}) {
+	l.log(context.Background(), slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) Noticef(format string, v ...interface{}) {
+	l.log(context.Background(), LevelNotice, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) Warnf(format string, v ...interface{}) {
+	l.log(context.Background(), slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) Errorf(format string, v ...interface{}) {
+	l.log(context.Background(), slog.LevelError, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) Fatalf(format string, v ...interface{}) {
+	l.log(context.Background(), LevelFatal, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxTracef(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, LevelTrace, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxDebugf(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxInfof(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxNoticef(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, LevelNotice, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxWarnf(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxErrorf(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, slog.LevelError, fmt.Sprintf(format, v...))
+}
+
+func (l *hertzLogger) CtxFatalf(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, LevelFatal, fmt.Sprintf(format, v...))
+}
+
+// SetLevel implements hlog.Control
+func (l *hertzLogger) SetLevel(level hlog.Level) {
+	l.level.Set(fromHertzLevel(level))
+}
+
+// SetOutput implements hlog.Control. The output is set by the logging
+// config, Hertz can't redirect it.
+func (l *hertzLogger) SetOutput(io.Writer) {}
+
+func fromHertzLevel(level hlog.Level) slog.Level {
+	switch level {
+	case hlog.LevelTrace:
+		return LevelTrace
+	case hlog.LevelDebug:
+		return slog.LevelDebug
+	case hlog.LevelInfo:
+		return slog.LevelInfo
+	case hlog.LevelNotice:
+		return LevelNotice
+	case hlog.LevelWarn:
+		return slog.LevelWarn
+	case hlog.LevelError:
+		return slog.LevelError
+	case hlog.LevelFatal:
+		return LevelFatal
+	}
+	return slog.LevelInfo
+}
+
+// replaceLevelNames names the extra hlog levels instead of DEBUG-4 and ERROR+4
+func replaceLevelNames(_ []string, attr slog.Attr) slog.Attr {
+	if attr.Key != slog.LevelKey {
+		return attr
+	}
+	level, ok := attr.Value.Any().(slog.Level)
+	if !ok {
+		return attr
+	}
+	switch level {
+	case LevelTrace:
+		attr.Value = slog.StringValue("TRACE")
+	case LevelNotice:
+		attr.Value = slog.StringValue("NOTICE")
+	case LevelFatal:
+		attr.Value = slog.StringValue("FATAL")
+	}
+	return attr
+}
@@ -0,0 +1,161 @@
+// Package logger configures the process wide structured logger. Logs of
+// log/slog, the standard log package and Hertz's hlog all go through it,
+// with the request, user and workspace of the context attached.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	formatJSON    = "json"
+	formatConsole = "console"
+	formatText    = "text"
+
+	outputStdout = "stdout"
+	outputStderr = "stderr"
+
+	logFileMode = 0o640
+)
+
+// Context fields, attached to every record logged with the context
+const (
+	RequestIDKey   = "request_id"
+	UserIDKey      = "user_id"
+	WorkspaceIDKey = "workspace_id"
+	TraceIDKey     = "trace_id"
+)
+
+type attrsKey struct{}
+
+// level is the minimum level of the logger set up by Setup
+var level = new(slog.LevelVar)
+
+// Setup makes a logger configured from cfg the default of slog, log and
+// hlog. service is added to every record. The returned closer closes the
+// log file, if there is one.
+func Setup(cfg *config.LoggingConfig, service string) (io.Closer, error) {
+	lvl, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	level.Set(lvl)
+
+	out, closer, err := openOutput(cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevelNames}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", formatJSON:
+		handler = slog.NewJSONHandler(out, opts)
+	case formatConsole, formatText:
+		handler = slog.NewTextHandler(out, opts)
+	default:
+		_ = closer.Close()
+		return nil, fmt.Errorf("unknown log format %q, use json or console", cfg.Format)
+	}
+
+	log := slog.New(&contextHandler{Handler: handler}).With("service", service)
+	slog.SetDefault(log)
+	hlog.SetLogger(newHertzLogger(log, level))
+
+	return closer, nil
+}
+
+// SetLevel changes the minimum level of the logger while it runs
+func SetLevel(name string) error {
+	lvl, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error. Empty means info.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", level)
+}
+
+func openOutput(output string) (io.Writer, io.Closer, error) {
+	switch output {
+	case "", outputStdout:
+		return os.Stdout, io.NopCloser(nil), nil
+	case outputStderr:
+		return os.Stderr, io.NopCloser(nil), nil
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFileMode) // #nosec G304 -- path comes from the config file
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, file, nil
+}
+
+// With returns a context whose log records carry the given key/value pairs,
+// such as the user of a request
+func With(ctx context.Context, args ...any) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	record := slog.Record{}
+	record.Add(args...)
+
+	attrs := make([]slog.Attr, 0, len(existing)+record.NumAttrs())
+	attrs = append(attrs, existing...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// contextHandler adds the fields stored with With and the trace ID to the
+// records logged with a context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+			record.AddAttrs(attrs...)
+		}
+		if traceID := tracing.TraceIDFromContext(ctx); traceID != "" {
+			record.AddAttrs(slog.String(TraceIDKey, traceID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"runtime"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// RegisterRuntime registers Go runtime gauges
+func RegisterRuntime(r *Registry) {
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.NewGaugeFunc("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects", func() float64 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return float64(stats.HeapAlloc)
+	})
+}
+
+// RegisterDBPool registers the connection stats of the PostgreSQL pool
+func RegisterDBPool(r *Registry, pool *pgxpool.Pool) {
+	registerPgxPool(r, "db_pool", "PostgreSQL", pool)
+}
+
+// RegisterDBReplicaPool registers the connection stats of the pool of the
+// PostgreSQL read replica
+func RegisterDBReplicaPool(r *Registry, pool *pgxpool.Pool) {
+	registerPgxPool(r, "db_replica_pool", "PostgreSQL replica", pool)
+}
+
+func registerPgxPool(r *Registry, prefix, db string, pool *pgxpool.Pool) {
+	r.NewGaugeFunc(prefix+"_connections_open", "Open "+db+" connections", func() float64 {
+		return float64(pool.Stat().TotalConns())
+	})
+	r.NewGaugeFunc(prefix+"_connections_acquired", db+" connections in use", func() float64 {
+		return float64(pool.Stat().AcquiredConns())
+	})
+	r.NewGaugeFunc(prefix+"_connections_idle", "Idle "+db+" connections", func() float64 {
+		return float64(pool.Stat().IdleConns())
+	})
+	r.NewGaugeFunc(prefix+"_connections_max", "Maximum "+db+" connections of the pool", func() float64 {
+		return float64(pool.Stat().MaxConns())
+	})
+	r.NewCounterFunc(prefix+"_acquires_total", "Connections acquired from the "+db+" pool", func() float64 {
+		return float64(pool.Stat().AcquireCount())
+	})
+	r.NewCounterFunc(
+		prefix+"_empty_acquires_total", "Acquires that waited because the "+db+" pool was exhausted",
+		func() float64 {
+			return float64(pool.Stat().EmptyAcquireCount())
+		},
+	)
+	r.NewCounterFunc(prefix+"_acquire_seconds_total", "Time spent acquiring "+db+" connections", func() float64 {
+		return pool.Stat().AcquireDuration().Seconds()
+	})
+	r.NewCounterFunc(
+		prefix+"_lifetime_closes_total", db+" connections closed because they reached their maximum lifetime",
+		func() float64 {
+			return float64(pool.Stat().MaxLifetimeDestroyCount())
+		},
+	)
+	r.NewCounterFunc(
+		prefix+"_idle_closes_total", db+" connections closed because they were idle for too long",
+		func() float64 {
+			return float64(pool.Stat().MaxIdleDestroyCount())
+		},
+	)
+}
+
+// RegisterRedis registers the connection pool stats of the Redis client
+func RegisterRedis(r *Registry, client *redis.Client) {
+	r.NewGaugeFunc("redis_pool_connections_open", "Open Redis connections", func() float64 {
+		return float64(client.PoolStats().TotalConns)
+	})
+	r.NewGaugeFunc("redis_pool_connections_idle", "Idle Redis connections", func() float64 {
+		return float64(client.PoolStats().IdleConns)
+	})
+	r.NewGaugeFunc("redis_pool_connections_max", "Maximum Redis connections of the pool", func() float64 {
+		return float64(client.Options().PoolSize)
+	})
+	r.NewCounterFunc("redis_pool_stale_closes_total", "Redis connections closed as stale", func() float64 {
+		return float64(client.PoolStats().StaleConns)
+	})
+	r.NewCounterFunc("redis_pool_hits_total", "Free connections found in the Redis pool", func() float64 {
+		return float64(client.PoolStats().Hits)
+	})
+	r.NewCounterFunc("redis_pool_misses_total", "Redis pool lookups that opened a new connection", func() float64 {
+		return float64(client.PoolStats().Misses)
+	})
+	r.NewCounterFunc("redis_pool_timeouts_total", "Redis pool waits that timed out", func() float64 {
+		return float64(client.PoolStats().Timeouts)
+	})
+}
+
+// RegisterNATS registers the message stats and state of the NATS connection
+func RegisterNATS(r *Registry, nc *nats.Conn) {
+	r.NewGaugeFunc("nats_connected", "Whether the NATS connection is up", func() float64 {
+		if nc.IsConnected() {
+			return 1
+		}
+		return 0
+	})
+	r.NewCounterFunc("nats_messages_in_total", "Messages received from NATS", func() float64 {
+		return float64(nc.Stats().InMsgs)
+	})
+	r.NewCounterFunc("nats_messages_out_total", "Messages published to NATS", func() float64 {
+		return float64(nc.Stats().OutMsgs)
+	})
+	r.NewCounterFunc("nats_bytes_in_total", "Bytes received from NATS", func() float64 {
+		return float64(nc.Stats().InBytes)
+	})
+	r.NewCounterFunc("nats_bytes_out_total", "Bytes published to NATS", func() float64 {
+		return float64(nc.Stats().OutBytes)
+	})
+	r.NewCounterFunc("nats_reconnects_total", "Reconnects of the NATS connection", func() float64 {
+		return float64(nc.Stats().Reconnects)
+	})
+}
+
+// RegisterHub registers the room and client counts of the local hub
+func RegisterHub(r *Registry, hub *service.Hub) {
+	r.NewGaugeFunc("hub_rooms", "Workspaces with realtime clients on this instance", func() float64 {
+		return float64(len(hub.GetAllRoomStats()))
+	})
+	r.NewGaugeFunc("hub_clients", "Realtime clients connected to this instance", func() float64 {
+		clients := 0
+		for _, count := range hub.GetAllRoomStats() {
+			clients += count
+		}
+		return float64(clients)
+	})
+}
+
+// RegisterOutboxRelay registers the throughput and backlog of the outbox relay
+func RegisterOutboxRelay(r *Registry, relay *service.OutboxRelay) {
+	r.NewCounterFunc("outbox_published_total", "Outbox messages published to NATS", func() float64 {
+		return float64(relay.Stats().Published)
+	})
+	r.NewCounterFunc("outbox_failures_total", "Outbox batches that failed to publish", func() float64 {
+		return float64(relay.Stats().Failed)
+	})
+	r.NewGaugeFunc("outbox_pending_messages", "Unpublished outbox messages at the last poll", func() float64 {
+		return float64(relay.Stats().Pending)
+	})
+	r.NewGaugeFunc("outbox_lag_seconds", "Age of the oldest unpublished outbox message at the last poll", func() float64 {
+		return relay.Stats().Lag.Seconds()
+	})
+}
+
+// RegisterAuditExporters registers the delivery and backlog of the audit
+// sinks by sink name
+func RegisterAuditExporters(r *Registry, exporters []*service.AuditExporter) {
+	if len(exporters) == 0 {
+		return
+	}
+
+	stat := func(value func(stats service.AuditExporterStats) float64) func() map[string]float64 {
+		return func() map[string]float64 {
+			samples := make(map[string]float64, len(exporters))
+			for _, exporter := range exporters {
+				stats := exporter.Stats()
+				samples[stats.Sink] = value(stats)
+			}
+			return samples
+		}
+	}
+
+	r.NewCounterFuncVec("audit_events_delivered_total", "Audit events shipped to the sink", "sink",
+		stat(func(stats service.AuditExporterStats) float64 { return float64(stats.Delivered) }))
+	r.NewCounterFuncVec("audit_delivery_failures_total", "Audit batches the sink failed to take", "sink",
+		stat(func(stats service.AuditExporterStats) float64 { return float64(stats.Failed) }))
+	r.NewGaugeFuncVec("audit_pending_events", "Audit events not shipped to the sink yet", "sink",
+		stat(func(stats service.AuditExporterStats) float64 { return float64(stats.Pending) }))
+	r.NewGaugeFuncVec("audit_delivery_lag_seconds", "Age of the oldest audit event not shipped to the sink yet", "sink",
+		stat(func(stats service.AuditExporterStats) float64 { return stats.Lag.Seconds() }))
+}
+
+// NewServiceRegistry creates a registry with the runtime, connection and hub
+// metrics that the api-gateway and the ws-server share
+func NewServiceRegistry(pool *pgxpool.Pool, client *redis.Client, nc *nats.Conn, hub *service.Hub) *Registry {
+	r := NewRegistry()
+	RegisterRuntime(r)
+	RegisterDBPool(r, pool)
+	RegisterRedis(r, client)
+	RegisterNATS(r, nc)
+	RegisterHub(r, hub)
+	return r
+}
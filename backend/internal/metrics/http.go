@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// httpDurationBuckets are the latency buckets of HTTP requests, in seconds
+var httpDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HTTPMetrics records request counts and latencies by route
+type HTTPMetrics struct {
+	requests  *CounterVec
+	duration  *HistogramVec
+	throttled *CounterVec
+}
+
+// NewHTTPMetrics registers the HTTP request metrics
+func NewHTTPMetrics(r *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounterVec(
+			"http_requests_total", "Number of HTTP requests by route and status",
+			"method", "route", "status",
+		),
+		duration: r.NewHistogramVec(
+			"http_request_duration_seconds", "Latency of HTTP requests by route",
+			httpDurationBuckets, "method", "route",
+		),
+		throttled: r.NewCounterVec(
+			"http_requests_throttled_total", "Number of HTTP requests rejected by a rate limit",
+			"limit",
+		),
+	}
+}
+
+// Observe records a finished request. route is the registered pattern such
+// as /api/v1/workspaces/:workspace_id, so IDs don't create new series.
+func (m *HTTPMetrics) Observe(method, route string, status int, duration time.Duration) {
+	m.requests.Inc(method, route, strconv.Itoa(status))
+	m.duration.Observe(duration.Seconds(), method, route)
+}
+
+// Throttled records a request rejected by the rate limit of limit, the route
+// prefix of an override or "default". A nil HTTPMetrics records nothing.
+func (m *HTTPMetrics) Throttled(limit string) {
+	if m == nil {
+		return
+	}
+	m.throttled.Inc(limit)
+}
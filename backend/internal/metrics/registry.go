@@ -0,0 +1,315 @@
+// Package metrics exposes application metrics in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+
+	// labelSeparator joins label values into map keys, it can't appear in
+	// valid UTF-8
+	labelSeparator = "\xff"
+)
+
+// collector writes the samples of one metric family
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds the metrics of a process and renders them for scraping
+type Registry struct {
+	collectors []collector
+	mu         sync.Mutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo renders all metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler serves the metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	values map[string]*sample
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+}
+
+// NewCounterVec registers a counter with the given label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*sample)}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter of the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the counter of the label values
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.values[key]
+	if !ok {
+		s = &sample{labelValues: labelValues}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, typeCounter)
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		writeSample(w, c.name, c.labels, s.labelValues, "", "", s.value)
+	}
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	values  map[string]*histogramSample
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+}
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds
+// and label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogramSample),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a value in the histogram of the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogramSample{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = s
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, typeHistogram)
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		for i, bound := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, s.labelValues, "le", formatFloat(bound), float64(s.counts[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.name+"_sum", h.labels, s.labelValues, "", "", s.sum)
+		writeSample(w, h.name+"_count", h.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// funcMetric is a gauge or counter whose value is read at scrape time, for
+// stats kept by other libraries like connection pools
+type funcMetric struct {
+	fn   func() float64
+	name string
+	help string
+	typ  string
+}
+
+// NewGaugeFunc registers a gauge that calls fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{name: name, help: help, typ: typeGauge, fn: fn})
+}
+
+// NewCounterFunc registers a counter that calls fn on every scrape. fn must
+// return a value that only goes up.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{name: name, help: help, typ: typeCounter, fn: fn})
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, m.name, m.help, m.typ)
+	writeSample(w, m.name, nil, nil, "", "", m.fn())
+}
+
+// funcVecMetric is a funcMetric with one label, whose values and samples
+// are read at scrape time
+type funcVecMetric struct {
+	fn    func() map[string]float64
+	name  string
+	help  string
+	typ   string
+	label string
+}
+
+// NewGaugeFuncVec registers a gauge that calls fn on every scrape for the
+// samples by value of label
+func (r *Registry) NewGaugeFuncVec(name, help, label string, fn func() map[string]float64) {
+	r.register(&funcVecMetric{name: name, help: help, typ: typeGauge, label: label, fn: fn})
+}
+
+// NewCounterFuncVec registers a counter that calls fn on every scrape for
+// the samples by value of label. The samples must only go up.
+func (r *Registry) NewCounterFuncVec(name, help, label string, fn func() map[string]float64) {
+	r.register(&funcVecMetric{name: name, help: help, typ: typeCounter, label: label, fn: fn})
+}
+
+func (m *funcVecMetric) write(w *bufio.Writer) {
+	samples := m.fn()
+	writeHeader(w, m.name, m.help, m.typ)
+	labels := []string{m.label}
+	for _, value := range sortedKeys(samples) {
+		writeSample(w, m.name, labels, []string{value}, "", "", samples[value])
+	}
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+type histogramSample struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	_, _ = w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	_, _ = w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeSample writes one line, extraLabel is the le label of histogram buckets
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	_, _ = w.WriteString(name)
+
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, label+`="`+escapeLabelValue(value)+`"`)
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		_, _ = w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	_, _ = w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// sortedKeys keeps the output stable between scrapes
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
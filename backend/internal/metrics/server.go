@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const metricsReadHeaderTimeout = 5 * time.Second
+
+// NewServer returns the HTTP server that serves /metrics on its own port, so
+// scrapes don't go through the public API
+func NewServer(port int, r *Registry) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: metricsReadHeaderTimeout,
+	}
+}
+
+// Start serves metrics in the background. A failing metrics server is logged
+// and doesn't stop the application.
+func Start(srv *http.Server) {
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			hlog.Errorf("Metrics server failed: %v", err)
+		}
+	}()
+	hlog.Infof("Metrics are served on %s/metrics", srv.Addr)
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// RequireAdmin allows only the users listed in the admin config. It must run
+// after the auth middleware.
+func RequireAdmin(cfg *config.AdminConfig) app.HandlerFunc {
+	admins := make(map[uuid.UUID]bool, len(cfg.UserIDs))
+	for _, id := range cfg.UserIDs {
+		adminID, err := uuid.Parse(id)
+		if err != nil {
+			hlog.Warnf("Ignoring invalid admin user ID %q: %v", id, err)
+			continue
+		}
+		admins[adminID] = true
+	}
+
+	return func(ctx context.Context, c *app.RequestContext) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "Unauthorized",
+			})
+			c.Abort()
+			return
+		}
+
+		uid, ok := userID.(uuid.UUID)
+		if !ok || !admins[uid] {
+			c.JSON(http.StatusForbidden, map[string]interface{}{
+				"error": "Admin access required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
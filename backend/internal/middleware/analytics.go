@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// Analytics records every API request with its route, status, latency and
+// user for usage analytics
+func Analytics(analytics *service.AnalyticsService) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+
+		ctx.Next(c)
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		var userID *uuid.UUID
+		if value, exists := ctx.Get("user_id"); exists {
+			if uid, ok := value.(uuid.UUID); ok {
+				userID = &uid
+			}
+		}
+
+		analytics.TrackAPIRequest(string(ctx.Method()), route, ctx.Response.StatusCode(), time.Since(start), userID)
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/bifshteksex/hertz-board/internal/logger"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// APIKeyAuth returns API key authentication middleware for automation
+// platforms. The key is read from the X-API-Key header or a bearer
+// Authorization header. The user and workspace of the key are stored in
+// the context as user_id and workspace_id, the key itself as api_key.
+func APIKeyAuth(apiKeyService *service.APIKeyService) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		secret := string(ctx.Request.Header.Peek("X-API-Key"))
+		if secret == "" {
+			secret = strings.TrimPrefix(string(ctx.Request.Header.Peek("Authorization")), "Bearer ")
+		}
+
+		if secret == "" {
+			ctx.JSON(consts.StatusUnauthorized, map[string]interface{}{
+				"error": "API key required",
+			})
+			ctx.Abort()
+			return
+		}
+
+		key, err := apiKeyService.Authenticate(c, secret)
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			ctx.JSON(consts.StatusUnauthorized, map[string]interface{}{
+				"error": "Invalid API key",
+			})
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			hlog.CtxErrorf(c, "Failed to authenticate API key: %v", err)
+			ctx.JSON(consts.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to authenticate API key",
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set("user_id", key.UserID)
+		ctx.Set("workspace_id", key.WorkspaceID)
+		ctx.Set("api_key", key)
+
+		c = service.WithAuditActor(c, models.AuditActorAPIKey, &key.UserID)
+		ctx.Next(logger.With(c, logger.UserIDKey, key.UserID.String()))
+	}
+}
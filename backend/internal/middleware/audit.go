@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// AuditContext attaches the client of a request to the context, so the
+// audit events recorded while serving it carry the IP, user agent and
+// request ID. Must run after RequestID.
+func AuditContext() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		ctx.Next(service.WithAuditRequest(c, ctx.ClientIP(), string(ctx.UserAgent()), GetRequestID(ctx)))
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 
+	"github.com/bifshteksex/hertz-board/internal/logger"
+	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/service"
 )
 
@@ -32,22 +34,48 @@ func Auth(jwtService *service.JWTService) app.HandlerFunc {
 			return
 		}
 
-		token := parts[1]
+		authenticate(c, ctx, jwtService, parts[1])
+	}
+}
+
+// StreamAuth returns JWT authentication middleware for streaming endpoints.
+// Browser EventSource cannot set headers, so the token may also be passed
+// as the "token" query parameter, the same way the WebSocket endpoint does.
+func StreamAuth(jwtService *service.JWTService) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		token := ctx.Query("token")
+		if token == "" {
+			token = strings.TrimPrefix(string(ctx.Request.Header.Peek("Authorization")), "Bearer ")
+		}
 
-		// Validate token
-		claims, err := jwtService.ValidateAccessToken(token)
-		if err != nil {
+		if token == "" {
 			ctx.JSON(consts.StatusUnauthorized, map[string]interface{}{
-				"error": "Invalid or expired token",
+				"error": "Authentication token required",
 			})
 			ctx.Abort()
 			return
 		}
 
-		// Set user information in context
-		ctx.Set("user_id", claims.UserID)
-		ctx.Set("user_email", claims.Email)
+		authenticate(c, ctx, jwtService, token)
+	}
+}
 
-		ctx.Next(c)
+// authenticate validates the access token and stores user info in context
+func authenticate(c context.Context, ctx *app.RequestContext, jwtService *service.JWTService, token string) {
+	claims, err := jwtService.ValidateAccessToken(token)
+	if err != nil {
+		ctx.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"error": "Invalid or expired token",
+		})
+		ctx.Abort()
+		return
 	}
+
+	// Set user information in context
+	ctx.Set("user_id", claims.UserID)
+	ctx.Set("user_email", claims.Email)
+	ctx.Set("username", claims.Username)
+
+	c = service.WithAuditActor(c, models.AuditActorUser, &claims.UserID)
+	ctx.Next(logger.With(c, logger.UserIDKey, claims.UserID.String()))
 }
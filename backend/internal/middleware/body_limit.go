@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// errBodyTooLarge is returned when reading past the limit of a streamed body
+var errBodyTooLarge = errors.New("request body too large")
+
+// BodyLimit rejects request bodies larger than the limit of their route, or
+// defaultLimit for routes without one. Routes are keyed by their registered
+// path, e.g. /api/v1/workspaces/:workspace_id/assets, so uploads can accept
+// larger bodies than the JSON API.
+//
+// Bodies with a Content-Length are checked before the handler runs. Chunked
+// bodies are streamed, reading past the limit fails.
+func BodyLimit(defaultLimit int64, routes map[string]int64) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		limit := defaultLimit
+		if routeLimit, ok := routes[c.FullPath()]; ok {
+			limit = routeLimit
+		}
+
+		length := c.Request.Header.ContentLength()
+		if int64(length) > limit {
+			c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+				"error":    "Request body too large",
+				"max_size": limit,
+			})
+			c.Abort()
+			return
+		}
+
+		if length < 0 && c.Request.IsBodyStream() {
+			body := &limitedBody{body: c.Request.BodyStream(), remaining: limit}
+			c.Request.ConstructBodyStream(c.Request.BodyBuffer(), body)
+		}
+
+		c.Next(ctx)
+	}
+}
+
+// limitedBody fails reads of a streamed body past its limit
+type limitedBody struct {
+	body      io.Reader
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// A body that ends exactly at the limit is fine
+		var probe [1]byte
+		n, err := b.body.Read(probe[:])
+		if n > 0 {
+			return 0, errBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// Close releases the underlying stream, which returns the connection to
+// the server
+func (b *limitedBody) Close() error {
+	if closer, ok := b.body.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
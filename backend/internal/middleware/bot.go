@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/bifshteksex/hertz-board/internal/logger"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// botRouteScopes maps the workspace routes bot tokens may call, by method
+// and registered path, to the scope they need. Other routes are closed to
+// bots.
+var botRouteScopes = map[string]string{
+	"GET /api/v1/workspaces/:workspace_id":                         models.BotScopeBoardRead,
+	"GET /api/v1/workspaces/:workspace_id/members":                 models.BotScopeBoardRead,
+	"GET /api/v1/workspaces/:workspace_id/elements":                models.BotScopeElementsRead,
+	"GET /api/v1/workspaces/:workspace_id/elements/by-type":        models.BotScopeElementsRead,
+	"GET /api/v1/workspaces/:workspace_id/elements/:element_id":    models.BotScopeElementsRead,
+	"POST /api/v1/workspaces/:workspace_id/elements":               models.BotScopeElementsCreate,
+	"POST /api/v1/workspaces/:workspace_id/elements/batch":         models.BotScopeElementsCreate,
+	"PUT /api/v1/workspaces/:workspace_id/elements/:element_id":    models.BotScopeElementsUpdate,
+	"PUT /api/v1/workspaces/:workspace_id/elements/batch":          models.BotScopeElementsUpdate,
+	"DELETE /api/v1/workspaces/:workspace_id/elements/:element_id": models.BotScopeElementsDelete,
+	"DELETE /api/v1/workspaces/:workspace_id/elements/batch":       models.BotScopeElementsDelete,
+}
+
+// WorkspaceAuth returns the authentication middleware of the workspace
+// routes. Users authenticate with a JWT, bots with a bot token that only
+// reaches the routes of its scopes, in the workspace of its bot. The bot is
+// stored in the context as user_id, the token as bot_token.
+func WorkspaceAuth(jwtService *service.JWTService, botService *service.BotService) app.HandlerFunc {
+	userAuth := Auth(jwtService)
+	return func(c context.Context, ctx *app.RequestContext) {
+		secret := strings.TrimPrefix(string(ctx.Request.Header.Peek("Authorization")), "Bearer ")
+		if !service.IsBotToken(secret) {
+			userAuth(c, ctx)
+			return
+		}
+
+		token, err := botService.Authenticate(c, secret)
+		if errors.Is(err, service.ErrInvalidBotToken) {
+			ctx.JSON(consts.StatusUnauthorized, map[string]interface{}{
+				"error": "Invalid or expired bot token",
+			})
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			hlog.CtxErrorf(c, "Failed to authenticate bot token: %v", err)
+			ctx.JSON(consts.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to authenticate bot token",
+			})
+			ctx.Abort()
+			return
+		}
+
+		if !authorizeBot(ctx, token) {
+			return
+		}
+
+		ctx.Set("user_id", token.BotID)
+		ctx.Set("bot_token", token)
+
+		c = service.WithAuditActor(c, models.AuditActorBot, &token.BotID)
+		ctx.Next(logger.With(c, logger.UserIDKey, token.BotID.String()))
+	}
+}
+
+// OptionalWorkspaceAuth authenticates like WorkspaceAuth when a request has
+// an Authorization header and lets it through without a user otherwise. It
+// serves the public read routes, whose access middleware decides whether
+// anonymous visitors are allowed.
+func OptionalWorkspaceAuth(jwtService *service.JWTService, botService *service.BotService) app.HandlerFunc {
+	workspaceAuth := WorkspaceAuth(jwtService, botService)
+	return func(c context.Context, ctx *app.RequestContext) {
+		if len(ctx.Request.Header.Peek("Authorization")) == 0 {
+			ctx.Next(c)
+			return
+		}
+		workspaceAuth(c, ctx)
+	}
+}
+
+// authorizeBot checks the scope, workspace and element types of a bot
+// request and responds when it's refused
+func authorizeBot(ctx *app.RequestContext, token *models.BotToken) bool {
+	scope := botRouteScopes[string(ctx.Method())+" "+ctx.FullPath()]
+	if scope == "" || !token.HasScope(scope) {
+		ctx.JSON(consts.StatusForbidden, map[string]interface{}{
+			"error": "Bot token is not allowed to call this endpoint",
+			"code":  "insufficient_scope",
+			"scope": scope,
+		})
+		ctx.Abort()
+		return false
+	}
+
+	if ctx.Param("workspace_id") != token.WorkspaceID.String() {
+		ctx.JSON(consts.StatusForbidden, map[string]interface{}{
+			"error": "Bot token belongs to another workspace",
+		})
+		ctx.Abort()
+		return false
+	}
+
+	if scope == models.BotScopeElementsCreate && len(token.ElementTypes) > 0 {
+		elementTypes, err := createdElementTypes(ctx)
+		if err != nil {
+			ctx.JSON(consts.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid request body",
+			})
+			ctx.Abort()
+			return false
+		}
+		for _, elementType := range elementTypes {
+			if !token.CanCreate(elementType) {
+				ctx.JSON(consts.StatusForbidden, map[string]interface{}{
+					"error":         "Bot token is not allowed to create " + string(elementType) + " elements",
+					"code":          "element_type_not_allowed",
+					"element_types": token.ElementTypes,
+				})
+				ctx.Abort()
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// createdElementTypes returns the types of the elements a create or batch
+// create request creates. The handler binds the body again.
+func createdElementTypes(ctx *app.RequestContext) ([]models.ElementType, error) {
+	body := ctx.Request.Body()
+
+	if strings.HasSuffix(ctx.FullPath(), "/batch") {
+		var req models.BatchCreateRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		types := make([]models.ElementType, len(req.Elements))
+		for i := range req.Elements {
+			types[i] = req.Elements[i].ElementType
+		}
+		return types, nil
+	}
+
+	var req models.CreateElementRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return []models.ElementType{req.ElementType}, nil
+}
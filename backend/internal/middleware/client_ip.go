@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ClientIP resolves the address of clients for ctx.ClientIP. X-Forwarded-For
+// and X-Real-IP are only used when the peer is one of the trusted proxies,
+// and X-Forwarded-For is followed back through trusted proxies only. Hertz
+// trusts them from any peer by default, which let clients pick the address
+// IP allowlists and rate limits see.
+func ClientIP(trustedProxies []*net.IPNet) app.ClientIP {
+	return app.ClientIPWithOption(app.ClientIPOptions{
+		RemoteIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+		TrustedCIDRs:    trustedProxies,
+	})
+}
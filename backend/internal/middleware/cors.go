@@ -13,9 +13,20 @@ const (
 	httpStatusNoContent = 204
 )
 
-// CORS returns a CORS middleware
-func CORS(cfg *config.CORSConfig) app.HandlerFunc {
+// exposedHeaders are the response headers the frontend may read
+var exposedHeaders = strings.Join([]string{
+	RequestIDHeader,
+	RateLimitLimitHeader,
+	RateLimitRemainingHeader,
+	RateLimitResetHeader,
+	"Retry-After",
+}, ", ")
+
+// CORS returns a CORS middleware. The allowed origins, methods and headers
+// are read from the current config, so reloading it applies them.
+func CORS(reloader *config.Reloader) app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
+		cfg := &reloader.Current().CORS
 		origin := string(ctx.Request.Header.Peek("Origin"))
 
 		// Check if origin is allowed
@@ -29,6 +40,7 @@ func CORS(cfg *config.CORSConfig) app.HandlerFunc {
 
 		if allowedOrigin != "" {
 			ctx.Response.Header.Set("Access-Control-Allow-Origin", allowedOrigin)
+			ctx.Response.Header.Set("Access-Control-Expose-Headers", exposedHeaders)
 		}
 
 		if cfg.AllowCredentials {
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// EmbedHeaders returns the CORS and framing headers of the embed API. Embeds
+// are fetched by the sites they are embedded in, without credentials, so
+// they replace the headers of the app's own CORS policy.
+func EmbedHeaders(cfg *config.EmbedConfig) app.HandlerFunc {
+	frameAncestors := "'none'"
+	if len(cfg.FrameAncestors) > 0 {
+		frameAncestors = strings.Join(cfg.FrameAncestors, " ")
+	}
+	csp := "frame-ancestors " + frameAncestors
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		origin := string(ctx.Request.Header.Peek("Origin"))
+
+		ctx.Response.Header.Del("Access-Control-Allow-Origin")
+		ctx.Response.Header.Del("Access-Control-Allow-Credentials")
+		for _, allowed := range cfg.AllowedOrigins {
+			if allowed == "*" {
+				ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+				break
+			}
+			if allowed == origin {
+				ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+				ctx.Response.Header.Set("Vary", "Origin")
+				break
+			}
+		}
+		ctx.Response.Header.Set("Access-Control-Expose-Headers", "ETag")
+		ctx.Response.Header.Set("Content-Security-Policy", csp)
+
+		ctx.Next(c)
+	}
+}
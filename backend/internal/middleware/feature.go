@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// RequireFeature answers 404 while a feature is listed in
+// features.disabled. The flag is read from the current config, so reloading
+// it switches the feature on and off.
+func RequireFeature(reloader *config.Reloader, feature string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		if !reloader.Current().Features.Enabled(feature) {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "This feature is disabled",
+				"code":  "feature_disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
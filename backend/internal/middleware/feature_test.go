@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+func TestRequireFeature(t *testing.T) {
+	reloader := config.NewReloader("", &config.Config{
+		Features: config.FeaturesConfig{Disabled: []string{config.FeatureAI}},
+	})
+
+	engine := route.NewEngine(hertzconfig.NewOptions(nil))
+	engine.GET("/ai", RequireFeature(reloader, config.FeatureAI), func(ctx context.Context, c *app.RequestContext) {
+		c.Status(http.StatusOK)
+	})
+	engine.GET("/embed", RequireFeature(reloader, config.FeatureEmbed), func(ctx context.Context, c *app.RequestContext) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/ai", http.StatusNotFound},
+		{"/embed", http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp := ut.PerformRequest(engine, http.MethodGet, tt.path, nil).Result()
+		if resp.StatusCode() != tt.want {
+			t.Errorf("GET %s: got status %d, want %d", tt.path, resp.StatusCode(), tt.want)
+		}
+	}
+}
@@ -2,33 +2,49 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/logger"
 )
 
-// Logger logs HTTP requests
+// Logger logs HTTP requests. The workspace of the route is attached to
+// every record logged while handling the request.
 func Logger() app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
 		start := time.Now()
-		path := string(ctx.Path())
-		method := string(ctx.Method())
-		requestID := GetRequestID(ctx)
+
+		if workspaceID := ctx.Param("workspace_id"); workspaceID != "" {
+			c = logger.With(c, logger.WorkspaceIDKey, workspaceID)
+		}
 
 		ctx.Next(c)
 
-		latency := time.Since(start)
 		statusCode := ctx.Response.StatusCode()
-		clientIP := ctx.ClientIP()
-
-		log.Printf("[%s] %s %s %d %v %s",
-			requestID,
-			method,
-			path,
-			statusCode,
-			latency,
-			clientIP,
-		)
+		level := slog.LevelInfo
+		if statusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []any{
+			"method", string(ctx.Method()),
+			"path", string(ctx.Path()),
+			"route", ctx.FullPath(),
+			"status", statusCode,
+			"latency", time.Since(start).String(),
+			"client_ip", ctx.ClientIP(),
+		}
+		// The user is only known once Auth ran further down the chain
+		if userID, exists := ctx.Get("user_id"); exists {
+			if uid, ok := userID.(uuid.UUID); ok {
+				attrs = append(attrs, logger.UserIDKey, uid.String())
+			}
+		}
+
+		slog.Log(c, level, "HTTP request", attrs...)
 	}
 }
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/bifshteksex/hertz-board/internal/metrics"
+)
+
+// unmatchedRoute labels requests that didn't match a route, so scans of
+// random paths don't create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count, status and latency of requests by route
+func Metrics(m *metrics.HTTPMetrics) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+
+		ctx.Next(c)
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.Observe(string(ctx.Method()), route, ctx.Response.StatusCode(), time.Since(start))
+	}
+}
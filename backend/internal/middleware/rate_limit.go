@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/metrics"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// Rate limit headers of the IETF RateLimit header fields draft
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// defaultRateLimitRoute names the bucket of the routes without an override
+const defaultRateLimitRoute = "default"
+
+// rateLimitRule is a limit of the routes whose pattern starts with route
+type rateLimitRule struct {
+	methods  map[string]bool // nil for every method
+	route    string
+	requests int
+	window   time.Duration
+}
+
+// rateLimitRules are the compiled limits of a config
+type rateLimitRules struct {
+	source      *config.Config
+	defaultRule *rateLimitRule
+	rules       []*rateLimitRule
+	enabled     bool
+}
+
+// RateLimit limits the requests of each user, or of each client address for
+// requests without a valid access token, by default to the configured
+// requests per duration over all routes. Routes with an override
+// have their own, usually tighter, limit. Limited requests get a 429 with
+// Retry-After, every request gets the RateLimit-* headers. Redis failures
+// are logged and let the request through. The limits are read from the
+// current config, so reloading it applies them. m may be nil.
+func RateLimit(
+	limiter *service.RateLimiter,
+	reloader *config.Reloader,
+	jwtService *service.JWTService,
+	m *metrics.HTTPMetrics,
+) (app.HandlerFunc, error) {
+	initial, err := compileRateLimit(reloader.Current())
+	if err != nil {
+		return nil, err
+	}
+	var compiled atomic.Pointer[rateLimitRules]
+	compiled.Store(initial)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		limits := compiled.Load()
+		if current := reloader.Current(); current != limits.source {
+			reloaded, err := compileRateLimit(current)
+			if err != nil {
+				hlog.CtxErrorf(c, "Keeping the previous rate limits: %v", err)
+				kept := *limits
+				kept.source = current
+				reloaded = &kept
+			}
+			compiled.CompareAndSwap(limits, reloaded)
+			limits = reloaded
+		}
+		if !limits.enabled {
+			ctx.Next(c)
+			return
+		}
+
+		rule := matchRateLimitRule(limits.rules, string(ctx.Method()), ctx.FullPath())
+		if rule == nil {
+			rule = limits.defaultRule
+		}
+		if rule.requests <= 0 || rule.window <= 0 {
+			ctx.Next(c)
+			return
+		}
+
+		result, err := limiter.Allow(c, rule.route+":"+rateLimitClient(ctx, jwtService), rule.requests, rule.window)
+		if err != nil {
+			hlog.CtxErrorf(c, "Failed to check rate limit of %s: %v", rule.route, err)
+			ctx.Next(c)
+			return
+		}
+
+		resetSeconds := int(math.Ceil(result.Reset.Seconds()))
+		reset := strconv.Itoa(resetSeconds)
+		ctx.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+		ctx.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		ctx.Header(RateLimitResetHeader, reset)
+
+		if !result.Allowed {
+			m.Throttled(rule.route)
+			ctx.Header("Retry-After", reset)
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, map[string]interface{}{
+				"error":       "Too many requests",
+				"code":        "rate_limited",
+				"retry_after": resetSeconds,
+			})
+			return
+		}
+
+		ctx.Next(c)
+	}, nil
+}
+
+// rateLimitClient names the bucket of a request. The limit runs before the
+// auth middleware, so the access token is validated here. Other requests
+// go by the address resolved through the trusted proxies, forwarding
+// headers from anyone else would give each request a bucket of its own.
+func rateLimitClient(ctx *app.RequestContext, jwtService *service.JWTService) string {
+	token := strings.TrimPrefix(string(ctx.Request.Header.Peek("Authorization")), "Bearer ")
+	if token == "" {
+		token = ctx.Query("token")
+	}
+	if token != "" {
+		if claims, err := jwtService.ValidateAccessToken(token); err == nil {
+			return "user:" + claims.UserID.String()
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+// compileRateLimit parses the rate limits of a config
+func compileRateLimit(source *config.Config) (*rateLimitRules, error) {
+	cfg := &source.RateLimit
+	limits := &rateLimitRules{source: source, enabled: cfg.Enabled}
+	if !cfg.Enabled {
+		return limits, nil
+	}
+
+	window, err := cfg.GetWindowDuration()
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit duration: %w", err)
+	}
+	limits.defaultRule = &rateLimitRule{route: defaultRateLimitRoute, requests: cfg.Requests, window: window}
+
+	limits.rules = make([]*rateLimitRule, 0, len(cfg.Routes))
+	for i := range cfg.Routes {
+		route := &cfg.Routes[i]
+		routeWindow, err := route.GetWindowDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit duration of %s: %w", route.Route, err)
+		}
+		rule := &rateLimitRule{route: route.Route, requests: route.Requests, window: routeWindow}
+		if len(route.Methods) > 0 {
+			rule.methods = make(map[string]bool, len(route.Methods))
+			for _, method := range route.Methods {
+				rule.methods[strings.ToUpper(method)] = true
+			}
+		}
+		limits.rules = append(limits.rules, rule)
+	}
+
+	return limits, nil
+}
+
+// matchRateLimitRule returns the rule with the longest route that prefixes
+// the route pattern, nil if none does
+func matchRateLimitRule(rules []*rateLimitRule, method, route string) *rateLimitRule {
+	var match *rateLimitRule
+	for _, rule := range rules {
+		if rule.methods != nil && !rule.methods[method] {
+			continue
+		}
+		if !strings.HasPrefix(route, rule.route) {
+			continue
+		}
+		if match == nil || len(rule.route) > len(match.route) {
+			match = rule
+		}
+	}
+	return match
+}
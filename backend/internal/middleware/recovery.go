@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"runtime/debug"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/bifshteksex/hertz-board/internal/logger"
 )
 
 // Recovery recovers from panics and returns 500
@@ -17,7 +20,11 @@ func Recovery() app.HandlerFunc {
 				requestID := GetRequestID(ctx)
 				stack := string(debug.Stack())
 
-				log.Printf("[%s] PANIC: %v\n%s", requestID, err, stack)
+				slog.ErrorContext(c, "Recovered from panic",
+					logger.RequestIDKey, requestID,
+					"panic", fmt.Sprint(err),
+					"stack", stack,
+				)
 
 				ctx.JSON(consts.StatusInternalServerError, map[string]interface{}{
 					"error":      "Internal server error",
@@ -5,6 +5,8 @@ import (
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/logger"
 )
 
 const RequestIDHeader = "X-Request-ID"
@@ -19,7 +21,7 @@ func RequestID() app.HandlerFunc {
 
 		ctx.Response.Header.Set(RequestIDHeader, requestID)
 		ctx.Set("request_id", requestID)
-		ctx.Next(c)
+		ctx.Next(logger.With(c, logger.RequestIDKey, requestID))
 	}
 }
 
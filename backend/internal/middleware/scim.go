@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// SCIMAuth returns bearer token authentication middleware for the SCIM
+// endpoints. Identity providers send the configured token in the
+// Authorization header, failures are answered with a SCIM error.
+func SCIMAuth(token string) app.HandlerFunc {
+	unauthorized, _ := json.Marshal(&models.SCIMErrorResponse{
+		Schemas: []string{models.SCIMSchemaError},
+		Status:  strconv.Itoa(consts.StatusUnauthorized),
+		Detail:  "Invalid bearer token",
+	})
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		header := string(ctx.Request.Header.Peek("Authorization"))
+		given, found := strings.CutPrefix(header, "Bearer ")
+		if token == "" || !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			ctx.Data(consts.StatusUnauthorized, "application/scim+json", unauthorized)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next(service.WithAuditActor(c, models.AuditActorSCIM, nil))
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+// TraceIDHeader returns the trace of a request to the client, so a bug
+// report can be matched with its trace
+const TraceIDHeader = "X-Trace-ID"
+
+// Tracing records a span for every request, continuing the trace of the
+// caller when it sends a traceparent header. Must run after RequestID.
+func Tracing() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !tracing.Enabled() {
+			ctx.Next(c)
+			return
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := string(ctx.Method())
+
+		c = tracing.Extract(c, string(ctx.GetHeader(tracing.TraceparentHeader)))
+		c, span := tracing.Start(c, method+" "+route, tracing.SpanKindServer)
+		defer span.End()
+
+		span.SetAttribute("http.method", method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", string(ctx.Path()))
+		span.SetAttribute("http.request_id", GetRequestID(ctx))
+		span.SetAttribute("client.address", ctx.ClientIP())
+		ctx.Header(TraceIDHeader, span.SpanContext().TraceID.String())
+
+		ctx.Next(c)
+
+		status := ctx.Response.StatusCode()
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.SetStatusError(fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
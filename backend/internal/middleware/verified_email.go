@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// RequireVerifiedEmail blocks users whose email isn't verified when the
+// policy is enabled. It must run after the auth middleware.
+func RequireVerifiedEmail(policy *service.EmailVerificationPolicy) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "Unauthorized",
+			})
+			c.Abort()
+			return
+		}
+
+		uid, ok := userID.(uuid.UUID)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "Invalid user ID",
+			})
+			c.Abort()
+			return
+		}
+
+		if err := policy.Check(ctx, uid); err != nil {
+			if errors.Is(err, service.ErrEmailNotVerified) {
+				c.JSON(http.StatusForbidden, map[string]interface{}{
+					"error":   "Email verification required",
+					"code":    service.EmailNotVerifiedCode,
+					"details": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+
+			hlog.CtxErrorf(ctx, "Failed to check email verification of user %s: %v", uid, err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to check email verification",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
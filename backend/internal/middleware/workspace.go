@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
@@ -13,14 +14,87 @@ import (
 
 type WorkspaceMiddleware struct {
 	workspaceService *service.WorkspaceService
+	ipAllowlist      *service.IPAllowlistService
 }
 
-func NewWorkspaceMiddleware(workspaceService *service.WorkspaceService) *WorkspaceMiddleware {
+func NewWorkspaceMiddleware(
+	workspaceService *service.WorkspaceService,
+	ipAllowlist *service.IPAllowlistService,
+) *WorkspaceMiddleware {
 	return &WorkspaceMiddleware{
 		workspaceService: workspaceService,
+		ipAllowlist:      ipAllowlist,
 	}
 }
 
+// checkIPAllowlist aborts the request when the workspace restricts access
+// to networks the client isn't in. userID is uuid.Nil for anonymous
+// visitors.
+func (m *WorkspaceMiddleware) checkIPAllowlist(
+	ctx context.Context,
+	c *app.RequestContext,
+	workspaceID, userID uuid.UUID,
+) bool {
+	if m.ipAllowlist == nil {
+		return true
+	}
+
+	err := m.ipAllowlist.CheckAccess(ctx, workspaceID, userID, c.ClientIP())
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, service.ErrIPNotAllowed) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": err.Error(),
+			"code":  "ip_not_allowed",
+		})
+	} else {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to check workspace access",
+		})
+	}
+	c.Abort()
+	return false
+}
+
+// RequireAllowedNetwork applies the IP allowlist of the workspace a request
+// was authenticated for, e.g. by an API key, to routes that don't name the
+// workspace in their path
+func (m *WorkspaceMiddleware) RequireAllowedNetwork() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		workspaceID, ok := c.Get("workspace_id")
+		wsID, isID := workspaceID.(uuid.UUID)
+		if !ok || !isID {
+			c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "Unauthorized",
+			})
+			c.Abort()
+			return
+		}
+		userID, _ := c.Get("user_id")
+		uid, _ := userID.(uuid.UUID)
+
+		if !m.checkIPAllowlist(ctx, c, wsID, uid) {
+			return
+		}
+		c.Next(ctx)
+	}
+}
+
+// respondAccessDenied aborts a request the user lacks the role for. Users
+// that aren't members are told they can request access.
+func respondAccessDenied(c *app.RequestContext, err error) {
+	body := map[string]interface{}{
+		"error": "Access denied",
+	}
+	if errors.Is(err, service.ErrWorkspaceAccessDenied) {
+		body["code"] = "access_required"
+	}
+	c.JSON(http.StatusForbidden, body)
+	c.Abort()
+}
+
 // RequireWorkspaceAccess checks if user has required access level to workspace
 func (m *WorkspaceMiddleware) RequireWorkspaceAccess(requiredRole models.WorkspaceRole) app.HandlerFunc {
 	return func(ctx context.Context, c *app.RequestContext) {
@@ -63,10 +137,11 @@ func (m *WorkspaceMiddleware) RequireWorkspaceAccess(requiredRole models.Workspa
 			return
 		}
 		if err := m.workspaceService.CheckPermission(ctx, workspaceID, uid, requiredRole); err != nil {
-			c.JSON(http.StatusForbidden, map[string]interface{}{
-				"error": "Access denied",
-			})
-			c.Abort()
+			respondAccessDenied(c, err)
+			return
+		}
+
+		if !m.checkIPAllowlist(ctx, c, workspaceID, uid) {
 			return
 		}
 
@@ -134,6 +209,10 @@ func (m *WorkspaceMiddleware) RequireWorkspaceOwner() app.HandlerFunc {
 			return
 		}
 
+		if !m.checkIPAllowlist(ctx, c, workspaceID, uid) {
+			return
+		}
+
 		// Store workspace ID in context
 		c.Set("workspace_id", workspaceID)
 		c.Next(ctx)
@@ -175,8 +254,7 @@ func (m *WorkspaceMiddleware) OptionalWorkspaceAccess() app.HandlerFunc {
 		// Check if user is authenticated
 		userID, authenticated := c.Get("user_id")
 
-		// If workspace is private and user is not authenticated, deny access
-		if !workspace.IsPublic && !authenticated {
+		if !authenticated && !anonymousAllowed(workspace) {
 			c.JSON(http.StatusUnauthorized, map[string]interface{}{
 				"error": "Authentication required",
 			})
@@ -185,9 +263,9 @@ func (m *WorkspaceMiddleware) OptionalWorkspaceAccess() app.HandlerFunc {
 		}
 
 		// If workspace is private and user is authenticated, check membership
+		uid, _ := userID.(uuid.UUID)
 		if !workspace.IsPublic && authenticated {
-			uid, ok := userID.(uuid.UUID)
-			if !ok {
+			if uid == uuid.Nil {
 				c.JSON(http.StatusUnauthorized, map[string]interface{}{
 					"error": "Invalid user ID",
 				})
@@ -195,16 +273,59 @@ func (m *WorkspaceMiddleware) OptionalWorkspaceAccess() app.HandlerFunc {
 				return
 			}
 			if err := m.workspaceService.CheckPermission(ctx, workspaceID, uid, models.WorkspaceRoleViewer); err != nil {
-				c.JSON(http.StatusForbidden, map[string]interface{}{
-					"error": "Access denied",
-				})
-				c.Abort()
+				respondAccessDenied(c, err)
 				return
 			}
 		}
 
+		// Public workspaces are restricted too, for anonymous visitors alike
+		if !m.checkIPAllowlist(ctx, c, workspaceID, uid) {
+			return
+		}
+
 		// Store workspace ID in context
 		c.Set("workspace_id", workspaceID)
 		c.Next(ctx)
 	}
 }
+
+// anonymousAllowed reports whether visitors without a token may read a
+// workspace. Private workspaces need a sign in, and public ones too unless
+// their owner allows anonymous viewing.
+func anonymousAllowed(workspace *models.Workspace) bool {
+	return workspace.IsPublic && workspace.PublicAccess.AllowAnonymous
+}
+
+// RequireUnfrozen rejects writes to a frozen board with 423 Locked. It runs
+// after the access middleware, which sets the workspace ID.
+func (m *WorkspaceMiddleware) RequireUnfrozen() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		workspaceID, ok := c.Get("workspace_id")
+		if !ok {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Workspace ID is required",
+			})
+			c.Abort()
+			return
+		}
+
+		err := m.workspaceService.CheckNotFrozen(ctx, workspaceID.(uuid.UUID))
+		if errors.Is(err, service.ErrBoardFrozen) {
+			c.JSON(http.StatusLocked, map[string]interface{}{
+				"error": err.Error(),
+				"code":  "board_frozen",
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to check workspace state",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/google/uuid"
+)
+
+func TestAnonymousReadOfPublicWorkspace(t *testing.T) {
+	workspace := &models.Workspace{
+		IsPublic:     true,
+		PublicAccess: models.PublicAccess{AllowAnonymous: true},
+	}
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	public := engine.Group("/api/v1/workspaces")
+	public.Use(OptionalWorkspaceAuth(nil, nil))
+	public.GET("/:workspace_id", func(ctx context.Context, c *app.RequestContext) {
+		if _, authenticated := c.Get("user_id"); authenticated || !anonymousAllowed(workspace) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	resp := ut.PerformRequest(engine, http.MethodGet, "/api/v1/workspaces/"+uuid.NewString(), nil).Result()
+	if resp.StatusCode() != http.StatusOK {
+		t.Errorf("GET without token: got status %d, want %d", resp.StatusCode(), http.StatusOK)
+	}
+}
+
+func TestWorkspaceAuthRequiresToken(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.GET("/api/v1/workspaces/:workspace_id", WorkspaceAuth(nil, nil), func(ctx context.Context, c *app.RequestContext) {
+		c.Status(http.StatusOK)
+	})
+
+	resp := ut.PerformRequest(engine, http.MethodGet, "/api/v1/workspaces/"+uuid.NewString(), nil).Result()
+	if resp.StatusCode() != http.StatusUnauthorized {
+		t.Errorf("GET without token: got status %d, want %d", resp.StatusCode(), http.StatusUnauthorized)
+	}
+}
+
+func TestAnonymousAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace models.Workspace
+		want      bool
+	}{
+		{
+			name:      "public, anonymous allowed",
+			workspace: models.Workspace{IsPublic: true, PublicAccess: models.PublicAccess{AllowAnonymous: true}},
+			want:      true,
+		},
+		{
+			name:      "public, sign in required",
+			workspace: models.Workspace{IsPublic: true},
+		},
+		{
+			name:      "private",
+			workspace: models.Workspace{PublicAccess: models.PublicAccess{AllowAnonymous: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := anonymousAllowed(&tt.workspace); got != tt.want {
+				t.Errorf("anonymousAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminWorkspaceListFilter filters the workspace list of the admin API
+type AdminWorkspaceListFilter struct {
+	Query  string `form:"q"`       // matches workspace name or owner email
+	SortBy string `form:"sort_by"` // created_at, updated_at or storage_used_bytes
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+// AdminWorkspace is a workspace of any user with its owner and size, as
+// listed by the admin API
+type AdminWorkspace struct {
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+	Owner            UserResponse `json:"owner"`
+	Name             string       `json:"name"`
+	ID               uuid.UUID    `json:"id"`
+	MemberCount      int          `json:"member_count"`
+	ElementCount     int          `json:"element_count"`
+	AssetCount       int          `json:"asset_count"`
+	StorageUsedBytes int64        `json:"storage_used_bytes"`
+	IsPublic         bool         `json:"is_public"`
+}
+
+// MaintenanceNoticeRequest is a notice broadcast to every connected client
+type MaintenanceNoticeRequest struct {
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	Message  string     `json:"message"`
+}
+
+// MaintenancePayload is the payload of maintenance messages
+type MaintenancePayload struct {
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	Message  string     `json:"message"`
+}
+
+// InstanceRoomStats are the rooms of one realtime server instance, as last
+// reported by the instance
+type InstanceRoomStats struct {
+	ReportedAt time.Time         `json:"reported_at"`
+	Rooms      map[uuid.UUID]int `json:"rooms"` // workspace_id -> connected clients
+	InstanceID uuid.UUID         `json:"instance_id"`
+	Clients    int               `json:"clients"`
+}
+
+// RealtimeRoomsResponse sums up the rooms of all realtime instances
+type RealtimeRoomsResponse struct {
+	Instances []InstanceRoomStats `json:"instances"`
+	Rooms     map[uuid.UUID]int   `json:"rooms"` // workspace_id -> clients on all instances
+	Clients   int                 `json:"clients"`
+}
+
+// AdminJob is a background job admins can run on demand
+type AdminJob struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
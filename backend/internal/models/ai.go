@@ -0,0 +1,59 @@
+package models
+
+import "github.com/google/uuid"
+
+// AIGeneratedKey is set to true in the element_data of elements created by
+// AI assistance, so clients can tell them apart
+const AIGeneratedKey = "ai_generated"
+
+// AISummarizeRequest asks for a summary of a board
+type AISummarizeRequest struct {
+	// ElementIDs is the selection to summarize, the whole board when empty
+	ElementIDs []uuid.UUID `json:"element_ids,omitempty"`
+	// Create adds the summary to the board as a text element
+	Create bool `json:"create"`
+}
+
+// AISummarizeResponse is the summary of a board
+type AISummarizeResponse struct {
+	Summary  string            `json:"summary"`
+	Elements []ElementResponse `json:"elements,omitempty"`
+}
+
+// AIClusterRequest asks to group the sticky notes of a board by theme
+type AIClusterRequest struct {
+	// ElementIDs is the selection to cluster, every sticky note when empty
+	ElementIDs []uuid.UUID `json:"element_ids,omitempty"`
+	// Create adds a text element listing the notes of each cluster to the board
+	Create bool `json:"create"`
+}
+
+// AICluster is a theme and the sticky notes that belong to it
+type AICluster struct {
+	Label      string      `json:"label"`
+	ElementIDs []uuid.UUID `json:"element_ids"`
+}
+
+// AIClusterResponse is the clusters of the sticky notes of a board
+type AIClusterResponse struct {
+	Clusters []AICluster       `json:"clusters"`
+	Elements []ElementResponse `json:"elements,omitempty"`
+}
+
+// AIIdeasRequest asks for new ideas about a topic or the content of a board
+type AIIdeasRequest struct {
+	// Prompt is the topic, the board is used when empty
+	Prompt string `json:"prompt"`
+	// ElementIDs is the selection given as context, the whole board when empty
+	ElementIDs []uuid.UUID `json:"element_ids,omitempty"`
+	// Count is the number of ideas, 5 when zero
+	Count int `json:"count"`
+	// Create adds each idea to the board as a sticky note
+	Create bool `json:"create"`
+}
+
+// AIIdeasResponse is the generated ideas
+type AIIdeasResponse struct {
+	Ideas    []string          `json:"ideas"`
+	Elements []ElementResponse `json:"elements,omitempty"`
+}
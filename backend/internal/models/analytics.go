@@ -0,0 +1,162 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClickHouse tables of analytics events
+const (
+	AnalyticsTableOperations  = "element_operations"
+	AnalyticsTableSessions    = "sessions"
+	AnalyticsTablePresence    = "presence"
+	AnalyticsTableAPIRequests = "api_requests"
+	AnalyticsTableViews       = "workspace_views"
+	AnalyticsTableCursors     = "cursor_samples"
+)
+
+// Kinds of cursor samples
+const (
+	CursorSampleMove      = "cursor"
+	CursorSampleSelection = "selection"
+)
+
+// Realtime transports of sessions
+const (
+	SessionTransportWebSocket = "websocket"
+	SessionTransportSSE       = "sse"
+)
+
+// AnalyticsEvent is a row on its way through NATS to its analytics table
+type AnalyticsEvent struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// OperationAnalytics is an element operation applied to a board
+type OperationAnalytics struct {
+	Time        time.Time `json:"time"`
+	OpType      string    `json:"op_type"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	ElementID   uuid.UUID `json:"element_id"`
+}
+
+// SessionAnalytics is a realtime connection from open to close
+type SessionAnalytics struct {
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+	Transport  string    `json:"transport"`
+	DurationMs int64     `json:"duration_ms"`
+	UserID     uuid.UUID `json:"user_id"`
+	Anonymous  bool      `json:"anonymous"`
+}
+
+// PresenceAnalytics is the time a client spent in the room of a board
+type PresenceAnalytics struct {
+	JoinedAt    time.Time `json:"joined_at"`
+	LeftAt      time.Time `json:"left_at"`
+	DurationMs  int64     `json:"duration_ms"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Anonymous   bool      `json:"anonymous"`
+}
+
+// APIRequestAnalytics is a request to the REST API
+type APIRequestAnalytics struct {
+	Time       time.Time  `json:"time"`
+	UserID     *uuid.UUID `json:"user_id"`
+	Method     string     `json:"method"`
+	Route      string     `json:"route"`
+	DurationMs float64    `json:"duration_ms"`
+	Status     int        `json:"status"`
+}
+
+// ViewAnalytics is a counted view of a workspace. Viewer identifies the
+// viewer like the view counts do, without the user ID of anonymous visitors.
+type ViewAnalytics struct {
+	Time         time.Time  `json:"time"`
+	UserID       *uuid.UUID `json:"user_id"`
+	Viewer       string     `json:"viewer"`
+	ReferrerHost string     `json:"referrer_host"`
+	WorkspaceID  uuid.UUID  `json:"workspace_id"`
+	Anonymous    bool       `json:"anonymous"`
+	Member       bool       `json:"member"`
+	FirstView    bool       `json:"first_view"`
+}
+
+// CursorAnalytics is a sampled cursor position on a board, or where the
+// cursor was when a user selected elements
+type CursorAnalytics struct {
+	Time        time.Time `json:"time"`
+	Kind        string    `json:"kind"`
+	X           float64   `json:"x"`
+	Y           float64   `json:"y"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Anonymous   bool      `json:"anonymous"`
+}
+
+// WorkspaceAnalytics is the engagement of a workspace per UTC day of a range
+type WorkspaceAnalytics struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// DailyActive counts the members that edited or opened the board
+	DailyActive []DailyCount `json:"daily_active_collaborators"`
+	Edits       []DailyCount `json:"edits"`
+	// BusiestHours counts the edits of the range by UTC hour of the day
+	BusiestHours []HourlyCount `json:"busiest_hours"`
+	// ElementGrowth is the number of elements on the board at the end of each day
+	ElementGrowth []DailyCount `json:"element_growth"`
+	// Views counts the views of members and visitors, UniqueViewers the
+	// viewers of each day
+	Views         []DailyCount `json:"views"`
+	UniqueViewers []DailyCount `json:"unique_viewers"`
+}
+
+// DailyCount is a value of a day, formatted YYYY-MM-DD
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// HourlyCount is a value of an hour of the day, 0 to 23
+type HourlyCount struct {
+	Hour  int   `json:"hour"`
+	Count int64 `json:"count"`
+}
+
+// CursorBounds is the area of a board cursor samples fell in
+type CursorBounds struct {
+	MinX    float64 `json:"min_x"`
+	MinY    float64 `json:"min_y"`
+	MaxX    float64 `json:"max_x"`
+	MaxY    float64 `json:"max_y"`
+	Samples int64   `json:"samples"`
+}
+
+// HeatmapCell is the number of cursor samples in a cell of a heatmap
+type HeatmapCell struct {
+	Column int   `json:"column"`
+	Row    int   `json:"row"`
+	Count  int64 `json:"count"`
+}
+
+// Heatmap is the density of cursor samples over the area of a board they
+// fell in. Cells holds Rows rows of Columns counts, the first cell starts
+// at OriginX, OriginY in board coordinates.
+type Heatmap struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Kind     string    `json:"kind,omitempty"`
+	OriginX  float64   `json:"origin_x"`
+	OriginY  float64   `json:"origin_y"`
+	CellSize float64   `json:"cell_size"`
+	Columns  int       `json:"columns"`
+	Rows     int       `json:"rows"`
+	Samples  int64     `json:"samples"`
+	MaxCount int64     `json:"max_count"`
+	Cells    [][]int64 `json:"cells"`
+}
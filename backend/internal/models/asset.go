@@ -1,25 +1,113 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Asset processing statuses
+const (
+	AssetStatusProcessing = "processing"
+	AssetStatusReady      = "ready"
+	AssetStatusFailed     = "failed"
+)
+
+// Antivirus scan statuses
+const (
+	ScanStatusPending  = "pending"
+	ScanStatusClean    = "clean"
+	ScanStatusInfected = "infected"
+	ScanStatusSkipped  = "skipped"
+)
+
+// Optimized image formats
+const (
+	ImageFormatWebP = "webp"
+	ImageFormatAVIF = "avif"
+)
+
+// AssetVariant is an optimized rendition of an image asset
+type AssetVariant struct {
+	URL    string `json:"url"`
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// AssetVariants maps a variant key ("webp_640") to its rendition
+type AssetVariants map[string]AssetVariant
+
+// VariantKey builds the key a variant is stored under
+func VariantKey(format string, width int) string {
+	return fmt.Sprintf("%s_%d", format, width)
+}
+
+// Scan implements the sql.Scanner interface for JSONB
+func (v *AssetVariants) Scan(value interface{}) error {
+	if value == nil {
+		*v = make(AssetVariants)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("failed to scan AssetVariants: unexpected type %T", value)
+		}
+		return json.Unmarshal([]byte(str), v)
+	}
+	return json.Unmarshal(bytes, v)
+}
+
+// Value implements the driver.Valuer interface for JSONB
+func (v AssetVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	return json.Marshal(v)
+}
+
+// AssetAttribution credits the author of media imported from a stock provider
+type AssetAttribution struct {
+	Provider   string `json:"provider"`
+	SourceID   string `json:"source_id"`
+	SourceURL  string `json:"source_url"`
+	AuthorName string `json:"author_name,omitempty"`
+	AuthorURL  string `json:"author_url,omitempty"`
+}
+
 // Asset represents a file asset (image, document, etc.)
 type Asset struct {
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	ThumbnailURL *string    `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
-	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
-	Width        *int       `json:"width,omitempty" db:"width"`
-	Height       *int       `json:"height,omitempty" db:"height"`
-	Filename     string     `json:"filename" db:"filename"`
-	ContentType  string     `json:"content_type" db:"content_type"`
-	URL          string     `json:"url" db:"url"`
-	Size         int64      `json:"size" db:"size"`
-	ID           uuid.UUID  `json:"id" db:"id"`
-	WorkspaceID  uuid.UUID  `json:"workspace_id" db:"workspace_id"`
-	UploadedBy   uuid.UUID  `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	ThumbnailURL  *string           `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
+	DeletedAt     *time.Time        `json:"deleted_at,omitempty" db:"deleted_at"`
+	Width         *int              `json:"width,omitempty" db:"width"`
+	Height        *int              `json:"height,omitempty" db:"height"`
+	ParentAssetID *uuid.UUID        `json:"parent_asset_id,omitempty" db:"parent_asset_id"`
+	PageNumber    *int              `json:"page_number,omitempty" db:"page_number"`
+	PageCount     *int              `json:"page_count,omitempty" db:"page_count"`
+	DurationMs    *int              `json:"duration_ms,omitempty" db:"duration_ms"`
+	Pages         []Asset           `json:"pages,omitempty" db:"-"`   // Rendered pages of a document
+	UsedBy        []uuid.UUID       `json:"used_by,omitempty" db:"-"` // Elements referencing the asset
+	UsageCount    int               `json:"usage_count" db:"-"`
+	Variants      AssetVariants     `json:"variants,omitempty" db:"variants"`
+	Attribution   *AssetAttribution `json:"attribution,omitempty" db:"attribution"`
+	OCRText       *string           `json:"ocr_text,omitempty" db:"ocr_text"` // Text recognized in an image
+	Filename      string            `json:"filename" db:"filename"`
+	ContentType   string            `json:"content_type" db:"content_type"`
+	URL           string            `json:"url" db:"url"`
+	Status        string            `json:"status" db:"status"`
+	ScanStatus    string            `json:"scan_status" db:"scan_status"`
+	Size          int64             `json:"size" db:"size"`
+	ID            uuid.UUID         `json:"id" db:"id"`
+	WorkspaceID   uuid.UUID         `json:"workspace_id" db:"workspace_id"`
+	UploadedBy    uuid.UUID         `json:"uploaded_by" db:"uploaded_by"`
 }
 
 // UploadAssetRequest represents a file upload request
@@ -29,23 +117,80 @@ type UploadAssetRequest struct {
 	Size        int64  `json:"size"`
 }
 
+// PresignedUploadResponse describes where the client should upload a file directly
+type PresignedUploadResponse struct {
+	ExpiresAt time.Time         `json:"expires_at"`
+	Headers   map[string]string `json:"headers"`
+	UploadURL string            `json:"upload_url"`
+	ObjectKey string            `json:"object_key"`
+	Method    string            `json:"method"`
+}
+
+// ConfirmUploadRequest confirms a file uploaded through a presigned URL
+type ConfirmUploadRequest struct {
+	ObjectKey string `json:"object_key"`
+	Filename  string `json:"filename"`
+}
+
+// ImportAssetRequest is the request body for importing an image from a URL
+type ImportAssetRequest struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+}
+
+// AssetListFilter represents filters for listing workspace assets
+type AssetListFilter struct {
+	Query       string `form:"q"`            // filename and recognized text search
+	ContentType string `form:"content_type"` // exact type or a family such as "image/*"
+	UploadedBy  string `form:"uploaded_by"`
+	SortBy      string `form:"sort_by"` // created_at, size or filename
+	SortOrder   string `form:"sort_order"`
+	Limit       int    `form:"limit"`
+	Offset      int    `form:"offset"`
+}
+
+// AssetPage is a page of the assets of one workspace
+type AssetPage struct {
+	Assets []Asset
+	Total  int
+}
+
+// AssetListResponse represents a paginated list of assets
+type AssetListResponse struct {
+	Assets []AssetResponse `json:"assets"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
 // AssetResponse represents an asset in API responses
 type AssetResponse struct {
-	CreatedAt    time.Time `json:"created_at"`
-	ThumbnailURL *string   `json:"thumbnail_url,omitempty"`
-	Width        *int      `json:"width,omitempty"`
-	Height       *int      `json:"height,omitempty"`
-	Filename     string    `json:"filename"`
-	ContentType  string    `json:"content_type"`
-	URL          string    `json:"url"`
-	Size         int64     `json:"size"`
-	ID           uuid.UUID `json:"id"`
-	WorkspaceID  uuid.UUID `json:"workspace_id"`
+	CreatedAt    time.Time         `json:"created_at"`
+	ThumbnailURL *string           `json:"thumbnail_url,omitempty"`
+	Width        *int              `json:"width,omitempty"`
+	Height       *int              `json:"height,omitempty"`
+	PageNumber   *int              `json:"page_number,omitempty"`
+	PageCount    *int              `json:"page_count,omitempty"`
+	DurationMs   *int              `json:"duration_ms,omitempty"`
+	Pages        []AssetResponse   `json:"pages,omitempty"`
+	UsedBy       []uuid.UUID       `json:"used_by,omitempty"`
+	UsageCount   int               `json:"usage_count"`
+	Variants     AssetVariants     `json:"variants,omitempty"`
+	Attribution  *AssetAttribution `json:"attribution,omitempty"`
+	OCRText      *string           `json:"ocr_text,omitempty"`
+	Filename     string            `json:"filename"`
+	ContentType  string            `json:"content_type"`
+	URL          string            `json:"url"`
+	Status       string            `json:"status"`
+	ScanStatus   string            `json:"scan_status"`
+	Size         int64             `json:"size"`
+	ID           uuid.UUID         `json:"id"`
+	WorkspaceID  uuid.UUID         `json:"workspace_id"`
 }
 
 // ToResponse converts Asset to AssetResponse
 func (a *Asset) ToResponse() AssetResponse {
-	return AssetResponse{
+	response := AssetResponse{
 		ID:           a.ID,
 		WorkspaceID:  a.WorkspaceID,
 		Filename:     a.Filename,
@@ -55,6 +200,61 @@ func (a *Asset) ToResponse() AssetResponse {
 		ThumbnailURL: a.ThumbnailURL,
 		Width:        a.Width,
 		Height:       a.Height,
+		PageNumber:   a.PageNumber,
+		PageCount:    a.PageCount,
+		DurationMs:   a.DurationMs,
+		Variants:     a.Variants,
+		Attribution:  a.Attribution,
+		OCRText:      a.OCRText,
+		UsedBy:       a.UsedBy,
+		UsageCount:   a.UsageCount,
+		Status:       a.Status,
+		ScanStatus:   a.ScanStatus,
 		CreatedAt:    a.CreatedAt,
 	}
+
+	if len(a.Pages) > 0 {
+		response.Pages = make([]AssetResponse, len(a.Pages))
+		for i := range a.Pages {
+			response.Pages[i] = a.Pages[i].ToResponse()
+		}
+	}
+
+	return response
+}
+
+// MediaTranscodeJob is queued to extract metadata and a poster frame from an uploaded video
+type MediaTranscodeJob struct {
+	ObjectKey   string    `json:"object_key"`
+	AssetID     uuid.UUID `json:"asset_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
+
+// AssetScanJob is queued to scan an uploaded file for malware
+type AssetScanJob struct {
+	ObjectKey   string    `json:"object_key"`
+	AssetID     uuid.UUID `json:"asset_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
+
+// ImageOptimizeJob is queued to generate optimized variants of an uploaded image
+type ImageOptimizeJob struct {
+	ObjectKey   string    `json:"object_key"`
+	AssetID     uuid.UUID `json:"asset_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
+
+// AssetOCRJob is queued to recognize the text in an uploaded image
+type AssetOCRJob struct {
+	ObjectKey   string    `json:"object_key"`
+	AssetID     uuid.UUID `json:"asset_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
+
+// PDFRenderJob is queued to render the pages of an uploaded PDF
+type PDFRenderJob struct {
+	ObjectKey   string    `json:"object_key"`
+	AssetID     uuid.UUID `json:"asset_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	UploadedBy  uuid.UUID `json:"uploaded_by"`
 }
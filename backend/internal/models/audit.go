@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditActorType is who performed an audited action
+type AuditActorType string
+
+const (
+	AuditActorUser   AuditActorType = "user"
+	AuditActorAPIKey AuditActorType = "api_key"
+	AuditActorBot    AuditActorType = "bot"
+	AuditActorSCIM   AuditActorType = "scim"
+	AuditActorSystem AuditActorType = "system"
+)
+
+// Audited actions
+const (
+	AuditUserRegistered      = "user.registered"
+	AuditUserLogin           = "user.login"
+	AuditUserLoginFailed     = "user.login_failed"
+	AuditUserPasswordReset   = "user.password_reset"
+	AuditUserPasswordChanged = "user.password_changed"
+
+	AuditWorkspaceDeleted       = "workspace.deleted"
+	AuditMemberRoleChanged      = "workspace.member_role_changed"
+	AuditMemberRemoved          = "workspace.member_removed"
+	AuditInviteCreated          = "workspace.invite_created"
+	AuditInviteAccepted         = "workspace.invite_accepted"
+	AuditInviteRevoked          = "workspace.invite_revoked"
+	AuditInviteDeclined         = "workspace.invite_declined"
+	AuditInviteLinkCreated      = "workspace.invite_link_created"
+	AuditInviteLinkRevoked      = "workspace.invite_link_revoked"
+	AuditInviteLinkRedeemed     = "workspace.invite_link_redeemed"
+	AuditIPAllowlistUpdated     = "workspace.ip_allowlist_updated"
+	AuditWorkspaceAccessBlocked = "workspace.access_blocked"
+	AuditAccessRequestApproved  = "workspace.access_request_approved"
+	AuditAccessRequestDenied    = "workspace.access_request_denied"
+	AuditBoardFrozen            = "workspace.board_frozen"
+	AuditBoardUnfrozen          = "workspace.board_unfrozen"
+	AuditAPIKeyCreated          = "api_key.created"
+	AuditAPIKeyDeleted          = "api_key.deleted"
+	AuditBotCreated             = "bot.created"
+	AuditBotDeleted             = "bot.deleted"
+	AuditBotTokenCreated        = "bot.token_created"
+	AuditBotTokenDeleted        = "bot.token_deleted"
+	AuditSCIMUserCreated        = "scim.user_created"
+	AuditSCIMUserUpdated        = "scim.user_updated"
+	AuditSCIMUserDeleted        = "scim.user_deleted"
+	AuditSCIMGroupChanged       = "scim.group_changed"
+	AuditSCIMGroupDeleted       = "scim.group_deleted"
+	AuditAdminContentDeleted    = "admin.content_deleted"
+	AuditAdminMaintenance       = "admin.maintenance_broadcast"
+	AuditAdminJobRun            = "admin.job_run"
+	AuditAdminConfigReloaded    = "admin.config_reloaded"
+)
+
+// AuditEvent is one entry of the audit log
+type AuditEvent struct {
+	CreatedAt   time.Time              `json:"created_at"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	ActorID     *uuid.UUID             `json:"actor_id,omitempty"`
+	WorkspaceID *uuid.UUID             `json:"workspace_id,omitempty"`
+	TargetType  string                 `json:"target_type,omitempty"`
+	TargetID    string                 `json:"target_id,omitempty"`
+	IPAddress   string                 `json:"ip_address,omitempty"`
+	UserAgent   string                 `json:"user_agent,omitempty"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	Action      string                 `json:"action"`
+	ActorType   AuditActorType         `json:"actor_type"`
+	Seq         int64                  `json:"seq"`
+	ID          uuid.UUID              `json:"id"`
+}
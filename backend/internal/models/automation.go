@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Polling triggers of automation platforms such as Zapier and Make
+const (
+	TriggerNewElement = "new_element"
+	TriggerNewComment = "new_comment"
+	TriggerNewMember  = "new_member"
+)
+
+// TriggerEvents maps every trigger with REST hooks to the event they fire
+// on. Comments publish no events, new_comment is polled only.
+var TriggerEvents = map[string]string{
+	TriggerNewElement: EventElementCreated,
+	TriggerNewMember:  EventMemberJoined,
+}
+
+// APIKey authenticates automation platforms. It acts as the user that
+// created it, within one workspace.
+type APIKey struct {
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	Name        string     `json:"name" db:"name"`
+	Prefix      string     `json:"prefix" db:"prefix"` // first characters of the key
+	KeyHash     string     `json:"-" db:"key_hash"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+}
+
+// CreateAPIKeyRequest creates an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// APIKeyWithSecret is returned when an API key is created, the only time
+// the key is shown
+type APIKeyWithSecret struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// AutomationAccount describes the workspace and user an API key acts as.
+// Automation platforms use it to test the key and label the connection.
+type AutomationAccount struct {
+	WorkspaceName string    `json:"workspace_name"`
+	UserName      string    `json:"user_name"`
+	UserEmail     string    `json:"user_email"`
+	WorkspaceID   uuid.UUID `json:"workspace_id"`
+	UserID        uuid.UUID `json:"user_id"`
+}
+
+// ElementTriggerItem is a new element as polling triggers and REST hooks
+// deliver it. Zapier deduplicates items by id.
+type ElementTriggerItem struct {
+	CreatedAt   time.Time   `json:"created_at"`
+	ElementData ElementData `json:"element_data"`
+	ElementType ElementType `json:"element_type"`
+	Text        string      `json:"text"` // plain text of text elements and notes
+	URL         string      `json:"url"`  // opens the board at the element
+	ID          uuid.UUID   `json:"id"`
+	WorkspaceID uuid.UUID   `json:"workspace_id"`
+	CreatedBy   uuid.UUID   `json:"created_by"`
+}
+
+// CommentTriggerItem is a new comment, a thread or a reply, as polling
+// triggers deliver it
+type CommentTriggerItem struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	ElementID   *uuid.UUID `json:"element_id,omitempty"` // element of the thread, none for the board
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`  // thread of replies
+	AuthorID    *uuid.UUID `json:"author_id,omitempty"`
+	AuthorName  *string    `json:"author_name,omitempty"`
+	Body        string     `json:"body"`
+	URL         string     `json:"url"` // opens the board at the element of the thread
+	ID          uuid.UUID  `json:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id"`
+}
+
+// MemberTriggerItem is a new workspace member as polling triggers and REST
+// hooks deliver it
+type MemberTriggerItem struct {
+	JoinedAt    time.Time     `json:"joined_at"`
+	AvatarURL   *string       `json:"avatar_url,omitempty"`
+	Name        string        `json:"name"`
+	Email       string        `json:"email"`
+	Username    string        `json:"username"`
+	Role        WorkspaceRole `json:"role"`
+	ID          uuid.UUID     `json:"id"` // membership ID
+	WorkspaceID uuid.UUID     `json:"workspace_id"`
+	UserID      uuid.UUID     `json:"user_id"`
+}
+
+// TriggerSubscriptionRequest subscribes a REST hook URL to a trigger, the
+// way Zapier and Make register instant triggers
+type TriggerSubscriptionRequest struct {
+	TargetURL string `json:"target_url"`
+	Event     string `json:"event"` // trigger key such as new_element
+}
+
+// TriggerSubscription is a REST hook subscription. The ID is used to
+// unsubscribe.
+type TriggerSubscription struct {
+	CreatedAt time.Time `json:"created_at"`
+	TargetURL string    `json:"target_url"`
+	Event     string    `json:"event"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// TriggerCursor is the position of the last item of a trigger page. Items
+// are listed newest first, the next page starts below the cursor.
+type TriggerCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Plan is a billing plan
+type Plan string
+
+const (
+	PlanFree Plan = "free"
+	PlanPro  Plan = "pro"
+	PlanTeam Plan = "team"
+)
+
+// Valid reports whether the plan is known
+func (p Plan) Valid() bool {
+	return p == PlanFree || p == PlanPro || p == PlanTeam
+}
+
+// Entitlements are the limits of a plan. Zero limits are unlimited.
+type Entitlements struct {
+	Plan              Plan  `json:"plan"`
+	StorageQuotaBytes int64 `json:"storage_quota_bytes"`
+	MaxMembers        int   `json:"max_members"`
+	MaxSnapshots      int   `json:"max_snapshots"`
+	IncludedEditors   int   `json:"included_editors"`
+	IncludedExports   int   `json:"included_exports"`
+}
+
+// Subscription is the Stripe subscription of a user
+type Subscription struct {
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	CurrentPeriodEnd     *time.Time `json:"current_period_end,omitempty"`
+	StripeSubscriptionID *string    `json:"-"`
+	Plan                 Plan       `json:"plan"`
+	Status               string     `json:"status"`
+	StripeCustomerID     string     `json:"-"`
+	UserID               uuid.UUID  `json:"user_id"`
+	CancelAtPeriodEnd    bool       `json:"cancel_at_period_end"`
+}
+
+// BillingResponse is the plan of a user with its entitlements
+type BillingResponse struct {
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Entitlements Entitlements  `json:"entitlements"`
+}
+
+// CheckoutRequest starts the purchase of a paid plan
+type CheckoutRequest struct {
+	Plan Plan `json:"plan"`
+}
+
+// BillingSessionResponse is a Stripe Checkout or billing portal page the
+// user is redirected to
+type BillingSessionResponse struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes of bot tokens. A token only reaches the routes of its scopes.
+const (
+	BotScopeBoardRead      = "board:read"      // workspace details and presence
+	BotScopeElementsRead   = "elements:read"   // list and get elements
+	BotScopeElementsCreate = "elements:create" // create elements, limited to the token's element types
+	BotScopeElementsUpdate = "elements:update"
+	BotScopeElementsDelete = "elements:delete"
+)
+
+// BotScopes lists every scope a bot token may be given
+var BotScopes = []string{
+	BotScopeBoardRead,
+	BotScopeElementsRead,
+	BotScopeElementsCreate,
+	BotScopeElementsUpdate,
+	BotScopeElementsDelete,
+}
+
+// Bot is an automation account of a workspace, such as a CI pipeline or an
+// alerting integration. It is a user that can't sign in and is a member of
+// the workspace it was created in only.
+type Bot struct {
+	CreatedAt   time.Time     `json:"created_at"`
+	AvatarURL   *string       `json:"avatar_url,omitempty"`
+	CreatedBy   *uuid.UUID    `json:"created_by,omitempty"`
+	Name        string        `json:"name"`
+	Username    string        `json:"username"`
+	Role        WorkspaceRole `json:"role"`
+	ID          uuid.UUID     `json:"id"` // user ID of the bot
+	WorkspaceID uuid.UUID     `json:"workspace_id"`
+}
+
+// BotToken authenticates a bot. It may only perform the operations of its
+// scopes, in the workspace of the bot.
+type BotToken struct {
+	CreatedAt    time.Time     `json:"created_at"`
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time    `json:"last_used_at,omitempty"`
+	CreatedBy    *uuid.UUID    `json:"created_by,omitempty"`
+	Name         string        `json:"name"`
+	Prefix       string        `json:"prefix"` // first characters of the token
+	TokenHash    string        `json:"-"`
+	Scopes       []string      `json:"scopes"`
+	ElementTypes []ElementType `json:"element_types"` // types the token may create, empty for any
+	ID           uuid.UUID     `json:"id"`
+	BotID        uuid.UUID     `json:"bot_id"`
+	WorkspaceID  uuid.UUID     `json:"workspace_id"`
+}
+
+// HasScope reports whether the token was given a scope
+func (t *BotToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanCreate reports whether the token may create elements of a type
+func (t *BotToken) CanCreate(elementType ElementType) bool {
+	if !t.HasScope(BotScopeElementsCreate) {
+		return false
+	}
+	if len(t.ElementTypes) == 0 {
+		return true
+	}
+	for _, allowed := range t.ElementTypes {
+		if allowed == elementType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateBotRequest creates a bot in a workspace
+type CreateBotRequest struct {
+	AvatarURL *string       `json:"avatar_url,omitempty"`
+	Name      string        `json:"name"`
+	Role      WorkspaceRole `json:"role,omitempty"` // editor or viewer, editor when empty
+}
+
+// CreateBotTokenRequest creates a token of a bot
+type CreateBotTokenRequest struct {
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`
+	Name         string        `json:"name"`
+	Scopes       []string      `json:"scopes"`
+	ElementTypes []ElementType `json:"element_types,omitempty"`
+}
+
+// BotTokenWithSecret is returned when a bot token is created, the only time
+// the token is shown
+type BotTokenWithSecret struct {
+	BotToken
+	Token string `json:"token"`
+}
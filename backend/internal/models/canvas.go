@@ -16,18 +16,22 @@ const (
 	ElementTypeText      ElementType = "text"
 	ElementTypeShape     ElementType = "shape"
 	ElementTypeImage     ElementType = "image"
+	ElementTypeVideo     ElementType = "video"
 	ElementTypeDrawing   ElementType = "drawing"
 	ElementTypeSticky    ElementType = "sticky"
 	ElementTypeList      ElementType = "list"
 	ElementTypeConnector ElementType = "connector"
 	ElementTypeGroup     ElementType = "group"
+	// ElementTypeFrame marks an area of the board, presentations show frames
+	// as slides
+	ElementTypeFrame ElementType = "frame"
 )
 
 // Valid returns true if the element type is valid
 func (t ElementType) Valid() bool {
 	switch t {
-	case ElementTypeText, ElementTypeShape, ElementTypeImage, ElementTypeDrawing,
-		ElementTypeSticky, ElementTypeList, ElementTypeConnector, ElementTypeGroup:
+	case ElementTypeText, ElementTypeShape, ElementTypeImage, ElementTypeVideo, ElementTypeDrawing,
+		ElementTypeSticky, ElementTypeList, ElementTypeConnector, ElementTypeGroup, ElementTypeFrame:
 		return true
 	}
 	return false
@@ -62,6 +66,33 @@ func (e ElementData) Value() (driver.Value, error) {
 	return json.Marshal(e)
 }
 
+// AssetID returns the asset displayed by an image or video element
+func (e ElementData) AssetID() (uuid.UUID, bool) {
+	raw, ok := e["asset_id"].(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Bounds returns the position and size of an element, zero for the values
+// it doesn't have
+func (e ElementData) Bounds() (Position, Size) {
+	position, _ := e["position"].(map[string]interface{})
+	size, _ := e["size"].(map[string]interface{})
+
+	number := func(m map[string]interface{}, key string) float64 {
+		v, _ := m[key].(float64)
+		return v
+	}
+	return Position{X: number(position, "x"), Y: number(position, "y")},
+		Size{Width: number(size, "width"), Height: number(size, "height")}
+}
+
 // CanvasElement represents a canvas element in the database
 type CanvasElement struct {
 	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
@@ -225,11 +256,13 @@ type ElementResponse struct {
 	ParentID    *uuid.UUID  `json:"parent_id,omitempty"`
 	UpdatedBy   *uuid.UUID  `json:"updated_by,omitempty"`
 	ElementData ElementData `json:"element_data"`
-	ElementType ElementType `json:"element_type"`
-	ZIndex      int         `json:"z_index"`
-	ID          uuid.UUID   `json:"id"`
-	WorkspaceID uuid.UUID   `json:"workspace_id"`
-	CreatedBy   uuid.UUID   `json:"created_by"`
+	// Comments counts the comment threads of the element, in reads only
+	Comments    *CommentCounts `json:"comments,omitempty"`
+	ElementType ElementType    `json:"element_type"`
+	ZIndex      int            `json:"z_index"`
+	ID          uuid.UUID      `json:"id"`
+	WorkspaceID uuid.UUID      `json:"workspace_id"`
+	CreatedBy   uuid.UUID      `json:"created_by"`
 }
 
 // ElementListResponse represents a list of canvas elements
@@ -258,30 +291,99 @@ func (e *CanvasElement) ToResponse() ElementResponse {
 
 // CanvasSnapshot represents a version snapshot of the canvas
 type CanvasSnapshot struct {
-	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
-	Description  *string     `json:"description,omitempty" db:"description"`
-	SnapshotData ElementData `json:"snapshot_data" db:"snapshot_data"`
-	Version      int         `json:"version" db:"version"`
-	ElementCount int         `json:"element_count" db:"element_count"`
-	ID           uuid.UUID   `json:"id" db:"id"`
-	WorkspaceID  uuid.UUID   `json:"workspace_id" db:"workspace_id"`
-	CreatedBy    uuid.UUID   `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	Name           *string     `json:"name,omitempty" db:"name"`
+	Description    *string     `json:"description,omitempty" db:"description"`
+	StorageKey     *string     `json:"-" db:"storage_key"`  // Payload location, nil for legacy inline snapshots
+	ContentHash    *string     `json:"-" db:"content_hash"` // SHA-256 of the element set, nil for older snapshots
+	SnapshotData   ElementData `json:"snapshot_data" db:"snapshot_data"`
+	Tags           []string    `json:"tags" db:"tags"`
+	Version        int         `json:"version" db:"version"`
+	ElementCount   int         `json:"element_count" db:"element_count"`
+	CompressedSize int64       `json:"-" db:"compressed_size"`
+	ID             uuid.UUID   `json:"id" db:"id"`
+	WorkspaceID    uuid.UUID   `json:"workspace_id" db:"workspace_id"`
+	CreatedBy      uuid.UUID   `json:"created_by" db:"created_by"`
+	Pinned         bool        `json:"pinned" db:"pinned"`
 }
 
 // CreateSnapshotRequest represents a request to create a snapshot
 type CreateSnapshotRequest struct {
-	Description *string `json:"description,omitempty"`
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Pinned      bool     `json:"pinned,omitempty"`
+}
+
+// UpdateSnapshotRequest represents a request to rename, tag or pin a snapshot.
+// Omitted fields are left unchanged, an empty tags list removes all tags.
+type UpdateSnapshotRequest struct {
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Pinned      *bool    `json:"pinned,omitempty"`
+}
+
+// RestoreSnapshotToNewRequest represents a request to branch a board from a snapshot
+type RestoreSnapshotToNewRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+// RestoreSnapshotElementsRequest represents a request to restore some elements
+// of a snapshot into the live board
+type RestoreSnapshotElementsRequest struct {
+	ElementIDs []uuid.UUID `json:"element_ids"`
+}
+
+// SnapshotRetentionPolicy decides which snapshots of a workspace the cleanup
+// job keeps. A snapshot survives when it is pinned, among the latest MaxCount
+// and younger than MaxAgeDays, or the newest of one of the last KeepDaily days
+// or KeepWeekly weeks.
+type SnapshotRetentionPolicy struct {
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	MaxAgeDays  *int       `json:"max_age_days,omitempty" db:"max_age_days"`
+	UpdatedBy   *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	MaxCount    int        `json:"max_count" db:"max_count"`
+	KeepDaily   int        `json:"keep_daily" db:"keep_daily"`
+	KeepWeekly  int        `json:"keep_weekly" db:"keep_weekly"`
+	WorkspaceID uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+}
+
+// UpdateSnapshotRetentionRequest replaces the retention policy of a workspace.
+// Omitting max_age_days disables the age limit.
+type UpdateSnapshotRetentionRequest struct {
+	MaxAgeDays *int `json:"max_age_days,omitempty"`
+	MaxCount   int  `json:"max_count"`
+	KeepDaily  int  `json:"keep_daily"`
+	KeepWeekly int  `json:"keep_weekly"`
+}
+
+// SnapshotListFilter represents filters for listing snapshots
+type SnapshotListFilter struct {
+	Pinned *bool  `form:"pinned"`
+	Tag    string `form:"tag"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+// SnapshotPage is a page of the snapshots of one workspace
+type SnapshotPage struct {
+	Snapshots []CanvasSnapshot
+	Total     int
 }
 
 // SnapshotResponse represents a snapshot in API responses
 type SnapshotResponse struct {
 	CreatedAt    time.Time `json:"created_at"`
+	Name         *string   `json:"name,omitempty"`
 	Description  *string   `json:"description,omitempty"`
+	Tags         []string  `json:"tags"`
 	Version      int       `json:"version"`
 	ElementCount int       `json:"element_count"`
 	ID           uuid.UUID `json:"id"`
 	WorkspaceID  uuid.UUID `json:"workspace_id"`
 	CreatedBy    uuid.UUID `json:"created_by"`
+	Pinned       bool      `json:"pinned"`
 }
 
 // SnapshotDetailResponse includes the full snapshot data
@@ -302,7 +404,10 @@ func (s *CanvasSnapshot) ToResponse() SnapshotResponse {
 		ID:           s.ID,
 		WorkspaceID:  s.WorkspaceID,
 		Version:      s.Version,
+		Name:         s.Name,
 		Description:  s.Description,
+		Tags:         s.Tags,
+		Pinned:       s.Pinned,
 		ElementCount: s.ElementCount,
 		CreatedBy:    s.CreatedBy,
 		CreatedAt:    s.CreatedAt,
@@ -331,6 +436,15 @@ type Operation struct {
 	OpType      string      `json:"op_type" db:"op_type"`     // create, update, delete, move
 }
 
+// ReplayResponse is a page of operations for replaying board history
+type ReplayResponse struct {
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	Operations []*Operation `json:"operations"`
+	NextOffset int          `json:"next_offset"`
+	HasMore    bool         `json:"has_more"`
+}
+
 // Element represents a simplified element model for CRDT operations
 type Element struct {
 	ID          uuid.UUID              `json:"id"`
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Comment actions broadcast to the room of a board
+const (
+	CommentActionCreated  = "created"
+	CommentActionUpdated  = "updated"
+	CommentActionDeleted  = "deleted"
+	CommentActionResolved = "resolved"
+	CommentActionReopened = "reopened"
+)
+
+// Comment is a comment thread on a board or an element, or a reply to one
+type Comment struct {
+	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
+	EditedAt     *time.Time        `json:"edited_at,omitempty" db:"edited_at"`
+	ResolvedAt   *time.Time        `json:"resolved_at,omitempty" db:"resolved_at"` // threads only
+	ResolvedBy   *uuid.UUID        `json:"resolved_by,omitempty" db:"resolved_by"` // threads only
+	ElementID    *uuid.UUID        `json:"element_id,omitempty" db:"element_id"`   // threads only, none for the board
+	ParentID     *uuid.UUID        `json:"parent_id,omitempty" db:"parent_id"`     // replies only
+	AuthorID     *uuid.UUID        `json:"author_id,omitempty" db:"author_id"`     // nil once the author is deleted
+	AuthorName   *string           `json:"author_name,omitempty" db:"author_name"`
+	AuthorAvatar *string           `json:"author_avatar,omitempty" db:"author_avatar"`
+	ResolverName *string           `json:"resolved_by_name,omitempty" db:"resolver_name"`
+	Replies      []Comment         `json:"replies,omitempty" db:"-"`
+	Reactions    []ReactionSummary `json:"reactions,omitempty" db:"-"`
+	Body         string            `json:"body" db:"body"`
+	ReplyCount   int               `json:"reply_count" db:"reply_count"`
+	ID           uuid.UUID         `json:"id" db:"id"`
+	WorkspaceID  uuid.UUID         `json:"workspace_id" db:"workspace_id"`
+}
+
+// Resolved reports whether the thread is resolved
+func (c *Comment) Resolved() bool {
+	return c.ResolvedAt != nil
+}
+
+// CommentMatch is a comment found by search, with the element its thread is
+// about
+type CommentMatch struct {
+	ThreadElementID *uuid.UUID
+	Comment
+}
+
+// CommentFilter selects the threads of a board
+type CommentFilter struct {
+	ElementID *uuid.UUID // threads of one element
+	Resolved  *bool      // resolved or open threads only
+}
+
+// CreateCommentRequest starts a thread on the board or an element
+type CreateCommentRequest struct {
+	ElementID *uuid.UUID `json:"element_id,omitempty"`
+	Body      string     `json:"body"`
+}
+
+// CreateReplyRequest replies to a thread
+type CreateReplyRequest struct {
+	Body string `json:"body"`
+}
+
+// UpdateCommentRequest edits the body of a comment
+type UpdateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// CommentListResponse is the threads of a board with their replies
+type CommentListResponse struct {
+	Threads []Comment `json:"threads"`
+	Total   int       `json:"total"`
+}
+
+// CommentCounts are the comment threads of an element
+type CommentCounts struct {
+	Threads    int `json:"threads"`
+	Unresolved int `json:"unresolved"`
+}
+
+// CommentEventPayload is broadcast when a comment is created, edited or
+// deleted or its thread is resolved or reopened
+type CommentEventPayload struct {
+	Action  string  `json:"action"`
+	Comment Comment `json:"comment"`
+}
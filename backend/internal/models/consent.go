@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsentDocument is a legal document users accept
+type ConsentDocument string
+
+const (
+	ConsentDocumentTerms   ConsentDocument = "terms"
+	ConsentDocumentPrivacy ConsentDocument = "privacy"
+)
+
+// UserConsent is the acceptance of a document version by a user
+type UserConsent struct {
+	AcceptedAt time.Time       `json:"accepted_at"`
+	IPAddress  *string         `json:"-"`
+	Document   ConsentDocument `json:"document"`
+	Version    string          `json:"version"`
+	ID         uuid.UUID       `json:"id"`
+	UserID     uuid.UUID       `json:"user_id"`
+}
+
+// ConsentDocumentStatus compares the current version of a document with the
+// version a user accepted last
+type ConsentDocumentStatus struct {
+	AcceptedAt      *time.Time      `json:"accepted_at,omitempty"`
+	AcceptedVersion *string         `json:"accepted_version,omitempty"`
+	Document        ConsentDocument `json:"document"`
+	CurrentVersion  string          `json:"current_version"`
+	URL             string          `json:"url,omitempty"`
+	Required        bool            `json:"required"`
+}
+
+// ConsentStatus tells the frontend whether to prompt a user to accept the
+// current terms and privacy policy
+type ConsentStatus struct {
+	Documents       []ConsentDocumentStatus `json:"documents"`
+	ConsentRequired bool                    `json:"consent_required"`
+}
+
+// AcceptConsentRequest accepts the current versions of the documents. The
+// versions must match the current ones, so a user never accepts a version
+// they weren't shown.
+type AcceptConsentRequest struct {
+	TermsVersion   string `json:"terms_version"`
+	PrivacyVersion string `json:"privacy_version"`
+}
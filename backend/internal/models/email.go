@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Email suppression reasons
+const (
+	EmailSuppressionBounced    = "bounced"
+	EmailSuppressionComplained = "complained"
+)
+
+// EmailSuppression is an email address that no longer receives emails because
+// it hard bounced or its owner marked an email as spam
+type EmailSuppression struct {
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Email     string    `json:"email" db:"email"`
+	Reason    string    `json:"reason" db:"reason"`
+	Provider  string    `json:"provider" db:"provider"`
+	Detail    string    `json:"detail,omitempty" db:"detail"`
+}
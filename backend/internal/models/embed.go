@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmbedToken gives read-only access to one workspace through the embed
+// API, for boards embedded in wikis and docs
+type EmbedToken struct {
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	Name        string     `json:"name" db:"name"`
+	Prefix      string     `json:"prefix" db:"prefix"` // first characters of the token
+	TokenHash   string     `json:"-" db:"token_hash"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+	CreatedBy   uuid.UUID  `json:"created_by" db:"created_by"`
+}
+
+// CreateEmbedTokenRequest creates an embed token
+type CreateEmbedTokenRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil for a token that doesn't expire
+	Name      string     `json:"name"`
+}
+
+// EmbedTokenWithSecret is returned when an embed token is created, the only
+// time the token is shown
+type EmbedTokenWithSecret struct {
+	EmbedToken
+	Token string `json:"token"`
+}
+
+// EmbedElement is an element as embeds render it, without authorship
+type EmbedElement struct {
+	ParentID    *uuid.UUID  `json:"parent_id,omitempty"`
+	ElementData ElementData `json:"element_data"`
+	ElementType ElementType `json:"element_type"`
+	ZIndex      int         `json:"z_index"`
+	ID          uuid.UUID   `json:"id"`
+}
+
+// EmbedBoard is the read-only content of an embedded board
+type EmbedBoard struct {
+	Name        string         `json:"name"`
+	Elements    []EmbedElement `json:"elements"`
+	WorkspaceID uuid.UUID      `json:"workspace_id"`
+	// Restricted is set when the workspace has an IP allowlist, so the
+	// board must not be kept by shared caches
+	Restricted bool `json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkspaceDataKey is the data key encrypting the content of a workspace,
+// wrapped by the master key MasterKeyID
+type WorkspaceDataKey struct {
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	MasterKeyID string    `json:"master_key_id"`
+	WrappedKey  []byte    `json:"-"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Domain event types published for integrations such as outgoing webhooks
+const (
+	EventElementCreated   = "element.created"
+	EventMemberJoined     = "member.joined"
+	EventSnapshotCreated  = "snapshot.created"
+	EventSnapshotRestored = "snapshot.restored"
+	EventSnapshotDeleted  = "snapshot.deleted"
+
+	// EventWebhookTest is only sent by the webhook test-fire endpoint
+	EventWebhookTest = "webhook.test"
+)
+
+// EventTypes lists the events integrations can subscribe to
+var EventTypes = []string{
+	EventElementCreated,
+	EventMemberJoined,
+	EventSnapshotCreated,
+	EventSnapshotRestored,
+	EventSnapshotDeleted,
+}
+
+// ValidEventType reports whether integrations can subscribe to the event type
+func ValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a domain event. Data holds the type specific payload, for
+// snapshot events the same one connected clients receive over WebSocket.
+type Event struct {
+	OccurredAt  time.Time   `json:"occurred_at"`
+	Data        interface{} `json:"data"`
+	ActorID     *uuid.UUID  `json:"actor_id,omitempty"` // nil for system actions such as retention cleanup
+	Type        string      `json:"type"`
+	ID          uuid.UUID   `json:"id"`
+	WorkspaceID uuid.UUID   `json:"workspace_id"`
+}
+
+// ElementEventPayload is the data of element events
+type ElementEventPayload struct {
+	ElementIDs []uuid.UUID `json:"element_ids"`
+}
+
+// MemberEventPayload is the data of member events
+type MemberEventPayload struct {
+	Role   WorkspaceRole `json:"role"`
+	UserID uuid.UUID     `json:"user_id"`
+}
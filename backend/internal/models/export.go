@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Formats of board exports
+const (
+	ExportFormatMarkdown   = "markdown"
+	ExportFormatConfluence = "confluence"
+	// Operation reports list the operations of a time range
+	ExportFormatOperationsCSV  = "operations_csv"
+	ExportFormatOperationsJSON = "operations_json"
+)
+
+// Statuses of board exports
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusCompleted  = "completed"
+	ExportStatusFailed     = "failed"
+)
+
+// BoardExport is a document rendered from a board in the background
+type BoardExport struct {
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	RequestedBy *uuid.UUID `json:"requested_by,omitempty" db:"requested_by"`
+	// RangeFrom and RangeTo bound the operations of a report, RangeFrom is
+	// nil for the whole history
+	RangeFrom *time.Time `json:"from,omitempty" db:"range_from"`
+	RangeTo   *time.Time `json:"to,omitempty" db:"range_to"`
+	SizeBytes *int64     `json:"size_bytes,omitempty" db:"size_bytes"`
+	Error     *string    `json:"error,omitempty" db:"error"`
+	ObjectKey *string    `json:"-" db:"object_key"`
+	// DownloadURL is a time-limited link to the document once completed
+	DownloadURL string    `json:"download_url,omitempty" db:"-"`
+	Format      string    `json:"format" db:"format"`
+	Status      string    `json:"status" db:"status"`
+	ID          uuid.UUID `json:"id" db:"id"`
+	WorkspaceID uuid.UUID `json:"workspace_id" db:"workspace_id"`
+}
+
+// CreateBoardExportRequest asks for an export of a board
+type CreateBoardExportRequest struct {
+	// Format is markdown (GitHub-flavored, also imported by Notion) or
+	// confluence (wiki markup)
+	Format string `json:"format" binding:"required"`
+}
+
+// BoardExportJob is queued to render an export
+type BoardExportJob struct {
+	ExportID    uuid.UUID `json:"export_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
+
+// OperationReportRow is an operation listed in an operation report. The
+// user and element are empty once they were deleted.
+type OperationReportRow struct {
+	Time        time.Time `json:"time"`
+	UserName    string    `json:"user_name"`
+	UserEmail   string    `json:"user_email"`
+	ElementType string    `json:"element_type"`
+	OpType      string    `json:"op_type"`
+	Timestamp   int64     `json:"lamport_timestamp"`
+	UserID      uuid.UUID `json:"user_id"`
+	ElementID   uuid.UUID `json:"element_id"`
+}
+
+// OperationReport is the JSON document of an operation report
+type OperationReport struct {
+	GeneratedAt time.Time            `json:"generated_at"`
+	From        *time.Time           `json:"from"`
+	To          time.Time            `json:"to"`
+	Operations  []OperationReportRow `json:"operations"`
+	WorkspaceID uuid.UUID            `json:"workspace_id"`
+}
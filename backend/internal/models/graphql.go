@@ -0,0 +1,8 @@
+package models
+
+// GraphQLRequest is a GraphQL operation sent over HTTP
+type GraphQLRequest struct {
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
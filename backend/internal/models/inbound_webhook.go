@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InboundWebhook is a URL integrations post text to, which then appears as a
+// sticky note in a column of the board
+type InboundWebhook struct {
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	Name          string     `json:"name" db:"name"`
+	DefaultColumn string     `json:"default_column" db:"default_column"`
+	TokenHash     string     `json:"-" db:"token_hash"`
+	Path          string     `json:"path" db:"-"` // API path the integration posts to
+	ID            uuid.UUID  `json:"id" db:"id"`
+	WorkspaceID   uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+	CreatedBy     uuid.UUID  `json:"created_by" db:"created_by"` // notes are created as this user
+	Active        bool       `json:"active" db:"active"`
+}
+
+// CreateInboundWebhookRequest creates an inbound webhook. DefaultColumn
+// defaults to Inbox.
+type CreateInboundWebhookRequest struct {
+	Name          string `json:"name"`
+	DefaultColumn string `json:"default_column"`
+}
+
+// UpdateInboundWebhookRequest changes an inbound webhook, omitted fields are kept
+type UpdateInboundWebhookRequest struct {
+	Name          *string `json:"name,omitempty"`
+	DefaultColumn *string `json:"default_column,omitempty"`
+	Active        *bool   `json:"active,omitempty"`
+	RotateToken   bool    `json:"rotate_token"`
+}
+
+// InboundWebhookWithToken is returned when an inbound webhook is created or
+// its token is rotated, the only times the token is shown
+type InboundWebhookWithToken struct {
+	InboundWebhook
+	Token string `json:"token,omitempty"`
+}
+
+// InboundWebhookPayload is posted to an inbound webhook. Column is the text
+// of the heading the note is placed under, the webhook default when empty.
+type InboundWebhookPayload struct {
+	Text   string `json:"text"`
+	Column string `json:"column"`
+	Color  string `json:"color"` // hex color of the note, yellow when empty
+}
+
+// InboundWebhookResult describes the note an inbound webhook created
+type InboundWebhookResult struct {
+	Column    string    `json:"column"`
+	ElementID uuid.UUID `json:"element_id"`
+}
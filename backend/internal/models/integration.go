@@ -0,0 +1,31 @@
+package models
+
+// Stock media providers
+const (
+	StockProviderUnsplash = "unsplash"
+	StockProviderGiphy    = "giphy"
+)
+
+// StockMediaItem is a single search result from a stock media provider
+type StockMediaItem struct {
+	Attribution AssetAttribution `json:"attribution"`
+	ID          string           `json:"id"`
+	Provider    string           `json:"provider"`
+	Title       string           `json:"title"`
+	PreviewURL  string           `json:"preview_url"`
+	Width       int              `json:"width"`
+	Height      int              `json:"height"`
+}
+
+// StockSearchResponse is a page of stock media search results
+type StockSearchResponse struct {
+	Items   []StockMediaItem `json:"items"`
+	Total   int              `json:"total"`
+	Page    int              `json:"page"`
+	PerPage int              `json:"per_page"`
+}
+
+// InsertStockMediaRequest is the request body for importing a stock media item
+type InsertStockMediaRequest struct {
+	ID string `json:"id"`
+}
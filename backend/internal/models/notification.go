@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	NotificationTypeMention        = "mention"
+	NotificationTypeAccessRequest  = "access_request"
+	NotificationTypeAccessApproved = "access_approved"
+	NotificationTypeAccessDenied   = "access_denied"
+)
+
+// Digest frequencies
+const (
+	DigestFrequencyFrequent = "frequent" // on every digest run
+	DigestFrequencyDaily    = "daily"
+	DigestFrequencyWeekly   = "weekly"
+	DigestFrequencyOff      = "off"
+)
+
+// Notification is an in-app notification of a user
+type Notification struct {
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	ReadAt      *time.Time             `json:"read_at,omitempty" db:"read_at"`
+	EmailedAt   *time.Time             `json:"-" db:"emailed_at"`
+	ActorID     *uuid.UUID             `json:"actor_id,omitempty" db:"actor_id"`
+	ElementID   *uuid.UUID             `json:"element_id,omitempty" db:"element_id"`
+	Data        map[string]interface{} `json:"data" db:"data"` // mentions: excerpt and url, the deep link to the element; access requests: request_id and url
+	Type        string                 `json:"type" db:"type"`
+	ID          uuid.UUID              `json:"id" db:"id"`
+	UserID      uuid.UUID              `json:"user_id" db:"user_id"`
+	WorkspaceID uuid.UUID              `json:"workspace_id" db:"workspace_id"`
+}
+
+// NotificationWithContext adds the names shown with a notification
+type NotificationWithContext struct {
+	ActorName     *string `json:"actor_name,omitempty"`
+	WorkspaceName string  `json:"workspace_name"`
+	Notification
+}
+
+// NotificationListResponse is a page of notifications
+type NotificationListResponse struct {
+	Notifications []NotificationWithContext `json:"notifications"`
+	UnreadCount   int                       `json:"unread_count"`
+}
+
+// NotificationPreferences are the notification settings of a user
+type NotificationPreferences struct {
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	LastDigestAt    *time.Time `json:"-" db:"last_digest_at"`
+	DigestFrequency string     `json:"digest_frequency" db:"digest_frequency"`
+	UserID          uuid.UUID  `json:"-" db:"user_id"`
+	EmailOnInvite   bool       `json:"email_on_invite" db:"email_on_invite"`
+	EmailOnMention  bool       `json:"email_on_mention" db:"email_on_mention"`
+	MuteWebhooks    bool       `json:"mute_webhooks" db:"mute_webhooks"` // keep the user's activity out of chat webhooks
+}
+
+// DefaultNotificationPreferences returns the settings of users that haven't
+// changed them
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{
+		DigestFrequency: DigestFrequencyFrequent,
+		UserID:          userID,
+		EmailOnInvite:   true,
+		EmailOnMention:  true,
+	}
+}
+
+// UpdateNotificationPreferencesRequest changes the given settings
+type UpdateNotificationPreferencesRequest struct {
+	EmailOnInvite   *bool   `json:"email_on_invite,omitempty"`
+	EmailOnMention  *bool   `json:"email_on_mention,omitempty"`
+	DigestFrequency *string `json:"digest_frequency,omitempty"`
+	MuteWebhooks    *bool   `json:"mute_webhooks,omitempty"`
+}
+
+// ValidDigestFrequency reports whether frequency is a known digest frequency
+func ValidDigestFrequency(frequency string) bool {
+	switch frequency {
+	case DigestFrequencyFrequent, DigestFrequencyDaily, DigestFrequencyWeekly, DigestFrequencyOff:
+		return true
+	}
+	return false
+}
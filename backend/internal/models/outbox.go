@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage is a NATS message stored in the transactional outbox until
+// the relay publishes it
+type OutboxMessage struct {
+	ID          uuid.UUID           `json:"id"`
+	Subject     string              `json:"subject"`
+	Payload     []byte              `json:"payload"`
+	Headers     map[string][]string `json:"headers"`
+	JetStream   bool                `json:"jetstream"` // publish to JetStream and wait for the ack
+	Attempts    int                 `json:"attempts"`
+	LastError   *string             `json:"last_error,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	PublishedAt *time.Time          `json:"published_at,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Presentation actions broadcast to the room of a board
+const (
+	PresentationActionSlidesUpdated    = "slides_updated"
+	PresentationActionStarted          = "started"
+	PresentationActionSlideChanged     = "slide_changed"
+	PresentationActionPresenterChanged = "presenter_changed"
+	PresentationActionStopped          = "stopped"
+)
+
+// Presentation is the slide order of a board, frames shown one after the
+// other, and the session presenting them if one is running
+type Presentation struct {
+	UpdatedAt    time.Time   `json:"updated_at"`
+	StartedAt    *time.Time  `json:"started_at,omitempty"`
+	PresenterID  *uuid.UUID  `json:"presenter_id,omitempty"`
+	SlideIDs     []uuid.UUID `json:"slide_ids"`
+	CurrentSlide int         `json:"current_slide"`
+	WorkspaceID  uuid.UUID   `json:"workspace_id"`
+
+	// CurrentSlideID is the frame shown, set while a session runs
+	CurrentSlideID *uuid.UUID `json:"current_slide_id,omitempty"`
+}
+
+// Running reports whether a session presents the slides
+func (p *Presentation) Running() bool {
+	return p.StartedAt != nil && p.PresenterID != nil
+}
+
+// SetSlidesRequest replaces the slide order of a board
+type SetSlidesRequest struct {
+	SlideIDs []uuid.UUID `json:"slide_ids"`
+}
+
+// StartPresentationRequest starts a session. The user starting it presents
+// unless presenter_id designates another member.
+type StartPresentationRequest struct {
+	PresenterID *uuid.UUID `json:"presenter_id,omitempty"`
+	Slide       int        `json:"slide"`
+}
+
+// ChangePresenterRequest hands a running session to another member
+type ChangePresenterRequest struct {
+	PresenterID uuid.UUID `json:"presenter_id"`
+}
+
+// PresentationGotoPayload is sent by the presenter to show another slide
+type PresentationGotoPayload struct {
+	Slide int `json:"slide"`
+}
+
+// PresentationEventPayload is broadcast when the slides or the session of a
+// board change, so followers show the slide of the presenter
+type PresentationEventPayload struct {
+	Action       string       `json:"action"`
+	Presentation Presentation `json:"presentation"`
+}
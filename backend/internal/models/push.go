@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushSubscription is the push service endpoint of a browser
+type PushSubscription struct {
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	Endpoint   string     `json:"endpoint" db:"endpoint"`
+	P256dh     string     `json:"-" db:"p256dh"`
+	Auth       string     `json:"-" db:"auth"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+}
+
+// PushSubscriptionKeys are the encryption keys of a browser subscription
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// RegisterPushSubscriptionRequest is the JSON form of a browser
+// PushSubscription
+type RegisterPushSubscriptionRequest struct {
+	Endpoint string               `json:"endpoint"`
+	Keys     PushSubscriptionKeys `json:"keys"`
+}
+
+// UnregisterPushSubscriptionRequest removes the subscription of an endpoint
+type UnregisterPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+}
+
+// PushMessage is the payload shown by the service worker as a notification
+type PushMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url,omitempty"` // opened when the notification is clicked
+	Tag   string `json:"tag,omitempty"` // replaces an earlier notification with the same tag
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReactionTarget is the kind of thing a reaction is on
+type ReactionTarget string
+
+// Things users can react to
+const (
+	ReactionTargetComment ReactionTarget = "comment"
+	ReactionTargetElement ReactionTarget = "element"
+)
+
+// Reaction is the reaction of a user with an emoji
+type Reaction struct {
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	TargetType  ReactionTarget `json:"target_type" db:"target_type"`
+	Emoji       string         `json:"emoji" db:"emoji"`
+	TargetID    uuid.UUID      `json:"target_id" db:"target_id"`
+	UserID      uuid.UUID      `json:"user_id" db:"user_id"`
+	WorkspaceID uuid.UUID      `json:"workspace_id" db:"workspace_id"`
+}
+
+// ReactionSummary aggregates the reactions with one emoji, in the order the
+// emoji was first used
+type ReactionSummary struct {
+	Emoji   string      `json:"emoji"`
+	UserIDs []uuid.UUID `json:"user_ids"`
+	Count   int         `json:"count"`
+}
+
+// ReactionRequest adds a reaction
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// ReactionsResponse is the reactions of a target after a change
+type ReactionsResponse struct {
+	Reactions []ReactionSummary `json:"reactions"`
+}
+
+// ReactionsUpdatedPayload is broadcast when a reaction is added or removed
+type ReactionsUpdatedPayload struct {
+	TargetType ReactionTarget    `json:"target_type"`
+	Reactions  []ReactionSummary `json:"reactions"`
+	TargetID   uuid.UUID         `json:"target_id"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordingStatus is the state of a session recording
+type RecordingStatus string
+
+const (
+	RecordingStatusRecording RecordingStatus = "recording"
+	RecordingStatusReady     RecordingStatus = "ready"
+)
+
+// SessionRecording is the operations and presence of a board recorded
+// between a start and a stop, for teammates to replay later
+type SessionRecording struct {
+	StartedAt  time.Time       `json:"started_at"`
+	StoppedAt  *time.Time      `json:"stopped_at,omitempty"`
+	StorageKey *string         `json:"-"`
+	Name       string          `json:"name"`
+	Status     RecordingStatus `json:"status"`
+	EventCount int             `json:"event_count"`
+	SizeBytes  int64           `json:"size_bytes"`
+	DurationMs int64           `json:"duration_ms"`
+	ID         uuid.UUID       `json:"id"`
+	// Truncated is set when the session had more events than a recording
+	// keeps, the later ones are missing
+	Truncated   bool      `json:"truncated"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	StartedBy   uuid.UUID `json:"started_by"`
+}
+
+// RecordedEvent is a realtime message of a recorded session, in the order
+// the room got it
+type RecordedEvent struct {
+	At      time.Time       `json:"at"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Type    MessageType     `json:"type"`
+	UserID  uuid.UUID       `json:"user_id"`
+}
+
+// StartRecordingRequest starts recording a session
+type StartRecordingRequest struct {
+	Name string `json:"name"`
+}
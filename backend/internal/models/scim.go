@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SCIM 2.0 schema URNs (RFC 7643, RFC 7644)
+const (
+	SCIMSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMSchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SCIMSchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SCIMSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// SCIMGroup is a group provisioned by the identity provider
+type SCIMGroup struct {
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ExternalID  *string   `json:"external_id,omitempty" db:"external_id"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	ID          uuid.UUID `json:"id" db:"id"`
+}
+
+// SCIMGroupMember is a user in a SCIM group
+type SCIMGroupMember struct {
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
+	GroupID uuid.UUID `json:"group_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+// SCIMGroupWorkspace grants the members of a SCIM group a role in a workspace
+type SCIMGroupWorkspace struct {
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	WorkspaceName string        `json:"workspace_name"`
+	Role          WorkspaceRole `json:"role" db:"role"`
+	GroupID       uuid.UUID     `json:"group_id" db:"group_id"`
+	WorkspaceID   uuid.UUID     `json:"workspace_id" db:"workspace_id"`
+}
+
+// SCIMGroupWithWorkspaces is a SCIM group with its workspace mappings, as
+// listed to admins
+type SCIMGroupWithWorkspaces struct {
+	Workspaces []SCIMGroupWorkspace `json:"workspaces"`
+	SCIMGroup
+	MemberCount int `json:"member_count"`
+}
+
+// SetSCIMGroupWorkspaceRequest maps a SCIM group to a workspace role
+type SetSCIMGroupWorkspaceRequest struct {
+	Role WorkspaceRole `json:"role" binding:"required"`
+}
+
+// SCIMFilter is a parsed `attribute eq "value"` filter, the only filter
+// identity providers send when they look up existing resources
+type SCIMFilter struct {
+	Attribute string // lowercase attribute path, e.g. username
+	Value     string
+}
+
+// SCIMMeta is the meta attribute of SCIM resources
+type SCIMMeta struct {
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	ResourceType string     `json:"resourceType"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// SCIMName is the name attribute of SCIM users
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmail is an entry of the emails attribute of SCIM users
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUserResource is the SCIM representation of a user. userName is the
+// email address of the user.
+type SCIMUserResource struct {
+	Name        *SCIMName   `json:"name,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Schemas     []string    `json:"schemas"`
+	Emails      []SCIMEmail `json:"emails,omitempty"`
+}
+
+// SCIMMemberRef references a member of a SCIM group
+type SCIMMemberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMGroupResource is the SCIM representation of a group
+type SCIMGroupResource struct {
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Schemas     []string        `json:"schemas"`
+	Members     []SCIMMemberRef `json:"members,omitempty"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Resources    any      `json:"Resources"`
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+}
+
+// SCIMPatchRequest is a SCIM PATCH request
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is one operation of a SCIM PATCH request. Value is
+// kept raw, its shape depends on the path.
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// SCIMErrorResponse is the body of SCIM error responses. Status is the HTTP
+// status code as a string, as RFC 7644 requires.
+type SCIMErrorResponse struct {
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+	Schemas  []string `json:"schemas"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SearchResultType is the kind of thing a search result is
+type SearchResultType string
+
+// Things workspace search looks into
+const (
+	SearchResultElement SearchResultType = "element"
+	SearchResultComment SearchResultType = "comment"
+	SearchResultAsset   SearchResultType = "asset"
+)
+
+// SearchResult is a match of workspace search. Results of all types are
+// ranked together by score.
+type SearchResult struct {
+	UpdatedAt   time.Time        `json:"updated_at"`
+	ElementID   *uuid.UUID       `json:"element_id,omitempty"`   // elements, and comments on one
+	ThreadID    *uuid.UUID       `json:"thread_id,omitempty"`    // comments only
+	ElementType ElementType      `json:"element_type,omitempty"` // elements only
+	ContentType string           `json:"content_type,omitempty"` // assets only
+	Type        SearchResultType `json:"type"`
+	Title       string           `json:"title"`
+	Snippet     string           `json:"snippet"`
+	Score       float64          `json:"score"`
+	ID          uuid.UUID        `json:"id"`
+}
+
+// SearchRequest is a workspace search
+type SearchRequest struct {
+	Types  []SearchResultType // all types when empty
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// SearchResponse is a page of workspace search results
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "github.com/google/uuid"
+
+// Keys set in the element_data of translated copies
+const (
+	// TranslatedFromKey holds the ID of the element a copy was translated from
+	TranslatedFromKey = "translated_from"
+	// TranslationLanguageKey holds the language a copy was translated to
+	TranslationLanguageKey = "translation_language"
+)
+
+// TranslateElementsRequest asks to translate the text of elements
+type TranslateElementsRequest struct {
+	ElementIDs []uuid.UUID `json:"element_ids"`
+	// TargetLanguage is a language code such as de, fr or pt-BR
+	TargetLanguage string `json:"target_language"`
+	// Create adds a translated copy of each element to the board, to the
+	// right of everything on it
+	Create bool `json:"create"`
+}
+
+// ElementTranslation is the translated text of an element: the HTML content
+// of a text element, the content of a sticky note or the items of a list
+type ElementTranslation struct {
+	ElementID      uuid.UUID   `json:"element_id"`
+	ElementType    ElementType `json:"element_type"`
+	Content        string      `json:"content,omitempty"`
+	Items          []string    `json:"items,omitempty"`
+	SourceLanguage string      `json:"source_language,omitempty"` // detected, when the backend reports it
+}
+
+// TranslateElementsResponse is the translations of the requested elements
+// that have text, in the order of the request
+type TranslateElementsResponse struct {
+	Translations []ElementTranslation `json:"translations"`
+	Elements     []ElementResponse    `json:"elements,omitempty"`
+}
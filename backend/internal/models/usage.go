@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsagePeriod is the metered usage of a workspace in a calendar month (UTC)
+type UsagePeriod struct {
+	PeriodStart      time.Time     `json:"period_start"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+	ReportedAt       *time.Time    `json:"reported_at,omitempty"`
+	Overage          *UsageOverage `json:"overage,omitempty"`
+	StoragePeakBytes int64         `json:"storage_peak_bytes"`
+	WorkspaceID      uuid.UUID     `json:"workspace_id"`
+	ActiveEditors    int           `json:"active_editors"`
+	ExportsRendered  int           `json:"exports_rendered"`
+}
+
+// UsageOverage is the usage of a month above the entitlements of the plan
+type UsageOverage struct {
+	StorageGB     int64 `json:"storage_gb"`
+	ActiveEditors int   `json:"active_editors"`
+	Exports       int   `json:"exports"`
+}
+
+// UsageResponse is the usage of a workspace, newest month first, with the
+// entitlements of its owner's plan when billing is enabled
+type UsageResponse struct {
+	Entitlements *Entitlements `json:"entitlements,omitempty"`
+	Periods      []UsagePeriod `json:"periods"`
+}
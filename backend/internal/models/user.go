@@ -1,22 +1,32 @@
 package models
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type User struct {
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
-	PasswordHash  *string   `json:"-" db:"password_hash"`
-	AvatarURL     *string   `json:"avatar_url,omitempty" db:"avatar_url"`
-	ProviderID    *string   `json:"-" db:"provider_id"`
-	Email         string    `json:"email" db:"email"`
-	Name          string    `json:"name" db:"name"`
-	Provider      string    `json:"provider" db:"provider"`
-	ID            uuid.UUID `json:"id" db:"id"`
-	EmailVerified bool      `json:"email_verified" db:"email_verified"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	DeactivatedAt  *time.Time `json:"-" db:"deactivated_at"` // set by the identity provider
+	PasswordHash   *string    `json:"-" db:"password_hash"`
+	AvatarURL      *string    `json:"avatar_url,omitempty" db:"avatar_url"`
+	ProviderID     *string    `json:"-" db:"provider_id"`
+	SCIMExternalID *string    `json:"-" db:"scim_external_id"`
+	Email          string     `json:"email" db:"email"`
+	Name           string     `json:"name" db:"name"`
+	Username       string     `json:"username" db:"username"`
+	Provider       string     `json:"provider" db:"provider"`
+	ID             uuid.UUID  `json:"id" db:"id"`
+	EmailVerified  bool       `json:"email_verified" db:"email_verified"`
+	IsBot          bool       `json:"is_bot" db:"is_bot"` // bot account of a workspace, see Bot
+}
+
+// Active reports whether the user may sign in
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
 }
 
 type RefreshToken struct {
@@ -36,11 +46,23 @@ type PasswordResetToken struct {
 	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
 }
 
+// usernamePattern matches usernames: 2-40 lowercase letters, digits, '_', '.'
+// or '-', not starting or ending with '.' or '-' so mentions at the end of a
+// sentence parse
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_][a-z0-9_.-]{0,38}[a-z0-9_]$`)
+
+// ValidUsername reports whether a lowercase username is valid
+func ValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
 // CreateUserRequest represents the request to create a new user
 type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
 	Name     string `json:"name" binding:"required,min=2"`
+	// AcceptTerms accepts the current terms of service and privacy policy
+	AcceptTerms bool `json:"accept_terms"`
 }
 
 // LoginRequest represents the login request
@@ -52,6 +74,7 @@ type LoginRequest struct {
 // UpdateProfileRequest represents the update profile request
 type UpdateProfileRequest struct {
 	Name      *string `json:"name,omitempty"`
+	Username  *string `json:"username,omitempty"`
 	AvatarURL *string `json:"avatar_url,omitempty"`
 }
 
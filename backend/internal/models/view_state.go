@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Viewport is the part of a board a user looks at, its center in canvas
+// coordinates and the zoom factor
+type Viewport struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Zoom float64 `json:"zoom"`
+}
+
+// ViewState is where a user left off on a board, restored when they open it
+// again on any device
+type ViewState struct {
+	UpdatedAt         time.Time   `json:"updated_at"`
+	CollapsedFrameIDs []uuid.UUID `json:"collapsed_frame_ids"`
+	// HiddenLayers are layer names of the client, the server doesn't
+	// interpret them
+	HiddenLayers []string `json:"hidden_layers"`
+	Viewport     Viewport `json:"viewport"`
+}
+
+// UpdateViewStateRequest changes the view state of a board. Fields left out
+// keep their value, so clients can save the viewport on its own.
+type UpdateViewStateRequest struct {
+	Viewport          *Viewport    `json:"viewport,omitempty"`
+	CollapsedFrameIDs *[]uuid.UUID `json:"collapsed_frame_ids,omitempty"`
+	HiddenLayers      *[]string    `json:"hidden_layers,omitempty"`
+}
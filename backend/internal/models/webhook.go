@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook payload formats. Chat formats render events as messages for the
+// incoming webhook of a chat provider instead of sending the event JSON.
+const (
+	WebhookFormatJSON  = "json"
+	WebhookFormatTeams = "teams"
+	// WebhookFormatRESTHook sends the trigger items of the event as a JSON
+	// array, for REST hooks subscribed by automation platforms
+	WebhookFormatRESTHook = "rest_hook"
+)
+
+// Webhook is a URL that receives signed event payloads of a workspace
+type Webhook struct {
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	URL         string     `json:"url" db:"url"`
+	Format      string     `json:"format" db:"format"`
+	Secret      string     `json:"-" db:"secret"`
+	Events      []string   `json:"events" db:"events"` // empty for all events
+	ID          uuid.UUID  `json:"id" db:"id"`
+	WorkspaceID uuid.UUID  `json:"workspace_id" db:"workspace_id"`
+	Active      bool       `json:"active" db:"active"`
+}
+
+// Subscribed reports whether the webhook receives events of the given type
+func (w *Webhook) Subscribed(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to a webhook, including retries
+type WebhookDelivery struct {
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	ResponseStatus *int            `json:"response_status,omitempty" db:"response_status"`
+	Error          *string         `json:"error,omitempty" db:"error"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	Status         string          `json:"status" db:"status"`
+	Payload        json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ID             uuid.UUID       `json:"id" db:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id" db:"webhook_id"`
+	EventID        uuid.UUID       `json:"event_id" db:"event_id"`
+}
+
+// CreateWebhookRequest registers a webhook. Events defaults to all events
+// and Format to json.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Format string   `json:"format"`
+	Events []string `json:"events"`
+}
+
+// UpdateWebhookRequest changes a webhook, omitted fields are kept
+type UpdateWebhookRequest struct {
+	URL          *string   `json:"url,omitempty"`
+	Format       *string   `json:"format,omitempty"`
+	Active       *bool     `json:"active,omitempty"`
+	Events       *[]string `json:"events,omitempty"`
+	RotateSecret bool      `json:"rotate_secret"`
+}
+
+// WebhookWithSecret is returned when a webhook is created or its secret is
+// rotated, the only times the secret is shown
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret,omitempty"`
+}
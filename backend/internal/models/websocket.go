@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,7 @@ type MessageType string
 const (
 	// Connection messages
 	MessageTypeJoinRoom   MessageType = "join_room"
+	MessageTypeJoinAck    MessageType = "join_ack"
 	MessageTypeLeaveRoom  MessageType = "leave_room"
 	MessageTypeUserJoined MessageType = "user_joined"
 	MessageTypeUserLeft   MessageType = "user_left"
@@ -28,6 +30,44 @@ const (
 	// Sync messages
 	MessageTypeSyncRequest  MessageType = "sync_request"
 	MessageTypeSyncResponse MessageType = "sync_response"
+	// MessageTypeBoardReloaded tells clients the whole board was replaced, e.g. by a snapshot restore
+	MessageTypeBoardReloaded MessageType = "board_reloaded"
+	// MessageTypeElementsRestored carries elements added back from a snapshot
+	MessageTypeElementsRestored MessageType = "elements_restored"
+	// MessageTypeElementsAdded carries elements created outside of a client
+	// session, e.g. by an inbound webhook
+	MessageTypeElementsAdded MessageType = "elements_added"
+
+	// Version history messages
+	MessageTypeSnapshotCreated  MessageType = "snapshot_created"
+	MessageTypeSnapshotRestored MessageType = "snapshot_restored"
+	MessageTypeSnapshotDeleted  MessageType = "snapshot_deleted"
+
+	// MessageTypeCommentEvent tells clients a comment was created, edited or
+	// deleted, or its thread was resolved or reopened
+	MessageTypeCommentEvent MessageType = "comment_event"
+	// MessageTypeReactionsUpdated carries the reactions of a comment or an
+	// element after one was added or removed
+	MessageTypeReactionsUpdated MessageType = "reactions_updated"
+
+	// MessageTypeBoardFrozen tells clients the board was frozen or
+	// unfrozen. Operations on a frozen board are rejected.
+	MessageTypeBoardFrozen MessageType = "board_frozen"
+
+	// MessageTypePresentationGoto is sent by the presenter of a running
+	// presentation to show another slide
+	MessageTypePresentationGoto MessageType = "presentation_goto"
+	// MessageTypePresentationEvent tells followers the presentation started,
+	// stopped, changed slide or presenter, or got new slides
+	MessageTypePresentationEvent MessageType = "presentation_event"
+
+	// MessageTypeNotification delivers an in-app notification to the clients
+	// of its user, whatever room they joined
+	MessageTypeNotification MessageType = "notification"
+
+	// MessageTypeMaintenance is a notice of admins to every connected client,
+	// e.g. before a deployment
+	MessageTypeMaintenance MessageType = "maintenance"
 
 	// Control messages
 	MessageTypeHeartbeat MessageType = "heartbeat"
@@ -35,6 +75,17 @@ const (
 	MessageTypeError     MessageType = "error"
 )
 
+// Realtime protocol versions negotiated in the join handshake
+const (
+	// ProtocolVersionLegacy clients receive a burst of user_joined messages on join
+	ProtocolVersionLegacy = 1
+	// ProtocolVersionCurrent clients receive a single join_ack with the participant list
+	ProtocolVersionCurrent = 2
+)
+
+// EncodingJSON is the message encoding supported by the server
+const EncodingJSON = "json"
+
 // WSMessage represents a WebSocket message
 type WSMessage struct {
 	Payload   interface{} `json:"payload,omitempty"`
@@ -42,12 +93,28 @@ type WSMessage struct {
 	UserID    uuid.UUID   `json:"user_id,omitempty"`
 	Type      MessageType `json:"type"`
 	RequestID string      `json:"request_id,omitempty"` // For request/response matching
+	Seq       uint64      `json:"seq,omitempty"`        // Stream sequence of room messages, with the jetstream transport
 }
 
 // JoinRoomPayload is the payload for join_room message
 type JoinRoomPayload struct {
-	WorkspaceID uuid.UUID `json:"workspace_id"`
-	UserColor   string    `json:"user_color,omitempty"` // Hex color for user cursor
+	Encodings       []string  `json:"encodings,omitempty"`  // Encodings supported by the client, in preference order
+	UserColor       string    `json:"user_color,omitempty"` // Hex color for user cursor
+	ProtocolVersion int       `json:"protocol_version,omitempty"`
+	WorkspaceID     uuid.UUID `json:"workspace_id"`
+	LastSeq         uint64    `json:"last_seq,omitempty"` // Highest seq received before reconnecting, replays the messages after it
+}
+
+// JoinAckPayload is sent to a client after it joins a room
+type JoinAckPayload struct {
+	Participants    []UserPresence `json:"participants"`
+	Encodings       []string       `json:"encodings"` // Encodings supported by the server
+	Encoding        string         `json:"encoding"`  // Negotiated encoding
+	Role            WorkspaceRole  `json:"role"`
+	MaxMessageSize  int64          `json:"max_message_size"`
+	BoardVersion    int64          `json:"board_version"` // Latest Lamport timestamp of the board
+	ProtocolVersion int            `json:"protocol_version"`
+	ClientID        uuid.UUID      `json:"client_id"`
 }
 
 // UserJoinedPayload is broadcast when a user joins
@@ -55,6 +122,8 @@ type UserJoinedPayload struct {
 	UserID    uuid.UUID `json:"user_id"`
 	UserName  string    `json:"user_name"`
 	UserColor string    `json:"user_color"`
+	Anonymous bool      `json:"anonymous,omitempty"`
+	Bot       bool      `json:"bot,omitempty"`
 }
 
 // UserLeftPayload is broadcast when a user leaves
@@ -86,6 +155,8 @@ type UserPresence struct {
 	LastSeen         time.Time       `json:"last_seen"`
 	UserName         string          `json:"user_name"`
 	UserColor        string          `json:"user_color"`
+	Anonymous        bool            `json:"anonymous,omitempty"` // Unauthenticated viewer of a public workspace
+	Bot              bool            `json:"bot,omitempty"`       // Bot account connected with a bot token
 }
 
 // PresenceUpdatePayload is broadcast to other users
@@ -93,6 +164,54 @@ type PresenceUpdatePayload struct {
 	Presence UserPresence `json:"presence"`
 }
 
+// BoardReloadedPayload is broadcast after the board was replaced server-side.
+// Clients discard local state and fetch the elements again.
+type BoardReloadedPayload struct {
+	SnapshotID       uuid.UUID `json:"snapshot_id"`
+	BackupSnapshotID uuid.UUID `json:"backup_snapshot_id"`
+	Version          int       `json:"version"`
+	ElementCount     int       `json:"element_count"`
+}
+
+// ElementsRestoredPayload is broadcast after elements were restored from a
+// snapshot. Clients add them to the board as new elements.
+type ElementsRestoredPayload struct {
+	Elements   []ElementResponse `json:"elements"`
+	SnapshotID uuid.UUID         `json:"snapshot_id"`
+	Version    int               `json:"version"`
+}
+
+// BoardFrozenPayload is broadcast when a board is frozen or unfrozen
+type BoardFrozenPayload struct {
+	FrozenAt *time.Time `json:"frozen_at,omitempty"`
+	FrozenBy *uuid.UUID `json:"frozen_by,omitempty"`
+	Frozen   bool       `json:"frozen"`
+}
+
+// ElementsAddedPayload is broadcast after elements were created outside of
+// a client session. Source tells what created them.
+type ElementsAddedPayload struct {
+	Source   string            `json:"source"`
+	Elements []ElementResponse `json:"elements"`
+}
+
+// Snapshot restore modes
+const (
+	SnapshotRestoreBoard        = "board"         // the board was replaced
+	SnapshotRestoreElements     = "elements"      // some elements were added back
+	SnapshotRestoreNewWorkspace = "new_workspace" // the snapshot was branched into a new workspace
+)
+
+// SnapshotEventPayload is broadcast when a snapshot is created, restored or
+// deleted, so clients can refresh their version history
+type SnapshotEventPayload struct {
+	BackupSnapshotID *uuid.UUID       `json:"backup_snapshot_id,omitempty"` // board restores only
+	NewWorkspaceID   *uuid.UUID       `json:"new_workspace_id,omitempty"`   // new_workspace restores only
+	Mode             string           `json:"mode,omitempty"`               // restores only
+	Snapshot         SnapshotResponse `json:"snapshot"`
+	ElementCount     int              `json:"element_count,omitempty"` // restored elements
+}
+
 // OperationType defines the type of CRDT operation
 type OperationType string
 
@@ -136,6 +255,15 @@ type ErrorPayload struct {
 	Message string `json:"message"`
 }
 
+// EventPublishRequest is a client message sent over REST by clients
+// that receive updates through the SSE event stream
+type EventPublishRequest struct {
+	Payload   interface{} `json:"payload"`
+	ClientID  uuid.UUID   `json:"client_id"`
+	Type      MessageType `json:"type"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
 // Client represents a connected WebSocket client
 type Client struct {
 	ID          uuid.UUID
@@ -143,16 +271,71 @@ type Client struct {
 	WorkspaceID uuid.UUID
 	Presence    *UserPresence
 	Send        chan *WSMessage // Channel for outbound messages
-	LastPing    time.Time
+	JoinAck     *WSMessage      // Pending join_ack, completed with participants by the room
+	lastPing    atomic.Int64    // Unix nanoseconds, written by the connection and read by the room
+	JoinedAt    time.Time       // When the client entered its room
+	ReplayAfter uint64          // Room messages after this seq are replayed on join, 0 for none
 	UserName    string
 	UserColor   string
+	IP          string // Address the connection came from
+	Role        WorkspaceRole
+	Anonymous   bool // Read-only connection without a token
+	Bot         bool // Read-only connection of a bot account
+}
+
+// LastPing returns when the connection last showed it is alive
+func (c *Client) LastPing() time.Time {
+	return time.Unix(0, c.lastPing.Load())
+}
+
+// Touch records that the connection is alive
+func (c *Client) Touch() {
+	c.lastPing.Store(time.Now().UnixNano())
 }
 
 // Room represents a workspace collaboration room
 type Room struct {
 	WorkspaceID uuid.UUID
-	Clients     map[uuid.UUID]*Client // client_id -> client
-	Broadcast   chan *WSMessage       // Broadcast channel
-	Register    chan *Client          // Register channel
-	Unregister  chan *Client          // Unregister channel
+	Clients     map[uuid.UUID]*Client   // client_id -> client
+	Broadcast   chan *WSMessage         // Broadcast channel
+	Direct      chan *UserMessage       // Messages for the clients of one user
+	Register    chan *Client            // Register channel
+	Unregister  chan *Client            // Unregister channel
+	Disconnect  chan *DisconnectRequest // Forced disconnects of one user
+	Replay      chan *ReplayResult      // Missed messages of rejoining clients
+}
+
+// ReplayResult carries the room messages a client missed while it was
+// disconnected. Complete is false when some of them are no longer kept.
+type ReplayResult struct {
+	Messages []*WSMessage
+	ClientID uuid.UUID
+	Complete bool
+}
+
+// DisconnectRequest asks a room to close the connections of one user
+type DisconnectRequest struct {
+	Done   chan int // receives the number of closed connections
+	Reason string
+	UserID uuid.UUID
+}
+
+// UserMessage is a message for every client of one user
+type UserMessage struct {
+	Message *WSMessage
+	UserID  uuid.UUID
+}
+
+// UserColor returns the cursor color of a user, the same on every connection
+func UserColor(userID uuid.UUID) string {
+	colors := []string{
+		"#FF6B6B", "#4ECDC4", "#45B7D1", "#FFA07A",
+		"#98D8C8", "#F7DC6F", "#BB8FCE", "#85C1E2",
+		"#F8B739", "#52B788", "#E76F51", "#2A9D8F",
+	}
+
+	// Use user ID bytes to select color
+	bytes := userID[:]
+	index := int(bytes[0]) % len(colors)
+	return colors[index]
 }
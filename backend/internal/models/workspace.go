@@ -15,6 +15,23 @@ const (
 	WorkspaceRoleViewer WorkspaceRole = "viewer"
 )
 
+// PublicRole defines what visitors of a public workspace that aren't
+// members may do besides viewing
+type PublicRole string
+
+const (
+	PublicRoleViewer    PublicRole = "viewer"
+	PublicRoleCommenter PublicRole = "commenter"
+)
+
+// PublicAccess holds what visitors of a public workspace that aren't members
+// may do. It has no effect on private workspaces.
+type PublicAccess struct {
+	Role           PublicRole `json:"role"`
+	AllowDuplicate bool       `json:"allow_duplicate"`
+	AllowAnonymous bool       `json:"allow_anonymous"`
+}
+
 // Workspace represents a collaborative workspace
 type Workspace struct {
 	CreatedAt    time.Time              `json:"created_at"`
@@ -27,6 +44,10 @@ type Workspace struct {
 	ID           uuid.UUID              `json:"id"`
 	OwnerID      uuid.UUID              `json:"owner_id"`
 	IsPublic     bool                   `json:"is_public"`
+	PublicAccess PublicAccess           `json:"public_access"`
+	FrozenAt     *time.Time             `json:"frozen_at,omitempty"` // Set while the board is read-only
+	FrozenBy     *uuid.UUID             `json:"frozen_by,omitempty"`
+	FreezeRole   WorkspaceRole          `json:"freeze_role"` // Least role that may freeze the board
 }
 
 // WorkspaceMember represents a user's membership in a workspace
@@ -53,13 +74,84 @@ type WorkspaceInvite struct {
 	CreatedBy   uuid.UUID     `json:"created_by"`
 }
 
+// WorkspaceInviteLink is a shareable link that lets any authenticated user
+// join a workspace with a role, until it expires, is used up or revoked
+type WorkspaceInviteLink struct {
+	CreatedAt   time.Time     `json:"created_at"`
+	ExpiresAt   *time.Time    `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time    `json:"revoked_at,omitempty"`
+	MaxUses     *int          `json:"max_uses,omitempty"` // nil for unlimited
+	CreatedBy   *uuid.UUID    `json:"created_by,omitempty"`
+	Role        WorkspaceRole `json:"role"`
+	TokenHash   string        `json:"-"`
+	UseCount    int           `json:"use_count"`
+	ID          uuid.UUID     `json:"id"`
+	WorkspaceID uuid.UUID     `json:"workspace_id"`
+}
+
+// InviteLinkRedemption is a user that joined through an invite link
+type InviteLinkRedemption struct {
+	RedeemedAt time.Time    `json:"redeemed_at"`
+	User       UserResponse `json:"user"`
+}
+
+// AccessRequestStatus is the state of a request to join a workspace
+type AccessRequestStatus string
+
+const (
+	AccessRequestPending  AccessRequestStatus = "pending"
+	AccessRequestApproved AccessRequestStatus = "approved"
+	AccessRequestDenied   AccessRequestStatus = "denied"
+)
+
+// WorkspaceAccessRequest is a request of a user to join a workspace they
+// have no access to
+type WorkspaceAccessRequest struct {
+	CreatedAt   time.Time           `json:"created_at"`
+	DecidedAt   *time.Time          `json:"decided_at,omitempty"`
+	DecidedBy   *uuid.UUID          `json:"decided_by,omitempty"`
+	Message     *string             `json:"message,omitempty"`
+	User        *UserResponse       `json:"user,omitempty"`
+	Role        WorkspaceRole       `json:"role"`
+	Status      AccessRequestStatus `json:"status"`
+	ID          uuid.UUID           `json:"id"`
+	WorkspaceID uuid.UUID           `json:"workspace_id"`
+	UserID      uuid.UUID           `json:"user_id"`
+}
+
 // WorkspaceWithRole extends Workspace with user's role
 type WorkspaceWithRole struct {
-	Owner    *User         `json:"owner,omitempty"`
-	UserRole WorkspaceRole `json:"user_role"`
+	LastVisitedAt *time.Time           `json:"last_visited_at,omitempty"`
+	Owner         *User                `json:"owner,omitempty"`
+	Views         *WorkspaceViewCounts `json:"views,omitempty"` // Set for the owner
+	UserRole      WorkspaceRole        `json:"user_role"`
 	Workspace
 }
 
+// WorkspaceVisit is the last visit of a user to a workspace
+type WorkspaceVisit struct {
+	VisitedAt   time.Time `json:"visited_at"`
+	UserID      uuid.UUID `json:"user_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+}
+
+// WorkspaceView is a visit to a workspace, by a signed in user or an
+// anonymous visitor of a public workspace
+type WorkspaceView struct {
+	UserID      *uuid.UUID
+	IP          string
+	UserAgent   string
+	Referrer    string
+	WorkspaceID uuid.UUID
+}
+
+// WorkspaceViewCounts counts the views of a workspace by members and
+// visitors alike. A viewer counts once per visit.
+type WorkspaceViewCounts struct {
+	Total  int64 `json:"total"`
+	Unique int64 `json:"unique"`
+}
+
 // WorkspaceMemberWithUser extends WorkspaceMember with user details
 type WorkspaceMemberWithUser struct {
 	User User `json:"user"`
@@ -83,6 +175,17 @@ type UpdateWorkspaceRequest struct {
 	IsPublic     *bool                  `json:"is_public,omitempty"`
 	ThumbnailURL *string                `json:"thumbnail_url,omitempty"`
 	Settings     map[string]interface{} `json:"settings,omitempty"`
+	// PublicAccess and FreezeRole can only be changed by the owner
+	PublicAccess *UpdatePublicAccessRequest `json:"public_access,omitempty"`
+	FreezeRole   *WorkspaceRole             `json:"freeze_role,omitempty"`
+}
+
+// UpdatePublicAccessRequest changes the public access settings of a
+// workspace, omitted fields are kept
+type UpdatePublicAccessRequest struct {
+	Role           *PublicRole `json:"role,omitempty"`
+	AllowDuplicate *bool       `json:"allow_duplicate,omitempty"`
+	AllowAnonymous *bool       `json:"allow_anonymous,omitempty"`
 }
 
 // InviteToWorkspaceRequest represents a request to invite a user to workspace
@@ -91,11 +194,35 @@ type InviteToWorkspaceRequest struct {
 	Role  WorkspaceRole `json:"role" binding:"required,oneof=editor viewer"`
 }
 
+// CreateInviteLinkRequest creates a shareable invite link
+type CreateInviteLinkRequest struct {
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+	MaxUses   *int          `json:"max_uses,omitempty"`
+	Role      WorkspaceRole `json:"role"` // editor or viewer
+}
+
+// RedeemInviteLinkRequest joins a workspace through an invite link
+type RedeemInviteLinkRequest struct {
+	Token string `json:"token"`
+}
+
 // AcceptInviteRequest represents a request to accept workspace invitation
 type AcceptInviteRequest struct {
 	Token string `json:"token" binding:"required"`
 }
 
+// RequestAccessRequest asks the owners of a workspace to join it
+type RequestAccessRequest struct {
+	Role    WorkspaceRole `json:"role,omitempty"` // editor or viewer, default viewer
+	Message string        `json:"message,omitempty"`
+}
+
+// ApproveAccessRequest approves a request to join a workspace
+type ApproveAccessRequest struct {
+	// Role overrides the role asked for
+	Role WorkspaceRole `json:"role,omitempty"`
+}
+
 // UpdateMemberRoleRequest represents a request to update member's role
 type UpdateMemberRoleRequest struct {
 	Role WorkspaceRole `json:"role" binding:"required,oneof=owner editor viewer"`
@@ -103,30 +230,46 @@ type UpdateMemberRoleRequest struct {
 
 // WorkspaceListFilter represents filters for listing workspaces
 type WorkspaceListFilter struct {
-	Query      string `form:"q"`
-	SortBy     string `form:"sort_by"`
+	Query      string `form:"q"`       // fuzzy match on name and description
+	SortBy     string `form:"sort_by"` // created_at, updated_at, name, or relevance with q
 	SortOrder  string `form:"sort_order"`
 	Limit      int    `form:"limit"`
 	Offset     int    `form:"offset"`
 	OwnedOnly  bool   `form:"owned_only"`
 	SharedOnly bool   `form:"shared_only"`
+	// Recent lists the visited workspaces only, last visited first
+	Recent bool `form:"recent"`
+	// SearchContent also matches the text of elements with q, as a weaker
+	// signal. Encrypted elements can't be matched.
+	SearchContent bool `form:"search_content"`
 }
 
 // --- Response DTOs ---
 
 // WorkspaceResponse represents workspace data in API responses
 type WorkspaceResponse struct {
-	CreatedAt    time.Time              `json:"created_at"`
-	UpdatedAt    time.Time              `json:"updated_at"`
-	Description  *string                `json:"description,omitempty"`
-	ThumbnailURL *string                `json:"thumbnail_url,omitempty"`
-	Settings     map[string]interface{} `json:"settings"`
-	UserRole     *WorkspaceRole         `json:"user_role,omitempty"`
-	Owner        *UserResponse          `json:"owner,omitempty"`
-	Name         string                 `json:"name"`
-	ID           uuid.UUID              `json:"id"`
-	OwnerID      uuid.UUID              `json:"owner_id"`
-	IsPublic     bool                   `json:"is_public"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	Description   *string                `json:"description,omitempty"`
+	ThumbnailURL  *string                `json:"thumbnail_url,omitempty"`
+	Settings      map[string]interface{} `json:"settings"`
+	UserRole      *WorkspaceRole         `json:"user_role,omitempty"`
+	Owner         *UserResponse          `json:"owner,omitempty"`
+	LastVisitedAt *time.Time             `json:"last_visited_at,omitempty"`
+	Views         *WorkspaceViewCounts   `json:"views,omitempty"` // Set for owned workspaces
+	Name          string                 `json:"name"`
+	ID            uuid.UUID              `json:"id"`
+	OwnerID       uuid.UUID              `json:"owner_id"`
+	IsPublic      bool                   `json:"is_public"`
+}
+
+// WorkspaceStats represents usage statistics of a workspace
+type WorkspaceStats struct {
+	MemberCount       int   `json:"member_count"`
+	ElementCount      int   `json:"element_count"`
+	AssetCount        int   `json:"asset_count"`
+	StorageUsedBytes  int64 `json:"storage_used_bytes"`
+	StorageQuotaBytes int64 `json:"storage_quota_bytes"` // 0 means unlimited
 }
 
 // WorkspaceListResponse represents paginated list of workspaces
@@ -145,6 +288,29 @@ type WorkspaceMemberResponse struct {
 	User     *UserResponse `json:"user"`
 }
 
+// MemberSuggestion is a member matching a mention being typed
+type MemberSuggestion struct {
+	AvatarURL *string   `json:"avatar_url,omitempty"`
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// Contributor is a user that created or edited elements on a board, to
+// credit authors. ElementsEdited counts the elements they changed after
+// creation, theirs or others'. Only elements still on the board count.
+type Contributor struct {
+	FirstContributionAt time.Time `json:"first_contribution_at"`
+	LastContributionAt  time.Time `json:"last_contribution_at"`
+	AvatarURL           *string   `json:"avatar_url,omitempty"`
+	Name                string    `json:"name"`
+	Username            string    `json:"username"`
+	ElementsCreated     int       `json:"elements_created"`
+	ElementsEdited      int       `json:"elements_edited"`
+	UserID              uuid.UUID `json:"user_id"`
+	IsBot               bool      `json:"is_bot"`
+}
+
 // WorkspaceInviteResponse represents workspace invite in API responses
 type WorkspaceInviteResponse struct {
 	ExpiresAt time.Time     `json:"expires_at"`
@@ -153,6 +319,26 @@ type WorkspaceInviteResponse struct {
 	Role      WorkspaceRole `json:"role"`
 	ID        uuid.UUID     `json:"id"`
 	CreatedBy *UserResponse `json:"created_by"`
+	// Suppression is set when invitation emails to the address are suppressed
+	Suppression *EmailSuppression `json:"suppression,omitempty"`
+}
+
+// UserInviteResponse is a pending invitation addressed to the current user
+type UserInviteResponse struct {
+	ExpiresAt time.Time       `json:"expires_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	InvitedBy *UserResponse   `json:"invited_by,omitempty"`
+	Workspace InviteWorkspace `json:"workspace"`
+	Role      WorkspaceRole   `json:"role"`
+	ID        uuid.UUID       `json:"id"`
+}
+
+// InviteWorkspace is the workspace an invitation is for
+type InviteWorkspace struct {
+	Description  *string   `json:"description,omitempty"`
+	ThumbnailURL *string   `json:"thumbnail_url,omitempty"`
+	Name         string    `json:"name"`
+	ID           uuid.UUID `json:"id"`
 }
 
 // InviteTokenResponse represents response with invitation token
@@ -161,3 +347,40 @@ type InviteTokenResponse struct {
 	ExpiresAt time.Time `json:"expires_at"`
 	InviteURL string    `json:"invite_url"`
 }
+
+// InviteLinkTokenResponse is a new invite link with its token, which is
+// only returned once
+type InviteLinkTokenResponse struct {
+	Link      *WorkspaceInviteLink `json:"link"`
+	Token     string               `json:"token"`
+	InviteURL string               `json:"invite_url"`
+}
+
+// WorkspaceIPRange is a network a workspace may be accessed from
+type WorkspaceIPRange struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CIDR        string     `json:"cidr"`
+	Description string     `json:"description"`
+	ID          uuid.UUID  `json:"id"`
+}
+
+// IPAllowlistResponse is the allowlist of a workspace with the address the
+// caller is seen from, so owners can tell which range they need
+type IPAllowlistResponse struct {
+	Ranges   []WorkspaceIPRange `json:"ranges"`
+	ClientIP string             `json:"client_ip"`
+}
+
+// IPRangeInput is a range of an allowlist update. A single address is
+// accepted as a range of one.
+type IPRangeInput struct {
+	CIDR        string `json:"cidr"`
+	Description string `json:"description"`
+}
+
+// UpdateIPAllowlistRequest replaces the allowlist of a workspace. An empty
+// list lifts the restriction.
+type UpdateIPAllowlistRequest struct {
+	Ranges []IPRangeInput `json:"ranges"`
+}
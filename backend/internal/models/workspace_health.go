@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Codes of workspace health recommendations
+const (
+	HealthPurgeTrash         = "purge_trash"
+	HealthCompactOperations  = "compact_operations"
+	HealthSnapshotRetention  = "set_snapshot_retention"
+	HealthSplitLargeElements = "split_large_elements"
+	HealthFlattenGroups      = "flatten_groups"
+	HealthSplitBoard         = "split_board"
+)
+
+// DataSize is the number of rows or objects of a workspace and their size
+// in bytes. Row sizes are as stored by PostgreSQL, before compression.
+type DataSize struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// WorkspaceSizes is the data a workspace keeps, by kind
+type WorkspaceSizes struct {
+	OldestOperationAt *time.Time `json:"oldest_operation_at,omitempty"`
+	Elements          DataSize   `json:"elements"`
+	// TrashedElements are deleted elements kept so they can be restored
+	TrashedElements DataSize `json:"trashed_elements"`
+	Operations      DataSize `json:"operations"`
+	// Snapshots counts stored payloads compressed, inline ones as stored
+	Snapshots DataSize `json:"snapshots"`
+	Assets    DataSize `json:"assets"`
+	// TrashedAssets are deleted assets waiting to be purged
+	TrashedAssets DataSize `json:"trashed_assets"`
+}
+
+// ElementSize is the stored size of an element
+type ElementSize struct {
+	ElementType ElementType `json:"element_type"`
+	Bytes       int64       `json:"bytes"`
+	ID          uuid.UUID   `json:"id"`
+}
+
+// HealthRecommendation is something owners can do to keep a board fast
+type HealthRecommendation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WorkspaceHealth is the size of a workspace and how to keep it performant
+type WorkspaceHealth struct {
+	GeneratedAt     time.Time      `json:"generated_at"`
+	Sizes           WorkspaceSizes `json:"sizes"`
+	LargestElements []ElementSize  `json:"largest_elements"`
+	// MaxGroupDepth is how deep groups nest, 0 without groups.
+	// DeepestElementID is an element at that depth.
+	DeepestElementID *uuid.UUID             `json:"deepest_element_id,omitempty"`
+	MaxGroupDepth    int                    `json:"max_group_depth"`
+	Recommendations  []HealthRecommendation `json:"recommendations"`
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/database"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// clickHouseTimeFormat formats DateTime64 query parameters
+const clickHouseTimeFormat = "2006-01-02 15:04:05.000"
+
+// AnalyticsRepository handles the analytics tables in ClickHouse
+type AnalyticsRepository struct {
+	ch             *database.ClickHouse
+	migrationsPath string
+}
+
+// NewAnalyticsRepository creates a new analytics repository. migrationsPath
+// holds the ClickHouse schema.
+func NewAnalyticsRepository(ch *database.ClickHouse, migrationsPath string) *AnalyticsRepository {
+	return &AnalyticsRepository{ch: ch, migrationsPath: migrationsPath}
+}
+
+// EnsureSchema creates the analytics tables that don't exist yet
+func (r *AnalyticsRepository) EnsureSchema(ctx context.Context) error {
+	return database.MigrateClickHouse(ctx, r.ch, r.migrationsPath)
+}
+
+// InsertRows writes JSON encoded rows into table in a single insert
+func (r *AnalyticsRepository) InsertRows(ctx context.Context, table string, rows []json.RawMessage) error {
+	var body bytes.Buffer
+	for _, row := range rows {
+		body.Write(row)
+		body.WriteByte('\n')
+	}
+
+	if err := r.ch.Insert(ctx, table, &body); err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", table, err)
+	}
+	return nil
+}
+
+// GetDailyActiveUsers counts the signed in users that edited or were present
+// in a workspace per day of [from, to). Days without activity are left out.
+func (r *AnalyticsRepository) GetDailyActiveUsers(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+) ([]models.DailyCount, error) {
+	query := `
+		SELECT toString(day) AS date, uniqExact(user_id) AS count
+		FROM (
+			SELECT toDate(time) AS day, user_id
+			FROM element_operations
+			WHERE workspace_id = {workspace_id:UUID}
+				AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+			UNION ALL
+			SELECT toDate(joined_at) AS day, user_id
+			FROM presence
+			WHERE workspace_id = {workspace_id:UUID} AND NOT anonymous
+				AND joined_at >= {from:DateTime64(3)} AND joined_at < {to:DateTime64(3)}
+		)
+		GROUP BY day
+		ORDER BY day
+	`
+
+	var counts []models.DailyCount
+	err := r.query(ctx, query, rangeParams(workspaceID, from, to), func(row []byte) error {
+		var count models.DailyCount
+		if err := json.Unmarshal(row, &count); err != nil {
+			return err
+		}
+		counts = append(counts, count)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily active users: %w", err)
+	}
+	return counts, nil
+}
+
+// GetDailyEdits counts the element operations of a workspace per day of
+// [from, to). Days without edits are left out.
+func (r *AnalyticsRepository) GetDailyEdits(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+) ([]models.DailyCount, error) {
+	query := `
+		SELECT toString(toDate(time)) AS date, count() AS count
+		FROM element_operations
+		WHERE workspace_id = {workspace_id:UUID}
+			AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+		GROUP BY date
+		ORDER BY date
+	`
+
+	var counts []models.DailyCount
+	err := r.query(ctx, query, rangeParams(workspaceID, from, to), func(row []byte) error {
+		var count models.DailyCount
+		if err := json.Unmarshal(row, &count); err != nil {
+			return err
+		}
+		counts = append(counts, count)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily edits: %w", err)
+	}
+	return counts, nil
+}
+
+// GetHourlyEdits counts the element operations of a workspace in [from, to)
+// by UTC hour of the day. Hours without edits are left out.
+func (r *AnalyticsRepository) GetHourlyEdits(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+) ([]models.HourlyCount, error) {
+	query := `
+		SELECT toHour(time) AS hour, count() AS count
+		FROM element_operations
+		WHERE workspace_id = {workspace_id:UUID}
+			AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+		GROUP BY hour
+		ORDER BY hour
+	`
+
+	var counts []models.HourlyCount
+	err := r.query(ctx, query, rangeParams(workspaceID, from, to), func(row []byte) error {
+		var count models.HourlyCount
+		if err := json.Unmarshal(row, &count); err != nil {
+			return err
+		}
+		counts = append(counts, count)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly edits: %w", err)
+	}
+	return counts, nil
+}
+
+// GetDailyViews counts the views of a workspace per day of [from, to).
+// Days without views are left out.
+func (r *AnalyticsRepository) GetDailyViews(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+) ([]models.DailyCount, error) {
+	query := `
+		SELECT toString(toDate(time)) AS date, count() AS count
+		FROM workspace_views
+		WHERE workspace_id = {workspace_id:UUID}
+			AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+		GROUP BY date
+		ORDER BY date
+	`
+
+	counts, err := r.queryDailyCounts(ctx, query, rangeParams(workspaceID, from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily views: %w", err)
+	}
+	return counts, nil
+}
+
+// GetDailyUniqueViewers counts the distinct viewers of a workspace per day
+// of [from, to). Days without views are left out.
+func (r *AnalyticsRepository) GetDailyUniqueViewers(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+) ([]models.DailyCount, error) {
+	query := `
+		SELECT toString(toDate(time)) AS date, uniqExact(viewer) AS count
+		FROM workspace_views
+		WHERE workspace_id = {workspace_id:UUID}
+			AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+		GROUP BY date
+		ORDER BY date
+	`
+
+	counts, err := r.queryDailyCounts(ctx, query, rangeParams(workspaceID, from, to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily unique viewers: %w", err)
+	}
+	return counts, nil
+}
+
+// GetCursorBounds returns the area the cursor samples of a workspace in
+// [from, to) fell in, nil if there are none. An empty kind covers all kinds.
+func (r *AnalyticsRepository) GetCursorBounds(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+	kind string,
+) (*models.CursorBounds, error) {
+	query := `
+		SELECT min(x) AS min_x, min(y) AS min_y, max(x) AS max_x, max(y) AS max_y, count() AS samples
+		FROM cursor_samples
+		WHERE workspace_id = {workspace_id:UUID}
+			AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+			AND ({kind:String} = '' OR kind = {kind:String})
+	`
+
+	params := rangeParams(workspaceID, from, to)
+	params["kind"] = kind
+
+	var bounds *models.CursorBounds
+	err := r.query(ctx, query, params, func(row []byte) error {
+		var b models.CursorBounds
+		if err := json.Unmarshal(row, &b); err != nil {
+			return err
+		}
+		bounds = &b
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cursor bounds: %w", err)
+	}
+	if bounds == nil || bounds.Samples == 0 {
+		return nil, nil
+	}
+	return bounds, nil
+}
+
+// GetCursorDensity counts the cursor samples of a workspace in [from, to)
+// per square cell of cellSize, counted from originX, originY. Cells without
+// samples are left out. An empty kind covers all kinds.
+func (r *AnalyticsRepository) GetCursorDensity(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+	kind string,
+	originX, originY, cellSize float64,
+) ([]models.HeatmapCell, error) {
+	query := `
+		SELECT
+			toInt64(floor((x - {origin_x:Float64}) / {cell_size:Float64})) AS "column",
+			toInt64(floor((y - {origin_y:Float64}) / {cell_size:Float64})) AS "row",
+			count() AS count
+		FROM cursor_samples
+		WHERE workspace_id = {workspace_id:UUID}
+			AND time >= {from:DateTime64(3)} AND time < {to:DateTime64(3)}
+			AND ({kind:String} = '' OR kind = {kind:String})
+		GROUP BY "column", "row"
+	`
+
+	params := rangeParams(workspaceID, from, to)
+	params["kind"] = kind
+	params["origin_x"] = strconv.FormatFloat(originX, 'g', -1, 64)
+	params["origin_y"] = strconv.FormatFloat(originY, 'g', -1, 64)
+	params["cell_size"] = strconv.FormatFloat(cellSize, 'g', -1, 64)
+
+	var cells []models.HeatmapCell
+	err := r.query(ctx, query, params, func(row []byte) error {
+		var cell models.HeatmapCell
+		if err := json.Unmarshal(row, &cell); err != nil {
+			return err
+		}
+		cells = append(cells, cell)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cursor density: %w", err)
+	}
+	return cells, nil
+}
+
+// queryDailyCounts runs a query returning date and count columns
+func (r *AnalyticsRepository) queryDailyCounts(
+	ctx context.Context,
+	query string,
+	params map[string]string,
+) ([]models.DailyCount, error) {
+	var counts []models.DailyCount
+	err := r.query(ctx, query, params, func(row []byte) error {
+		var count models.DailyCount
+		if err := json.Unmarshal(row, &count); err != nil {
+			return err
+		}
+		counts = append(counts, count)
+		return nil
+	})
+	return counts, err
+}
+
+// query runs a ClickHouse query and passes each JSON row to scan
+func (r *AnalyticsRepository) query(
+	ctx context.Context,
+	query string,
+	params map[string]string,
+	scan func(row []byte) error,
+) error {
+	rows, err := r.ch.Query(ctx, query, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scanner := bufio.NewScanner(rows)
+	for scanner.Scan() {
+		if err := scan(scanner.Bytes()); err != nil {
+			return fmt.Errorf("failed to decode row: %w", err)
+		}
+	}
+	return scanner.Err()
+}
+
+func rangeParams(workspaceID uuid.UUID, from, to time.Time) map[string]string {
+	return map[string]string{
+		"workspace_id": workspaceID.String(),
+		"from":         from.UTC().Format(clickHouseTimeFormat),
+		"to":           to.UTC().Format(clickHouseTimeFormat),
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, workspace_id, user_id, name, prefix, key_hash, last_used_at, created_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.WorkspaceID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateAPIKey creates a new API key
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, workspace_id, user_id, name, prefix, key_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		key.ID,
+		key.WorkspaceID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of the key, nil if it
+// doesn't exist
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, keyHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys retrieves all API keys of a workspace
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context, workspaceID uuid.UUID) ([]models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE workspace_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// MarkAPIKeyUsed records that an API key authenticated a request. The time
+// is updated at most once a minute, polling keys are used all the time.
+func (r *APIKeyRepository) MarkAPIKeyUsed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIKey deletes an API key. Returns false if it doesn't exist.
+func (r *APIKeyRepository) DeleteAPIKey(ctx context.Context, workspaceID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM api_keys WHERE workspace_id = $1 AND id = $2`, workspaceID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API key: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -11,24 +14,74 @@ import (
 	"github.com/bifshteksex/hertz-board/internal/models"
 )
 
+// ErrStorageQuotaExceeded is returned when an asset does not fit in the workspace quota
+var ErrStorageQuotaExceeded = errors.New("workspace storage quota exceeded")
+
+const (
+	defaultAssetPageSize = 50
+	maxAssetPageSize     = 200
+)
+
+// likeEscaper escapes LIKE wildcards in user input, backslash is the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type AssetRepository struct {
-	db *pgxpool.Pool
+	db   *pgxpool.Pool
+	read *readPool
 }
 
-func NewAssetRepository(db *pgxpool.Pool) *AssetRepository {
-	return &AssetRepository{db: db}
+// NewAssetRepository creates the repository. Listings and counts are read
+// from replica when it is not nil.
+func NewAssetRepository(db, replica *pgxpool.Pool) *AssetRepository {
+	return &AssetRepository{db: db, read: newReadPool(db, replica)}
 }
 
-// CreateAsset creates a new asset record
+// CreateAsset creates a new asset record and adds its size to the workspace usage
 func (r *AssetRepository) CreateAsset(ctx context.Context, asset *models.Asset) error {
+	return r.CreateAssetWithinQuota(ctx, asset, 0)
+}
+
+// CreateAssetWithinQuota creates a new asset record if the workspace usage
+// stays within quota bytes. A quota of zero or less means unlimited.
+func (r *AssetRepository) CreateAssetWithinQuota(ctx context.Context, asset *models.Asset, quota int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	usageQuery := `
+		UPDATE workspaces
+		SET storage_used_bytes = storage_used_bytes + $2
+		WHERE id = $1 AND ($3 <= 0 OR storage_used_bytes + $2 <= $3)
+	`
+
+	result, err := tx.Exec(ctx, usageQuery, asset.WorkspaceID, asset.Size, quota)
+	if err != nil {
+		return fmt.Errorf("failed to update storage usage: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrStorageQuotaExceeded
+	}
+
 	query := `
 		INSERT INTO assets (
-			id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height,
+			parent_asset_id, page_number, page_count, duration_ms, status, scan_status, attribution
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING created_at
 	`
 
-	return r.db.QueryRow(ctx, query,
+	if asset.Status == "" {
+		asset.Status = models.AssetStatusReady
+	}
+	if asset.ScanStatus == "" {
+		asset.ScanStatus = models.ScanStatusPending
+	}
+
+	err = tx.QueryRow(ctx, query,
 		asset.ID,
 		asset.WorkspaceID,
 		asset.UploadedBy,
@@ -39,13 +92,43 @@ func (r *AssetRepository) CreateAsset(ctx context.Context, asset *models.Asset)
 		asset.ThumbnailURL,
 		asset.Width,
 		asset.Height,
+		asset.ParentAssetID,
+		asset.PageNumber,
+		asset.PageCount,
+		asset.DurationMs,
+		asset.Status,
+		asset.ScanStatus,
+		asset.Attribution,
 	).Scan(&asset.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert asset: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetStorageUsage returns the number of bytes used by assets in a workspace
+func (r *AssetRepository) GetStorageUsage(ctx context.Context, workspaceID uuid.UUID) (int64, error) {
+	query := `SELECT storage_used_bytes FROM workspaces WHERE id = $1`
+
+	var used int64
+	if err := r.db.QueryRow(ctx, query, workspaceID).Scan(&used); err != nil {
+		return 0, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	return used, nil
 }
 
 // GetAssetByID retrieves an asset by ID
 func (r *AssetRepository) GetAssetByID(ctx context.Context, id uuid.UUID) (*models.Asset, error) {
 	query := `
-		SELECT id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height, created_at, deleted_at
+		SELECT id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height,
+		       parent_asset_id, page_number, page_count, duration_ms, status, scan_status, variants, attribution, ocr_text,
+		       created_at, deleted_at
 		FROM assets
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -67,6 +150,15 @@ func (r *AssetRepository) scanAsset(row pgx.Row) (*models.Asset, error) {
 		&asset.ThumbnailURL,
 		&asset.Width,
 		&asset.Height,
+		&asset.ParentAssetID,
+		&asset.PageNumber,
+		&asset.PageCount,
+		&asset.DurationMs,
+		&asset.Status,
+		&asset.ScanStatus,
+		&asset.Variants,
+		&asset.Attribution,
+		&asset.OCRText,
 		&asset.CreatedAt,
 		&asset.DeletedAt,
 	)
@@ -97,6 +189,15 @@ func (r *AssetRepository) scanAssets(rows pgx.Rows) ([]models.Asset, error) {
 			&asset.ThumbnailURL,
 			&asset.Width,
 			&asset.Height,
+			&asset.ParentAssetID,
+			&asset.PageNumber,
+			&asset.PageCount,
+			&asset.DurationMs,
+			&asset.Status,
+			&asset.ScanStatus,
+			&asset.Variants,
+			&asset.Attribution,
+			&asset.OCRText,
 			&asset.CreatedAt,
 			&asset.DeletedAt,
 		)
@@ -109,59 +210,402 @@ func (r *AssetRepository) scanAssets(rows pgx.Rows) ([]models.Asset, error) {
 	return assets, rows.Err()
 }
 
-// GetAssetsByWorkspace retrieves all assets for a workspace
-func (r *AssetRepository) GetAssetsByWorkspace(ctx context.Context, workspaceID uuid.UUID) ([]models.Asset, error) {
+// ListAssets retrieves a page of workspace assets matching the filter along
+// with the total number of matches
+func (r *AssetRepository) ListAssets(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	filter models.AssetListFilter,
+) ([]models.Asset, int, error) {
+	where, args, err := assetListWhere(`
+		WHERE workspace_id = $1 AND deleted_at IS NULL AND parent_asset_id IS NULL
+		  AND scan_status <> 'infected'
+	`, []interface{}{workspaceID}, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	argCount := len(args)
+
+	var total int
+	if err := r.read.QueryRow(ctx, "SELECT COUNT(*) FROM assets"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count assets: %w", err)
+	}
+
+	orderBy, limit, offset := assetListPage(filter)
+
 	query := `
-		SELECT id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height, created_at, deleted_at
+		SELECT id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height,
+		       parent_asset_id, page_number, page_count, duration_ms, status, scan_status, variants, attribution, ocr_text,
+		       created_at, deleted_at
+		FROM assets` + where + fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, argCount+1, argCount+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.read.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query assets: %w", err)
+	}
+	defer rows.Close()
+
+	assets, err := r.scanAssets(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return assets, total, nil
+}
+
+// ListAssetsByWorkspaces retrieves a page of assets of each of several
+// workspaces with one count and one list query. Every workspace is paged
+// separately, like ListAssets does.
+func (r *AssetRepository) ListAssetsByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+	filter models.AssetListFilter,
+) (map[uuid.UUID]*models.AssetPage, error) {
+	where, args, err := assetListWhere(`
+		WHERE workspace_id = ANY($1) AND deleted_at IS NULL AND parent_asset_id IS NULL
+		  AND scan_status <> 'infected'
+	`, []interface{}{workspaceIDs}, filter)
+	if err != nil {
+		return nil, err
+	}
+	argCount := len(args)
+
+	pages := make(map[uuid.UUID]*models.AssetPage, len(workspaceIDs))
+	for _, workspaceID := range workspaceIDs {
+		pages[workspaceID] = &models.AssetPage{}
+	}
+
+	countRows, err := r.read.Query(ctx, "SELECT workspace_id, COUNT(*) FROM assets"+where+" GROUP BY workspace_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count assets: %w", err)
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var workspaceID uuid.UUID
+		var total int
+		if err := countRows.Scan(&workspaceID, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan asset count: %w", err)
+		}
+		if page, ok := pages[workspaceID]; ok {
+			page.Total = total
+		}
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating asset counts: %w", err)
+	}
+
+	orderBy, limit, offset := assetListPage(filter)
+	columns := `id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height,
+		       parent_asset_id, page_number, page_count, duration_ms, status, scan_status, variants, attribution, ocr_text,
+		       created_at, deleted_at`
+	query := `
+		SELECT ` + columns + `
+		FROM (
+			SELECT ` + columns + `,
+			       ROW_NUMBER() OVER (PARTITION BY workspace_id ORDER BY ` + orderBy + `) AS position
+			FROM assets` + where + `
+		) a` + fmt.Sprintf(" WHERE position > $%d AND position <= $%d ORDER BY workspace_id, position", argCount+1, argCount+2)
+	args = append(args, offset, offset+limit)
+
+	rows, err := r.read.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %w", err)
+	}
+	defer rows.Close()
+
+	assets, err := r.scanAssets(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range assets {
+		if page, ok := pages[assets[i].WorkspaceID]; ok {
+			page.Assets = append(page.Assets, assets[i])
+		}
+	}
+
+	return pages, nil
+}
+
+// assetListWhere appends the conditions of an asset filter to a WHERE
+// clause whose arguments are args
+func assetListWhere(where string, args []interface{}, filter models.AssetListFilter) (string, []interface{}, error) {
+	// Filenames match partially, recognized text by its words
+	if filter.Query != "" {
+		where += fmt.Sprintf(
+			" AND (filename ILIKE $%d OR search_vector @@ websearch_to_tsquery('simple', $%d))", len(args)+1, len(args)+2,
+		)
+		args = append(args, "%"+likeEscaper.Replace(filter.Query)+"%", filter.Query)
+	}
+
+	if filter.ContentType != "" {
+		if family, ok := strings.CutSuffix(filter.ContentType, "/*"); ok {
+			args = append(args, likeEscaper.Replace(family)+"/%")
+			where += fmt.Sprintf(" AND content_type LIKE $%d", len(args))
+		} else {
+			args = append(args, filter.ContentType)
+			where += fmt.Sprintf(" AND content_type = $%d", len(args))
+		}
+	}
+
+	if filter.UploadedBy != "" {
+		uploaderID, err := uuid.Parse(filter.UploadedBy)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid uploaded_by: %w", err)
+		}
+		args = append(args, uploaderID)
+		where += fmt.Sprintf(" AND uploaded_by = $%d", len(args))
+	}
+
+	return where, args, nil
+}
+
+// assetListPage returns the ORDER BY expression, limit and offset of an
+// asset filter
+func assetListPage(filter models.AssetListFilter) (orderBy string, limit, offset int) {
+	sortBy := "created_at"
+	if filter.SortBy == "size" || filter.SortBy == "filename" {
+		sortBy = filter.SortBy
+	}
+
+	sortOrder := "DESC"
+	if filter.SortOrder == "asc" {
+		sortOrder = "ASC"
+	}
+
+	limit = defaultAssetPageSize
+	if filter.Limit > 0 && filter.Limit <= maxAssetPageSize {
+		limit = filter.Limit
+	}
+
+	if filter.Offset > 0 {
+		offset = filter.Offset
+	}
+
+	return sortBy + " " + sortOrder + ", id ASC", limit, offset
+}
+
+// GetAssetPages retrieves the rendered pages of a document asset
+func (r *AssetRepository) GetAssetPages(ctx context.Context, parentID uuid.UUID) ([]models.Asset, error) {
+	query := `
+		SELECT id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height,
+		       parent_asset_id, page_number, page_count, duration_ms, status, scan_status, variants, attribution, ocr_text,
+		       created_at, deleted_at
 		FROM assets
-		WHERE workspace_id = $1 AND deleted_at IS NULL
-		ORDER BY created_at DESC
+		WHERE parent_asset_id = $1 AND deleted_at IS NULL
+		ORDER BY page_number ASC
 	`
 
-	rows, err := r.db.Query(ctx, query, workspaceID)
+	rows, err := r.db.Query(ctx, query, parentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query assets: %w", err)
+		return nil, fmt.Errorf("failed to query asset pages: %w", err)
 	}
 	defer rows.Close()
 
 	return r.scanAssets(rows)
 }
 
-// DeleteAsset soft deletes an asset
+// UpdateAssetStatus updates the processing status and page count of an asset
+func (r *AssetRepository) UpdateAssetStatus(ctx context.Context, id uuid.UUID, status string, pageCount *int) error {
+	query := `
+		UPDATE assets
+		SET status = $2, page_count = COALESCE($3, page_count)
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, status, pageCount); err != nil {
+		return fmt.Errorf("failed to update asset status: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAssetMedia stores metadata extracted from a processed media file
+func (r *AssetRepository) UpdateAssetMedia(ctx context.Context, asset *models.Asset) error {
+	query := `
+		UPDATE assets
+		SET width = $2, height = $3, duration_ms = $4, thumbnail_url = $5, status = $6
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		asset.ID,
+		asset.Width,
+		asset.Height,
+		asset.DurationMs,
+		asset.ThumbnailURL,
+		asset.Status,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update asset media: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateScanStatus stores the antivirus scan result of an asset and its pages
+func (r *AssetRepository) UpdateScanStatus(ctx context.Context, id uuid.UUID, status string, signature *string) error {
+	query := `
+		UPDATE assets
+		SET scan_status = $2, scan_signature = $3
+		WHERE id = $1 OR parent_asset_id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, status, signature); err != nil {
+		return fmt.Errorf("failed to update scan status: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAssetVariants stores the optimized renditions of an image
+func (r *AssetRepository) UpdateAssetVariants(ctx context.Context, id uuid.UUID, variants models.AssetVariants) error {
+	query := `
+		UPDATE assets
+		SET variants = $2
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, variants); err != nil {
+		return fmt.Errorf("failed to update asset variants: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAssetOCRText stores the text recognized in an image, which
+// updates its search index
+func (r *AssetRepository) UpdateAssetOCRText(ctx context.Context, id uuid.UUID, text string) error {
+	query := `
+		UPDATE assets
+		SET ocr_text = $2
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, text); err != nil {
+		return fmt.Errorf("failed to update asset text: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAsset soft deletes an asset together with its pages and releases
+// their size from the workspace usage
 func (r *AssetRepository) DeleteAsset(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
 	query := `
 		UPDATE assets
 		SET deleted_at = NOW()
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE (id = $1 OR parent_asset_id = $1) AND deleted_at IS NULL
+		RETURNING workspace_id, size
 	`
 
-	result, err := r.db.Exec(ctx, query, id)
+	rows, err := tx.Query(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete asset: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
+	var workspaceID uuid.UUID
+	var freed int64
+	deleted := 0
+	for rows.Next() {
+		var size int64
+		if err := rows.Scan(&workspaceID, &size); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan deleted asset: %w", err)
+		}
+		freed += size
+		deleted++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+
+	if deleted == 0 {
 		return fmt.Errorf("asset not found or already deleted")
 	}
 
+	usageQuery := `
+		UPDATE workspaces
+		SET storage_used_bytes = GREATEST(storage_used_bytes - $2, 0)
+		WHERE id = $1
+	`
+
+	if _, err := tx.Exec(ctx, usageQuery, workspaceID, freed); err != nil {
+		return fmt.Errorf("failed to update storage usage: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+// GetDeletedAssetsBefore retrieves assets soft-deleted before the cutoff.
+// Pages are returned before their documents so objects are removed bottom-up.
+func (r *AssetRepository) GetDeletedAssetsBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Asset, error) {
+	query := `
+		SELECT id, workspace_id, uploaded_by, filename, content_type, size, url, thumbnail_url, width, height,
+		       parent_asset_id, page_number, page_count, duration_ms, status, scan_status, variants, attribution, ocr_text,
+		       created_at, deleted_at
+		FROM assets
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		ORDER BY (parent_asset_id IS NULL), deleted_at ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted assets: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanAssets(rows)
+}
+
+// PurgeAsset permanently removes a soft-deleted asset record. Documents are
+// kept until all of their pages have been purged.
+func (r *AssetRepository) PurgeAsset(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		DELETE FROM assets a
+		WHERE a.id = $1
+		  AND a.deleted_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM assets p WHERE p.parent_asset_id = a.id)
+	`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to purge asset: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
 // GetOrphanedAssets retrieves assets that are not referenced by any canvas element
 func (r *AssetRepository) GetOrphanedAssets(ctx context.Context, workspaceID uuid.UUID) ([]models.Asset, error) {
 	query := `
 		SELECT a.id, a.workspace_id, a.uploaded_by, a.filename, a.content_type,
 		       a.size, a.url, a.thumbnail_url, a.width, a.height,
+		       a.parent_asset_id, a.page_number, a.page_count, a.duration_ms, a.status, a.scan_status, a.variants, a.attribution, a.ocr_text,
 		       a.created_at, a.deleted_at
 		FROM assets a
 		WHERE a.workspace_id = $1
 		  AND a.deleted_at IS NULL
+		  AND a.parent_asset_id IS NULL
 		  AND NOT EXISTS (
-		      SELECT 1 FROM canvas_elements ce
-		      WHERE ce.workspace_id = a.workspace_id
-		        AND ce.deleted_at IS NULL
-		        AND ce.element_type = 'image'
-		        AND ce.element_data->>'asset_id' = a.id::text
+		      SELECT 1 FROM asset_references r
+		      JOIN assets ra ON ra.id = r.asset_id
+		      WHERE ra.id = a.id OR ra.parent_asset_id = a.id
 		  )
 		  AND a.created_at < NOW() - INTERVAL '1 hour' -- Grace period for upload
 	`
@@ -174,3 +618,61 @@ func (r *AssetRepository) GetOrphanedAssets(ctx context.Context, workspaceID uui
 
 	return r.scanAssets(rows)
 }
+
+// GetAssetUsageCounts returns how many canvas elements reference each asset.
+// References to the pages of a document count towards the document.
+func (r *AssetRepository) GetAssetUsageCounts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT COALESCE(a.parent_asset_id, a.id) AS root_id, COUNT(*)
+		FROM asset_references r
+		JOIN assets a ON a.id = r.asset_id
+		WHERE COALESCE(a.parent_asset_id, a.id) = ANY($1)
+		GROUP BY root_id
+	`
+
+	rows, err := r.read.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query asset usage: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan asset usage: %w", err)
+		}
+		counts[id] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// GetAssetReferences returns the canvas elements that reference an asset or its pages
+func (r *AssetRepository) GetAssetReferences(ctx context.Context, assetID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT r.element_id
+		FROM asset_references r
+		JOIN assets a ON a.id = r.asset_id
+		WHERE a.id = $1 OR a.parent_asset_id = $1
+		ORDER BY r.created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query asset references: %w", err)
+	}
+	defer rows.Close()
+
+	var elementIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan asset reference: %w", err)
+		}
+		elementIDs = append(elementIDs, id)
+	}
+
+	return elementIDs, rows.Err()
+}
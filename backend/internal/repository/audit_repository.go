@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// AuditRepository handles the audit log and the positions of the sinks it
+// is exported to
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Insert appends an event to the audit log
+func (r *AuditRepository) Insert(ctx context.Context, event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (
+			id, action, actor_type, actor_id, workspace_id, target_type, target_id,
+			ip_address, user_agent, request_id, metadata
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11)
+		RETURNING seq, created_at
+	`
+
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+
+	err := r.db.QueryRow(ctx, query,
+		event.ID, event.Action, event.ActorType, event.ActorID, event.WorkspaceID,
+		event.TargetType, event.TargetID, event.IPAddress, event.UserAgent, event.RequestID, metadata,
+	).Scan(&event.Seq, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	return nil
+}
+
+// EnsureCursor registers a sink, which then ships the log from its start
+func (r *AuditRepository) EnsureCursor(ctx context.Context, sink string) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO audit_export_cursors (sink) VALUES ($1) ON CONFLICT (sink) DO NOTHING`, sink)
+	if err != nil {
+		return fmt.Errorf("failed to create audit cursor: %w", err)
+	}
+	return nil
+}
+
+// ExportPending locks the cursor of a sink and hands up to limit events
+// after it to send, in log order. Only events older than settle are read:
+// sequence numbers are taken before the commit, so a fresh event can still
+// be overtaken by one with a lower number. The cursor moves past the
+// events when send succeeds. When another instance holds the cursor
+// nothing is sent and ok is false.
+func (r *AuditRepository) ExportPending(
+	ctx context.Context,
+	sink string,
+	limit int,
+	settle time.Duration,
+	send func(events []models.AuditEvent) error,
+) (sent int, ok bool, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var lastSeq int64
+	err = tx.QueryRow(ctx,
+		`SELECT last_seq FROM audit_export_cursors WHERE sink = $1 FOR UPDATE SKIP LOCKED`, sink,
+	).Scan(&lastSeq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to lock audit cursor: %w", err)
+	}
+
+	query := `
+		SELECT seq, id, action, actor_type, actor_id, workspace_id,
+			COALESCE(target_type, ''), COALESCE(target_id, ''), COALESCE(ip_address, ''),
+			COALESCE(user_agent, ''), COALESCE(request_id, ''), metadata, created_at
+		FROM audit_events
+		WHERE seq > $1 AND created_at < NOW() - $3::interval
+		ORDER BY seq
+		LIMIT $2
+	`
+
+	rows, err := tx.Query(ctx, query, lastSeq, limit, settle)
+	if err != nil {
+		return 0, true, fmt.Errorf("failed to load audit events: %w", err)
+	}
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(
+			&event.Seq, &event.ID, &event.Action, &event.ActorType, &event.ActorID, &event.WorkspaceID,
+			&event.TargetType, &event.TargetID, &event.IPAddress,
+			&event.UserAgent, &event.RequestID, &event.Metadata, &event.CreatedAt,
+		); err != nil {
+			rows.Close()
+			return 0, true, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, true, fmt.Errorf("failed to load audit events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return 0, true, nil
+	}
+
+	if err := send(events); err != nil {
+		return 0, true, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE audit_export_cursors SET last_seq = $2, updated_at = NOW() WHERE sink = $1`,
+		sink, events[len(events)-1].Seq,
+	); err != nil {
+		return 0, true, fmt.Errorf("failed to move audit cursor: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, true, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(events), true, nil
+}
+
+// CountPending returns the number of events a sink hasn't shipped yet and
+// the age of the oldest one
+func (r *AuditRepository) CountPending(ctx context.Context, sink string) (int64, time.Duration, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(e.created_at)), 0)::float8
+		FROM audit_events e
+		JOIN audit_export_cursors c ON c.sink = $1
+		WHERE e.seq > c.last_seq
+	`
+
+	var count int64
+	var lag float64
+	if err := r.db.QueryRow(ctx, query, sink).Scan(&count, &lag); err != nil {
+		return 0, 0, fmt.Errorf("failed to count pending audit events: %w", err)
+	}
+	return count, time.Duration(lag * float64(time.Second)), nil
+}
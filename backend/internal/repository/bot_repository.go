@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type BotRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBotRepository(db *pgxpool.Pool) *BotRepository {
+	return &BotRepository{db: db}
+}
+
+const botColumns = `u.id, wm.workspace_id, u.name, u.username, u.avatar_url, wm.role, wm.invited_by, u.created_at`
+
+func scanBot(row pgx.Row) (*models.Bot, error) {
+	var bot models.Bot
+	err := row.Scan(
+		&bot.ID,
+		&bot.WorkspaceID,
+		&bot.Name,
+		&bot.Username,
+		&bot.AvatarURL,
+		&bot.Role,
+		&bot.CreatedBy,
+		&bot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+const botTokenColumns = `id, bot_id, workspace_id, name, prefix, token_hash, scopes, element_types,
+	created_by, expires_at, last_used_at, created_at`
+
+func scanBotToken(row pgx.Row) (*models.BotToken, error) {
+	var token models.BotToken
+	var elementTypes []string
+	err := row.Scan(
+		&token.ID,
+		&token.BotID,
+		&token.WorkspaceID,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		&token.Scopes,
+		&elementTypes,
+		&token.CreatedBy,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ElementTypes = make([]models.ElementType, len(elementTypes))
+	for i, t := range elementTypes {
+		token.ElementTypes[i] = models.ElementType(t)
+	}
+	return &token, nil
+}
+
+// CreateBot creates the user of a bot and makes it a member of its
+// workspace. The username of the bot is made unique with a numeric suffix.
+func (r *BotRepository) CreateBot(ctx context.Context, bot *models.Bot, email string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	userQuery := `
+		INSERT INTO users (id, email, name, username, avatar_url, provider, email_verified, is_bot)
+		VALUES ($1, $2, $3, generate_username($4), $5, 'bot', TRUE, TRUE)
+		RETURNING username, created_at
+	`
+	err = tx.QueryRow(ctx, userQuery,
+		bot.ID,
+		email,
+		bot.Name,
+		bot.Username,
+		bot.AvatarURL,
+	).Scan(&bot.Username, &bot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create bot user: %w", err)
+	}
+
+	memberQuery := `
+		INSERT INTO workspace_members (id, workspace_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.Exec(ctx, memberQuery, uuid.New(), bot.WorkspaceID, bot.ID, bot.Role, bot.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to add bot to workspace: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetBot retrieves a bot of a workspace, nil if it doesn't exist
+func (r *BotRepository) GetBot(ctx context.Context, workspaceID, botID uuid.UUID) (*models.Bot, error) {
+	query := `
+		SELECT ` + botColumns + `
+		FROM users u
+		INNER JOIN workspace_members wm ON wm.user_id = u.id
+		WHERE u.id = $1 AND wm.workspace_id = $2 AND u.is_bot AND u.deactivated_at IS NULL
+	`
+
+	bot, err := scanBot(r.db.QueryRow(ctx, query, botID, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot: %w", err)
+	}
+
+	return bot, nil
+}
+
+// ListBots retrieves the bots of a workspace
+func (r *BotRepository) ListBots(ctx context.Context, workspaceID uuid.UUID) ([]models.Bot, error) {
+	query := `
+		SELECT ` + botColumns + `
+		FROM users u
+		INNER JOIN workspace_members wm ON wm.user_id = u.id
+		WHERE wm.workspace_id = $1 AND u.is_bot AND u.deactivated_at IS NULL
+		ORDER BY u.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bots: %w", err)
+	}
+	defer rows.Close()
+
+	bots := []models.Bot{}
+	for rows.Next() {
+		bot, err := scanBot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bot: %w", err)
+		}
+		bots = append(bots, *bot)
+	}
+
+	return bots, rows.Err()
+}
+
+// DeactivateBot revokes the tokens of a bot, removes it from its workspace
+// and deactivates its user. The user is kept so the elements it created
+// stay attributed. Returns false if the bot doesn't exist.
+func (r *BotRepository) DeactivateBot(ctx context.Context, workspaceID, botID uuid.UUID) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET deactivated_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND is_bot AND deactivated_at IS NULL
+		  AND EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $2 AND user_id = $1)
+	`, botID, workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to deactivate bot: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM bot_tokens WHERE bot_id = $1`, botID); err != nil {
+		return false, fmt.Errorf("failed to revoke bot tokens: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`, workspaceID, botID); err != nil {
+		return false, fmt.Errorf("failed to remove bot from workspace: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// CreateToken creates a new bot token
+func (r *BotRepository) CreateToken(ctx context.Context, token *models.BotToken) error {
+	query := `
+		INSERT INTO bot_tokens (id, bot_id, workspace_id, name, prefix, token_hash, scopes, element_types,
+		                        created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at
+	`
+
+	elementTypes := make([]string, len(token.ElementTypes))
+	for i, t := range token.ElementTypes {
+		elementTypes[i] = string(t)
+	}
+
+	err := r.db.QueryRow(ctx, query,
+		token.ID,
+		token.BotID,
+		token.WorkspaceID,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		token.Scopes,
+		elementTypes,
+		token.CreatedBy,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create bot token: %w", err)
+	}
+
+	return nil
+}
+
+// GetTokenByHash retrieves a bot token by the hash of the token, nil if it
+// doesn't exist
+func (r *BotRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.BotToken, error) {
+	query := `SELECT ` + botTokenColumns + ` FROM bot_tokens WHERE token_hash = $1`
+
+	token, err := scanBotToken(r.db.QueryRow(ctx, query, tokenHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot token: %w", err)
+	}
+
+	return token, nil
+}
+
+// ListTokens retrieves the tokens of a bot
+func (r *BotRepository) ListTokens(ctx context.Context, botID uuid.UUID) ([]models.BotToken, error) {
+	query := `SELECT ` + botTokenColumns + ` FROM bot_tokens WHERE bot_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, botID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.BotToken{}
+	for rows.Next() {
+		token, err := scanBotToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bot token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// MarkTokenUsed records that a bot token authenticated a request, at most
+// once a minute
+func (r *BotRepository) MarkTokenUsed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE bot_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update bot token: %w", err)
+	}
+	return nil
+}
+
+// DeleteToken deletes a token of a bot. Returns false if it doesn't exist.
+func (r *BotRepository) DeleteToken(ctx context.Context, botID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM bot_tokens WHERE bot_id = $1 AND id = $2`, botID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete bot token: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -2,25 +2,51 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/bifshteksex/hertz-board/internal/encryption"
 	"github.com/bifshteksex/hertz-board/internal/models"
 )
 
 type CanvasRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	read    *readPool
+	keyring *encryption.Keyring
 }
 
-func NewCanvasRepository(db *pgxpool.Pool) *CanvasRepository {
-	return &CanvasRepository{db: db}
+// NewCanvasRepository creates the repository. Listings and counts are read
+// from replica when it is not nil. Element data is encrypted with keyring
+// when it is not nil.
+func NewCanvasRepository(db, replica *pgxpool.Pool, keyring *encryption.Keyring) *CanvasRepository {
+	return &CanvasRepository{db: db, read: newReadPool(db, replica), keyring: keyring}
 }
 
-// CreateElement creates a new canvas element
-func (r *CanvasRepository) CreateElement(ctx context.Context, element *models.CanvasElement) error {
+// CreateElement creates a new canvas element and records the asset it
+// references. The outbox messages are written in the same transaction.
+func (r *CanvasRepository) CreateElement(
+	ctx context.Context,
+	element *models.CanvasElement,
+	outbox ...*models.OutboxMessage,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	data, err := sealData(ctx, r.keyring, element.WorkspaceID, element.ID, element.ElementData)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO canvas_elements (
 			id, workspace_id, element_type, element_data, z_index, parent_id, created_by, updated_by
@@ -28,16 +54,33 @@ func (r *CanvasRepository) CreateElement(ctx context.Context, element *models.Ca
 		RETURNING created_at, updated_at
 	`
 
-	return r.db.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		element.ID,
 		element.WorkspaceID,
 		element.ElementType,
-		element.ElementData,
+		data,
 		element.ZIndex,
 		element.ParentID,
 		element.CreatedBy,
 		element.UpdatedBy,
 	).Scan(&element.CreatedAt, &element.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create element: %w", err)
+	}
+
+	if err := syncAssetReference(ctx, tx, element); err != nil {
+		return err
+	}
+
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
 }
 
 // GetElementByID retrieves a canvas element by ID
@@ -71,6 +114,10 @@ func (r *CanvasRepository) GetElementByID(ctx context.Context, id uuid.UUID) (*m
 		return nil, fmt.Errorf("failed to get element: %w", err)
 	}
 
+	if err := r.openElement(ctx, &element); err != nil {
+		return nil, err
+	}
+
 	return &element, nil
 }
 
@@ -109,6 +156,9 @@ func (r *CanvasRepository) GetElementsByWorkspace(ctx context.Context, workspace
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan element: %w", err)
 		}
+		if err := r.openElement(ctx, &element); err != nil {
+			return nil, err
+		}
 		elements = append(elements, element)
 	}
 
@@ -119,8 +169,73 @@ func (r *CanvasRepository) GetElementsByWorkspace(ctx context.Context, workspace
 	return elements, nil
 }
 
-// UpdateElement updates a canvas element
+// GetElementsByWorkspaces retrieves the elements of several workspaces with
+// one query, in the order of GetElementsByWorkspace
+func (r *CanvasRepository) GetElementsByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+) (map[uuid.UUID][]models.CanvasElement, error) {
+	query := `
+		SELECT id, workspace_id, element_type, element_data, z_index, parent_id,
+		       created_by, updated_by, created_at, updated_at, deleted_at
+		FROM canvas_elements
+		WHERE workspace_id = ANY($1) AND deleted_at IS NULL
+		ORDER BY z_index ASC, created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query elements: %w", err)
+	}
+	defer rows.Close()
+
+	elements := make(map[uuid.UUID][]models.CanvasElement, len(workspaceIDs))
+	for rows.Next() {
+		var element models.CanvasElement
+		err := rows.Scan(
+			&element.ID,
+			&element.WorkspaceID,
+			&element.ElementType,
+			&element.ElementData,
+			&element.ZIndex,
+			&element.ParentID,
+			&element.CreatedBy,
+			&element.UpdatedBy,
+			&element.CreatedAt,
+			&element.UpdatedAt,
+			&element.DeletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan element: %w", err)
+		}
+		if err := r.openElement(ctx, &element); err != nil {
+			return nil, err
+		}
+		elements[element.WorkspaceID] = append(elements[element.WorkspaceID], element)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating elements: %w", err)
+	}
+
+	return elements, nil
+}
+
+// UpdateElement updates a canvas element and its asset reference
 func (r *CanvasRepository) UpdateElement(ctx context.Context, element *models.CanvasElement) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	data, err := sealData(ctx, r.keyring, element.WorkspaceID, element.ID, element.ElementData)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE canvas_elements
 		SET element_data = $1, z_index = $2, parent_id = $3, updated_by = $4, updated_at = NOW()
@@ -128,8 +243,8 @@ func (r *CanvasRepository) UpdateElement(ctx context.Context, element *models.Ca
 		RETURNING updated_at
 	`
 
-	err := r.db.QueryRow(ctx, query,
-		element.ElementData,
+	err = tx.QueryRow(ctx, query,
+		data,
 		element.ZIndex,
 		element.ParentID,
 		element.UpdatedBy,
@@ -143,18 +258,34 @@ func (r *CanvasRepository) UpdateElement(ctx context.Context, element *models.Ca
 		return fmt.Errorf("failed to update element: %w", err)
 	}
 
+	if err := syncAssetReference(ctx, tx, element); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-// DeleteElement soft deletes a canvas element
+// DeleteElement soft deletes a canvas element and drops its asset reference
 func (r *CanvasRepository) DeleteElement(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
 	query := `
 		UPDATE canvas_elements
 		SET deleted_at = NOW()
 		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := tx.Exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete element: %w", err)
 	}
@@ -163,6 +294,14 @@ func (r *CanvasRepository) DeleteElement(ctx context.Context, id uuid.UUID) erro
 		return fmt.Errorf("element not found or already deleted")
 	}
 
+	if err := removeAssetReference(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -184,8 +323,44 @@ func (r *CanvasRepository) HardDeleteElement(ctx context.Context, id uuid.UUID)
 
 // Batch operations
 
-// BatchCreateElements creates multiple canvas elements in a transaction
-func (r *CanvasRepository) BatchCreateElements(ctx context.Context, elements []models.CanvasElement) error {
+// BatchCreateElements creates multiple canvas elements in a transaction,
+// together with the outbox messages
+func (r *CanvasRepository) BatchCreateElements(
+	ctx context.Context,
+	elements []models.CanvasElement,
+	outbox ...*models.OutboxMessage,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := r.insertElements(ctx, tx, elements); err != nil {
+		return err
+	}
+
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceWorkspaceElements soft deletes every element of a workspace and
+// creates the given ones in a single transaction, so the board is never left
+// empty or half restored
+func (r *CanvasRepository) ReplaceWorkspaceElements(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	elements []models.CanvasElement,
+) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -195,38 +370,115 @@ func (r *CanvasRepository) BatchCreateElements(ctx context.Context, elements []m
 	}()
 
 	query := `
-		INSERT INTO canvas_elements (
-			id, workspace_id, element_type, element_data, z_index, parent_id, created_by, updated_by
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING created_at, updated_at
+		UPDATE canvas_elements
+		SET deleted_at = NOW()
+		WHERE workspace_id = $1 AND deleted_at IS NULL
 	`
 
+	if _, err := tx.Exec(ctx, query, workspaceID); err != nil {
+		return fmt.Errorf("failed to delete workspace elements: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM asset_references WHERE workspace_id = $1`, workspaceID); err != nil {
+		return fmt.Errorf("failed to delete asset references: %w", err)
+	}
+
+	if err := r.insertElements(ctx, tx, elements); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// elementCopyColumns are the columns written by insertElements
+var elementCopyColumns = []string{
+	"id", "workspace_id", "element_type", "element_data", "z_index", "parent_id",
+	"created_by", "updated_by", "created_at", "updated_at",
+}
+
+// insertElements creates elements and their asset references within tx. The
+// rows are sent with COPY, which is an order of magnitude faster than one
+// INSERT per element for large imports.
+func (r *CanvasRepository) insertElements(ctx context.Context, tx pgx.Tx, elements []models.CanvasElement) error {
+	if len(elements) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	data := make([]models.ElementData, len(elements))
 	for i := range elements {
-		err := tx.QueryRow(ctx, query,
+		elements[i].CreatedAt = now
+		elements[i].UpdatedAt = now
+
+		sealed, err := sealData(ctx, r.keyring, elements[i].WorkspaceID, elements[i].ID, elements[i].ElementData)
+		if err != nil {
+			return err
+		}
+		data[i] = sealed
+	}
+
+	source := pgx.CopyFromSlice(len(elements), func(i int) ([]any, error) {
+		return []any{
 			elements[i].ID,
 			elements[i].WorkspaceID,
 			elements[i].ElementType,
-			elements[i].ElementData,
+			data[i],
 			elements[i].ZIndex,
 			elements[i].ParentID,
 			elements[i].CreatedBy,
 			elements[i].UpdatedBy,
-		).Scan(&elements[i].CreatedAt, &elements[i].UpdatedAt)
-
-		if err != nil {
-			return fmt.Errorf("failed to create element %d: %w", i, err)
-		}
-	}
+			elements[i].CreatedAt,
+			elements[i].UpdatedAt,
+		}, nil
+	})
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"canvas_elements"}, elementCopyColumns, source); err != nil {
+		return fmt.Errorf("failed to create elements: %w", err)
 	}
 
-	return nil
+	return syncAssetReferences(ctx, tx, elements)
 }
 
-// BatchUpdateElements updates multiple canvas elements in a transaction
+// BatchUpdateElements updates multiple canvas elements with a single
+// statement. Every element must exist and appear only once.
 func (r *CanvasRepository) BatchUpdateElements(ctx context.Context, elements []models.CanvasElement) error {
+	if len(elements) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(elements))
+	data := make([]string, len(elements))
+	zIndexes := make([]int, len(elements))
+	parentIDs := make([]*uuid.UUID, len(elements))
+	updatedBy := make([]*uuid.UUID, len(elements))
+	positions := make(map[uuid.UUID]int, len(elements))
+
+	for i := range elements {
+		if _, exists := positions[elements[i].ID]; exists {
+			return fmt.Errorf("element %s appears more than once in the batch", elements[i].ID)
+		}
+		positions[elements[i].ID] = i
+
+		sealed, err := sealData(ctx, r.keyring, elements[i].WorkspaceID, elements[i].ID, elements[i].ElementData)
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(sealed)
+		if err != nil {
+			return fmt.Errorf("failed to encode element %d: %w", i, err)
+		}
+
+		ids[i] = elements[i].ID
+		data[i] = string(encoded)
+		zIndexes[i] = elements[i].ZIndex
+		parentIDs[i] = elements[i].ParentID
+		updatedBy[i] = elements[i].UpdatedBy
+	}
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -236,29 +488,49 @@ func (r *CanvasRepository) BatchUpdateElements(ctx context.Context, elements []m
 	}()
 
 	query := `
-		UPDATE canvas_elements
-		SET element_data = $1, z_index = $2, parent_id = $3, updated_by = $4, updated_at = NOW()
-		WHERE id = $5 AND deleted_at IS NULL
-		RETURNING updated_at
+		UPDATE canvas_elements ce
+		SET element_data = v.element_data::jsonb,
+		    z_index = v.z_index,
+		    parent_id = v.parent_id,
+		    updated_by = v.updated_by,
+		    updated_at = NOW()
+		FROM unnest($1::uuid[], $2::text[], $3::int[], $4::uuid[], $5::uuid[])
+			AS v(id, element_data, z_index, parent_id, updated_by)
+		WHERE ce.id = v.id AND ce.deleted_at IS NULL
+		RETURNING ce.id, ce.updated_at
 	`
 
-	for i := range elements {
-		err := tx.QueryRow(ctx, query,
-			elements[i].ElementData,
-			elements[i].ZIndex,
-			elements[i].ParentID,
-			elements[i].UpdatedBy,
-			elements[i].ID,
-		).Scan(&elements[i].UpdatedAt)
+	rows, err := tx.Query(ctx, query, ids, data, zIndexes, parentIDs, updatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update elements: %w", err)
+	}
 
-		if err == pgx.ErrNoRows {
-			return fmt.Errorf("element %s not found or already deleted", elements[i].ID)
+	updated := make(map[uuid.UUID]bool, len(elements))
+	for rows.Next() {
+		var id uuid.UUID
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &updatedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan updated element: %w", err)
 		}
-		if err != nil {
-			return fmt.Errorf("failed to update element %d: %w", i, err)
+		elements[positions[id]].UpdatedAt = updatedAt
+		updated[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to update elements: %w", err)
+	}
+
+	for i := range elements {
+		if !updated[elements[i].ID] {
+			return fmt.Errorf("element %s not found or already deleted", elements[i].ID)
 		}
 	}
 
+	if err := syncAssetReferences(ctx, tx, elements); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -290,6 +562,10 @@ func (r *CanvasRepository) BatchDeleteElements(ctx context.Context, ids []uuid.U
 		if result.RowsAffected() == 0 {
 			return fmt.Errorf("element %s not found or already deleted", id)
 		}
+
+		if err := removeAssetReference(ctx, tx, id); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -308,7 +584,7 @@ func (r *CanvasRepository) GetElementCount(ctx context.Context, workspaceID uuid
 	`
 
 	var count int
-	err := r.db.QueryRow(ctx, query, workspaceID).Scan(&count)
+	err := r.read.QueryRow(ctx, query, workspaceID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count elements: %w", err)
 	}
@@ -330,7 +606,7 @@ func (r *CanvasRepository) GetElementsByType(
 		ORDER BY z_index ASC, created_at ASC
 	`
 
-	rows, err := r.db.Query(ctx, query, workspaceID, elementType)
+	rows, err := r.read.Query(ctx, query, workspaceID, elementType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query elements by type: %w", err)
 	}
@@ -355,6 +631,91 @@ func (r *CanvasRepository) GetElementsByType(
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan element: %w", err)
 		}
+		if err := r.openElement(ctx, &element); err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+
+	return elements, rows.Err()
+}
+
+// ListElementsPage retrieves the elements of a workspace newest first,
+// starting below the cursor when it is not nil
+func (r *CanvasRepository) ListElementsPage(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	cursor *models.TriggerCursor,
+	limit int,
+) ([]models.CanvasElement, error) {
+	var before *time.Time
+	beforeID := uuid.Nil
+	if cursor != nil {
+		before = &cursor.CreatedAt
+		beforeID = cursor.ID
+	}
+
+	query := `
+		SELECT id, workspace_id, element_type, element_data, z_index, parent_id,
+		       created_by, updated_by, created_at, updated_at, deleted_at
+		FROM canvas_elements
+		WHERE workspace_id = $1 AND deleted_at IS NULL
+		  AND ($2::timestamp IS NULL OR (created_at, id) < ($2::timestamp, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.read.Query(ctx, query, workspaceID, before, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query elements page: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanCanvasElements(ctx, rows)
+}
+
+// GetElementsByIDs retrieves the elements with the given IDs that weren't
+// deleted, in no particular order
+func (r *CanvasRepository) GetElementsByIDs(ctx context.Context, ids []uuid.UUID) ([]models.CanvasElement, error) {
+	query := `
+		SELECT id, workspace_id, element_type, element_data, z_index, parent_id,
+		       created_by, updated_by, created_at, updated_at, deleted_at
+		FROM canvas_elements
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query elements: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanCanvasElements(ctx, rows)
+}
+
+func (r *CanvasRepository) scanCanvasElements(ctx context.Context, rows pgx.Rows) ([]models.CanvasElement, error) {
+	elements := []models.CanvasElement{}
+	for rows.Next() {
+		var element models.CanvasElement
+		err := rows.Scan(
+			&element.ID,
+			&element.WorkspaceID,
+			&element.ElementType,
+			&element.ElementData,
+			&element.ZIndex,
+			&element.ParentID,
+			&element.CreatedBy,
+			&element.UpdatedBy,
+			&element.CreatedAt,
+			&element.UpdatedAt,
+			&element.DeletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan element: %w", err)
+		}
+		if err := r.openElement(ctx, &element); err != nil {
+			return nil, err
+		}
 		elements = append(elements, element)
 	}
 
@@ -396,6 +757,9 @@ func (r *CanvasRepository) GetChildElements(ctx context.Context, parentID uuid.U
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan child element: %w", err)
 		}
+		if err := r.openElement(ctx, &element); err != nil {
+			return nil, err
+		}
 		elements = append(elements, element)
 	}
 
@@ -404,16 +768,308 @@ func (r *CanvasRepository) GetChildElements(ctx context.Context, parentID uuid.U
 
 // DeleteWorkspaceElements deletes all elements in a workspace (for workspace deletion)
 func (r *CanvasRepository) DeleteWorkspaceElements(ctx context.Context, workspaceID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
 	query := `
 		UPDATE canvas_elements
 		SET deleted_at = NOW()
 		WHERE workspace_id = $1 AND deleted_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query, workspaceID)
-	if err != nil {
+	if _, err := tx.Exec(ctx, query, workspaceID); err != nil {
 		return fmt.Errorf("failed to delete workspace elements: %w", err)
 	}
 
+	if _, err := tx.Exec(ctx, `DELETE FROM asset_references WHERE workspace_id = $1`, workspaceID); err != nil {
+		return fmt.Errorf("failed to delete asset references: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
+
+// EncryptElementsAfter encrypts the data of up to limit elements with IDs
+// above afterID that was stored before encryption was enabled, deleted
+// elements included. It returns the last ID looked at, uuid.Nil when there
+// are no more elements. The rows are locked, so concurrent updates are
+// never overwritten with stale data.
+func (r *CanvasRepository) EncryptElementsAfter(
+	ctx context.Context,
+	afterID uuid.UUID,
+	limit int,
+) (lastID uuid.UUID, encrypted int, err error) {
+	if r.keyring == nil {
+		return uuid.Nil, 0, fmt.Errorf("encryption is not configured")
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		SELECT id, workspace_id, element_data
+		FROM canvas_elements
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE
+	`
+
+	rows, err := tx.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to query elements: %w", err)
+	}
+
+	var ids []uuid.UUID
+	var data []string
+	for rows.Next() {
+		var element models.CanvasElement
+		if err := rows.Scan(&element.ID, &element.WorkspaceID, &element.ElementData); err != nil {
+			rows.Close()
+			return uuid.Nil, 0, fmt.Errorf("failed to scan element: %w", err)
+		}
+		lastID = element.ID
+		if isSealed(element.ElementData) {
+			continue
+		}
+
+		sealed, err := sealData(ctx, r.keyring, element.WorkspaceID, element.ID, element.ElementData)
+		if err != nil {
+			rows.Close()
+			return uuid.Nil, 0, err
+		}
+		encoded, err := json.Marshal(sealed)
+		if err != nil {
+			rows.Close()
+			return uuid.Nil, 0, fmt.Errorf("failed to encode element %s: %w", element.ID, err)
+		}
+		ids = append(ids, element.ID)
+		data = append(data, string(encoded))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to query elements: %w", err)
+	}
+
+	if len(ids) > 0 {
+		update := `
+			UPDATE canvas_elements ce
+			SET element_data = v.element_data::jsonb
+			FROM unnest($1::uuid[], $2::text[]) AS v(id, element_data)
+			WHERE ce.id = v.id
+		`
+		if _, err := tx.Exec(ctx, update, ids, data); err != nil {
+			return uuid.Nil, 0, fmt.Errorf("failed to encrypt elements: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return lastID, len(ids), nil
+}
+
+// openElement decrypts the data of an element read from the database
+func (r *CanvasRepository) openElement(ctx context.Context, element *models.CanvasElement) error {
+	if err := openData(ctx, r.keyring, element.WorkspaceID, element.ID, &element.ElementData); err != nil {
+		return fmt.Errorf("failed to open element %s: %w", element.ID, err)
+	}
+	return nil
+}
+
+// Asset references
+
+// syncAssetReference replaces the asset reference of an element with the
+// asset_id in its data. Ids of assets from other workspaces are ignored.
+func syncAssetReference(ctx context.Context, tx pgx.Tx, element *models.CanvasElement) error {
+	if err := removeAssetReference(ctx, tx, element.ID); err != nil {
+		return err
+	}
+
+	assetID, ok := element.ElementData.AssetID()
+	if !ok {
+		return nil
+	}
+
+	query := `
+		INSERT INTO asset_references (asset_id, element_id, workspace_id)
+		SELECT a.id, ce.id, ce.workspace_id
+		FROM canvas_elements ce
+		JOIN assets a ON a.id = $1 AND a.workspace_id = ce.workspace_id
+		WHERE ce.id = $2
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, query, assetID, element.ID); err != nil {
+		return fmt.Errorf("failed to record asset reference: %w", err)
+	}
+
+	return nil
+}
+
+// syncAssetReferences replaces the asset references of several elements at
+// once, like syncAssetReference
+func syncAssetReferences(ctx context.Context, tx pgx.Tx, elements []models.CanvasElement) error {
+	ids := make([]uuid.UUID, 0, len(elements))
+	var elementIDs, assetIDs []uuid.UUID
+	for i := range elements {
+		ids = append(ids, elements[i].ID)
+		if assetID, ok := elements[i].ElementData.AssetID(); ok {
+			elementIDs = append(elementIDs, elements[i].ID)
+			assetIDs = append(assetIDs, assetID)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM asset_references WHERE element_id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("failed to remove asset references: %w", err)
+	}
+
+	if len(elementIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO asset_references (asset_id, element_id, workspace_id)
+		SELECT a.id, ce.id, ce.workspace_id
+		FROM unnest($1::uuid[], $2::uuid[]) AS r(element_id, asset_id)
+		JOIN canvas_elements ce ON ce.id = r.element_id
+		JOIN assets a ON a.id = r.asset_id AND a.workspace_id = ce.workspace_id
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, query, elementIDs, assetIDs); err != nil {
+		return fmt.Errorf("failed to record asset references: %w", err)
+	}
+
+	return nil
+}
+
+// removeAssetReference drops the asset reference of an element
+func removeAssetReference(ctx context.Context, tx pgx.Tx, elementID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM asset_references WHERE element_id = $1`, elementID); err != nil {
+		return fmt.Errorf("failed to remove asset reference: %w", err)
+	}
+	return nil
+}
+
+// GetElementGrowth returns the number of elements of a workspace at the end
+// of each day from the day of from to the day of to
+func (r *CanvasRepository) GetElementGrowth(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+) ([]models.DailyCount, error) {
+	query := `
+		SELECT to_char(d.day, 'YYYY-MM-DD'), COUNT(e.id)
+		FROM generate_series($2::date, $3::date, INTERVAL '1 day') AS d(day)
+		LEFT JOIN canvas_elements e
+			ON e.workspace_id = $1
+			AND e.created_at < d.day + INTERVAL '1 day'
+			AND (e.deleted_at IS NULL OR e.deleted_at >= d.day + INTERVAL '1 day')
+		GROUP BY d.day
+		ORDER BY d.day
+	`
+
+	rows, err := r.read.Query(ctx, query, workspaceID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get element growth: %w", err)
+	}
+	defer rows.Close()
+
+	var growth []models.DailyCount
+	for rows.Next() {
+		var count models.DailyCount
+		if err := rows.Scan(&count.Date, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan element growth: %w", err)
+		}
+		growth = append(growth, count)
+	}
+
+	return growth, rows.Err()
+}
+
+// GetLargestElements returns the largest elements of a workspace as stored,
+// largest first
+func (r *CanvasRepository) GetLargestElements(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	limit int,
+) ([]models.ElementSize, error) {
+	query := `
+		SELECT id, element_type, pg_column_size(e.*) AS bytes
+		FROM canvas_elements e
+		WHERE workspace_id = $1 AND deleted_at IS NULL
+		ORDER BY bytes DESC
+		LIMIT $2
+	`
+
+	rows, err := r.read.Query(ctx, query, workspaceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get largest elements: %w", err)
+	}
+	defer rows.Close()
+
+	elements := []models.ElementSize{}
+	for rows.Next() {
+		var element models.ElementSize
+		if err := rows.Scan(&element.ID, &element.ElementType, &element.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan element size: %w", err)
+		}
+		elements = append(elements, element)
+	}
+
+	return elements, rows.Err()
+}
+
+// GetMaxGroupDepth returns how many groups the most deeply nested element of
+// a workspace is in, and that element. Nesting is followed to maxDepth at
+// most. Returns 0 and nil without groups.
+func (r *CanvasRepository) GetMaxGroupDepth(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	maxDepth int,
+) (int, *uuid.UUID, error) {
+	// Elements whose group was deleted count as top level
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT e.id, 0 AS depth
+			FROM canvas_elements e
+			WHERE e.workspace_id = $1 AND e.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM canvas_elements p WHERE p.id = e.parent_id AND p.deleted_at IS NULL
+				)
+			UNION ALL
+			SELECT e.id, t.depth + 1
+			FROM canvas_elements e
+			INNER JOIN tree t ON e.parent_id = t.id
+			WHERE e.workspace_id = $1 AND e.deleted_at IS NULL AND t.depth < $2
+		)
+		SELECT id, depth FROM tree WHERE depth > 0 ORDER BY depth DESC LIMIT 1
+	`
+
+	var elementID uuid.UUID
+	var depth int
+	err := r.read.QueryRow(ctx, query, workspaceID, maxDepth).Scan(&elementID, &depth)
+	if err == pgx.ErrNoRows {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get group depth: %w", err)
+	}
+
+	return depth, &elementID, nil
+}
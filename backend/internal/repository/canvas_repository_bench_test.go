@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// The benchmarks run against a migrated database, e.g.
+//
+//	BENCH_DATABASE_URL=postgres://localhost/hertzboard_bench go test -run '^$' -bench . ./internal/repository/
+//
+// They create a user and a workspace of their own and delete them when done.
+
+var benchBatchSizes = []int{10, 100, 1000}
+
+// benchWorkspace connects to BENCH_DATABASE_URL and creates a user and a
+// workspace for the elements of a benchmark
+func benchWorkspace(b *testing.B) (*pgxpool.Pool, uuid.UUID, uuid.UUID) {
+	b.Helper()
+
+	dsn := os.Getenv("BENCH_DATABASE_URL")
+	if dsn == "" {
+		b.Skip("BENCH_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		b.Fatalf("failed to connect: %v", err)
+	}
+	b.Cleanup(db.Close)
+
+	userID, workspaceID := uuid.New(), uuid.New()
+	_, err = db.Exec(ctx, `INSERT INTO users (id, email, name) VALUES ($1, $2, 'Benchmark')`,
+		userID, "bench-"+userID.String()+"@example.com")
+	if err != nil {
+		b.Fatalf("failed to create user: %v", err)
+	}
+	_, err = db.Exec(ctx, `INSERT INTO workspaces (id, name, owner_id) VALUES ($1, 'Benchmark', $2)`,
+		workspaceID, userID)
+	if err != nil {
+		b.Fatalf("failed to create workspace: %v", err)
+	}
+
+	b.Cleanup(func() {
+		_, _ = db.Exec(ctx, `DELETE FROM workspaces WHERE id = $1`, workspaceID)
+		_, _ = db.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	return db, workspaceID, userID
+}
+
+func benchElements(workspaceID, userID uuid.UUID, n int) []models.CanvasElement {
+	elements := make([]models.CanvasElement, n)
+	for i := range elements {
+		elements[i] = models.CanvasElement{
+			ID:          uuid.New(),
+			WorkspaceID: workspaceID,
+			ElementType: models.ElementTypeSticky,
+			ElementData: models.ElementData{
+				"text":     "Sticky " + strconv.Itoa(i),
+				"position": map[string]interface{}{"x": float64(i * 10), "y": float64(i * 5)},
+				"size":     map[string]interface{}{"width": 200.0, "height": 200.0},
+			},
+			ZIndex:    i,
+			CreatedBy: userID,
+			UpdatedBy: &userID,
+		}
+	}
+	return elements
+}
+
+// insertElementsRowByRow is the former insert of batch creates, one INSERT
+// per element, kept as the baseline of BenchmarkInsertElements
+func insertElementsRowByRow(ctx context.Context, tx pgx.Tx, elements []models.CanvasElement) error {
+	query := `
+		INSERT INTO canvas_elements (
+			id, workspace_id, element_type, element_data, z_index, parent_id, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+
+	for i := range elements {
+		err := tx.QueryRow(ctx, query,
+			elements[i].ID,
+			elements[i].WorkspaceID,
+			elements[i].ElementType,
+			elements[i].ElementData,
+			elements[i].ZIndex,
+			elements[i].ParentID,
+			elements[i].CreatedBy,
+			elements[i].UpdatedBy,
+		).Scan(&elements[i].CreatedAt, &elements[i].UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create element %d: %w", i, err)
+		}
+	}
+
+	return syncAssetReferences(ctx, tx, elements)
+}
+
+// updateElementsRowByRow is the former batch update, one UPDATE per
+// element, kept as the baseline of BenchmarkBatchUpdateElements
+func updateElementsRowByRow(ctx context.Context, db *pgxpool.Pool, elements []models.CanvasElement) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		UPDATE canvas_elements
+		SET element_data = $1, z_index = $2, parent_id = $3, updated_by = $4, updated_at = NOW()
+		WHERE id = $5 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+
+	for i := range elements {
+		err := tx.QueryRow(ctx, query,
+			elements[i].ElementData,
+			elements[i].ZIndex,
+			elements[i].ParentID,
+			elements[i].UpdatedBy,
+			elements[i].ID,
+		).Scan(&elements[i].UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update element %d: %w", i, err)
+		}
+	}
+
+	if err := syncAssetReferences(ctx, tx, elements); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// BenchmarkInsertElements compares COPY with one INSERT per element. Every
+// batch is rolled back, so the table doesn't grow between iterations.
+func BenchmarkInsertElements(b *testing.B) {
+	db, workspaceID, userID := benchWorkspace(b)
+	repo := NewCanvasRepository(db, nil, nil)
+	ctx := context.Background()
+
+	inserts := []struct {
+		name   string
+		insert func(ctx context.Context, tx pgx.Tx, elements []models.CanvasElement) error
+	}{
+		{"copy", repo.insertElements},
+		{"row-by-row", insertElementsRowByRow},
+	}
+
+	for _, size := range benchBatchSizes {
+		elements := benchElements(workspaceID, userID, size)
+		for _, ins := range inserts {
+			b.Run(fmt.Sprintf("%s/%d", ins.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tx, err := db.Begin(ctx)
+					if err != nil {
+						b.Fatalf("failed to begin transaction: %v", err)
+					}
+					if err := ins.insert(ctx, tx, elements); err != nil {
+						_ = tx.Rollback(ctx)
+						b.Fatal(err)
+					}
+					if err := tx.Rollback(ctx); err != nil {
+						b.Fatalf("failed to roll back: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkBatchUpdateElements compares a single UPDATE ... FROM unnest with
+// one UPDATE per element. The elements of a size are deleted and the table
+// vacuumed before the next size, so every size runs against the same table.
+func BenchmarkBatchUpdateElements(b *testing.B) {
+	db, workspaceID, userID := benchWorkspace(b)
+	repo := NewCanvasRepository(db, nil, nil)
+	ctx := context.Background()
+
+	updates := []struct {
+		name   string
+		update func(ctx context.Context, elements []models.CanvasElement) error
+	}{
+		{"unnest", repo.BatchUpdateElements},
+		{"row-by-row", func(ctx context.Context, elements []models.CanvasElement) error {
+			return updateElementsRowByRow(ctx, db, elements)
+		}},
+	}
+
+	for _, size := range benchBatchSizes {
+		elements := benchElements(workspaceID, userID, size)
+		if err := repo.BatchCreateElements(ctx, elements); err != nil {
+			b.Fatalf("failed to create elements: %v", err)
+		}
+
+		for _, upd := range updates {
+			b.Run(fmt.Sprintf("%s/%d", upd.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					for j := range elements {
+						elements[j].ZIndex = i + j
+					}
+					if err := upd.update(ctx, elements); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+
+		if _, err := db.Exec(ctx, `DELETE FROM canvas_elements WHERE workspace_id = $1`, workspaceID); err != nil {
+			b.Fatalf("failed to delete elements: %v", err)
+		}
+		if _, err := db.Exec(ctx, `VACUUM ANALYZE canvas_elements`); err != nil {
+			b.Fatalf("failed to vacuum canvas_elements: %v", err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type CommentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommentRepository(db *pgxpool.Pool) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+const commentColumns = `c.id, c.workspace_id, c.element_id, c.parent_id, c.author_id, a.name, a.avatar_url, c.body,
+	c.resolved_at, c.resolved_by, r.name, c.edited_at, c.created_at,
+	(SELECT COUNT(*) FROM comments rc WHERE rc.parent_id = c.id)`
+
+const commentJoins = `
+	FROM comments c
+	LEFT JOIN users a ON a.id = c.author_id
+	LEFT JOIN users r ON r.id = c.resolved_by`
+
+// scanComment scans the comment columns, followed by extra columns of a
+// query into extra
+func scanComment(row pgx.Row, extra ...any) (*models.Comment, error) {
+	var comment models.Comment
+	dest := []any{
+		&comment.ID,
+		&comment.WorkspaceID,
+		&comment.ElementID,
+		&comment.ParentID,
+		&comment.AuthorID,
+		&comment.AuthorName,
+		&comment.AuthorAvatar,
+		&comment.Body,
+		&comment.ResolvedAt,
+		&comment.ResolvedBy,
+		&comment.ResolverName,
+		&comment.EditedAt,
+		&comment.CreatedAt,
+		&comment.ReplyCount,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func scanComments(rows pgx.Rows) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, *comment)
+	}
+	return comments, rows.Err()
+}
+
+// CreateComment creates a thread or a reply
+func (r *CommentRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	query := `
+		INSERT INTO comments (id, workspace_id, element_id, parent_id, author_id, body)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		comment.ID,
+		comment.WorkspaceID,
+		comment.ElementID,
+		comment.ParentID,
+		comment.AuthorID,
+		comment.Body,
+	).Scan(&comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return nil
+}
+
+// GetComment retrieves a comment of a workspace, nil if it doesn't exist
+func (r *CommentRepository) GetComment(ctx context.Context, workspaceID, id uuid.UUID) (*models.Comment, error) {
+	query := `SELECT ` + commentColumns + commentJoins + ` WHERE c.id = $1 AND c.workspace_id = $2`
+
+	comment, err := scanComment(r.db.QueryRow(ctx, query, id, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// ListThreads retrieves the threads of a workspace matching the filter,
+// oldest first
+func (r *CommentRepository) ListThreads(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	filter models.CommentFilter,
+) ([]models.Comment, error) {
+	query := `SELECT ` + commentColumns + commentJoins + ` WHERE c.workspace_id = $1 AND c.parent_id IS NULL`
+	args := []interface{}{workspaceID}
+
+	if filter.ElementID != nil {
+		args = append(args, *filter.ElementID)
+		query += fmt.Sprintf(" AND c.element_id = $%d", len(args))
+	}
+	if filter.Resolved != nil {
+		if *filter.Resolved {
+			query += " AND c.resolved_at IS NOT NULL"
+		} else {
+			query += " AND c.resolved_at IS NULL"
+		}
+	}
+	query += " ORDER BY c.created_at, c.id"
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment threads: %w", err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+// ListReplies retrieves the replies of threads, oldest first
+func (r *CommentRepository) ListReplies(ctx context.Context, threadIDs []uuid.UUID) ([]models.Comment, error) {
+	if len(threadIDs) == 0 {
+		return []models.Comment{}, nil
+	}
+
+	query := `SELECT ` + commentColumns + commentJoins + ` WHERE c.parent_id = ANY($1) ORDER BY c.created_at, c.id`
+
+	rows, err := r.db.Query(ctx, query, threadIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment replies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+// UpdateBody changes the body of a comment and marks it as edited
+func (r *CommentRepository) UpdateBody(ctx context.Context, id uuid.UUID, body string) error {
+	if _, err := r.db.Exec(ctx, `UPDATE comments SET body = $2, edited_at = NOW() WHERE id = $1`, id, body); err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	return nil
+}
+
+// ResolveThread marks an open thread as resolved by a user. Returns false if
+// the thread already was resolved.
+func (r *CommentRepository) ResolveThread(ctx context.Context, id, resolvedBy uuid.UUID) (bool, error) {
+	query := `
+		UPDATE comments SET resolved_at = NOW(), resolved_by = $2
+		WHERE id = $1 AND parent_id IS NULL AND resolved_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, resolvedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve comment thread: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReopenThread clears the resolve state of a thread. Returns false if the
+// thread wasn't resolved.
+func (r *CommentRepository) ReopenThread(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE comments SET resolved_at = NULL, resolved_by = NULL
+		WHERE id = $1 AND parent_id IS NULL AND resolved_at IS NOT NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to reopen comment thread: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteComment deletes a comment, with its replies for a thread. Returns
+// the IDs of the deleted comments.
+func (r *CommentRepository) DeleteComment(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `DELETE FROM comments WHERE id = $1 OR parent_id = $1 RETURNING id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete comment: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var deleted uuid.UUID
+		if err := rows.Scan(&deleted); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted comment: %w", err)
+		}
+		ids = append(ids, deleted)
+	}
+
+	return ids, rows.Err()
+}
+
+// SearchComments retrieves the most recent comments of a workspace whose
+// body contains the query or its words, with the element of their thread
+func (r *CommentRepository) SearchComments(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	query string,
+	limit int,
+) ([]models.CommentMatch, error) {
+	sql := `SELECT ` + commentColumns + `, COALESCE(c.element_id, p.element_id)` + commentJoins + `
+		LEFT JOIN comments p ON p.id = c.parent_id
+		WHERE c.workspace_id = $1
+		  AND (c.body ILIKE $2 OR to_tsvector('simple', c.body) @@ websearch_to_tsquery('simple', $3))
+		ORDER BY c.created_at DESC, c.id
+		LIMIT $4`
+
+	rows, err := r.db.Query(ctx, sql, workspaceID, "%"+likeEscaper.Replace(query)+"%", query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search comments: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []models.CommentMatch{}
+	for rows.Next() {
+		var elementID *uuid.UUID
+		comment, err := scanComment(rows, &elementID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		matches = append(matches, models.CommentMatch{Comment: *comment, ThreadElementID: elementID})
+	}
+
+	return matches, rows.Err()
+}
+
+// ListCommentsPage returns comments of a workspace, threads and replies,
+// newest first, starting below the cursor. Each has the element of its
+// thread.
+func (r *CommentRepository) ListCommentsPage(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	cursor *models.TriggerCursor,
+	limit int,
+) ([]models.CommentMatch, error) {
+	var before *time.Time
+	beforeID := uuid.Nil
+	if cursor != nil {
+		before = &cursor.CreatedAt
+		beforeID = cursor.ID
+	}
+
+	query := `SELECT ` + commentColumns + `, COALESCE(c.element_id, p.element_id)` + commentJoins + `
+		LEFT JOIN comments p ON p.id = c.parent_id
+		WHERE c.workspace_id = $1
+		  AND ($2::timestamp IS NULL OR (c.created_at, c.id) < ($2::timestamp, $3))
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $4`
+
+	rows, err := r.db.Query(ctx, query, workspaceID, before, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.CommentMatch{}
+	for rows.Next() {
+		var elementID *uuid.UUID
+		comment, err := scanComment(rows, &elementID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, models.CommentMatch{Comment: *comment, ThreadElementID: elementID})
+	}
+
+	return comments, rows.Err()
+}
+
+// ElementExists reports whether an element of a workspace exists
+func (r *CommentRepository) ElementExists(ctx context.Context, workspaceID, elementID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM canvas_elements WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NULL
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, elementID, workspaceID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check element: %w", err)
+	}
+	return exists, nil
+}
+
+// CountThreadsByElement counts the threads and open threads of the elements
+// of a workspace that have any
+func (r *CommentRepository) CountThreadsByElement(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) (map[uuid.UUID]models.CommentCounts, error) {
+	query := `
+		SELECT element_id, COUNT(*), COUNT(*) FILTER (WHERE resolved_at IS NULL)
+		FROM comments
+		WHERE workspace_id = $1 AND parent_id IS NULL AND element_id IS NOT NULL
+		GROUP BY element_id
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comment threads: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]models.CommentCounts)
+	for rows.Next() {
+		var elementID uuid.UUID
+		var c models.CommentCounts
+		if err := rows.Scan(&elementID, &c.Threads, &c.Unresolved); err != nil {
+			return nil, fmt.Errorf("failed to scan comment counts: %w", err)
+		}
+		counts[elementID] = c
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type ConsentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewConsentRepository(db *pgxpool.Pool) *ConsentRepository {
+	return &ConsentRepository{db: db}
+}
+
+// RecordConsents stores accepted document versions. Versions a user
+// accepted before keep their original acceptance.
+func (r *ConsentRepository) RecordConsents(ctx context.Context, consents []models.UserConsent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		INSERT INTO user_consents (id, user_id, document, version, ip_address)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, document, version) DO NOTHING
+	`
+
+	for i := range consents {
+		consent := &consents[i]
+		_, err := tx.Exec(ctx, query, consent.ID, consent.UserID, consent.Document, consent.Version, consent.IPAddress)
+		if err != nil {
+			return fmt.Errorf("failed to record consent: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit consents: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestConsents returns the most recently accepted version of each
+// document a user accepted
+func (r *ConsentRepository) GetLatestConsents(ctx context.Context, userID uuid.UUID) ([]models.UserConsent, error) {
+	query := `
+		SELECT DISTINCT ON (document) id, user_id, document, version, ip_address, accepted_at
+		FROM user_consents
+		WHERE user_id = $1
+		ORDER BY document, accepted_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []models.UserConsent
+	for rows.Next() {
+		var consent models.UserConsent
+		err := rows.Scan(
+			&consent.ID,
+			&consent.UserID,
+			&consent.Document,
+			&consent.Version,
+			&consent.IPAddress,
+			&consent.AcceptedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consent: %w", err)
+		}
+		consents = append(consents, consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating consents: %w", err)
+	}
+
+	return consents, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// DataKeyRepository stores the wrapped data keys of encrypted workspaces
+type DataKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDataKeyRepository(db *pgxpool.Pool) *DataKeyRepository {
+	return &DataKeyRepository{db: db}
+}
+
+// GetDataKey returns the data key of a workspace, nil when it has none yet
+func (r *DataKeyRepository) GetDataKey(ctx context.Context, workspaceID uuid.UUID) (*models.WorkspaceDataKey, error) {
+	query := `
+		SELECT workspace_id, master_key_id, wrapped_key, created_at, updated_at
+		FROM workspace_data_keys
+		WHERE workspace_id = $1
+	`
+
+	var key models.WorkspaceDataKey
+	err := r.db.QueryRow(ctx, query, workspaceID).Scan(
+		&key.WorkspaceID,
+		&key.MasterKeyID,
+		&key.WrappedKey,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// CreateDataKey stores the data key of a workspace unless another instance
+// stored one first, and returns the key that was kept
+func (r *DataKeyRepository) CreateDataKey(ctx context.Context, key *models.WorkspaceDataKey) (*models.WorkspaceDataKey, error) {
+	query := `
+		INSERT INTO workspace_data_keys (workspace_id, master_key_id, wrapped_key)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, key.WorkspaceID, key.MasterKeyID, key.WrappedKey); err != nil {
+		return nil, fmt.Errorf("failed to create data key: %w", err)
+	}
+
+	stored, err := r.GetDataKey(ctx, key.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		// The workspace was deleted in between
+		return nil, fmt.Errorf("workspace not found")
+	}
+	return stored, nil
+}
+
+// RewrapDataKey replaces the wrapped form of a data key after the master
+// key was rotated. The key itself doesn't change.
+func (r *DataKeyRepository) RewrapDataKey(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	masterKeyID string,
+	wrappedKey []byte,
+) error {
+	query := `
+		UPDATE workspace_data_keys
+		SET master_key_id = $2, wrapped_key = $3, updated_at = NOW()
+		WHERE workspace_id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, workspaceID, masterKeyID, wrappedKey); err != nil {
+		return fmt.Errorf("failed to rewrap data key: %w", err)
+	}
+	return nil
+}
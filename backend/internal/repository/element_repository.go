@@ -2,11 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,8 +21,8 @@ func NewElementRepository(db *pgxpool.Pool) *ElementRepository {
 	return &ElementRepository{db: db}
 }
 
-// Create creates a new element
-func (r *ElementRepository) Create(ctx context.Context, element *models.Element) error {
+// Create creates a new element, together with the outbox messages
+func (r *ElementRepository) Create(ctx context.Context, element *models.Element, outbox ...*models.OutboxMessage) error {
 	query := `
 		INSERT INTO elements (
 			id, workspace_id, type, content, pos_x, pos_y, width, height,
@@ -37,7 +40,15 @@ func (r *ElementRepository) Create(ctx context.Context, element *models.Element)
 		element.UpdatedAt = now
 	}
 
-	_, err := r.db.Exec(ctx, query,
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, query,
 		element.ID,
 		element.WorkspaceID,
 		element.Type,
@@ -55,21 +66,28 @@ func (r *ElementRepository) Create(ctx context.Context, element *models.Element)
 		element.CreatedAt,
 		element.UpdatedAt,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-// GetByID retrieves an element by ID
-func (r *ElementRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Element, error) {
+// GetByID retrieves an element of a workspace by ID
+func (r *ElementRepository) GetByID(ctx context.Context, workspaceID, id uuid.UUID) (*models.Element, error) {
 	query := `
 		SELECT id, workspace_id, type, content, pos_x, pos_y, width, height,
 			z_index, rotation, style, version, created_by, updated_by, created_at, updated_at, deleted_at
 		FROM elements
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NULL
 	`
 
 	var element models.Element
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id, workspaceID).Scan(
 		&element.ID,
 		&element.WorkspaceID,
 		&element.Type,
@@ -96,13 +114,27 @@ func (r *ElementRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	return &element, nil
 }
 
+// GetWorkspaceID returns the workspace an element belongs to, deleted
+// elements included, or uuid.Nil if there is no such element
+func (r *ElementRepository) GetWorkspaceID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	var workspaceID uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT workspace_id FROM elements WHERE id = $1`, id).Scan(&workspaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get element workspace: %w", err)
+	}
+	return workspaceID, nil
+}
+
 // Update updates an element
 func (r *ElementRepository) Update(ctx context.Context, element *models.Element) error {
 	query := `
 		UPDATE elements
 		SET content = $1, pos_x = $2, pos_y = $3, width = $4, height = $5,
 			z_index = $6, rotation = $7, style = $8, version = $9, updated_by = $10, updated_at = $11
-		WHERE id = $12 AND deleted_at IS NULL
+		WHERE id = $12 AND workspace_id = $13 AND deleted_at IS NULL
 	`
 
 	element.UpdatedAt = time.Now()
@@ -120,20 +152,21 @@ func (r *ElementRepository) Update(ctx context.Context, element *models.Element)
 		element.UpdatedBy,
 		element.UpdatedAt,
 		element.ID,
+		element.WorkspaceID,
 	)
 
 	return err
 }
 
-// Delete soft deletes an element
-func (r *ElementRepository) Delete(ctx context.Context, id uuid.UUID) error {
+// Delete soft deletes an element of a workspace
+func (r *ElementRepository) Delete(ctx context.Context, workspaceID, id uuid.UUID) error {
 	query := `
 		UPDATE elements
 		SET deleted_at = $1
-		WHERE id = $2 AND deleted_at IS NULL
+		WHERE id = $2 AND workspace_id = $3 AND deleted_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query, time.Now(), id)
+	_, err := r.db.Exec(ctx, query, time.Now(), id, workspaceID)
 	return err
 }
 
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// EmailSuppressionRepository handles suppressed email addresses. Addresses
+// are expected lowercase.
+type EmailSuppressionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewEmailSuppressionRepository creates a new email suppression repository
+func NewEmailSuppressionRepository(db *pgxpool.Pool) *EmailSuppressionRepository {
+	return &EmailSuppressionRepository{db: db}
+}
+
+const emailSuppressionColumns = `email, reason, provider, detail, created_at, updated_at`
+
+func scanEmailSuppression(row pgx.Row) (*models.EmailSuppression, error) {
+	var s models.EmailSuppression
+	err := row.Scan(&s.Email, &s.Reason, &s.Provider, &s.Detail, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Upsert suppresses an address or updates the reason it is suppressed for
+func (r *EmailSuppressionRepository) Upsert(ctx context.Context, suppression *models.EmailSuppression) error {
+	query := `
+		INSERT INTO email_suppressions (email, reason, provider, detail)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (email) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			provider = EXCLUDED.provider,
+			detail = EXCLUDED.detail,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		suppression.Email,
+		suppression.Reason,
+		suppression.Provider,
+		suppression.Detail,
+	).Scan(&suppression.CreatedAt, &suppression.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to suppress email: %w", err)
+	}
+
+	return nil
+}
+
+// GetByEmail retrieves the suppression of an address, nil if it isn't
+// suppressed
+func (r *EmailSuppressionRepository) GetByEmail(ctx context.Context, email string) (*models.EmailSuppression, error) {
+	query := `SELECT ` + emailSuppressionColumns + ` FROM email_suppressions WHERE email = $1`
+
+	suppression, err := scanEmailSuppression(r.db.QueryRow(ctx, query, email))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email suppression: %w", err)
+	}
+
+	return suppression, nil
+}
+
+// GetByEmails retrieves the suppressions of the given addresses, keyed by
+// address
+func (r *EmailSuppressionRepository) GetByEmails(
+	ctx context.Context,
+	emails []string,
+) (map[string]*models.EmailSuppression, error) {
+	query := `SELECT ` + emailSuppressionColumns + ` FROM email_suppressions WHERE email = ANY($1)`
+
+	rows, err := r.db.Query(ctx, query, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	suppressions := make(map[string]*models.EmailSuppression)
+	for rows.Next() {
+		suppression, err := scanEmailSuppression(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email suppression: %w", err)
+		}
+		suppressions[suppression.Email] = suppression
+	}
+
+	return suppressions, rows.Err()
+}
+
+// List retrieves suppressions, most recently updated first
+func (r *EmailSuppressionRepository) List(ctx context.Context, limit, offset int) ([]models.EmailSuppression, error) {
+	query := `
+		SELECT ` + emailSuppressionColumns + `
+		FROM email_suppressions
+		ORDER BY updated_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	suppressions := []models.EmailSuppression{}
+	for rows.Next() {
+		suppression, err := scanEmailSuppression(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email suppression: %w", err)
+		}
+		suppressions = append(suppressions, *suppression)
+	}
+
+	return suppressions, rows.Err()
+}
+
+// Delete lifts the suppression of an address. Returns false if it wasn't
+// suppressed.
+func (r *EmailSuppressionRepository) Delete(ctx context.Context, email string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM email_suppressions WHERE email = $1`, email)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete email suppression: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
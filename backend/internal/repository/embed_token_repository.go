@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type EmbedTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmbedTokenRepository(db *pgxpool.Pool) *EmbedTokenRepository {
+	return &EmbedTokenRepository{db: db}
+}
+
+const embedTokenColumns = `id, workspace_id, created_by, name, prefix, token_hash, expires_at, last_used_at, created_at`
+
+func scanEmbedToken(row pgx.Row) (*models.EmbedToken, error) {
+	var token models.EmbedToken
+	err := row.Scan(
+		&token.ID,
+		&token.WorkspaceID,
+		&token.CreatedBy,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CreateEmbedToken creates a new embed token
+func (r *EmbedTokenRepository) CreateEmbedToken(ctx context.Context, token *models.EmbedToken) error {
+	query := `
+		INSERT INTO embed_tokens (id, workspace_id, created_by, name, prefix, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		token.ID,
+		token.WorkspaceID,
+		token.CreatedBy,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create embed token: %w", err)
+	}
+
+	return nil
+}
+
+// GetEmbedTokenByHash retrieves an embed token by the hash of the token,
+// nil if it doesn't exist
+func (r *EmbedTokenRepository) GetEmbedTokenByHash(ctx context.Context, tokenHash string) (*models.EmbedToken, error) {
+	query := `SELECT ` + embedTokenColumns + ` FROM embed_tokens WHERE token_hash = $1`
+
+	token, err := scanEmbedToken(r.db.QueryRow(ctx, query, tokenHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embed token: %w", err)
+	}
+
+	return token, nil
+}
+
+// ListEmbedTokens retrieves all embed tokens of a workspace
+func (r *EmbedTokenRepository) ListEmbedTokens(ctx context.Context, workspaceID uuid.UUID) ([]models.EmbedToken, error) {
+	query := `SELECT ` + embedTokenColumns + ` FROM embed_tokens WHERE workspace_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embed tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.EmbedToken{}
+	for rows.Next() {
+		token, err := scanEmbedToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan embed token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// MarkEmbedTokenUsed records that an embed token served a board. The time
+// is updated at most once a minute, embeds are loaded by every visitor.
+func (r *EmbedTokenRepository) MarkEmbedTokenUsed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE embed_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to update embed token: %w", err)
+	}
+	return nil
+}
+
+// DeleteEmbedToken deletes an embed token. Returns false if it doesn't exist.
+func (r *EmbedTokenRepository) DeleteEmbedToken(ctx context.Context, workspaceID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM embed_tokens WHERE workspace_id = $1 AND id = $2`, workspaceID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete embed token: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/encryption"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// sealedField is the only key of a JSONB value sealed by sealData
+const sealedField = "$enc"
+
+// sealData returns the value stored for data: the data itself without a
+// keyring, else an object holding the ciphertext, bound to the row rowID
+func sealData(
+	ctx context.Context,
+	keyring *encryption.Keyring,
+	workspaceID, rowID uuid.UUID,
+	data models.ElementData,
+) (models.ElementData, error) {
+	if keyring == nil {
+		return data, nil
+	}
+
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data: %w", err)
+	}
+
+	sealed, err := keyring.Seal(ctx, workspaceID, plaintext, rowID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+
+	return models.ElementData{sealedField: base64.StdEncoding.EncodeToString(sealed)}, nil
+}
+
+// isSealed reports whether a stored value was sealed by sealData
+func isSealed(data models.ElementData) bool {
+	if len(data) != 1 {
+		return false
+	}
+	_, ok := data[sealedField].(string)
+	return ok
+}
+
+// openData replaces a value sealed by sealData with the data. Values
+// written before encryption was enabled are left as they are.
+func openData(
+	ctx context.Context,
+	keyring *encryption.Keyring,
+	workspaceID, rowID uuid.UUID,
+	data *models.ElementData,
+) error {
+	if !isSealed(*data) {
+		return nil
+	}
+	if keyring == nil {
+		return fmt.Errorf("data is encrypted but encryption is not configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString((*data)[sealedField].(string))
+	if err != nil {
+		return fmt.Errorf("failed to decode encrypted data: %w", err)
+	}
+
+	plaintext, err := keyring.Open(ctx, workspaceID, sealed, rowID[:])
+	if err != nil {
+		return fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	var opened models.ElementData
+	if err := json.Unmarshal(plaintext, &opened); err != nil {
+		return fmt.Errorf("failed to decode decrypted data: %w", err)
+	}
+	*data = opened
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type ExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExportRepository(db *pgxpool.Pool) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+const exportColumns = `id, workspace_id, requested_by, format, status, object_key, size_bytes, error,
+	created_at, completed_at, range_from, range_to`
+
+func scanExport(row pgx.Row) (*models.BoardExport, error) {
+	var export models.BoardExport
+	err := row.Scan(
+		&export.ID,
+		&export.WorkspaceID,
+		&export.RequestedBy,
+		&export.Format,
+		&export.Status,
+		&export.ObjectKey,
+		&export.SizeBytes,
+		&export.Error,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.RangeFrom,
+		&export.RangeTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// CreateExport creates a new pending export
+func (r *ExportRepository) CreateExport(ctx context.Context, export *models.BoardExport) error {
+	query := `
+		INSERT INTO board_exports (id, workspace_id, requested_by, format, status, range_from, range_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		export.ID,
+		export.WorkspaceID,
+		export.RequestedBy,
+		export.Format,
+		export.Status,
+		export.RangeFrom,
+		export.RangeTo,
+	).Scan(&export.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+
+	return nil
+}
+
+// GetExport retrieves an export of a workspace in any format, nil if it
+// doesn't exist
+func (r *ExportRepository) GetExport(ctx context.Context, workspaceID, id uuid.UUID) (*models.BoardExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM board_exports WHERE id = $1 AND workspace_id = $2`
+
+	export, err := scanExport(r.db.QueryRow(ctx, query, id, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+
+	return export, nil
+}
+
+// ListExports retrieves the latest exports of a workspace in formats
+func (r *ExportRepository) ListExports(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	formats []string,
+	limit int,
+) ([]models.BoardExport, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM board_exports
+		WHERE workspace_id = $1 AND format = ANY($2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID, formats, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []models.BoardExport{}
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		exports = append(exports, *export)
+	}
+
+	return exports, rows.Err()
+}
+
+// MarkProcessing moves a pending export to processing. Returns false if it
+// was deleted or already picked up.
+func (r *ExportRepository) MarkProcessing(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE board_exports SET status = $2 WHERE id = $1 AND status = $3
+	`, id, models.ExportStatusProcessing, models.ExportStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to update export: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkCompleted records the rendered document of an export
+func (r *ExportRepository) MarkCompleted(ctx context.Context, id uuid.UUID, objectKey string, size int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE board_exports SET status = $2, object_key = $3, size_bytes = $4, completed_at = NOW()
+		WHERE id = $1
+	`, id, models.ExportStatusCompleted, objectKey, size)
+	if err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records why an export failed
+func (r *ExportRepository) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE board_exports SET status = $2, error = $3, completed_at = NOW()
+		WHERE id = $1
+	`, id, models.ExportStatusFailed, reason)
+	if err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// DeleteExport deletes an export of a workspace in formats and returns it,
+// nil if it doesn't exist
+func (r *ExportRepository) DeleteExport(
+	ctx context.Context,
+	workspaceID, id uuid.UUID,
+	formats []string,
+) (*models.BoardExport, error) {
+	query := `
+		DELETE FROM board_exports
+		WHERE id = $1 AND workspace_id = $2 AND format = ANY($3)
+		RETURNING ` + exportColumns
+
+	export, err := scanExport(r.db.QueryRow(ctx, query, id, workspaceID, formats))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete export: %w", err)
+	}
+
+	return export, nil
+}
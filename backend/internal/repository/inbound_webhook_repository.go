@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type InboundWebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewInboundWebhookRepository(db *pgxpool.Pool) *InboundWebhookRepository {
+	return &InboundWebhookRepository{db: db}
+}
+
+const inboundWebhookColumns = `id, workspace_id, name, token_hash, default_column, active, created_by,
+	last_used_at, created_at, updated_at`
+
+func scanInboundWebhook(row pgx.Row) (*models.InboundWebhook, error) {
+	var webhook models.InboundWebhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.WorkspaceID,
+		&webhook.Name,
+		&webhook.TokenHash,
+		&webhook.DefaultColumn,
+		&webhook.Active,
+		&webhook.CreatedBy,
+		&webhook.LastUsedAt,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// CreateInboundWebhook creates a new inbound webhook
+func (r *InboundWebhookRepository) CreateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error {
+	query := `
+		INSERT INTO inbound_webhooks (id, workspace_id, name, token_hash, default_column, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		webhook.ID,
+		webhook.WorkspaceID,
+		webhook.Name,
+		webhook.TokenHash,
+		webhook.DefaultColumn,
+		webhook.Active,
+		webhook.CreatedBy,
+	).Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create inbound webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetInboundWebhook retrieves an inbound webhook of a workspace, nil if it doesn't exist
+func (r *InboundWebhookRepository) GetInboundWebhook(
+	ctx context.Context,
+	workspaceID, id uuid.UUID,
+) (*models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE workspace_id = $1 AND id = $2`
+
+	webhook, err := scanInboundWebhook(r.db.QueryRow(ctx, query, workspaceID, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// GetInboundWebhookByID retrieves an inbound webhook regardless of workspace,
+// nil if it doesn't exist
+func (r *InboundWebhookRepository) GetInboundWebhookByID(ctx context.Context, id uuid.UUID) (*models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE id = $1`
+
+	webhook, err := scanInboundWebhook(r.db.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// ListInboundWebhooks retrieves all inbound webhooks of a workspace
+func (r *InboundWebhookRepository) ListInboundWebhooks(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) ([]models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE workspace_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.InboundWebhook{}
+	for rows.Next() {
+		webhook, err := scanInboundWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inbound webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// UpdateInboundWebhook saves the name, token, default column and active flag
+// of an inbound webhook
+func (r *InboundWebhookRepository) UpdateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error {
+	query := `
+		UPDATE inbound_webhooks
+		SET name = $3, token_hash = $4, default_column = $5, active = $6, updated_at = NOW()
+		WHERE workspace_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		webhook.WorkspaceID,
+		webhook.ID,
+		webhook.Name,
+		webhook.TokenHash,
+		webhook.DefaultColumn,
+		webhook.Active,
+	).Scan(&webhook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update inbound webhook: %w", err)
+	}
+
+	return nil
+}
+
+// MarkInboundWebhookUsed records that an inbound webhook created a note
+func (r *InboundWebhookRepository) MarkInboundWebhookUsed(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `UPDATE inbound_webhooks SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to update inbound webhook: %w", err)
+	}
+	return nil
+}
+
+// DeleteInboundWebhook deletes an inbound webhook. Returns false if it
+// doesn't exist.
+func (r *InboundWebhookRepository) DeleteInboundWebhook(ctx context.Context, workspaceID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM inbound_webhooks WHERE workspace_id = $1 AND id = $2`, workspaceID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete inbound webhook: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
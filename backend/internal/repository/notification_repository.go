@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// NotificationRepository handles mentions and in-app notifications
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetMembersByUsernames retrieves the members of a workspace with the given
+// lowercase usernames
+func (r *NotificationRepository) GetMembersByUsernames(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	usernames []string,
+) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.email, u.name, u.username
+		FROM workspace_members wm
+		INNER JOIN users u ON wm.user_id = u.id
+		WHERE wm.workspace_id = $1 AND lower(u.username) = ANY($2)
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members by usernames: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// ReplaceMentions sets the users mentioned in an element and returns the
+// ones that weren't mentioned before
+func (r *NotificationRepository) ReplaceMentions(
+	ctx context.Context,
+	workspaceID, elementID, authorID uuid.UUID,
+	userIDs []uuid.UUID,
+) ([]uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM mentions
+		WHERE element_id = $1 AND NOT (mentioned_user_id = ANY($2))
+	`, elementID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete mentions: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO mentions (id, workspace_id, element_id, mentioned_user_id, author_id)
+		SELECT gen_random_uuid(), $1, $2, user_id, $3
+		FROM unnest($4::uuid[]) AS user_id
+		ON CONFLICT (element_id, mentioned_user_id) DO NOTHING
+		RETURNING mentioned_user_id
+	`, workspaceID, elementID, authorID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mentions: %w", err)
+	}
+
+	added := []uuid.UUID{}
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan mention: %w", err)
+		}
+		added = append(added, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to create mentions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return added, nil
+}
+
+// CreateNotification creates a notification
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	query := `
+		INSERT INTO notifications (id, user_id, workspace_id, type, actor_id, element_id, data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		notification.ID,
+		notification.UserID,
+		notification.WorkspaceID,
+		notification.Type,
+		notification.ActorID,
+		notification.ElementID,
+		notification.Data,
+	).Scan(&notification.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+const notificationWithContextQuery = `
+	SELECT n.id, n.user_id, n.workspace_id, n.type, n.actor_id, n.element_id, n.data,
+	       n.read_at, n.emailed_at, n.created_at, w.name, a.name
+	FROM notifications n
+	INNER JOIN workspaces w ON n.workspace_id = w.id
+	LEFT JOIN users a ON n.actor_id = a.id
+`
+
+func (r *NotificationRepository) queryNotifications(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) ([]models.NotificationWithContext, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.NotificationWithContext{}
+	for rows.Next() {
+		var n models.NotificationWithContext
+		err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.WorkspaceID,
+			&n.Type,
+			&n.ActorID,
+			&n.ElementID,
+			&n.Data,
+			&n.ReadAt,
+			&n.EmailedAt,
+			&n.CreatedAt,
+			&n.WorkspaceName,
+			&n.ActorName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// ListNotifications retrieves the notifications of a user, newest first
+func (r *NotificationRepository) ListNotifications(
+	ctx context.Context,
+	userID uuid.UUID,
+	unreadOnly bool,
+	limit, offset int,
+) ([]models.NotificationWithContext, error) {
+	query := notificationWithContextQuery + `
+		WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)
+		ORDER BY n.created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	return r.queryNotifications(ctx, query, userID, unreadOnly, limit, offset)
+}
+
+// CountUnread counts the unread notifications of a user
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`,
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks a notification of a user as read. Returns false if the
+// notification doesn't exist.
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE user_id = $1 AND id = $2
+	`, userID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkAllRead marks all notifications of a user as read
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetDigestNotifications retrieves unread notifications created before the
+// given time that weren't emailed yet and whose users are due a digest,
+// oldest first
+func (r *NotificationRepository) GetDigestNotifications(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]models.NotificationWithContext, error) {
+	// Users with daily or weekly digests are only included once their
+	// previous digest is old enough, users with digests off not at all
+	query := notificationWithContextQuery + `
+		LEFT JOIN notification_preferences p ON n.user_id = p.user_id
+		WHERE n.read_at IS NULL AND n.emailed_at IS NULL AND n.created_at < $1
+		  AND COALESCE(p.digest_frequency, 'frequent') <> 'off'
+		  AND (p.last_digest_at IS NULL OR p.last_digest_at < NOW() - CASE p.digest_frequency
+		        WHEN 'daily' THEN INTERVAL '1 day'
+		        WHEN 'weekly' THEN INTERVAL '7 days'
+		        ELSE INTERVAL '0'
+		      END)
+		ORDER BY n.created_at
+		LIMIT $2
+	`
+	return r.queryNotifications(ctx, query, before, limit)
+}
+
+// MarkEmailed records that notifications were included in an email digest
+func (r *NotificationRepository) MarkEmailed(ctx context.Context, ids []uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE notifications SET emailed_at = NOW() WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications as emailed: %w", err)
+	}
+	return nil
+}
+
+const notificationPreferencesColumns = `
+	user_id, email_on_invite, email_on_mention, digest_frequency, mute_webhooks, last_digest_at, updated_at
+`
+
+func scanNotificationPreferences(row pgx.Row) (*models.NotificationPreferences, error) {
+	var prefs models.NotificationPreferences
+	err := row.Scan(
+		&prefs.UserID,
+		&prefs.EmailOnInvite,
+		&prefs.EmailOnMention,
+		&prefs.DigestFrequency,
+		&prefs.MuteWebhooks,
+		&prefs.LastDigestAt,
+		&prefs.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+// GetPreferences retrieves the notification preferences of a user. Returns
+// nil if the user never changed them.
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	query := `SELECT ` + notificationPreferencesColumns + ` FROM notification_preferences WHERE user_id = $1`
+	return scanNotificationPreferences(r.db.QueryRow(ctx, query, userID))
+}
+
+// GetPreferencesByEmail retrieves the notification preferences of the user
+// with the given email. Returns nil if there is no such user or the user
+// never changed them.
+func (r *NotificationRepository) GetPreferencesByEmail(ctx context.Context, email string) (*models.NotificationPreferences, error) {
+	query := `
+		SELECT p.user_id, p.email_on_invite, p.email_on_mention, p.digest_frequency,
+		       p.mute_webhooks, p.last_digest_at, p.updated_at
+		FROM notification_preferences p
+		INNER JOIN users u ON p.user_id = u.id
+		WHERE u.email = $1
+	`
+	return scanNotificationPreferences(r.db.QueryRow(ctx, query, email))
+}
+
+// UpsertPreferences stores the notification preferences of a user
+func (r *NotificationRepository) UpsertPreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences
+			(user_id, email_on_invite, email_on_mention, digest_frequency, mute_webhooks)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			email_on_invite = EXCLUDED.email_on_invite,
+			email_on_mention = EXCLUDED.email_on_mention,
+			digest_frequency = EXCLUDED.digest_frequency,
+			mute_webhooks = EXCLUDED.mute_webhooks,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		prefs.UserID,
+		prefs.EmailOnInvite,
+		prefs.EmailOnMention,
+		prefs.DigestFrequency,
+		prefs.MuteWebhooks,
+	).Scan(&prefs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDigestSent records that a user was sent a digest
+func (r *NotificationRepository) MarkDigestSent(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO notification_preferences (user_id, last_digest_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET last_digest_at = NOW()
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark digest as sent: %w", err)
+	}
+	return nil
+}
@@ -2,14 +2,21 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// OperationRepository handles CRDT operations. The table is partitioned by
+// month of created_at and, within a month, by hash of workspace_id. Every
+// query filters on the workspace, so it reads one partition per month, and
+// queries that filter on created_at only read the months of that range.
+// Retention keeps the number of months small.
 type OperationRepository struct {
 	db *pgxpool.Pool
 }
@@ -18,7 +25,9 @@ func NewOperationRepository(db *pgxpool.Pool) *OperationRepository {
 	return &OperationRepository{db: db}
 }
 
-// Create stores a new operation
+// Create stores a new operation. op.ID must be new, the database only
+// enforces (id, created_at, workspace_id) to be unique since operations are
+// partitioned.
 func (r *OperationRepository) Create(ctx context.Context, op *models.Operation) error {
 	query := `
 		INSERT INTO operations (
@@ -42,16 +51,16 @@ func (r *OperationRepository) Create(ctx context.Context, op *models.Operation)
 	return err
 }
 
-// GetByID retrieves an operation by ID
-func (r *OperationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Operation, error) {
+// GetByID retrieves an operation of a workspace by ID
+func (r *OperationRepository) GetByID(ctx context.Context, workspaceID, id uuid.UUID) (*models.Operation, error) {
 	query := `
 		SELECT id, workspace_id, element_id, user_id, op_type, data, timestamp, created_at
 		FROM operations
-		WHERE id = $1
+		WHERE id = $1 AND workspace_id = $2
 	`
 
 	var op models.Operation
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id, workspaceID).Scan(
 		&op.ID,
 		&op.WorkspaceID,
 		&op.ElementID,
@@ -111,19 +120,19 @@ func (r *OperationRepository) GetByWorkspaceID(
 	return operations, nil
 }
 
-// GetByElementID retrieves operations for an element
+// GetByElementID retrieves operations for an element of a workspace
 func (r *OperationRepository) GetByElementID(
 	ctx context.Context,
-	elementID uuid.UUID,
+	workspaceID, elementID uuid.UUID,
 ) ([]*models.Operation, error) {
 	query := `
 		SELECT id, workspace_id, element_id, user_id, op_type, data, timestamp, created_at
 		FROM operations
-		WHERE element_id = $1
+		WHERE element_id = $1 AND workspace_id = $2
 		ORDER BY timestamp ASC
 	`
 
-	rows, err := r.db.Query(ctx, query, elementID)
+	rows, err := r.db.Query(ctx, query, elementID, workspaceID)
 	if err != nil {
 		return nil, err
 	}
@@ -194,20 +203,139 @@ func (r *OperationRepository) GetSince(
 	return operations, nil
 }
 
-// DeleteOldOperations deletes operations older than specified duration
-func (r *OperationRepository) DeleteOldOperations(ctx context.Context, olderThan time.Duration) (int64, error) {
+// GetInRange retrieves operations created within a time range in replay order
+func (r *OperationRepository) GetInRange(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+	limit, offset int,
+) ([]*models.Operation, error) {
+	query := `
+		SELECT id, workspace_id, element_id, user_id, op_type, data, timestamp, created_at
+		FROM operations
+		WHERE workspace_id = $1 AND created_at >= $2 AND created_at <= $3
+		ORDER BY created_at ASC, timestamp ASC, id ASC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID, from, to, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := make([]*models.Operation, 0)
+	for rows.Next() {
+		var op models.Operation
+		err := rows.Scan(
+			&op.ID,
+			&op.WorkspaceID,
+			&op.ElementID,
+			&op.UserID,
+			&op.OpType,
+			&op.Data,
+			&op.Timestamp,
+			&op.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, &op)
+	}
+
+	return operations, nil
+}
+
+// ForEachInReport passes the operations of a workspace created in [from, to)
+// to fn in replay order, with who made them and the type of their element.
+// A zero from starts at the beginning of history. At most limit operations
+// are read.
+func (r *OperationRepository) ForEachInReport(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+	limit int,
+	fn func(row *models.OperationReportRow) error,
+) error {
+	query := `
+		SELECT o.created_at, o.user_id, COALESCE(u.name, ''), COALESCE(u.email, ''),
+			o.element_id, COALESCE(e.element_type, ''), o.op_type, o.timestamp
+		FROM operations o
+		LEFT JOIN users u ON u.id = o.user_id
+		LEFT JOIN canvas_elements e ON e.id = o.element_id
+		WHERE o.workspace_id = $1 AND o.created_at >= $2 AND o.created_at < $3
+		ORDER BY o.created_at ASC, o.timestamp ASC, o.id ASC
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID, from, to, limit)
+	if err != nil {
+		return fmt.Errorf("failed to list report operations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.OperationReportRow
+		err := rows.Scan(
+			&row.Time,
+			&row.UserID,
+			&row.UserName,
+			&row.UserEmail,
+			&row.ElementID,
+			&row.ElementType,
+			&row.OpType,
+			&row.Timestamp,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan report operation: %w", err)
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteOldOperations deletes operations older than specified duration. The
+// monthly partitions that are entirely older are dropped, the rest of the
+// expired rows are deleted. It returns the dropped partitions and the number
+// of deleted rows.
+func (r *OperationRepository) DeleteOldOperations(ctx context.Context, olderThan time.Duration) ([]string, int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT drop_operations_partitions((NOW() - $1::interval)::timestamp)`, olderThan)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to drop operation partitions: %w", err)
+	}
+	dropped, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to drop operation partitions: %w", err)
+	}
+
 	query := `
 		DELETE FROM operations
-		WHERE created_at < $1
+		WHERE created_at < NOW() - $1::interval
 	`
 
-	cutoffTime := time.Now().Add(-olderThan)
-	result, err := r.db.Exec(ctx, query, cutoffTime)
+	result, err := r.db.Exec(ctx, query, olderThan)
 	if err != nil {
-		return 0, err
+		return dropped, 0, fmt.Errorf("failed to delete operations: %w", err)
 	}
 
-	return result.RowsAffected(), nil
+	return dropped, result.RowsAffected(), nil
+}
+
+// EnsurePartitions creates the partitions of the current month and of the
+// monthsAhead months after it, so new operations never land in the default
+// partition. It returns the partitions it created.
+func (r *OperationRepository) EnsurePartitions(ctx context.Context, monthsAhead int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT ensure_operations_partitions($1)`, monthsAhead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation partitions: %w", err)
+	}
+	created, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation partitions: %w", err)
+	}
+	return created, nil
 }
 
 // GetOperationCount returns the count of operations for a workspace
@@ -222,3 +350,16 @@ func (r *OperationRepository) GetOperationCount(ctx context.Context, workspaceID
 	err := r.db.QueryRow(ctx, query, workspaceID).Scan(&count)
 	return count, err
 }
+
+// GetLatestTimestamp returns the highest Lamport timestamp for a workspace
+func (r *OperationRepository) GetLatestTimestamp(ctx context.Context, workspaceID uuid.UUID) (int64, error) {
+	query := `
+		SELECT COALESCE(MAX(timestamp), 0)
+		FROM operations
+		WHERE workspace_id = $1
+	`
+
+	var timestamp int64
+	err := r.db.QueryRow(ctx, query, workspaceID).Scan(&timestamp)
+	return timestamp, err
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// OutboxRepository handles the transactional outbox. Repositories write
+// messages in the transaction of the change that caused them with
+// insertOutboxMessages, the outbox relay publishes them after the commit.
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+const insertOutboxQuery = `
+	INSERT INTO outbox_events (id, subject, payload, headers, jetstream)
+	SELECT id, subject, payload, headers, jetstream
+	FROM unnest($1::uuid[], $2::text[], $3::bytea[], $4::jsonb[], $5::bool[])
+		AS m(id, subject, payload, headers, jetstream)
+`
+
+// Enqueue stores messages outside of any other transaction, for changes
+// that aren't written to PostgreSQL or can't share their transaction
+func (r *OutboxRepository) Enqueue(ctx context.Context, messages ...*models.OutboxMessage) error {
+	args, err := outboxInsertArgs(messages)
+	if err != nil || args == nil {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, insertOutboxQuery, args...); err != nil {
+		return fmt.Errorf("failed to enqueue outbox messages: %w", err)
+	}
+
+	return nil
+}
+
+// insertOutboxMessages stores messages in tx, so they are published if and
+// only if the transaction commits
+func insertOutboxMessages(ctx context.Context, tx pgx.Tx, messages []*models.OutboxMessage) error {
+	args, err := outboxInsertArgs(messages)
+	if err != nil || args == nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, insertOutboxQuery, args...); err != nil {
+		return fmt.Errorf("failed to write outbox messages: %w", err)
+	}
+
+	return nil
+}
+
+// outboxInsertArgs returns the arguments of insertOutboxQuery, nil when
+// there are no messages. nil messages are skipped.
+func outboxInsertArgs(messages []*models.OutboxMessage) ([]any, error) {
+	var ids []uuid.UUID
+	var subjects []string
+	var payloads [][]byte
+	var headers []string
+	var jetstream []bool
+
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		if msg.ID == uuid.Nil {
+			msg.ID = uuid.New()
+		}
+
+		encoded, err := json.Marshal(msg.Headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal outbox headers: %w", err)
+		}
+		if msg.Headers == nil {
+			encoded = []byte("{}")
+		}
+
+		ids = append(ids, msg.ID)
+		subjects = append(subjects, msg.Subject)
+		payloads = append(payloads, msg.Payload)
+		headers = append(headers, string(encoded))
+		jetstream = append(jetstream, msg.JetStream)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	return []any{ids, subjects, payloads, headers, jetstream}, nil
+}
+
+// PublishPending locks up to limit unpublished messages in write order and
+// hands them to publish. publish returns how many of the messages it
+// published, in order, and why it stopped early. Those are marked published
+// and the one that failed records the error. Rows locked by another relay
+// are skipped, so several relays can run at once.
+//
+// A message published right before the commit fails is published again by
+// the next call, delivery is at least once.
+func (r *OutboxRepository) PublishPending(
+	ctx context.Context,
+	limit int,
+	publish func(messages []models.OutboxMessage) (int, error),
+) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		SELECT id, subject, payload, headers, jetstream, attempts, last_error, created_at
+		FROM outbox_events
+		WHERE published_at IS NULL
+		ORDER BY seq
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load outbox messages: %w", err)
+	}
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var msg models.OutboxMessage
+		if err := rows.Scan(
+			&msg.ID, &msg.Subject, &msg.Payload, &msg.Headers, &msg.JetStream,
+			&msg.Attempts, &msg.LastError, &msg.CreatedAt,
+		); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to load outbox messages: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	published, publishErr := publish(messages)
+
+	if published > 0 {
+		ids := make([]uuid.UUID, published)
+		for i := range ids {
+			ids[i] = messages[i].ID
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE outbox_events SET published_at = NOW(), attempts = attempts + 1 WHERE id = ANY($1)`, ids,
+		); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox messages published: %w", err)
+		}
+	}
+
+	if publishErr != nil && published < len(messages) {
+		if _, err := tx.Exec(ctx,
+			`UPDATE outbox_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1`,
+			messages[published].ID, publishErr.Error(),
+		); err != nil {
+			return 0, fmt.Errorf("failed to record outbox error: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return published, publishErr
+}
+
+// CountPending returns the number of unpublished messages and the age of
+// the oldest one
+func (r *OutboxRepository) CountPending(ctx context.Context) (int64, time.Duration, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)::float8
+		FROM outbox_events
+		WHERE published_at IS NULL
+	`
+
+	var count int64
+	var lag float64
+	if err := r.db.QueryRow(ctx, query).Scan(&count, &lag); err != nil {
+		return 0, 0, fmt.Errorf("failed to count outbox messages: %w", err)
+	}
+	return count, time.Duration(lag * float64(time.Second)), nil
+}
+
+// DeletePublishedOlderThan deletes messages published more than age ago
+func (r *OutboxRepository) DeletePublishedOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM outbox_events WHERE published_at < NOW() - $1::interval`, age)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox messages: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type PresentationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPresentationRepository(db *pgxpool.Pool) *PresentationRepository {
+	return &PresentationRepository{db: db}
+}
+
+const presentationColumns = `workspace_id, slide_ids, presenter_id, current_slide, started_at, updated_at`
+
+// presentationRunning matches presentations whose session runs. Deleting
+// the account of the presenter ends it.
+const presentationRunning = `started_at IS NOT NULL AND presenter_id IS NOT NULL`
+
+// scanPresentation scans the presentation columns, nil if there was no row
+func scanPresentation(row pgx.Row) (*models.Presentation, error) {
+	var p models.Presentation
+	err := row.Scan(
+		&p.WorkspaceID,
+		&p.SlideIDs,
+		&p.PresenterID,
+		&p.CurrentSlide,
+		&p.StartedAt,
+		&p.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.SlideIDs == nil {
+		p.SlideIDs = []uuid.UUID{}
+	}
+	return &p, nil
+}
+
+// GetPresentation retrieves the presentation of a workspace, nil if it has
+// no slides yet
+func (r *PresentationRepository) GetPresentation(ctx context.Context, workspaceID uuid.UUID) (*models.Presentation, error) {
+	query := `SELECT ` + presentationColumns + ` FROM workspace_presentations WHERE workspace_id = $1`
+
+	p, err := scanPresentation(r.db.QueryRow(ctx, query, workspaceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presentation: %w", err)
+	}
+	return p, nil
+}
+
+// SetSlides replaces the slide order of a workspace. A running session
+// stays on the last slide if its slide was dropped, and ends without slides.
+func (r *PresentationRepository) SetSlides(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	slideIDs []uuid.UUID,
+) (*models.Presentation, error) {
+	query := `
+		INSERT INTO workspace_presentations (workspace_id, slide_ids)
+		VALUES ($1, $2)
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			slide_ids = EXCLUDED.slide_ids,
+			current_slide = LEAST(workspace_presentations.current_slide, GREATEST(cardinality(EXCLUDED.slide_ids) - 1, 0)),
+			presenter_id = CASE WHEN cardinality(EXCLUDED.slide_ids) = 0 THEN NULL ELSE workspace_presentations.presenter_id END,
+			started_at = CASE WHEN cardinality(EXCLUDED.slide_ids) = 0 THEN NULL ELSE workspace_presentations.started_at END,
+			updated_at = NOW()
+		RETURNING ` + presentationColumns
+
+	p, err := scanPresentation(r.db.QueryRow(ctx, query, workspaceID, slideIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set presentation slides: %w", err)
+	}
+	return p, nil
+}
+
+// StartPresentation starts a session on a slide. Returns nil if a session
+// already runs or the slide doesn't exist.
+func (r *PresentationRepository) StartPresentation(
+	ctx context.Context,
+	workspaceID, presenterID uuid.UUID,
+	slide int,
+) (*models.Presentation, error) {
+	query := `
+		UPDATE workspace_presentations
+		SET presenter_id = $2, current_slide = $3, started_at = NOW(), updated_at = NOW()
+		WHERE workspace_id = $1 AND NOT (` + presentationRunning + `) AND $3 < cardinality(slide_ids)
+		RETURNING ` + presentationColumns
+
+	p, err := scanPresentation(r.db.QueryRow(ctx, query, workspaceID, presenterID, slide))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start presentation: %w", err)
+	}
+	return p, nil
+}
+
+// StopPresentation ends the running session. Returns nil if none runs.
+func (r *PresentationRepository) StopPresentation(ctx context.Context, workspaceID uuid.UUID) (*models.Presentation, error) {
+	query := `
+		UPDATE workspace_presentations
+		SET presenter_id = NULL, started_at = NULL, current_slide = 0, updated_at = NOW()
+		WHERE workspace_id = $1 AND ` + presentationRunning + `
+		RETURNING ` + presentationColumns
+
+	p, err := scanPresentation(r.db.QueryRow(ctx, query, workspaceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop presentation: %w", err)
+	}
+	return p, nil
+}
+
+// SetPresenter hands the running session to another user. Returns nil if
+// none runs.
+func (r *PresentationRepository) SetPresenter(
+	ctx context.Context,
+	workspaceID, presenterID uuid.UUID,
+) (*models.Presentation, error) {
+	query := `
+		UPDATE workspace_presentations
+		SET presenter_id = $2, updated_at = NOW()
+		WHERE workspace_id = $1 AND ` + presentationRunning + `
+		RETURNING ` + presentationColumns
+
+	p, err := scanPresentation(r.db.QueryRow(ctx, query, workspaceID, presenterID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to change presenter: %w", err)
+	}
+	return p, nil
+}
+
+// SetCurrentSlide shows another slide of the session the user presents.
+// Returns nil if the user doesn't present or the slide doesn't exist.
+func (r *PresentationRepository) SetCurrentSlide(
+	ctx context.Context,
+	workspaceID, presenterID uuid.UUID,
+	slide int,
+) (*models.Presentation, error) {
+	query := `
+		UPDATE workspace_presentations
+		SET current_slide = $3, updated_at = NOW()
+		WHERE workspace_id = $1 AND presenter_id = $2 AND started_at IS NOT NULL AND $3 < cardinality(slide_ids)
+		RETURNING ` + presentationColumns
+
+	p, err := scanPresentation(r.db.QueryRow(ctx, query, workspaceID, presenterID, slide))
+	if err != nil {
+		return nil, fmt.Errorf("failed to change slide: %w", err)
+	}
+	return p, nil
+}
+
+// CountFrames counts the frames of a workspace among elements
+func (r *PresentationRepository) CountFrames(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	elementIDs []uuid.UUID,
+) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM canvas_elements
+		WHERE id = ANY($1) AND workspace_id = $2 AND element_type = $3 AND deleted_at IS NULL
+	`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, elementIDs, workspaceID, models.ElementTypeFrame).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count frames: %w", err)
+	}
+	return count, nil
+}
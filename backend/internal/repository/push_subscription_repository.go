@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// PushSubscriptionRepository handles browser push subscriptions
+type PushSubscriptionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPushSubscriptionRepository creates a new push subscription repository
+func NewPushSubscriptionRepository(db *pgxpool.Pool) *PushSubscriptionRepository {
+	return &PushSubscriptionRepository{db: db}
+}
+
+// Upsert stores a subscription. Endpoints are unique per browser, so an
+// endpoint that is registered again moves to the new user and keys.
+func (r *PushSubscriptionRepository) Upsert(ctx context.Context, sub *models.PushSubscription) error {
+	query := `
+		INSERT INTO push_subscriptions (id, user_id, endpoint, p256dh, auth, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (endpoint) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			user_agent = EXCLUDED.user_agent
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		sub.ID,
+		sub.UserID,
+		sub.Endpoint,
+		sub.P256dh,
+		sub.Auth,
+		sub.UserAgent,
+	).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save push subscription: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser retrieves the subscriptions of a user
+func (r *PushSubscriptionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PushSubscription, error) {
+	query := `
+		SELECT id, user_id, endpoint, p256dh, auth, user_agent, created_at, last_used_at
+		FROM push_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.PushSubscription{}
+	for rows.Next() {
+		var sub models.PushSubscription
+		err := rows.Scan(
+			&sub.ID,
+			&sub.UserID,
+			&sub.Endpoint,
+			&sub.P256dh,
+			&sub.Auth,
+			&sub.UserAgent,
+			&sub.CreatedAt,
+			&sub.LastUsedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// Delete removes the subscription of an endpoint for a user. Returns false
+// if the user has no such subscription.
+func (r *PushSubscriptionRepository) Delete(ctx context.Context, userID uuid.UUID, endpoint string) (bool, error) {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`,
+		userID, endpoint,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteByID removes a subscription the push service reported as gone
+func (r *PushSubscriptionRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM push_subscriptions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return nil
+}
+
+// MarkUsed records that a push was accepted for a subscription
+func (r *PushSubscriptionRepository) MarkUsed(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `UPDATE push_subscriptions SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to mark push subscription as used: %w", err)
+	}
+	return nil
+}
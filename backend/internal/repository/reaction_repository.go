@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type ReactionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReactionRepository(db *pgxpool.Pool) *ReactionRepository {
+	return &ReactionRepository{db: db}
+}
+
+// AddReaction adds a reaction. Returns false if the user already reacted to
+// the target with the emoji.
+func (r *ReactionRepository) AddReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	query := `
+		INSERT INTO reactions (target_type, target_id, user_id, emoji, workspace_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+	`
+
+	tag, err := r.db.Exec(ctx, query,
+		reaction.TargetType,
+		reaction.TargetID,
+		reaction.UserID,
+		reaction.Emoji,
+		reaction.WorkspaceID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add reaction: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RemoveReaction removes the reaction of a user with an emoji. Returns false
+// if there was none.
+func (r *ReactionRepository) RemoveReaction(
+	ctx context.Context,
+	targetType models.ReactionTarget,
+	targetID, userID uuid.UUID,
+	emoji string,
+) (bool, error) {
+	query := `DELETE FROM reactions WHERE target_type = $1 AND target_id = $2 AND user_id = $3 AND emoji = $4`
+
+	tag, err := r.db.Exec(ctx, query, targetType, targetID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CountEmojis counts the distinct emojis used on a target
+func (r *ReactionRepository) CountEmojis(ctx context.Context, targetType models.ReactionTarget, targetID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(DISTINCT emoji) FROM reactions WHERE target_type = $1 AND target_id = $2`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, targetType, targetID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count reactions: %w", err)
+	}
+	return count, nil
+}
+
+// Summaries aggregates the reactions of targets by emoji. Targets without
+// reactions are left out.
+func (r *ReactionRepository) Summaries(
+	ctx context.Context,
+	targetType models.ReactionTarget,
+	targetIDs []uuid.UUID,
+) (map[uuid.UUID][]models.ReactionSummary, error) {
+	summaries := make(map[uuid.UUID][]models.ReactionSummary)
+	if len(targetIDs) == 0 {
+		return summaries, nil
+	}
+
+	// Emojis in the order they were first used on each target, users in the
+	// order they reacted
+	query := `
+		SELECT target_id, emoji, user_id
+		FROM reactions
+		WHERE target_type = $1 AND target_id = ANY($2)
+		ORDER BY target_id,
+			MIN(created_at) OVER (PARTITION BY target_id, emoji), emoji,
+			created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, targetType, targetIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID, userID uuid.UUID
+		var emoji string
+		if err := rows.Scan(&targetID, &emoji, &userID); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+
+		target := summaries[targetID]
+		if len(target) == 0 || target[len(target)-1].Emoji != emoji {
+			target = append(target, models.ReactionSummary{Emoji: emoji})
+		}
+		summary := &target[len(target)-1]
+		summary.UserIDs = append(summary.UserIDs, userID)
+		summary.Count++
+		summaries[targetID] = target
+	}
+
+	return summaries, rows.Err()
+}
+
+// DeleteTargets removes the reactions of targets that are deleted
+func (r *ReactionRepository) DeleteTargets(ctx context.Context, targetType models.ReactionTarget, targetIDs []uuid.UUID) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+
+	query := `DELETE FROM reactions WHERE target_type = $1 AND target_id = ANY($2)`
+	if _, err := r.db.Exec(ctx, query, targetType, targetIDs); err != nil {
+		return fmt.Errorf("failed to delete reactions: %w", err)
+	}
+	return nil
+}
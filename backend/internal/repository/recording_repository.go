@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type RecordingRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRecordingRepository(db *pgxpool.Pool) *RecordingRepository {
+	return &RecordingRepository{db: db}
+}
+
+const recordingColumns = `id, workspace_id, started_by, name, status, storage_key, event_count, size_bytes,
+	duration_ms, truncated, started_at, stopped_at`
+
+// scanRecording scans the recording columns, nil if there was no row
+func scanRecording(row pgx.Row) (*models.SessionRecording, error) {
+	var rec models.SessionRecording
+	err := row.Scan(
+		&rec.ID,
+		&rec.WorkspaceID,
+		&rec.StartedBy,
+		&rec.Name,
+		&rec.Status,
+		&rec.StorageKey,
+		&rec.EventCount,
+		&rec.SizeBytes,
+		&rec.DurationMs,
+		&rec.Truncated,
+		&rec.StartedAt,
+		&rec.StoppedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// CreateRecording creates a running recording. Returns false if the
+// workspace already records a session.
+func (r *RecordingRepository) CreateRecording(ctx context.Context, rec *models.SessionRecording) (bool, error) {
+	query := `
+		INSERT INTO session_recordings (id, workspace_id, started_by, name, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (workspace_id) WHERE status = 'recording' DO NOTHING
+	`
+
+	tag, err := r.db.Exec(ctx, query,
+		rec.ID, rec.WorkspaceID, rec.StartedBy, rec.Name, rec.Status, rec.StartedAt.UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create recording: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetRecording retrieves a recording of a workspace, nil if not found
+func (r *RecordingRepository) GetRecording(
+	ctx context.Context,
+	workspaceID, recordingID uuid.UUID,
+) (*models.SessionRecording, error) {
+	query := `SELECT ` + recordingColumns + ` FROM session_recordings WHERE id = $1 AND workspace_id = $2`
+
+	rec, err := scanRecording(r.db.QueryRow(ctx, query, recordingID, workspaceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recording: %w", err)
+	}
+	return rec, nil
+}
+
+// GetRunningRecording retrieves the recording a workspace records, nil if
+// none runs
+func (r *RecordingRepository) GetRunningRecording(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) (*models.SessionRecording, error) {
+	query := `SELECT ` + recordingColumns + ` FROM session_recordings WHERE workspace_id = $1 AND status = 'recording'`
+
+	rec, err := scanRecording(r.db.QueryRow(ctx, query, workspaceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running recording: %w", err)
+	}
+	return rec, nil
+}
+
+// ListRecordings lists the recordings of a workspace, latest first
+func (r *RecordingRepository) ListRecordings(ctx context.Context, workspaceID uuid.UUID) ([]models.SessionRecording, error) {
+	query := `
+		SELECT ` + recordingColumns + `
+		FROM session_recordings
+		WHERE workspace_id = $1
+		ORDER BY started_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	defer rows.Close()
+
+	recordings := []models.SessionRecording{}
+	for rows.Next() {
+		rec, err := scanRecording(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recording: %w", err)
+		}
+		recordings = append(recordings, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	return recordings, nil
+}
+
+// FinishRecording marks a running recording ready with its stored events.
+// Returns nil if it isn't running anymore.
+func (r *RecordingRepository) FinishRecording(
+	ctx context.Context,
+	rec *models.SessionRecording,
+) (*models.SessionRecording, error) {
+	query := `
+		UPDATE session_recordings
+		SET status = 'ready', storage_key = $2, event_count = $3, size_bytes = $4, duration_ms = $5,
+			truncated = $6, stopped_at = $7
+		WHERE id = $1 AND status = 'recording'
+		RETURNING ` + recordingColumns
+
+	finished, err := scanRecording(r.db.QueryRow(ctx, query,
+		rec.ID, rec.StorageKey, rec.EventCount, rec.SizeBytes, rec.DurationMs, rec.Truncated, rec.StoppedAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish recording: %w", err)
+	}
+	return finished, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// readPool runs read-only queries on the read replica and retries them on
+// the primary when the replica can't be reached. Only reads that tolerate
+// replication lag go through it: listings, counts and searches. Reads that
+// back permission checks or follow a write stay on the primary.
+type readPool struct {
+	primary *pgxpool.Pool
+	replica *pgxpool.Pool
+}
+
+// newReadPool returns a pool that reads from replica, or from primary only
+// when replica is nil
+func newReadPool(primary, replica *pgxpool.Pool) *readPool {
+	return &readPool{primary: primary, replica: replica}
+}
+
+// Query runs a query that returns rows
+func (p *readPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if p.replica == nil {
+		return p.primary.Query(ctx, sql, args...)
+	}
+
+	rows, err := p.replica.Query(ctx, sql, args...)
+	if err != nil && replicaUnavailable(ctx, err) {
+		hlog.CtxWarnf(ctx, "Read replica unavailable, querying the primary: %v", err)
+		return p.primary.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+// QueryRow runs a query that returns at most one row. The fallback happens
+// on Scan, where pgx reports the errors of QueryRow.
+func (p *readPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if p.replica == nil {
+		return p.primary.QueryRow(ctx, sql, args...)
+	}
+	return &fallbackRow{ctx: ctx, pool: p, sql: sql, args: args}
+}
+
+type fallbackRow struct {
+	ctx  context.Context
+	pool *readPool
+	sql  string
+	args []any
+}
+
+func (r *fallbackRow) Scan(dest ...any) error {
+	err := r.pool.replica.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	if err != nil && replicaUnavailable(r.ctx, err) {
+		hlog.CtxWarnf(r.ctx, "Read replica unavailable, querying the primary: %v", err)
+		return r.pool.primary.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+	return err
+}
+
+// replicaUnavailable reports whether err means the replica could not serve
+// the query at all, as opposed to the query itself failing
+func replicaUnavailable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	// Connection exceptions and server shutdowns
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	return false
+}
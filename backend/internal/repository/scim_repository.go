@@ -0,0 +1,524 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// SCIMRepository handles the users, groups and group workspace mappings
+// provisioned by an identity provider through SCIM
+type SCIMRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSCIMRepository creates a new SCIM repository
+func NewSCIMRepository(db *pgxpool.Pool) *SCIMRepository {
+	return &SCIMRepository{db: db}
+}
+
+const scimUserColumns = `id, email, password_hash, name, username, avatar_url, provider, provider_id,
+	email_verified, created_at, updated_at, deactivated_at, scim_external_id`
+
+// scimUserFilters maps the filterable SCIM user attributes to conditions
+var scimUserFilters = map[string]string{
+	"id":           "id::text = $1",
+	"username":     "lower(email) = lower($1)",
+	"emails.value": "lower(email) = lower($1)",
+	"externalid":   "scim_external_id = $1",
+}
+
+// scimGroupFilters maps the filterable SCIM group attributes to conditions
+var scimGroupFilters = map[string]string{
+	"id":          "id::text = $1",
+	"displayname": "lower(display_name) = lower($1)",
+	"externalid":  "external_id = $1",
+}
+
+// ListUsers returns a page of users ordered by creation and the total number
+// of users matching the filter. A nil filter matches all users.
+func (r *SCIMRepository) ListUsers(
+	ctx context.Context,
+	filter *models.SCIMFilter,
+	offset, limit int,
+) ([]models.User, int, error) {
+	where, args, err := scimFilterCondition(scimUserFilters, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Bots belong to their workspace, not to the identity provider
+	where = "NOT is_bot AND (" + where + ")"
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users
+		WHERE %s
+		ORDER BY created_at, id
+		OFFSET $%d LIMIT $%d
+	`, scimUserColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, append(args, offset, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanSCIMUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// UpdateUser updates the attributes of a user the identity provider owns.
+// The email address comes from the identity provider, so it counts as
+// verified.
+func (r *SCIMRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users
+		SET email = $1, name = $2, scim_external_id = $3, email_verified = TRUE, updated_at = NOW()
+		WHERE id = $4
+		RETURNING email_verified, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		user.Email,
+		user.Name,
+		user.SCIMExternalID,
+		user.ID,
+	).Scan(&user.EmailVerified, &user.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserActive activates or deactivates a user. Deactivating a user also
+// signs them out by deleting their refresh tokens. It reports whether the
+// state changed.
+func (r *SCIMRepository) SetUserActive(ctx context.Context, user *models.User, active bool) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		UPDATE users
+		SET deactivated_at = CASE WHEN $2 THEN NULL ELSE NOW() END, updated_at = NOW()
+		WHERE id = $1 AND (deactivated_at IS NULL) = NOT $2
+		RETURNING deactivated_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query, user.ID, active).Scan(&user.DeactivatedAt, &user.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update user state: %w", err)
+	}
+
+	if !active {
+		if _, err := tx.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, user.ID); err != nil {
+			return false, fmt.Errorf("failed to delete user refresh tokens: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateGroup creates a SCIM group
+func (r *SCIMRepository) CreateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	query := `
+		INSERT INTO scim_groups (id, display_name, external_id)
+		VALUES ($1, $2, $3)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, group.ID, group.DisplayName, group.ExternalID).
+		Scan(&group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create SCIM group: %w", err)
+	}
+
+	return nil
+}
+
+// GetGroup retrieves a SCIM group by ID, nil if it doesn't exist
+func (r *SCIMRepository) GetGroup(ctx context.Context, groupID uuid.UUID) (*models.SCIMGroup, error) {
+	query := `SELECT id, display_name, external_id, created_at, updated_at FROM scim_groups WHERE id = $1`
+
+	var group models.SCIMGroup
+	err := r.db.QueryRow(ctx, query, groupID).Scan(
+		&group.ID,
+		&group.DisplayName,
+		&group.ExternalID,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SCIM group: %w", err)
+	}
+
+	return &group, nil
+}
+
+// GetGroupByDisplayName retrieves a SCIM group by its case-insensitive
+// display name, nil if it doesn't exist
+func (r *SCIMRepository) GetGroupByDisplayName(ctx context.Context, displayName string) (*models.SCIMGroup, error) {
+	groups, _, err := r.ListGroups(ctx, &models.SCIMFilter{Attribute: "displayname", Value: displayName}, 0, 1)
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+// ListGroups returns a page of SCIM groups ordered by creation and the total
+// number of groups matching the filter. A nil filter matches all groups.
+func (r *SCIMRepository) ListGroups(
+	ctx context.Context,
+	filter *models.SCIMFilter,
+	offset, limit int,
+) ([]models.SCIMGroup, int, error) {
+	where, args, err := scimFilterCondition(scimGroupFilters, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM scim_groups WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count SCIM groups: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, display_name, external_id, created_at, updated_at
+		FROM scim_groups
+		WHERE %s
+		ORDER BY created_at, id
+		OFFSET $%d LIMIT $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, append(args, offset, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.SCIMGroup{}
+	for rows.Next() {
+		var group models.SCIMGroup
+		if err := rows.Scan(
+			&group.ID,
+			&group.DisplayName,
+			&group.ExternalID,
+			&group.CreatedAt,
+			&group.UpdatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan SCIM group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM groups: %w", err)
+	}
+
+	return groups, total, nil
+}
+
+// UpdateGroup updates the display name and external ID of a SCIM group
+func (r *SCIMRepository) UpdateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	query := `
+		UPDATE scim_groups
+		SET display_name = $1, external_id = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, group.DisplayName, group.ExternalID, group.ID).Scan(&group.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update SCIM group: %w", err)
+	}
+
+	return nil
+}
+
+// TouchGroup bumps the modification time of a SCIM group after a change of
+// its members
+func (r *SCIMRepository) TouchGroup(ctx context.Context, group *models.SCIMGroup) error {
+	query := `UPDATE scim_groups SET updated_at = NOW() WHERE id = $1 RETURNING updated_at`
+
+	if err := r.db.QueryRow(ctx, query, group.ID).Scan(&group.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update SCIM group: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes a SCIM group with its members and workspace mappings
+func (r *SCIMRepository) DeleteGroup(ctx context.Context, groupID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM scim_groups WHERE id = $1`, groupID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete SCIM group: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ListGroupMembers returns the members of the given SCIM groups by group
+func (r *SCIMRepository) ListGroupMembers(
+	ctx context.Context,
+	groupIDs []uuid.UUID,
+) (map[uuid.UUID][]models.SCIMGroupMember, error) {
+	query := `
+		SELECT gm.group_id, gm.user_id, u.name, u.email
+		FROM scim_group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = ANY($1)
+		ORDER BY u.email
+	`
+
+	rows, err := r.db.Query(ctx, query, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SCIM group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[uuid.UUID][]models.SCIMGroupMember, len(groupIDs))
+	for rows.Next() {
+		var member models.SCIMGroupMember
+		if err := rows.Scan(&member.GroupID, &member.UserID, &member.Name, &member.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan SCIM group member: %w", err)
+		}
+		members[member.GroupID] = append(members[member.GroupID], member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list SCIM group members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddGroupMembers adds users to a SCIM group. Unknown users are skipped.
+func (r *SCIMRepository) AddGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	query := `
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT $1, id FROM users WHERE id = ANY($2)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, query, groupID, userIDs); err != nil {
+		return fmt.Errorf("failed to add SCIM group members: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveGroupMembers removes users from a SCIM group
+func (r *SCIMRepository) RemoveGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	query := `DELETE FROM scim_group_members WHERE group_id = $1 AND user_id = ANY($2)`
+
+	if _, err := r.db.Exec(ctx, query, groupID, userIDs); err != nil {
+		return fmt.Errorf("failed to remove SCIM group members: %w", err)
+	}
+
+	return nil
+}
+
+// ListGroupWorkspaces returns the workspace mappings of the given SCIM
+// groups by group
+func (r *SCIMRepository) ListGroupWorkspaces(
+	ctx context.Context,
+	groupIDs []uuid.UUID,
+) (map[uuid.UUID][]models.SCIMGroupWorkspace, error) {
+	query := `
+		SELECT gw.group_id, gw.workspace_id, w.name, gw.role, gw.created_at
+		FROM scim_group_workspaces gw
+		JOIN workspaces w ON w.id = gw.workspace_id
+		WHERE gw.group_id = ANY($1)
+		ORDER BY w.name
+	`
+
+	rows, err := r.db.Query(ctx, query, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SCIM group workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := make(map[uuid.UUID][]models.SCIMGroupWorkspace, len(groupIDs))
+	for rows.Next() {
+		var mapping models.SCIMGroupWorkspace
+		if err := rows.Scan(
+			&mapping.GroupID,
+			&mapping.WorkspaceID,
+			&mapping.WorkspaceName,
+			&mapping.Role,
+			&mapping.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan SCIM group workspace: %w", err)
+		}
+		mappings[mapping.GroupID] = append(mappings[mapping.GroupID], mapping)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list SCIM group workspaces: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// SetGroupWorkspace grants the members of a SCIM group a role in a workspace
+func (r *SCIMRepository) SetGroupWorkspace(ctx context.Context, mapping *models.SCIMGroupWorkspace) error {
+	query := `
+		INSERT INTO scim_group_workspaces (group_id, workspace_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (group_id, workspace_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, mapping.GroupID, mapping.WorkspaceID, mapping.Role).Scan(&mapping.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set SCIM group workspace: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGroupWorkspace removes the mapping of a SCIM group to a workspace
+func (r *SCIMRepository) DeleteGroupWorkspace(ctx context.Context, groupID, workspaceID uuid.UUID) (bool, error) {
+	query := `DELETE FROM scim_group_workspaces WHERE group_id = $1 AND workspace_id = $2`
+
+	result, err := r.db.Exec(ctx, query, groupID, workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete SCIM group workspace: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// scimDesiredMemberships selects the memberships the SCIM groups grant the
+// users in $1: the highest role per workspace, for active users only
+const scimDesiredMemberships = `
+	WITH desired AS (
+		SELECT DISTINCT ON (gm.user_id, gw.workspace_id) gm.user_id, gw.workspace_id, gw.role
+		FROM scim_group_members gm
+		JOIN scim_group_workspaces gw ON gw.group_id = gm.group_id
+		JOIN users u ON u.id = gm.user_id AND u.deactivated_at IS NULL
+		WHERE gm.user_id = ANY($1)
+		ORDER BY gm.user_id, gw.workspace_id, CASE gw.role WHEN 'editor' THEN 0 ELSE 1 END
+	)
+`
+
+// SyncMemberships brings the SCIM managed workspace memberships of users in
+// line with their groups. Memberships that weren't granted by SCIM, such as
+// owners and invited members, are never changed.
+func (r *SCIMRepository) SyncMemberships(ctx context.Context, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	statements := []string{
+		scimDesiredMemberships + `
+			DELETE FROM workspace_members wm
+			WHERE wm.scim_managed AND wm.user_id = ANY($1)
+			  AND NOT EXISTS (
+				SELECT 1 FROM desired d WHERE d.user_id = wm.user_id AND d.workspace_id = wm.workspace_id
+			  )
+		`,
+		scimDesiredMemberships + `
+			UPDATE workspace_members wm
+			SET role = d.role
+			FROM desired d
+			WHERE wm.scim_managed AND wm.user_id = d.user_id AND wm.workspace_id = d.workspace_id
+			  AND wm.role <> d.role
+		`,
+		scimDesiredMemberships + `
+			INSERT INTO workspace_members (id, workspace_id, user_id, role, scim_managed)
+			SELECT gen_random_uuid(), d.workspace_id, d.user_id, d.role, TRUE
+			FROM desired d
+			ON CONFLICT (workspace_id, user_id) DO NOTHING
+		`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, userIDs); err != nil {
+			return fmt.Errorf("failed to sync SCIM memberships: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// scimFilterCondition returns the WHERE condition and arguments of a filter
+func scimFilterCondition(conditions map[string]string, filter *models.SCIMFilter) (string, []any, error) {
+	if filter == nil {
+		return "TRUE", nil, nil
+	}
+
+	condition, ok := conditions[filter.Attribute]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported filter attribute %q", filter.Attribute)
+	}
+
+	return condition, []any{filter.Value}, nil
+}
+
+func scanSCIMUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Name,
+		&user.Username,
+		&user.AvatarURL,
+		&user.Provider,
+		&user.ProviderID,
+		&user.EmailVerified,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeactivatedAt,
+		&user.SCIMExternalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
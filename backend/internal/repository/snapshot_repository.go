@@ -8,45 +8,74 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/bifshteksex/hertz-board/internal/encryption"
 	"github.com/bifshteksex/hertz-board/internal/models"
 )
 
 type SnapshotRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	read    *readPool
+	keyring *encryption.Keyring
 }
 
-func NewSnapshotRepository(db *pgxpool.Pool) *SnapshotRepository {
-	return &SnapshotRepository{db: db}
+// NewSnapshotRepository creates the repository. Listings and counts are read
+// from replica when it is not nil. Inline snapshot data is encrypted with
+// keyring when it is not nil.
+func NewSnapshotRepository(db, replica *pgxpool.Pool, keyring *encryption.Keyring) *SnapshotRepository {
+	return &SnapshotRepository{db: db, read: newReadPool(db, replica), keyring: keyring}
 }
 
 // CreateSnapshot creates a new canvas snapshot
 func (r *SnapshotRepository) CreateSnapshot(ctx context.Context, snapshot *models.CanvasSnapshot) error {
 	query := `
 		INSERT INTO canvas_snapshots (
-			id, workspace_id, version, description, snapshot_data, element_count, created_by
-		) VALUES ($1, $2, get_next_snapshot_version($2), $3, $4, $5, $6)
+			id, workspace_id, version, name, description, tags, pinned,
+			snapshot_data, storage_key, compressed_size, content_hash, element_count, created_by
+		) VALUES ($1, $2, get_next_snapshot_version($2), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING version, created_at
 	`
 
+	// Payloads in object storage are not duplicated in the row
+	var snapshotData interface{}
+	if snapshot.StorageKey == nil {
+		sealed, err := sealData(ctx, r.keyring, snapshot.WorkspaceID, snapshot.ID, snapshot.SnapshotData)
+		if err != nil {
+			return err
+		}
+		snapshotData = sealed
+	}
+
 	return r.db.QueryRow(ctx, query,
 		snapshot.ID,
 		snapshot.WorkspaceID,
+		snapshot.Name,
 		snapshot.Description,
-		snapshot.SnapshotData,
+		snapshot.Tags,
+		snapshot.Pinned,
+		snapshotData,
+		snapshot.StorageKey,
+		snapshot.CompressedSize,
+		snapshot.ContentHash,
 		snapshot.ElementCount,
 		snapshot.CreatedBy,
 	).Scan(&snapshot.Version, &snapshot.CreatedAt)
 }
 
 // scanSnapshot scans a row into a CanvasSnapshot
-func (r *SnapshotRepository) scanSnapshot(row pgx.Row) (*models.CanvasSnapshot, error) {
+func (r *SnapshotRepository) scanSnapshot(ctx context.Context, row pgx.Row) (*models.CanvasSnapshot, error) {
 	var snapshot models.CanvasSnapshot
 	err := row.Scan(
 		&snapshot.ID,
 		&snapshot.WorkspaceID,
 		&snapshot.Version,
+		&snapshot.Name,
 		&snapshot.Description,
+		&snapshot.Tags,
+		&snapshot.Pinned,
 		&snapshot.SnapshotData,
+		&snapshot.StorageKey,
+		&snapshot.CompressedSize,
+		&snapshot.ContentHash,
 		&snapshot.ElementCount,
 		&snapshot.CreatedBy,
 		&snapshot.CreatedAt,
@@ -59,67 +88,75 @@ func (r *SnapshotRepository) scanSnapshot(row pgx.Row) (*models.CanvasSnapshot,
 		return nil, fmt.Errorf("failed to scan snapshot: %w", err)
 	}
 
+	err = openData(ctx, r.keyring, snapshot.WorkspaceID, snapshot.ID, &snapshot.SnapshotData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot %s: %w", snapshot.ID, err)
+	}
+
 	return &snapshot, nil
 }
 
 // GetSnapshotByID retrieves a snapshot by ID
 func (r *SnapshotRepository) GetSnapshotByID(ctx context.Context, id uuid.UUID) (*models.CanvasSnapshot, error) {
 	query := `
-		SELECT id, workspace_id, version, description, snapshot_data, element_count, created_by, created_at
+		SELECT id, workspace_id, version, name, description, tags, pinned, snapshot_data, storage_key, COALESCE(compressed_size, 0),
+		       content_hash, element_count, created_by, created_at
 		FROM canvas_snapshots
 		WHERE id = $1
 	`
 
-	return r.scanSnapshot(r.db.QueryRow(ctx, query, id))
+	return r.scanSnapshot(ctx, r.db.QueryRow(ctx, query, id))
 }
 
 // GetSnapshotByVersion retrieves a snapshot by workspace and version number
 func (r *SnapshotRepository) GetSnapshotByVersion(ctx context.Context, workspaceID uuid.UUID, version int) (*models.CanvasSnapshot, error) {
 	query := `
-		SELECT id, workspace_id, version, description, snapshot_data, element_count, created_by, created_at
+		SELECT id, workspace_id, version, name, description, tags, pinned, snapshot_data, storage_key, COALESCE(compressed_size, 0),
+		       content_hash, element_count, created_by, created_at
 		FROM canvas_snapshots
 		WHERE workspace_id = $1 AND version = $2
 	`
 
-	return r.scanSnapshot(r.db.QueryRow(ctx, query, workspaceID, version))
+	return r.scanSnapshot(ctx, r.db.QueryRow(ctx, query, workspaceID, version))
 }
 
 // GetLatestSnapshot retrieves the latest snapshot for a workspace
 func (r *SnapshotRepository) GetLatestSnapshot(ctx context.Context, workspaceID uuid.UUID) (*models.CanvasSnapshot, error) {
 	query := `
-		SELECT id, workspace_id, version, description, snapshot_data, element_count, created_by, created_at
+		SELECT id, workspace_id, version, name, description, tags, pinned, snapshot_data, storage_key, COALESCE(compressed_size, 0),
+		       content_hash, element_count, created_by, created_at
 		FROM canvas_snapshots
 		WHERE workspace_id = $1
 		ORDER BY version DESC
 		LIMIT 1
 	`
 
-	return r.scanSnapshot(r.db.QueryRow(ctx, query, workspaceID))
+	return r.scanSnapshot(ctx, r.db.QueryRow(ctx, query, workspaceID))
 }
 
-// ListSnapshots retrieves all snapshots for a workspace with pagination
+// ListSnapshots retrieves snapshots of a workspace matching the filter with pagination
 func (r *SnapshotRepository) ListSnapshots(
 	ctx context.Context,
 	workspaceID uuid.UUID,
-	limit, offset int,
+	filter models.SnapshotListFilter,
 ) ([]models.CanvasSnapshot, int, error) {
+	where, args := snapshotListWhere(` WHERE workspace_id = $1`, []interface{}{workspaceID}, filter)
+	argCount := len(args)
+
 	// Get total count
 	var total int
-	countQuery := `SELECT COUNT(*) FROM canvas_snapshots WHERE workspace_id = $1`
-	if err := r.db.QueryRow(ctx, countQuery, workspaceID).Scan(&total); err != nil {
+	if err := r.read.QueryRow(ctx, "SELECT COUNT(*) FROM canvas_snapshots"+where, args...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count snapshots: %w", err)
 	}
 
 	// Get snapshots
 	query := `
-		SELECT id, workspace_id, version, description, snapshot_data, element_count, created_by, created_at
-		FROM canvas_snapshots
-		WHERE workspace_id = $1
-		ORDER BY version DESC
-		LIMIT $2 OFFSET $3
-	`
+		SELECT id, workspace_id, version, name, description, tags, pinned, storage_key, COALESCE(compressed_size, 0),
+		       element_count, created_by, created_at
+		FROM canvas_snapshots` + where + fmt.Sprintf(" ORDER BY version DESC LIMIT $%d OFFSET $%d", argCount+1, argCount+2)
+	args = append(args, filter.Limit, filter.Offset)
 
-	rows, err := r.db.Query(ctx, query, workspaceID, limit, offset)
+	rows, err := r.read.Query(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
 	}
@@ -132,8 +169,12 @@ func (r *SnapshotRepository) ListSnapshots(
 			&snapshot.ID,
 			&snapshot.WorkspaceID,
 			&snapshot.Version,
+			&snapshot.Name,
 			&snapshot.Description,
-			&snapshot.SnapshotData,
+			&snapshot.Tags,
+			&snapshot.Pinned,
+			&snapshot.StorageKey,
+			&snapshot.CompressedSize,
 			&snapshot.ElementCount,
 			&snapshot.CreatedBy,
 			&snapshot.CreatedAt,
@@ -151,21 +192,325 @@ func (r *SnapshotRepository) ListSnapshots(
 	return snapshots, total, nil
 }
 
-// DeleteOldSnapshots deletes old snapshots keeping only the latest N versions
-func (r *SnapshotRepository) DeleteOldSnapshots(ctx context.Context, workspaceID uuid.UUID, keepCount int) error {
+// ListSnapshotsByWorkspaces retrieves a page of snapshots of each of several
+// workspaces with one count and one list query. Every workspace is paged
+// separately, like ListSnapshots does.
+func (r *SnapshotRepository) ListSnapshotsByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+	filter models.SnapshotListFilter,
+) (map[uuid.UUID]*models.SnapshotPage, error) {
+	where, args := snapshotListWhere(` WHERE workspace_id = ANY($1)`, []interface{}{workspaceIDs}, filter)
+	argCount := len(args)
+
+	pages := make(map[uuid.UUID]*models.SnapshotPage, len(workspaceIDs))
+	for _, workspaceID := range workspaceIDs {
+		pages[workspaceID] = &models.SnapshotPage{}
+	}
+
+	countRows, err := r.read.Query(ctx, "SELECT workspace_id, COUNT(*) FROM canvas_snapshots"+where+" GROUP BY workspace_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var workspaceID uuid.UUID
+		var total int
+		if err := countRows.Scan(&workspaceID, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot count: %w", err)
+		}
+		if page, ok := pages[workspaceID]; ok {
+			page.Total = total
+		}
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating snapshot counts: %w", err)
+	}
+
+	query := `
+		SELECT id, workspace_id, version, name, description, tags, pinned, storage_key, compressed_size,
+		       element_count, created_by, created_at
+		FROM (
+			SELECT id, workspace_id, version, name, description, tags, pinned, storage_key,
+			       COALESCE(compressed_size, 0) AS compressed_size, element_count, created_by, created_at,
+			       ROW_NUMBER() OVER (PARTITION BY workspace_id ORDER BY version DESC) AS position
+			FROM canvas_snapshots` + where + `
+		) s` + fmt.Sprintf(" WHERE position > $%d AND position <= $%d ORDER BY workspace_id, version DESC", argCount+1, argCount+2)
+	args = append(args, filter.Offset, filter.Offset+filter.Limit)
+
+	rows, err := r.read.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot models.CanvasSnapshot
+		err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.WorkspaceID,
+			&snapshot.Version,
+			&snapshot.Name,
+			&snapshot.Description,
+			&snapshot.Tags,
+			&snapshot.Pinned,
+			&snapshot.StorageKey,
+			&snapshot.CompressedSize,
+			&snapshot.ElementCount,
+			&snapshot.CreatedBy,
+			&snapshot.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		if page, ok := pages[snapshot.WorkspaceID]; ok {
+			page.Snapshots = append(page.Snapshots, snapshot)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating snapshots: %w", err)
+	}
+
+	return pages, nil
+}
+
+// snapshotListWhere appends the conditions of a snapshot filter to a WHERE
+// clause whose arguments are args
+func snapshotListWhere(where string, args []interface{}, filter models.SnapshotListFilter) (string, []interface{}) {
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		where += fmt.Sprintf(" AND $%d = ANY(tags)", len(args))
+	}
+
+	if filter.Pinned != nil {
+		args = append(args, *filter.Pinned)
+		where += fmt.Sprintf(" AND pinned = $%d", len(args))
+	}
+
+	return where, args
+}
+
+// GetSnapshotsForRetention retrieves the metadata of all snapshots of a
+// workspace without payloads, newest first
+func (r *SnapshotRepository) GetSnapshotsForRetention(ctx context.Context, workspaceID uuid.UUID) ([]models.CanvasSnapshot, error) {
+	query := `
+		SELECT id, version, name, description, tags, pinned, element_count, created_by, created_at
+		FROM canvas_snapshots
+		WHERE workspace_id = $1
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []models.CanvasSnapshot
+	for rows.Next() {
+		snapshot := models.CanvasSnapshot{WorkspaceID: workspaceID}
+		err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.Version,
+			&snapshot.Name,
+			&snapshot.Description,
+			&snapshot.Tags,
+			&snapshot.Pinned,
+			&snapshot.ElementCount,
+			&snapshot.CreatedBy,
+			&snapshot.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// DeleteSnapshots deletes unpinned snapshots of a workspace and returns the
+// storage keys of the deleted payloads
+func (r *SnapshotRepository) DeleteSnapshots(ctx context.Context, workspaceID uuid.UUID, ids []uuid.UUID) ([]string, error) {
 	query := `
 		DELETE FROM canvas_snapshots
 		WHERE workspace_id = $1
-		  AND version < (
-		      SELECT MAX(version) - $2
-		      FROM canvas_snapshots
-		      WHERE workspace_id = $1
-		  )
+		  AND id = ANY($2)
+		  AND NOT pinned
+		RETURNING storage_key
 	`
 
-	_, err := r.db.Exec(ctx, query, workspaceID, keepCount)
+	rows, err := r.db.Query(ctx, query, workspaceID, ids)
 	if err != nil {
-		return fmt.Errorf("failed to delete old snapshots: %w", err)
+		return nil, fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key *string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan storage key: %w", err)
+		}
+		if key != nil {
+			keys = append(keys, *key)
+		}
+	}
+
+	return keys, rows.Err()
+}
+
+// GetSnapshotWorkspaces retrieves IDs of workspaces that have snapshots,
+// ordered by ID and starting after afterID for keyset pagination
+func (r *SnapshotRepository) GetSnapshotWorkspaces(ctx context.Context, afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT DISTINCT workspace_id
+		FROM canvas_snapshots
+		WHERE workspace_id > $1
+		ORDER BY workspace_id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetRetentionPolicy retrieves the retention policy of a workspace, nil when
+// the workspace uses the default
+func (r *SnapshotRepository) GetRetentionPolicy(ctx context.Context, workspaceID uuid.UUID) (*models.SnapshotRetentionPolicy, error) {
+	query := `
+		SELECT workspace_id, max_count, max_age_days, keep_daily, keep_weekly, updated_by, updated_at
+		FROM snapshot_retention_policies
+		WHERE workspace_id = $1
+	`
+
+	var policy models.SnapshotRetentionPolicy
+	err := r.db.QueryRow(ctx, query, workspaceID).Scan(
+		&policy.WorkspaceID,
+		&policy.MaxCount,
+		&policy.MaxAgeDays,
+		&policy.KeepDaily,
+		&policy.KeepWeekly,
+		&policy.UpdatedBy,
+		&policy.UpdatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+// UpsertRetentionPolicy creates or replaces the retention policy of a workspace
+func (r *SnapshotRepository) UpsertRetentionPolicy(ctx context.Context, policy *models.SnapshotRetentionPolicy) error {
+	query := `
+		INSERT INTO snapshot_retention_policies (
+			workspace_id, max_count, max_age_days, keep_daily, keep_weekly, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (workspace_id) DO UPDATE SET
+			max_count = EXCLUDED.max_count,
+			max_age_days = EXCLUDED.max_age_days,
+			keep_daily = EXCLUDED.keep_daily,
+			keep_weekly = EXCLUDED.keep_weekly,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		policy.WorkspaceID,
+		policy.MaxCount,
+		policy.MaxAgeDays,
+		policy.KeepDaily,
+		policy.KeepWeekly,
+		policy.UpdatedBy,
+	).Scan(&policy.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save retention policy: %w", err)
+	}
+
+	return nil
+}
+
+// GetInlineSnapshots retrieves snapshots whose payload is still stored in the row
+func (r *SnapshotRepository) GetInlineSnapshots(ctx context.Context, limit int) ([]models.CanvasSnapshot, error) {
+	query := `
+		SELECT id, workspace_id, version, name, description, tags, pinned, snapshot_data, storage_key, COALESCE(compressed_size, 0),
+		       content_hash, element_count, created_by, created_at
+		FROM canvas_snapshots
+		WHERE storage_key IS NULL
+		ORDER BY created_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inline snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []models.CanvasSnapshot
+	for rows.Next() {
+		snapshot, err := r.scanSnapshot(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// UpdateSnapshot updates the name, description, tags and pinned flag of a snapshot
+func (r *SnapshotRepository) UpdateSnapshot(ctx context.Context, snapshot *models.CanvasSnapshot) error {
+	query := `
+		UPDATE canvas_snapshots
+		SET name = $2, description = $3, tags = $4, pinned = $5
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query, snapshot.ID, snapshot.Name, snapshot.Description, snapshot.Tags, snapshot.Pinned)
+	if err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("snapshot not found")
+	}
+
+	return nil
+}
+
+// SetSnapshotStorage records where a payload was stored and drops the inline copy
+func (r *SnapshotRepository) SetSnapshotStorage(ctx context.Context, id uuid.UUID, storageKey string, size int64) error {
+	query := `
+		UPDATE canvas_snapshots
+		SET storage_key = $2, compressed_size = $3, snapshot_data = NULL
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, storageKey, size); err != nil {
+		return fmt.Errorf("failed to update snapshot storage: %w", err)
 	}
 
 	return nil
@@ -176,7 +521,7 @@ func (r *SnapshotRepository) GetSnapshotCount(ctx context.Context, workspaceID u
 	var count int
 	query := `SELECT COUNT(*) FROM canvas_snapshots WHERE workspace_id = $1`
 
-	err := r.db.QueryRow(ctx, query, workspaceID).Scan(&count)
+	err := r.read.QueryRow(ctx, query, workspaceID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count snapshots: %w", err)
 	}
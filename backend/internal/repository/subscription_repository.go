@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type SubscriptionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSubscriptionRepository(db *pgxpool.Pool) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+const subscriptionColumns = `s.user_id, s.plan, s.status, s.stripe_customer_id, s.stripe_subscription_id,
+	s.current_period_end, s.cancel_at_period_end, s.created_at, s.updated_at`
+
+func (r *SubscriptionRepository) getSubscription(ctx context.Context, query string, arg interface{}) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := r.db.QueryRow(ctx, query, arg).Scan(
+		&sub.UserID,
+		&sub.Plan,
+		&sub.Status,
+		&sub.StripeCustomerID,
+		&sub.StripeSubscriptionID,
+		&sub.CurrentPeriodEnd,
+		&sub.CancelAtPeriodEnd,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+// GetSubscription retrieves the subscription of a user, nil if the user
+// never checked out
+func (r *SubscriptionRepository) GetSubscription(ctx context.Context, userID uuid.UUID) (*models.Subscription, error) {
+	return r.getSubscription(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions s WHERE s.user_id = $1`, userID)
+}
+
+// GetSubscriptionByCustomer retrieves the subscription of a Stripe customer
+func (r *SubscriptionRepository) GetSubscriptionByCustomer(ctx context.Context, customerID string) (*models.Subscription, error) {
+	return r.getSubscription(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions s WHERE s.stripe_customer_id = $1`, customerID)
+}
+
+// GetWorkspaceOwnerSubscription retrieves the subscription of the owner of
+// a workspace, nil if the owner has none
+func (r *SubscriptionRepository) GetWorkspaceOwnerSubscription(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) (*models.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM workspaces w
+		INNER JOIN subscriptions s ON s.user_id = w.owner_id
+		WHERE w.id = $1
+	`
+
+	return r.getSubscription(ctx, query, workspaceID)
+}
+
+// CreateSubscription records the Stripe customer of a user before their
+// first checkout
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, sub *models.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (user_id, plan, status, stripe_customer_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, sub.UserID, sub.Plan, sub.Status, sub.StripeCustomerID).
+		Scan(&sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSubscription stores the Stripe subscription state of a customer
+func (r *SubscriptionRepository) UpdateSubscription(ctx context.Context, sub *models.Subscription) error {
+	query := `
+		UPDATE subscriptions
+		SET plan = $2, status = $3, stripe_subscription_id = $4, current_period_end = $5,
+		    cancel_at_period_end = $6, updated_at = NOW()
+		WHERE stripe_customer_id = $1
+	`
+
+	result, err := r.db.Exec(ctx, query,
+		sub.StripeCustomerID,
+		sub.Plan,
+		sub.Status,
+		sub.StripeSubscriptionID,
+		sub.CurrentPeriodEnd,
+		sub.CancelAtPeriodEnd,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no subscription for customer %s", sub.StripeCustomerID)
+	}
+
+	return nil
+}
+
+// IsEventProcessed reports whether a Stripe webhook event was handled before
+func (r *SubscriptionRepository) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM stripe_events WHERE id = $1)`, eventID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check stripe event: %w", err)
+	}
+
+	return exists, nil
+}
+
+// MarkEventProcessed records a handled Stripe webhook event
+func (r *SubscriptionRepository) MarkEventProcessed(ctx context.Context, eventID, eventType string) error {
+	query := `INSERT INTO stripe_events (id, event_type) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`
+
+	if _, err := r.db.Exec(ctx, query, eventID, eventType); err != nil {
+		return fmt.Errorf("failed to record stripe event: %w", err)
+	}
+
+	return nil
+}
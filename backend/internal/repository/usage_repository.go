@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type UsageRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUsageRepository(db *pgxpool.Pool) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// RecordActiveEditor counts a user as an active editor of a workspace in a
+// month. Repeated calls for the same user and month count once.
+func (r *UsageRepository) RecordActiveEditor(ctx context.Context, workspaceID, userID uuid.UUID, period time.Time) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO usage_active_editors (workspace_id, period_start, user_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+			RETURNING workspace_id
+		)
+		INSERT INTO usage_periods (workspace_id, period_start, active_editors)
+		SELECT workspace_id, $2, 1 FROM inserted
+		ON CONFLICT (workspace_id, period_start) DO UPDATE
+		SET active_editors = usage_periods.active_editors + 1, updated_at = NOW()
+	`
+
+	if _, err := r.db.Exec(ctx, query, workspaceID, period, userID); err != nil {
+		return fmt.Errorf("failed to record active editor: %w", err)
+	}
+
+	return nil
+}
+
+// IncrementExports counts a rendered export of a workspace in a month
+func (r *UsageRepository) IncrementExports(ctx context.Context, workspaceID uuid.UUID, period time.Time) error {
+	query := `
+		INSERT INTO usage_periods (workspace_id, period_start, exports_rendered)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (workspace_id, period_start) DO UPDATE
+		SET exports_rendered = usage_periods.exports_rendered + 1, updated_at = NOW()
+	`
+
+	if _, err := r.db.Exec(ctx, query, workspaceID, period); err != nil {
+		return fmt.Errorf("failed to record export: %w", err)
+	}
+
+	return nil
+}
+
+// SampleStorage raises the peak storage of the month of every workspace
+// that stores assets to its current usage, and returns how many workspaces
+// were sampled
+func (r *UsageRepository) SampleStorage(ctx context.Context, period time.Time) (int64, error) {
+	query := `
+		INSERT INTO usage_periods (workspace_id, period_start, storage_peak_bytes)
+		SELECT id, $1, storage_used_bytes
+		FROM workspaces
+		WHERE deleted_at IS NULL AND storage_used_bytes > 0
+		ON CONFLICT (workspace_id, period_start) DO UPDATE
+		SET storage_peak_bytes = GREATEST(usage_periods.storage_peak_bytes, EXCLUDED.storage_peak_bytes),
+		    updated_at = NOW()
+	`
+
+	result, err := r.db.Exec(ctx, query, period)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sample storage usage: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+const usagePeriodColumns = `workspace_id, period_start, active_editors, storage_peak_bytes, exports_rendered,
+	reported_at, updated_at`
+
+func scanUsagePeriods(rows pgx.Rows) ([]models.UsagePeriod, error) {
+	defer rows.Close()
+
+	periods := []models.UsagePeriod{}
+	for rows.Next() {
+		var period models.UsagePeriod
+		err := rows.Scan(
+			&period.WorkspaceID,
+			&period.PeriodStart,
+			&period.ActiveEditors,
+			&period.StoragePeakBytes,
+			&period.ExportsRendered,
+			&period.ReportedAt,
+			&period.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage period: %w", err)
+		}
+		periods = append(periods, period)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage periods: %w", err)
+	}
+
+	return periods, nil
+}
+
+// ListUsage returns the last months of usage of a workspace, newest first
+func (r *UsageRepository) ListUsage(ctx context.Context, workspaceID uuid.UUID, months int) ([]models.UsagePeriod, error) {
+	query := `
+		SELECT ` + usagePeriodColumns + `
+		FROM usage_periods
+		WHERE workspace_id = $1
+		ORDER BY period_start DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID, months)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	return scanUsagePeriods(rows)
+}
+
+// ListUnreportedUsage returns usage of months before the given one that
+// hasn't been reported to billing yet, oldest first
+func (r *UsageRepository) ListUnreportedUsage(ctx context.Context, before time.Time, limit int) ([]models.UsagePeriod, error) {
+	query := `
+		SELECT ` + usagePeriodColumns + `
+		FROM usage_periods
+		WHERE reported_at IS NULL AND period_start < $1
+		ORDER BY period_start, workspace_id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unreported usage: %w", err)
+	}
+
+	return scanUsagePeriods(rows)
+}
+
+// MarkReported records that the usage of a month was reported to billing
+func (r *UsageRepository) MarkReported(ctx context.Context, workspaceID uuid.UUID, period time.Time) error {
+	query := `
+		UPDATE usage_periods SET reported_at = NOW()
+		WHERE workspace_id = $1 AND period_start = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, workspaceID, period); err != nil {
+		return fmt.Errorf("failed to mark usage reported: %w", err)
+	}
+
+	return nil
+}
@@ -25,9 +25,9 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, name, provider, provider_id, email_verified)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at
+		INSERT INTO users (email, password_hash, name, provider, provider_id, email_verified, scim_external_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, username, created_at, updated_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
@@ -37,7 +37,8 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.Provider,
 		user.ProviderID,
 		user.EmailVerified,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		user.SCIMExternalID,
+	).Scan(&user.ID, &user.Username, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
@@ -49,8 +50,8 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, avatar_url, provider, provider_id,
-		       email_verified, created_at, updated_at
+		SELECT id, email, password_hash, name, username, avatar_url, provider, provider_id,
+		       email_verified, created_at, updated_at, deactivated_at, scim_external_id, is_bot
 		FROM users
 		WHERE id = $1
 	`
@@ -61,12 +62,16 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Username,
 		&user.AvatarURL,
 		&user.Provider,
 		&user.ProviderID,
 		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeactivatedAt,
+		&user.SCIMExternalID,
+		&user.IsBot,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -79,11 +84,59 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 	return &user, nil
 }
 
+// GetByIDs retrieves the users with the given IDs. Missing users are left
+// out of the result.
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error) {
+	query := `
+		SELECT id, email, password_hash, name, username, avatar_url, provider, provider_id,
+		       email_verified, created_at, updated_at, deactivated_at, scim_external_id, is_bot
+		FROM users
+		WHERE id = ANY($1)
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by ids: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, len(ids))
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.PasswordHash,
+			&user.Name,
+			&user.Username,
+			&user.AvatarURL,
+			&user.Provider,
+			&user.ProviderID,
+			&user.EmailVerified,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.DeactivatedAt,
+			&user.SCIMExternalID,
+			&user.IsBot,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, avatar_url, provider, provider_id,
-		       email_verified, created_at, updated_at
+		SELECT id, email, password_hash, name, username, avatar_url, provider, provider_id,
+		       email_verified, created_at, updated_at, deactivated_at, scim_external_id, is_bot
 		FROM users
 		WHERE email = $1
 	`
@@ -94,12 +147,16 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Username,
 		&user.AvatarURL,
 		&user.Provider,
 		&user.ProviderID,
 		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeactivatedAt,
+		&user.SCIMExternalID,
+		&user.IsBot,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -115,8 +172,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 // GetByProvider retrieves a user by OAuth provider
 func (r *UserRepository) GetByProvider(ctx context.Context, provider, providerID string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, avatar_url, provider, provider_id,
-		       email_verified, created_at, updated_at
+		SELECT id, email, password_hash, name, username, avatar_url, provider, provider_id,
+		       email_verified, created_at, updated_at, deactivated_at, scim_external_id, is_bot
 		FROM users
 		WHERE provider = $1 AND provider_id = $2
 	`
@@ -127,12 +184,16 @@ func (r *UserRepository) GetByProvider(ctx context.Context, provider, providerID
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Username,
 		&user.AvatarURL,
 		&user.Provider,
 		&user.ProviderID,
 		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeactivatedAt,
+		&user.SCIMExternalID,
+		&user.IsBot,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -145,17 +206,55 @@ func (r *UserRepository) GetByProvider(ctx context.Context, provider, providerID
 	return &user, nil
 }
 
+// GetByUsername retrieves a user by username, compared case-insensitively
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	query := `
+		SELECT id, email, password_hash, name, username, avatar_url, provider, provider_id,
+		       email_verified, created_at, updated_at, deactivated_at, scim_external_id, is_bot
+		FROM users
+		WHERE lower(username) = lower($1)
+	`
+
+	var user models.User
+	err := r.db.QueryRow(ctx, query, username).Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Name,
+		&user.Username,
+		&user.AvatarURL,
+		&user.Provider,
+		&user.ProviderID,
+		&user.EmailVerified,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeactivatedAt,
+		&user.SCIMExternalID,
+		&user.IsBot,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	return &user, nil
+}
+
 // Update updates a user
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET name = $1, avatar_url = $2, email_verified = $3, updated_at = NOW()
-		WHERE id = $4
+		SET name = $1, username = $2, avatar_url = $3, email_verified = $4, updated_at = NOW()
+		WHERE id = $5
 		RETURNING updated_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
 		user.Name,
+		user.Username,
 		user.AvatarURL,
 		user.EmailVerified,
 		user.ID,
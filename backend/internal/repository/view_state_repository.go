@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type ViewStateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewViewStateRepository(db *pgxpool.Pool) *ViewStateRepository {
+	return &ViewStateRepository{db: db}
+}
+
+// GetViewState retrieves the persisted view state of a user on a workspace,
+// nil if none was persisted
+func (r *ViewStateRepository) GetViewState(
+	ctx context.Context,
+	userID, workspaceID uuid.UUID,
+) (*models.ViewState, error) {
+	query := `SELECT state FROM workspace_view_states WHERE user_id = $1 AND workspace_id = $2`
+
+	var stateJSON []byte
+	err := r.db.QueryRow(ctx, query, userID, workspaceID).Scan(&stateJSON)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get view state: %w", err)
+	}
+
+	var state models.ViewState
+	if err := json.Unmarshal(stateJSON, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal view state: %w", err)
+	}
+	return &state, nil
+}
+
+// UpsertViewState persists the view state of a user on a workspace unless a
+// newer one is persisted already. States of users or workspaces that no
+// longer exist are skipped.
+func (r *ViewStateRepository) UpsertViewState(
+	ctx context.Context,
+	userID, workspaceID uuid.UUID,
+	state *models.ViewState,
+) error {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal view state: %w", err)
+	}
+
+	query := `
+		INSERT INTO workspace_view_states (user_id, workspace_id, state, updated_at)
+		SELECT u.id, w.id, $3, $4
+		FROM users u
+		INNER JOIN workspaces w ON w.id = $2
+		WHERE u.id = $1
+		ON CONFLICT (user_id, workspace_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = EXCLUDED.updated_at
+		WHERE workspace_view_states.updated_at <= EXCLUDED.updated_at
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID, workspaceID, stateJSON, state.UpdatedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to persist view state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = `id, workspace_id, url, format, secret, events, active, created_by, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.WorkspaceID,
+		&webhook.URL,
+		&webhook.Format,
+		&webhook.Secret,
+		&webhook.Events,
+		&webhook.Active,
+		&webhook.CreatedBy,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook creates a new webhook
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, workspace_id, url, format, secret, events, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		webhook.ID,
+		webhook.WorkspaceID,
+		webhook.URL,
+		webhook.Format,
+		webhook.Secret,
+		webhook.Events,
+		webhook.Active,
+		webhook.CreatedBy,
+	).Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetWebhook retrieves a webhook of a workspace, nil if it doesn't exist
+func (r *WebhookRepository) GetWebhook(ctx context.Context, workspaceID, id uuid.UUID) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE workspace_id = $1 AND id = $2`
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, workspaceID, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// GetWebhookByID retrieves a webhook regardless of workspace, nil if it doesn't exist
+func (r *WebhookRepository) GetWebhookByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks retrieves all webhooks of a workspace
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, workspaceID uuid.UUID) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE workspace_id = $1 ORDER BY created_at`
+	return r.queryWebhooks(ctx, query, workspaceID)
+}
+
+// GetActiveWebhooks retrieves the enabled webhooks of a workspace
+func (r *WebhookRepository) GetActiveWebhooks(ctx context.Context, workspaceID uuid.UUID) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE workspace_id = $1 AND active ORDER BY created_at`
+	return r.queryWebhooks(ctx, query, workspaceID)
+}
+
+func (r *WebhookRepository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook saves the URL, secret, events and active flag of a webhook
+func (r *WebhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = $3, secret = $4, events = $5, active = $6, updated_at = NOW()
+		WHERE workspace_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		webhook.WorkspaceID,
+		webhook.ID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.Active,
+	).Scan(&webhook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log. Returns false if
+// the webhook doesn't exist.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, workspaceID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE workspace_id = $1 AND id = $2`, workspaceID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts,
+	response_status, error, created_at, delivered_at`
+
+func scanWebhookDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// CreateDelivery creates a pending delivery
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		delivery.ID,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+	).Scan(&delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// GetDelivery retrieves a delivery, nil if it doesn't exist
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// UpdateDeliveryAttempt records the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDeliveryAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, error = $5, delivered_at = $6
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries retrieves the delivery log of a webhook, newest first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit, offset int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, webhookID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
 
@@ -13,11 +14,14 @@ import (
 )
 
 type WorkspaceRepository struct {
-	db *pgxpool.Pool
+	db   *pgxpool.Pool
+	read *readPool
 }
 
-func NewWorkspaceRepository(db *pgxpool.Pool) *WorkspaceRepository {
-	return &WorkspaceRepository{db: db}
+// NewWorkspaceRepository creates the repository. Listings and counts are read
+// from replica when it is not nil.
+func NewWorkspaceRepository(db, replica *pgxpool.Pool) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db, read: newReadPool(db, replica)}
 }
 
 // --- Workspace CRUD ---
@@ -39,7 +43,7 @@ func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, workspace *mo
 	query := `
 		INSERT INTO workspaces (id, name, description, owner_id, thumbnail_url, is_public, settings)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING created_at, updated_at
+		RETURNING public_role, public_allow_duplicate, public_allow_anonymous, freeze_role, created_at, updated_at
 	`
 	err = tx.QueryRow(ctx, query,
 		workspace.ID,
@@ -49,7 +53,14 @@ func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, workspace *mo
 		workspace.ThumbnailURL,
 		workspace.IsPublic,
 		settingsJSON,
-	).Scan(&workspace.CreatedAt, &workspace.UpdatedAt)
+	).Scan(
+		&workspace.PublicAccess.Role,
+		&workspace.PublicAccess.AllowDuplicate,
+		&workspace.PublicAccess.AllowAnonymous,
+		&workspace.FreezeRole,
+		&workspace.CreatedAt,
+		&workspace.UpdatedAt,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to insert workspace: %w", err)
 	}
@@ -74,7 +85,9 @@ func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, workspace *mo
 // GetWorkspaceByID retrieves a workspace by ID (excluding soft-deleted)
 func (r *WorkspaceRepository) GetWorkspaceByID(ctx context.Context, id uuid.UUID) (*models.Workspace, error) {
 	query := `
-		SELECT id, name, description, owner_id, thumbnail_url, is_public, settings, deleted_at, created_at, updated_at
+		SELECT id, name, description, owner_id, thumbnail_url, is_public, settings,
+			public_role, public_allow_duplicate, public_allow_anonymous, freeze_role, frozen_at, frozen_by,
+			deleted_at, created_at, updated_at
 		FROM workspaces
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -90,6 +103,12 @@ func (r *WorkspaceRepository) GetWorkspaceByID(ctx context.Context, id uuid.UUID
 		&workspace.ThumbnailURL,
 		&workspace.IsPublic,
 		&settingsJSON,
+		&workspace.PublicAccess.Role,
+		&workspace.PublicAccess.AllowDuplicate,
+		&workspace.PublicAccess.AllowAnonymous,
+		&workspace.FreezeRole,
+		&workspace.FrozenAt,
+		&workspace.FrozenBy,
 		&workspace.DeletedAt,
 		&workspace.CreatedAt,
 		&workspace.UpdatedAt,
@@ -108,6 +127,161 @@ func (r *WorkspaceRepository) GetWorkspaceByID(ctx context.Context, id uuid.UUID
 	return &workspace, nil
 }
 
+// GetWorkspaceStats returns member, element and asset counts and storage usage
+func (r *WorkspaceRepository) GetWorkspaceStats(ctx context.Context, id uuid.UUID) (*models.WorkspaceStats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM workspace_members WHERE workspace_id = w.id),
+			(SELECT COUNT(*) FROM canvas_elements WHERE workspace_id = w.id AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM assets WHERE workspace_id = w.id AND deleted_at IS NULL AND parent_asset_id IS NULL),
+			w.storage_used_bytes
+		FROM workspaces w
+		WHERE w.id = $1 AND w.deleted_at IS NULL
+	`
+
+	var stats models.WorkspaceStats
+	err := r.read.QueryRow(ctx, query, id).Scan(
+		&stats.MemberCount,
+		&stats.ElementCount,
+		&stats.AssetCount,
+		&stats.StorageUsedBytes,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get workspace stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// GetContributors returns the users that created or edited the elements on
+// a workspace, of one element if elementID isn't nil, most contributions
+// first. Edits are taken from the operations and the last editor of each
+// element, so changes older than the operation retention still count.
+func (r *WorkspaceRepository) GetContributors(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	elementID *uuid.UUID,
+	limit int,
+) ([]models.Contributor, error) {
+	query := `
+		WITH elements AS (
+			SELECT id, created_by, created_at, updated_by, updated_at
+			FROM canvas_elements
+			WHERE workspace_id = $1 AND deleted_at IS NULL AND ($2::uuid IS NULL OR id = $2)
+		),
+		created AS (
+			SELECT created_by AS user_id, COUNT(*) AS elements,
+				MIN(created_at) AS first_at, MAX(created_at) AS last_at
+			FROM elements
+			GROUP BY created_by
+		),
+		edits AS (
+			SELECT o.user_id, o.element_id, o.created_at
+			FROM operations o
+			INNER JOIN elements e ON e.id = o.element_id
+			WHERE o.workspace_id = $1 AND o.op_type <> 'create'
+			UNION ALL
+			SELECT updated_by, id, updated_at
+			FROM elements
+			WHERE updated_by IS NOT NULL AND updated_at > created_at
+		),
+		edited AS (
+			SELECT user_id, COUNT(DISTINCT element_id) AS elements,
+				MIN(created_at) AS first_at, MAX(created_at) AS last_at
+			FROM edits
+			GROUP BY user_id
+		)
+		SELECT u.id, u.name, u.username, u.avatar_url, u.is_bot,
+			COALESCE(c.elements, 0), COALESCE(ed.elements, 0),
+			LEAST(c.first_at, ed.first_at), GREATEST(c.last_at, ed.last_at)
+		FROM (SELECT user_id FROM created UNION SELECT user_id FROM edited) contributors
+		INNER JOIN users u ON u.id = contributors.user_id
+		LEFT JOIN created c ON c.user_id = contributors.user_id
+		LEFT JOIN edited ed ON ed.user_id = contributors.user_id
+		ORDER BY COALESCE(c.elements, 0) + COALESCE(ed.elements, 0) DESC, u.name
+		LIMIT $3
+	`
+
+	rows, err := r.read.Query(ctx, query, workspaceID, elementID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contributors: %w", err)
+	}
+	defer rows.Close()
+
+	contributors := []models.Contributor{}
+	for rows.Next() {
+		var contributor models.Contributor
+		err := rows.Scan(
+			&contributor.UserID,
+			&contributor.Name,
+			&contributor.Username,
+			&contributor.AvatarURL,
+			&contributor.IsBot,
+			&contributor.ElementsCreated,
+			&contributor.ElementsEdited,
+			&contributor.FirstContributionAt,
+			&contributor.LastContributionAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contributor: %w", err)
+		}
+		contributors = append(contributors, contributor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get contributors: %w", err)
+	}
+	return contributors, nil
+}
+
+// GetWorkspaceSizes returns how many elements, operations, snapshots and
+// assets a workspace keeps and their size
+func (r *WorkspaceRepository) GetWorkspaceSizes(ctx context.Context, id uuid.UUID) (*models.WorkspaceSizes, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FILTER (WHERE deleted_at IS NULL) FROM canvas_elements WHERE workspace_id = $1),
+			(SELECT COALESCE(SUM(pg_column_size(e.*)) FILTER (WHERE deleted_at IS NULL), 0)
+				FROM canvas_elements e WHERE workspace_id = $1),
+			(SELECT COUNT(*) FILTER (WHERE deleted_at IS NOT NULL) FROM canvas_elements WHERE workspace_id = $1),
+			(SELECT COALESCE(SUM(pg_column_size(e.*)) FILTER (WHERE deleted_at IS NOT NULL), 0)
+				FROM canvas_elements e WHERE workspace_id = $1),
+			(SELECT COUNT(*) FROM operations WHERE workspace_id = $1),
+			(SELECT COALESCE(SUM(pg_column_size(o.*)), 0) FROM operations o WHERE workspace_id = $1),
+			(SELECT MIN(created_at) FROM operations WHERE workspace_id = $1),
+			(SELECT COUNT(*) FROM canvas_snapshots WHERE workspace_id = $1),
+			(SELECT COALESCE(SUM(COALESCE(compressed_size, 0) + COALESCE(pg_column_size(snapshot_data), 0)), 0)
+				FROM canvas_snapshots WHERE workspace_id = $1),
+			(SELECT COUNT(*) FILTER (WHERE deleted_at IS NULL) FROM assets WHERE workspace_id = $1),
+			(SELECT COALESCE(SUM(size) FILTER (WHERE deleted_at IS NULL), 0) FROM assets WHERE workspace_id = $1),
+			(SELECT COUNT(*) FILTER (WHERE deleted_at IS NOT NULL) FROM assets WHERE workspace_id = $1),
+			(SELECT COALESCE(SUM(size) FILTER (WHERE deleted_at IS NOT NULL), 0) FROM assets WHERE workspace_id = $1)
+	`
+
+	var sizes models.WorkspaceSizes
+	err := r.read.QueryRow(ctx, query, id).Scan(
+		&sizes.Elements.Count,
+		&sizes.Elements.Bytes,
+		&sizes.TrashedElements.Count,
+		&sizes.TrashedElements.Bytes,
+		&sizes.Operations.Count,
+		&sizes.Operations.Bytes,
+		&sizes.OldestOperationAt,
+		&sizes.Snapshots.Count,
+		&sizes.Snapshots.Bytes,
+		&sizes.Assets.Count,
+		&sizes.Assets.Bytes,
+		&sizes.TrashedAssets.Count,
+		&sizes.TrashedAssets.Bytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace sizes: %w", err)
+	}
+
+	return &sizes, nil
+}
+
 // UpdateWorkspace updates workspace fields
 func (r *WorkspaceRepository) UpdateWorkspace(ctx context.Context, workspace *models.Workspace) error {
 	settingsJSON, err := json.Marshal(workspace.Settings)
@@ -117,8 +291,9 @@ func (r *WorkspaceRepository) UpdateWorkspace(ctx context.Context, workspace *mo
 
 	query := `
 		UPDATE workspaces
-		SET name = $1, description = $2, is_public = $3, thumbnail_url = $4, settings = $5
-		WHERE id = $6 AND deleted_at IS NULL
+		SET name = $1, description = $2, is_public = $3, thumbnail_url = $4, settings = $5,
+			public_role = $6, public_allow_duplicate = $7, public_allow_anonymous = $8, freeze_role = $9
+		WHERE id = $10 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 
@@ -128,6 +303,10 @@ func (r *WorkspaceRepository) UpdateWorkspace(ctx context.Context, workspace *mo
 		workspace.IsPublic,
 		workspace.ThumbnailURL,
 		settingsJSON,
+		workspace.PublicAccess.Role,
+		workspace.PublicAccess.AllowDuplicate,
+		workspace.PublicAccess.AllowAnonymous,
+		workspace.FreezeRole,
 		workspace.ID,
 	).Scan(&workspace.UpdatedAt)
 
@@ -141,6 +320,36 @@ func (r *WorkspaceRepository) UpdateWorkspace(ctx context.Context, workspace *mo
 	return nil
 }
 
+// FreezeWorkspace makes a board read-only. Returns false if it already was
+// frozen.
+func (r *WorkspaceRepository) FreezeWorkspace(ctx context.Context, id, frozenBy uuid.UUID) (bool, error) {
+	query := `
+		UPDATE workspaces SET frozen_at = NOW(), frozen_by = $2
+		WHERE id = $1 AND deleted_at IS NULL AND frozen_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, frozenBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to freeze workspace: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UnfreezeWorkspace makes a frozen board editable again. Returns false if it
+// wasn't frozen.
+func (r *WorkspaceRepository) UnfreezeWorkspace(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE workspaces SET frozen_at = NULL, frozen_by = NULL
+		WHERE id = $1 AND deleted_at IS NULL AND frozen_at IS NOT NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to unfreeze workspace: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 // SoftDeleteWorkspace marks workspace as deleted
 func (r *WorkspaceRepository) SoftDeleteWorkspace(ctx context.Context, id uuid.UUID) error {
 	query := `
@@ -161,27 +370,62 @@ func (r *WorkspaceRepository) SoftDeleteWorkspace(ctx context.Context, id uuid.U
 	return nil
 }
 
-// ListWorkspacesByUser retrieves workspaces accessible to user with filters
+// ListWorkspacesByUser retrieves workspaces accessible to user with filters.
+// The owner of each workspace is loaded in the same query.
 func (r *WorkspaceRepository) ListWorkspacesByUser(
 	ctx context.Context,
 	userID uuid.UUID,
 	filter models.WorkspaceListFilter,
 ) ([]models.WorkspaceWithRole, int, error) {
+	args := []interface{}{userID}
+	argCount := 1
+
+	// Names and descriptions match by trigram similarity, so typos and
+	// reordered words still find a board. Element text is a weaker signal.
+	relevance := "0::real"
+	match := ""
+	if filter.Query != "" {
+		args = append(args, filter.Query, "%"+likeEscaper.Replace(filter.Query)+"%")
+		q, like := fmt.Sprintf("$%d", argCount+1), fmt.Sprintf("$%d", argCount+2)
+		argCount += 2
+
+		relevance = fmt.Sprintf(`GREATEST(similarity(w.name, %[1]s), word_similarity(%[1]s, w.name),
+				0.5 * GREATEST(similarity(COALESCE(w.description, ''), %[1]s),
+					word_similarity(%[1]s, COALESCE(w.description, ''))))
+			+ CASE WHEN w.name ILIKE %[2]s THEN 0.5 ELSE 0 END`, q, like)
+		conditions := fmt.Sprintf(`w.name ILIKE %[2]s OR w.name %% %[1]s OR %[1]s <%% w.name
+			OR w.description ILIKE %[2]s OR w.description %% %[1]s OR %[1]s <%% w.description`, q, like)
+
+		if filter.SearchContent {
+			// Sealed element data has none of these fields
+			content := fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM canvas_elements ce
+				WHERE ce.workspace_id = w.id AND ce.deleted_at IS NULL
+				  AND (ce.element_data->>'plain_text' ILIKE %[1]s OR ce.element_data->>'content' ILIKE %[1]s)
+			)`, like)
+			relevance += fmt.Sprintf(" + CASE WHEN %s THEN 0.2 ELSE 0 END", content)
+			conditions += " OR " + content
+		}
+		match = " AND (" + conditions + ")"
+	}
+
 	// Build query with filters
 	query := `
 		SELECT DISTINCT
 			w.id, w.name, w.description, w.owner_id, w.thumbnail_url,
 			w.is_public, w.settings, w.created_at, w.updated_at,
 			wm.role,
-			COUNT(*) OVER() as total_count
+			u.email, u.name, u.avatar_url,
+			v.visited_at,
+			COUNT(*) OVER() as total_count,
+			` + relevance + ` AS relevance
 		FROM workspaces w
 		INNER JOIN workspace_members wm ON w.id = wm.workspace_id
+		INNER JOIN users u ON w.owner_id = u.id
+		LEFT JOIN workspace_visits v ON v.workspace_id = w.id AND v.user_id = $1
 		WHERE w.deleted_at IS NULL
 			AND wm.user_id = $1
-	`
-
-	args := []interface{}{userID}
-	argCount := 1
+	` + match
 
 	// Apply filters
 	if filter.OwnedOnly {
@@ -189,17 +433,19 @@ func (r *WorkspaceRepository) ListWorkspacesByUser(
 	} else if filter.SharedOnly {
 		query += " AND w.owner_id != $1"
 	}
-
-	if filter.Query != "" {
-		argCount++
-		query += fmt.Sprintf(" AND w.name ILIKE $%d", argCount)
-		args = append(args, "%"+filter.Query+"%")
+	if filter.Recent {
+		query += " AND v.visited_at IS NOT NULL"
 	}
 
 	// Sorting
-	sortBy := "created_at"
+	sortBy := "w.created_at"
 	if filter.SortBy == "updated_at" || filter.SortBy == "name" {
-		sortBy = filter.SortBy
+		sortBy = "w." + filter.SortBy
+	} else if filter.SortBy == "relevance" && filter.Query != "" {
+		sortBy = "relevance"
+	}
+	if filter.Recent {
+		sortBy = "v.visited_at"
 	}
 
 	sortOrder := "DESC"
@@ -207,7 +453,7 @@ func (r *WorkspaceRepository) ListWorkspacesByUser(
 		sortOrder = "ASC"
 	}
 
-	query += fmt.Sprintf(" ORDER BY w.%s %s", sortBy, sortOrder)
+	query += fmt.Sprintf(" ORDER BY %s %s, w.id", sortBy, sortOrder)
 
 	// Pagination
 	limit := 20
@@ -228,7 +474,7 @@ func (r *WorkspaceRepository) ListWorkspacesByUser(
 	query += fmt.Sprintf(" OFFSET $%d", argCount)
 	args = append(args, offset)
 
-	rows, err := r.db.Query(ctx, query, args...)
+	rows, err := r.read.Query(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list workspaces: %w", err)
 	}
@@ -236,9 +482,11 @@ func (r *WorkspaceRepository) ListWorkspacesByUser(
 
 	var workspaces []models.WorkspaceWithRole
 	var totalCount int
+	var rank float64 // only orders the results
 
 	for rows.Next() {
 		var ws models.WorkspaceWithRole
+		var owner models.User
 		var settingsJSON []byte
 
 		err := rows.Scan(
@@ -252,12 +500,20 @@ func (r *WorkspaceRepository) ListWorkspacesByUser(
 			&ws.CreatedAt,
 			&ws.UpdatedAt,
 			&ws.UserRole,
+			&owner.Email,
+			&owner.Name,
+			&owner.AvatarURL,
+			&ws.LastVisitedAt,
 			&totalCount,
+			&rank,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan workspace: %w", err)
 		}
 
+		owner.ID = ws.OwnerID
+		ws.Owner = &owner
+
 		if err := json.Unmarshal(settingsJSON, &ws.Settings); err != nil {
 			return nil, 0, fmt.Errorf("failed to unmarshal settings: %w", err)
 		}
@@ -272,41 +528,213 @@ func (r *WorkspaceRepository) ListWorkspacesByUser(
 	return workspaces, totalCount, nil
 }
 
-// --- Workspace Members ---
+// UpsertVisits records the last visits of a user to workspaces, keeping the
+// later time of visits already recorded. Visits to workspaces or of users
+// that no longer exist are skipped.
+func (r *WorkspaceRepository) UpsertVisits(ctx context.Context, userID uuid.UUID, visits []models.WorkspaceVisit) error {
+	if len(visits) == 0 {
+		return nil
+	}
+
+	workspaceIDs := make([]uuid.UUID, len(visits))
+	visitedAt := make([]time.Time, len(visits))
+	for i, visit := range visits {
+		workspaceIDs[i] = visit.WorkspaceID
+		visitedAt[i] = visit.VisitedAt.UTC()
+	}
 
-// AddMember adds a user to workspace with specified role
-func (r *WorkspaceRepository) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
 	query := `
-		INSERT INTO workspace_members (id, workspace_id, user_id, role, invited_by)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING joined_at
-		ON CONFLICT (workspace_id, user_id) DO NOTHING
+		INSERT INTO workspace_visits (user_id, workspace_id, visited_at)
+		SELECT u.id, v.workspace_id, v.visited_at
+		FROM unnest($2::uuid[], $3::timestamp[]) AS v(workspace_id, visited_at)
+		INNER JOIN workspaces w ON w.id = v.workspace_id
+		INNER JOIN users u ON u.id = $1
+		ON CONFLICT (user_id, workspace_id)
+		DO UPDATE SET visited_at = GREATEST(workspace_visits.visited_at, EXCLUDED.visited_at)
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	if _, err := r.db.Exec(ctx, query, userID, workspaceIDs, visitedAt); err != nil {
+		return fmt.Errorf("failed to record workspace visits: %w", err)
+	}
+	return nil
+}
+
+// RecordView counts a view of a workspace by a viewer and reports whether it
+// was the first view of that viewer
+func (r *WorkspaceRepository) RecordView(ctx context.Context, workspaceID uuid.UUID, viewerKey string) (bool, error) {
+	query := `
+		INSERT INTO workspace_views (workspace_id, viewer_key)
+		VALUES ($1, $2)
+		ON CONFLICT (workspace_id, viewer_key)
+		DO UPDATE SET view_count = workspace_views.view_count + 1, last_viewed_at = NOW()
+		RETURNING view_count
+	`
+
+	var viewCount int64
+	if err := r.db.QueryRow(ctx, query, workspaceID, viewerKey).Scan(&viewCount); err != nil {
+		return false, fmt.Errorf("failed to record workspace view: %w", err)
+	}
+	return viewCount == 1, nil
+}
+
+// GetViewCounts returns the view counts of workspaces. Workspaces nobody
+// viewed yet are left out.
+func (r *WorkspaceRepository) GetViewCounts(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+) (map[uuid.UUID]models.WorkspaceViewCounts, error) {
+	counts := make(map[uuid.UUID]models.WorkspaceViewCounts, len(workspaceIDs))
+	if len(workspaceIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `
+		SELECT workspace_id, COALESCE(SUM(view_count), 0), COUNT(*)
+		FROM workspace_views
+		WHERE workspace_id = ANY($1)
+		GROUP BY workspace_id
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace view counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workspaceID uuid.UUID
+		var count models.WorkspaceViewCounts
+		if err := rows.Scan(&workspaceID, &count.Total, &count.Unique); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace view counts: %w", err)
+		}
+		counts[workspaceID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get workspace view counts: %w", err)
+	}
+	return counts, nil
+}
+
+// ListAllWorkspaces lists the workspaces of all users with their owner and
+// size for admins
+func (r *WorkspaceRepository) ListAllWorkspaces(
+	ctx context.Context,
+	filter models.AdminWorkspaceListFilter,
+) ([]models.AdminWorkspace, int, error) {
+	query := `
+		SELECT
+			w.id, w.name, w.is_public, w.storage_used_bytes, w.created_at, w.updated_at,
+			u.id, u.email, u.name, u.avatar_url,
+			(SELECT COUNT(*) FROM workspace_members WHERE workspace_id = w.id),
+			(SELECT COUNT(*) FROM canvas_elements WHERE workspace_id = w.id AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM assets WHERE workspace_id = w.id AND deleted_at IS NULL AND parent_asset_id IS NULL),
+			COUNT(*) OVER() as total_count
+		FROM workspaces w
+		INNER JOIN users u ON w.owner_id = u.id
+		WHERE w.deleted_at IS NULL
+	`
+
+	args := []interface{}{}
+	argCount := 0
+
+	if filter.Query != "" {
+		argCount++
+		query += fmt.Sprintf(" AND (w.name ILIKE $%d OR u.email ILIKE $%d)", argCount, argCount)
+		args = append(args, "%"+filter.Query+"%")
+	}
+
+	sortBy := "created_at"
+	if filter.SortBy == "updated_at" || filter.SortBy == "storage_used_bytes" {
+		sortBy = filter.SortBy
+	}
+	query += fmt.Sprintf(" ORDER BY w.%s DESC, w.id", sortBy)
+
+	argCount++
+	query += fmt.Sprintf(" LIMIT $%d", argCount)
+	args = append(args, filter.Limit)
+
+	argCount++
+	query += fmt.Sprintf(" OFFSET $%d", argCount)
+	args = append(args, filter.Offset)
+
+	rows, err := r.read.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := []models.AdminWorkspace{}
+	var totalCount int
+
+	for rows.Next() {
+		var ws models.AdminWorkspace
+		err := rows.Scan(
+			&ws.ID,
+			&ws.Name,
+			&ws.IsPublic,
+			&ws.StorageUsedBytes,
+			&ws.CreatedAt,
+			&ws.UpdatedAt,
+			&ws.Owner.ID,
+			&ws.Owner.Email,
+			&ws.Owner.Name,
+			&ws.Owner.AvatarURL,
+			&ws.MemberCount,
+			&ws.ElementCount,
+			&ws.AssetCount,
+			&totalCount,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+
+		workspaces = append(workspaces, ws)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating workspaces: %w", err)
+	}
+
+	return workspaces, totalCount, nil
+}
+
+// --- Workspace Members ---
+
+// AddMember adds a user to workspace with specified role
+func (r *WorkspaceRepository) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	return scanAddedMember(r.db.QueryRow(ctx, addMemberQuery,
 		member.ID,
 		member.WorkspaceID,
 		member.UserID,
 		member.Role,
 		member.InvitedBy,
-	).Scan(&member.JoinedAt)
+	), member)
+}
 
-	if err != nil {
+const addMemberQuery = `
+	INSERT INTO workspace_members (id, workspace_id, user_id, role, invited_by)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (workspace_id, user_id) DO NOTHING
+	RETURNING joined_at
+`
+
+func scanAddedMember(row pgx.Row, member *models.WorkspaceMember) error {
+	if err := row.Scan(&member.JoinedAt); err != nil {
 		if err == pgx.ErrNoRows {
 			return fmt.Errorf("member already exists")
 		}
 		return fmt.Errorf("failed to add member: %w", err)
 	}
-
 	return nil
 }
 
 // GetMember retrieves member information
 func (r *WorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID uuid.UUID) (*models.WorkspaceMember, error) {
 	query := `
-		SELECT id, workspace_id, user_id, role, invited_by, joined_at
-		FROM workspace_members
-		WHERE workspace_id = $1 AND user_id = $2
+		SELECT wm.id, wm.workspace_id, wm.user_id, wm.role, wm.invited_by, wm.joined_at
+		FROM workspace_members wm
+		JOIN users u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1 AND wm.user_id = $2 AND u.deactivated_at IS NULL
 	`
 
 	var member models.WorkspaceMember
@@ -329,6 +757,68 @@ func (r *WorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID
 	return &member, nil
 }
 
+// SyncLDAPMemberships replaces the memberships a user's LDAP groups grant
+// with roles, keyed by workspace. Unknown workspaces are skipped, and
+// memberships that weren't granted by LDAP are never changed.
+func (r *WorkspaceRepository) SyncLDAPMemberships(
+	ctx context.Context,
+	userID uuid.UUID,
+	roles map[uuid.UUID]models.WorkspaceRole,
+) error {
+	workspaceIDs := make([]uuid.UUID, 0, len(roles))
+	roleNames := make([]string, 0, len(roles))
+	for workspaceID, role := range roles {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+		roleNames = append(roleNames, string(role))
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	desired := `
+		WITH desired AS (
+			SELECT d.workspace_id, d.role
+			FROM unnest($2::uuid[], $3::text[]) AS d(workspace_id, role)
+			JOIN workspaces w ON w.id = d.workspace_id
+		)
+	`
+	statements := []string{
+		desired + `
+			DELETE FROM workspace_members wm
+			WHERE wm.ldap_managed AND wm.user_id = $1
+			  AND wm.workspace_id NOT IN (SELECT workspace_id FROM desired)
+		`,
+		desired + `
+			UPDATE workspace_members wm
+			SET role = d.role
+			FROM desired d
+			WHERE wm.ldap_managed AND wm.user_id = $1 AND wm.workspace_id = d.workspace_id AND wm.role <> d.role
+		`,
+		desired + `
+			INSERT INTO workspace_members (id, workspace_id, user_id, role, ldap_managed)
+			SELECT gen_random_uuid(), d.workspace_id, $1, d.role, TRUE
+			FROM desired d
+			ON CONFLICT (workspace_id, user_id) DO NOTHING
+		`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, userID, workspaceIDs, roleNames); err != nil {
+			return fmt.Errorf("failed to sync LDAP memberships: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateMemberRole updates member's role in workspace
 func (r *WorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID uuid.UUID, role models.WorkspaceRole) error {
 	query := `
@@ -373,14 +863,14 @@ func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uuid.
 	query := `
 		SELECT
 			wm.id, wm.workspace_id, wm.user_id, wm.role, wm.invited_by, wm.joined_at,
-			u.id, u.email, u.name, u.avatar_url
+			u.id, u.email, u.name, u.username, u.avatar_url, u.is_bot
 		FROM workspace_members wm
 		INNER JOIN users u ON wm.user_id = u.id
 		WHERE wm.workspace_id = $1
 		ORDER BY wm.joined_at ASC
 	`
 
-	rows, err := r.db.Query(ctx, query, workspaceID)
+	rows, err := r.read.Query(ctx, query, workspaceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list members: %w", err)
 	}
@@ -401,7 +891,9 @@ func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uuid.
 			&m.User.ID,
 			&m.User.Email,
 			&m.User.Name,
+			&m.User.Username,
 			&m.User.AvatarURL,
+			&m.User.IsBot,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan member: %w", err)
@@ -417,58 +909,257 @@ func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uuid.
 	return members, nil
 }
 
-// --- Workspace Invites ---
-
-// CreateInvite creates a new workspace invitation
-func (r *WorkspaceRepository) CreateInvite(ctx context.Context, invite *models.WorkspaceInvite) error {
+// ListMembersByWorkspaces retrieves the members of several workspaces at
+// once, keyed by workspace
+func (r *WorkspaceRepository) ListMembersByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+) (map[uuid.UUID][]models.WorkspaceMemberWithUser, error) {
 	query := `
-		INSERT INTO workspace_invites (id, workspace_id, email, role, token_hash, expires_at, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING created_at
+		SELECT
+			wm.id, wm.workspace_id, wm.user_id, wm.role, wm.invited_by, wm.joined_at,
+			u.id, u.email, u.name, u.username, u.avatar_url, u.is_bot
+		FROM workspace_members wm
+		INNER JOIN users u ON wm.user_id = u.id
+		WHERE wm.workspace_id = ANY($1)
+		ORDER BY wm.joined_at ASC
 	`
 
-	err := r.db.QueryRow(ctx, query,
-		invite.ID,
-		invite.WorkspaceID,
-		invite.Email,
-		invite.Role,
-		invite.TokenHash,
-		invite.ExpiresAt,
-		invite.CreatedBy,
-	).Scan(&invite.CreatedAt)
-
+	rows, err := r.read.Query(ctx, query, workspaceIDs)
 	if err != nil {
-		return fmt.Errorf("failed to create invite: %w", err)
+		return nil, fmt.Errorf("failed to list members: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
-}
-
-// GetInviteByToken retrieves an invite by token hash
-func (r *WorkspaceRepository) GetInviteByToken(ctx context.Context, tokenHash string) (*models.WorkspaceInvite, error) {
-	query := `
-		SELECT id, workspace_id, email, role, token_hash, expires_at, created_by, created_at, accepted_at, accepted_by
-		FROM workspace_invites
-		WHERE token_hash = $1
-	`
+	members := make(map[uuid.UUID][]models.WorkspaceMemberWithUser, len(workspaceIDs))
 
-	var invite models.WorkspaceInvite
-	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
-		&invite.ID,
-		&invite.WorkspaceID,
-		&invite.Email,
-		&invite.Role,
-		&invite.TokenHash,
-		&invite.ExpiresAt,
-		&invite.CreatedBy,
-		&invite.CreatedAt,
-		&invite.AcceptedAt,
-		&invite.AcceptedBy,
-	)
+	for rows.Next() {
+		var m models.WorkspaceMemberWithUser
 
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
+		err := rows.Scan(
+			&m.ID,
+			&m.WorkspaceID,
+			&m.UserID,
+			&m.Role,
+			&m.InvitedBy,
+			&m.JoinedAt,
+			&m.User.ID,
+			&m.User.Email,
+			&m.User.Name,
+			&m.User.Username,
+			&m.User.AvatarURL,
+			&m.User.IsBot,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+
+		members[m.WorkspaceID] = append(members[m.WorkspaceID], m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating members: %w", err)
+	}
+
+	return members, nil
+}
+
+// ListMembersPage retrieves the members of a workspace with their users,
+// most recently joined first, starting below the cursor when it is not nil
+func (r *WorkspaceRepository) ListMembersPage(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	cursor *models.TriggerCursor,
+	limit int,
+) ([]models.WorkspaceMemberWithUser, error) {
+	var before *time.Time
+	beforeID := uuid.Nil
+	if cursor != nil {
+		before = &cursor.CreatedAt
+		beforeID = cursor.ID
+	}
+
+	query := `
+		SELECT
+			wm.id, wm.workspace_id, wm.user_id, wm.role, wm.invited_by, wm.joined_at,
+			u.id, u.email, u.name, u.username, u.avatar_url, u.is_bot
+		FROM workspace_members wm
+		INNER JOIN users u ON wm.user_id = u.id
+		WHERE wm.workspace_id = $1
+		  AND ($2::timestamp IS NULL OR (wm.joined_at, wm.id) < ($2::timestamp, $3))
+		ORDER BY wm.joined_at DESC, wm.id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.read.Query(ctx, query, workspaceID, before, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.WorkspaceMemberWithUser{}
+	for rows.Next() {
+		var m models.WorkspaceMemberWithUser
+		err := rows.Scan(
+			&m.ID,
+			&m.WorkspaceID,
+			&m.UserID,
+			&m.Role,
+			&m.InvitedBy,
+			&m.JoinedAt,
+			&m.User.ID,
+			&m.User.Email,
+			&m.User.Name,
+			&m.User.Username,
+			&m.User.AvatarURL,
+			&m.User.IsBot,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating members: %w", err)
+	}
+
+	return members, nil
+}
+
+// SuggestMembers retrieves the members other than a user whose name or a
+// word of it, or username starts with a prefix. Members the user recently
+// talked with in comment threads come first, then those recently active on
+// the board.
+func (r *WorkspaceRepository) SuggestMembers(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	prefix string,
+	limit int,
+) ([]models.MemberSuggestion, error) {
+	query := `
+		WITH my_threads AS (
+			SELECT DISTINCT COALESCE(parent_id, id) AS thread_id
+			FROM comments
+			WHERE workspace_id = $1 AND author_id = $2
+		)
+		SELECT u.id, u.name, u.username, u.avatar_url
+		FROM workspace_members wm
+		INNER JOIN users u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1 AND wm.user_id <> $2 AND u.deactivated_at IS NULL
+		  AND ($3 = '' OR u.name ILIKE $3 || '%' OR u.name ILIKE '% ' || $3 || '%' OR u.username ILIKE $3 || '%')
+		ORDER BY
+			(SELECT MAX(c.created_at) FROM comments c
+			 WHERE c.workspace_id = $1 AND c.author_id = u.id
+			   AND COALESCE(c.parent_id, c.id) IN (SELECT thread_id FROM my_threads)) DESC NULLS LAST,
+			(SELECT MAX(ce.updated_at) FROM canvas_elements ce
+			 WHERE ce.workspace_id = $1 AND ce.updated_by = u.id) DESC NULLS LAST,
+			u.name, u.id
+		LIMIT $4
+	`
+
+	rows, err := r.read.Query(ctx, query, workspaceID, userID, likeEscaper.Replace(prefix), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest members: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.MemberSuggestion{}
+	for rows.Next() {
+		var s models.MemberSuggestion
+		if err := rows.Scan(&s.ID, &s.Name, &s.Username, &s.AvatarURL); err != nil {
+			return nil, fmt.Errorf("failed to scan member suggestion: %w", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// --- Workspace Invites ---
+
+// CreateInvite creates a new workspace invitation
+func (r *WorkspaceRepository) CreateInvite(ctx context.Context, invite *models.WorkspaceInvite) error {
+	query := `
+		INSERT INTO workspace_invites (id, workspace_id, email, role, token_hash, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		invite.ID,
+		invite.WorkspaceID,
+		invite.Email,
+		invite.Role,
+		invite.TokenHash,
+		invite.ExpiresAt,
+		invite.CreatedBy,
+	).Scan(&invite.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	return nil
+}
+
+// GetInviteByToken retrieves an invite by token hash
+func (r *WorkspaceRepository) GetInviteByToken(ctx context.Context, tokenHash string) (*models.WorkspaceInvite, error) {
+	query := `
+		SELECT id, workspace_id, email, role, token_hash, expires_at, created_by, created_at, accepted_at, accepted_by
+		FROM workspace_invites
+		WHERE token_hash = $1
+	`
+
+	var invite models.WorkspaceInvite
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&invite.ID,
+		&invite.WorkspaceID,
+		&invite.Email,
+		&invite.Role,
+		&invite.TokenHash,
+		&invite.ExpiresAt,
+		&invite.CreatedBy,
+		&invite.CreatedAt,
+		&invite.AcceptedAt,
+		&invite.AcceptedBy,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	return &invite, nil
+}
+
+// GetInviteByID retrieves an invite, nil if it doesn't exist
+func (r *WorkspaceRepository) GetInviteByID(ctx context.Context, id uuid.UUID) (*models.WorkspaceInvite, error) {
+	query := `
+		SELECT id, workspace_id, email, role, token_hash, expires_at, created_by, created_at, accepted_at, accepted_by
+		FROM workspace_invites
+		WHERE id = $1
+	`
+
+	var invite models.WorkspaceInvite
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&invite.ID,
+		&invite.WorkspaceID,
+		&invite.Email,
+		&invite.Role,
+		&invite.TokenHash,
+		&invite.ExpiresAt,
+		&invite.CreatedBy,
+		&invite.CreatedAt,
+		&invite.AcceptedAt,
+		&invite.AcceptedBy,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get invite: %w", err)
 	}
@@ -476,15 +1167,95 @@ func (r *WorkspaceRepository) GetInviteByToken(ctx context.Context, tokenHash st
 	return &invite, nil
 }
 
-// MarkInviteAsAccepted marks an invitation as accepted
-func (r *WorkspaceRepository) MarkInviteAsAccepted(ctx context.Context, inviteID, userID uuid.UUID) error {
+// ListInvitesByEmail retrieves the pending invitations to an email address,
+// whatever its case, with their workspace and inviter, newest first
+func (r *WorkspaceRepository) ListInvitesByEmail(ctx context.Context, email string) ([]models.UserInviteResponse, error) {
+	query := `
+		SELECT wi.id, wi.role, wi.expires_at, wi.created_at,
+			w.id, w.name, w.description, w.thumbnail_url,
+			u.id, u.email, u.name, u.avatar_url
+		FROM workspace_invites wi
+		INNER JOIN workspaces w ON w.id = wi.workspace_id
+		LEFT JOIN users u ON u.id = wi.created_by
+		WHERE LOWER(wi.email) = LOWER($1)
+			AND wi.accepted_at IS NULL
+			AND wi.expires_at > CURRENT_TIMESTAMP
+			AND w.deleted_at IS NULL
+		ORDER BY wi.created_at DESC, wi.id
+	`
+
+	rows, err := r.read.Query(ctx, query, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.UserInviteResponse{}
+	for rows.Next() {
+		var invite models.UserInviteResponse
+		var inviterID *uuid.UUID
+		var inviterEmail, inviterName *string
+		var inviterAvatar *string
+
+		err := rows.Scan(
+			&invite.ID,
+			&invite.Role,
+			&invite.ExpiresAt,
+			&invite.CreatedAt,
+			&invite.Workspace.ID,
+			&invite.Workspace.Name,
+			&invite.Workspace.Description,
+			&invite.Workspace.ThumbnailURL,
+			&inviterID,
+			&inviterEmail,
+			&inviterName,
+			&inviterAvatar,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+
+		if inviterID != nil {
+			invite.InvitedBy = &models.UserResponse{
+				ID:        *inviterID,
+				Email:     *inviterEmail,
+				Name:      *inviterName,
+				AvatarURL: inviterAvatar,
+			}
+		}
+		invites = append(invites, invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+// AcceptInvite marks an invitation as accepted and adds the member it was
+// for in a single transaction, together with the outbox messages of the join
+func (r *WorkspaceRepository) AcceptInvite(
+	ctx context.Context,
+	inviteID uuid.UUID,
+	member *models.WorkspaceMember,
+	outbox ...*models.OutboxMessage,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
 	query := `
 		UPDATE workspace_invites
 		SET accepted_at = CURRENT_TIMESTAMP, accepted_by = $1
 		WHERE id = $2 AND accepted_at IS NULL
 	`
 
-	result, err := r.db.Exec(ctx, query, userID, inviteID)
+	result, err := tx.Exec(ctx, query, member.UserID, inviteID)
 	if err != nil {
 		return fmt.Errorf("failed to mark invite as accepted: %w", err)
 	}
@@ -493,6 +1264,24 @@ func (r *WorkspaceRepository) MarkInviteAsAccepted(ctx context.Context, inviteID
 		return fmt.Errorf("invite not found or already accepted")
 	}
 
+	if err := scanAddedMember(tx.QueryRow(ctx, addMemberQuery,
+		member.ID,
+		member.WorkspaceID,
+		member.UserID,
+		member.Role,
+		member.InvitedBy,
+	), member); err != nil {
+		return err
+	}
+
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -524,7 +1313,7 @@ func (r *WorkspaceRepository) ListPendingInvites(ctx context.Context, workspaceI
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, workspaceID)
+	rows, err := r.read.Query(ctx, query, workspaceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pending invites: %w", err)
 	}
@@ -613,3 +1402,514 @@ func (r *WorkspaceRepository) GetInviteByWorkspaceAndEmail(
 
 	return &invite, nil
 }
+
+// --- Workspace Invite Links ---
+
+const inviteLinkColumns = `id, workspace_id, role, token_hash, max_uses, use_count, expires_at, created_by, created_at, revoked_at`
+
+func scanInviteLink(row pgx.Row) (*models.WorkspaceInviteLink, error) {
+	var link models.WorkspaceInviteLink
+	err := row.Scan(
+		&link.ID,
+		&link.WorkspaceID,
+		&link.Role,
+		&link.TokenHash,
+		&link.MaxUses,
+		&link.UseCount,
+		&link.ExpiresAt,
+		&link.CreatedBy,
+		&link.CreatedAt,
+		&link.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// CreateInviteLink creates a shareable invite link
+func (r *WorkspaceRepository) CreateInviteLink(ctx context.Context, link *models.WorkspaceInviteLink) error {
+	query := `
+		INSERT INTO workspace_invite_links (id, workspace_id, role, token_hash, max_uses, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		link.ID,
+		link.WorkspaceID,
+		link.Role,
+		link.TokenHash,
+		link.MaxUses,
+		link.ExpiresAt,
+		link.CreatedBy,
+	).Scan(&link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invite link: %w", err)
+	}
+
+	return nil
+}
+
+// GetInviteLinkByToken retrieves an invite link by token hash, nil if it
+// doesn't exist
+func (r *WorkspaceRepository) GetInviteLinkByToken(ctx context.Context, tokenHash string) (*models.WorkspaceInviteLink, error) {
+	query := `SELECT ` + inviteLinkColumns + ` FROM workspace_invite_links WHERE token_hash = $1`
+
+	link, err := scanInviteLink(r.db.QueryRow(ctx, query, tokenHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite link: %w", err)
+	}
+
+	return link, nil
+}
+
+// GetInviteLink retrieves an invite link of a workspace, nil if it doesn't
+// exist
+func (r *WorkspaceRepository) GetInviteLink(ctx context.Context, workspaceID, id uuid.UUID) (*models.WorkspaceInviteLink, error) {
+	query := `SELECT ` + inviteLinkColumns + ` FROM workspace_invite_links WHERE id = $1 AND workspace_id = $2`
+
+	link, err := scanInviteLink(r.db.QueryRow(ctx, query, id, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite link: %w", err)
+	}
+
+	return link, nil
+}
+
+// ListInviteLinks retrieves the invite links of a workspace that weren't
+// revoked, newest first
+func (r *WorkspaceRepository) ListInviteLinks(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceInviteLink, error) {
+	query := `SELECT ` + inviteLinkColumns + `
+		FROM workspace_invite_links
+		WHERE workspace_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC, id`
+
+	rows, err := r.read.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invite links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.WorkspaceInviteLink{}
+	for rows.Next() {
+		link, err := scanInviteLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite link: %w", err)
+		}
+		links = append(links, *link)
+	}
+
+	return links, rows.Err()
+}
+
+// RevokeInviteLink stops an invite link from working. Returns false if it
+// doesn't exist or was already revoked.
+func (r *WorkspaceRepository) RevokeInviteLink(ctx context.Context, workspaceID, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE workspace_invite_links SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND workspace_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, workspaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invite link: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// RedeemInviteLink uses an invite link and adds the member it lets join in
+// a single transaction, together with the outbox messages of the join.
+// Returns false if the link was revoked, expired or used up meanwhile.
+func (r *WorkspaceRepository) RedeemInviteLink(
+	ctx context.Context,
+	linkID uuid.UUID,
+	member *models.WorkspaceMember,
+	outbox ...*models.OutboxMessage,
+) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// The counter is checked and incremented at once, so concurrent
+	// redemptions can't exceed max_uses
+	query := `
+		UPDATE workspace_invite_links SET use_count = use_count + 1
+		WHERE id = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			AND (max_uses IS NULL OR use_count < max_uses)
+	`
+
+	result, err := tx.Exec(ctx, query, linkID)
+	if err != nil {
+		return false, fmt.Errorf("failed to use invite link: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO workspace_invite_link_redemptions (link_id, user_id) VALUES ($1, $2)`,
+		linkID, member.UserID,
+	); err != nil {
+		return false, fmt.Errorf("failed to record invite link redemption: %w", err)
+	}
+
+	if err := scanAddedMember(tx.QueryRow(ctx, addMemberQuery,
+		member.ID,
+		member.WorkspaceID,
+		member.UserID,
+		member.Role,
+		member.InvitedBy,
+	), member); err != nil {
+		return false, err
+	}
+
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListInviteLinkRedemptions retrieves the users that joined through an
+// invite link, most recent first
+func (r *WorkspaceRepository) ListInviteLinkRedemptions(ctx context.Context, linkID uuid.UUID) ([]models.InviteLinkRedemption, error) {
+	query := `
+		SELECT ilr.redeemed_at, u.id, u.email, u.name, u.avatar_url
+		FROM workspace_invite_link_redemptions ilr
+		INNER JOIN users u ON u.id = ilr.user_id
+		WHERE ilr.link_id = $1
+		ORDER BY ilr.redeemed_at DESC, u.id
+	`
+
+	rows, err := r.read.Query(ctx, query, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invite link redemptions: %w", err)
+	}
+	defer rows.Close()
+
+	redemptions := []models.InviteLinkRedemption{}
+	for rows.Next() {
+		var redemption models.InviteLinkRedemption
+		err := rows.Scan(
+			&redemption.RedeemedAt,
+			&redemption.User.ID,
+			&redemption.User.Email,
+			&redemption.User.Name,
+			&redemption.User.AvatarURL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite link redemption: %w", err)
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
+
+// --- Workspace Access Requests ---
+
+const accessRequestQuery = `
+	SELECT ar.id, ar.workspace_id, ar.user_id, ar.role, ar.message, ar.status, ar.decided_by, ar.decided_at, ar.created_at,
+	u.email, u.name, u.avatar_url
+	FROM workspace_access_requests ar
+	INNER JOIN users u ON u.id = ar.user_id
+`
+
+func scanAccessRequest(row pgx.Row) (*models.WorkspaceAccessRequest, error) {
+	var request models.WorkspaceAccessRequest
+	var user models.UserResponse
+	err := row.Scan(
+		&request.ID,
+		&request.WorkspaceID,
+		&request.UserID,
+		&request.Role,
+		&request.Message,
+		&request.Status,
+		&request.DecidedBy,
+		&request.DecidedAt,
+		&request.CreatedAt,
+		&user.Email,
+		&user.Name,
+		&user.AvatarURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ID = request.UserID
+	request.User = &user
+	return &request, nil
+}
+
+// CreateAccessRequest creates a pending access request. Returns false if the
+// user already has a pending request for the workspace.
+func (r *WorkspaceRepository) CreateAccessRequest(ctx context.Context, request *models.WorkspaceAccessRequest) (bool, error) {
+	query := `
+		INSERT INTO workspace_access_requests (id, workspace_id, user_id, role, message, status)
+		VALUES ($1, $2, $3, $4, $5, 'pending')
+		ON CONFLICT (workspace_id, user_id) WHERE status = 'pending' DO NOTHING
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		request.ID,
+		request.WorkspaceID,
+		request.UserID,
+		request.Role,
+		request.Message,
+	).Scan(&request.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create access request: %w", err)
+	}
+
+	request.Status = models.AccessRequestPending
+	return true, nil
+}
+
+// GetAccessRequest retrieves an access request of a workspace, nil if it
+// doesn't exist
+func (r *WorkspaceRepository) GetAccessRequest(ctx context.Context, workspaceID, id uuid.UUID) (*models.WorkspaceAccessRequest, error) {
+	query := accessRequestQuery + `WHERE ar.id = $1 AND ar.workspace_id = $2`
+
+	request, err := scanAccessRequest(r.db.QueryRow(ctx, query, id, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request: %w", err)
+	}
+
+	return request, nil
+}
+
+// ListPendingAccessRequests retrieves the pending access requests of a
+// workspace, oldest first
+func (r *WorkspaceRepository) ListPendingAccessRequests(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) ([]models.WorkspaceAccessRequest, error) {
+	query := accessRequestQuery + `
+		WHERE ar.workspace_id = $1 AND ar.status = 'pending' AND u.deactivated_at IS NULL
+		ORDER BY ar.created_at, ar.id`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.WorkspaceAccessRequest{}
+	for rows.Next() {
+		request, err := scanAccessRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access request: %w", err)
+		}
+		requests = append(requests, *request)
+	}
+
+	return requests, rows.Err()
+}
+
+// ApproveAccessRequest marks a pending access request as approved and adds
+// the member it was for in a single transaction, together with the outbox
+// messages of the join. Returns false if the request wasn't pending.
+func (r *WorkspaceRepository) ApproveAccessRequest(
+	ctx context.Context,
+	requestID, decidedBy uuid.UUID,
+	member *models.WorkspaceMember,
+	outbox ...*models.OutboxMessage,
+) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `
+		UPDATE workspace_access_requests
+		SET status = 'approved', role = $3, decided_by = $2, decided_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	result, err := tx.Exec(ctx, query, requestID, decidedBy, member.Role)
+	if err != nil {
+		return false, fmt.Errorf("failed to approve access request: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := scanAddedMember(tx.QueryRow(ctx, addMemberQuery,
+		member.ID,
+		member.WorkspaceID,
+		member.UserID,
+		member.Role,
+		member.InvitedBy,
+	), member); err != nil {
+		return false, err
+	}
+
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// DenyAccessRequest marks a pending access request as denied. Returns false
+// if the request wasn't pending.
+func (r *WorkspaceRepository) DenyAccessRequest(ctx context.Context, requestID, decidedBy uuid.UUID) (bool, error) {
+	query := `
+		UPDATE workspace_access_requests
+		SET status = 'denied', decided_by = $2, decided_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	result, err := r.db.Exec(ctx, query, requestID, decidedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to deny access request: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// --- IP allowlists ---
+
+// ListIPRanges returns the allowlist of a workspace. It reads the primary,
+// a lagging replica would keep a removed range open.
+func (r *WorkspaceRepository) ListIPRanges(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceIPRange, error) {
+	query := `
+		SELECT id, cidr::text, description, created_by, created_at
+		FROM workspace_ip_ranges
+		WHERE workspace_id = $1
+		ORDER BY created_at, cidr
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP ranges: %w", err)
+	}
+	defer rows.Close()
+
+	ranges := []models.WorkspaceIPRange{}
+	for rows.Next() {
+		var ipRange models.WorkspaceIPRange
+		if err := rows.Scan(
+			&ipRange.ID,
+			&ipRange.CIDR,
+			&ipRange.Description,
+			&ipRange.CreatedBy,
+			&ipRange.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan IP range: %w", err)
+		}
+		ranges = append(ranges, ipRange)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating IP ranges: %w", err)
+	}
+
+	return ranges, nil
+}
+
+// ListIPRangesByWorkspaces returns the allowlisted CIDRs of several
+// workspaces with one query. Workspaces without an allowlist are missing.
+func (r *WorkspaceRepository) ListIPRangesByWorkspaces(ctx context.Context, workspaceIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	query := `
+		SELECT workspace_id, cidr::text
+		FROM workspace_ip_ranges
+		WHERE workspace_id = ANY($1)
+		ORDER BY created_at, cidr
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP ranges: %w", err)
+	}
+	defer rows.Close()
+
+	cidrs := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var workspaceID uuid.UUID
+		var cidr string
+		if err := rows.Scan(&workspaceID, &cidr); err != nil {
+			return nil, fmt.Errorf("failed to scan IP range: %w", err)
+		}
+		cidrs[workspaceID] = append(cidrs[workspaceID], cidr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating IP ranges: %w", err)
+	}
+
+	return cidrs, nil
+}
+
+// ReplaceIPRanges replaces the allowlist of a workspace. Ranges that stay
+// keep their ID, creator and creation time.
+func (r *WorkspaceRepository) ReplaceIPRanges(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	ranges []models.IPRangeInput,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	cidrs := make([]string, len(ranges))
+	for i := range ranges {
+		cidrs[i] = ranges[i].CIDR
+	}
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM workspace_ip_ranges WHERE workspace_id = $1 AND NOT (cidr = ANY($2::cidr[]))`,
+		workspaceID, cidrs,
+	); err != nil {
+		return fmt.Errorf("failed to delete IP ranges: %w", err)
+	}
+
+	query := `
+		INSERT INTO workspace_ip_ranges (workspace_id, cidr, description, created_by)
+		VALUES ($1, $2::cidr, $3, $4)
+		ON CONFLICT (workspace_id, cidr) DO UPDATE SET description = EXCLUDED.description
+	`
+	for i := range ranges {
+		if _, err := tx.Exec(ctx, query, workspaceID, ranges[i].CIDR, ranges[i].Description, userID); err != nil {
+			return fmt.Errorf("failed to save IP range: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
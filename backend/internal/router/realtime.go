@@ -0,0 +1,48 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+
+	"github.com/bifshteksex/hertz-board/internal/handler"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// SetupRealtime registers the WebSocket endpoint and hub metrics.
+// It is shared by the api-gateway and the standalone ws-server, so both
+// deployment topologies serve realtime traffic through the same Hub code path.
+func SetupRealtime(h *server.Hertz, wsHandler *handler.WebSocketHandler, hub *service.Hub) {
+	// WebSocket endpoint (requires JWT token as query parameter)
+	// Use HTTP adaptor to integrate gorilla/websocket with Hertz
+	upgrade := adaptor.HertzHandler(http.HandlerFunc(wsHandler.HandleWebSocket))
+	h.GET("/ws", func(c context.Context, ctx *app.RequestContext) {
+		// The client address is resolved by Hertz through the trusted
+		// proxies, the adapted request only has the peer address
+		upgrade(handler.WithClientIP(c, ctx.ClientIP()), ctx)
+	})
+
+	h.GET("/metrics", hubMetrics(hub))
+}
+
+// hubMetrics returns room and client counts for the local hub instance
+func hubMetrics(hub *service.Hub) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		stats := hub.GetAllRoomStats()
+
+		clients := 0
+		for _, count := range stats {
+			clients += count
+		}
+
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"rooms":     len(stats),
+			"clients":   clients,
+			"timestamp": time.Now().Unix(),
+		})
+	}
+}
@@ -7,29 +7,72 @@ import (
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
-	"github.com/cloudwego/hertz/pkg/common/adaptor"
 
 	"github.com/bifshteksex/hertz-board/internal/config"
 	"github.com/bifshteksex/hertz-board/internal/handler"
+	"github.com/bifshteksex/hertz-board/internal/metrics"
 	"github.com/bifshteksex/hertz-board/internal/middleware"
 	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/service"
 )
 
+// multipartOverhead is the room left for the part headers and boundaries of
+// a multipart upload on top of the file itself
+const multipartOverhead = 64 << 10
+
 // Dependencies holds all service dependencies
 type Dependencies struct {
-	JWTService       *service.JWTService
-	WorkspaceService *service.WorkspaceService
-	CRDTService      *service.CRDTService
-	Hub              *service.Hub
-	AuthHandler      *handler.AuthHandler
-	UserHandler      *handler.UserHandler
-	OAuthHandler     *handler.OAuthHandler
-	WorkspaceHandler *handler.WorkspaceHandler
-	CanvasHandler    *handler.CanvasHandler
-	AssetHandler     *handler.AssetHandler
-	SnapshotHandler  *handler.SnapshotHandler
-	WSHandler        *handler.WebSocketHandler
+	JWTService            *service.JWTService
+	WorkspaceService      *service.WorkspaceService
+	IPAllowlistService    *service.IPAllowlistService
+	CRDTService           *service.CRDTService
+	Hub                   *service.Hub
+	APIKeyService         *service.APIKeyService
+	BotService            *service.BotService
+	AuthHandler           *handler.AuthHandler
+	UserHandler           *handler.UserHandler
+	ConsentHandler        *handler.ConsentHandler
+	OAuthHandler          *handler.OAuthHandler
+	WorkspaceHandler      *handler.WorkspaceHandler
+	HealthHandler         *handler.WorkspaceHealthHandler
+	IPAllowlistHandler    *handler.IPAllowlistHandler
+	CanvasHandler         *handler.CanvasHandler
+	AssetHandler          *handler.AssetHandler
+	IntegrationHandler    *handler.IntegrationHandler
+	StorageHandler        *handler.StorageHandler
+	SnapshotHandler       *handler.SnapshotHandler
+	ExportHandler         *handler.ExportHandler
+	CommentHandler        *handler.CommentHandler
+	PresentationHandler   *handler.PresentationHandler
+	ViewStateHandler      *handler.ViewStateHandler
+	RecordingHandler      *handler.RecordingHandler
+	SearchHandler         *handler.SearchHandler
+	OperationHandler      *handler.OperationHandler
+	WSHandler             *handler.WebSocketHandler
+	SSEHandler            *handler.SSEHandler
+	AdminHandler          *handler.AdminHandler
+	EmailWebhookHandler   *handler.EmailWebhookHandler
+	WebhookHandler        *handler.WebhookHandler
+	InboundWebhookHandler *handler.InboundWebhookHandler
+	APIKeyHandler         *handler.APIKeyHandler
+	BotHandler            *handler.BotHandler
+	EmbedHandler          *handler.EmbedHandler
+	TriggerHandler        *handler.TriggerHandler
+	SCIMHandler           *handler.SCIMHandler    // nil when SCIM is disabled
+	BillingHandler        *handler.BillingHandler // nil when billing is disabled
+	UsageHandler          *handler.UsageHandler   // nil when metering is disabled
+	NotificationHandler   *handler.NotificationHandler
+	PushHandler           *handler.PushHandler
+	AnalyticsHandler      *handler.AnalyticsHandler
+	DocsHandler           *handler.DocsHandler        // nil when API docs are disabled
+	GraphQLHandler        *handler.GraphQLHandler     // nil when GraphQL is disabled
+	AIHandler             *handler.AIHandler          // nil when AI assistance is disabled
+	TranslationHandler    *handler.TranslationHandler // nil when translation is disabled
+	EmailVerification     *service.EmailVerificationPolicy
+	ConfigReloader        *config.Reloader
+	HTTPMetrics           *metrics.HTTPMetrics      // nil when metrics are disabled
+	RateLimit             app.HandlerFunc           // nil to skip rate limiting
+	Analytics             *service.AnalyticsService // nil when analytics are disabled
 }
 
 // Setup configures all routes and middleware
@@ -37,19 +80,70 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 	// Global middleware
 	h.Use(middleware.Recovery())
 	h.Use(middleware.RequestID())
+	h.Use(middleware.AuditContext())
+	h.Use(middleware.Tracing())
 	h.Use(middleware.Logger())
-	h.Use(middleware.CORS(&cfg.CORS))
+	if deps.HTTPMetrics != nil {
+		h.Use(middleware.Metrics(deps.HTTPMetrics))
+	}
+	h.Use(middleware.CORS(deps.ConfigReloader))
+	h.Use(middleware.BodyLimit(cfg.App.MaxBodySize, map[string]int64{
+		"/api/v1/workspaces/:workspace_id/assets": cfg.Upload.MaxSize + multipartOverhead,
+		"/api/v1/storage/*key":                    cfg.Upload.MaxSize,
+	}))
 
 	// Health check endpoints
 	h.GET("/health", healthCheck)
 	h.GET("/readiness", readinessCheck)
 
-	// WebSocket endpoint (requires JWT token as query parameter)
-	// Use HTTP adaptor to integrate gorilla/websocket with Hertz
-	h.GET("/ws", adaptor.HertzHandler(http.HandlerFunc(deps.WSHandler.HandleWebSocket)))
+	// OpenAPI spec and Swagger UI
+	if deps.DocsHandler != nil {
+		h.GET("/api/docs", deps.DocsHandler.GetUI)
+		h.GET("/api/openapi.json", deps.DocsHandler.GetSpec)
+	}
+
+	// SCIM provisioning, authenticated with the configured bearer token
+	if deps.SCIMHandler != nil {
+		scim := h.Group("/scim/v2")
+		scim.Use(middleware.SCIMAuth(cfg.SCIM.Token))
+		scim.GET("/ServiceProviderConfig", deps.SCIMHandler.GetServiceProviderConfig)
+		scim.GET("/ResourceTypes", deps.SCIMHandler.GetResourceTypes)
+		scim.GET("/Schemas", deps.SCIMHandler.GetSchemas)
+		scim.GET("/Users", deps.SCIMHandler.ListUsers)
+		scim.POST("/Users", deps.SCIMHandler.CreateUser)
+		scim.GET("/Users/:user_id", deps.SCIMHandler.GetUser)
+		scim.PUT("/Users/:user_id", deps.SCIMHandler.ReplaceUser)
+		scim.PATCH("/Users/:user_id", deps.SCIMHandler.PatchUser)
+		scim.DELETE("/Users/:user_id", deps.SCIMHandler.DeleteUser)
+		scim.GET("/Groups", deps.SCIMHandler.ListGroups)
+		scim.POST("/Groups", deps.SCIMHandler.CreateGroup)
+		scim.GET("/Groups/:group_id", deps.SCIMHandler.GetGroup)
+		scim.PUT("/Groups/:group_id", deps.SCIMHandler.ReplaceGroup)
+		scim.PATCH("/Groups/:group_id", deps.SCIMHandler.PatchGroup)
+		scim.DELETE("/Groups/:group_id", deps.SCIMHandler.DeleteGroup)
+	}
+
+	// Read-only boards for iframes, authenticated by the embed token
+	embed := h.Group("/embed",
+		middleware.RequireFeature(deps.ConfigReloader, config.FeatureEmbed),
+		middleware.EmbedHeaders(&cfg.Embed),
+	)
+	if deps.RateLimit != nil {
+		embed.Use(deps.RateLimit)
+	}
+	embed.GET("/:token/elements", deps.EmbedHandler.GetEmbedElements)
+
+	// WebSocket endpoint and hub metrics
+	SetupRealtime(h, deps.WSHandler, deps.Hub)
 
 	// API v1 routes
 	v1 := h.Group("/api/v1")
+	if deps.Analytics != nil {
+		v1.Use(middleware.Analytics(deps.Analytics))
+	}
+	if deps.RateLimit != nil {
+		v1.Use(deps.RateLimit)
+	}
 
 	// Auth routes
 	auth := v1.Group("/auth")
@@ -66,32 +160,172 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 	auth.GET("/github", deps.OAuthHandler.GitHubAuth)
 	auth.GET("/github/callback", deps.OAuthHandler.GitHubCallback)
 
+	requireVerifiedEmail := middleware.RequireVerifiedEmail(deps.EmailVerification)
+
 	// User routes (protected)
 	users := v1.Group("/users")
 	users.Use(middleware.Auth(deps.JWTService))
 	users.GET("/me", deps.UserHandler.GetProfile)
 	users.PUT("/me", deps.UserHandler.UpdateProfile)
 	users.PUT("/me/password", deps.UserHandler.ChangePassword)
+	users.GET("/me/consent", deps.ConsentHandler.GetConsentStatus)
+	users.POST("/me/consent", deps.ConsentHandler.AcceptConsent)
+
+	// Invitations addressed to the user's email, answered without the emailed
+	// token, so the email must be verified instead. The service requires it
+	// even when auth.require_verified_email is off.
+	users.GET("/me/invites", requireVerifiedEmail, deps.WorkspaceHandler.ListUserInvites)
+	users.POST("/me/invites/:invite_id/accept", requireVerifiedEmail, deps.WorkspaceHandler.AcceptUserInvite)
+	users.POST("/me/invites/:invite_id/decline", requireVerifiedEmail, deps.WorkspaceHandler.DeclineUserInvite)
+
+	// Notification routes (protected)
+	notifications := v1.Group("/notifications")
+	notifications.Use(middleware.Auth(deps.JWTService))
+	notifications.GET("", deps.NotificationHandler.ListNotifications)
+	notifications.POST("/read-all", deps.NotificationHandler.MarkAllNotificationsRead)
+	notifications.GET("/preferences", deps.NotificationHandler.GetNotificationPreferences)
+	notifications.PUT("/preferences", deps.NotificationHandler.UpdateNotificationPreferences)
+	notifications.GET("/push/public-key", deps.PushHandler.GetPushPublicKey)
+	notifications.POST("/push/subscriptions", deps.PushHandler.RegisterPushSubscription)
+	notifications.DELETE("/push/subscriptions", deps.PushHandler.UnregisterPushSubscription)
+	notifications.POST("/:notification_id/read", deps.NotificationHandler.MarkNotificationRead)
+
+	// Billing routes (protected), the Stripe webhook is authenticated by
+	// its signature
+	if deps.BillingHandler != nil {
+		billing := v1.Group("/billing")
+		billing.Use(middleware.Auth(deps.JWTService))
+		billing.GET("", deps.BillingHandler.GetBilling)
+		billing.POST("/checkout", deps.BillingHandler.CreateCheckoutSession)
+		billing.POST("/portal", deps.BillingHandler.CreatePortalSession)
+
+		v1.POST("/webhooks/stripe", deps.BillingHandler.HandleStripeWebhook)
+	}
+
+	// Stock media search (protected)
+	integrations := v1.Group("/integrations")
+	integrations.Use(
+		middleware.RequireFeature(deps.ConfigReloader, config.FeatureIntegrations),
+		middleware.Auth(deps.JWTService),
+	)
+	integrations.GET("/unsplash/search", deps.IntegrationHandler.SearchUnsplash)
+	integrations.GET("/giphy/search", deps.IntegrationHandler.SearchGiphy)
+
+	workspaceMiddleware := middleware.NewWorkspaceMiddleware(deps.WorkspaceService, deps.IPAllowlistService)
+
+	// GraphQL API, subscriptions are streamed over Server-Sent Events
+	if deps.GraphQLHandler != nil {
+		v1.POST("/graphql",
+			middleware.RequireFeature(deps.ConfigReloader, config.FeatureGraphQL),
+			middleware.Auth(deps.JWTService),
+			deps.GraphQLHandler.Query,
+		)
+	}
+
+	// Email provider feedback, authenticated by the provider signature
+	v1.POST("/webhooks/email/:provider", deps.EmailWebhookHandler.HandleFeedback)
+
+	// Inbound webhooks, authenticated by the webhook token
+	v1.POST("/hooks/:hook_id", deps.InboundWebhookHandler.ReceiveInboundWebhook)
+
+	// Triggers of automation platforms such as Zapier and Make (API key)
+	automation := v1.Group("/automation")
+	automation.Use(
+		middleware.RequireFeature(deps.ConfigReloader, config.FeatureAutomation),
+		middleware.APIKeyAuth(deps.APIKeyService),
+		workspaceMiddleware.RequireAllowedNetwork(),
+	)
+	automation.GET("/me", deps.TriggerHandler.GetAccount)
+	automation.GET("/triggers/new-element", deps.TriggerHandler.PollNewElements)
+	automation.GET("/triggers/new-comment", deps.TriggerHandler.PollNewComments)
+	automation.GET("/triggers/new-member", deps.TriggerHandler.PollNewMembers)
+	automation.POST("/subscriptions", deps.TriggerHandler.Subscribe)
+	automation.DELETE("/subscriptions/:subscription_id", deps.TriggerHandler.Unsubscribe)
+
+	// Admin routes (protected, configured admins only)
+	admin := v1.Group("/admin")
+	admin.Use(middleware.Auth(deps.JWTService), middleware.RequireAdmin(&cfg.Admin))
+	admin.GET("/emails/dead-letters", deps.AdminHandler.ListDeadLetterEmails)
+	admin.POST("/emails/dead-letters/:sequence/requeue", deps.AdminHandler.RequeueDeadLetterEmail)
+	admin.DELETE("/emails/dead-letters/:sequence", deps.AdminHandler.DeleteDeadLetterEmail)
+	admin.GET("/emails/suppressions", deps.AdminHandler.ListEmailSuppressions)
+	admin.DELETE("/emails/suppressions/:email", deps.AdminHandler.DeleteEmailSuppression)
+	admin.GET("/workspaces", deps.AdminHandler.ListWorkspaces)
+	admin.DELETE("/workspaces/:workspace_id", deps.AdminHandler.DeleteWorkspace)
+	admin.DELETE("/workspaces/:workspace_id/elements/:element_id", deps.AdminHandler.DeleteElement)
+	admin.DELETE("/workspaces/:workspace_id/assets/:asset_id", deps.AdminHandler.PurgeAsset)
+	admin.GET("/realtime/rooms", deps.AdminHandler.ListRealtimeRooms)
+	admin.POST("/realtime/maintenance", deps.AdminHandler.BroadcastMaintenance)
+	admin.GET("/jobs", deps.AdminHandler.ListJobs)
+	admin.POST("/jobs/:job/run", deps.AdminHandler.RunJob)
+	admin.POST("/config/reload", deps.AdminHandler.ReloadConfig)
+	if deps.SCIMHandler != nil {
+		admin.GET("/scim/groups", deps.SCIMHandler.ListGroupWorkspaces)
+		admin.PUT("/scim/groups/:group_id/workspaces/:workspace_id", deps.SCIMHandler.SetGroupWorkspace)
+		admin.DELETE("/scim/groups/:group_id/workspaces/:workspace_id", deps.SCIMHandler.DeleteGroupWorkspace)
+	}
+
+	// Filesystem storage objects, authorized by the presigned URL signature
+	if deps.StorageHandler != nil {
+		v1.GET("/storage/*key", deps.StorageHandler.GetObject)
+		v1.PUT("/storage/*key", deps.StorageHandler.PutObject)
+	}
 
 	// Workspace routes
-	workspaceMiddleware := middleware.NewWorkspaceMiddleware(deps.WorkspaceService)
-
 	workspaces := v1.Group("/workspaces")
-	workspaces.Use(middleware.Auth(deps.JWTService))
+	workspaces.Use(middleware.WorkspaceAuth(deps.JWTService, deps.BotService))
+
+	// Public read routes, also open to anonymous visitors of public
+	// workspaces whose owner allows it, so a token is optional
+	publicWorkspaces := v1.Group("/workspaces")
+	publicWorkspaces.Use(middleware.OptionalWorkspaceAuth(deps.JWTService, deps.BotService))
+
+	publicWorkspaces.GET("/:workspace_id",
+		workspaceMiddleware.OptionalWorkspaceAccess(),
+		deps.WorkspaceHandler.GetWorkspace,
+	)
+
+	publicWorkspaces.GET("/:workspace_id/elements",
+		workspaceMiddleware.OptionalWorkspaceAccess(),
+		deps.CanvasHandler.GetWorkspaceElements,
+	)
+
+	publicWorkspaces.GET("/:workspace_id/elements/by-type",
+		workspaceMiddleware.OptionalWorkspaceAccess(),
+		deps.CanvasHandler.GetElementsByType,
+	)
+
+	publicWorkspaces.GET("/:workspace_id/elements/:element_id",
+		workspaceMiddleware.OptionalWorkspaceAccess(),
+		deps.CanvasHandler.GetElement,
+	)
+
+	// Server-Sent Events fallback for networks that block WebSockets.
+	// Registered outside the workspaces group because EventSource
+	// passes the token as a query parameter.
+	v1.GET("/workspaces/:workspace_id/events",
+		middleware.StreamAuth(deps.JWTService),
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.SSEHandler.Events,
+	)
+
+	// Asset content redirects to a presigned URL. Also registered outside
+	// the workspaces group so it can be used directly in img/video tags.
+	v1.GET("/workspaces/:workspace_id/assets/:asset_id/content",
+		middleware.StreamAuth(deps.JWTService),
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.AssetHandler.GetAssetContent,
+	)
 
 	// Workspace CRUD
 	workspaces.POST("", deps.WorkspaceHandler.CreateWorkspace)
 	workspaces.GET("", deps.WorkspaceHandler.ListWorkspaces)
 
-	// Accept invite (no workspace_id param)
+	// Accept invite or redeem invite link (no workspace_id param)
 	workspaces.POST("/invites/accept", deps.WorkspaceHandler.AcceptInvite)
+	workspaces.POST("/invite-links/redeem", deps.WorkspaceHandler.RedeemInviteLink)
 
 	// Specific workspace routes (require workspace access)
-	workspaces.GET("/:workspace_id",
-		workspaceMiddleware.OptionalWorkspaceAccess(),
-		deps.WorkspaceHandler.GetWorkspace,
-	)
-
 	workspaces.PUT("/:workspace_id",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
 		deps.WorkspaceHandler.UpdateWorkspace,
@@ -107,12 +341,73 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 		deps.WorkspaceHandler.DuplicateWorkspace,
 	)
 
+	// Freezing makes the board read-only, the service checks the role the
+	// owner allows to do it
+	workspaces.POST("/:workspace_id/freeze",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.WorkspaceHandler.FreezeWorkspace,
+	)
+
+	workspaces.POST("/:workspace_id/unfreeze",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.WorkspaceHandler.UnfreezeWorkspace,
+	)
+
+	workspaces.GET("/:workspace_id/stats",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.WorkspaceHandler.GetWorkspaceStats,
+	)
+
+	workspaces.GET("/:workspace_id/contributors",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.WorkspaceHandler.GetContributors,
+	)
+
+	workspaces.GET("/:workspace_id/health",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.HealthHandler.GetWorkspaceHealth,
+	)
+
+	workspaces.GET("/:workspace_id/analytics",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.AnalyticsHandler.GetWorkspaceAnalytics,
+	)
+
+	// Heatmaps are for whoever facilitates sessions on the board
+	workspaces.GET("/:workspace_id/analytics/heatmap",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.AnalyticsHandler.GetWorkspaceHeatmap,
+	)
+
+	if deps.UsageHandler != nil {
+		workspaces.GET("/:workspace_id/usage",
+			workspaceMiddleware.RequireWorkspaceOwner(),
+			deps.UsageHandler.GetWorkspaceUsage,
+		)
+	}
+
+	// Network restrictions (owner only)
+	workspaces.GET("/:workspace_id/ip-allowlist",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.IPAllowlistHandler.GetIPAllowlist,
+	)
+
+	workspaces.PUT("/:workspace_id/ip-allowlist",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.IPAllowlistHandler.UpdateIPAllowlist,
+	)
+
 	// Member management (require editor access)
 	workspaces.GET("/:workspace_id/members",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
 		deps.WorkspaceHandler.ListMembers,
 	)
 
+	workspaces.GET("/:workspace_id/members/suggest",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.WorkspaceHandler.SuggestMembers,
+	)
+
 	workspaces.PUT("/:workspace_id/members/:user_id",
 		workspaceMiddleware.RequireWorkspaceOwner(),
 		deps.WorkspaceHandler.UpdateMemberRole,
@@ -123,9 +418,10 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 		deps.WorkspaceHandler.RemoveMember,
 	)
 
-	// Invitation management (require editor access to create, owner to manage)
+	// Invitation management (require editor access and a verified email to create, owner to manage)
 	workspaces.POST("/:workspace_id/invites",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		requireVerifiedEmail,
 		deps.WorkspaceHandler.CreateInvite,
 	)
 
@@ -139,54 +435,117 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 		deps.WorkspaceHandler.RevokeInvite,
 	)
 
-	// Canvas element routes (require editor access to modify)
-	workspaces.GET("/:workspace_id/elements",
-		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
-		deps.CanvasHandler.GetWorkspaceElements,
+	// Invite links let anyone with the link join, so only the owner manages them
+	workspaces.POST("/:workspace_id/invite-links",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.CreateInviteLink,
 	)
 
-	workspaces.POST("/:workspace_id/elements",
-		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
-		deps.CanvasHandler.CreateElement,
+	workspaces.GET("/:workspace_id/invite-links",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.ListInviteLinks,
 	)
 
-	workspaces.GET("/:workspace_id/elements/by-type",
-		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
-		deps.CanvasHandler.GetElementsByType,
+	workspaces.GET("/:workspace_id/invite-links/:link_id/redemptions",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.ListInviteLinkRedemptions,
 	)
 
-	workspaces.GET("/:workspace_id/elements/:element_id",
-		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
-		deps.CanvasHandler.GetElement,
+	workspaces.DELETE("/:workspace_id/invite-links/:link_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.RevokeInviteLink,
+	)
+
+	// Access requests, by users without access to the workspace, answered by the owner
+	workspaces.POST("/:workspace_id/request-access", deps.WorkspaceHandler.RequestAccess)
+
+	workspaces.GET("/:workspace_id/access-requests",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.ListAccessRequests,
+	)
+
+	workspaces.POST("/:workspace_id/access-requests/:request_id/approve",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.ApproveAccessRequest,
+	)
+
+	workspaces.POST("/:workspace_id/access-requests/:request_id/deny",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WorkspaceHandler.DenyAccessRequest,
+	)
+
+	// Canvas element routes (require editor access to modify)
+	workspaces.POST("/:workspace_id/elements",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CanvasHandler.CreateElement,
 	)
 
 	workspaces.PUT("/:workspace_id/elements/:element_id",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.CanvasHandler.UpdateElement,
 	)
 
 	workspaces.DELETE("/:workspace_id/elements/:element_id",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.CanvasHandler.DeleteElement,
 	)
 
 	// Batch element operations
 	workspaces.POST("/:workspace_id/elements/batch",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.CanvasHandler.BatchCreateElements,
 	)
 
 	workspaces.PUT("/:workspace_id/elements/batch",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.CanvasHandler.BatchUpdateElements,
 	)
 
 	workspaces.DELETE("/:workspace_id/elements/batch",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.CanvasHandler.BatchDeleteElements,
 	)
 
-	// Asset routes (require editor access to upload)
+	// Translation, viewers get the translations and editors may add copies
+	if deps.TranslationHandler != nil {
+		workspaces.POST("/:workspace_id/elements/translate",
+			middleware.RequireFeature(deps.ConfigReloader, config.FeatureTranslation),
+			workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+			deps.TranslationHandler.TranslateElements,
+		)
+	}
+
+	// AI assistance, reading the board and optionally adding its results
+	if deps.AIHandler != nil {
+		workspaces.POST("/:workspace_id/ai/summarize",
+			middleware.RequireFeature(deps.ConfigReloader, config.FeatureAI),
+			workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+			workspaceMiddleware.RequireUnfrozen(),
+			deps.AIHandler.Summarize,
+		)
+
+		workspaces.POST("/:workspace_id/ai/cluster",
+			middleware.RequireFeature(deps.ConfigReloader, config.FeatureAI),
+			workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+			workspaceMiddleware.RequireUnfrozen(),
+			deps.AIHandler.Cluster,
+		)
+
+		workspaces.POST("/:workspace_id/ai/ideas",
+			middleware.RequireFeature(deps.ConfigReloader, config.FeatureAI),
+			workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+			workspaceMiddleware.RequireUnfrozen(),
+			deps.AIHandler.GenerateIdeas,
+		)
+	}
+
+	// Asset routes (require editor access and a verified email to upload)
 	workspaces.GET("/:workspace_id/assets",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
 		deps.AssetHandler.GetWorkspaceAssets,
@@ -194,9 +553,39 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 
 	workspaces.POST("/:workspace_id/assets",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		requireVerifiedEmail,
 		deps.AssetHandler.UploadAsset,
 	)
 
+	workspaces.POST("/:workspace_id/assets/presign",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		requireVerifiedEmail,
+		deps.AssetHandler.PresignUpload,
+	)
+
+	workspaces.POST("/:workspace_id/assets/confirm",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		requireVerifiedEmail,
+		deps.AssetHandler.ConfirmUpload,
+	)
+
+	workspaces.POST("/:workspace_id/assets/from-url",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		requireVerifiedEmail,
+		deps.AssetHandler.ImportAsset,
+	)
+
+	workspaces.POST("/:workspace_id/integrations/:provider/insert",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		requireVerifiedEmail,
+		deps.IntegrationHandler.InsertStockMedia,
+	)
+
 	workspaces.GET("/:workspace_id/assets/:asset_id",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
 		deps.AssetHandler.GetAsset,
@@ -204,6 +593,7 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 
 	workspaces.DELETE("/:workspace_id/assets/:asset_id",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.AssetHandler.DeleteAsset,
 	)
 
@@ -228,6 +618,16 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 		deps.SnapshotHandler.GetSnapshotByVersion,
 	)
 
+	workspaces.GET("/:workspace_id/snapshots/retention",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.SnapshotHandler.GetRetentionPolicy,
+	)
+
+	workspaces.PUT("/:workspace_id/snapshots/retention",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.SnapshotHandler.UpdateRetentionPolicy,
+	)
+
 	workspaces.GET("/:workspace_id/snapshots/:snapshot_id",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
 		deps.SnapshotHandler.GetSnapshot,
@@ -235,13 +635,334 @@ func Setup(h *server.Hertz, cfg *config.Config, deps *Dependencies) {
 
 	workspaces.POST("/:workspace_id/snapshots/:snapshot_id/restore",
 		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
 		deps.SnapshotHandler.RestoreSnapshot,
 	)
 
+	workspaces.POST("/:workspace_id/snapshots/:snapshot_id/restore-elements",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.SnapshotHandler.RestoreSnapshotElements,
+	)
+
+	// Branching only reads the source board, like duplicating it
+	workspaces.POST("/:workspace_id/snapshots/:snapshot_id/restore-to-new",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.SnapshotHandler.RestoreSnapshotToNew,
+	)
+
+	workspaces.PUT("/:workspace_id/snapshots/:snapshot_id",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.SnapshotHandler.UpdateSnapshot,
+	)
+
 	workspaces.DELETE("/:workspace_id/snapshots/:snapshot_id",
 		workspaceMiddleware.RequireWorkspaceOwner(),
 		deps.SnapshotHandler.DeleteSnapshot,
 	)
+
+	// Board exports, rendered in the background
+	workspaces.GET("/:workspace_id/exports",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.ExportHandler.ListExports,
+	)
+
+	workspaces.POST("/:workspace_id/exports",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.ExportHandler.CreateExport,
+	)
+
+	workspaces.GET("/:workspace_id/exports/:export_id",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.ExportHandler.GetExport,
+	)
+
+	workspaces.DELETE("/:workspace_id/exports/:export_id",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.ExportHandler.DeleteExport,
+	)
+
+	// Search across elements, comments and assets
+	workspaces.GET("/:workspace_id/search",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.SearchHandler.Search,
+	)
+
+	// Comment threads, members of any role take part in discussions
+	workspaces.GET("/:workspace_id/comments",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.CommentHandler.ListComments,
+	)
+
+	workspaces.POST("/:workspace_id/comments",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.CreateComment,
+	)
+
+	workspaces.POST("/:workspace_id/comments/:comment_id/replies",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.ReplyToComment,
+	)
+
+	workspaces.PUT("/:workspace_id/comments/:comment_id",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.UpdateComment,
+	)
+
+	workspaces.DELETE("/:workspace_id/comments/:comment_id",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.DeleteComment,
+	)
+
+	workspaces.POST("/:workspace_id/comments/:comment_id/resolve",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.ResolveComment,
+	)
+
+	workspaces.POST("/:workspace_id/comments/:comment_id/reopen",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.ReopenComment,
+	)
+
+	workspaces.POST("/:workspace_id/comments/:comment_id/reactions",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.AddCommentReaction,
+	)
+
+	workspaces.DELETE("/:workspace_id/comments/:comment_id/reactions",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		workspaceMiddleware.RequireUnfrozen(),
+		deps.CommentHandler.RemoveCommentReaction,
+	)
+
+	// Presentations: editors arrange and start them, the presenter or an
+	// editor stops or hands them over
+	workspaces.GET("/:workspace_id/presentation",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.PresentationHandler.GetPresentation,
+	)
+
+	workspaces.PUT("/:workspace_id/presentation/slides",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.PresentationHandler.SetPresentationSlides,
+	)
+
+	workspaces.POST("/:workspace_id/presentation/start",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.PresentationHandler.StartPresentation,
+	)
+
+	workspaces.POST("/:workspace_id/presentation/stop",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.PresentationHandler.StopPresentation,
+	)
+
+	workspaces.PUT("/:workspace_id/presentation/presenter",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.PresentationHandler.ChangePresenter,
+	)
+
+	// Where the current user left off, saved by viewers too
+	workspaces.GET("/:workspace_id/view-state",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.ViewStateHandler.GetViewState,
+	)
+
+	workspaces.PUT("/:workspace_id/view-state",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.ViewStateHandler.UpdateViewState,
+	)
+
+	// Session recordings: editors record, anyone on the board plays back
+	workspaces.POST("/:workspace_id/recordings",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.RecordingHandler.StartRecording,
+	)
+
+	workspaces.GET("/:workspace_id/recordings",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.RecordingHandler.ListRecordings,
+	)
+
+	workspaces.GET("/:workspace_id/recordings/:recording_id",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.RecordingHandler.GetRecording,
+	)
+
+	workspaces.POST("/:workspace_id/recordings/:recording_id/stop",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleEditor),
+		deps.RecordingHandler.StopRecording,
+	)
+
+	workspaces.GET("/:workspace_id/recordings/:recording_id/playback",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.RecordingHandler.PlayRecording,
+	)
+
+	// Outgoing webhooks (owner only)
+	workspaces.GET("/:workspace_id/webhooks",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.ListWebhooks,
+	)
+
+	workspaces.POST("/:workspace_id/webhooks",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.CreateWebhook,
+	)
+
+	workspaces.GET("/:workspace_id/webhooks/:webhook_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.GetWebhook,
+	)
+
+	workspaces.PUT("/:workspace_id/webhooks/:webhook_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.UpdateWebhook,
+	)
+
+	workspaces.DELETE("/:workspace_id/webhooks/:webhook_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.DeleteWebhook,
+	)
+
+	workspaces.GET("/:workspace_id/webhooks/:webhook_id/deliveries",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.ListDeliveries,
+	)
+
+	workspaces.POST("/:workspace_id/webhooks/:webhook_id/test",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.WebhookHandler.TestWebhook,
+	)
+
+	// Inbound webhooks (owner only)
+	workspaces.GET("/:workspace_id/inbound-webhooks",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.InboundWebhookHandler.ListInboundWebhooks,
+	)
+
+	workspaces.POST("/:workspace_id/inbound-webhooks",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.InboundWebhookHandler.CreateInboundWebhook,
+	)
+
+	workspaces.GET("/:workspace_id/inbound-webhooks/:webhook_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.InboundWebhookHandler.GetInboundWebhook,
+	)
+
+	workspaces.PUT("/:workspace_id/inbound-webhooks/:webhook_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.InboundWebhookHandler.UpdateInboundWebhook,
+	)
+
+	workspaces.DELETE("/:workspace_id/inbound-webhooks/:webhook_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.InboundWebhookHandler.DeleteInboundWebhook,
+	)
+
+	// API keys of automation platforms (owner only)
+	workspaces.GET("/:workspace_id/api-keys",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.APIKeyHandler.ListAPIKeys,
+	)
+
+	workspaces.POST("/:workspace_id/api-keys",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.APIKeyHandler.CreateAPIKey,
+	)
+
+	workspaces.DELETE("/:workspace_id/api-keys/:key_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.APIKeyHandler.DeleteAPIKey,
+	)
+
+	// Bot accounts and their scoped tokens (owner only)
+	workspaces.GET("/:workspace_id/bots",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.BotHandler.ListBots,
+	)
+
+	workspaces.POST("/:workspace_id/bots",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.BotHandler.CreateBot,
+	)
+
+	workspaces.DELETE("/:workspace_id/bots/:bot_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.BotHandler.DeleteBot,
+	)
+
+	workspaces.GET("/:workspace_id/bots/:bot_id/tokens",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.BotHandler.ListBotTokens,
+	)
+
+	workspaces.POST("/:workspace_id/bots/:bot_id/tokens",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.BotHandler.CreateBotToken,
+	)
+
+	workspaces.DELETE("/:workspace_id/bots/:bot_id/tokens/:token_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.BotHandler.DeleteBotToken,
+	)
+
+	// Embed tokens of read-only boards (owner only)
+	workspaces.GET("/:workspace_id/embed-tokens",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.EmbedHandler.ListEmbedTokens,
+	)
+
+	workspaces.POST("/:workspace_id/embed-tokens",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.EmbedHandler.CreateEmbedToken,
+	)
+
+	workspaces.DELETE("/:workspace_id/embed-tokens/:token_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.EmbedHandler.DeleteEmbedToken,
+	)
+
+	// Operation history replay
+	workspaces.GET("/:workspace_id/replay",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.OperationHandler.Replay,
+	)
+
+	// Operation history reports for compliance reviews (owner only)
+	workspaces.GET("/:workspace_id/operations/export",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.ExportHandler.ExportOperations,
+	)
+
+	workspaces.GET("/:workspace_id/operations/exports",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.ExportHandler.ListOperationReports,
+	)
+
+	workspaces.GET("/:workspace_id/operations/exports/:export_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.ExportHandler.GetOperationReport,
+	)
+
+	workspaces.DELETE("/:workspace_id/operations/exports/:export_id",
+		workspaceMiddleware.RequireWorkspaceOwner(),
+		deps.ExportHandler.DeleteOperationReport,
+	)
+
+	// Realtime updates from SSE clients (editor role is checked per message type)
+	workspaces.POST("/:workspace_id/events",
+		workspaceMiddleware.RequireWorkspaceAccess(models.WorkspaceRoleViewer),
+		deps.SSEHandler.Publish,
+	)
 }
 
 // healthCheck returns basic health status
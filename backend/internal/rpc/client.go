@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/rpc/realtimev1"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// broadcastTimeout bounds a broadcast to the ws-server before it falls back
+// to the broker
+const broadcastTimeout = 3 * time.Second
+
+// Client calls the realtime service of the ws-server
+type Client struct {
+	conn     *grpc.ClientConn
+	realtime realtimev1.RealtimeServiceClient
+	fallback service.RoomBroadcaster
+}
+
+// NewClient connects to the ws-server at cfg.Addr. The connection is
+// established lazily. Broadcasts that fail are delivered through fallback,
+// usually the local hub, so a ws-server restart doesn't lose them.
+func NewClient(cfg *config.GRPCConfig, fallback service.RoomBroadcaster) (*Client, error) {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if cfg.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(cfg.Token)))
+	}
+
+	conn, err := grpc.NewClient(cfg.Addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", cfg.Addr, err)
+	}
+
+	return &Client{
+		conn:     conn,
+		realtime: realtimev1.NewRealtimeServiceClient(conn),
+		fallback: fallback,
+	}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// BroadcastToRoom sends a message to the clients of a room through the
+// ws-server. It implements service.RoomBroadcaster.
+func (c *Client) BroadcastToRoom(workspaceID uuid.UUID, msg *models.WSMessage, excludeClientID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+	defer cancel()
+
+	if err := c.Broadcast(ctx, workspaceID, msg, excludeClientID); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to broadcast to room %s over gRPC, using the broker: %v", workspaceID, err)
+		if c.fallback != nil {
+			c.fallback.BroadcastToRoom(workspaceID, msg, excludeClientID)
+		}
+	}
+}
+
+// Broadcast sends a message to the clients of a room
+func (c *Client) Broadcast(ctx context.Context, workspaceID uuid.UUID, msg *models.WSMessage, excludeClientID uuid.UUID) error {
+	protoMsg, err := toProtoMessage(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req := &realtimev1.BroadcastRequest{
+		WorkspaceId: workspaceID.String(),
+		Message:     protoMsg,
+	}
+	if excludeClientID != uuid.Nil {
+		req.ExcludeClientId = excludeClientID.String()
+	}
+
+	_, err = c.realtime.Broadcast(ctx, req)
+	return err
+}
+
+// RoomStats returns the client counts per room of the instance that served
+// the call, of one room or of all rooms when workspaceID is uuid.Nil
+func (c *Client) RoomStats(ctx context.Context, workspaceID uuid.UUID) (*realtimev1.GetRoomStatsResponse, error) {
+	req := &realtimev1.GetRoomStatsRequest{}
+	if workspaceID != uuid.Nil {
+		req.WorkspaceId = workspaceID.String()
+	}
+	return c.realtime.GetRoomStats(ctx, req)
+}
+
+// DisconnectUser closes the connections of a user, in one room or in all
+// rooms when workspaceID is uuid.Nil. Returns the number of closed
+// connections.
+func (c *Client) DisconnectUser(ctx context.Context, workspaceID, userID uuid.UUID, reason string) (int, error) {
+	req := &realtimev1.DisconnectUserRequest{
+		UserId: userID.String(),
+		Reason: reason,
+	}
+	if workspaceID != uuid.Nil {
+		req.WorkspaceId = workspaceID.String()
+	}
+
+	resp, err := c.realtime.DisconnectUser(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.GetDisconnected()), nil
+}
+
+// ApplyOperations stores operations of a workspace and broadcasts them to
+// its room. Returns the number of applied operations.
+func (c *Client) ApplyOperations(ctx context.Context, workspaceID uuid.UUID, ops []models.OperationPayload) (int, error) {
+	req := &realtimev1.ApplyOperationsRequest{
+		WorkspaceId: workspaceID.String(),
+		Operations:  make([]*realtimev1.Operation, 0, len(ops)),
+	}
+	for i := range ops {
+		protoOp, err := toProtoOperation(&ops[i])
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode operation: %w", err)
+		}
+		req.Operations = append(req.Operations, protoOp)
+	}
+
+	resp, err := c.realtime.ApplyOperations(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.GetApplied()), nil
+}
+
+// bearerToken sends the shared token with every call
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows the token over plaintext, the API is only
+// reachable on the internal network
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
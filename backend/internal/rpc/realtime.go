@@ -0,0 +1,273 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/rpc/realtimev1"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// realtimeServer implements the realtime service over the hub and the CRDT
+// service of the ws-server
+type realtimeServer struct {
+	realtimev1.UnimplementedRealtimeServiceServer
+	hub  *service.Hub
+	crdt *service.CRDTService
+}
+
+// Broadcast sends a message to a room through the hub, which also fans it
+// out to the other instances
+func (s *realtimeServer) Broadcast(
+	_ context.Context,
+	req *realtimev1.BroadcastRequest,
+) (*realtimev1.BroadcastResponse, error) {
+	workspaceID, err := parseID(req.GetWorkspaceId(), "workspace_id", true)
+	if err != nil {
+		return nil, err
+	}
+	excludeClientID, err := parseID(req.GetExcludeClientId(), "exclude_client_id", false)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetMessage().GetType() == "" {
+		return nil, status.Error(codes.InvalidArgument, "message type is required")
+	}
+
+	msg, err := fromProtoMessage(req.GetMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	s.hub.BroadcastToRoom(workspaceID, msg, excludeClientID)
+	return &realtimev1.BroadcastResponse{}, nil
+}
+
+// GetRoomStats returns the client counts of the rooms of this instance
+func (s *realtimeServer) GetRoomStats(
+	_ context.Context,
+	req *realtimev1.GetRoomStatsRequest,
+) (*realtimev1.GetRoomStatsResponse, error) {
+	workspaceID, err := parseID(req.GetWorkspaceId(), "workspace_id", false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &realtimev1.GetRoomStatsResponse{InstanceId: s.hub.InstanceID().String()}
+	if workspaceID != uuid.Nil {
+		if clients, exists := s.hub.GetRoomStats(workspaceID); exists {
+			resp.Rooms = append(resp.Rooms, &realtimev1.RoomStats{
+				WorkspaceId: workspaceID.String(),
+				Clients:     int32(clients), //nolint:gosec // rooms are capped far below int32
+			})
+		}
+		return resp, nil
+	}
+
+	for id, clients := range s.hub.GetAllRoomStats() {
+		resp.Rooms = append(resp.Rooms, &realtimev1.RoomStats{
+			WorkspaceId: id.String(),
+			Clients:     int32(clients), //nolint:gosec // rooms are capped far below int32
+		})
+	}
+	return resp, nil
+}
+
+// DisconnectUser closes the connections of a user to this instance
+func (s *realtimeServer) DisconnectUser(
+	ctx context.Context,
+	req *realtimev1.DisconnectUserRequest,
+) (*realtimev1.DisconnectUserResponse, error) {
+	userID, err := parseID(req.GetUserId(), "user_id", true)
+	if err != nil {
+		return nil, err
+	}
+	workspaceID, err := parseID(req.GetWorkspaceId(), "workspace_id", false)
+	if err != nil {
+		return nil, err
+	}
+
+	disconnected := s.hub.DisconnectUser(workspaceID, userID, req.GetReason())
+	hlog.CtxInfof(ctx, "Disconnected %d connections of user %s", disconnected, userID)
+
+	return &realtimev1.DisconnectUserResponse{
+		Disconnected: int32(disconnected), //nolint:gosec // bounded by the clients of the instance
+	}, nil
+}
+
+// ApplyOperations stores operations in order and broadcasts the applied ones
+// to the room, a single operation as an operation message and several as a
+// batch. It stops at the first operation that fails.
+func (s *realtimeServer) ApplyOperations(
+	ctx context.Context,
+	req *realtimev1.ApplyOperationsRequest,
+) (*realtimev1.ApplyOperationsResponse, error) {
+	workspaceID, err := parseID(req.GetWorkspaceId(), "workspace_id", true)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]models.OperationPayload, 0, len(req.GetOperations()))
+	for _, protoOp := range req.GetOperations() {
+		op, err := fromProtoOperation(workspaceID, protoOp)
+		if err != nil {
+			return nil, err
+		}
+		if op.Timestamp == 0 {
+			op.Timestamp = s.crdt.GenerateTimestamp()
+		}
+		ops = append(ops, *op)
+	}
+
+	applied := 0
+	var applyErr error
+	for i := range ops {
+		if applyErr = s.crdt.ApplyOperation(ctx, &ops[i]); applyErr != nil {
+			hlog.CtxErrorf(ctx, "Failed to apply operation for element %s: %v", ops[i].ElementID, applyErr)
+			break
+		}
+		applied++
+	}
+
+	if applied > 0 {
+		s.broadcastOperations(workspaceID, ops[:applied])
+	}
+	if errors.Is(applyErr, service.ErrElementNotFound) {
+		return nil, status.Errorf(codes.NotFound, "applied %d of %d operations: %v", applied, len(ops), applyErr)
+	}
+	if applyErr != nil {
+		return nil, status.Errorf(codes.Internal, "applied %d of %d operations: %v", applied, len(ops), applyErr)
+	}
+
+	return &realtimev1.ApplyOperationsResponse{
+		Applied: int32(applied), //nolint:gosec // bounded by the request size
+	}, nil
+}
+
+func (s *realtimeServer) broadcastOperations(workspaceID uuid.UUID, ops []models.OperationPayload) {
+	msg := &models.WSMessage{
+		Type:      models.MessageTypeBatch,
+		UserID:    ops[0].UserID,
+		Timestamp: time.Now(),
+		Payload:   models.BatchPayload{Operations: ops},
+	}
+	if len(ops) == 1 {
+		msg.Type = models.MessageTypeOperation
+		msg.Payload = ops[0]
+	}
+	s.hub.BroadcastToRoom(workspaceID, msg, uuid.Nil)
+}
+
+// parseID parses a UUID field of a request. Optional fields may be empty
+// and are returned as uuid.Nil.
+func parseID(value, field string, required bool) (uuid.UUID, error) {
+	if value == "" {
+		if required {
+			return uuid.Nil, status.Errorf(codes.InvalidArgument, "%s is required", field)
+		}
+		return uuid.Nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid %s", field)
+	}
+	return id, nil
+}
+
+// fromProtoMessage converts a message for the hub. The payload stays raw
+// JSON, it is written to clients as is.
+func fromProtoMessage(m *realtimev1.WSMessage) (*models.WSMessage, error) {
+	userID, err := parseID(m.GetUserId(), "message user_id", false)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &models.WSMessage{
+		Type:      models.MessageType(m.GetType()),
+		UserID:    userID,
+		RequestID: m.GetRequestId(),
+		Timestamp: time.Now(),
+	}
+	if m.GetTimestampUnixMs() != 0 {
+		msg.Timestamp = time.UnixMilli(m.GetTimestampUnixMs())
+	}
+	if len(m.GetPayload()) > 0 {
+		if !json.Valid(m.GetPayload()) {
+			return nil, status.Error(codes.InvalidArgument, "message payload must be JSON")
+		}
+		msg.Payload = json.RawMessage(m.GetPayload())
+	}
+	return msg, nil
+}
+
+// toProtoMessage converts a hub message, encoding its payload as JSON
+func toProtoMessage(msg *models.WSMessage) (*realtimev1.WSMessage, error) {
+	m := &realtimev1.WSMessage{
+		Type:            string(msg.Type),
+		RequestId:       msg.RequestID,
+		TimestampUnixMs: msg.Timestamp.UnixMilli(),
+	}
+	if msg.UserID != uuid.Nil {
+		m.UserId = msg.UserID.String()
+	}
+	if msg.Payload != nil {
+		payload, err := json.Marshal(msg.Payload)
+		if err != nil {
+			return nil, err
+		}
+		m.Payload = payload
+	}
+	return m, nil
+}
+
+func fromProtoOperation(workspaceID uuid.UUID, protoOp *realtimev1.Operation) (*models.OperationPayload, error) {
+	elementID, err := parseID(protoOp.GetElementId(), "element_id", true)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := parseID(protoOp.GetUserId(), "user_id", true)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &models.OperationPayload{
+		ElementID:   elementID,
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		OpType:      models.OperationType(protoOp.GetOpType()),
+		Timestamp:   protoOp.GetTimestamp(),
+	}
+	if len(protoOp.GetData()) > 0 {
+		if !json.Valid(protoOp.GetData()) {
+			return nil, status.Error(codes.InvalidArgument, "operation data must be JSON")
+		}
+		op.Data = json.RawMessage(protoOp.GetData())
+	}
+	return op, nil
+}
+
+func toProtoOperation(op *models.OperationPayload) (*realtimev1.Operation, error) {
+	protoOp := &realtimev1.Operation{
+		ElementId: op.ElementID.String(),
+		UserId:    op.UserID.String(),
+		OpType:    string(op.OpType),
+		Timestamp: op.Timestamp,
+	}
+	if op.Data != nil {
+		data, err := json.Marshal(op.Data)
+		if err != nil {
+			return nil, err
+		}
+		protoOp.Data = data
+	}
+	return protoOp, nil
+}
@@ -0,0 +1,748 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: realtime/v1/realtime.proto
+
+package realtimev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// WSMessage mirrors the WebSocket message envelope. The payload is kept as
+// JSON so it reaches clients without being decoded and encoded again.
+type WSMessage struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Type            string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Payload         []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	UserId          string                 `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	RequestId       string                 `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	TimestampUnixMs int64                  `protobuf:"varint,5,opt,name=timestamp_unix_ms,json=timestampUnixMs,proto3" json:"timestamp_unix_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WSMessage) Reset() {
+	*x = WSMessage{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WSMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WSMessage) ProtoMessage() {}
+
+func (x *WSMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WSMessage.ProtoReflect.Descriptor instead.
+func (*WSMessage) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WSMessage) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WSMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *WSMessage) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *WSMessage) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *WSMessage) GetTimestampUnixMs() int64 {
+	if x != nil {
+		return x.TimestampUnixMs
+	}
+	return 0
+}
+
+type BroadcastRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	WorkspaceId string                 `protobuf:"bytes,1,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	Message     *WSMessage             `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Client that must not receive the message, usually its sender
+	ExcludeClientId string `protobuf:"bytes,3,opt,name=exclude_client_id,json=excludeClientId,proto3" json:"exclude_client_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *BroadcastRequest) Reset() {
+	*x = BroadcastRequest{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastRequest) ProtoMessage() {}
+
+func (x *BroadcastRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastRequest.ProtoReflect.Descriptor instead.
+func (*BroadcastRequest) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BroadcastRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+func (x *BroadcastRequest) GetMessage() *WSMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *BroadcastRequest) GetExcludeClientId() string {
+	if x != nil {
+		return x.ExcludeClientId
+	}
+	return ""
+}
+
+type BroadcastResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BroadcastResponse) Reset() {
+	*x = BroadcastResponse{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastResponse) ProtoMessage() {}
+
+func (x *BroadcastResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastResponse.ProtoReflect.Descriptor instead.
+func (*BroadcastResponse) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{2}
+}
+
+type GetRoomStatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only this room, empty for all rooms
+	WorkspaceId   string `protobuf:"bytes,1,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoomStatsRequest) Reset() {
+	*x = GetRoomStatsRequest{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoomStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoomStatsRequest) ProtoMessage() {}
+
+func (x *GetRoomStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoomStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetRoomStatsRequest) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRoomStatsRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+type RoomStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkspaceId   string                 `protobuf:"bytes,1,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	Clients       int32                  `protobuf:"varint,2,opt,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoomStats) Reset() {
+	*x = RoomStats{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomStats) ProtoMessage() {}
+
+func (x *RoomStats) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomStats.ProtoReflect.Descriptor instead.
+func (*RoomStats) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RoomStats) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+func (x *RoomStats) GetClients() int32 {
+	if x != nil {
+		return x.Clients
+	}
+	return 0
+}
+
+type GetRoomStatsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the ws-server instance, rooms are local to it
+	InstanceId    string       `protobuf:"bytes,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	Rooms         []*RoomStats `protobuf:"bytes,2,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoomStatsResponse) Reset() {
+	*x = GetRoomStatsResponse{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoomStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoomStatsResponse) ProtoMessage() {}
+
+func (x *GetRoomStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoomStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetRoomStatsResponse) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetRoomStatsResponse) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *GetRoomStatsResponse) GetRooms() []*RoomStats {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+type DisconnectUserRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Only connections to this room, empty for all rooms
+	WorkspaceId string `protobuf:"bytes,2,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	// Sent to the clients in an error message before they are closed
+	Reason        string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectUserRequest) Reset() {
+	*x = DisconnectUserRequest{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectUserRequest) ProtoMessage() {}
+
+func (x *DisconnectUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectUserRequest.ProtoReflect.Descriptor instead.
+func (*DisconnectUserRequest) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DisconnectUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *DisconnectUserRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+func (x *DisconnectUserRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type DisconnectUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Disconnected  int32                  `protobuf:"varint,1,opt,name=disconnected,proto3" json:"disconnected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectUserResponse) Reset() {
+	*x = DisconnectUserResponse{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectUserResponse) ProtoMessage() {}
+
+func (x *DisconnectUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectUserResponse.ProtoReflect.Descriptor instead.
+func (*DisconnectUserResponse) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DisconnectUserResponse) GetDisconnected() int32 {
+	if x != nil {
+		return x.Disconnected
+	}
+	return 0
+}
+
+// Operation mirrors the CRDT operation payload. Element data is JSON.
+type Operation struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ElementId string                 `protobuf:"bytes,1,opt,name=element_id,json=elementId,proto3" json:"element_id,omitempty"`
+	UserId    string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	OpType    string                 `protobuf:"bytes,3,opt,name=op_type,json=opType,proto3" json:"op_type,omitempty"`
+	Data      []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// Lamport timestamp, generated by the server when zero
+	Timestamp     int64 `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Operation) GetElementId() string {
+	if x != nil {
+		return x.ElementId
+	}
+	return ""
+}
+
+func (x *Operation) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Operation) GetOpType() string {
+	if x != nil {
+		return x.OpType
+	}
+	return ""
+}
+
+func (x *Operation) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Operation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type ApplyOperationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkspaceId   string                 `protobuf:"bytes,1,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"`
+	Operations    []*Operation           `protobuf:"bytes,2,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyOperationsRequest) Reset() {
+	*x = ApplyOperationsRequest{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyOperationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyOperationsRequest) ProtoMessage() {}
+
+func (x *ApplyOperationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyOperationsRequest.ProtoReflect.Descriptor instead.
+func (*ApplyOperationsRequest) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ApplyOperationsRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+func (x *ApplyOperationsRequest) GetOperations() []*Operation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type ApplyOperationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Applied       int32                  `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyOperationsResponse) Reset() {
+	*x = ApplyOperationsResponse{}
+	mi := &file_realtime_v1_realtime_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyOperationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyOperationsResponse) ProtoMessage() {}
+
+func (x *ApplyOperationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_realtime_v1_realtime_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyOperationsResponse.ProtoReflect.Descriptor instead.
+func (*ApplyOperationsResponse) Descriptor() ([]byte, []int) {
+	return file_realtime_v1_realtime_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ApplyOperationsResponse) GetApplied() int32 {
+	if x != nil {
+		return x.Applied
+	}
+	return 0
+}
+
+var File_realtime_v1_realtime_proto protoreflect.FileDescriptor
+
+const file_realtime_v1_realtime_proto_rawDesc = "" +
+	"\n" +
+	"\x1arealtime/v1/realtime.proto\x12\x16hertzboard.realtime.v1\"\x9d\x01\n" +
+	"\tWSMessage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x04 \x01(\tR\trequestId\x12*\n" +
+	"\x11timestamp_unix_ms\x18\x05 \x01(\x03R\x0ftimestampUnixMs\"\x9e\x01\n" +
+	"\x10BroadcastRequest\x12!\n" +
+	"\fworkspace_id\x18\x01 \x01(\tR\vworkspaceId\x12;\n" +
+	"\amessage\x18\x02 \x01(\v2!.hertzboard.realtime.v1.WSMessageR\amessage\x12*\n" +
+	"\x11exclude_client_id\x18\x03 \x01(\tR\x0fexcludeClientId\"\x13\n" +
+	"\x11BroadcastResponse\"8\n" +
+	"\x13GetRoomStatsRequest\x12!\n" +
+	"\fworkspace_id\x18\x01 \x01(\tR\vworkspaceId\"H\n" +
+	"\tRoomStats\x12!\n" +
+	"\fworkspace_id\x18\x01 \x01(\tR\vworkspaceId\x12\x18\n" +
+	"\aclients\x18\x02 \x01(\x05R\aclients\"p\n" +
+	"\x14GetRoomStatsResponse\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\tR\n" +
+	"instanceId\x127\n" +
+	"\x05rooms\x18\x02 \x03(\v2!.hertzboard.realtime.v1.RoomStatsR\x05rooms\"k\n" +
+	"\x15DisconnectUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
+	"\fworkspace_id\x18\x02 \x01(\tR\vworkspaceId\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"<\n" +
+	"\x16DisconnectUserResponse\x12\"\n" +
+	"\fdisconnected\x18\x01 \x01(\x05R\fdisconnected\"\x8e\x01\n" +
+	"\tOperation\x12\x1d\n" +
+	"\n" +
+	"element_id\x18\x01 \x01(\tR\telementId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x17\n" +
+	"\aop_type\x18\x03 \x01(\tR\x06opType\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"~\n" +
+	"\x16ApplyOperationsRequest\x12!\n" +
+	"\fworkspace_id\x18\x01 \x01(\tR\vworkspaceId\x12A\n" +
+	"\n" +
+	"operations\x18\x02 \x03(\v2!.hertzboard.realtime.v1.OperationR\n" +
+	"operations\"3\n" +
+	"\x17ApplyOperationsResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\x05R\aapplied2\xc3\x03\n" +
+	"\x0fRealtimeService\x12`\n" +
+	"\tBroadcast\x12(.hertzboard.realtime.v1.BroadcastRequest\x1a).hertzboard.realtime.v1.BroadcastResponse\x12i\n" +
+	"\fGetRoomStats\x12+.hertzboard.realtime.v1.GetRoomStatsRequest\x1a,.hertzboard.realtime.v1.GetRoomStatsResponse\x12o\n" +
+	"\x0eDisconnectUser\x12-.hertzboard.realtime.v1.DisconnectUserRequest\x1a..hertzboard.realtime.v1.DisconnectUserResponse\x12r\n" +
+	"\x0fApplyOperations\x12..hertzboard.realtime.v1.ApplyOperationsRequest\x1a/.hertzboard.realtime.v1.ApplyOperationsResponseBGZEgithub.com/bifshteksex/hertz-board/internal/rpc/realtimev1;realtimev1b\x06proto3"
+
+var (
+	file_realtime_v1_realtime_proto_rawDescOnce sync.Once
+	file_realtime_v1_realtime_proto_rawDescData []byte
+)
+
+func file_realtime_v1_realtime_proto_rawDescGZIP() []byte {
+	file_realtime_v1_realtime_proto_rawDescOnce.Do(func() {
+		file_realtime_v1_realtime_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_realtime_v1_realtime_proto_rawDesc), len(file_realtime_v1_realtime_proto_rawDesc)))
+	})
+	return file_realtime_v1_realtime_proto_rawDescData
+}
+
+var file_realtime_v1_realtime_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_realtime_v1_realtime_proto_goTypes = []any{
+	(*WSMessage)(nil),               // 0: hertzboard.realtime.v1.WSMessage
+	(*BroadcastRequest)(nil),        // 1: hertzboard.realtime.v1.BroadcastRequest
+	(*BroadcastResponse)(nil),       // 2: hertzboard.realtime.v1.BroadcastResponse
+	(*GetRoomStatsRequest)(nil),     // 3: hertzboard.realtime.v1.GetRoomStatsRequest
+	(*RoomStats)(nil),               // 4: hertzboard.realtime.v1.RoomStats
+	(*GetRoomStatsResponse)(nil),    // 5: hertzboard.realtime.v1.GetRoomStatsResponse
+	(*DisconnectUserRequest)(nil),   // 6: hertzboard.realtime.v1.DisconnectUserRequest
+	(*DisconnectUserResponse)(nil),  // 7: hertzboard.realtime.v1.DisconnectUserResponse
+	(*Operation)(nil),               // 8: hertzboard.realtime.v1.Operation
+	(*ApplyOperationsRequest)(nil),  // 9: hertzboard.realtime.v1.ApplyOperationsRequest
+	(*ApplyOperationsResponse)(nil), // 10: hertzboard.realtime.v1.ApplyOperationsResponse
+}
+var file_realtime_v1_realtime_proto_depIdxs = []int32{
+	0,  // 0: hertzboard.realtime.v1.BroadcastRequest.message:type_name -> hertzboard.realtime.v1.WSMessage
+	4,  // 1: hertzboard.realtime.v1.GetRoomStatsResponse.rooms:type_name -> hertzboard.realtime.v1.RoomStats
+	8,  // 2: hertzboard.realtime.v1.ApplyOperationsRequest.operations:type_name -> hertzboard.realtime.v1.Operation
+	1,  // 3: hertzboard.realtime.v1.RealtimeService.Broadcast:input_type -> hertzboard.realtime.v1.BroadcastRequest
+	3,  // 4: hertzboard.realtime.v1.RealtimeService.GetRoomStats:input_type -> hertzboard.realtime.v1.GetRoomStatsRequest
+	6,  // 5: hertzboard.realtime.v1.RealtimeService.DisconnectUser:input_type -> hertzboard.realtime.v1.DisconnectUserRequest
+	9,  // 6: hertzboard.realtime.v1.RealtimeService.ApplyOperations:input_type -> hertzboard.realtime.v1.ApplyOperationsRequest
+	2,  // 7: hertzboard.realtime.v1.RealtimeService.Broadcast:output_type -> hertzboard.realtime.v1.BroadcastResponse
+	5,  // 8: hertzboard.realtime.v1.RealtimeService.GetRoomStats:output_type -> hertzboard.realtime.v1.GetRoomStatsResponse
+	7,  // 9: hertzboard.realtime.v1.RealtimeService.DisconnectUser:output_type -> hertzboard.realtime.v1.DisconnectUserResponse
+	10, // 10: hertzboard.realtime.v1.RealtimeService.ApplyOperations:output_type -> hertzboard.realtime.v1.ApplyOperationsResponse
+	7,  // [7:11] is the sub-list for method output_type
+	3,  // [3:7] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_realtime_v1_realtime_proto_init() }
+func file_realtime_v1_realtime_proto_init() {
+	if File_realtime_v1_realtime_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_realtime_v1_realtime_proto_rawDesc), len(file_realtime_v1_realtime_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_realtime_v1_realtime_proto_goTypes,
+		DependencyIndexes: file_realtime_v1_realtime_proto_depIdxs,
+		MessageInfos:      file_realtime_v1_realtime_proto_msgTypes,
+	}.Build()
+	File_realtime_v1_realtime_proto = out.File
+	file_realtime_v1_realtime_proto_goTypes = nil
+	file_realtime_v1_realtime_proto_depIdxs = nil
+}
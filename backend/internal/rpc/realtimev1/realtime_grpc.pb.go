@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: realtime/v1/realtime.proto
+
+package realtimev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RealtimeService_Broadcast_FullMethodName       = "/hertzboard.realtime.v1.RealtimeService/Broadcast"
+	RealtimeService_GetRoomStats_FullMethodName    = "/hertzboard.realtime.v1.RealtimeService/GetRoomStats"
+	RealtimeService_DisconnectUser_FullMethodName  = "/hertzboard.realtime.v1.RealtimeService/DisconnectUser"
+	RealtimeService_ApplyOperations_FullMethodName = "/hertzboard.realtime.v1.RealtimeService/ApplyOperations"
+)
+
+// RealtimeServiceClient is the client API for RealtimeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RealtimeService is the internal API of the ws-server. The api-gateway uses
+// it to push REST-originated changes into live rooms, admin tooling to
+// inspect and manage realtime state. It is not exposed to clients.
+type RealtimeServiceClient interface {
+	// Broadcast sends a message to every client of a workspace room, on all
+	// ws-server instances
+	Broadcast(ctx context.Context, in *BroadcastRequest, opts ...grpc.CallOption) (*BroadcastResponse, error)
+	// GetRoomStats returns the rooms of the instance that serves the call
+	GetRoomStats(ctx context.Context, in *GetRoomStatsRequest, opts ...grpc.CallOption) (*GetRoomStatsResponse, error)
+	// DisconnectUser closes the connections of a user, in one room or in all
+	// rooms of the instance
+	DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error)
+	// ApplyOperations stores CRDT operations and broadcasts them to the room
+	// of their workspace
+	ApplyOperations(ctx context.Context, in *ApplyOperationsRequest, opts ...grpc.CallOption) (*ApplyOperationsResponse, error)
+}
+
+type realtimeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRealtimeServiceClient(cc grpc.ClientConnInterface) RealtimeServiceClient {
+	return &realtimeServiceClient{cc}
+}
+
+func (c *realtimeServiceClient) Broadcast(ctx context.Context, in *BroadcastRequest, opts ...grpc.CallOption) (*BroadcastResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BroadcastResponse)
+	err := c.cc.Invoke(ctx, RealtimeService_Broadcast_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *realtimeServiceClient) GetRoomStats(ctx context.Context, in *GetRoomStatsRequest, opts ...grpc.CallOption) (*GetRoomStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRoomStatsResponse)
+	err := c.cc.Invoke(ctx, RealtimeService_GetRoomStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *realtimeServiceClient) DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DisconnectUserResponse)
+	err := c.cc.Invoke(ctx, RealtimeService_DisconnectUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *realtimeServiceClient) ApplyOperations(ctx context.Context, in *ApplyOperationsRequest, opts ...grpc.CallOption) (*ApplyOperationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApplyOperationsResponse)
+	err := c.cc.Invoke(ctx, RealtimeService_ApplyOperations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RealtimeServiceServer is the server API for RealtimeService service.
+// All implementations must embed UnimplementedRealtimeServiceServer
+// for forward compatibility.
+//
+// RealtimeService is the internal API of the ws-server. The api-gateway uses
+// it to push REST-originated changes into live rooms, admin tooling to
+// inspect and manage realtime state. It is not exposed to clients.
+type RealtimeServiceServer interface {
+	// Broadcast sends a message to every client of a workspace room, on all
+	// ws-server instances
+	Broadcast(context.Context, *BroadcastRequest) (*BroadcastResponse, error)
+	// GetRoomStats returns the rooms of the instance that serves the call
+	GetRoomStats(context.Context, *GetRoomStatsRequest) (*GetRoomStatsResponse, error)
+	// DisconnectUser closes the connections of a user, in one room or in all
+	// rooms of the instance
+	DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error)
+	// ApplyOperations stores CRDT operations and broadcasts them to the room
+	// of their workspace
+	ApplyOperations(context.Context, *ApplyOperationsRequest) (*ApplyOperationsResponse, error)
+	mustEmbedUnimplementedRealtimeServiceServer()
+}
+
+// UnimplementedRealtimeServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRealtimeServiceServer struct{}
+
+func (UnimplementedRealtimeServiceServer) Broadcast(context.Context, *BroadcastRequest) (*BroadcastResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Broadcast not implemented")
+}
+func (UnimplementedRealtimeServiceServer) GetRoomStats(context.Context, *GetRoomStatsRequest) (*GetRoomStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoomStats not implemented")
+}
+func (UnimplementedRealtimeServiceServer) DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectUser not implemented")
+}
+func (UnimplementedRealtimeServiceServer) ApplyOperations(context.Context, *ApplyOperationsRequest) (*ApplyOperationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyOperations not implemented")
+}
+func (UnimplementedRealtimeServiceServer) mustEmbedUnimplementedRealtimeServiceServer() {}
+func (UnimplementedRealtimeServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeRealtimeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RealtimeServiceServer will
+// result in compilation errors.
+type UnsafeRealtimeServiceServer interface {
+	mustEmbedUnimplementedRealtimeServiceServer()
+}
+
+func RegisterRealtimeServiceServer(s grpc.ServiceRegistrar, srv RealtimeServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRealtimeServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RealtimeService_ServiceDesc, srv)
+}
+
+func _RealtimeService_Broadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RealtimeServiceServer).Broadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RealtimeService_Broadcast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RealtimeServiceServer).Broadcast(ctx, req.(*BroadcastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RealtimeService_GetRoomStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoomStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RealtimeServiceServer).GetRoomStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RealtimeService_GetRoomStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RealtimeServiceServer).GetRoomStats(ctx, req.(*GetRoomStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RealtimeService_DisconnectUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RealtimeServiceServer).DisconnectUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RealtimeService_DisconnectUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RealtimeServiceServer).DisconnectUser(ctx, req.(*DisconnectUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RealtimeService_ApplyOperations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyOperationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RealtimeServiceServer).ApplyOperations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RealtimeService_ApplyOperations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RealtimeServiceServer).ApplyOperations(ctx, req.(*ApplyOperationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RealtimeService_ServiceDesc is the grpc.ServiceDesc for RealtimeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RealtimeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hertzboard.realtime.v1.RealtimeService",
+	HandlerType: (*RealtimeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Broadcast",
+			Handler:    _RealtimeService_Broadcast_Handler,
+		},
+		{
+			MethodName: "GetRoomStats",
+			Handler:    _RealtimeService_GetRoomStats_Handler,
+		},
+		{
+			MethodName: "DisconnectUser",
+			Handler:    _RealtimeService_DisconnectUser_Handler,
+		},
+		{
+			MethodName: "ApplyOperations",
+			Handler:    _RealtimeService_ApplyOperations_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "realtime/v1/realtime.proto",
+}
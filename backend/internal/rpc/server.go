@@ -0,0 +1,76 @@
+// Package rpc is the internal gRPC API between the api-gateway and the
+// ws-server. The service is defined in api/proto/realtime/v1, regenerate
+// realtimev1 with make backend-proto after changing it.
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/rpc/realtimev1"
+	"github.com/bifshteksex/hertz-board/internal/service"
+)
+
+// NewServer returns the gRPC server of the realtime service. Reflection is
+// registered so operators can inspect it with grpcurl. When a token is
+// configured every call must send it as a bearer token.
+func NewServer(cfg *config.GRPCConfig, hub *service.Hub, crdt *service.CRDTService) *grpc.Server {
+	var opts []grpc.ServerOption
+	if cfg.Token != "" {
+		opts = append(opts, grpc.UnaryInterceptor(requireToken(cfg.Token)))
+	}
+
+	srv := grpc.NewServer(opts...)
+	realtimev1.RegisterRealtimeServiceServer(srv, &realtimeServer{hub: hub, crdt: crdt})
+	reflection.Register(srv)
+	return srv
+}
+
+// Start listens on the configured interface and port and serves in the
+// background
+func Start(srv *grpc.Server, cfg *config.GRPCConfig) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			hlog.Errorf("gRPC server failed: %v", err)
+		}
+	}()
+	hlog.Infof("Internal gRPC API is served on %s", addr)
+	return nil
+}
+
+func requireToken(token string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var given string
+		if values := md.Get("authorization"); len(values) > 0 {
+			given = strings.TrimPrefix(values[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(ctx, req)
+	}
+}
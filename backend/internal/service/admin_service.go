@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	defaultAdminWorkspaceLimit = 50
+	maxAdminWorkspaceLimit     = 200
+	maxMaintenanceNoticeLength = 500
+)
+
+// Background jobs admins can run on demand
+const (
+	JobAssetPurge          = "asset_purge"
+	JobSnapshotRetention   = "snapshot_retention"
+	JobNotificationDigest  = "notification_digest"
+	JobOperationPartitions = "operation_partitions"
+	JobUsageMetering       = "usage_metering"
+	JobWorkspaceVisits     = "workspace_visits"
+	JobViewStates          = "view_states"
+)
+
+var (
+	// ErrAdminContentNotFound is returned when the workspace, element or
+	// asset to delete doesn't exist
+	ErrAdminContentNotFound = errors.New("content not found")
+	// ErrJobNotFound is returned for unknown background jobs
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobPending is returned when a job was already triggered and hasn't
+	// started yet
+	ErrJobPending = errors.New("job is already pending")
+)
+
+// Job is a background worker that can be run on demand
+type Job interface {
+	// Trigger starts a run and returns false if one is already pending
+	Trigger() bool
+}
+
+type adminJob struct {
+	job         Job
+	description string
+}
+
+// AdminService backs the operations API of instance admins: listing all
+// workspaces, removing abusive content, inspecting realtime rooms,
+// broadcasting maintenance notices and running background jobs
+type AdminService struct {
+	workspaceRepo *repository.WorkspaceRepository
+	canvasService *CanvasService
+	assetService  *AssetService
+	crdt          *CRDTService
+	rooms         RoomBroadcaster
+	hub           *Hub
+	registry      *RoomRegistry
+	audit         *AuditService
+	jobs          map[string]adminJob
+}
+
+// NewAdminService creates a new admin service. Jobs are added with
+// RegisterJob once their workers are started.
+func NewAdminService(
+	workspaceRepo *repository.WorkspaceRepository,
+	canvasService *CanvasService,
+	assetService *AssetService,
+	crdt *CRDTService,
+	rooms RoomBroadcaster,
+	hub *Hub,
+	registry *RoomRegistry,
+	audit *AuditService,
+) *AdminService {
+	return &AdminService{
+		workspaceRepo: workspaceRepo,
+		canvasService: canvasService,
+		assetService:  assetService,
+		crdt:          crdt,
+		rooms:         rooms,
+		hub:           hub,
+		registry:      registry,
+		audit:         audit,
+		jobs:          make(map[string]adminJob),
+	}
+}
+
+// RegisterJob makes a background job available to admins. It must be
+// called before the server starts.
+func (s *AdminService) RegisterJob(name, description string, job Job) {
+	s.jobs[name] = adminJob{job: job, description: description}
+}
+
+// ListWorkspaces lists the workspaces of all users, newest first unless
+// sorted by update time or storage usage
+func (s *AdminService) ListWorkspaces(
+	ctx context.Context,
+	filter models.AdminWorkspaceListFilter,
+) ([]models.AdminWorkspace, int, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAdminWorkspaceLimit
+	}
+	if filter.Limit > maxAdminWorkspaceLimit {
+		filter.Limit = maxAdminWorkspaceLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.workspaceRepo.ListAllWorkspaces(ctx, filter)
+}
+
+// DeleteWorkspace soft deletes a workspace of any user
+func (s *AdminService) DeleteWorkspace(ctx context.Context, adminID, workspaceID uuid.UUID) error {
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace == nil {
+		return ErrAdminContentNotFound
+	}
+
+	if err := s.workspaceRepo.SoftDeleteWorkspace(ctx, workspaceID); err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	hlog.CtxInfof(ctx, "Admin %s deleted workspace %s of user %s", adminID, workspaceID, workspace.OwnerID)
+	s.recordContentDeleted(ctx, workspaceID, "workspace", workspaceID)
+	return nil
+}
+
+// DeleteElement deletes an element with its children and removes it from
+// the boards of connected clients
+func (s *AdminService) DeleteElement(ctx context.Context, adminID, workspaceID, elementID uuid.UUID) error {
+	element, err := s.canvasService.GetElement(ctx, elementID)
+	if err != nil {
+		return err
+	}
+	if element.WorkspaceID != workspaceID {
+		return ErrAdminContentNotFound
+	}
+
+	if err := s.canvasService.DeleteElement(ctx, elementID); err != nil {
+		return err
+	}
+
+	s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+		Type:      models.MessageTypeOperation,
+		UserID:    adminID,
+		Timestamp: time.Now(),
+		Payload: models.OperationPayload{
+			ElementID:   elementID,
+			WorkspaceID: workspaceID,
+			UserID:      adminID,
+			Timestamp:   s.crdt.GenerateTimestamp(),
+			OpType:      models.OperationTypeDelete,
+		},
+	}, uuid.Nil)
+
+	hlog.CtxInfof(ctx, "Admin %s deleted element %s of workspace %s", adminID, elementID, workspaceID)
+	s.recordContentDeleted(ctx, workspaceID, "element", elementID)
+	return nil
+}
+
+// PurgeAsset permanently deletes an asset and its stored objects, without
+// keeping it for the recovery period
+func (s *AdminService) PurgeAsset(ctx context.Context, adminID, workspaceID, assetID uuid.UUID) error {
+	if err := s.assetService.PurgeAsset(ctx, workspaceID, assetID); err != nil {
+		return err
+	}
+
+	hlog.CtxInfof(ctx, "Admin %s purged asset %s of workspace %s", adminID, assetID, workspaceID)
+	s.recordContentDeleted(ctx, workspaceID, "asset", assetID)
+	return nil
+}
+
+func (s *AdminService) recordContentDeleted(ctx context.Context, workspaceID uuid.UUID, targetType string, targetID uuid.UUID) {
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditAdminContentDeleted,
+		WorkspaceID: &workspaceID,
+		TargetType:  targetType,
+		TargetID:    targetID.String(),
+	})
+}
+
+// ListRealtimeRooms returns the rooms and connected clients of every
+// realtime instance
+func (s *AdminService) ListRealtimeRooms(ctx context.Context) (*models.RealtimeRoomsResponse, error) {
+	return s.registry.List(ctx)
+}
+
+// BroadcastMaintenance sends a maintenance notice to every client connected
+// to any realtime instance
+func (s *AdminService) BroadcastMaintenance(ctx context.Context, adminID uuid.UUID, req *models.MaintenanceNoticeRequest) error {
+	message := strings.TrimSpace(req.Message)
+	if message == "" || utf8.RuneCountInString(message) > maxMaintenanceNoticeLength {
+		return fmt.Errorf("message is required and must be at most %d characters", maxMaintenanceNoticeLength)
+	}
+
+	s.hub.BroadcastToAll(&models.WSMessage{
+		Type:      models.MessageTypeMaintenance,
+		UserID:    adminID,
+		Timestamp: time.Now(),
+		Payload: models.MaintenancePayload{
+			Message:  message,
+			StartsAt: req.StartsAt,
+		},
+	})
+
+	hlog.CtxInfof(ctx, "Admin %s broadcast maintenance notice: %s", adminID, message)
+	s.audit.Record(ctx, &AuditEntry{
+		Action:   models.AuditAdminMaintenance,
+		Metadata: map[string]interface{}{"message": message},
+	})
+	return nil
+}
+
+// ListJobs returns the background jobs admins can run, sorted by name
+func (s *AdminService) ListJobs() []models.AdminJob {
+	jobs := make([]models.AdminJob, 0, len(s.jobs))
+	for name, job := range s.jobs {
+		jobs = append(jobs, models.AdminJob{Name: name, Description: job.description})
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// RunJob starts a background job. The job runs on its worker, so this
+// returns before it is done.
+func (s *AdminService) RunJob(ctx context.Context, adminID uuid.UUID, name string) error {
+	job, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if !job.job.Trigger() {
+		return ErrJobPending
+	}
+
+	hlog.CtxInfof(ctx, "Admin %s triggered job %s", adminID, name)
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditAdminJobRun,
+		TargetType: "job",
+		TargetID:   name,
+	})
+	return nil
+}
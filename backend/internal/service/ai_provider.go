@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// AI providers
+const (
+	AIProviderOpenAI    = "openai"
+	AIProviderAnthropic = "anthropic"
+)
+
+const (
+	aiProviderTimeout = 60 * time.Second
+	aiMaxErrorBody    = 512
+	aiMaxReplyTokens  = 2048
+)
+
+// ErrAIProvider is returned when the language model fails or replies with
+// something unusable
+var ErrAIProvider = errors.New("AI provider failed")
+
+// AIPrompt is a request to a language model
+type AIPrompt struct {
+	// System describes the task and the format of the reply
+	System string
+	// User is the content to work on
+	User string
+}
+
+// AIProvider completes prompts with a language model
+type AIProvider interface {
+	Complete(ctx context.Context, prompt *AIPrompt) (string, error)
+}
+
+// NewAIProvider creates the provider selected by ai.provider config
+func NewAIProvider(cfg *config.AIConfig) (AIProvider, error) {
+	switch cfg.Provider {
+	case AIProviderOpenAI:
+		return NewOpenAIProvider(cfg), nil
+	case AIProviderAnthropic:
+		return NewAnthropicProvider(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider: %s", cfg.Provider)
+	}
+}
+
+// callAIAPI posts a JSON request to a provider API and decodes its response
+func callAIAPI(ctx context.Context, client *http.Client, url string, headers map[string]string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal AI request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAIProvider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, aiMaxErrorBody))
+		return fmt.Errorf("%w: responded with status %d: %s", ErrAIProvider, resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: failed to decode response: %w", ErrAIProvider, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+const (
+	anthropicAPIURL       = "https://api.anthropic.com/v1/messages"
+	anthropicAPIVersion   = "2023-06-01"
+	anthropicDefaultModel = "claude-3-5-haiku-latest"
+)
+
+// AnthropicProvider completes prompts through the Anthropic messages API
+type AnthropicProvider struct {
+	httpClient *http.Client
+	url        string
+	apiKey     string
+	model      string
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	System    string             `json:"system"`
+	Messages  []anthropicMessage `json:"messages"`
+	MaxTokens int                `json:"max_tokens"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// NewAnthropicProvider creates an Anthropic provider
+func NewAnthropicProvider(cfg *config.AIConfig) *AnthropicProvider {
+	url := anthropicAPIURL
+	if cfg.BaseURL != "" {
+		url = strings.TrimSuffix(cfg.BaseURL, "/") + "/messages"
+	}
+	model := cfg.Model
+	if model == "" {
+		model = anthropicDefaultModel
+	}
+
+	return &AnthropicProvider{
+		httpClient: &http.Client{Timeout: aiProviderTimeout},
+		url:        url,
+		apiKey:     cfg.APIKey,
+		model:      model,
+	}
+}
+
+// Complete completes a prompt via Anthropic
+func (p *AnthropicProvider) Complete(ctx context.Context, prompt *AIPrompt) (string, error) {
+	var resp anthropicResponse
+	err := callAIAPI(ctx, p.httpClient, p.url, map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicAPIVersion,
+	}, &anthropicRequest{
+		Model:     p.model,
+		System:    prompt.System,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt.User}},
+		MaxTokens: aiMaxReplyTokens,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("%w: empty reply", ErrAIProvider)
+	}
+	return text.String(), nil
+}
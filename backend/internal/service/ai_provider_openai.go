@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+const (
+	openAIDefaultBaseURL = "https://api.openai.com/v1"
+	openAIDefaultModel   = "gpt-4o-mini"
+)
+
+// OpenAIProvider completes prompts through the OpenAI chat completions API,
+// or a compatible endpoint
+type OpenAIProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model     string          `json:"model"`
+	Messages  []openAIMessage `json:"messages"`
+	MaxTokens int             `json:"max_tokens"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// NewOpenAIProvider creates an OpenAI provider
+func NewOpenAIProvider(cfg *config.AIConfig) *OpenAIProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = openAIDefaultBaseURL
+	}
+	model := cfg.Model
+	if model == "" {
+		model = openAIDefaultModel
+	}
+
+	return &OpenAIProvider{
+		httpClient: &http.Client{Timeout: aiProviderTimeout},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      model,
+	}
+}
+
+// Complete completes a prompt via OpenAI
+func (p *OpenAIProvider) Complete(ctx context.Context, prompt *AIPrompt) (string, error) {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var resp openAIResponse
+	err := callAIAPI(ctx, p.httpClient, p.baseURL+"/chat/completions", headers, &openAIRequest{
+		Model: p.model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: prompt.System},
+			{Role: "user", Content: prompt.User},
+		},
+		MaxTokens: aiMaxReplyTokens,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("%w: empty reply", ErrAIProvider)
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	// AISource marks elements created by AI assistance in realtime messages
+	AISource = "ai"
+
+	// maxAIElementText is how much of the text of each element is sent to
+	// the model
+	maxAIElementText  = 500
+	maxAIPromptLength = 1000
+	maxAIClusters     = 8
+	defaultAIIdeas    = 5
+	maxAIIdeas        = 20
+	maxAIIdeaLength   = 500
+
+	// Layout of the created elements, in canvas units
+	aiColumnGap      = 80
+	aiElementGap     = 20
+	aiTextWidth      = 400
+	aiTextLineHeight = 24
+	aiTextLineChars  = 50
+	aiNoteSize       = 200
+	aiNoteFont       = 16
+	aiNoteColor      = "#ddd6fe"
+)
+
+// ErrAINoContent is returned when the board or the selection has no text to
+// work with
+var ErrAINoContent = errors.New("the board or selection has no text to work with")
+
+// AIService answers the AI assistance requests of a board with a language
+// model. Results can be added to the board as new elements, created like
+// any other element and marked with models.AIGeneratedKey.
+type AIService struct {
+	provider      AIProvider
+	canvasService *CanvasService
+	rooms         RoomBroadcaster
+	maxElements   int
+}
+
+// NewAIService creates an AI service with the provider selected by config
+func NewAIService(cfg *config.AIConfig, canvasService *CanvasService, rooms RoomBroadcaster) (*AIService, error) {
+	provider, err := NewAIProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AIService{
+		provider:      provider,
+		canvasService: canvasService,
+		rooms:         rooms,
+		maxElements:   cfg.MaxElements,
+	}, nil
+}
+
+// Summarize summarizes the text of a board or a selection
+func (s *AIService) Summarize(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.AISummarizeRequest,
+) (*models.AISummarizeResponse, error) {
+	board, selected, err := s.loadElements(ctx, workspaceID, req.ElementIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.provider.Complete(ctx, &AIPrompt{
+		System: "You summarize the content of a collaborative whiteboard. Each line is an element of the board. " +
+			"Reply with the summary only, in plain text of at most a few short paragraphs, " +
+			"in the language of the elements.",
+		User: formatAIElements(selected),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := strings.TrimSpace(reply)
+	if summary == "" {
+		return nil, fmt.Errorf("%w: empty summary", ErrAIProvider)
+	}
+
+	resp := &models.AISummarizeResponse{Summary: summary}
+	if req.Create {
+		x, y, zIndex := aiPlacement(board, selected)
+		data, err := toElementData(models.TextElementData{
+			Content:   aiTextHTML("Summary", strings.Split(summary, "\n"), false),
+			PlainText: summary,
+			BaseElementData: models.BaseElementData{
+				Position: models.Position{X: x, Y: y},
+				Size:     models.Size{Width: aiTextWidth, Height: aiTextHeight(summary)},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Elements, err = s.createElements(ctx, workspaceID, userID, []models.CreateElementRequest{{
+			ElementType: models.ElementTypeText,
+			ElementData: data,
+			ZIndex:      zIndex,
+		}})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Cluster groups the sticky notes of a board or a selection by theme
+func (s *AIService) Cluster(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.AIClusterRequest,
+) (*models.AIClusterResponse, error) {
+	sticky := models.ElementTypeSticky
+	board, notes, err := s.loadElements(ctx, workspaceID, req.ElementIDs, &sticky)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.provider.Complete(ctx, &AIPrompt{
+		System: "You group the numbered sticky notes of a collaborative whiteboard by theme. " +
+			"Every note belongs to exactly one group, use at most " + strconv.Itoa(maxAIClusters) + " groups " +
+			"and label each with a few words in the language of the notes. Reply with JSON only, in the form " +
+			`{"clusters":[{"label":"Pricing","notes":[1,4]}]}`,
+		User: formatAIElements(notes),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Clusters []struct {
+			Label string `json:"label"`
+			Notes []int  `json:"notes"`
+		} `json:"clusters"`
+	}
+	if err := decodeAIJSON(reply, &parsed); err != nil {
+		return nil, err
+	}
+
+	// Notes the model left out, made up or repeated are dropped
+	assigned := make(map[int]bool, len(notes))
+	clusters := []models.AICluster{}
+	for _, c := range parsed.Clusters {
+		cluster := models.AICluster{Label: strings.TrimSpace(c.Label), ElementIDs: []uuid.UUID{}}
+		for _, n := range c.Notes {
+			if n < 1 || n > len(notes) || assigned[n] {
+				continue
+			}
+			assigned[n] = true
+			cluster.ElementIDs = append(cluster.ElementIDs, notes[n-1].ID)
+		}
+		if cluster.Label != "" && len(cluster.ElementIDs) > 0 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("%w: no clusters in reply", ErrAIProvider)
+	}
+
+	resp := &models.AIClusterResponse{Clusters: clusters}
+	if req.Create {
+		texts := make(map[uuid.UUID]string, len(notes))
+		for i := range notes {
+			texts[notes[i].ID] = elementPlainText(&notes[i])
+		}
+
+		x, y, zIndex := aiPlacement(board, notes)
+		requests := make([]models.CreateElementRequest, 0, len(clusters))
+		for _, cluster := range clusters {
+			items := make([]string, len(cluster.ElementIDs))
+			for i, id := range cluster.ElementIDs {
+				items[i] = texts[id]
+			}
+			plain := cluster.Label + "\n" + strings.Join(items, "\n")
+			height := aiTextHeight(plain)
+
+			data, err := toElementData(models.TextElementData{
+				Content:   aiTextHTML(cluster.Label, items, true),
+				PlainText: plain,
+				BaseElementData: models.BaseElementData{
+					Position: models.Position{X: x, Y: y},
+					Size:     models.Size{Width: aiTextWidth, Height: height},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			requests = append(requests, models.CreateElementRequest{
+				ElementType: models.ElementTypeText,
+				ElementData: data,
+				ZIndex:      zIndex,
+			})
+			y += height + aiElementGap
+			zIndex++
+		}
+
+		resp.Elements, err = s.createElements(ctx, workspaceID, userID, requests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// GenerateIdeas generates ideas about a topic, with the board or a
+// selection as context
+func (s *AIService) GenerateIdeas(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.AIIdeasRequest,
+) (*models.AIIdeasResponse, error) {
+	topic := strings.TrimSpace(req.Prompt)
+	if utf8.RuneCountInString(topic) > maxAIPromptLength {
+		return nil, fmt.Errorf("prompt must be at most %d characters", maxAIPromptLength)
+	}
+	count := req.Count
+	if count == 0 {
+		count = defaultAIIdeas
+	}
+	if count < 1 || count > maxAIIdeas {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxAIIdeas)
+	}
+
+	board, selected, err := s.loadElements(ctx, workspaceID, req.ElementIDs, nil)
+	// A topic is enough on an empty board
+	if errors.Is(err, ErrAINoContent) && topic != "" {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var user strings.Builder
+	if topic != "" {
+		user.WriteString("Topic: " + topic + "\n\n")
+	}
+	if len(selected) > 0 {
+		user.WriteString("Board:\n" + formatAIElements(selected))
+	}
+
+	reply, err := s.provider.Complete(ctx, &AIPrompt{
+		System: "You brainstorm for a collaborative whiteboard. Suggest " + strconv.Itoa(count) + " new ideas " +
+			"about the topic, or the content of the board when there is no topic, that aren't on the board yet. " +
+			"Each idea fits on a sticky note, in the language of the topic or board. " +
+			`Reply with JSON only, in the form {"ideas":["First idea","Second idea"]}`,
+		User: user.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Ideas []string `json:"ideas"`
+	}
+	if err := decodeAIJSON(reply, &parsed); err != nil {
+		return nil, err
+	}
+
+	ideas := []string{}
+	for _, idea := range parsed.Ideas {
+		idea = truncateRunes(strings.TrimSpace(idea), maxAIIdeaLength)
+		if idea != "" && len(ideas) < count {
+			ideas = append(ideas, idea)
+		}
+	}
+	if len(ideas) == 0 {
+		return nil, fmt.Errorf("%w: no ideas in reply", ErrAIProvider)
+	}
+
+	resp := &models.AIIdeasResponse{Ideas: ideas}
+	if req.Create {
+		x, y, zIndex := aiPlacement(board, selected)
+		requests := make([]models.CreateElementRequest, len(ideas))
+		for i, idea := range ideas {
+			data, err := toElementData(models.StickyNoteData{
+				Content: idea,
+				Color:   aiNoteColor,
+				BaseElementData: models.BaseElementData{
+					Position: models.Position{X: x, Y: y + float64(i)*(aiNoteSize+aiElementGap)},
+					Size:     models.Size{Width: aiNoteSize, Height: aiNoteSize},
+					Style:    models.Style{FontSize: aiNoteFont},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			requests[i] = models.CreateElementRequest{
+				ElementType: models.ElementTypeSticky,
+				ElementData: data,
+				ZIndex:      zIndex + i,
+			}
+		}
+
+		resp.Elements, err = s.createElements(ctx, workspaceID, userID, requests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// loadElements returns the elements of a board and those of them with text
+// that are selected, every one when ids is empty, optionally of one type.
+// At most maxElements are selected. The board is also returned with
+// ErrAINoContent.
+func (s *AIService) loadElements(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	ids []uuid.UUID,
+	elementType *models.ElementType,
+) (board, selected []models.CanvasElement, err error) {
+	board, err = s.canvasService.GetWorkspaceElements(ctx, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	for i := range board {
+		if len(wanted) > 0 && !wanted[board[i].ID] {
+			continue
+		}
+		if elementType != nil && board[i].ElementType != *elementType {
+			continue
+		}
+		if strings.TrimSpace(elementPlainText(&board[i])) == "" {
+			continue
+		}
+		selected = append(selected, board[i])
+		if len(selected) == s.maxElements {
+			break
+		}
+	}
+
+	if len(selected) == 0 {
+		return board, nil, ErrAINoContent
+	}
+	return board, selected, nil
+}
+
+// createElements adds AI results to the board and shows them in the room
+func (s *AIService) createElements(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	requests []models.CreateElementRequest,
+) ([]models.ElementResponse, error) {
+	for i := range requests {
+		requests[i].ElementData[models.AIGeneratedKey] = true
+	}
+
+	elements, err := s.canvasService.BatchCreateElements(
+		ctx, workspaceID, userID, models.BatchCreateRequest{Elements: requests},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.ElementResponse, len(elements))
+	for i := range elements {
+		responses[i] = elements[i].ToResponse()
+	}
+
+	if s.rooms != nil {
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypeElementsAdded,
+			UserID:    userID,
+			Timestamp: time.Now(),
+			Payload: models.ElementsAddedPayload{
+				Elements: responses,
+				Source:   AISource,
+			},
+		}, uuid.Nil)
+	}
+
+	return responses, nil
+}
+
+// formatAIElements numbers the elements one per line, with their type and
+// text
+func formatAIElements(elements []models.CanvasElement) string {
+	var b strings.Builder
+	for i := range elements {
+		text := strings.Join(strings.Fields(elementPlainText(&elements[i])), " ")
+		fmt.Fprintf(&b, "%d. (%s) %s\n", i+1, elements[i].ElementType, truncateRunes(text, maxAIElementText))
+	}
+	return b.String()
+}
+
+// decodeAIJSON decodes the JSON object of a reply, ignoring the text or
+// code fences around it some models add
+func decodeAIJSON(reply string, v any) error {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("%w: reply is not JSON", ErrAIProvider)
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), v); err != nil {
+		return fmt.Errorf("%w: failed to decode reply: %w", ErrAIProvider, err)
+	}
+	return nil
+}
+
+// aiPlacement returns where results go: right of everything on the board,
+// level with the top of the elements they came from, above every element
+func aiPlacement(board, source []models.CanvasElement) (x, y float64, zIndex int) {
+	for i := range board {
+		position, size := board[i].ElementData.Bounds()
+		x = max(x, position.X+size.Width+aiColumnGap)
+		zIndex = max(zIndex, board[i].ZIndex+1)
+	}
+	for i := range source {
+		position, _ := source[i].ElementData.Bounds()
+		if i == 0 || position.Y < y {
+			y = position.Y
+		}
+	}
+	return x, y, zIndex
+}
+
+// aiTextHTML returns the content of a text element with a heading and
+// paragraphs, or a bullet list
+func aiTextHTML(heading string, lines []string, list bool) string {
+	var b strings.Builder
+	b.WriteString("<h3>" + html.EscapeString(heading) + "</h3>")
+	if list {
+		b.WriteString("<ul>")
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if list {
+			b.WriteString("<li>" + html.EscapeString(line) + "</li>")
+		} else {
+			b.WriteString("<p>" + html.EscapeString(line) + "</p>")
+		}
+	}
+	if list {
+		b.WriteString("</ul>")
+	}
+	return b.String()
+}
+
+// aiTextHeight estimates the height of a text element of aiTextWidth
+func aiTextHeight(text string) float64 {
+	lines := 1 // the heading
+	for _, line := range strings.Split(text, "\n") {
+		lines += utf8.RuneCountInString(line)/aiTextLineChars + 1
+	}
+	return float64(lines * aiTextLineHeight)
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
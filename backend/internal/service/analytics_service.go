@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// AnalyticsSubject is the NATS subject of analytics events
+const AnalyticsSubject = "analytics.events"
+
+// cursorSampleRate is the share of cursor moves recorded for heatmaps.
+// Clients send moves many times a second, a sample shows where attention
+// went just as well.
+const cursorSampleRate = 0.05
+
+// AnalyticsService publishes usage events to NATS, from where the analytics
+// worker stores them in ClickHouse. Tracking is fire and forget, it never
+// fails or slows down the caller. Element creations are the exception, they
+// are written to the transactional outbox with the elements. A nil service
+// tracks nothing.
+type AnalyticsService struct {
+	nats *nats.Conn
+}
+
+// NewAnalyticsService creates a new analytics service
+func NewAnalyticsService(nc *nats.Conn) *AnalyticsService {
+	return &AnalyticsService{nats: nc}
+}
+
+// TrackOperation records a change of an element by a user, made in realtime
+// or over the REST API
+func (s *AnalyticsService) TrackOperation(workspaceID, elementID, userID uuid.UUID, opType models.OperationType) {
+	if s == nil {
+		return
+	}
+	s.publish(models.AnalyticsTableOperations, &models.OperationAnalytics{
+		Time:        time.Now(),
+		OpType:      string(opType),
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		ElementID:   elementID,
+	})
+}
+
+// TrackSession records a realtime connection that was open since startedAt
+func (s *AnalyticsService) TrackSession(client *models.Client, transport string, startedAt time.Time) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.publish(models.AnalyticsTableSessions, &models.SessionAnalytics{
+		StartedAt:  startedAt,
+		EndedAt:    now,
+		Transport:  transport,
+		DurationMs: now.Sub(startedAt).Milliseconds(),
+		UserID:     client.UserID,
+		Anonymous:  client.Anonymous,
+	})
+}
+
+// TrackPresence records the time a client spent in the room it just left
+func (s *AnalyticsService) TrackPresence(client *models.Client) {
+	if s == nil || client.JoinedAt.IsZero() {
+		return
+	}
+	now := time.Now()
+	s.publish(models.AnalyticsTablePresence, &models.PresenceAnalytics{
+		JoinedAt:    client.JoinedAt,
+		LeftAt:      now,
+		DurationMs:  now.Sub(client.JoinedAt).Milliseconds(),
+		WorkspaceID: client.WorkspaceID,
+		UserID:      client.UserID,
+		Anonymous:   client.Anonymous,
+	})
+}
+
+// TrackAPIRequest records a finished API request. userID is nil for
+// unauthenticated requests.
+func (s *AnalyticsService) TrackAPIRequest(method, route string, status int, duration time.Duration, userID *uuid.UUID) {
+	if s == nil {
+		return
+	}
+	s.publish(models.AnalyticsTableAPIRequests, &models.APIRequestAnalytics{
+		Time:       time.Now(),
+		UserID:     userID,
+		Method:     method,
+		Route:      route,
+		DurationMs: float64(duration) / float64(time.Millisecond),
+		Status:     status,
+	})
+}
+
+// TrackView records a counted view of a workspace
+func (s *AnalyticsService) TrackView(view *models.ViewAnalytics) {
+	if s == nil {
+		return
+	}
+	view.Time = time.Now()
+	s.publish(models.AnalyticsTableViews, view)
+}
+
+// TrackCursor records the cursor of a client in its room, for a sample of
+// the moves. Selections are all recorded, at the cursor they were made with.
+func (s *AnalyticsService) TrackCursor(client *models.Client, kind string, position models.CursorPosition) {
+	if s == nil {
+		return
+	}
+	if kind == models.CursorSampleMove && rand.Float64() >= cursorSampleRate {
+		return
+	}
+	s.publish(models.AnalyticsTableCursors, &models.CursorAnalytics{
+		Time:        time.Now(),
+		Kind:        kind,
+		X:           position.X,
+		Y:           position.Y,
+		WorkspaceID: client.WorkspaceID,
+		UserID:      client.UserID,
+		Anonymous:   client.Anonymous,
+	})
+}
+
+// operation returns the outbox message of an element change, for changes
+// written in a transaction so they are tracked exactly when they commit. A
+// nil service returns nil.
+func (s *AnalyticsService) operation(
+	ctx context.Context,
+	workspaceID, elementID, userID uuid.UUID,
+	opType models.OperationType,
+) *models.OutboxMessage {
+	if s == nil {
+		return nil
+	}
+	event, err := encodeAnalyticsEvent(models.AnalyticsTableOperations, &models.OperationAnalytics{
+		Time:        time.Now(),
+		OpType:      string(opType),
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		ElementID:   elementID,
+	})
+	if err != nil {
+		hlog.CtxErrorf(ctx, "%v", err)
+		return nil
+	}
+	return newOutboxMessage(ctx, AnalyticsSubject, event, false)
+}
+
+func (s *AnalyticsService) publish(table string, row interface{}) {
+	event, err := encodeAnalyticsEvent(table, row)
+	if err != nil {
+		hlog.Errorf("%v", err)
+		return
+	}
+
+	// Core NATS buffers while reconnecting and drops when it can't deliver,
+	// analytics are best effort
+	if err := s.nats.Publish(AnalyticsSubject, event); err != nil {
+		hlog.Warnf("Failed to publish %s analytics event: %v", table, err)
+	}
+}
+
+func encodeAnalyticsEvent(table string, row interface{}) ([]byte, error) {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s analytics event: %w", table, err)
+	}
+
+	event, err := json.Marshal(&models.AnalyticsEvent{Table: table, Row: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s analytics event: %w", table, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	analyticsFlushTimeout = 30 * time.Second
+	// analyticsQueueSize is the number of events waiting for the batcher
+	analyticsQueueSize = 4096
+)
+
+// analyticsTables are the tables events may be written to
+var analyticsTables = map[string]bool{
+	models.AnalyticsTableOperations:  true,
+	models.AnalyticsTableSessions:    true,
+	models.AnalyticsTablePresence:    true,
+	models.AnalyticsTableAPIRequests: true,
+	models.AnalyticsTableViews:       true,
+	models.AnalyticsTableCursors:     true,
+}
+
+// AnalyticsWorker batches analytics events from NATS into ClickHouse. While
+// ClickHouse is unavailable events are kept up to maxBuffered and inserted
+// once it is back, the rest is dropped. The application never waits on it.
+type AnalyticsWorker struct {
+	repo    *repository.AnalyticsRepository
+	sub     *nats.Subscription
+	events  chan *models.AnalyticsEvent
+	done    chan struct{}
+	stopped chan struct{}
+
+	// Owned by the run goroutine
+	buffers     map[string][]json.RawMessage
+	buffered    int
+	dropped     int
+	schemaReady bool
+	available   bool
+
+	interval    time.Duration
+	batchSize   int
+	maxBuffered int
+}
+
+// NewAnalyticsWorker creates and starts a new analytics worker. Events are
+// inserted once batchSize of them are buffered or every interval.
+func NewAnalyticsWorker(
+	nc *nats.Conn,
+	repo *repository.AnalyticsRepository,
+	batchSize, maxBuffered int,
+	interval time.Duration,
+) (*AnalyticsWorker, error) {
+	if interval <= 0 || batchSize <= 0 {
+		return nil, fmt.Errorf("interval and batch size must be positive")
+	}
+
+	worker := &AnalyticsWorker{
+		repo:        repo,
+		events:      make(chan *models.AnalyticsEvent, analyticsQueueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+		buffers:     make(map[string][]json.RawMessage),
+		available:   true,
+		interval:    interval,
+		batchSize:   batchSize,
+		maxBuffered: max(maxBuffered, batchSize),
+	}
+
+	// Every event is stored once, by one of the instances
+	sub, err := nc.QueueSubscribe(AnalyticsSubject, "analytics-writers", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to analytics events: %w", err)
+	}
+	worker.sub = sub
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the subscription and inserts the buffered events
+func (w *AnalyticsWorker) Close() error {
+	err := w.sub.Unsubscribe()
+	close(w.done)
+	<-w.stopped
+	return err
+}
+
+// handleMessage hands an event to the batcher, dropping it when the batcher
+// is behind so NATS never blocks on ClickHouse
+func (w *AnalyticsWorker) handleMessage(msg *nats.Msg) {
+	var event models.AnalyticsEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		hlog.Errorf("Failed to unmarshal analytics event: %v", err)
+		return
+	}
+	if !analyticsTables[event.Table] {
+		hlog.Warnf("Ignoring analytics event for unknown table %q", event.Table)
+		return
+	}
+
+	select {
+	case w.events <- &event:
+	default:
+	}
+}
+
+// run buffers events and flushes them on a full batch, on every tick and on close
+func (w *AnalyticsWorker) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-w.events:
+			w.add(event)
+			if w.buffered >= w.batchSize && w.available {
+				w.flush()
+			}
+		case <-ticker.C:
+			w.flush()
+		case <-w.done:
+			w.flush()
+			return
+		}
+	}
+}
+
+func (w *AnalyticsWorker) add(event *models.AnalyticsEvent) {
+	if w.buffered >= w.maxBuffered {
+		if w.dropped == 0 {
+			hlog.Warnf("Analytics buffer is full, dropping events until ClickHouse catches up")
+		}
+		w.dropped++
+		return
+	}
+	w.buffers[event.Table] = append(w.buffers[event.Table], event.Row)
+	w.buffered++
+}
+
+// flush inserts the buffered events table by table. On failure the remaining
+// tables are kept for the next flush.
+func (w *AnalyticsWorker) flush() {
+	if w.buffered == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), analyticsFlushTimeout)
+	defer cancel()
+
+	if !w.schemaReady {
+		if err := w.repo.EnsureSchema(ctx); err != nil {
+			w.markUnavailable(err)
+			return
+		}
+		w.schemaReady = true
+	}
+
+	for table, rows := range w.buffers {
+		if err := w.repo.InsertRows(ctx, table, rows); err != nil {
+			w.markUnavailable(err)
+			return
+		}
+		delete(w.buffers, table)
+		w.buffered -= len(rows)
+	}
+
+	if !w.available {
+		hlog.Infof("ClickHouse is available again, inserted the buffered analytics events")
+		w.available = true
+	}
+	if w.dropped > 0 {
+		hlog.Warnf("Dropped %d analytics events while ClickHouse was behind", w.dropped)
+		w.dropped = 0
+	}
+}
+
+// markUnavailable logs the first failure of an outage, later ones are
+// expected until ClickHouse is back
+func (w *AnalyticsWorker) markUnavailable(err error) {
+	if w.available {
+		hlog.Warnf("ClickHouse is unavailable, buffering analytics events: %v", err)
+		w.available = false
+	}
+}
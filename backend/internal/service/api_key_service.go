@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	apiKeyPrefix           = "hbk_"
+	apiKeyBytes            = 32
+	apiKeyDisplayLength    = 12
+	maxAPIKeysPerWorkspace = 20
+	maxAPIKeyNameLength    = 100
+)
+
+var (
+	// ErrAPIKeyNotFound is returned for unknown API keys
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyLimitReached is returned when a workspace has too many API keys
+	ErrAPIKeyLimitReached = errors.New("API key limit reached")
+	// ErrInvalidAPIKey is returned for unknown keys and keys whose user left
+	// the workspace
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// APIKeyService manages workspace API keys and authenticates requests made
+// with them. A key acts as the user that created it, and stops working when
+// that user leaves the workspace.
+type APIKeyService struct {
+	apiKeyRepo    *repository.APIKeyRepository
+	workspaceRepo *repository.WorkspaceRepository
+	audit         *AuditService
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(
+	apiKeyRepo *repository.APIKeyRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	audit *AuditService,
+) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:    apiKeyRepo,
+		workspaceRepo: workspaceRepo,
+		audit:         audit,
+	}
+}
+
+// CreateAPIKey creates an API key acting as the user and returns it with the key
+func (s *APIKeyService) CreateAPIKey(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.CreateAPIKeyRequest,
+) (*models.APIKeyWithSecret, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return nil, fmt.Errorf("name is required and must be at most %d characters", maxAPIKeyNameLength)
+	}
+
+	existing, err := s.apiKeyRepo.ListAPIKeys(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAPIKeysPerWorkspace {
+		return nil, ErrAPIKeyLimitReached
+	}
+
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(b)
+
+	key := &models.APIKey{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Name:        name,
+		Prefix:      secret[:apiKeyDisplayLength],
+		KeyHash:     hashAPIKey(secret),
+	}
+
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditAPIKeyCreated,
+		WorkspaceID: &workspaceID,
+		TargetType:  "api_key",
+		TargetID:    key.ID.String(),
+		Metadata:    map[string]interface{}{"name": key.Name, "prefix": key.Prefix},
+	})
+
+	return &models.APIKeyWithSecret{APIKey: *key, Key: secret}, nil
+}
+
+// ListAPIKeys returns the API keys of a workspace
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, workspaceID uuid.UUID) ([]models.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys(ctx, workspaceID)
+}
+
+// DeleteAPIKey revokes an API key
+func (s *APIKeyService) DeleteAPIKey(ctx context.Context, workspaceID, keyID uuid.UUID) error {
+	deleted, err := s.apiKeyRepo.DeleteAPIKey(ctx, workspaceID, keyID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPIKeyNotFound
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditAPIKeyDeleted,
+		WorkspaceID: &workspaceID,
+		TargetType:  "api_key",
+		TargetID:    keyID.String(),
+	})
+	return nil
+}
+
+// Authenticate returns the API key of a request. Keys whose user is no
+// longer a member of the workspace are refused.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, key.WorkspaceID, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.apiKeyRepo.MarkAPIKeyUsed(ctx, key.ID); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to record use of API key %s: %v", key.ID, err)
+	}
+
+	return key, nil
+}
+
+// hashAPIKey returns the hex SHA-256 of a key, keys are random so no salt
+// is needed
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
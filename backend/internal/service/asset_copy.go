@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// copyAssetPageSize is how many assets CopyWorkspaceAssets loads at a time
+const copyAssetPageSize = 100
+
+// CopyAsset duplicates an asset into the target workspace. Every stored object
+// (original, thumbnail, variants and rendered pages) is copied server-side, so
+// the copy never shares URLs with the source and survives its deletion.
+func (s *AssetService) CopyAsset(
+	ctx context.Context,
+	src *models.Asset,
+	targetWorkspaceID, userID uuid.UUID,
+) (*models.Asset, error) {
+	if src.ScanStatus == models.ScanStatusInfected {
+		return nil, ErrAssetQuarantined
+	}
+	if src.Status == models.AssetStatusProcessing {
+		return nil, fmt.Errorf("asset is still processing")
+	}
+
+	pages := src.Pages
+	if src.PageCount != nil && pages == nil {
+		var err error
+		pages, err = s.assetRepo.GetAssetPages(ctx, src.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset pages: %w", err)
+		}
+	}
+
+	var copied []string
+	cleanup := func() {
+		for _, key := range copied {
+			_ = s.storage.Remove(ctx, key)
+		}
+	}
+
+	asset, err := s.copyAssetObjects(ctx, src, targetWorkspaceID, userID, &copied)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	quota, err := s.StorageQuota(ctx, targetWorkspaceID)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if err := s.assetRepo.CreateAssetWithinQuota(ctx, asset, quota); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to create asset record: %w", err)
+	}
+	s.finishAssetCopy(ctx, asset)
+
+	// Pages are stored as child assets, a failed page leaves the document usable
+	for i := range pages {
+		var pageObjects []string
+		page, err := s.copyAssetObjects(ctx, &pages[i], targetWorkspaceID, userID, &pageObjects)
+		if err == nil {
+			page.ParentAssetID = &asset.ID
+			err = s.assetRepo.CreateAsset(ctx, page)
+		}
+		if err != nil {
+			for _, key := range pageObjects {
+				_ = s.storage.Remove(ctx, key)
+			}
+			hlog.CtxErrorf(ctx, "Failed to copy page %s of asset %s: %v", pages[i].ID, src.ID, err)
+			continue
+		}
+		s.finishAssetCopy(ctx, page)
+		asset.Pages = append(asset.Pages, *page)
+	}
+
+	return asset, nil
+}
+
+// CopyWorkspaceAssets copies every asset of a workspace into another one and
+// returns a map from source to copied asset IDs, so callers can rewrite
+// element references. Assets that can't be copied are skipped and logged.
+func (s *AssetService) CopyWorkspaceAssets(
+	ctx context.Context,
+	sourceWorkspaceID, targetWorkspaceID, userID uuid.UUID,
+) (map[uuid.UUID]uuid.UUID, error) {
+	mapping := make(map[uuid.UUID]uuid.UUID)
+
+	filter := models.AssetListFilter{SortBy: "created_at", SortOrder: "asc", Limit: copyAssetPageSize}
+	for {
+		assets, total, err := s.assetRepo.ListAssets(ctx, sourceWorkspaceID, filter)
+		if err != nil {
+			return mapping, fmt.Errorf("failed to list assets: %w", err)
+		}
+
+		for i := range assets {
+			copied, err := s.CopyAsset(ctx, &assets[i], targetWorkspaceID, userID)
+			if err != nil {
+				hlog.CtxErrorf(ctx, "Failed to copy asset %s to workspace %s: %v", assets[i].ID, targetWorkspaceID, err)
+				continue
+			}
+			mapping[assets[i].ID] = copied.ID
+		}
+
+		filter.Offset += len(assets)
+		if len(assets) == 0 || filter.Offset >= total {
+			return mapping, nil
+		}
+	}
+}
+
+// copyAssetObjects copies the stored objects of a single asset and returns
+// the new, not yet persisted, asset. Keys of copied objects are appended to
+// copied so the caller can clean them up.
+func (s *AssetService) copyAssetObjects(
+	ctx context.Context,
+	src *models.Asset,
+	targetWorkspaceID, userID uuid.UUID,
+	copied *[]string,
+) (*models.Asset, error) {
+	copyObject := func(objectURL string) (string, error) {
+		srcKey := s.extractObjectName(objectURL)
+		dstKey := newObjectName(targetWorkspaceID, filepath.Ext(srcKey))
+		if err := s.storage.Copy(ctx, srcKey, dstKey); err != nil {
+			return "", err
+		}
+		*copied = append(*copied, dstKey)
+		return s.getObjectURL(dstKey), nil
+	}
+
+	objectURL, err := copyObject(src.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var thumbnailURL *string
+	if src.ThumbnailURL != nil {
+		thumbURL, err := copyObject(*src.ThumbnailURL)
+		if err != nil {
+			return nil, err
+		}
+		thumbnailURL = &thumbURL
+	}
+
+	variants := make(models.AssetVariants, len(src.Variants))
+	for key, variant := range src.Variants {
+		variantURL, err := copyObject(variant.URL)
+		if err != nil {
+			return nil, err
+		}
+		variant.URL = variantURL
+		variants[key] = variant
+	}
+
+	return &models.Asset{
+		ID:           uuid.New(),
+		WorkspaceID:  targetWorkspaceID,
+		UploadedBy:   userID,
+		Filename:     src.Filename,
+		ContentType:  src.ContentType,
+		Size:         src.Size,
+		URL:          objectURL,
+		ThumbnailURL: thumbnailURL,
+		Width:        src.Width,
+		Height:       src.Height,
+		PageNumber:   src.PageNumber,
+		PageCount:    src.PageCount,
+		DurationMs:   src.DurationMs,
+		Status:       src.Status,
+		ScanStatus:   src.ScanStatus,
+		Variants:     variants,
+		Attribution:  src.Attribution,
+	}, nil
+}
+
+// finishAssetCopy stores the copied variants and queues a scan when the
+// source had not been scanned yet
+func (s *AssetService) finishAssetCopy(ctx context.Context, asset *models.Asset) {
+	if len(asset.Variants) > 0 {
+		if err := s.assetRepo.UpdateAssetVariants(ctx, asset.ID, asset.Variants); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to store variants of copied asset %s: %v", asset.ID, err)
+		}
+	}
+
+	if asset.ScanStatus != models.ScanStatusPending {
+		return
+	}
+
+	scanJob := &models.AssetScanJob{
+		ObjectKey:   s.extractObjectName(asset.URL),
+		AssetID:     asset.ID,
+		WorkspaceID: asset.WorkspaceID,
+	}
+	if err := s.publishJob(ctx, AssetScanSubject, scanJob); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to queue scan for asset %s: %v", asset.ID, err)
+	}
+}
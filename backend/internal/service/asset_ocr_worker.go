@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	assetOCRTimeout = 3 * time.Minute
+
+	// maxOCRTextLength caps the stored text, well below the size limit of
+	// the search index
+	maxOCRTextLength = 100_000
+)
+
+// AssetOCRWorker recognizes the text in uploaded images so they can be
+// searched by it
+type AssetOCRWorker struct {
+	assetService *AssetService
+	provider     OCRProvider
+	nats         *nats.Conn
+	sub          *nats.Subscription
+}
+
+// NewAssetOCRWorker creates a new OCR worker
+func NewAssetOCRWorker(nc *nats.Conn, assetService *AssetService, provider OCRProvider) (*AssetOCRWorker, error) {
+	worker := &AssetOCRWorker{
+		assetService: assetService,
+		provider:     provider,
+		nats:         nc,
+	}
+
+	// Subscribe to OCR queue
+	sub, err := nc.QueueSubscribe(AssetOCRSubject, "asset-ocr", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to asset OCR queue: %w", err)
+	}
+
+	worker.sub = sub
+	return worker, nil
+}
+
+// Close closes the OCR worker subscription
+func (w *AssetOCRWorker) Close() error {
+	if w.sub != nil {
+		return w.sub.Unsubscribe()
+	}
+	return nil
+}
+
+// handleMessage processes an OCR job
+func (w *AssetOCRWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "asset.ocr")
+	defer span.End()
+
+	var job models.AssetOCRJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal asset OCR job: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, assetOCRTimeout)
+	defer cancel()
+
+	data, err := w.assetService.readObject(ctx, job.ObjectKey)
+	if err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to read image %s for OCR: %v", job.AssetID, err)
+		return
+	}
+
+	text, err := w.provider.Recognize(ctx, data)
+	if err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to recognize text in image %s: %v", job.AssetID, err)
+		return
+	}
+
+	text = normalizeOCRText(text)
+	if err := w.assetService.assetRepo.UpdateAssetOCRText(ctx, job.AssetID, text); err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to store text of image %s: %v", job.AssetID, err)
+		return
+	}
+
+	hlog.CtxInfof(ctx, "Recognized %d characters in image %s", utf8.RuneCountInString(text), job.AssetID)
+}
+
+// normalizeOCRText collapses the spaces of recognized text, drops empty
+// lines and caps its length
+func normalizeOCRText(text string) string {
+	text = strings.ToValidUTF8(strings.ReplaceAll(text, "\x00", ""), "")
+
+	var lines []string
+	remaining := maxOCRTextLength
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		line = truncateRunes(line, remaining)
+		lines = append(lines, line)
+
+		remaining -= utf8.RuneCountInString(line) + 1
+		if remaining <= 0 {
+			break
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	// assetPurgeBatchSize limits how many assets are purged per batch
+	assetPurgeBatchSize = 100
+	assetPurgeTimeout   = 5 * time.Minute
+)
+
+// AssetPurgeWorker periodically hard-deletes assets that were soft-deleted
+// longer than the retention period ago
+type AssetPurgeWorker struct {
+	assetService *AssetService
+	done         chan struct{}
+	trigger      chan struct{}
+	retention    time.Duration
+	interval     time.Duration
+}
+
+// NewAssetPurgeWorker creates and starts a new asset purge worker
+func NewAssetPurgeWorker(assetService *AssetService, retention, interval time.Duration) (*AssetPurgeWorker, error) {
+	if retention <= 0 || interval <= 0 {
+		return nil, fmt.Errorf("retention and interval must be positive")
+	}
+
+	worker := &AssetPurgeWorker{
+		assetService: assetService,
+		done:         make(chan struct{}),
+		trigger:      make(chan struct{}, 1),
+		retention:    retention,
+		interval:     interval,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the purge worker
+func (w *AssetPurgeWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger purges expired assets now instead of at the next tick. It returns
+// false if a purge is already pending.
+func (w *AssetPurgeWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// run purges on every tick until the worker is closed
+func (w *AssetPurgeWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.purge()
+		case <-w.trigger:
+			w.purge()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// purge removes expired assets batch by batch until none are left
+func (w *AssetPurgeWorker) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), assetPurgeTimeout)
+	defer cancel()
+
+	cutoff := time.Now().Add(-w.retention)
+	total := 0
+	for {
+		count, err := w.assetService.PurgeDeletedAssets(ctx, cutoff, assetPurgeBatchSize)
+		if err != nil {
+			hlog.CtxErrorf(ctx, "Failed to purge deleted assets: %v", err)
+			break
+		}
+
+		total += count
+		// A short batch means there is nothing more to do this round
+		if count < assetPurgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		hlog.CtxInfof(ctx, "Purged %d deleted assets", total)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const assetScanTimeout = 2 * time.Minute
+
+// AssetScanWorker scans uploaded files for malware and quarantines infected assets
+type AssetScanWorker struct {
+	assetService *AssetService
+	emailService *EmailService
+	userRepo     *repository.UserRepository
+	scanner      VirusScanner
+	nats         *nats.Conn
+	sub          *nats.Subscription
+}
+
+// NewAssetScanWorker creates a new asset scan worker
+func NewAssetScanWorker(
+	nc *nats.Conn,
+	assetService *AssetService,
+	scanner VirusScanner,
+	emailService *EmailService,
+	userRepo *repository.UserRepository,
+) (*AssetScanWorker, error) {
+	worker := &AssetScanWorker{
+		assetService: assetService,
+		emailService: emailService,
+		userRepo:     userRepo,
+		scanner:      scanner,
+		nats:         nc,
+	}
+
+	// Subscribe to scan queue
+	sub, err := nc.QueueSubscribe(AssetScanSubject, "asset-scanners", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to asset scan queue: %w", err)
+	}
+
+	worker.sub = sub
+	return worker, nil
+}
+
+// Close closes the scan worker subscription
+func (w *AssetScanWorker) Close() error {
+	if w.sub != nil {
+		return w.sub.Unsubscribe()
+	}
+	return nil
+}
+
+// handleMessage processes a scan job
+func (w *AssetScanWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "asset.scan")
+	defer span.End()
+
+	var job models.AssetScanJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal asset scan job: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, assetScanTimeout)
+	defer cancel()
+
+	result, err := w.scan(ctx, &job)
+	if err != nil {
+		// The asset stays pending so it can be rescanned
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to scan asset %s: %v", job.AssetID, err)
+		return
+	}
+
+	var status string
+	var signature *string
+	switch {
+	case result.Skipped:
+		status = models.ScanStatusSkipped
+	case result.Infected:
+		status = models.ScanStatusInfected
+		signature = &result.Signature
+	default:
+		status = models.ScanStatusClean
+	}
+
+	if err := w.assetService.assetRepo.UpdateScanStatus(ctx, job.AssetID, status, signature); err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to update scan status of asset %s: %v", job.AssetID, err)
+		return
+	}
+
+	if result.Infected {
+		hlog.CtxWarnf(ctx, "Quarantined asset %s: %s", job.AssetID, result.Signature)
+		w.notifyQuarantined(ctx, job.AssetID, result.Signature)
+	}
+}
+
+// scan streams the stored object to the scanner
+func (w *AssetScanWorker) scan(ctx context.Context, job *models.AssetScanJob) (*ScanResult, error) {
+	// Avoid downloading the file when scanning is disabled
+	if _, ok := w.scanner.(NoopScanner); ok {
+		return &ScanResult{Skipped: true}, nil
+	}
+
+	data, err := w.assetService.readObject(ctx, job.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.scanner.Scan(ctx, bytes.NewReader(data))
+}
+
+// notifyQuarantined emails the uploader and the workspace owner
+func (w *AssetScanWorker) notifyQuarantined(ctx context.Context, assetID uuid.UUID, signature string) {
+	asset, err := w.assetService.assetRepo.GetAssetByID(ctx, assetID)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to load quarantined asset %s: %v", assetID, err)
+		return
+	}
+
+	workspace, err := w.assetService.workspaceRepo.GetWorkspaceByID(ctx, asset.WorkspaceID)
+	if err != nil || workspace == nil {
+		hlog.CtxErrorf(ctx, "Failed to load workspace of quarantined asset %s: %v", assetID, err)
+		return
+	}
+
+	recipients := []uuid.UUID{asset.UploadedBy}
+	if workspace.OwnerID != asset.UploadedBy {
+		recipients = append(recipients, workspace.OwnerID)
+	}
+
+	for _, userID := range recipients {
+		user, err := w.userRepo.GetByID(ctx, userID)
+		if err != nil || user == nil {
+			continue
+		}
+
+		if err := w.emailService.SendAssetQuarantined(user.Email, user.Name, asset.Filename, workspace.Name, signature); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to notify %s about quarantined asset %s: %v", user.Email, assetID, err)
+		}
+	}
+}
@@ -3,32 +3,86 @@ package service
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/nats-io/nats.go"
 	"github.com/nfnt/resize"
 
+	"github.com/bifshteksex/hertz-board/internal/config"
 	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 )
 
 const (
-	MaxFileSize     = 10 * 1024 * 1024 // 10MB
+	MaxImageSize    = 10 * 1024 * 1024 // 10MB, images are decoded in memory
 	ThumbnailWidth  = 300
 	ThumbnailHeight = 300
 	MaxImageWidth   = 4000
 	MaxImageHeight  = 4000
+
+	// PresignedUploadExpiry is how long a presigned upload URL stays valid
+	PresignedUploadExpiry = 15 * time.Minute
+
+	// DefaultSignedURLExpiry is used when no asset URL expiry is configured
+	DefaultSignedURLExpiry = time.Hour
+
+	// AssetVariantThumbnail selects the thumbnail when requesting asset content
+	AssetVariantThumbnail = "thumbnail"
+
+	// ContentTypePDF is rendered into one image asset per page
+	ContentTypePDF = "application/pdf"
+
+	// PDFRenderSubject is the NATS subject PDF render jobs are published to
+	PDFRenderSubject = "assets.pdf.render"
+
+	// MediaTranscodeSubject is the NATS subject video processing jobs are published to
+	MediaTranscodeSubject = "assets.media.transcode"
+
+	// ImageOptimizeSubject is the NATS subject image optimization jobs are published to
+	ImageOptimizeSubject = "assets.image.optimize"
+
+	// AssetScanSubject is the NATS subject antivirus scan jobs are published to
+	AssetScanSubject = "assets.scan"
+
+	// AssetOCRSubject is the NATS subject text recognition jobs are published to
+	AssetOCRSubject = "assets.ocr"
+
+	// gifDelayUnit is the unit of GIF frame delays (1/100 s) in milliseconds
+	gifDelayUnit = 10
 )
 
+var (
+	// ErrAssetQuarantined is returned when an asset was flagged by the antivirus scanner
+	ErrAssetQuarantined = errors.New("asset is quarantined")
+
+	// ErrFileTooLarge is returned when an upload exceeds the maximum size of its type
+	ErrFileTooLarge = errors.New("file too large")
+)
+
+// AssetInUseError is returned when deleting an asset that canvas elements still display
+type AssetInUseError struct {
+	ElementIDs []uuid.UUID
+}
+
+func (e *AssetInUseError) Error() string {
+	return fmt.Sprintf("asset is used by %d canvas elements", len(e.ElementIDs))
+}
+
 var AllowedImageTypes = map[string]bool{
 	"image/jpeg": true,
 	"image/png":  true,
@@ -36,131 +90,467 @@ var AllowedImageTypes = map[string]bool{
 	"image/webp": true,
 }
 
+var AllowedVideoTypes = map[string]bool{
+	"video/mp4":  true,
+	"video/webm": true,
+}
+
 type AssetService struct {
 	assetRepo     *repository.AssetRepository
 	workspaceRepo *repository.WorkspaceRepository
-	minioClient   *minio.Client
-	bucketName    string
-	endpoint      string
+	storage       ObjectStorage
+	nats          *nats.Conn
+	billing       *BillingService
+	remoteClient  *http.Client
+	urlExpiry     time.Duration
+	storageQuota  int64
+	maxFileSize   int64
+	stripMetadata bool
+	ocrEnabled    bool
 }
 
 func NewAssetService(
 	assetRepo *repository.AssetRepository,
 	workspaceRepo *repository.WorkspaceRepository,
-	minioEndpoint, minioAccessKey, minioSecretKey string,
-	useSSL bool,
+	nc *nats.Conn,
+	storage ObjectStorage,
+	cfg *config.MinIOConfig,
+	uploadCfg *config.UploadConfig,
+	billing *BillingService,
 ) (*AssetService, error) {
-	// Initialize MinIO client
-	minioClient, err := minio.New(minioEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(minioAccessKey, minioSecretKey, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+	urlExpiry := DefaultSignedURLExpiry
+	if cfg.URLExpiry != "" {
+		parsed, err := cfg.GetURLExpiryDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset URL expiry: %w", err)
+		}
+		urlExpiry = parsed
 	}
 
-	bucketName := "hertz-board-assets"
+	return &AssetService{
+		assetRepo:     assetRepo,
+		workspaceRepo: workspaceRepo,
+		storage:       storage,
+		nats:          nc,
+		billing:       billing,
+		remoteClient:  newRemoteClient(),
+		urlExpiry:     urlExpiry,
+		storageQuota:  uploadCfg.WorkspaceQuota,
+		maxFileSize:   uploadCfg.MaxSize,
+		stripMetadata: uploadCfg.StripMetadata,
+		ocrEnabled:    uploadCfg.OCR.Provider != OCRProviderNone,
+	}, nil
+}
 
-	// Create bucket if it doesn't exist
-	ctx := context.Background()
-	exists, err := minioClient.BucketExists(ctx, bucketName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+// UploadAsset uploads a file to object storage and creates an asset record
+func (s *AssetService) UploadAsset(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	filename, contentType string,
+	size int64,
+	reader io.Reader,
+) (*models.Asset, error) {
+	return s.uploadAsset(ctx, workspaceID, userID, filename, contentType, size, reader, nil)
+}
+
+// UploadAssetStream uploads a file of unknown size, such as a multipart
+// part, without holding it in memory. Files other than images are spooled
+// to a temporary file to learn their size before they're stored.
+func (s *AssetService) UploadAssetStream(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	filename, contentType string,
+	reader io.Reader,
+) (*models.Asset, error) {
+	if err := s.validateUpload(0, contentType); err != nil {
+		return nil, err
 	}
+	maxSize := s.maxUploadSize(contentType)
 
-	if !exists {
-		err = minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
+	if AllowedImageTypes[contentType] {
+		data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
 		if err != nil {
-			return nil, fmt.Errorf("failed to create bucket: %w", err)
+			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
+		return s.uploadAsset(ctx, workspaceID, userID, filename, contentType, int64(len(data)), bytes.NewReader(data), nil)
+	}
 
-		// Set bucket policy to public read
-		policy := fmt.Sprintf(`{
-			"Version": "2012-10-17",
-			"Statement": [{
-				"Effect": "Allow",
-				"Principal": {"AWS": ["*"]},
-				"Action": ["s3:GetObject"],
-				"Resource": ["arn:aws:s3:::%s/*"]
-			}]
-		}`, bucketName)
+	spool, err := os.CreateTemp("", "hertzboard-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
 
-		err = minioClient.SetBucketPolicy(ctx, bucketName, policy)
-		if err != nil {
-			return nil, fmt.Errorf("failed to set bucket policy: %w", err)
-		}
+	size, err := io.Copy(spool, io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind upload file: %w", err)
 	}
 
-	return &AssetService{
-		assetRepo:     assetRepo,
-		workspaceRepo: workspaceRepo,
-		minioClient:   minioClient,
-		bucketName:    bucketName,
-		endpoint:      minioEndpoint,
-	}, nil
+	return s.uploadAsset(ctx, workspaceID, userID, filename, contentType, size, spool, nil)
 }
 
-// UploadAsset uploads a file to MinIO and creates an asset record
-func (s *AssetService) UploadAsset(
+// uploadAsset stores a file and creates its asset record, crediting the
+// author when the file comes from a stock media provider
+func (s *AssetService) uploadAsset(
 	ctx context.Context,
 	workspaceID, userID uuid.UUID,
 	filename, contentType string,
 	size int64,
 	reader io.Reader,
+	attribution *models.AssetAttribution,
 ) (*models.Asset, error) {
 	if err := s.validateUpload(size, contentType); err != nil {
 		return nil, err
 	}
 
-	fileData, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+	if err := s.checkQuota(ctx, workspaceID, size); err != nil {
+		return nil, err
 	}
 
 	ext := filepath.Ext(filename)
-	objectName := fmt.Sprintf("%s/%s/%s%s", workspaceID, time.Now().Format("2006/01"), uuid.New(), ext)
+	objectName := newObjectName(workspaceID, ext)
+
+	asset := &models.Asset{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		UploadedBy:  userID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		URL:         s.getObjectURL(objectName),
+		Attribution: attribution,
+	}
 
-	isImage := AllowedImageTypes[contentType]
-	width, height, thumbnailURL, err := s.processImage(ctx, fileData, contentType, isImage, ext, workspaceID)
+	// Images are read for their dimensions and thumbnail, other files are
+	// streamed to storage as they are
+	if AllowedImageTypes[contentType] {
+		if err := s.storeImage(ctx, asset, objectName, ext, reader); err != nil {
+			return nil, err
+		}
+	} else if err := s.storage.Put(ctx, objectName, reader, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	if err := s.createAsset(ctx, asset, objectName); err != nil {
+		s.cleanupUploadedFiles(ctx, objectName, asset.ThumbnailURL)
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// storeImage stores an uploaded image and its thumbnail, filling in the
+// dimensions of the asset
+func (s *AssetService) storeImage(ctx context.Context, asset *models.Asset, objectName, ext string, reader io.Reader) error {
+	fileData, err := io.ReadAll(io.LimitReader(reader, MaxImageSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(fileData) > MaxImageSize {
+		return fmt.Errorf("%w: images may be at most %d bytes", ErrFileTooLarge, MaxImageSize)
+	}
+
+	if s.shouldStripMetadata(asset.ContentType) {
+		fileData, err = stripJPEGMetadata(fileData)
+		if err != nil {
+			return fmt.Errorf("failed to strip image metadata: %w", err)
+		}
+	}
+	asset.Size = int64(len(fileData))
+
+	width, height, thumbnailURL, err := s.processImage(ctx, fileData, asset.ContentType, true, ext, asset.WorkspaceID)
 	if err != nil {
+		return err
+	}
+
+	if err := s.uploadFile(ctx, objectName, fileData, asset.Size, asset.ContentType); err != nil {
+		s.cleanupUploadedFiles(ctx, objectName, thumbnailURL)
+		return err
+	}
+
+	asset.Width = width
+	asset.Height = height
+	asset.ThumbnailURL = thumbnailURL
+	asset.DurationMs = animationDuration(fileData, asset.ContentType)
+	return nil
+}
+
+// PresignUpload validates upload metadata and returns a presigned URL the
+// client can PUT the file to directly, bypassing the API gateway
+func (s *AssetService) PresignUpload(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	req *models.UploadAssetRequest,
+) (*models.PresignedUploadResponse, error) {
+	if err := s.validateUpload(req.Size, req.ContentType); err != nil {
 		return nil, err
 	}
 
-	if err := s.uploadFile(ctx, objectName, fileData, size, contentType); err != nil {
+	if err := s.checkQuota(ctx, workspaceID, req.Size); err != nil {
 		return nil, err
 	}
 
+	uploadKey := newUploadKey(workspaceID, filepath.Ext(req.Filename))
+
+	uploadURL, err := s.storage.PresignPut(ctx, uploadKey, PresignedUploadExpiry, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PresignedUploadResponse{
+		UploadURL: uploadURL,
+		ObjectKey: uploadKey,
+		Method:    "PUT",
+		Headers: map[string]string{
+			"Content-Type": req.ContentType,
+		},
+		ExpiresAt: time.Now().Add(PresignedUploadExpiry),
+	}, nil
+}
+
+// ConfirmUpload validates a file uploaded through a presigned URL and
+// creates the asset record for it. The upload is moved to a key of the
+// server first, the presigned URL stays valid until it expires and must not
+// be able to replace a confirmed file. Invalid uploads are removed from
+// storage.
+func (s *AssetService) ConfirmUpload(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.ConfirmUploadRequest,
+) (*models.Asset, error) {
+	// Upload keys are issued per workspace, reject keys from elsewhere
+	if !strings.HasPrefix(req.ObjectKey, uploadKeyPrefix(workspaceID)) || strings.Contains(req.ObjectKey, "..") {
+		return nil, fmt.Errorf("invalid object key")
+	}
+
+	objectName := newObjectName(workspaceID, filepath.Ext(req.ObjectKey))
+	if err := s.storage.Copy(ctx, req.ObjectKey, objectName); err != nil {
+		return nil, fmt.Errorf("uploaded file not found: %w", err)
+	}
+	if err := s.storage.Remove(ctx, req.ObjectKey); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to remove confirmed upload %s: %v", req.ObjectKey, err)
+	}
+
+	// Everything below checks the copy, later writes to the upload key
+	// don't reach it
+	info, err := s.storage.Stat(ctx, objectName)
+	if err != nil {
+		s.cleanupUploadedFiles(ctx, objectName, nil)
+		return nil, fmt.Errorf("failed to stat uploaded file: %w", err)
+	}
+
+	if err := s.validateUpload(info.Size, info.ContentType); err != nil {
+		s.cleanupUploadedFiles(ctx, objectName, nil)
+		return nil, err
+	}
+
+	// Images are read back once to extract dimensions and build the thumbnail
+	var width, height, durationMs *int
+	var thumbnailURL *string
+	size := info.Size
+	isImage := AllowedImageTypes[info.ContentType]
+	if isImage {
+		fileData, readErr := s.readObject(ctx, objectName)
+		if readErr != nil {
+			s.cleanupUploadedFiles(ctx, objectName, nil)
+			return nil, readErr
+		}
+
+		// The client uploaded the original, replace it with the stripped copy
+		if s.shouldStripMetadata(info.ContentType) {
+			fileData, err = stripJPEGMetadata(fileData)
+			if err != nil {
+				s.cleanupUploadedFiles(ctx, objectName, nil)
+				return nil, fmt.Errorf("failed to strip image metadata: %w", err)
+			}
+			size = int64(len(fileData))
+			if err := s.uploadFile(ctx, objectName, fileData, size, info.ContentType); err != nil {
+				s.cleanupUploadedFiles(ctx, objectName, nil)
+				return nil, err
+			}
+		}
+
+		width, height, thumbnailURL, err = s.processImage(
+			ctx, fileData, info.ContentType, isImage, filepath.Ext(objectName), workspaceID,
+		)
+		if err != nil {
+			s.cleanupUploadedFiles(ctx, objectName, nil)
+			return nil, err
+		}
+		durationMs = animationDuration(fileData, info.ContentType)
+	}
+
+	filename := req.Filename
+	if filename == "" {
+		filename = filepath.Base(req.ObjectKey)
+	}
+
 	asset := &models.Asset{
 		ID:           uuid.New(),
 		WorkspaceID:  workspaceID,
 		UploadedBy:   userID,
 		Filename:     filename,
-		ContentType:  contentType,
+		ContentType:  info.ContentType,
 		Size:         size,
 		URL:          s.getObjectURL(objectName),
 		ThumbnailURL: thumbnailURL,
 		Width:        width,
 		Height:       height,
+		DurationMs:   durationMs,
 	}
 
-	if err := s.assetRepo.CreateAsset(ctx, asset); err != nil {
+	if err := s.createAsset(ctx, asset, objectName); err != nil {
 		s.cleanupUploadedFiles(ctx, objectName, thumbnailURL)
-		return nil, fmt.Errorf("failed to create asset record: %w", err)
+		return nil, err
 	}
 
 	return asset, nil
 }
 
-func (s *AssetService) validateUpload(size int64, contentType string) error {
-	if size > MaxFileSize {
-		return fmt.Errorf("file size exceeds maximum allowed size of %d bytes", MaxFileSize)
+// shouldStripMetadata reports whether EXIF data must be removed before storing
+func (s *AssetService) shouldStripMetadata(contentType string) bool {
+	return s.stripMetadata && contentType == "image/jpeg"
+}
+
+// createAsset stores the asset record. PDFs and videos are stored as
+// processing and queued for the matching background worker.
+func (s *AssetService) createAsset(ctx context.Context, asset *models.Asset, objectName string) error {
+	var subject string
+	var job interface{}
+	switch {
+	case asset.ContentType == ContentTypePDF:
+		subject = PDFRenderSubject
+		job = &models.PDFRenderJob{
+			ObjectKey:   objectName,
+			AssetID:     asset.ID,
+			WorkspaceID: asset.WorkspaceID,
+			UploadedBy:  asset.UploadedBy,
+		}
+	case AllowedVideoTypes[asset.ContentType]:
+		subject = MediaTranscodeSubject
+		job = &models.MediaTranscodeJob{
+			ObjectKey:   objectName,
+			AssetID:     asset.ID,
+			WorkspaceID: asset.WorkspaceID,
+		}
+	}
+
+	if job != nil {
+		asset.Status = models.AssetStatusProcessing
+	}
+
+	quota, err := s.StorageQuota(ctx, asset.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	if err := s.assetRepo.CreateAssetWithinQuota(ctx, asset, quota); err != nil {
+		return fmt.Errorf("failed to create asset record: %w", err)
+	}
+
+	// Every upload is scanned, the scanner decides whether scanning is enabled
+	scanJob := &models.AssetScanJob{
+		ObjectKey:   objectName,
+		AssetID:     asset.ID,
+		WorkspaceID: asset.WorkspaceID,
+	}
+	if err := s.publishJob(ctx, AssetScanSubject, scanJob); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to queue scan for asset %s: %v", asset.ID, err)
+	}
+
+	// Optimized variants are optional, the original stays usable without them
+	if AllowedImageTypes[asset.ContentType] && asset.DurationMs == nil {
+		optimizeJob := &models.ImageOptimizeJob{
+			ObjectKey:   objectName,
+			AssetID:     asset.ID,
+			WorkspaceID: asset.WorkspaceID,
+		}
+		if err := s.publishJob(ctx, ImageOptimizeSubject, optimizeJob); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to queue optimization for asset %s: %v", asset.ID, err)
+		}
+	}
+
+	// Recognized text only makes images searchable
+	if s.ocrEnabled && AllowedImageTypes[asset.ContentType] {
+		ocrJob := &models.AssetOCRJob{
+			ObjectKey:   objectName,
+			AssetID:     asset.ID,
+			WorkspaceID: asset.WorkspaceID,
+		}
+		if err := s.publishJob(ctx, AssetOCRSubject, ocrJob); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to queue OCR for asset %s: %v", asset.ID, err)
+		}
 	}
-	if !AllowedImageTypes[contentType] && !strings.HasPrefix(contentType, "image/") {
+
+	if job == nil {
+		return nil
+	}
+
+	if err := s.publishJob(ctx, subject, job); err != nil {
+		// The asset exists but will never be processed, mark it as failed
+		_ = s.assetRepo.UpdateAssetStatus(ctx, asset.ID, models.AssetStatusFailed, nil)
+		asset.Status = models.AssetStatusFailed
+		return err
+	}
+
+	return nil
+}
+
+// publishJob queues a background processing job for an asset
+func (s *AssetService) publishJob(ctx context.Context, subject string, job interface{}) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal asset job: %w", err)
+	}
+
+	if err := s.nats.PublishMsg(tracing.NewMsg(ctx, subject, data)); err != nil {
+		return fmt.Errorf("failed to publish asset job: %w", err)
+	}
+
+	return nil
+}
+
+// readObject downloads an object into memory
+func (s *AssetService) readObject(ctx context.Context, objectName string) ([]byte, error) {
+	object, err := s.storage.Get(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(io.LimitReader(object, MaxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	return data, nil
+}
+
+func (s *AssetService) validateUpload(size int64, contentType string) error {
+	if contentType != ContentTypePDF && !AllowedVideoTypes[contentType] &&
+		!AllowedImageTypes[contentType] && !strings.HasPrefix(contentType, "image/") {
 		return fmt.Errorf("unsupported file type: %s", contentType)
 	}
+	if maxSize := s.maxUploadSize(contentType); size > maxSize {
+		return fmt.Errorf("%w: maximum allowed size is %d bytes", ErrFileTooLarge, maxSize)
+	}
 	return nil
 }
 
+// maxUploadSize returns the largest file of a content type that is accepted.
+// Images are processed in memory and also capped at MaxImageSize.
+func (s *AssetService) maxUploadSize(contentType string) int64 {
+	if AllowedImageTypes[contentType] && s.maxFileSize > MaxImageSize {
+		return MaxImageSize
+	}
+	return s.maxFileSize
+}
+
 func (s *AssetService) processImage(
 	ctx context.Context,
 	fileData []byte,
@@ -219,14 +609,7 @@ func (s *AssetService) createAndUploadThumbnail(
 		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
 	}
 
-	_, err = s.minioClient.PutObject(
-		ctx,
-		s.bucketName,
-		thumbnailName,
-		bytes.NewReader(thumbnailBuf.Bytes()),
-		int64(thumbnailBuf.Len()),
-		minio.PutObjectOptions{ContentType: contentType},
-	)
+	err = s.storage.Put(ctx, thumbnailName, bytes.NewReader(thumbnailBuf.Bytes()), int64(thumbnailBuf.Len()), contentType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload thumbnail: %w", err)
 	}
@@ -236,19 +619,16 @@ func (s *AssetService) createAndUploadThumbnail(
 }
 
 func (s *AssetService) uploadFile(ctx context.Context, objectName string, fileData []byte, size int64, contentType string) error {
-	_, err := s.minioClient.PutObject(ctx, s.bucketName, objectName, bytes.NewReader(fileData), size, minio.PutObjectOptions{
-		ContentType: contentType,
-	})
-	if err != nil {
+	if err := s.storage.Put(ctx, objectName, bytes.NewReader(fileData), size, contentType); err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	return nil
 }
 
 func (s *AssetService) cleanupUploadedFiles(ctx context.Context, objectName string, thumbnailURL *string) {
-	_ = s.minioClient.RemoveObject(ctx, s.bucketName, objectName, minio.RemoveObjectOptions{})
+	_ = s.storage.Remove(ctx, objectName)
 	if thumbnailURL != nil {
-		_ = s.minioClient.RemoveObject(ctx, s.bucketName, *thumbnailURL, minio.RemoveObjectOptions{})
+		_ = s.storage.Remove(ctx, s.extractObjectName(*thumbnailURL))
 	}
 }
 
@@ -259,26 +639,129 @@ func (s *AssetService) GetAsset(ctx context.Context, id uuid.UUID) (*models.Asse
 		return nil, fmt.Errorf("failed to get asset: %w", err)
 	}
 
+	if asset.ContentType == ContentTypePDF {
+		pages, err := s.assetRepo.GetAssetPages(ctx, asset.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset pages: %w", err)
+		}
+		asset.Pages = pages
+	}
+
+	usedBy, err := s.assetRepo.GetAssetReferences(ctx, asset.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset usage: %w", err)
+	}
+	asset.UsedBy = usedBy
+	asset.UsageCount = len(usedBy)
+
 	return asset, nil
 }
 
-// GetWorkspaceAssets retrieves all assets for a workspace
-func (s *AssetService) GetWorkspaceAssets(ctx context.Context, workspaceID uuid.UUID) ([]models.Asset, error) {
-	assets, err := s.assetRepo.GetAssetsByWorkspace(ctx, workspaceID)
+// GetWorkspaceAsset retrieves an asset by ID, ensuring it belongs to the workspace
+func (s *AssetService) GetWorkspaceAsset(ctx context.Context, workspaceID, id uuid.UUID) (*models.Asset, error) {
+	asset, err := s.GetAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if asset.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("asset not found")
+	}
+
+	if asset.ScanStatus == models.ScanStatusInfected {
+		return nil, ErrAssetQuarantined
+	}
+
+	return asset, nil
+}
+
+// GetWorkspaceAssets retrieves a page of workspace assets and the total number of matches
+func (s *AssetService) GetWorkspaceAssets(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	filter models.AssetListFilter,
+) ([]models.Asset, int, error) {
+	assets, total, err := s.assetRepo.ListAssets(ctx, workspaceID, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get workspace assets: %w", err)
+	}
+
+	if len(assets) == 0 {
+		return assets, total, nil
+	}
+
+	ids := make([]uuid.UUID, len(assets))
+	for i := range assets {
+		ids[i] = assets[i].ID
+	}
+
+	counts, err := s.assetRepo.GetAssetUsageCounts(ctx, ids)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get asset usage: %w", err)
+	}
+	for i := range assets {
+		assets[i].UsageCount = counts[assets[i].ID]
+	}
+
+	return assets, total, nil
+}
+
+// GetAssetsByWorkspaces retrieves a page of assets of each of several
+// workspaces with the same filter, along with their usage counts
+func (s *AssetService) GetAssetsByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+	filter models.AssetListFilter,
+) (map[uuid.UUID]*models.AssetPage, error) {
+	pages, err := s.assetRepo.ListAssetsByWorkspaces(ctx, workspaceIDs, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workspace assets: %w", err)
 	}
 
-	return assets, nil
+	var ids []uuid.UUID
+	for _, page := range pages {
+		for i := range page.Assets {
+			ids = append(ids, page.Assets[i].ID)
+		}
+	}
+	if len(ids) == 0 {
+		return pages, nil
+	}
+
+	counts, err := s.assetRepo.GetAssetUsageCounts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset usage: %w", err)
+	}
+	for _, page := range pages {
+		for i := range page.Assets {
+			page.Assets[i].UsageCount = counts[page.Assets[i].ID]
+		}
+	}
+
+	return pages, nil
 }
 
-// DeleteAsset soft deletes an asset
-func (s *AssetService) DeleteAsset(ctx context.Context, id uuid.UUID) error {
+// DeleteAsset soft deletes an asset. Assets still displayed on the canvas are
+// only deleted when force is set, otherwise an AssetInUseError is returned.
+func (s *AssetService) DeleteAsset(ctx context.Context, workspaceID, id uuid.UUID, force bool) error {
 	// Get asset info
-	_, err := s.assetRepo.GetAssetByID(ctx, id)
+	asset, err := s.assetRepo.GetAssetByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("asset not found: %w", err)
 	}
+	if asset.WorkspaceID != workspaceID {
+		return fmt.Errorf("asset not found")
+	}
+
+	if !force {
+		usedBy, err := s.assetRepo.GetAssetReferences(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get asset usage: %w", err)
+		}
+		if len(usedBy) > 0 {
+			return &AssetInUseError{ElementIDs: usedBy}
+		}
+	}
 
 	// Soft delete in database
 	if err := s.assetRepo.DeleteAsset(ctx, id); err != nil {
@@ -291,6 +774,88 @@ func (s *AssetService) DeleteAsset(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// PurgeDeletedAssets permanently removes assets soft-deleted before the
+// cutoff: the original, thumbnail and variant objects, then the record itself
+func (s *AssetService) PurgeDeletedAssets(ctx context.Context, cutoff time.Time, batchSize int) (int, error) {
+	assets, err := s.assetRepo.GetDeletedAssetsBefore(ctx, cutoff, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted assets: %w", err)
+	}
+
+	count := 0
+	for i := range assets {
+		if err := s.removeAssetObjects(ctx, &assets[i]); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to remove objects of asset %s: %v", assets[i].ID, err)
+			continue
+		}
+
+		purged, err := s.assetRepo.PurgeAsset(ctx, assets[i].ID)
+		if err != nil {
+			hlog.CtxErrorf(ctx, "Failed to purge asset %s: %v", assets[i].ID, err)
+			continue
+		}
+		if purged {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// PurgeAsset deletes an asset even if it is on the canvas and removes its
+// objects and records right away instead of after the retention period, for
+// content that must not stay recoverable
+func (s *AssetService) PurgeAsset(ctx context.Context, workspaceID, id uuid.UUID) error {
+	asset, err := s.assetRepo.GetAssetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("asset not found: %w", err)
+	}
+	if asset.WorkspaceID != workspaceID {
+		return fmt.Errorf("asset not found")
+	}
+
+	pages, err := s.assetRepo.GetAssetPages(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get asset pages: %w", err)
+	}
+
+	if err := s.DeleteAsset(ctx, workspaceID, id, true); err != nil {
+		return err
+	}
+
+	// Pages go first, documents are only purged once their pages are gone
+	targets := append(pages, *asset)
+	for i := range targets {
+		if err := s.removeAssetObjects(ctx, &targets[i]); err != nil {
+			return fmt.Errorf("failed to remove objects of asset %s: %w", targets[i].ID, err)
+		}
+		if _, err := s.assetRepo.PurgeAsset(ctx, targets[i].ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeAssetObjects deletes every stored object belonging to an asset
+func (s *AssetService) removeAssetObjects(ctx context.Context, asset *models.Asset) error {
+	objectURLs := []string{asset.URL}
+	if asset.ThumbnailURL != nil {
+		objectURLs = append(objectURLs, *asset.ThumbnailURL)
+	}
+	for _, variant := range asset.Variants {
+		objectURLs = append(objectURLs, variant.URL)
+	}
+
+	for _, objectURL := range objectURLs {
+		if err := s.storage.Remove(ctx, s.extractObjectName(objectURL)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // CleanupOrphanedAssets finds and deletes assets not referenced by any element
 func (s *AssetService) CleanupOrphanedAssets(ctx context.Context, workspaceID uuid.UUID) (int, error) {
 	orphanedAssets, err := s.assetRepo.GetOrphanedAssets(ctx, workspaceID)
@@ -300,10 +865,9 @@ func (s *AssetService) CleanupOrphanedAssets(ctx context.Context, workspaceID uu
 
 	count := 0
 	for i := range orphanedAssets {
-		// Delete from MinIO
+		// Delete from object storage
 		objectName := s.extractObjectName(orphanedAssets[i].URL)
-		err := s.minioClient.RemoveObject(ctx, s.bucketName, objectName, minio.RemoveObjectOptions{})
-		if err != nil {
+		if err := s.storage.Remove(ctx, objectName); err != nil {
 			// Log error but continue
 			continue
 		}
@@ -311,7 +875,7 @@ func (s *AssetService) CleanupOrphanedAssets(ctx context.Context, workspaceID uu
 		// Delete thumbnail if exists
 		if orphanedAssets[i].ThumbnailURL != nil {
 			thumbnailName := s.extractObjectName(*orphanedAssets[i].ThumbnailURL)
-			_ = s.minioClient.RemoveObject(ctx, s.bucketName, thumbnailName, minio.RemoveObjectOptions{})
+			_ = s.storage.Remove(ctx, thumbnailName)
 		}
 
 		// Soft delete in database
@@ -325,24 +889,154 @@ func (s *AssetService) CleanupOrphanedAssets(ctx context.Context, workspaceID uu
 	return count, nil
 }
 
+// StorageQuota returns the storage quota of a workspace in bytes, 0 means
+// unlimited. With billing enabled it is the quota of the owner's plan.
+func (s *AssetService) StorageQuota(ctx context.Context, workspaceID uuid.UUID) (int64, error) {
+	if s.billing == nil {
+		return s.storageQuota, nil
+	}
+
+	entitlements, err := s.billing.WorkspaceEntitlements(ctx, workspaceID)
+	if err != nil {
+		return 0, err
+	}
+	return entitlements.StorageQuotaBytes, nil
+}
+
+// checkQuota rejects uploads that would not fit in the workspace quota before
+// any data is stored. The final check happens atomically when the record is created.
+func (s *AssetService) checkQuota(ctx context.Context, workspaceID uuid.UUID, size int64) error {
+	quota, err := s.StorageQuota(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if quota <= 0 {
+		return nil
+	}
+
+	used, err := s.assetRepo.GetStorageUsage(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if used+size > quota {
+		return fmt.Errorf("%w: %d of %d bytes used", repository.ErrStorageQuotaExceeded, used, quota)
+	}
+
+	return nil
+}
+
+// SignAsset replaces the stored object URLs of an asset, its pages and its
+// variants with time-limited presigned GET URLs
+func (s *AssetService) SignAsset(ctx context.Context, asset *models.Asset) error {
+	signedURL, err := s.presignGet(ctx, asset.URL)
+	if err != nil {
+		return err
+	}
+	asset.URL = signedURL
+
+	if asset.ThumbnailURL != nil {
+		signedThumb, thumbErr := s.presignGet(ctx, *asset.ThumbnailURL)
+		if thumbErr != nil {
+			return thumbErr
+		}
+		asset.ThumbnailURL = &signedThumb
+	}
+
+	for key, variant := range asset.Variants {
+		signedVariant, variantErr := s.presignGet(ctx, variant.URL)
+		if variantErr != nil {
+			return variantErr
+		}
+		variant.URL = signedVariant
+		asset.Variants[key] = variant
+	}
+
+	for i := range asset.Pages {
+		if err := s.SignAsset(ctx, &asset.Pages[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ContentURL returns a presigned URL for the original file, its thumbnail or
+// one of its optimized variants
+func (s *AssetService) ContentURL(ctx context.Context, asset *models.Asset, variant string) (string, error) {
+	switch variant {
+	case "":
+		return s.presignGet(ctx, asset.URL)
+	case AssetVariantThumbnail:
+		if asset.ThumbnailURL == nil {
+			return "", fmt.Errorf("asset has no thumbnail")
+		}
+		return s.presignGet(ctx, *asset.ThumbnailURL)
+	default:
+		v, ok := asset.Variants[variant]
+		if !ok {
+			return "", fmt.Errorf("asset has no variant %s", variant)
+		}
+		return s.presignGet(ctx, v.URL)
+	}
+}
+
+// SignedURLExpiry returns how long presigned asset URLs stay valid
+func (s *AssetService) SignedURLExpiry() time.Duration {
+	return s.urlExpiry
+}
+
+// presignGet signs a stored object URL for direct download
+func (s *AssetService) presignGet(ctx context.Context, storedURL string) (string, error) {
+	cacheControl := fmt.Sprintf("private, max-age=%d", int(s.urlExpiry.Seconds()))
+	return s.storage.PresignGet(ctx, s.extractObjectName(storedURL), s.urlExpiry, cacheControl)
+}
+
 // Helper functions
 
+// newObjectName builds a unique object key under the workspace prefix
+func newObjectName(workspaceID uuid.UUID, ext string) string {
+	return fmt.Sprintf("%s/%s/%s%s", workspaceID, time.Now().Format("2006/01"), uuid.New(), ext)
+}
+
+// uploadKeyPrefix is where presigned uploads of a workspace are stored until
+// they are confirmed. Assets never live under it.
+func uploadKeyPrefix(workspaceID uuid.UUID) string {
+	return workspaceID.String() + "/uploads/"
+}
+
+func newUploadKey(workspaceID uuid.UUID, ext string) string {
+	return uploadKeyPrefix(workspaceID) + uuid.New().String() + ext
+}
+
 func (s *AssetService) getObjectURL(objectName string) string {
-	// In production, this should use a CDN URL
-	return fmt.Sprintf("http://%s/%s/%s", s.endpoint, s.bucketName, objectName)
+	return s.storage.ObjectURL(objectName)
 }
 
-func (s *AssetService) extractObjectName(url string) string {
-	// Extract object name from full URL
-	const urlParts = 2
-	parts := strings.SplitN(url, s.bucketName+"/", urlParts)
-	if len(parts) == urlParts {
-		return parts[1]
-	}
-	return url
+func (s *AssetService) extractObjectName(objectURL string) string {
+	return s.storage.ObjectKey(objectURL)
 }
 
 // ValidateContentType checks if the content type is allowed
 func (s *AssetService) ValidateContentType(contentType string) bool {
-	return AllowedImageTypes[contentType]
+	return AllowedImageTypes[contentType] || AllowedVideoTypes[contentType] || contentType == ContentTypePDF
+}
+
+// animationDuration returns the total playback time of an animated GIF
+func animationDuration(fileData []byte, contentType string) *int {
+	if contentType != "image/gif" {
+		return nil
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(fileData))
+	if err != nil || len(anim.Image) < 2 {
+		return nil
+	}
+
+	total := 0
+	for _, delay := range anim.Delay {
+		total += delay * gifDelayUnit
+	}
+
+	return &total
 }
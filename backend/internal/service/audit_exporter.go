@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+// Audit sink types
+const (
+	AuditSinkSyslog = "syslog"
+	AuditSinkSplunk = "splunk"
+	AuditSinkS3     = "s3"
+)
+
+const (
+	defaultAuditInterval   = 5 * time.Second
+	defaultAuditMaxBackoff = 5 * time.Minute
+	defaultAuditBatchSize  = 500
+
+	auditSendTimeout  = time.Minute
+	auditSetupTimeout = 10 * time.Second
+	// auditSettleDelay lets the events that took a sequence number commit
+	// before the cursor passes them
+	auditSettleDelay = 2 * time.Second
+)
+
+// AuditSink ships batches of audit events to an external system. Send
+// either delivers the whole batch or fails, a failed batch is sent again.
+type AuditSink interface {
+	Send(ctx context.Context, events []models.AuditEvent) error
+	Close() error
+}
+
+// auditRetryAfterError is returned by sinks that ask to be left alone for a
+// while, e.g. an overloaded Splunk
+type auditRetryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *auditRetryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *auditRetryAfterError) Unwrap() error {
+	return e.err
+}
+
+// NewAuditSink creates the sink selected by the type of cfg
+func NewAuditSink(cfg *config.AuditSinkConfig) (AuditSink, error) {
+	switch cfg.Type {
+	case AuditSinkSyslog:
+		return NewSyslogAuditSink(&cfg.Syslog)
+	case AuditSinkSplunk:
+		return NewSplunkAuditSink(&cfg.Splunk)
+	case AuditSinkS3:
+		return NewS3AuditSink(&cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown audit sink type: %s", cfg.Type)
+	}
+}
+
+// AuditExporterStats is a snapshot of the counters of an exporter
+type AuditExporterStats struct {
+	Sink      string
+	Delivered uint64        // events shipped since start
+	Failed    uint64        // batches that failed since start
+	Pending   int64         // events not shipped yet at the last run
+	Lag       time.Duration // age of the oldest event not shipped yet at the last run
+}
+
+// AuditExporter ships the audit log to one sink. The log in PostgreSQL is
+// the buffer: recording never waits for a sink, the exporter reads batches
+// after its cursor and only moves the cursor once a batch is delivered. A
+// full batch is followed by the next one right away until the sink has
+// caught up. A failing sink is retried with exponential backoff, or after
+// the delay it asks for, so a struggling SIEM isn't flooded. Only one
+// instance ships to a sink at a time.
+type AuditExporter struct {
+	auditRepo  *repository.AuditRepository
+	sink       AuditSink
+	name       string
+	interval   time.Duration
+	maxBackoff time.Duration
+	batchSize  int
+	done       chan struct{}
+	stopped    chan struct{}
+
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	pending   atomic.Int64
+	lag       atomic.Int64
+}
+
+// NewAuditExporter creates and starts an exporter of the sink configured by
+// cfg. Unset intervals and the batch size fall back to defaults.
+func NewAuditExporter(auditRepo *repository.AuditRepository, cfg *config.AuditSinkConfig) (*AuditExporter, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("audit sink name is required")
+	}
+
+	interval := defaultAuditInterval
+	if cfg.Interval != "" {
+		parsed, err := cfg.GetIntervalDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval of audit sink %s: %w", cfg.Name, err)
+		}
+		interval = parsed
+	}
+	maxBackoff := defaultAuditMaxBackoff
+	if cfg.MaxBackoff != "" {
+		parsed, err := cfg.GetMaxBackoffDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid max backoff of audit sink %s: %w", cfg.Name, err)
+		}
+		maxBackoff = parsed
+	}
+	batchSize := cfg.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultAuditBatchSize
+	}
+	if interval <= 0 || maxBackoff <= 0 || batchSize <= 0 {
+		return nil, fmt.Errorf("interval, max backoff and batch size of audit sink %s must be positive", cfg.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), auditSetupTimeout)
+	defer cancel()
+	if err := auditRepo.EnsureCursor(ctx, cfg.Name); err != nil {
+		return nil, err
+	}
+
+	sink, err := NewAuditSink(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit sink %s: %w", cfg.Name, err)
+	}
+
+	e := &AuditExporter{
+		auditRepo:  auditRepo,
+		sink:       sink,
+		name:       cfg.Name,
+		interval:   interval,
+		maxBackoff: maxBackoff,
+		batchSize:  batchSize,
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go e.run()
+
+	return e, nil
+}
+
+// NewAuditExporters starts an exporter per configured sink
+func NewAuditExporters(auditRepo *repository.AuditRepository, cfg *config.AuditConfig) ([]*AuditExporter, error) {
+	names := make(map[string]bool, len(cfg.Sinks))
+	exporters := make([]*AuditExporter, 0, len(cfg.Sinks))
+	for i := range cfg.Sinks {
+		if names[cfg.Sinks[i].Name] {
+			CloseAuditExporters(exporters)
+			return nil, fmt.Errorf("duplicate audit sink name: %s", cfg.Sinks[i].Name)
+		}
+		names[cfg.Sinks[i].Name] = true
+
+		exporter, err := NewAuditExporter(auditRepo, &cfg.Sinks[i])
+		if err != nil {
+			CloseAuditExporters(exporters)
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
+
+// CloseAuditExporters stops the exporters
+func CloseAuditExporters(exporters []*AuditExporter) {
+	for _, exporter := range exporters {
+		if err := exporter.Close(); err != nil {
+			hlog.Warnf("Failed to close audit sink %s: %v", exporter.name, err)
+		}
+	}
+}
+
+// Close stops the exporter after the batch in flight and closes the sink
+func (e *AuditExporter) Close() error {
+	close(e.done)
+	<-e.stopped
+	return e.sink.Close()
+}
+
+// Stats returns the counters of the exporter
+func (e *AuditExporter) Stats() AuditExporterStats {
+	return AuditExporterStats{
+		Sink:      e.name,
+		Delivered: e.delivered.Load(),
+		Failed:    e.failed.Load(),
+		Pending:   e.pending.Load(),
+		Lag:       time.Duration(e.lag.Load()),
+	}
+}
+
+func (e *AuditExporter) run() {
+	defer close(e.stopped)
+
+	wait := e.interval
+	backoff := time.Duration(0)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-timer.C:
+		}
+
+		if err := e.export(); err != nil {
+			e.failed.Add(1)
+			backoff = min(max(2*backoff, e.interval), e.maxBackoff)
+			wait = backoff
+			var retryAfter *auditRetryAfterError
+			if errors.As(err, &retryAfter) && retryAfter.after > wait {
+				wait = min(retryAfter.after, e.maxBackoff)
+			}
+			hlog.Warnf("Failed to ship audit events to %s, retrying in %s: %v", e.name, wait, err)
+		} else {
+			backoff = 0
+			wait = e.interval
+		}
+
+		e.updateBacklog()
+		timer.Reset(wait)
+	}
+}
+
+// export ships batches until the sink has caught up or fails
+func (e *AuditExporter) export() error {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), auditSendTimeout)
+		sent, ok, err := e.auditRepo.ExportPending(ctx, e.name, e.batchSize, auditSettleDelay,
+			func(events []models.AuditEvent) error {
+				return e.sink.Send(ctx, events)
+			})
+		cancel()
+		if err != nil {
+			return err
+		}
+		e.delivered.Add(uint64(sent)) // #nosec G115 -- sent is never negative
+
+		// Another instance holds the cursor, or the backlog is drained
+		if !ok || sent < e.batchSize {
+			return nil
+		}
+
+		select {
+		case <-e.done:
+			return nil
+		default:
+		}
+	}
+}
+
+func (e *AuditExporter) updateBacklog() {
+	ctx, cancel := context.WithTimeout(context.Background(), auditSetupTimeout)
+	defer cancel()
+
+	pending, lag, err := e.auditRepo.CountPending(ctx, e.name)
+	if err != nil {
+		hlog.Warnf("Failed to count pending audit events of %s: %v", e.name, err)
+		return
+	}
+	e.pending.Store(pending)
+	e.lag.Store(int64(lag))
+}
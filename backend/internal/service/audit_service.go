@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+type auditContextKey struct{}
+
+// auditContext is the origin of a request, attached by the middleware
+type auditContext struct {
+	actorID   *uuid.UUID
+	actorType models.AuditActorType
+	ip        string
+	userAgent string
+	requestID string
+}
+
+// WithAuditRequest attaches the client of a request to ctx, so the events
+// recorded while serving it carry the IP, user agent and request ID
+func WithAuditRequest(ctx context.Context, ip, userAgent, requestID string) context.Context {
+	origin := auditFromContext(ctx)
+	origin.ip = ip
+	origin.userAgent = userAgent
+	origin.requestID = requestID
+	return context.WithValue(ctx, auditContextKey{}, origin)
+}
+
+// WithAuditActor attaches the authenticated actor of a request to ctx
+func WithAuditActor(ctx context.Context, actorType models.AuditActorType, actorID *uuid.UUID) context.Context {
+	origin := auditFromContext(ctx)
+	origin.actorType = actorType
+	origin.actorID = actorID
+	return context.WithValue(ctx, auditContextKey{}, origin)
+}
+
+func auditFromContext(ctx context.Context) auditContext {
+	origin, ok := ctx.Value(auditContextKey{}).(auditContext)
+	if !ok {
+		return auditContext{actorType: models.AuditActorSystem}
+	}
+	return origin
+}
+
+// AuditService appends security relevant actions to the audit log. The
+// actor and client are taken from the context of the request. Recording
+// never fails the action, a failed write is logged instead. A nil service
+// records nothing.
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(auditRepo *repository.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// AuditEntry describes an audited action. Actor is only needed when the
+// context has none yet, e.g. at login.
+type AuditEntry struct {
+	Metadata    map[string]interface{}
+	Actor       *uuid.UUID
+	WorkspaceID *uuid.UUID
+	Action      string
+	TargetType  string
+	TargetID    string
+}
+
+// Record appends an entry to the audit log
+func (s *AuditService) Record(ctx context.Context, entry *AuditEntry) {
+	if s == nil {
+		return
+	}
+
+	origin := auditFromContext(ctx)
+	event := &models.AuditEvent{
+		ID:          uuid.New(),
+		Action:      entry.Action,
+		ActorType:   origin.actorType,
+		ActorID:     origin.actorID,
+		WorkspaceID: entry.WorkspaceID,
+		TargetType:  entry.TargetType,
+		TargetID:    entry.TargetID,
+		IPAddress:   origin.ip,
+		UserAgent:   origin.userAgent,
+		RequestID:   origin.requestID,
+		Metadata:    entry.Metadata,
+	}
+	if entry.Actor != nil {
+		event.ActorType = models.AuditActorUser
+		event.ActorID = entry.Actor
+	}
+
+	// The action already happened, the entry outlives a cancelled request
+	if err := s.auditRepo.Insert(context.WithoutCancel(ctx), event); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to record audit event %s: %v", entry.Action, err)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	splunkEventPath     = "/services/collector/event"
+	splunkMaxErrorBody  = 512
+	defaultSplunkSource = "hertzboard"
+	defaultSplunkType   = "hertzboard:audit"
+)
+
+// SplunkAuditSink sends batches of events to a Splunk HTTP Event Collector
+// in one request each
+type SplunkAuditSink struct {
+	httpClient *http.Client
+	endpoint   string
+	token      string
+	index      string
+	source     string
+	sourceType string
+	host       string
+}
+
+// splunkEvent is the envelope of an event in the HEC format
+type splunkEvent struct {
+	Event      *models.AuditEvent `json:"event"`
+	Host       string             `json:"host,omitempty"`
+	Source     string             `json:"source,omitempty"`
+	SourceType string             `json:"sourcetype,omitempty"`
+	Index      string             `json:"index,omitempty"`
+	Time       float64            `json:"time"`
+}
+
+// NewSplunkAuditSink creates a sink for the collector of cfg
+func NewSplunkAuditSink(cfg *config.AuditSplunkConfig) (*SplunkAuditSink, error) {
+	if cfg.URL == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("splunk url and token are required")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // opt-in for self-signed collector certificates
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	source := cfg.Source
+	if source == "" {
+		source = defaultSplunkSource
+	}
+	sourceType := cfg.SourceType
+	if sourceType == "" {
+		sourceType = defaultSplunkType
+	}
+
+	host, _ := os.Hostname()
+
+	return &SplunkAuditSink{
+		httpClient: &http.Client{Timeout: auditSendTimeout, Transport: tracing.NewTransport(transport)},
+		endpoint:   strings.TrimSuffix(cfg.URL, "/") + splunkEventPath,
+		token:      cfg.Token,
+		index:      cfg.Index,
+		source:     source,
+		sourceType: sourceType,
+		host:       host,
+	}, nil
+}
+
+// Send posts the events as concatenated JSON objects
+func (s *SplunkAuditSink) Send(ctx context.Context, events []models.AuditEvent) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i := range events {
+		event := &events[i]
+		if err := encoder.Encode(&splunkEvent{
+			Event:      event,
+			Host:       s.host,
+			Source:     s.source,
+			SourceType: s.sourceType,
+			Index:      s.index,
+			Time:       float64(event.CreatedAt.UnixMilli()) / 1000,
+		}); err != nil {
+			return fmt.Errorf("failed to marshal audit event: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create splunk request: %w", err)
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call splunk: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	errBody, _ := io.ReadAll(io.LimitReader(resp.Body, splunkMaxErrorBody))
+	err = fmt.Errorf("splunk responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(errBody))
+
+	// The collector is overloaded or its queue is full
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+			return &auditRetryAfterError{err: err, after: time.Duration(seconds) * time.Second}
+		}
+	}
+	return err
+}
+
+// Close releases idle connections
+func (s *SplunkAuditSink) Close() error {
+	s.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
@@ -12,22 +14,50 @@ import (
 	"github.com/bifshteksex/hertz-board/internal/repository"
 )
 
+// ErrUserDeactivated is returned when a user deactivated by the identity
+// provider signs in
+var ErrUserDeactivated = errors.New("account is deactivated")
+
+// ldapProvider is the provider of users that log in through LDAP
+const ldapProvider = "ldap"
+
 // AuthService handles authentication logic
 type AuthService struct {
-	userRepo   *repository.UserRepository
-	jwtService *JWTService
+	userRepo      *repository.UserRepository
+	workspaceRepo *repository.WorkspaceRepository
+	jwtService    *JWTService
+	ldap          *LDAPAuthenticator // nil when LDAP is disabled
+	consent       *ConsentService
+	audit         *AuditService
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(userRepo *repository.UserRepository, jwtService *JWTService) *AuthService {
+// NewAuthService creates a new auth service. ldap is nil when logins are
+// only checked against local passwords.
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	jwtService *JWTService,
+	ldap *LDAPAuthenticator,
+	consent *ConsentService,
+	audit *AuditService,
+) *AuthService {
 	return &AuthService{
-		userRepo:   userRepo,
-		jwtService: jwtService,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+		jwtService:    jwtService,
+		ldap:          ldap,
+		consent:       consent,
+		audit:         audit,
 	}
 }
 
-// Register registers a new user
-func (s *AuthService) Register(ctx context.Context, req *models.CreateUserRequest) (*models.AuthResponse, error) {
+// Register registers a new user. When legal documents are configured the
+// user must accept them and the acceptance is recorded with the client IP.
+func (s *AuthService) Register(ctx context.Context, req *models.CreateUserRequest, clientIP string) (*models.AuthResponse, error) {
+	if s.consent.Required() && !req.AcceptTerms {
+		return nil, ErrConsentRequired
+	}
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
@@ -56,6 +86,18 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 		return nil, fmt.Errorf("failed to create user: %w", createErr)
 	}
 
+	// Without a record the user is asked to accept again on the next visit
+	if consentErr := s.consent.RecordCurrent(ctx, user.ID, clientIP); consentErr != nil {
+		hlog.CtxErrorf(ctx, "Failed to record consent of user %s: %v", user.ID, consentErr)
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditUserRegistered,
+		Actor:      &user.ID,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	})
+
 	// Generate tokens
 	tokens, err := s.generateTokenPair(ctx, user)
 	if err != nil {
@@ -68,8 +110,40 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 	}, nil
 }
 
-// Login authenticates a user
+// Login authenticates a user. With LDAP enabled the directory checks the
+// password, and users that aren't in it fall back to their local password
+// when the config allows it. Successful and failed logins are audited.
 func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
+	resp, err := s.login(ctx, req)
+	if err != nil {
+		s.audit.Record(ctx, &AuditEntry{
+			Action:     models.AuditUserLoginFailed,
+			TargetType: "user",
+			Metadata:   map[string]interface{}{"email": req.Email, "reason": err.Error()},
+		})
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditUserLogin,
+		Actor:      &resp.User.ID,
+		TargetType: "user",
+		TargetID:   resp.User.ID.String(),
+	})
+	return resp, nil
+}
+
+func (s *AuthService) login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
+	if s.ldap != nil {
+		resp, err := s.loginLDAP(ctx, req)
+		if !errors.Is(err, ErrLDAPUserNotFound) {
+			return resp, err
+		}
+		if !s.ldap.LocalFallback() {
+			return nil, fmt.Errorf("invalid credentials")
+		}
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
@@ -88,6 +162,9 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	if !verifyPassword(*user.PasswordHash, req.Password) {
 		return nil, fmt.Errorf("invalid credentials")
 	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
 
 	// Generate tokens
 	tokens, err := s.generateTokenPair(ctx, user)
@@ -101,6 +178,59 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	}, nil
 }
 
+// loginLDAP authenticates a user against the directory. Users are created
+// on their first login and matched by DN or email address after that, and
+// the memberships their groups grant are updated on every login.
+func (s *AuthService) loginLDAP(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
+	identity, err := s.ldap.Authenticate(ctx, req.Email, req.Password)
+	if errors.Is(err, ErrLDAPInvalidCredentials) {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByProvider(ctx, ldapProvider, identity.DN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by provider: %w", err)
+	}
+	if user == nil {
+		user, err = s.userRepo.GetByEmail(ctx, identity.Email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+	}
+	if user == nil {
+		user = &models.User{
+			Email:         identity.Email,
+			Name:          identity.Name,
+			Provider:      ldapProvider,
+			ProviderID:    &identity.DN,
+			EmailVerified: true, // the directory owns the address
+		}
+		if createErr := s.userRepo.Create(ctx, user); createErr != nil {
+			return nil, fmt.Errorf("failed to create user: %w", createErr)
+		}
+	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
+
+	if err := s.workspaceRepo.SyncLDAPMemberships(ctx, user.ID, identity.Roles); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.generateTokenPair(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	return &models.AuthResponse{
+		User:   user,
+		Tokens: tokens,
+	}, nil
+}
+
 // RefreshToken refreshes access token using refresh token
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
 	// Hash the refresh token
@@ -123,6 +253,9 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*m
 	if user == nil {
 		return nil, fmt.Errorf("user not found")
 	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
 
 	// Delete old refresh token
 	if deleteErr := s.userRepo.DeleteRefreshToken(ctx, tokenHash); deleteErr != nil {
@@ -214,9 +347,25 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword stri
 		return fmt.Errorf("failed to delete user sessions: %w", err)
 	}
 
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditUserPasswordReset,
+		Actor:      &resetToken.UserID,
+		TargetType: "user",
+		TargetID:   resetToken.UserID.String(),
+	})
+
 	return nil
 }
 
+// PasswordChanged audits a password change of a signed in user
+func (s *AuthService) PasswordChanged(ctx context.Context, userID uuid.UUID) {
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditUserPasswordChanged,
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+}
+
 // generateTokenPair generates access and refresh token pair
 func (s *AuthService) generateTokenPair(ctx context.Context, user *models.User) (*models.TokenPair, error) {
 	// Generate access token
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	bytesPerMB = 1024 * 1024
+	bytesPerGB = 1024 * bytesPerMB
+)
+
+// Stripe subscription statuses that keep the paid plan. Past due
+// subscriptions keep it while Stripe retries the payment.
+var entitledSubscriptionStatuses = map[string]bool{
+	"active":   true,
+	"trialing": true,
+	"past_due": true,
+}
+
+var (
+	// ErrPlanLimitReached is returned when an action would exceed the
+	// entitlements of the workspace owner's plan
+	ErrPlanLimitReached = errors.New("plan limit reached")
+	// ErrInvalidPlan is returned for unknown plans and plans without a price
+	ErrInvalidPlan = errors.New("plan must be pro or team")
+	// ErrAlreadySubscribed is returned when a user with a paid plan checks
+	// out again instead of changing the plan in the billing portal
+	ErrAlreadySubscribed = errors.New("already subscribed, change the plan in the billing portal")
+	// ErrNoSubscription is returned when a user without a Stripe customer
+	// opens the billing portal
+	ErrNoSubscription = errors.New("no subscription")
+	// ErrInvalidStripeEvent is returned for webhook payloads that can't be
+	// parsed
+	ErrInvalidStripeEvent = errors.New("invalid stripe event")
+)
+
+// stripeEvent is the envelope of Stripe webhook events
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// stripeSubscription holds the subscription fields billing stores. The
+// period end moved to the items in newer API versions, both are read.
+type stripeSubscription struct {
+	ID                string `json:"id"`
+	Customer          string `json:"customer"`
+	Status            string `json:"status"`
+	CurrentPeriodEnd  int64  `json:"current_period_end"`
+	CancelAtPeriodEnd bool   `json:"cancel_at_period_end"`
+	Items             struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// BillingService sells plans through Stripe and resolves the entitlements
+// of workspaces from the plan of their owner
+type BillingService struct {
+	cfg              *config.BillingConfig
+	subscriptionRepo *repository.SubscriptionRepository
+	userRepo         *repository.UserRepository
+	stripe           *stripeClient
+	frontendURL      string
+}
+
+// NewBillingService creates a new billing service
+func NewBillingService(
+	cfg *config.BillingConfig,
+	subscriptionRepo *repository.SubscriptionRepository,
+	userRepo *repository.UserRepository,
+	frontendURL string,
+) (*BillingService, error) {
+	if cfg.SecretKey == "" || cfg.WebhookSecret == "" {
+		return nil, fmt.Errorf("billing requires billing.secret_key and billing.webhook_secret")
+	}
+	for _, plan := range []models.Plan{models.PlanPro, models.PlanTeam} {
+		if cfg.Plans[string(plan)].PriceID == "" {
+			return nil, fmt.Errorf("billing requires a price_id for the %s plan", plan)
+		}
+	}
+
+	return &BillingService{
+		cfg:              cfg,
+		subscriptionRepo: subscriptionRepo,
+		userRepo:         userRepo,
+		stripe:           newStripeClient(cfg.APIURL, cfg.SecretKey),
+		frontendURL:      frontendURL,
+	}, nil
+}
+
+// Entitlements returns the limits of a plan
+func (s *BillingService) Entitlements(plan models.Plan) models.Entitlements {
+	planCfg := s.cfg.Plans[string(plan)]
+	return models.Entitlements{
+		Plan:              plan,
+		StorageQuotaBytes: planCfg.StorageQuotaMB * bytesPerMB,
+		MaxMembers:        planCfg.MaxMembers,
+		MaxSnapshots:      planCfg.MaxSnapshots,
+		IncludedEditors:   planCfg.IncludedEditors,
+		IncludedExports:   planCfg.IncludedExports,
+	}
+}
+
+// effectivePlan is the plan a subscription entitles to, free once it
+// lapsed
+func effectivePlan(sub *models.Subscription) models.Plan {
+	if sub == nil || !entitledSubscriptionStatuses[sub.Status] {
+		return models.PlanFree
+	}
+	return sub.Plan
+}
+
+// WorkspaceEntitlements returns the limits of a workspace, those of the
+// plan of its owner
+func (s *BillingService) WorkspaceEntitlements(ctx context.Context, workspaceID uuid.UUID) (*models.Entitlements, error) {
+	sub, err := s.subscriptionRepo.GetWorkspaceOwnerSubscription(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	entitlements := s.Entitlements(effectivePlan(sub))
+	return &entitlements, nil
+}
+
+// GetBilling returns the subscription and entitlements of a user
+func (s *BillingService) GetBilling(ctx context.Context, userID uuid.UUID) (*models.BillingResponse, error) {
+	sub, err := s.subscriptionRepo.GetSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BillingResponse{
+		Subscription: sub,
+		Entitlements: s.Entitlements(effectivePlan(sub)),
+	}, nil
+}
+
+// CreateCheckoutSession starts a Stripe Checkout for a paid plan and returns
+// the page to redirect the user to. The plan is applied by the webhook once
+// the payment succeeded.
+func (s *BillingService) CreateCheckoutSession(
+	ctx context.Context,
+	userID uuid.UUID,
+	req *models.CheckoutRequest,
+) (*models.BillingSessionResponse, error) {
+	if req.Plan != models.PlanPro && req.Plan != models.PlanTeam {
+		return nil, ErrInvalidPlan
+	}
+
+	sub, err := s.subscriptionRepo.GetSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if effectivePlan(sub) != models.PlanFree {
+		return nil, ErrAlreadySubscribed
+	}
+
+	if sub == nil {
+		sub, err = s.createCustomer(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	checkoutURL, err := s.stripe.createCheckoutSession(ctx,
+		sub.StripeCustomerID,
+		s.cfg.Plans[string(req.Plan)].PriceID,
+		userID.String(),
+		s.frontendURL+"/settings/billing?checkout=success",
+		s.frontendURL+"/settings/billing",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkout session: %w", err)
+	}
+
+	return &models.BillingSessionResponse{URL: checkoutURL}, nil
+}
+
+// createCustomer creates the Stripe customer of a user on their first
+// checkout. The user stays on the free plan until a subscription starts.
+func (s *BillingService) createCustomer(ctx context.Context, userID uuid.UUID) (*models.Subscription, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	customerID, err := s.stripe.createCustomer(ctx, user.Email, user.Name, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stripe customer: %w", err)
+	}
+
+	sub := &models.Subscription{
+		UserID:           userID,
+		Plan:             models.PlanFree,
+		Status:           "incomplete",
+		StripeCustomerID: customerID,
+	}
+	if err := s.subscriptionRepo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// CreatePortalSession opens the Stripe billing portal of a user, where they
+// change or cancel their plan
+func (s *BillingService) CreatePortalSession(ctx context.Context, userID uuid.UUID) (*models.BillingSessionResponse, error) {
+	sub, err := s.subscriptionRepo.GetSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrNoSubscription
+	}
+
+	portalURL, err := s.stripe.createPortalSession(ctx, sub.StripeCustomerID, s.frontendURL+"/settings/billing")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create billing portal session: %w", err)
+	}
+
+	return &models.BillingSessionResponse{URL: portalURL}, nil
+}
+
+// HandleWebhook verifies a Stripe webhook and applies subscription changes.
+// Events are recorded once handled, so redeliveries are skipped. Errors make
+// Stripe retry the webhook.
+func (s *BillingService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if err := verifyStripeSignature(payload, signature, s.cfg.WebhookSecret, time.Now()); err != nil {
+		return err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" {
+		return ErrInvalidStripeEvent
+	}
+
+	processed, err := s.subscriptionRepo.IsEventProcessed(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	switch event.Type {
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		if err := s.applySubscription(ctx, event.Data.Object); err != nil {
+			return err
+		}
+	default:
+		hlog.CtxDebugf(ctx, "Ignoring stripe event %s of type %s", event.ID, event.Type)
+	}
+
+	return s.subscriptionRepo.MarkEventProcessed(ctx, event.ID, event.Type)
+}
+
+// applySubscription stores the state of a Stripe subscription with the
+// plan of its price. Deleted subscriptions arrive with the canceled status.
+func (s *BillingService) applySubscription(ctx context.Context, object json.RawMessage) error {
+	var stripeSub stripeSubscription
+	if err := json.Unmarshal(object, &stripeSub); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStripeEvent, err)
+	}
+
+	existing, err := s.subscriptionRepo.GetSubscriptionByCustomer(ctx, stripeSub.Customer)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		// Customers created outside of the app, e.g. in the dashboard
+		hlog.CtxWarnf(ctx, "Ignoring subscription %s of unknown stripe customer %s", stripeSub.ID, stripeSub.Customer)
+		return nil
+	}
+
+	plan := models.PlanFree
+	periodEnd := stripeSub.CurrentPeriodEnd
+	if len(stripeSub.Items.Data) > 0 {
+		item := stripeSub.Items.Data[0]
+		plan = s.planOfPrice(item.Price.ID)
+		if periodEnd == 0 {
+			periodEnd = item.CurrentPeriodEnd
+		}
+	}
+
+	sub := &models.Subscription{
+		StripeCustomerID:     stripeSub.Customer,
+		StripeSubscriptionID: &stripeSub.ID,
+		Plan:                 plan,
+		Status:               stripeSub.Status,
+		CancelAtPeriodEnd:    stripeSub.CancelAtPeriodEnd,
+	}
+	if periodEnd > 0 {
+		end := time.Unix(periodEnd, 0)
+		sub.CurrentPeriodEnd = &end
+	}
+
+	if err := s.subscriptionRepo.UpdateSubscription(ctx, sub); err != nil {
+		return err
+	}
+
+	hlog.CtxInfof(ctx, "User %s is on the %s plan (subscription %s)", existing.UserID, effectivePlan(sub), sub.Status)
+	return nil
+}
+
+// planOfPrice maps a Stripe price to the plan it sells, free for prices of
+// no configured plan
+func (s *BillingService) planOfPrice(priceID string) models.Plan {
+	for name, plan := range s.cfg.Plans {
+		if plan.PriceID != "" && plan.PriceID == priceID && models.Plan(name).Valid() {
+			return models.Plan(name)
+		}
+	}
+	return models.PlanFree
+}
+
+// Overage returns the usage of a month above the entitlements of a plan.
+// Storage above the quota, left after a downgrade, is counted in started GB.
+func (s *BillingService) Overage(entitlements *models.Entitlements, usage *models.UsagePeriod) *models.UsageOverage {
+	overage := &models.UsageOverage{}
+	if entitlements.IncludedEditors > 0 && usage.ActiveEditors > entitlements.IncludedEditors {
+		overage.ActiveEditors = usage.ActiveEditors - entitlements.IncludedEditors
+	}
+	if entitlements.IncludedExports > 0 && usage.ExportsRendered > entitlements.IncludedExports {
+		overage.Exports = usage.ExportsRendered - entitlements.IncludedExports
+	}
+	if entitlements.StorageQuotaBytes > 0 && usage.StoragePeakBytes > entitlements.StorageQuotaBytes {
+		overage.StorageGB = (usage.StoragePeakBytes - entitlements.StorageQuotaBytes + bytesPerGB - 1) / bytesPerGB
+	}
+	return overage
+}
+
+// ReportUsage bills the overage of a closed month to the subscription of
+// the workspace owner through the configured Stripe meters. Workspaces of
+// owners without a paid plan have nothing to bill.
+func (s *BillingService) ReportUsage(ctx context.Context, usage *models.UsagePeriod) error {
+	sub, err := s.subscriptionRepo.GetWorkspaceOwnerSubscription(ctx, usage.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	plan := effectivePlan(sub)
+	if plan == models.PlanFree {
+		return nil
+	}
+
+	entitlements := s.Entitlements(plan)
+	overage := s.Overage(&entitlements, usage)
+
+	meters := []struct {
+		dimension string
+		meter     string
+		value     int64
+	}{
+		{"active_editors", s.cfg.Meters.ActiveEditors, int64(overage.ActiveEditors)},
+		{"storage_gb", s.cfg.Meters.StorageGB, overage.StorageGB},
+		{"exports", s.cfg.Meters.Exports, int64(overage.Exports)},
+	}
+	for _, m := range meters {
+		if m.meter == "" || m.value <= 0 {
+			continue
+		}
+
+		identifier := fmt.Sprintf("%s:%s:%s", usage.WorkspaceID, usage.PeriodStart.Format("2006-01"), m.dimension)
+		if err := s.stripe.createMeterEvent(ctx, m.meter, sub.StripeCustomerID, identifier, m.value); err != nil {
+			return fmt.Errorf("failed to report %s overage: %w", m.dimension, err)
+		}
+		hlog.CtxInfof(ctx, "Reported %d %s overage of workspace %s", m.value, m.dimension, usage.WorkspaceID)
+	}
+
+	return nil
+}
+
+// checkLimit returns ErrPlanLimitReached when count plus added exceeds a
+// limit of the workspace's plan. Zero limits are unlimited.
+func checkLimit(entitlements *models.Entitlements, what string, count, added, limit int) error {
+	if limit <= 0 || count+added <= limit {
+		return nil
+	}
+	return fmt.Errorf("%w: the %s plan allows %d %s per workspace", ErrPlanLimitReached, entitlements.Plan, limit, what)
+}
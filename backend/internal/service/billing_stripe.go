@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStripeAPIURL = "https://api.stripe.com"
+	stripeTimeout       = 15 * time.Second
+	// stripeWebhookTolerance is how old a signed webhook may be, to limit replays
+	stripeWebhookTolerance = 5 * time.Minute
+	maxStripeErrorBody     = 4096
+)
+
+// stripeClient calls the parts of the Stripe API billing needs. Requests
+// are form encoded, responses JSON.
+type stripeClient struct {
+	httpClient *http.Client
+	apiURL     string
+	secretKey  string
+}
+
+func newStripeClient(apiURL, secretKey string) *stripeClient {
+	if apiURL == "" {
+		apiURL = defaultStripeAPIURL
+	}
+	return &stripeClient{
+		httpClient: &http.Client{Timeout: stripeTimeout},
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		secretKey:  secretKey,
+	}
+}
+
+type stripeObject struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+type stripeErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// post sends a form to an API path and decodes the object it returns
+func (c *stripeClient) post(ctx context.Context, path string, form url.Values) (*stripeObject, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxStripeErrorBody))
+		var stripeErr stripeErrorResponse
+		if json.Unmarshal(body, &stripeErr) == nil && stripeErr.Error.Message != "" {
+			return nil, fmt.Errorf("stripe returned %d: %s", resp.StatusCode, stripeErr.Error.Message)
+		}
+		return nil, fmt.Errorf("stripe returned %d", resp.StatusCode)
+	}
+
+	var object stripeObject
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return nil, fmt.Errorf("failed to decode stripe response: %w", err)
+	}
+
+	return &object, nil
+}
+
+// createCustomer creates the Stripe customer of a user
+func (c *stripeClient) createCustomer(ctx context.Context, email, name, userID string) (string, error) {
+	customer, err := c.post(ctx, "/v1/customers", url.Values{
+		"email":             {email},
+		"name":              {name},
+		"metadata[user_id]": {userID},
+	})
+	if err != nil {
+		return "", err
+	}
+	return customer.ID, nil
+}
+
+// createCheckoutSession creates a hosted subscription checkout of a price
+// and returns its URL
+func (c *stripeClient) createCheckoutSession(
+	ctx context.Context,
+	customerID, priceID, userID, successURL, cancelURL string,
+) (string, error) {
+	session, err := c.post(ctx, "/v1/checkout/sessions", url.Values{
+		"mode":                                 {"subscription"},
+		"customer":                             {customerID},
+		"client_reference_id":                  {userID},
+		"line_items[0][price]":                 {priceID},
+		"line_items[0][quantity]":              {"1"},
+		"subscription_data[metadata][user_id]": {userID},
+		"success_url":                          {successURL},
+		"cancel_url":                           {cancelURL},
+	})
+	if err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// createPortalSession creates a billing portal session where customers
+// change their plan, payment method or cancel, and returns its URL
+func (c *stripeClient) createPortalSession(ctx context.Context, customerID, returnURL string) (string, error) {
+	session, err := c.post(ctx, "/v1/billing_portal/sessions", url.Values{
+		"customer":   {customerID},
+		"return_url": {returnURL},
+	})
+	if err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// createMeterEvent reports usage to a billing meter. Stripe ignores events
+// with an identifier it has seen, so retries don't bill twice.
+func (c *stripeClient) createMeterEvent(ctx context.Context, eventName, customerID, identifier string, value int64) error {
+	_, err := c.post(ctx, "/v1/billing/meter_events", url.Values{
+		"event_name":                  {eventName},
+		"identifier":                  {identifier},
+		"payload[stripe_customer_id]": {customerID},
+		"payload[value]":              {strconv.FormatInt(value, 10)},
+	})
+	return err
+}
+
+// verifyStripeSignature checks the Stripe-Signature header of a webhook: an
+// HMAC-SHA256 of the timestamp and the body with the endpoint secret
+func verifyStripeSignature(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	signedAt := time.Unix(unix, 0)
+	if now.Sub(signedAt) > stripeWebhookTolerance || signedAt.Sub(now) > stripeWebhookTolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,662 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	// maxSectionTitleLength is the longest first line of a section that is
+	// taken as its title
+	maxSectionTitleLength = 80
+	// sectionHeadingLevel is the level of section headings, below the title
+	// of the document. Headings of text elements are pushed below it.
+	sectionHeadingLevel = 2
+	maxHeadingLevel     = 6
+)
+
+// boardFormatter writes the blocks and inline markup of an exported
+// document in one markup language
+type boardFormatter interface {
+	heading(level int, text string) string
+	bullet(depth int, text string) string
+	numbered(depth int, text string) string
+	task(depth int, checked bool, text string) string
+	rule() string
+	codeBlock(text string) string
+
+	escape(text string) string
+	lineBreak() string
+	bold(text string) string
+	italic(text string) string
+	strike(text string) string
+	code(text string) string
+	link(text, href string) string
+}
+
+// newBoardFormatter returns the formatter of an export format
+func newBoardFormatter(format string) (boardFormatter, error) {
+	switch format {
+	case models.ExportFormatMarkdown:
+		return markdownFormatter{}, nil
+	case models.ExportFormatConfluence:
+		return confluenceFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+// exportItem is an element or section placed on the board
+type exportItem struct {
+	element  *models.CanvasElement
+	section  *exportSection
+	position models.Position
+	size     models.Size
+}
+
+// exportSection is a group, or a shape drawn around other elements, and
+// the elements with text it holds
+type exportSection struct {
+	container *models.CanvasElement
+	items     []exportItem
+}
+
+// renderBoardExport renders the text elements, sticky notes and lists of a
+// board as a document, in reading order. Groups and shapes drawn around
+// elements become sections.
+func renderBoardExport(title string, elements []models.CanvasElement, format string) (string, error) {
+	f, err := newBoardFormatter(format)
+	if err != nil {
+		return "", err
+	}
+
+	items := groupExportSections(elements)
+	sortReadingOrder(items)
+
+	w := &exportWriter{f: f}
+	w.block(f.heading(1, f.escape(title)), false)
+
+	sections := 0
+	afterSection := false
+	for _, item := range items {
+		if item.section == nil {
+			// Content after a section doesn't belong to it
+			if afterSection {
+				w.block(f.rule(), false)
+				afterSection = false
+			}
+			w.element(item.element)
+			continue
+		}
+
+		sections++
+		sortReadingOrder(item.section.items)
+		contents := item.section.items
+		heading := "Section " + strconv.Itoa(sections)
+		if text, ok := sectionTitle(contents[0].element); ok {
+			heading = text
+			contents = contents[1:]
+		}
+		w.block(f.heading(sectionHeadingLevel, f.escape(heading)), false)
+		for _, content := range contents {
+			w.element(content.element)
+		}
+		afterSection = true
+	}
+
+	return w.String(), nil
+}
+
+// groupExportSections returns the elements with text that belong to no
+// section and the sections, each with the elements it holds. An element
+// belongs to its group, or else to the smallest shape around its center.
+func groupExportSections(elements []models.CanvasElement) []exportItem {
+	byID := make(map[uuid.UUID]*models.CanvasElement, len(elements))
+	groupOf := make(map[uuid.UUID]uuid.UUID)
+	for i := range elements {
+		byID[elements[i].ID] = &elements[i]
+		if elements[i].ElementType != models.ElementTypeGroup {
+			continue
+		}
+		children, _ := elements[i].ElementData["child_ids"].([]interface{})
+		for _, child := range children {
+			if id, err := uuid.Parse(fmt.Sprint(child)); err == nil {
+				groupOf[id] = elements[i].ID
+			}
+		}
+	}
+
+	sections := make(map[uuid.UUID]*exportSection)
+	var items []exportItem
+	for i := range elements {
+		element := &elements[i]
+		if !hasExportText(element) {
+			continue
+		}
+		position, size := element.ElementData.Bounds()
+		item := exportItem{element: element, position: position, size: size}
+
+		container := exportContainer(element, elements, byID, groupOf)
+		if container == nil {
+			items = append(items, item)
+			continue
+		}
+
+		section, ok := sections[container.ID]
+		if !ok {
+			section = &exportSection{container: container}
+			sections[container.ID] = section
+		}
+		section.items = append(section.items, item)
+	}
+
+	for _, section := range sections {
+		position, size := section.container.ElementData.Bounds()
+		if size.Width == 0 && size.Height == 0 {
+			// Groups without bounds are placed where their elements are
+			position, size = itemsBounds(section.items)
+		}
+		items = append(items, exportItem{section: section, position: position, size: size})
+	}
+	return items
+}
+
+// exportContainer returns the group or shape an element belongs to, nil if
+// none
+func exportContainer(
+	element *models.CanvasElement,
+	elements []models.CanvasElement,
+	byID map[uuid.UUID]*models.CanvasElement,
+	groupOf map[uuid.UUID]uuid.UUID,
+) *models.CanvasElement {
+	if element.ParentID != nil {
+		if parent := byID[*element.ParentID]; parent != nil && parent.ElementType == models.ElementTypeGroup {
+			return parent
+		}
+	}
+	if id, ok := groupOf[element.ID]; ok {
+		return byID[id]
+	}
+
+	position, size := element.ElementData.Bounds()
+	centerX, centerY := position.X+size.Width/2, position.Y+size.Height/2
+	area := size.Width * size.Height
+
+	var container *models.CanvasElement
+	var containerArea float64
+	for i := range elements {
+		if elements[i].ElementType != models.ElementTypeShape {
+			continue
+		}
+		shapePosition, shapeSize := elements[i].ElementData.Bounds()
+		shapeArea := shapeSize.Width * shapeSize.Height
+		inside := centerX >= shapePosition.X && centerX <= shapePosition.X+shapeSize.Width &&
+			centerY >= shapePosition.Y && centerY <= shapePosition.Y+shapeSize.Height
+		if !inside || shapeArea <= area {
+			continue
+		}
+		if container == nil || shapeArea < containerArea {
+			container, containerArea = &elements[i], shapeArea
+		}
+	}
+	return container
+}
+
+// itemsBounds returns the box around items
+func itemsBounds(items []exportItem) (models.Position, models.Size) {
+	var minX, minY, maxX, maxY float64
+	for i, item := range items {
+		right, bottom := item.position.X+item.size.Width, item.position.Y+item.size.Height
+		if i == 0 {
+			minX, minY, maxX, maxY = item.position.X, item.position.Y, right, bottom
+			continue
+		}
+		minX, minY = min(minX, item.position.X), min(minY, item.position.Y)
+		maxX, maxY = max(maxX, right), max(maxY, bottom)
+	}
+	return models.Position{X: minX, Y: minY}, models.Size{Width: maxX - minX, Height: maxY - minY}
+}
+
+// sortReadingOrder sorts items in rows from top to bottom, and from left to
+// right within a row. An item starts a new row when its top is below the
+// middle of the first item of the current row.
+func sortReadingOrder(items []exportItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].position.Y < items[j].position.Y
+	})
+
+	for start := 0; start < len(items); {
+		rowBottom := items[start].position.Y + max(items[start].size.Height, 1)/2
+		end := start + 1
+		for end < len(items) && items[end].position.Y < rowBottom {
+			end++
+		}
+		row := items[start:end]
+		sort.SliceStable(row, func(i, j int) bool {
+			return row[i].position.X < row[j].position.X
+		})
+		start = end
+	}
+}
+
+// hasExportText reports whether an element has text that is exported
+func hasExportText(element *models.CanvasElement) bool {
+	switch element.ElementType {
+	case models.ElementTypeText, models.ElementTypeSticky:
+		return strings.TrimSpace(elementPlainText(element)) != ""
+	case models.ElementTypeList:
+		items, _ := element.ElementData["items"].([]interface{})
+		return len(items) > 0
+	default:
+		return false
+	}
+}
+
+// sectionTitle returns the text of an element that reads like the title of
+// its section: a text element with a single short line
+func sectionTitle(element *models.CanvasElement) (string, bool) {
+	if element.ElementType != models.ElementTypeText {
+		return "", false
+	}
+	text := strings.TrimSpace(elementPlainText(element))
+	if strings.Contains(text, "\n") || utf8.RuneCountInString(text) > maxSectionTitleLength {
+		return "", false
+	}
+	return text, true
+}
+
+// exportWriter collects the blocks of a document. Consecutive list blocks
+// form one list.
+type exportWriter struct {
+	f        boardFormatter
+	b        strings.Builder
+	lastList bool
+}
+
+func (w *exportWriter) block(text string, list bool) {
+	if text == "" {
+		return
+	}
+	switch {
+	case w.b.Len() == 0:
+	case list && w.lastList:
+		w.b.WriteString("\n")
+	default:
+		w.b.WriteString("\n\n")
+	}
+	w.b.WriteString(text)
+	w.lastList = list
+}
+
+func (w *exportWriter) String() string {
+	return w.b.String() + "\n"
+}
+
+// element writes the blocks of an element
+func (w *exportWriter) element(element *models.CanvasElement) {
+	switch element.ElementType {
+	case models.ElementTypeText:
+		content, _ := element.ElementData["content"].(string)
+		if strings.TrimSpace(content) == "" {
+			w.paragraph(elementPlainText(element))
+			return
+		}
+		w.html(content)
+	case models.ElementTypeSticky:
+		w.block(w.f.bullet(0, w.lines(elementPlainText(element))), true)
+	case models.ElementTypeList:
+		w.list(element.ElementData)
+	}
+}
+
+// paragraph writes plain text as a paragraph
+func (w *exportWriter) paragraph(text string) {
+	w.block(w.lines(strings.TrimSpace(text)), false)
+}
+
+// lines escapes text and keeps its line breaks
+func (w *exportWriter) lines(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i := range lines {
+		lines[i] = w.f.escape(strings.TrimSpace(lines[i]))
+	}
+	return strings.Join(lines, w.f.lineBreak())
+}
+
+// list writes the items of a list element
+func (w *exportWriter) list(data models.ElementData) {
+	listType, _ := data["list_type"].(string)
+	items, _ := data["items"].([]interface{})
+
+	var lines []string
+	for _, raw := range items {
+		item, _ := raw.(map[string]interface{})
+		content, _ := item["content"].(string)
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		text := w.lines(content)
+		switch listType {
+		case "numbered":
+			lines = append(lines, w.f.numbered(0, text))
+		case "checkbox":
+			checked, _ := item["checked"].(bool)
+			lines = append(lines, w.f.task(0, checked, text))
+		default:
+			lines = append(lines, w.f.bullet(0, text))
+		}
+	}
+	w.block(strings.Join(lines, "\n"), true)
+}
+
+// html writes the rich text of a text element
+func (w *exportWriter) html(content string) {
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		w.paragraph(html.UnescapeString(htmlTagPattern.ReplaceAllString(content, "")))
+		return
+	}
+
+	var inline []*html.Node
+	flush := func() {
+		if len(inline) > 0 {
+			w.block(strings.TrimSpace(w.inline(inline)), false)
+			inline = nil
+		}
+	}
+	for _, node := range nodes {
+		if node.Type == html.ElementNode && isHTMLBlock(node.DataAtom) {
+			flush()
+			w.htmlBlock(node)
+			continue
+		}
+		inline = append(inline, node)
+	}
+	flush()
+}
+
+// htmlBlock writes a block element of rich text
+func (w *exportWriter) htmlBlock(node *html.Node) {
+	switch node.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(node.Data[1]-'0') + sectionHeadingLevel
+		w.block(w.f.heading(min(level, maxHeadingLevel), strings.TrimSpace(w.inline(children(node)))), false)
+	case atom.Ul, atom.Ol:
+		w.block(strings.Join(w.htmlList(node, 0), "\n"), true)
+	case atom.Pre:
+		w.block(w.f.codeBlock(strings.Trim(textContent(node), "\n")), false)
+	default:
+		// Paragraphs, quotes and divs. Divs may hold further blocks.
+		var inline []*html.Node
+		flush := func() {
+			if len(inline) > 0 {
+				w.block(strings.TrimSpace(w.inline(inline)), false)
+				inline = nil
+			}
+		}
+		for _, child := range children(node) {
+			if child.Type == html.ElementNode && isHTMLBlock(child.DataAtom) {
+				flush()
+				w.htmlBlock(child)
+				continue
+			}
+			inline = append(inline, child)
+		}
+		flush()
+	}
+}
+
+// htmlList returns the lines of a list and its nested lists
+func (w *exportWriter) htmlList(list *html.Node, depth int) []string {
+	var lines []string
+	for _, item := range children(list) {
+		if item.DataAtom != atom.Li {
+			continue
+		}
+
+		var inline, nested []*html.Node
+		checked, isTask := false, false
+		for _, child := range children(item) {
+			switch {
+			case child.DataAtom == atom.Ul || child.DataAtom == atom.Ol:
+				nested = append(nested, child)
+			case child.DataAtom == atom.Input:
+				isTask = true
+				_, checked = htmlAttr(child, "checked")
+			case child.DataAtom == atom.P || child.DataAtom == atom.Div || child.DataAtom == atom.Label:
+				inline = append(inline, children(child)...)
+			default:
+				inline = append(inline, child)
+			}
+		}
+		if state, ok := htmlAttr(item, "data-checked"); ok {
+			isTask, checked = true, state == "true"
+		}
+
+		text := strings.TrimSpace(w.inline(inline))
+		switch {
+		case isTask:
+			lines = append(lines, w.f.task(depth, checked, text))
+		case list.DataAtom == atom.Ol:
+			lines = append(lines, w.f.numbered(depth, text))
+		default:
+			lines = append(lines, w.f.bullet(depth, text))
+		}
+		for _, child := range nested {
+			lines = append(lines, w.htmlList(child, depth+1)...)
+		}
+	}
+	return lines
+}
+
+// inline returns the markup of inline rich text
+func (w *exportWriter) inline(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case html.TextNode:
+			b.WriteString(w.f.escape(strings.Join(strings.Fields(node.Data), " ") + trailingSpace(node.Data)))
+		case html.ElementNode:
+			text := w.inline(children(node))
+			switch node.DataAtom {
+			case atom.Br:
+				b.WriteString(w.f.lineBreak())
+			case atom.Strong, atom.B:
+				b.WriteString(w.wrap(text, w.f.bold))
+			case atom.Em, atom.I:
+				b.WriteString(w.wrap(text, w.f.italic))
+			case atom.S, atom.Strike, atom.Del:
+				b.WriteString(w.wrap(text, w.f.strike))
+			case atom.Code:
+				b.WriteString(w.f.code(textContent(node)))
+			case atom.A:
+				if href, ok := htmlAttr(node, "href"); ok && href != "" {
+					b.WriteString(w.f.link(strings.TrimSpace(text), href))
+				} else {
+					b.WriteString(text)
+				}
+			default:
+				b.WriteString(text)
+			}
+		}
+	}
+	return b.String()
+}
+
+// wrap applies inline markup to text, keeping the spaces around it outside
+// the markup
+func (w *exportWriter) wrap(text string, markup func(string) string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + markup(trimmed) + text[start+len(trimmed):]
+}
+
+func isHTMLBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Blockquote, atom.Pre:
+		return true
+	}
+	return false
+}
+
+func children(node *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+func htmlAttr(node *html.Node, name string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// trailingSpace keeps the space between a text node and the next inline
+// element
+func trailingSpace(text string) string {
+	if strings.TrimSpace(text) != "" && strings.TrimRight(text, " \t\n") != text {
+		return " "
+	}
+	return ""
+}
+
+// markdownFormatter writes GitHub-flavored Markdown, which Notion imports
+type markdownFormatter struct{}
+
+// markdownEscaper escapes the punctuation of Markdown syntax. Escaped
+// punctuation is always literal, so it's escaped wherever it appears.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `|`, `\|`, `~`, `\~`, `#`, `\#`, `-`, `\-`, `+`, `\+`,
+)
+
+func (markdownFormatter) heading(level int, text string) string {
+	return strings.Repeat("#", level) + " " + text
+}
+
+func (markdownFormatter) bullet(depth int, text string) string {
+	return strings.Repeat("  ", depth) + "- " + indentContinuation(text, 2*depth+2)
+}
+
+func (markdownFormatter) numbered(depth int, text string) string {
+	return strings.Repeat("   ", depth) + "1. " + indentContinuation(text, 3*depth+3)
+}
+
+func (markdownFormatter) task(depth int, checked bool, text string) string {
+	box := "[ ] "
+	if checked {
+		box = "[x] "
+	}
+	return strings.Repeat("  ", depth) + "- " + box + indentContinuation(text, 2*depth+2)
+}
+
+func (markdownFormatter) rule() string                  { return "---" }
+func (markdownFormatter) escape(text string) string     { return markdownEscaper.Replace(text) }
+func (markdownFormatter) lineBreak() string             { return "\\\n" }
+func (markdownFormatter) bold(text string) string       { return "**" + text + "**" }
+func (markdownFormatter) italic(text string) string     { return "*" + text + "*" }
+func (markdownFormatter) strike(text string) string     { return "~~" + text + "~~" }
+func (markdownFormatter) link(text, href string) string { return "[" + text + "](<" + href + ">)" }
+
+func (markdownFormatter) code(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+func (markdownFormatter) codeBlock(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + text + "\n" + fence
+}
+
+// indentContinuation indents the lines after the first of a list item so
+// they stay in the item
+func indentContinuation(text string, indent int) string {
+	return strings.ReplaceAll(text, "\n", "\n"+strings.Repeat(" ", indent))
+}
+
+// confluenceFormatter writes Confluence wiki markup
+type confluenceFormatter struct{}
+
+// Backslashes are left alone, two of them are a line break
+var confluenceEscaper = strings.NewReplacer(
+	`*`, `\*`, `_`, `\_`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`,
+	`|`, `\|`, `^`, `\^`, `~`, `\~`, `+`, `\+`, `-`, `\-`, `!`, `\!`, `#`, `\#`,
+)
+
+func (confluenceFormatter) heading(level int, text string) string {
+	return "h" + strconv.Itoa(level) + ". " + text
+}
+
+func (confluenceFormatter) bullet(depth int, text string) string {
+	return strings.Repeat("*", depth+1) + " " + text
+}
+
+func (confluenceFormatter) numbered(depth int, text string) string {
+	return strings.Repeat("#", depth+1) + " " + text
+}
+
+func (confluenceFormatter) task(depth int, checked bool, text string) string {
+	mark := "(x) "
+	if checked {
+		mark = "(/) "
+	}
+	return strings.Repeat("*", depth+1) + " " + mark + text
+}
+
+func (confluenceFormatter) rule() string                  { return "----" }
+func (confluenceFormatter) escape(text string) string     { return confluenceEscaper.Replace(text) }
+func (confluenceFormatter) lineBreak() string             { return `\\ ` }
+func (confluenceFormatter) bold(text string) string       { return "*" + text + "*" }
+func (confluenceFormatter) italic(text string) string     { return "_" + text + "_" }
+func (confluenceFormatter) strike(text string) string     { return "-" + text + "-" }
+func (confluenceFormatter) link(text, href string) string { return "[" + text + "|" + href + "]" }
+
+func (confluenceFormatter) code(text string) string {
+	return "{{" + confluenceEscaper.Replace(text) + "}}"
+}
+
+func (confluenceFormatter) codeBlock(text string) string {
+	return "{noformat}\n" + text + "\n{noformat}"
+}
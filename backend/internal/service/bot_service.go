@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	botTokenPrefix        = "hbt_"
+	botTokenBytes         = 32
+	botTokenDisplayLength = 12
+	maxBotsPerWorkspace   = 20
+	maxTokensPerBot       = 10
+	maxBotNameLength      = 100
+
+	// botEmailDomain is the domain of the placeholder addresses of bot
+	// users, reserved so nothing is ever delivered to them
+	botEmailDomain = "bots.invalid"
+)
+
+var (
+	// ErrBotNotFound is returned for unknown bots
+	ErrBotNotFound = errors.New("bot not found")
+	// ErrBotTokenNotFound is returned for unknown bot tokens
+	ErrBotTokenNotFound = errors.New("bot token not found")
+	// ErrBotLimitReached is returned when a workspace or bot has too many
+	// bots or tokens
+	ErrBotLimitReached = errors.New("bot limit reached")
+	// ErrInvalidBotToken is returned for unknown and expired bot tokens and
+	// tokens of bots that were removed from their workspace
+	ErrInvalidBotToken = errors.New("invalid bot token")
+)
+
+// BotService manages the bot accounts of workspaces and authenticates the
+// requests made with their tokens
+type BotService struct {
+	botRepo       *repository.BotRepository
+	workspaceRepo *repository.WorkspaceRepository
+	audit         *AuditService
+}
+
+// NewBotService creates a new bot service
+func NewBotService(
+	botRepo *repository.BotRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	audit *AuditService,
+) *BotService {
+	return &BotService{
+		botRepo:       botRepo,
+		workspaceRepo: workspaceRepo,
+		audit:         audit,
+	}
+}
+
+// IsBotToken reports whether a bearer token is a bot token rather than a JWT
+func IsBotToken(secret string) bool {
+	return strings.HasPrefix(secret, botTokenPrefix)
+}
+
+// CreateBot creates a bot in a workspace
+func (s *BotService) CreateBot(
+	ctx context.Context,
+	workspaceID, creatorID uuid.UUID,
+	req *models.CreateBotRequest,
+) (*models.Bot, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxBotNameLength {
+		return nil, fmt.Errorf("name is required and must be at most %d characters", maxBotNameLength)
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.WorkspaceRoleEditor
+	}
+	if role != models.WorkspaceRoleEditor && role != models.WorkspaceRoleViewer {
+		return nil, fmt.Errorf("role must be editor or viewer")
+	}
+
+	existing, err := s.botRepo.ListBots(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxBotsPerWorkspace {
+		return nil, ErrBotLimitReached
+	}
+
+	bot := &models.Bot{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Username:    botUsername(name),
+		AvatarURL:   req.AvatarURL,
+		Role:        role,
+		CreatedBy:   &creatorID,
+	}
+	email := fmt.Sprintf("bot-%s@%s", bot.ID, botEmailDomain)
+	if err := s.botRepo.CreateBot(ctx, bot, email); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditBotCreated,
+		WorkspaceID: &workspaceID,
+		TargetType:  "bot",
+		TargetID:    bot.ID.String(),
+		Metadata:    map[string]interface{}{"name": bot.Name, "role": bot.Role},
+	})
+
+	return bot, nil
+}
+
+// ListBots returns the bots of a workspace
+func (s *BotService) ListBots(ctx context.Context, workspaceID uuid.UUID) ([]models.Bot, error) {
+	return s.botRepo.ListBots(ctx, workspaceID)
+}
+
+// DeleteBot removes a bot from its workspace and revokes its tokens. The
+// elements it created are kept.
+func (s *BotService) DeleteBot(ctx context.Context, workspaceID, botID uuid.UUID) error {
+	deleted, err := s.botRepo.DeactivateBot(ctx, workspaceID, botID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrBotNotFound
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditBotDeleted,
+		WorkspaceID: &workspaceID,
+		TargetType:  "bot",
+		TargetID:    botID.String(),
+	})
+	return nil
+}
+
+// CreateToken creates a token of a bot and returns it with the token
+func (s *BotService) CreateToken(
+	ctx context.Context,
+	workspaceID, botID, creatorID uuid.UUID,
+	req *models.CreateBotTokenRequest,
+) (*models.BotTokenWithSecret, error) {
+	if err := validateBotTokenRequest(req); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.GetBot(ctx, workspaceID, botID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.botRepo.ListTokens(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxTokensPerBot {
+		return nil, ErrBotLimitReached
+	}
+
+	b := make([]byte, botTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate bot token: %w", err)
+	}
+	secret := botTokenPrefix + hex.EncodeToString(b)
+
+	elementTypes := req.ElementTypes
+	if elementTypes == nil {
+		elementTypes = []models.ElementType{}
+	}
+	token := &models.BotToken{
+		ID:           uuid.New(),
+		BotID:        botID,
+		WorkspaceID:  workspaceID,
+		Name:         strings.TrimSpace(req.Name),
+		Prefix:       secret[:botTokenDisplayLength],
+		TokenHash:    hashAPIKey(secret),
+		Scopes:       req.Scopes,
+		ElementTypes: elementTypes,
+		CreatedBy:    &creatorID,
+		ExpiresAt:    req.ExpiresAt,
+	}
+
+	if err := s.botRepo.CreateToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditBotTokenCreated,
+		WorkspaceID: &workspaceID,
+		TargetType:  "bot_token",
+		TargetID:    token.ID.String(),
+		Metadata: map[string]interface{}{
+			"bot_id":        botID,
+			"name":          token.Name,
+			"prefix":        token.Prefix,
+			"scopes":        token.Scopes,
+			"element_types": token.ElementTypes,
+		},
+	})
+
+	return &models.BotTokenWithSecret{BotToken: *token, Token: secret}, nil
+}
+
+// ListTokens returns the tokens of a bot
+func (s *BotService) ListTokens(ctx context.Context, workspaceID, botID uuid.UUID) ([]models.BotToken, error) {
+	if _, err := s.GetBot(ctx, workspaceID, botID); err != nil {
+		return nil, err
+	}
+	return s.botRepo.ListTokens(ctx, botID)
+}
+
+// DeleteToken revokes a token of a bot
+func (s *BotService) DeleteToken(ctx context.Context, workspaceID, botID, tokenID uuid.UUID) error {
+	if _, err := s.GetBot(ctx, workspaceID, botID); err != nil {
+		return err
+	}
+
+	deleted, err := s.botRepo.DeleteToken(ctx, botID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrBotTokenNotFound
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditBotTokenDeleted,
+		WorkspaceID: &workspaceID,
+		TargetType:  "bot_token",
+		TargetID:    tokenID.String(),
+		Metadata:    map[string]interface{}{"bot_id": botID},
+	})
+	return nil
+}
+
+// Authenticate returns the bot token of a request. Expired tokens and
+// tokens of bots that are no longer members of their workspace are refused.
+func (s *BotService) Authenticate(ctx context.Context, secret string) (*models.BotToken, error) {
+	if !IsBotToken(secret) {
+		return nil, ErrInvalidBotToken
+	}
+
+	token, err := s.botRepo.GetTokenByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || (token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)) {
+		return nil, ErrInvalidBotToken
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, token.WorkspaceID, token.BotID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrInvalidBotToken
+	}
+
+	if err := s.botRepo.MarkTokenUsed(ctx, token.ID); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to record use of bot token %s: %v", token.ID, err)
+	}
+
+	return token, nil
+}
+
+// GetBot returns a bot of a workspace
+func (s *BotService) GetBot(ctx context.Context, workspaceID, botID uuid.UUID) (*models.Bot, error) {
+	bot, err := s.botRepo.GetBot(ctx, workspaceID, botID)
+	if err != nil {
+		return nil, err
+	}
+	if bot == nil {
+		return nil, ErrBotNotFound
+	}
+	return bot, nil
+}
+
+// validateBotTokenRequest checks the name, scopes, element types and expiry
+// of a new token
+func validateBotTokenRequest(req *models.CreateBotTokenRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxBotNameLength {
+		return fmt.Errorf("name is required and must be at most %d characters", maxBotNameLength)
+	}
+
+	if len(req.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required, one of %s", strings.Join(models.BotScopes, ", "))
+	}
+	for _, scope := range req.Scopes {
+		if !validBotScope(scope) {
+			return fmt.Errorf("unknown scope %q, must be one of %s", scope, strings.Join(models.BotScopes, ", "))
+		}
+	}
+
+	for _, elementType := range req.ElementTypes {
+		if !elementType.Valid() {
+			return fmt.Errorf("unknown element type %q", elementType)
+		}
+	}
+	if len(req.ElementTypes) > 0 {
+		token := models.BotToken{Scopes: req.Scopes}
+		if !token.HasScope(models.BotScopeElementsCreate) {
+			return fmt.Errorf("element_types only apply to tokens with the %s scope", models.BotScopeElementsCreate)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	return nil
+}
+
+func validBotScope(scope string) bool {
+	for _, s := range models.BotScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// botUsername derives the username of a bot from its name, e.g.
+// "CI Deploy" becomes ci-deploy-bot. The database makes it unique.
+func botUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+
+	username := strings.Trim(b.String(), "-")
+	switch {
+	case username == "":
+		return "bot"
+	case !strings.HasSuffix(username, "bot"):
+		return username + "-bot"
+	}
+	return username
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// Realtime transports used for cross-instance fan-out
+const (
+	TransportRedis     = "redis"
+	TransportJetStream = "jetstream"
+)
+
+// BrokerMessage is the envelope exchanged between hub instances. Messages
+// with a UserID go to the clients of that user instead of a workspace room,
+// messages with neither a UserID nor a WorkspaceID go to every room.
+type BrokerMessage struct {
+	Message         *models.WSMessage `json:"message"`
+	WorkspaceID     uuid.UUID         `json:"workspace_id"`
+	UserID          uuid.UUID         `json:"user_id,omitempty"`
+	ExcludeClientID uuid.UUID         `json:"exclude_client_id"`
+	InstanceID      uuid.UUID         `json:"instance_id"`
+
+	// Seq is the stream sequence of the message, set on publish and delivery
+	// by brokers that keep messages. Zero for the others.
+	Seq uint64 `json:"-"`
+}
+
+// Broker fans hub messages out to other server instances
+type Broker interface {
+	// Publish sends a message to all hub instances
+	Publish(ctx context.Context, msg *BrokerMessage) error
+
+	// Subscribe starts delivering messages from all instances to handler.
+	// It returns once the subscription is established.
+	Subscribe(ctx context.Context, handler func(*BrokerMessage)) error
+
+	// Close stops the subscription
+	Close() error
+}
+
+// ReplayBroker is a Broker that keeps workspace messages, so clients that
+// rejoin a room can get the ones they missed
+type ReplayBroker interface {
+	Broker
+
+	// Replay returns up to limit messages of a workspace published after
+	// afterSeq, oldest first. complete is false if some of them already
+	// expired or more than limit are left.
+	Replay(ctx context.Context, workspaceID uuid.UUID, afterSeq uint64, limit int) (msgs []*BrokerMessage, complete bool, err error)
+}
+
+// NewBroker creates the broker selected by websocket.transport config
+func NewBroker(cfg *config.Config, redisClient *redis.Client, natsConn *nats.Conn) (Broker, error) {
+	switch cfg.WebSocket.Transport {
+	case "", TransportRedis:
+		return NewRedisBroker(redisClient), nil
+	case TransportJetStream:
+		if natsConn == nil {
+			return nil, fmt.Errorf("jetstream transport requires a NATS connection")
+		}
+		return NewJetStreamBroker(natsConn, &cfg.NATS.JetStream)
+	default:
+		return nil, fmt.Errorf("unknown realtime transport: %s", cfg.WebSocket.Transport)
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// jetStreamBrokerSubjects are the workspace room and user message subjects
+var jetStreamBrokerSubjects = []string{"workspace.*", "user.*"}
+
+// jetStreamReplayTimeout bounds reading the missed messages of a rejoining client
+const jetStreamReplayTimeout = 5 * time.Second
+
+// JetStreamBroker fans out hub messages over a NATS JetStream stream.
+// Messages are persisted per workspace or user subject for the configured
+// max age, so they survive broker restarts and can be replayed.
+//
+// Every instance reads the stream through durable consumers named after it
+// and acks each message once it has been handed to its rooms, so after a
+// reconnect delivery resumes where it stopped instead of skipping what was
+// published in between. Consumers of instances that are gone are removed by
+// the server after the max age.
+type JetStreamBroker struct {
+	js            nats.JetStreamContext
+	subscriptions []*nats.Subscription
+	streamName    string
+	consumerName  string
+	maxAge        time.Duration
+}
+
+// NewJetStreamBroker creates a JetStream broker and ensures its stream exists
+func NewJetStreamBroker(nc *nats.Conn, cfg *config.JetStreamConfig) (*JetStreamBroker, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	maxAge, err := cfg.GetMaxAgeDuration()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JetStream max age: %w", err)
+	}
+
+	consumerName, err := cfg.GetConsumerName()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JetStream consumer name: %w", err)
+	}
+
+	streamConfig := &nats.StreamConfig{
+		Name:     cfg.StreamName,
+		Subjects: jetStreamBrokerSubjects,
+		Storage:  nats.FileStorage,
+		MaxAge:   maxAge,
+	}
+
+	if err := ensureStream(js, streamConfig); err != nil {
+		return nil, err
+	}
+
+	return &JetStreamBroker{
+		js:           js,
+		streamName:   cfg.StreamName,
+		consumerName: consumerName,
+		maxAge:       maxAge,
+	}, nil
+}
+
+// Publish publishes a message to the workspace subject, or the user subject
+// for user messages, waits for the ack and sets the stream sequence of msg
+func (b *JetStreamBroker) Publish(ctx context.Context, msg *BrokerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal broker message: %w", err)
+	}
+
+	subject := fmt.Sprintf("workspace.%s", msg.WorkspaceID)
+	if msg.UserID != uuid.Nil {
+		subject = fmt.Sprintf("user.%s", msg.UserID)
+	}
+	ack, err := b.js.Publish(subject, data, nats.Context(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to publish to JetStream: %w", err)
+	}
+	msg.Seq = ack.Sequence
+
+	return nil
+}
+
+// Subscribe binds to the durable consumers of this instance, creating them
+// on first start, and acks every message after handler returns
+func (b *JetStreamBroker) Subscribe(ctx context.Context, handler func(*BrokerMessage)) error {
+	for _, subject := range jetStreamBrokerSubjects {
+		durable := fmt.Sprintf("%s-%s", b.consumerName, strings.TrimSuffix(subject, ".*"))
+		if err := b.ensureConsumer(durable, subject); err != nil {
+			return err
+		}
+
+		sub, err := b.js.Subscribe("", func(msg *nats.Msg) {
+			var brokerMsg BrokerMessage
+			if err := json.Unmarshal(msg.Data, &brokerMsg); err != nil {
+				hlog.CtxErrorf(ctx, "Failed to unmarshal JetStream message: %v", err)
+				// Redelivering it would fail the same way
+				if err := msg.Term(); err != nil {
+					hlog.CtxErrorf(ctx, "Failed to terminate JetStream message: %v", err)
+				}
+				return
+			}
+			if meta, err := msg.Metadata(); err == nil {
+				brokerMsg.Seq = meta.Sequence.Stream
+			}
+
+			handler(&brokerMsg)
+
+			if err := msg.Ack(); err != nil {
+				hlog.CtxErrorf(ctx, "Failed to ack JetStream message: %v", err)
+			}
+		}, nats.Bind(b.streamName, durable), nats.ManualAck())
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to JetStream: %w", err)
+		}
+
+		b.subscriptions = append(b.subscriptions, sub)
+	}
+
+	hlog.CtxInfof(ctx, "Started JetStream consumers %s on stream %s", b.consumerName, b.streamName)
+
+	return nil
+}
+
+// Replay reads the messages of a workspace after afterSeq from the stream.
+// Checking the stream first avoids waiting on a consumer for messages of
+// a workspace that has none left to deliver.
+func (b *JetStreamBroker) Replay(ctx context.Context, workspaceID uuid.UUID, afterSeq uint64, limit int) ([]*BrokerMessage, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, jetStreamReplayTimeout)
+	defer cancel()
+
+	info, err := b.js.StreamInfo(b.streamName, nats.Context(ctx))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get JetStream stream info: %w", err)
+	}
+	// Messages after afterSeq may have expired already
+	if afterSeq+1 < info.State.FirstSeq {
+		return nil, false, nil
+	}
+
+	subject := fmt.Sprintf("workspace.%s", workspaceID)
+	last, err := b.js.GetLastMsg(b.streamName, subject, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get last JetStream message: %w", err)
+	}
+	if last.Sequence <= afterSeq {
+		return nil, true, nil
+	}
+
+	sub, err := b.js.SubscribeSync(subject,
+		nats.BindStream(b.streamName), nats.StartSequence(afterSeq+1), nats.AckNone())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to subscribe to JetStream: %w", err)
+	}
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			hlog.CtxWarnf(ctx, "Failed to remove JetStream replay consumer: %v", err)
+		}
+	}()
+
+	var msgs []*BrokerMessage
+	for len(msgs) < limit {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read JetStream message: %w", err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read JetStream message metadata: %w", err)
+		}
+
+		var brokerMsg BrokerMessage
+		if err := json.Unmarshal(msg.Data, &brokerMsg); err == nil {
+			brokerMsg.Seq = meta.Sequence.Stream
+			msgs = append(msgs, &brokerMsg)
+		}
+
+		if meta.NumPending == 0 {
+			return msgs, true, nil
+		}
+	}
+
+	return msgs, false, nil
+}
+
+// Close stops the subscriptions. The durable consumers are kept, so a
+// restarted instance resumes from its last ack.
+func (b *JetStreamBroker) Close() error {
+	var errs []error
+	for _, sub := range b.subscriptions {
+		errs = append(errs, sub.Unsubscribe())
+	}
+	return errors.Join(errs...)
+}
+
+// ensureConsumer creates the durable push consumer of this instance for a
+// subject unless it exists. It starts at new messages and is removed by the
+// server once the instance has been gone for longer than messages are kept.
+func (b *JetStreamBroker) ensureConsumer(durable, subject string) error {
+	_, err := b.js.ConsumerInfo(b.streamName, durable)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrConsumerNotFound) {
+		return fmt.Errorf("failed to get JetStream consumer %s: %w", durable, err)
+	}
+
+	_, err = b.js.AddConsumer(b.streamName, &nats.ConsumerConfig{
+		Durable:           durable,
+		DeliverSubject:    fmt.Sprintf("hub.deliver.%s", durable),
+		DeliverPolicy:     nats.DeliverNewPolicy,
+		AckPolicy:         nats.AckExplicitPolicy,
+		FilterSubject:     subject,
+		InactiveThreshold: b.maxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create JetStream consumer %s: %w", durable, err)
+	}
+	return nil
+}
+
+// ensureStream creates the stream or updates it to the given config
+func ensureStream(js nats.JetStreamContext, streamConfig *nats.StreamConfig) error {
+	_, err := js.StreamInfo(streamConfig.Name)
+	switch {
+	case errors.Is(err, nats.ErrStreamNotFound):
+		_, err = js.AddStream(streamConfig)
+	case err == nil:
+		_, err = js.UpdateStream(streamConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to configure JetStream stream %s: %w", streamConfig.Name, err)
+	}
+	return nil
+}
+
+// retryDelay returns the delay before redelivering a message, doubling the
+// initial backoff after every failed attempt up to maxDelay
+func retryDelay(attempt int, initial, maxDelay time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker fans out hub messages over Redis pub/sub.
+// Delivery is best-effort: instances that are offline miss messages.
+type RedisBroker struct {
+	redis  *redis.Client
+	pubsub *redis.PubSub
+}
+
+// NewRedisBroker creates a new Redis pub/sub broker
+func NewRedisBroker(redisClient *redis.Client) *RedisBroker {
+	return &RedisBroker{
+		redis: redisClient,
+	}
+}
+
+// Publish publishes a message to the workspace channel, or the user channel
+// for user messages
+func (b *RedisBroker) Publish(ctx context.Context, msg *BrokerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal broker message: %w", err)
+	}
+
+	channel := fmt.Sprintf("workspace:%s", msg.WorkspaceID)
+	if msg.UserID != uuid.Nil {
+		channel = fmt.Sprintf("user:%s", msg.UserID)
+	}
+	if err := b.redis.Publish(ctx, channel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish to Redis: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to all workspace and user channels
+func (b *RedisBroker) Subscribe(ctx context.Context, handler func(*BrokerMessage)) error {
+	patterns := []string{"workspace:*", "user:*"}
+	b.pubsub = b.redis.PSubscribe(ctx, patterns...)
+	// Every pattern is confirmed separately
+	for range patterns {
+		if _, err := b.pubsub.Receive(ctx); err != nil {
+			return fmt.Errorf("failed to subscribe to Redis: %w", err)
+		}
+	}
+
+	hlog.CtxInfof(ctx, "Started Redis subscription for workspace and user channels")
+
+	go func() {
+		for msg := range b.pubsub.Channel() {
+			var brokerMsg BrokerMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &brokerMsg); err != nil {
+				hlog.CtxErrorf(ctx, "Failed to unmarshal Redis message: %v", err)
+				continue
+			}
+
+			handler(&brokerMsg)
+		}
+	}()
+
+	return nil
+}
+
+// Close closes the Redis subscription
+func (b *RedisBroker) Close() error {
+	if b.pubsub == nil {
+		return nil
+	}
+	return b.pubsub.Close()
+}
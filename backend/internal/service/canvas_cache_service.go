@@ -65,6 +65,55 @@ func (s *CanvasCacheService) SetWorkspaceElements(ctx context.Context, workspace
 	return nil
 }
 
+// GetWorkspacesElements retrieves the cached elements of several workspaces
+// with one round trip. Workspaces that aren't cached are missing.
+func (s *CanvasCacheService) GetWorkspacesElements(ctx context.Context, workspaceIDs []uuid.UUID) map[uuid.UUID][]models.CanvasElement {
+	keys := make([]string, len(workspaceIDs))
+	for i, workspaceID := range workspaceIDs {
+		keys[i] = fmt.Sprintf(workspaceElementsKey, workspaceID)
+	}
+
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil
+	}
+
+	cached := make(map[uuid.UUID][]models.CanvasElement, len(workspaceIDs))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var elements []models.CanvasElement
+		if err := json.Unmarshal([]byte(data), &elements); err != nil {
+			continue
+		}
+		cached[workspaceIDs[i]] = elements
+	}
+
+	return cached
+}
+
+// SetWorkspacesElements stores the elements of several workspaces in cache
+// with one round trip
+func (s *CanvasCacheService) SetWorkspacesElements(ctx context.Context, elements map[uuid.UUID][]models.CanvasElement) error {
+	_, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for workspaceID, workspaceElements := range elements {
+			data, err := json.Marshal(workspaceElements)
+			if err != nil {
+				return fmt.Errorf("failed to marshal elements: %w", err)
+			}
+			pipe.Set(ctx, fmt.Sprintf(workspaceElementsKey, workspaceID), data, workspaceElementsTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cache elements: %w", err)
+	}
+
+	return nil
+}
+
 // InvalidateWorkspaceElements removes workspace elements from cache
 func (s *CanvasCacheService) InvalidateWorkspaceElements(ctx context.Context, workspaceID uuid.UUID) error {
 	key := fmt.Sprintf(workspaceElementsKey, workspaceID)
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"html"
+	"regexp"
 
 	"github.com/google/uuid"
 
@@ -10,21 +12,35 @@ import (
 	"github.com/bifshteksex/hertz-board/internal/repository"
 )
 
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
 type CanvasService struct {
 	canvasRepo    *repository.CanvasRepository
 	workspaceRepo *repository.WorkspaceRepository
 	cacheService  *CanvasCacheService
+	events        *EventPublisher
+	notifications *NotificationService
+	analytics     *AnalyticsService
+	metering      *MeteringService
 }
 
 func NewCanvasService(
 	canvasRepo *repository.CanvasRepository,
 	workspaceRepo *repository.WorkspaceRepository,
 	cacheService *CanvasCacheService,
+	events *EventPublisher,
+	notifications *NotificationService,
+	analytics *AnalyticsService,
+	metering *MeteringService,
 ) *CanvasService {
 	return &CanvasService{
 		canvasRepo:    canvasRepo,
 		workspaceRepo: workspaceRepo,
 		cacheService:  cacheService,
+		events:        events,
+		notifications: notifications,
+		analytics:     analytics,
+		metering:      metering,
 	}
 }
 
@@ -67,7 +83,12 @@ func (s *CanvasService) CreateElement(
 		}
 	}
 
-	if err := s.canvasRepo.CreateElement(ctx, element); err != nil {
+	// The event and the analytics are only published when the element commits
+	created := s.events.event(ctx, models.EventElementCreated, workspaceID, &userID, models.ElementEventPayload{
+		ElementIDs: []uuid.UUID{element.ID},
+	})
+	tracked := s.analytics.operation(ctx, workspaceID, element.ID, userID, models.OperationTypeCreate)
+	if err := s.canvasRepo.CreateElement(ctx, element, created, tracked); err != nil {
 		return nil, fmt.Errorf("failed to create element: %w", err)
 	}
 
@@ -76,6 +97,9 @@ func (s *CanvasService) CreateElement(
 		_ = s.cacheService.InvalidateWorkspaceElements(ctx, workspaceID)
 	}
 
+	s.syncMentions(ctx, element, userID)
+	s.metering.RecordEditor(workspaceID, userID)
+
 	return element, nil
 }
 
@@ -112,6 +136,48 @@ func (s *CanvasService) GetWorkspaceElements(ctx context.Context, workspaceID uu
 	return elements, nil
 }
 
+// GetElementsByWorkspaces retrieves the elements of several workspaces, the
+// ones that aren't cached with a single query
+func (s *CanvasService) GetElementsByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+) (map[uuid.UUID][]models.CanvasElement, error) {
+	elements := make(map[uuid.UUID][]models.CanvasElement, len(workspaceIDs))
+	if s.cacheService != nil {
+		for workspaceID, cached := range s.cacheService.GetWorkspacesElements(ctx, workspaceIDs) {
+			elements[workspaceID] = cached
+		}
+	}
+
+	var missing []uuid.UUID
+	for _, workspaceID := range workspaceIDs {
+		if _, ok := elements[workspaceID]; !ok {
+			missing = append(missing, workspaceID)
+		}
+	}
+	if len(missing) == 0 {
+		return elements, nil
+	}
+
+	loaded, err := s.canvasRepo.GetElementsByWorkspaces(ctx, missing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace elements: %w", err)
+	}
+
+	// Empty boards are cached too
+	fetched := make(map[uuid.UUID][]models.CanvasElement, len(missing))
+	for _, workspaceID := range missing {
+		fetched[workspaceID] = loaded[workspaceID]
+		elements[workspaceID] = loaded[workspaceID]
+	}
+
+	if s.cacheService != nil {
+		_ = s.cacheService.SetWorkspacesElements(ctx, fetched)
+	}
+
+	return elements, nil
+}
+
 // UpdateElement updates a canvas element
 func (s *CanvasService) UpdateElement(
 	ctx context.Context,
@@ -155,6 +221,12 @@ func (s *CanvasService) UpdateElement(
 		_ = s.cacheService.InvalidateElement(ctx, id)
 	}
 
+	if req.ElementData != nil {
+		s.syncMentions(ctx, element, userID)
+	}
+	s.analytics.TrackOperation(element.WorkspaceID, element.ID, userID, models.OperationTypeUpdate)
+	s.metering.RecordEditor(element.WorkspaceID, userID)
+
 	return element, nil
 }
 
@@ -195,8 +267,21 @@ func (s *CanvasService) DeleteElement(ctx context.Context, id uuid.UUID) error {
 
 // Batch operations
 
+// syncMentions updates the mentions of sticky notes after their content was
+// set by the given user
+func (s *CanvasService) syncMentions(ctx context.Context, element *models.CanvasElement, userID uuid.UUID) {
+	if element.ElementType != models.ElementTypeSticky {
+		return
+	}
+	content, _ := element.ElementData["content"].(string)
+	s.notifications.syncMentions(ctx, element.WorkspaceID, element.ID, userID, content)
+}
+
 const (
 	maxBatchSize = 100
+
+	// elementEncryptionBatchSize is how many elements are encrypted at a time
+	elementEncryptionBatchSize = 500
 )
 
 // BatchCreateElements creates multiple canvas elements
@@ -213,6 +298,12 @@ func (s *CanvasService) BatchCreateElements(
 		return nil, fmt.Errorf("cannot create more than %d elements at once", maxBatchSize)
 	}
 
+	// AI, translation and inbound webhooks create elements through here
+	// without the routes of the board
+	if err := s.checkNotFrozen(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
 	elements := make([]models.CanvasElement, len(req.Elements))
 	for i, createReq := range req.Elements {
 		// Validate element type
@@ -237,7 +328,18 @@ func (s *CanvasService) BatchCreateElements(
 		}
 	}
 
-	if err := s.canvasRepo.BatchCreateElements(ctx, elements); err != nil {
+	ids := make([]uuid.UUID, len(elements))
+	for i := range elements {
+		ids[i] = elements[i].ID
+	}
+	outbox := make([]*models.OutboxMessage, 0, len(elements)+1)
+	outbox = append(outbox, s.events.event(ctx, models.EventElementCreated, workspaceID, &userID,
+		models.ElementEventPayload{ElementIDs: ids}))
+	for i := range elements {
+		outbox = append(outbox, s.analytics.operation(ctx, workspaceID, elements[i].ID, userID, models.OperationTypeCreate))
+	}
+
+	if err := s.canvasRepo.BatchCreateElements(ctx, elements, outbox...); err != nil {
 		return nil, fmt.Errorf("failed to batch create elements: %w", err)
 	}
 
@@ -246,6 +348,11 @@ func (s *CanvasService) BatchCreateElements(
 		_ = s.cacheService.InvalidateWorkspaceElements(ctx, workspaceID)
 	}
 
+	for i := range elements {
+		s.syncMentions(ctx, &elements[i], userID)
+	}
+	s.metering.RecordEditor(workspaceID, userID)
+
 	return elements, nil
 }
 
@@ -305,11 +412,23 @@ func (s *CanvasService) BatchUpdateElements(
 		_ = s.cacheService.InvalidateMultipleElements(ctx, elementIDs)
 	}
 
+	for i, update := range req.Updates {
+		if update.ElementData != nil {
+			s.syncMentions(ctx, &elements[i], userID)
+		}
+		s.analytics.TrackOperation(workspaceID, update.ID, userID, models.OperationTypeUpdate)
+	}
+	s.metering.RecordEditor(workspaceID, userID)
+
 	return elements, nil
 }
 
-// BatchDeleteElements soft deletes multiple canvas elements
-func (s *CanvasService) BatchDeleteElements(ctx context.Context, workspaceID uuid.UUID, req models.BatchDeleteRequest) error {
+// BatchDeleteElements soft deletes multiple canvas elements and their children
+func (s *CanvasService) BatchDeleteElements(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req models.BatchDeleteRequest,
+) error {
 	if len(req.IDs) == 0 {
 		return fmt.Errorf("no elements to delete")
 	}
@@ -355,6 +474,11 @@ func (s *CanvasService) BatchDeleteElements(ctx context.Context, workspaceID uui
 		_ = s.cacheService.InvalidateMultipleElements(ctx, allIDs)
 	}
 
+	for _, id := range allIDs {
+		s.analytics.TrackOperation(workspaceID, id, userID, models.OperationTypeDelete)
+	}
+	s.metering.RecordEditor(workspaceID, userID)
+
 	return nil
 }
 
@@ -403,9 +527,12 @@ func (s *CanvasService) validateElementTypeSpecific(elementType models.ElementTy
 		return s.validateTextElement(data)
 	case models.ElementTypeImage:
 		return s.validateImageElement(data)
+	case models.ElementTypeVideo:
+		return s.validateVideoElement(data)
 	case models.ElementTypeConnector:
 		return s.validateConnectorElement(data)
-	case models.ElementTypeShape, models.ElementTypeDrawing, models.ElementTypeSticky, models.ElementTypeList, models.ElementTypeGroup:
+	case models.ElementTypeShape, models.ElementTypeDrawing, models.ElementTypeSticky, models.ElementTypeList, models.ElementTypeGroup,
+		models.ElementTypeFrame:
 		return nil
 	default:
 		return nil
@@ -426,6 +553,13 @@ func (s *CanvasService) validateImageElement(data models.ElementData) error {
 	return nil
 }
 
+func (s *CanvasService) validateVideoElement(data models.ElementData) error {
+	if _, ok := data["url"]; !ok {
+		return fmt.Errorf("video element must have 'url' field")
+	}
+	return nil
+}
+
 func (s *CanvasService) validateConnectorElement(data models.ElementData) error {
 	if _, hasStart := data["start_element_id"]; !hasStart {
 		if _, hasStartPoint := data["start_point"]; !hasStartPoint {
@@ -439,3 +573,52 @@ func (s *CanvasService) validateConnectorElement(data models.ElementData) error
 	}
 	return nil
 }
+
+// elementPlainText returns the text of text elements and sticky notes, empty
+// for other elements. The HTML of text elements is stripped when they have
+// no plain_text.
+func elementPlainText(element *models.CanvasElement) string {
+	switch element.ElementType {
+	case models.ElementTypeText:
+		if text, _ := element.ElementData["plain_text"].(string); text != "" {
+			return text
+		}
+		content, _ := element.ElementData["content"].(string)
+		return html.UnescapeString(htmlTagPattern.ReplaceAllString(content, ""))
+	case models.ElementTypeSticky:
+		content, _ := element.ElementData["content"].(string)
+		return content
+	default:
+		return ""
+	}
+}
+
+// EncryptExistingElements encrypts the data of elements stored before
+// encryption was enabled and returns how many were encrypted
+func (s *CanvasService) EncryptExistingElements(ctx context.Context) (int, error) {
+	encrypted := 0
+	afterID := uuid.Nil
+	for {
+		lastID, count, err := s.canvasRepo.EncryptElementsAfter(ctx, afterID, elementEncryptionBatchSize)
+		if err != nil {
+			return encrypted, err
+		}
+		encrypted += count
+		if lastID == uuid.Nil {
+			return encrypted, nil
+		}
+		afterID = lastID
+	}
+}
+
+// checkNotFrozen returns ErrBoardFrozen if the board is frozen
+func (s *CanvasService) checkNotFrozen(ctx context.Context, workspaceID uuid.UUID) error {
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace: %w", err)
+	}
+	if workspace != nil && workspace.FrozenAt != nil {
+		return ErrBoardFrozen
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// ChatMessage is an event rendered for people reading a chat channel. It is
+// provider neutral, ChatFormatter turns it into the payload of a provider.
+type ChatMessage struct {
+	Title    string
+	Text     string
+	LinkURL  string // empty when the frontend URL isn't configured
+	LinkText string
+	Facts    []ChatFact
+}
+
+// ChatFact is a name/value line shown below the message text
+type ChatFact struct {
+	Name  string
+	Value string
+}
+
+// ChatFormatter renders chat messages for the incoming webhook of a provider
+type ChatFormatter interface {
+	Format(msg *ChatMessage) ([]byte, error)
+}
+
+// chatFormatters maps webhook formats to chat providers. Adding a provider
+// takes a formatter and a webhook format, delivery and retries are shared.
+var chatFormatters = map[string]ChatFormatter{
+	models.WebhookFormatTeams: TeamsFormatter{},
+}
+
+// chatEvent is an event decoded from a delivery payload, with the type
+// specific data left raw
+type chatEvent struct {
+	OccurredAt  time.Time       `json:"occurred_at"`
+	ActorID     *uuid.UUID      `json:"actor_id"`
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data"`
+	WorkspaceID uuid.UUID       `json:"workspace_id"`
+}
+
+// renderChatPayload renders the event of a delivery for a chat webhook
+func (s *WebhookService) renderChatPayload(
+	ctx context.Context,
+	formatter ChatFormatter,
+	delivery *models.WebhookDelivery,
+) ([]byte, error) {
+	var event chatEvent
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	msg, err := s.buildChatMessage(ctx, &event)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatter.Format(msg)
+}
+
+// buildChatMessage describes an event in a sentence like "Alice added 3
+// elements to Roadmap" and links to the board
+func (s *WebhookService) buildChatMessage(ctx context.Context, event *chatEvent) (*ChatMessage, error) {
+	workspaceName := "a board"
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, event.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace != nil {
+		workspaceName = workspace.Name
+	}
+
+	actor, err := s.chatUserName(ctx, event.ActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &ChatMessage{
+		LinkURL:  s.boardURL(event.WorkspaceID),
+		LinkText: "Open board",
+	}
+
+	switch event.Type {
+	case models.EventElementCreated:
+		var data models.ElementEventPayload
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode event data: %w", err)
+		}
+		msg.Title = "New elements"
+		msg.Text = fmt.Sprintf("%s added %s to %s", actor, pluralize(len(data.ElementIDs), "element"), workspaceName)
+		if len(data.ElementIDs) > 0 && msg.LinkURL != "" {
+			msg.LinkURL = elementURL(s.frontendURL, event.WorkspaceID, data.ElementIDs[0])
+			msg.LinkText = "Show element"
+		}
+
+	case models.EventMemberJoined:
+		var data models.MemberEventPayload
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode event data: %w", err)
+		}
+		member, err := s.chatUserName(ctx, &data.UserID)
+		if err != nil {
+			return nil, err
+		}
+		msg.Title = "New member"
+		msg.Text = fmt.Sprintf("%s joined %s", member, workspaceName)
+		msg.Facts = []ChatFact{{Name: "Role", Value: string(data.Role)}}
+
+	case models.EventSnapshotCreated, models.EventSnapshotRestored, models.EventSnapshotDeleted:
+		var data models.SnapshotEventPayload
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode event data: %w", err)
+		}
+		buildSnapshotChatMessage(msg, event.Type, actor, workspaceName, &data)
+		if data.NewWorkspaceID != nil && msg.LinkURL != "" {
+			msg.LinkURL = s.boardURL(*data.NewWorkspaceID)
+			msg.LinkText = "Open new board"
+		}
+
+	case models.EventWebhookTest:
+		msg.Title = "Test message"
+		msg.Text = fmt.Sprintf("%s sent a test message from %s. The connector is working.", actor, workspaceName)
+
+	default:
+		msg.Title = event.Type
+		msg.Text = workspaceName
+	}
+
+	return msg, nil
+}
+
+func buildSnapshotChatMessage(msg *ChatMessage, eventType, actor, workspaceName string, data *models.SnapshotEventPayload) {
+	snapshot := &data.Snapshot
+	version := "version " + strconv.Itoa(snapshot.Version)
+
+	switch eventType {
+	case models.EventSnapshotCreated:
+		msg.Title = "Snapshot created"
+		msg.Text = fmt.Sprintf("%s saved %s of %s", actor, version, workspaceName)
+	case models.EventSnapshotRestored:
+		msg.Title = "Snapshot restored"
+		msg.Text = fmt.Sprintf("%s restored %s of %s", actor, version, workspaceName)
+		if data.Mode != "" {
+			msg.Facts = append(msg.Facts, ChatFact{Name: "Mode", Value: data.Mode})
+		}
+	default:
+		msg.Title = "Snapshot deleted"
+		msg.Text = fmt.Sprintf("%s deleted %s of %s", actor, version, workspaceName)
+	}
+
+	if snapshot.Name != nil && *snapshot.Name != "" {
+		msg.Facts = append(msg.Facts, ChatFact{Name: "Name", Value: *snapshot.Name})
+	}
+	msg.Facts = append(msg.Facts, ChatFact{Name: "Elements", Value: strconv.Itoa(snapshot.ElementCount)})
+}
+
+// chatUserName returns the display name of a user. A nil user is a system
+// action such as retention cleanup.
+func (s *WebhookService) chatUserName(ctx context.Context, userID *uuid.UUID) (string, error) {
+	if userID == nil {
+		return "HertzBoard", nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, *userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil || user.Name == "" {
+		return "Someone", nil
+	}
+	return user.Name, nil
+}
+
+// boardURL returns the frontend link of a workspace, empty when the frontend
+// URL isn't configured
+func (s *WebhookService) boardURL(workspaceID uuid.UUID) string {
+	return workspaceURL(s.frontendURL, workspaceID)
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
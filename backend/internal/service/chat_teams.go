@@ -0,0 +1,89 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.4"
+)
+
+// TeamsFormatter renders chat messages as Adaptive Cards, the format accepted
+// by Microsoft Teams incoming webhooks and Workflows webhook triggers
+type TeamsFormatter struct{}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	Content     adaptiveCard `json:"content"`
+	ContentType string       `json:"contentType"`
+}
+
+type adaptiveCard struct {
+	Schema  string               `json:"$schema"`
+	Type    string               `json:"type"`
+	Version string               `json:"version"`
+	Body    []adaptiveCardBlock  `json:"body"`
+	Actions []adaptiveCardAction `json:"actions,omitempty"`
+}
+
+// adaptiveCardBlock is a TextBlock or a FactSet
+type adaptiveCardBlock struct {
+	Type   string             `json:"type"`
+	Text   string             `json:"text,omitempty"`
+	Weight string             `json:"weight,omitempty"`
+	Size   string             `json:"size,omitempty"`
+	Facts  []adaptiveCardFact `json:"facts,omitempty"`
+	Wrap   bool               `json:"wrap,omitempty"`
+}
+
+type adaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type adaptiveCardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Format implements ChatFormatter
+func (TeamsFormatter) Format(msg *ChatMessage) ([]byte, error) {
+	card := adaptiveCard{
+		Schema:  adaptiveCardSchema,
+		Type:    "AdaptiveCard",
+		Version: adaptiveCardVersion,
+		Body: []adaptiveCardBlock{
+			{Type: "TextBlock", Text: msg.Title, Weight: "Bolder", Size: "Medium", Wrap: true},
+			{Type: "TextBlock", Text: msg.Text, Wrap: true},
+		},
+	}
+
+	if len(msg.Facts) > 0 {
+		facts := make([]adaptiveCardFact, len(msg.Facts))
+		for i, fact := range msg.Facts {
+			facts[i] = adaptiveCardFact{Title: fact.Name, Value: fact.Value}
+		}
+		card.Body = append(card.Body, adaptiveCardBlock{Type: "FactSet", Facts: facts})
+	}
+
+	if msg.LinkURL != "" {
+		card.Actions = []adaptiveCardAction{{Type: "Action.OpenUrl", Title: msg.LinkText, URL: msg.LinkURL}}
+	}
+
+	payload, err := json.Marshal(teamsMessage{
+		Type:        "message",
+		Attachments: []teamsAttachment{{ContentType: adaptiveCardContentType, Content: card}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Teams message: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,518 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	maxCommentLength = 5000
+
+	// maxReactionEmojis is how many different emojis a comment can get
+	maxReactionEmojis = 20
+	maxEmojiRunes     = 16
+)
+
+var (
+	// ErrCommentNotFound is returned when a comment doesn't exist in the workspace
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrCommentForbidden is returned when a user may not comment on a board,
+	// or edit or delete a comment of someone else
+	ErrCommentForbidden = errors.New("not allowed to change this comment")
+	// ErrInvalidEmoji is returned for a reaction that isn't an emoji
+	ErrInvalidEmoji = errors.New("emoji must be a single emoji")
+)
+
+// CommentService manages comment threads on boards and their elements
+type CommentService struct {
+	commentRepo   *repository.CommentRepository
+	workspaceRepo *repository.WorkspaceRepository
+	rooms         RoomBroadcaster
+	reactionRepo  *repository.ReactionRepository
+}
+
+// NewCommentService creates a new comment service
+func NewCommentService(
+	commentRepo *repository.CommentRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	rooms RoomBroadcaster,
+	reactionRepo *repository.ReactionRepository,
+) *CommentService {
+	return &CommentService{
+		commentRepo:   commentRepo,
+		workspaceRepo: workspaceRepo,
+		rooms:         rooms,
+		reactionRepo:  reactionRepo,
+	}
+}
+
+// ListThreads returns the threads of a board matching the filter, each with
+// its replies
+func (s *CommentService) ListThreads(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	filter models.CommentFilter,
+) (*models.CommentListResponse, error) {
+	threads, err := s.commentRepo.ListThreads(ctx, workspaceID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(threads))
+	byID := make(map[uuid.UUID]*models.Comment, len(threads))
+	for i := range threads {
+		ids[i] = threads[i].ID
+		byID[threads[i].ID] = &threads[i]
+	}
+
+	replies, err := s.commentRepo.ListReplies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range replies {
+		ids = append(ids, replies[i].ID)
+	}
+	reactions, err := s.reactionRepo.Summaries(ctx, models.ReactionTargetComment, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range threads {
+		threads[i].Reactions = reactions[threads[i].ID]
+	}
+
+	for _, reply := range replies {
+		reply.Reactions = reactions[reply.ID]
+		if thread, ok := byID[*reply.ParentID]; ok {
+			thread.Replies = append(thread.Replies, reply)
+		}
+	}
+
+	return &models.CommentListResponse{Threads: threads, Total: len(threads)}, nil
+}
+
+// CountsByElement returns the comment threads of the elements of a board
+// that have any
+func (s *CommentService) CountsByElement(ctx context.Context, workspaceID uuid.UUID) (map[uuid.UUID]models.CommentCounts, error) {
+	return s.commentRepo.CountThreadsByElement(ctx, workspaceID)
+}
+
+// CreateThread starts a thread on the board, or on one of its elements
+func (s *CommentService) CreateThread(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.CreateCommentRequest,
+) (*models.Comment, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	body, err := validateCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ElementID != nil {
+		exists, err := s.commentRepo.ElementExists(ctx, workspaceID, *req.ElementID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("element not found")
+		}
+	}
+
+	return s.create(ctx, &models.Comment{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		ElementID:   req.ElementID,
+		AuthorID:    &userID,
+		Body:        body,
+	})
+}
+
+// Reply adds a reply to a thread. Replying to a reply adds to its thread,
+// threads are one level deep.
+func (s *CommentService) Reply(
+	ctx context.Context,
+	workspaceID, userID, commentID uuid.UUID,
+	req *models.CreateReplyRequest,
+) (*models.Comment, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	body, err := validateCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := s.getThread(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.create(ctx, &models.Comment{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		ParentID:    &thread.ID,
+		AuthorID:    &userID,
+		Body:        body,
+	})
+}
+
+// Update edits the body of a comment of the user
+func (s *CommentService) Update(
+	ctx context.Context,
+	workspaceID, userID, commentID uuid.UUID,
+	req *models.UpdateCommentRequest,
+) (*models.Comment, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	body, err := validateCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.get(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID == nil || *comment.AuthorID != userID {
+		return nil, ErrCommentForbidden
+	}
+
+	if err := s.commentRepo.UpdateBody(ctx, comment.ID, body); err != nil {
+		return nil, err
+	}
+
+	comment, err = s.get(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(workspaceID, userID, models.CommentActionUpdated, comment)
+
+	return comment, nil
+}
+
+// Delete deletes a comment, with its replies for a thread. Owners of the
+// workspace may delete the comments of anyone.
+func (s *CommentService) Delete(ctx context.Context, workspaceID, userID, commentID uuid.UUID) error {
+	member, err := s.requireMember(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	comment, err := s.get(ctx, workspaceID, commentID)
+	if err != nil {
+		return err
+	}
+	isAuthor := comment.AuthorID != nil && *comment.AuthorID == userID
+	if !isAuthor && member.Role != models.WorkspaceRoleOwner {
+		return ErrCommentForbidden
+	}
+
+	deleted, err := s.commentRepo.DeleteComment(ctx, comment.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.reactionRepo.DeleteTargets(ctx, models.ReactionTargetComment, deleted); err != nil {
+		return err
+	}
+	s.broadcast(workspaceID, userID, models.CommentActionDeleted, comment)
+
+	return nil
+}
+
+// Resolve marks the thread of a comment as resolved by the user. Resolving
+// a resolved thread is a no-op.
+func (s *CommentService) Resolve(ctx context.Context, workspaceID, userID, commentID uuid.UUID) (*models.Comment, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	thread, err := s.getThread(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := s.commentRepo.ResolveThread(ctx, thread.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.afterTransition(ctx, workspaceID, userID, thread.ID, changed, models.CommentActionResolved)
+}
+
+// Reopen clears the resolve state of the thread of a comment. Reopening an
+// open thread is a no-op.
+func (s *CommentService) Reopen(ctx context.Context, workspaceID, userID, commentID uuid.UUID) (*models.Comment, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	thread, err := s.getThread(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := s.commentRepo.ReopenThread(ctx, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.afterTransition(ctx, workspaceID, userID, thread.ID, changed, models.CommentActionReopened)
+}
+
+// AddReaction adds a reaction of the user to a comment. Adding a reaction
+// twice is a no-op.
+func (s *CommentService) AddReaction(
+	ctx context.Context,
+	workspaceID, userID, commentID uuid.UUID,
+	emoji string,
+) ([]models.ReactionSummary, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	emoji, err := validateEmoji(emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.get(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := s.reactionRepo.CountEmojis(ctx, models.ReactionTargetComment, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if used >= maxReactionEmojis && !hasEmoji(comment.Reactions, emoji) {
+		return nil, fmt.Errorf("a comment can get at most %d different reactions", maxReactionEmojis)
+	}
+
+	added, err := s.reactionRepo.AddReaction(ctx, &models.Reaction{
+		TargetType:  models.ReactionTargetComment,
+		TargetID:    comment.ID,
+		UserID:      userID,
+		Emoji:       emoji,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.afterReaction(ctx, workspaceID, userID, comment.ID, added)
+}
+
+// RemoveReaction removes a reaction of the user from a comment. Removing a
+// missing reaction is a no-op.
+func (s *CommentService) RemoveReaction(
+	ctx context.Context,
+	workspaceID, userID, commentID uuid.UUID,
+	emoji string,
+) ([]models.ReactionSummary, error) {
+	if _, err := s.requireMember(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.get(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.reactionRepo.RemoveReaction(
+		ctx, models.ReactionTargetComment, comment.ID, userID, strings.TrimSpace(emoji),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.afterReaction(ctx, workspaceID, userID, comment.ID, removed)
+}
+
+// afterReaction returns the reactions of a comment after a change, and tells
+// the room when they changed
+func (s *CommentService) afterReaction(
+	ctx context.Context,
+	workspaceID, userID, commentID uuid.UUID,
+	changed bool,
+) ([]models.ReactionSummary, error) {
+	summaries, err := s.reactionRepo.Summaries(ctx, models.ReactionTargetComment, []uuid.UUID{commentID})
+	if err != nil {
+		return nil, err
+	}
+	reactions := summaries[commentID]
+	if reactions == nil {
+		reactions = []models.ReactionSummary{}
+	}
+
+	if changed && s.rooms != nil {
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypeReactionsUpdated,
+			UserID:    userID,
+			Timestamp: time.Now(),
+			Payload: models.ReactionsUpdatedPayload{
+				TargetType: models.ReactionTargetComment,
+				TargetID:   commentID,
+				Reactions:  reactions,
+			},
+		}, uuid.Nil)
+	}
+
+	return reactions, nil
+}
+
+// afterTransition reloads a thread after it was resolved or reopened, and
+// tells the room when its state changed
+func (s *CommentService) afterTransition(
+	ctx context.Context,
+	workspaceID, userID, threadID uuid.UUID,
+	changed bool,
+	action string,
+) (*models.Comment, error) {
+	thread, err := s.get(ctx, workspaceID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		s.broadcast(workspaceID, userID, action, thread)
+	}
+	return thread, nil
+}
+
+func (s *CommentService) create(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	if err := s.commentRepo.CreateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	// Reload for the author details
+	created, err := s.get(ctx, comment.WorkspaceID, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(comment.WorkspaceID, *comment.AuthorID, models.CommentActionCreated, created)
+
+	return created, nil
+}
+
+func (s *CommentService) get(ctx context.Context, workspaceID, commentID uuid.UUID) (*models.Comment, error) {
+	comment, err := s.commentRepo.GetComment(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		return nil, ErrCommentNotFound
+	}
+
+	reactions, err := s.reactionRepo.Summaries(ctx, models.ReactionTargetComment, []uuid.UUID{comment.ID})
+	if err != nil {
+		return nil, err
+	}
+	comment.Reactions = reactions[comment.ID]
+
+	return comment, nil
+}
+
+// getThread returns the thread of a comment, the comment itself for a thread
+func (s *CommentService) getThread(ctx context.Context, workspaceID, commentID uuid.UUID) (*models.Comment, error) {
+	comment, err := s.get(ctx, workspaceID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID == nil {
+		return comment, nil
+	}
+	return s.get(ctx, workspaceID, *comment.ParentID)
+}
+
+// requireMember returns the membership of the user. Public boards are
+// readable by anyone, but only members take part in discussions, unless the
+// owner lets visitors comment. Those get a viewer membership.
+func (s *CommentService) requireMember(ctx context.Context, workspaceID, userID uuid.UUID) (*models.WorkspaceMember, error) {
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	if member != nil {
+		return member, nil
+	}
+
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil || !workspace.IsPublic || workspace.PublicAccess.Role != models.PublicRoleCommenter {
+		return nil, ErrCommentForbidden
+	}
+	return &models.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Role:        models.WorkspaceRoleViewer,
+	}, nil
+}
+
+func (s *CommentService) broadcast(workspaceID, userID uuid.UUID, action string, comment *models.Comment) {
+	if s.rooms == nil {
+		return
+	}
+	s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+		Type:      models.MessageTypeCommentEvent,
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Payload:   models.CommentEventPayload{Action: action, Comment: *comment},
+	}, uuid.Nil)
+}
+
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("body is required")
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("body must be at most %d characters", maxCommentLength)
+	}
+	return body, nil
+}
+
+// validateEmoji accepts a single emoji, including sequences joined with
+// zero width joiners, modifiers and keycaps
+func validateEmoji(emoji string) (string, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxEmojiRunes {
+		return "", ErrInvalidEmoji
+	}
+
+	pictographic := false
+	for _, r := range emoji {
+		switch {
+		case unicode.IsSpace(r), unicode.IsControl(r), unicode.IsLetter(r):
+			return "", ErrInvalidEmoji
+		case unicode.Is(unicode.So, r), r >= 0x1F000, r == 0x20E3: // 0x20E3 closes keycaps
+			pictographic = true
+		}
+	}
+	if !pictographic {
+		return "", ErrInvalidEmoji
+	}
+	return emoji, nil
+}
+
+func hasEmoji(reactions []models.ReactionSummary, emoji string) bool {
+	for _, reaction := range reactions {
+		if reaction.Emoji == emoji {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// ConfigReloadChannel is the Redis channel reload requests are sent to
+const ConfigReloadChannel = "config:reload"
+
+// ConfigReloadService reloads the configuration on request of an admin and
+// tells the other api-gateway and ws-server instances to reload theirs
+type ConfigReloadService struct {
+	reloader   *config.Reloader
+	redis      *redis.Client
+	audit      *AuditService
+	instanceID string
+}
+
+// NewConfigReloadService creates a new config reload service
+func NewConfigReloadService(reloader *config.Reloader, redisClient *redis.Client, audit *AuditService) *ConfigReloadService {
+	return &ConfigReloadService{
+		reloader:   reloader,
+		redis:      redisClient,
+		audit:      audit,
+		instanceID: uuid.NewString(),
+	}
+}
+
+// Reload reloads the configuration of this instance and asks the others to
+// do the same. The result is the one of this instance.
+func (s *ConfigReloadService) Reload(ctx context.Context, adminID uuid.UUID) (*config.ReloadResult, error) {
+	result, err := s.reloader.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	hlog.CtxInfof(ctx, "Admin %s reloaded the config", adminID)
+	config.LogReload(ctx, result)
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditAdminConfigReloaded,
+		TargetType: "config",
+		Metadata: map[string]interface{}{
+			"applied":          result.Applied,
+			"restart_required": result.RestartRequired,
+		},
+	})
+
+	// Instances that miss the message pick up the change by watching the file
+	if err := s.redis.Publish(ctx, ConfigReloadChannel, s.instanceID).Err(); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to ask the other instances to reload the config: %v", err)
+	}
+
+	return result, nil
+}
+
+// Run reloads the configuration when another instance asks to, until ctx
+// is done
+func (s *ConfigReloadService) Run(ctx context.Context) {
+	pubsub := s.redis.Subscribe(ctx, ConfigReloadChannel)
+	defer pubsub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-pubsub.Channel():
+			if !ok {
+				return
+			}
+			if msg.Payload == s.instanceID {
+				continue
+			}
+			result, err := s.reloader.Reload()
+			if err != nil {
+				hlog.CtxErrorf(ctx, "Failed to reload the config on request of another instance: %v", err)
+				continue
+			}
+			config.LogReload(ctx, result)
+		}
+	}
+}
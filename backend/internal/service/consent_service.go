@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+var (
+	// ErrConsentRequired is returned when a user registers without accepting
+	// the terms of service and privacy policy
+	ErrConsentRequired = errors.New("the terms of service and privacy policy must be accepted")
+	// ErrConsentVersionMismatch is returned when a user accepts a version that
+	// is no longer current, e.g. after a policy update while the prompt was open
+	ErrConsentVersionMismatch = errors.New("the accepted versions are not the current ones")
+)
+
+// ConsentService records which versions of the terms of service and the
+// privacy policy users accepted, and tells whether they have to accept an
+// updated version
+type ConsentService struct {
+	consentRepo *repository.ConsentRepository
+	cfg         *config.LegalConfig
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(consentRepo *repository.ConsentRepository, cfg *config.LegalConfig) *ConsentService {
+	return &ConsentService{
+		consentRepo: consentRepo,
+		cfg:         cfg,
+	}
+}
+
+type consentDocument struct {
+	document models.ConsentDocument
+	version  string
+	url      string
+}
+
+// documents returns the documents with a configured version
+func (s *ConsentService) documents() []consentDocument {
+	var documents []consentDocument
+	if s.cfg.TermsVersion != "" {
+		documents = append(documents, consentDocument{models.ConsentDocumentTerms, s.cfg.TermsVersion, s.cfg.TermsURL})
+	}
+	if s.cfg.PrivacyVersion != "" {
+		documents = append(documents, consentDocument{models.ConsentDocumentPrivacy, s.cfg.PrivacyVersion, s.cfg.PrivacyURL})
+	}
+	return documents
+}
+
+// Required reports whether users have to accept any document
+func (s *ConsentService) Required() bool {
+	return len(s.documents()) > 0
+}
+
+// GetStatus compares the versions a user accepted with the current ones
+func (s *ConsentService) GetStatus(ctx context.Context, userID uuid.UUID) (*models.ConsentStatus, error) {
+	consents, err := s.consentRepo.GetLatestConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[models.ConsentDocument]*models.UserConsent, len(consents))
+	for i := range consents {
+		accepted[consents[i].Document] = &consents[i]
+	}
+
+	status := &models.ConsentStatus{Documents: []models.ConsentDocumentStatus{}}
+	for _, doc := range s.documents() {
+		docStatus := models.ConsentDocumentStatus{
+			Document:       doc.document,
+			CurrentVersion: doc.version,
+			URL:            doc.url,
+			Required:       true,
+		}
+		if consent := accepted[doc.document]; consent != nil {
+			docStatus.AcceptedVersion = &consent.Version
+			docStatus.AcceptedAt = &consent.AcceptedAt
+			docStatus.Required = consent.Version != doc.version
+		}
+		if docStatus.Required {
+			status.ConsentRequired = true
+		}
+		status.Documents = append(status.Documents, docStatus)
+	}
+
+	return status, nil
+}
+
+// Accept records that a user accepted the current versions of the documents
+// and returns the updated status
+func (s *ConsentService) Accept(
+	ctx context.Context,
+	userID uuid.UUID,
+	req *models.AcceptConsentRequest,
+	clientIP string,
+) (*models.ConsentStatus, error) {
+	if req.TermsVersion != s.cfg.TermsVersion || req.PrivacyVersion != s.cfg.PrivacyVersion {
+		return nil, ErrConsentVersionMismatch
+	}
+
+	if err := s.RecordCurrent(ctx, userID, clientIP); err != nil {
+		return nil, err
+	}
+
+	return s.GetStatus(ctx, userID)
+}
+
+// RecordCurrent records that a user accepted the current versions of the
+// documents, at registration or on a re-acceptance prompt
+func (s *ConsentService) RecordCurrent(ctx context.Context, userID uuid.UUID, clientIP string) error {
+	documents := s.documents()
+	if len(documents) == 0 {
+		return nil
+	}
+
+	var ip *string
+	if clientIP != "" {
+		ip = &clientIP
+	}
+
+	consents := make([]models.UserConsent, len(documents))
+	for i, doc := range documents {
+		consents[i] = models.UserConsent{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Document:  doc.document,
+			Version:   doc.version,
+			IPAddress: ip,
+		}
+	}
+
+	return s.consentRepo.RecordConsents(ctx, consents)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,14 +11,22 @@ import (
 	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/repository"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
 )
 
 const (
 	// maxOperationsToFetch is the maximum number of operations to fetch from the database
 	maxOperationsToFetch = 1000
+	// maxTimestampLead is how far a client timestamp may run ahead of the
+	// server clock, enough for the local operations of an offline client
+	maxTimestampLead = 10000
 )
 
+// ErrElementNotFound rejects operations on elements that don't exist or
+// belong to another workspace
+var ErrElementNotFound = errors.New("element not found")
+
 // LamportClock implements a Lamport timestamp for ordering operations
 type LamportClock struct {
 	counter int64
@@ -62,6 +71,10 @@ type CRDTService struct {
 	elementRepo   *repository.ElementRepository
 	operationRepo *repository.OperationRepository
 	clock         *LamportClock
+	events        *EventPublisher
+	notifications *NotificationService
+	analytics     *AnalyticsService
+	metering      *MeteringService
 	ctx           context.Context
 }
 
@@ -69,22 +82,42 @@ type CRDTService struct {
 func NewCRDTService(
 	elementRepo *repository.ElementRepository,
 	operationRepo *repository.OperationRepository,
+	events *EventPublisher,
+	notifications *NotificationService,
+	analytics *AnalyticsService,
+	metering *MeteringService,
 ) *CRDTService {
 	return &CRDTService{
 		elementRepo:   elementRepo,
 		operationRepo: operationRepo,
+		events:        events,
+		notifications: notifications,
+		analytics:     analytics,
+		metering:      metering,
 		clock:         NewLamportClock(),
 		ctx:           context.Background(),
 	}
 }
 
 // ApplyOperation applies a CRDT operation and returns the resulting element state
-func (s *CRDTService) ApplyOperation(op *models.OperationPayload) error {
+func (s *CRDTService) ApplyOperation(ctx context.Context, op *models.OperationPayload) error {
+	// Elements are addressed by ID alone, make sure it is on this board
+	// before anything is stored
+	elementWorkspaceID, err := s.elementRepo.GetWorkspaceID(ctx, op.ElementID)
+	if err != nil {
+		return err
+	}
+	if elementWorkspaceID != op.WorkspaceID &&
+		(elementWorkspaceID != uuid.Nil || op.OpType != models.OperationTypeCreate) {
+		return ErrElementNotFound
+	}
+
 	// Update Lamport clock
+	op.Timestamp = s.clampTimestamp(ctx, op.WorkspaceID, op.Timestamp)
 	s.clock.Update(op.Timestamp)
 
 	// Store operation in database
-	err := s.operationRepo.Create(s.ctx, &models.Operation{
+	err = s.operationRepo.Create(ctx, &models.Operation{
 		ID:          uuid.New(),
 		WorkspaceID: op.WorkspaceID,
 		ElementID:   op.ElementID,
@@ -101,22 +134,52 @@ func (s *CRDTService) ApplyOperation(op *models.OperationPayload) error {
 	// Apply operation to element
 	switch op.OpType {
 	case models.OperationTypeCreate:
-		return s.applyCreate(op)
+		err = s.applyCreate(ctx, op)
 	case models.OperationTypeUpdate:
-		return s.applyUpdate(op)
+		err = s.applyUpdate(ctx, op)
 	case models.OperationTypeDelete:
-		return s.applyDelete(op)
+		err = s.applyDelete(ctx, op)
 	case models.OperationTypeMove:
-		return s.applyMove(op)
+		err = s.applyMove(ctx, op)
 	default:
-		return fmt.Errorf("unknown operation type: %s", op.OpType)
+		err = fmt.Errorf("unknown operation type: %s", op.OpType)
 	}
+	if err != nil {
+		return err
+	}
+
+	s.analytics.TrackOperation(op.WorkspaceID, op.ElementID, op.UserID, op.OpType)
+	s.metering.RecordEditor(op.WorkspaceID, op.UserID)
+	return nil
+}
+
+// clampTimestamp keeps client timestamps from running far ahead of the
+// server clock, they would win every last-write-wins comparison and drag the
+// clock along. The clock starts at zero, so timestamps beyond it are checked
+// against the latest timestamp of the board before they are clamped.
+func (s *CRDTService) clampTimestamp(ctx context.Context, workspaceID uuid.UUID, timestamp int64) int64 {
+	if timestamp <= 0 {
+		return s.clock.Tick()
+	}
+
+	limit := s.clock.Get() + maxTimestampLead
+	if timestamp <= limit {
+		return timestamp
+	}
+
+	version, err := s.operationRepo.GetLatestTimestamp(ctx, workspaceID)
+	if err != nil {
+		hlog.CtxWarnf(ctx, "Failed to get board version of workspace %s: %v", workspaceID, err)
+	} else if version+maxTimestampLead > limit {
+		limit = version + maxTimestampLead
+	}
+	return min(timestamp, limit)
 }
 
 // applyCreate creates a new element
-func (s *CRDTService) applyCreate(op *models.OperationPayload) error {
+func (s *CRDTService) applyCreate(ctx context.Context, op *models.OperationPayload) error {
 	// Check if element already exists (idempotent operation)
-	existing, err := s.elementRepo.GetByID(s.ctx, op.ElementID)
+	existing, err := s.elementRepo.GetByID(ctx, op.WorkspaceID, op.ElementID)
 	if err == nil && existing != nil {
 		// Element exists, check timestamp for LWW
 		if op.Timestamp <= existing.Version {
@@ -171,13 +234,22 @@ func (s *CRDTService) applyCreate(op *models.OperationPayload) error {
 		UpdatedBy:   op.UserID,
 	}
 
-	return s.elementRepo.Create(s.ctx, element)
+	created := s.events.event(ctx, models.EventElementCreated, op.WorkspaceID, &op.UserID, models.ElementEventPayload{
+		ElementIDs: []uuid.UUID{op.ElementID},
+	})
+	if err := s.elementRepo.Create(ctx, element, created); err != nil {
+		return err
+	}
+	if element.Type == string(models.ElementTypeSticky) {
+		s.notifications.syncMentions(ctx, op.WorkspaceID, op.ElementID, op.UserID, content)
+	}
+	return nil
 }
 
 // applyUpdate updates an existing element using LWW (Last-Write-Wins)
-func (s *CRDTService) applyUpdate(op *models.OperationPayload) error {
+func (s *CRDTService) applyUpdate(ctx context.Context, op *models.OperationPayload) error {
 	// Get existing element
-	existing, err := s.elementRepo.GetByID(s.ctx, op.ElementID)
+	existing, err := s.elementRepo.GetByID(ctx, op.WorkspaceID, op.ElementID)
 	if err != nil {
 		return fmt.Errorf("element not found: %w", err)
 	}
@@ -201,7 +273,8 @@ func (s *CRDTService) applyUpdate(op *models.OperationPayload) error {
 	}
 
 	// Apply updates to element (partial updates)
-	if content, ok := updateData["content"].(string); ok {
+	content, contentChanged := updateData["content"].(string)
+	if contentChanged {
 		existing.Content = content
 	}
 	if posX, ok := updateData["pos_x"].(float64); ok {
@@ -230,13 +303,20 @@ func (s *CRDTService) applyUpdate(op *models.OperationPayload) error {
 	existing.Version = op.Timestamp
 	existing.UpdatedBy = op.UserID
 
-	return s.elementRepo.Update(s.ctx, existing)
+	if err := s.elementRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+
+	if contentChanged && existing.Type == string(models.ElementTypeSticky) {
+		s.notifications.syncMentions(ctx, op.WorkspaceID, op.ElementID, op.UserID, content)
+	}
+	return nil
 }
 
 // applyDelete marks an element as deleted using tombstone
-func (s *CRDTService) applyDelete(op *models.OperationPayload) error {
+func (s *CRDTService) applyDelete(ctx context.Context, op *models.OperationPayload) error {
 	// Get existing element
-	existing, err := s.elementRepo.GetByID(s.ctx, op.ElementID)
+	existing, err := s.elementRepo.GetByID(ctx, op.WorkspaceID, op.ElementID)
 	if err != nil {
 		// Element doesn't exist, operation is already applied
 		return nil
@@ -249,13 +329,13 @@ func (s *CRDTService) applyDelete(op *models.OperationPayload) error {
 	}
 
 	// Soft delete the element
-	return s.elementRepo.Delete(s.ctx, op.ElementID)
+	return s.elementRepo.Delete(ctx, op.WorkspaceID, op.ElementID)
 }
 
 // applyMove updates element position
-func (s *CRDTService) applyMove(op *models.OperationPayload) error {
+func (s *CRDTService) applyMove(ctx context.Context, op *models.OperationPayload) error {
 	// Get existing element
-	existing, err := s.elementRepo.GetByID(s.ctx, op.ElementID)
+	existing, err := s.elementRepo.GetByID(ctx, op.WorkspaceID, op.ElementID)
 	if err != nil {
 		return fmt.Errorf("element not found: %w", err)
 	}
@@ -290,7 +370,7 @@ func (s *CRDTService) applyMove(op *models.OperationPayload) error {
 	existing.Version = op.Timestamp
 	existing.UpdatedBy = op.UserID
 
-	return s.elementRepo.Update(s.ctx, existing)
+	return s.elementRepo.Update(ctx, existing)
 }
 
 // ResolveConflict resolves conflicts between concurrent operations
@@ -352,6 +432,43 @@ func (s *CRDTService) BuildStateVector(operations []*models.Operation) map[strin
 	return stateVector
 }
 
+// GetReplay returns a page of operations created between from and to, in the
+// order they were applied, so clients can animate how the board evolved
+func (s *CRDTService) GetReplay(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+	limit, offset int,
+) (*models.ReplayResponse, error) {
+	// Fetch one extra operation to detect whether another page exists
+	operations, err := s.operationRepo.GetInRange(ctx, workspaceID, from, to, limit+1, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operations: %w", err)
+	}
+
+	hasMore := len(operations) > limit
+	if hasMore {
+		operations = operations[:limit]
+	}
+
+	return &models.ReplayResponse{
+		From:       from,
+		To:         to,
+		Operations: operations,
+		NextOffset: offset + len(operations),
+		HasMore:    hasMore,
+	}, nil
+}
+
+// GetBoardVersion returns the latest Lamport timestamp applied to a workspace
+func (s *CRDTService) GetBoardVersion(ctx context.Context, workspaceID uuid.UUID) (int64, error) {
+	version, err := s.operationRepo.GetLatestTimestamp(ctx, workspaceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get board version: %w", err)
+	}
+	return version, nil
+}
+
 // GenerateTimestamp generates a new Lamport timestamp
 func (s *CRDTService) GenerateTimestamp() int64 {
 	return s.clock.Tick()
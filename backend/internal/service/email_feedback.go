@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// Email feedback types reported by provider webhooks
+const (
+	EmailFeedbackDelivered  = "delivered"
+	EmailFeedbackBounced    = "bounced"
+	EmailFeedbackComplained = "complained"
+)
+
+// emailWebhookMaxAge is how old a signed webhook may be, to limit replays
+const emailWebhookMaxAge = 15 * time.Minute
+
+var (
+	// ErrEmailWebhookDisabled is returned for providers that aren't configured
+	// or have no webhook key
+	ErrEmailWebhookDisabled = errors.New("email webhook is not configured")
+	// ErrInvalidEmailWebhook is returned for payloads that can't be parsed
+	ErrInvalidEmailWebhook = errors.New("invalid email webhook payload")
+)
+
+// EmailFeedback is a delivery, bounce or complaint reported by the provider
+type EmailFeedback struct {
+	OccurredAt time.Time `json:"occurred_at"`
+	Provider   string    `json:"provider"`
+	Type       string    `json:"type"`
+	Email      string    `json:"email"`
+	MessageID  string    `json:"message_id,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	// Permanent is set for hard bounces
+	Permanent bool `json:"permanent"`
+}
+
+// EmailWebhookRequest is the raw webhook request, so providers can verify
+// their signatures over the exact body
+type EmailWebhookRequest struct {
+	Header http.Header
+	Query  url.Values
+	Body   []byte
+}
+
+// HandleWebhook verifies and parses a provider feedback webhook and records
+// the reported events. Only the configured provider is accepted. Errors make
+// the provider retry the webhook.
+func (s *EmailService) HandleWebhook(ctx context.Context, provider string, req *EmailWebhookRequest) error {
+	if provider != s.cfg.Provider || s.cfg.WebhookKey == "" {
+		return ErrEmailWebhookDisabled
+	}
+
+	var feedback []EmailFeedback
+	var err error
+	switch provider {
+	case EmailProviderSendGrid:
+		feedback, err = s.parseSendGridWebhook(req)
+	case EmailProviderMailgun:
+		feedback, err = s.parseMailgunWebhook(req)
+	case EmailProviderSES:
+		feedback, err = s.parseSESWebhook(ctx, req)
+	default:
+		return ErrEmailWebhookDisabled
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.RecordFeedback(ctx, feedback)
+}
+
+// RecordFeedback logs provider feedback and suppresses addresses that hard
+// bounced or complained, so no further emails are sent to them
+func (s *EmailService) RecordFeedback(ctx context.Context, feedback []EmailFeedback) error {
+	for i := range feedback {
+		f := &feedback[i]
+		hlog.CtxInfof(ctx, "Email %s for %s via %s (permanent: %t): %s", f.Type, f.Email, f.Provider, f.Permanent, f.Reason)
+
+		if err := s.suppress(ctx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendGrid
+
+type sendGridEvent struct {
+	Email     string `json:"email"`
+	Event     string `json:"event"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	MessageID string `json:"sg_message_id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// parseSendGridWebhook verifies the signed Event Webhook. The webhook key is
+// the base64 verification key shown in the SendGrid settings.
+func (s *EmailService) parseSendGridWebhook(req *EmailWebhookRequest) ([]EmailFeedback, error) {
+	timestamp := req.Header.Get("X-Twilio-Email-Event-Webhook-Timestamp")
+	signature, err := base64.StdEncoding.DecodeString(req.Header.Get("X-Twilio-Email-Event-Webhook-Signature"))
+	if err != nil || timestamp == "" {
+		return nil, ErrInvalidSignature
+	}
+	if err := checkWebhookTimestamp(timestamp); err != nil {
+		return nil, err
+	}
+
+	der, err := base64.StdEncoding.DecodeString(s.cfg.WebhookKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sendgrid webhook key: %w", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sendgrid webhook key: %w", err)
+	}
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("sendgrid webhook key is not an ECDSA key")
+	}
+
+	hash := sha256.Sum256(append([]byte(timestamp), req.Body...))
+	if !ecdsa.VerifyASN1(publicKey, hash[:], signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	var events []sendGridEvent
+	if err := json.Unmarshal(req.Body, &events); err != nil {
+		return nil, ErrInvalidEmailWebhook
+	}
+
+	var feedback []EmailFeedback
+	for _, event := range events {
+		f := EmailFeedback{
+			OccurredAt: time.Unix(event.Timestamp, 0),
+			Provider:   EmailProviderSendGrid,
+			Email:      event.Email,
+			MessageID:  event.MessageID,
+			Reason:     event.Reason,
+		}
+		switch event.Event {
+		case "delivered":
+			f.Type = EmailFeedbackDelivered
+		case "bounce":
+			// Blocks are temporary rejections reported as bounce events
+			f.Type = EmailFeedbackBounced
+			f.Permanent = event.Type != "blocked"
+		case "spamreport":
+			f.Type = EmailFeedbackComplained
+		default:
+			continue
+		}
+		feedback = append(feedback, f)
+	}
+
+	return feedback, nil
+}
+
+// Mailgun
+
+type mailgunWebhook struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		Event     string  `json:"event"`
+		Severity  string  `json:"severity"`
+		Recipient string  `json:"recipient"`
+		Timestamp float64 `json:"timestamp"`
+		Message   struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+			} `json:"headers"`
+		} `json:"message"`
+		DeliveryStatus struct {
+			Description string `json:"description"`
+			Message     string `json:"message"`
+		} `json:"delivery-status"`
+	} `json:"event-data"`
+}
+
+// parseMailgunWebhook verifies the signature embedded in the payload. The
+// webhook key is the Mailgun HTTP webhook signing key.
+func (s *EmailService) parseMailgunWebhook(req *EmailWebhookRequest) ([]EmailFeedback, error) {
+	var webhook mailgunWebhook
+	if err := json.Unmarshal(req.Body, &webhook); err != nil {
+		return nil, ErrInvalidEmailWebhook
+	}
+
+	sig := webhook.Signature
+	if err := checkWebhookTimestamp(sig.Timestamp); err != nil {
+		return nil, err
+	}
+	expected := hex.EncodeToString(hmacSHA256([]byte(s.cfg.WebhookKey), sig.Timestamp+sig.Token))
+	if !hmac.Equal([]byte(expected), []byte(sig.Signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	event := webhook.EventData
+	f := EmailFeedback{
+		OccurredAt: time.Unix(int64(event.Timestamp), 0),
+		Provider:   EmailProviderMailgun,
+		Email:      event.Recipient,
+		MessageID:  event.Message.Headers.MessageID,
+		Reason:     event.DeliveryStatus.Description,
+	}
+	if f.Reason == "" {
+		f.Reason = event.DeliveryStatus.Message
+	}
+
+	switch event.Event {
+	case "delivered":
+		f.Type = EmailFeedbackDelivered
+	case "failed":
+		f.Type = EmailFeedbackBounced
+		f.Permanent = event.Severity == "permanent"
+	case "complained":
+		f.Type = EmailFeedbackComplained
+	default:
+		return nil, nil
+	}
+
+	return []EmailFeedback{f}, nil
+}
+
+// SES
+
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+type sesRecipient struct {
+	EmailAddress   string `json:"emailAddress"`
+	DiagnosticCode string `json:"diagnosticCode"`
+}
+
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce struct {
+		Timestamp         time.Time      `json:"timestamp"`
+		BounceType        string         `json:"bounceType"`
+		BouncedRecipients []sesRecipient `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		Timestamp            time.Time      `json:"timestamp"`
+		FeedbackType         string         `json:"complaintFeedbackType"`
+		ComplainedRecipients []sesRecipient `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Delivery struct {
+		Timestamp  time.Time `json:"timestamp"`
+		Recipients []string  `json:"recipients"`
+	} `json:"delivery"`
+}
+
+// parseSESWebhook handles SES notifications delivered by an SNS HTTPS
+// subscription. SNS can't sign with a shared key, so the subscription URL
+// must carry the webhook key as the "token" query parameter.
+func (s *EmailService) parseSESWebhook(ctx context.Context, req *EmailWebhookRequest) ([]EmailFeedback, error) {
+	token := req.Query.Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.WebhookKey)) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	var msg snsMessage
+	if err := json.Unmarshal(req.Body, &msg); err != nil {
+		return nil, ErrInvalidEmailWebhook
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		return nil, s.confirmSNSSubscription(ctx, msg.SubscribeURL)
+	case "Notification":
+	default:
+		return nil, nil
+	}
+
+	var notification sesNotification
+	if err := json.Unmarshal([]byte(msg.Message), &notification); err != nil {
+		return nil, ErrInvalidEmailWebhook
+	}
+
+	// Notifications use notificationType, event publishing uses eventType
+	notificationType := notification.NotificationType
+	if notificationType == "" {
+		notificationType = notification.EventType
+	}
+
+	var feedback []EmailFeedback
+	newFeedback := func(feedbackType, email, reason string, at time.Time) EmailFeedback {
+		return EmailFeedback{
+			OccurredAt: at,
+			Provider:   EmailProviderSES,
+			Type:       feedbackType,
+			Email:      email,
+			MessageID:  notification.Mail.MessageID,
+			Reason:     reason,
+		}
+	}
+
+	switch notificationType {
+	case "Delivery":
+		for _, email := range notification.Delivery.Recipients {
+			feedback = append(feedback, newFeedback(EmailFeedbackDelivered, email, "", notification.Delivery.Timestamp))
+		}
+	case "Bounce":
+		bounce := notification.Bounce
+		for _, recipient := range bounce.BouncedRecipients {
+			f := newFeedback(EmailFeedbackBounced, recipient.EmailAddress, recipient.DiagnosticCode, bounce.Timestamp)
+			f.Permanent = bounce.BounceType == "Permanent"
+			feedback = append(feedback, f)
+		}
+	case "Complaint":
+		complaint := notification.Complaint
+		for _, recipient := range complaint.ComplainedRecipients {
+			feedback = append(feedback,
+				newFeedback(EmailFeedbackComplained, recipient.EmailAddress, complaint.FeedbackType, complaint.Timestamp))
+		}
+	}
+
+	return feedback, nil
+}
+
+// confirmSNSSubscription visits the SubscribeURL of a new SNS subscription.
+// Only SNS endpoints are called so the webhook can't be used to make the
+// server fetch arbitrary URLs.
+func (s *EmailService) confirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" ||
+		!strings.HasPrefix(u.Hostname(), "sns.") || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return ErrInvalidEmailWebhook
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if err := callEmailAPI(s.httpClient, req); err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+	}
+
+	hlog.CtxInfof(ctx, "Confirmed SNS subscription for SES feedback")
+	return nil
+}
+
+// checkWebhookTimestamp rejects webhooks signed too long ago
+func checkWebhookTimestamp(timestamp string) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age > emailWebhookMaxAge || age < -emailWebhookMaxAge {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// EmailSubject is the subject emails are queued on
+	EmailSubject = "emails"
+	// EmailDeadLetterSubject receives emails that failed every attempt
+	EmailDeadLetterSubject = "emails.dead"
+
+	emailStreamName           = "EMAILS"
+	emailDeadLetterStreamName = "EMAILS_DEAD"
+	emailConsumerName         = "email-workers"
+
+	// emailAckWait is how long a worker may take to send an email before
+	// JetStream redelivers it
+	emailAckWait = time.Minute
+	// emailDeadLetterMaxAge is how long dead letters are kept for inspection
+	emailDeadLetterMaxAge = 30 * 24 * time.Hour
+
+	defaultEmailMaxAttempts     = 5
+	defaultEmailRetryBackoff    = 30 * time.Second
+	defaultEmailMaxRetryBackoff = 30 * time.Minute
+)
+
+// ErrDeadLetterNotFound is returned for unknown dead letter sequences
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterEmail is an email that could not be sent after all attempts
+type DeadLetterEmail struct {
+	FailedAt time.Time    `json:"failed_at"`
+	Error    string       `json:"error"`
+	Message  EmailMessage `json:"message"`
+	Sequence uint64       `json:"sequence"`
+	Attempts int          `json:"attempts"`
+}
+
+// ensureEmailStreams creates the email queue and dead letter streams. The
+// queue is a work queue, so messages are removed once a worker acks them.
+func ensureEmailStreams(js nats.JetStreamContext) error {
+	if err := ensureStream(js, &nats.StreamConfig{
+		Name:      emailStreamName,
+		Subjects:  []string{EmailSubject},
+		Storage:   nats.FileStorage,
+		Retention: nats.WorkQueuePolicy,
+	}); err != nil {
+		return err
+	}
+
+	return ensureStream(js, &nats.StreamConfig{
+		Name:     emailDeadLetterStreamName,
+		Subjects: []string{EmailDeadLetterSubject},
+		Storage:  nats.FileStorage,
+		MaxAge:   emailDeadLetterMaxAge,
+	})
+}
+
+// ListDeadLetters returns dead letters newest first. Only sequences below
+// before are returned, so the last sequence of a page fetches the next one.
+func (s *EmailService) ListDeadLetters(before uint64, limit int) ([]DeadLetterEmail, error) {
+	info, err := s.js.StreamInfo(emailDeadLetterStreamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter stream: %w", err)
+	}
+
+	last := info.State.LastSeq
+	if before > 0 && before <= last {
+		last = before - 1
+	}
+
+	deadLetters := make([]DeadLetterEmail, 0, limit)
+	for seq := last; seq >= info.State.FirstSeq && seq > 0 && len(deadLetters) < limit; seq-- {
+		deadLetter, err := s.getDeadLetter(seq)
+		if errors.Is(err, ErrDeadLetterNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, *deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+// RequeueDeadLetter puts a dead letter back on the email queue with a fresh
+// set of attempts
+func (s *EmailService) RequeueDeadLetter(sequence uint64) error {
+	deadLetter, err := s.getDeadLetter(sequence)
+	if err != nil {
+		return err
+	}
+
+	if err := s.PublishEmail(&deadLetter.Message); err != nil {
+		return err
+	}
+
+	return s.DeleteDeadLetter(sequence)
+}
+
+// DeleteDeadLetter discards a dead letter
+func (s *EmailService) DeleteDeadLetter(sequence uint64) error {
+	if err := s.js.DeleteMsg(emailDeadLetterStreamName, sequence); err != nil {
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			return ErrDeadLetterNotFound
+		}
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *EmailService) getDeadLetter(sequence uint64) (*DeadLetterEmail, error) {
+	msg, err := s.js.GetMsg(emailDeadLetterStreamName, sequence)
+	if err != nil {
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	var deadLetter DeadLetterEmail
+	if err := json.Unmarshal(msg.Data, &deadLetter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	deadLetter.Sequence = msg.Sequence
+
+	return &deadLetter, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+)
+
+const (
+	// emailLimitWindow is the fixed window the send limits count in
+	emailLimitWindow = time.Hour
+
+	emailRecipientLimitKey = "email_limit:recipient:%s:%d"
+	emailWorkspaceLimitKey = "email_limit:workspace:%s:%d"
+)
+
+// EmailRateLimitError is returned when a send limit is reached
+type EmailRateLimitError struct {
+	Limit      string // "recipient" or "workspace"
+	RetryAfter time.Duration
+}
+
+func (e *EmailRateLimitError) Error() string {
+	return fmt.Sprintf("too many emails per %s, retry in %s", e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// RetryAfterSeconds returns the wait in whole seconds, for Retry-After headers
+func (e *EmailRateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// AllowInvite counts an invitation email against the hourly limits of the
+// recipient and the workspace. It returns an EmailRateLimitError once either
+// limit is reached. Limits of 0 are disabled. Redis failures are logged and
+// let the email through, so an outage doesn't block invitations.
+func (s *EmailService) AllowInvite(ctx context.Context, workspaceID uuid.UUID, to string) error {
+	recipient := strings.ToLower(strings.TrimSpace(to))
+	if err := s.allow(ctx, "recipient", emailRecipientLimitKey, recipient, s.cfg.MaxInvitesPerRecipientPerHour); err != nil {
+		return err
+	}
+	return s.allow(ctx, "workspace", emailWorkspaceLimitKey, workspaceID.String(), s.cfg.MaxInvitesPerWorkspacePerHour)
+}
+
+// allow increments the counter of the current window and checks it against max
+func (s *EmailService) allow(ctx context.Context, limit, keyPattern, subject string, maxSends int) error {
+	if maxSends <= 0 || s.redis == nil {
+		return nil
+	}
+
+	now := time.Now()
+	window := now.Truncate(emailLimitWindow)
+	key := fmt.Sprintf(keyPattern, subject, window.Unix())
+
+	pipe := s.redis.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, emailLimitWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to check email %s limit: %v", limit, err)
+		return nil
+	}
+
+	if count.Val() > int64(maxSends) {
+		return &EmailRateLimitError{
+			Limit:      limit,
+			RetryAfter: window.Add(emailLimitWindow).Sub(now),
+		}
+	}
+
+	return nil
+}
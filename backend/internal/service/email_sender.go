@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// Email providers
+const (
+	EmailProviderSMTP     = "smtp"
+	EmailProviderSendGrid = "sendgrid"
+	EmailProviderSES      = "ses"
+	EmailProviderMailgun  = "mailgun"
+)
+
+const (
+	emailSendTimeout     = 30 * time.Second
+	emailProviderTimeout = 15 * time.Second
+	emailMaxErrorBody    = 512
+)
+
+// OutgoingEmail is a rendered email ready to be delivered
+type OutgoingEmail struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	// Text is the plain-text alternative, empty if there is none
+	Text string
+}
+
+// EmailSender delivers rendered emails
+type EmailSender interface {
+	Send(ctx context.Context, email *OutgoingEmail) error
+}
+
+// NewEmailSender creates the sender selected by email.provider config
+func NewEmailSender(cfg *config.EmailConfig) (EmailSender, error) {
+	switch cfg.Provider {
+	case "", EmailProviderSMTP:
+		return NewSMTPSender(cfg), nil
+	case EmailProviderSendGrid:
+		return NewSendGridSender(cfg)
+	case EmailProviderSES:
+		return NewSESSender(cfg)
+	case EmailProviderMailgun:
+		return NewMailgunSender(cfg)
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", cfg.Provider)
+	}
+}
+
+// callEmailAPI sends a request to a provider API and checks the response status
+func callEmailAPI(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call email provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, emailMaxErrorBody))
+		return fmt.Errorf("email provider responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+const (
+	mailgunAPIURL   = "https://api.mailgun.net/v3"
+	mailgunEUAPIURL = "https://api.eu.mailgun.net/v3"
+	mailgunRegionEU = "eu"
+)
+
+// MailgunSender delivers emails through the Mailgun messages API
+type MailgunSender struct {
+	httpClient *http.Client
+	endpoint   string
+	apiKey     string
+}
+
+// NewMailgunSender creates a Mailgun sender. Domains in the EU region need
+// email.region set to "eu".
+func NewMailgunSender(cfg *config.EmailConfig) (*MailgunSender, error) {
+	if cfg.APIKey == "" || cfg.Domain == "" {
+		return nil, fmt.Errorf("mailgun requires email.api_key and email.domain")
+	}
+
+	baseURL := mailgunAPIURL
+	if strings.EqualFold(cfg.Region, mailgunRegionEU) {
+		baseURL = mailgunEUAPIURL
+	}
+
+	return &MailgunSender{
+		httpClient: &http.Client{Timeout: emailProviderTimeout},
+		endpoint:   fmt.Sprintf("%s/%s/messages", baseURL, url.PathEscape(cfg.Domain)),
+		apiKey:     cfg.APIKey,
+	}, nil
+}
+
+// Send sends an email via Mailgun
+func (s *MailgunSender) Send(ctx context.Context, email *OutgoingEmail) error {
+	form := url.Values{}
+	form.Set("from", email.From)
+	form.Set("to", email.To)
+	form.Set("subject", email.Subject)
+	form.Set("html", email.HTML)
+	if email.Text != "" {
+		form.Set("text", email.Text)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth("api", s.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return callEmailAPI(s.httpClient, req)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+const sendGridAPIURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender delivers emails through the SendGrid v3 API
+type SendGridSender struct {
+	httpClient *http.Client
+	apiKey     string
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridMail struct {
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// NewSendGridSender creates a SendGrid sender
+func NewSendGridSender(cfg *config.EmailConfig) (*SendGridSender, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("sendgrid requires email.api_key")
+	}
+
+	return &SendGridSender{
+		httpClient: &http.Client{Timeout: emailProviderTimeout},
+		apiKey:     cfg.APIKey,
+	}, nil
+}
+
+// Send sends an email via SendGrid
+func (s *SendGridSender) Send(ctx context.Context, email *OutgoingEmail) error {
+	// SendGrid requires text/plain to come before text/html
+	var content []sendGridContent
+	if email.Text != "" {
+		content = append(content, sendGridContent{Type: "text/plain", Value: email.Text})
+	}
+	content = append(content, sendGridContent{Type: "text/html", Value: email.HTML})
+
+	body, err := json.Marshal(&sendGridMail{
+		From:             parseSendGridAddress(email.From),
+		Subject:          email.Subject,
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: email.To}}}},
+		Content:          content,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal sendgrid mail: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridAPIURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return callEmailAPI(s.httpClient, req)
+}
+
+// parseSendGridAddress splits "Name <address>" senders, which SendGrid
+// expects as separate fields
+func parseSendGridAddress(address string) sendGridAddress {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return sendGridAddress{Email: address}
+	}
+	return sendGridAddress{Email: parsed.Address, Name: parsed.Name}
+}
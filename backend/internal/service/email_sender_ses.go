@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/awssig"
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+const (
+	sesAPIURLFormat = "https://email.%s.amazonaws.com/v2/email/outbound-emails"
+	sesService      = "ses"
+	sesCharset      = "UTF-8"
+)
+
+// SESSender delivers emails through the Amazon SES v2 API
+type SESSender struct {
+	httpClient *http.Client
+	endpoint   string
+	region     string
+	accessKey  string
+	secretKey  string
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesBody struct {
+	HTML *sesContent `json:"Html,omitempty"`
+	Text *sesContent `json:"Text,omitempty"`
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    sesBody    `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// NewSESSender creates an SES sender
+func NewSESSender(cfg *config.EmailConfig) (*SESSender, error) {
+	if cfg.Region == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("ses requires email.region, email.access_key and email.secret_key")
+	}
+
+	return &SESSender{
+		httpClient: &http.Client{Timeout: emailProviderTimeout},
+		endpoint:   fmt.Sprintf(sesAPIURLFormat, cfg.Region),
+		region:     cfg.Region,
+		accessKey:  cfg.AccessKey,
+		secretKey:  cfg.SecretKey,
+	}, nil
+}
+
+// Send sends an email via SES
+func (s *SESSender) Send(ctx context.Context, email *OutgoingEmail) error {
+	var payload sesSendEmailRequest
+	payload.FromEmailAddress = email.From
+	payload.Destination.ToAddresses = []string{email.To}
+	payload.Content.Simple.Subject = sesContent{Data: email.Subject, Charset: sesCharset}
+	payload.Content.Simple.Body.HTML = &sesContent{Data: email.HTML, Charset: sesCharset}
+	if email.Text != "" {
+		payload.Content.Simple.Body.Text = &sesContent{Data: email.Text, Charset: sesCharset}
+	}
+
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ses request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	awssig.SignRequest(req, body, awssig.Credentials{AccessKey: s.accessKey, SecretKey: s.secretKey}, s.region, sesService, time.Now())
+
+	return callEmailAPI(s.httpClient, req)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// SMTPSender delivers emails through an SMTP server
+type SMTPSender struct {
+	cfg *config.EmailConfig
+}
+
+// NewSMTPSender creates an SMTP sender
+func NewSMTPSender(cfg *config.EmailConfig) *SMTPSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send sends an email via SMTP
+func (s *SMTPSender) Send(_ context.Context, email *OutgoingEmail) error {
+	from := email.From
+	to := email.To
+
+	message, err := buildEmailMessage(from, to, email.Subject, email.HTML, email.Text)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	// Send via SMTP
+	addr := fmt.Sprintf("%s:%d", s.cfg.SMTPHost, s.cfg.SMTPPort)
+
+	// Credentials are read per email, so rotated ones are picked up
+	user, password := s.cfg.CurrentSMTPCredentials()
+
+	// For development (MailHog), we don't need authentication
+	if user == "" && password == "" {
+		// Connect without auth
+		c, dialErr := smtp.Dial(addr)
+		if dialErr != nil {
+			return fmt.Errorf("failed to connect to SMTP server: %w", dialErr)
+		}
+		defer c.Close()
+
+		if mailErr := c.Mail(from); mailErr != nil {
+			return fmt.Errorf("failed to set sender: %w", mailErr)
+		}
+
+		if rcptErr := c.Rcpt(to); rcptErr != nil {
+			return fmt.Errorf("failed to set recipient: %w", rcptErr)
+		}
+
+		wc, dataErr := c.Data()
+		if dataErr != nil {
+			return fmt.Errorf("failed to create data writer: %w", dataErr)
+		}
+		defer wc.Close()
+
+		if _, writeErr := wc.Write([]byte(message)); writeErr != nil {
+			return fmt.Errorf("failed to write message: %w", writeErr)
+		}
+
+		return nil
+	}
+
+	// For production with authentication
+	auth := smtp.PlainAuth("", user, password, s.cfg.SMTPHost)
+	err = smtp.SendMail(addr, auth, from, []string{to}, []byte(message))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// buildEmailMessage builds the MIME message. Emails with a plain-text
+// alternative are sent as multipart/alternative, the others as HTML only.
+func buildEmailMessage(from, to, subject, html, text string) (string, error) {
+	header := fmt.Sprintf("From: %s\r\n", from) +
+		fmt.Sprintf("To: %s\r\n", to) +
+		fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject)) +
+		"MIME-Version: 1.0\r\n"
+
+	if text == "" {
+		return header +
+			"Content-Type: text/html; charset=UTF-8\r\n" +
+			"\r\n" +
+			html, nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	}
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return "", err
+		}
+		if _, err := pw.Write([]byte(part.content)); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	return header +
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n", mw.Boundary()) +
+		"\r\n" +
+		body.String(), nil
+}
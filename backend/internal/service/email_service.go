@@ -1,21 +1,30 @@
 package service
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"html/template"
-	"net/smtp"
+	"net/http"
+	"time"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
 )
 
 // EmailService handles email sending
 type EmailService struct {
-	cfg  *config.EmailConfig
-	nats *nats.Conn
+	cfg              *config.EmailConfig
+	js               nats.JetStreamContext
+	outbox           *repository.OutboxRepository
+	httpClient       *http.Client
+	notificationRepo *repository.NotificationRepository
+	suppressionRepo  *repository.EmailSuppressionRepository
+	redis            *redis.Client
 }
 
 type EmailMessage struct {
@@ -25,22 +34,54 @@ type EmailMessage struct {
 	Data    map[string]interface{} `json:"data"`
 }
 
-// NewEmailService creates a new email service
-func NewEmailService(cfg *config.EmailConfig, nc *nats.Conn) *EmailService {
-	return &EmailService{
-		cfg:  cfg,
-		nats: nc,
+// NewEmailService creates a new email service and ensures the email streams
+// exist. Emails are queued through outbox, so they survive NATS outages.
+// notificationRepo provides the notification preferences of users,
+// suppressionRepo the addresses that bounced or complained and redisClient
+// counts sends for the rate limits.
+func NewEmailService(
+	cfg *config.EmailConfig,
+	nc *nats.Conn,
+	outbox *repository.OutboxRepository,
+	notificationRepo *repository.NotificationRepository,
+	suppressionRepo *repository.EmailSuppressionRepository,
+	redisClient *redis.Client,
+) (*EmailService, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	if err := ensureEmailStreams(js); err != nil {
+		return nil, err
 	}
+
+	return &EmailService{
+		cfg:              cfg,
+		js:               js,
+		outbox:           outbox,
+		httpClient:       &http.Client{Timeout: emailProviderTimeout},
+		notificationRepo: notificationRepo,
+		suppressionRepo:  suppressionRepo,
+		redis:            redisClient,
+	}, nil
 }
 
-// PublishEmail publishes an email message to NATS queue
+// PublishEmail queues an email message on the JetStream email queue through
+// the outbox. Emails to suppressed addresses are dropped.
 func (s *EmailService) PublishEmail(msg *EmailMessage) error {
+	if s.isSuppressed(msg.To) {
+		hlog.Warnf("Dropped %s email to suppressed address %s", msg.Type, msg.To)
+		return nil
+	}
+
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal email message: %w", err)
 	}
 
-	if err := s.nats.Publish("emails", data); err != nil {
+	ctx := context.Background()
+	if err := s.outbox.Enqueue(ctx, newOutboxMessage(ctx, EmailSubject, data, true)); err != nil {
 		return fmt.Errorf("failed to publish email: %w", err)
 	}
 
@@ -87,8 +128,17 @@ func (s *EmailService) SendEmailVerification(to, name, token, verifyURL string)
 	})
 }
 
-// SendWorkspaceInvite sends a workspace invitation email
-func (s *EmailService) SendWorkspaceInvite(to, workspaceName, inviterName, inviteURL string) error {
+// SendWorkspaceInvite sends a workspace invitation email, unless the invitee
+// has an account and turned invitation emails off
+func (s *EmailService) SendWorkspaceInvite(ctx context.Context, to, workspaceName, inviterName, inviteURL string) error {
+	prefs, err := s.notificationRepo.GetPreferencesByEmail(ctx, to)
+	if err != nil {
+		return err
+	}
+	if prefs != nil && !prefs.EmailOnInvite {
+		return nil
+	}
+
 	return s.PublishEmail(&EmailMessage{
 		To:      to,
 		Subject: fmt.Sprintf("You've been invited to %s", workspaceName),
@@ -101,22 +151,102 @@ func (s *EmailService) SendWorkspaceInvite(to, workspaceName, inviterName, invit
 	})
 }
 
-// EmailWorker processes email messages from NATS queue
+// SendAssetQuarantined notifies a user that an uploaded file was quarantined
+func (s *EmailService) SendAssetQuarantined(to, name, filename, workspaceName, signature string) error {
+	return s.PublishEmail(&EmailMessage{
+		To:      to,
+		Subject: fmt.Sprintf("A file in %s was quarantined", workspaceName),
+		Type:    "asset_quarantined",
+		Data: map[string]interface{}{
+			"name":           name,
+			"filename":       filename,
+			"workspace_name": workspaceName,
+			"signature":      signature,
+		},
+	})
+}
+
+// SendMentionDigest sends a digest of unread mentions. Each mention has the
+// keys actor_name, workspace_name, excerpt and url.
+func (s *EmailService) SendMentionDigest(to, name string, mentions []map[string]interface{}) error {
+	subject := "You were mentioned on HertzBoard"
+	if len(mentions) > 1 {
+		subject = fmt.Sprintf("You were mentioned %d times on HertzBoard", len(mentions))
+	}
+
+	return s.PublishEmail(&EmailMessage{
+		To:      to,
+		Subject: subject,
+		Type:    "mention_digest",
+		Data: map[string]interface{}{
+			"name":     name,
+			"mentions": mentions,
+		},
+	})
+}
+
+// EmailWorker processes email messages from the JetStream email queue.
+// Failed emails are retried with exponential backoff and moved to the dead
+// letter stream after the last attempt.
 type EmailWorker struct {
-	cfg  *config.EmailConfig
-	nats *nats.Conn
-	sub  *nats.Subscription
+	cfg             *config.EmailConfig
+	js              nats.JetStreamContext
+	sub             *nats.Subscription
+	templates       *EmailTemplates
+	sender          EmailSender
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	maxAttempts     int
 }
 
 // NewEmailWorker creates a new email worker
 func NewEmailWorker(cfg *config.EmailConfig, nc *nats.Conn) (*EmailWorker, error) {
+	templates, err := NewEmailTemplates(cfg.TemplatesDir, cfg.ReloadTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	sender, err := NewEmailSender(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email sender: %w", err)
+	}
+
+	retryBackoff, maxRetryBackoff, err := emailRetryConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultEmailMaxAttempts
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	if err := ensureEmailStreams(js); err != nil {
+		return nil, err
+	}
+
 	worker := &EmailWorker{
-		cfg:  cfg,
-		nats: nc,
+		cfg:             cfg,
+		js:              js,
+		templates:       templates,
+		sender:          sender,
+		retryBackoff:    retryBackoff,
+		maxRetryBackoff: maxRetryBackoff,
+		maxAttempts:     maxAttempts,
 	}
 
-	// Subscribe to email queue
-	sub, err := nc.QueueSubscribe("emails", "email-workers", worker.handleMessage)
+	// Subscribe to email queue with a durable consumer shared by all workers
+	sub, err := js.QueueSubscribe(EmailSubject, emailConsumerName, worker.handleMessage,
+		nats.Durable(emailConsumerName),
+		nats.BindStream(emailStreamName),
+		nats.ManualAck(),
+		nats.AckWait(emailAckWait),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe to email queue: %w", err)
 	}
@@ -135,160 +265,104 @@ func (w *EmailWorker) Close() error {
 
 // handleMessage processes an email message
 func (w *EmailWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "email.send")
+	defer span.End()
+
 	var emailMsg EmailMessage
 	if err := json.Unmarshal(msg.Data, &emailMsg); err != nil {
-		fmt.Printf("Failed to unmarshal email message: %v\n", err)
+		hlog.CtxErrorf(ctx, "Failed to unmarshal email message: %v", err)
+		_ = msg.Term()
+		return
+	}
+
+	attempt := 1
+	if meta, err := msg.Metadata(); err == nil {
+		attempt = int(meta.NumDelivered)
+	}
+
+	sendErr := w.sendEmail(ctx, &emailMsg)
+	span.RecordError(sendErr)
+	if sendErr == nil {
+		if err := msg.Ack(); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to ack email to %s: %v", emailMsg.To, err)
+		}
+		hlog.CtxInfof(ctx, "Email sent successfully to %s", emailMsg.To)
 		return
 	}
 
-	if err := w.sendEmail(&emailMsg); err != nil {
-		fmt.Printf("Failed to send email to %s: %v\n", emailMsg.To, err)
-		// TODO: Implement retry logic with exponential backoff
+	if attempt < w.maxAttempts {
+		delay := retryDelay(attempt, w.retryBackoff, w.maxRetryBackoff)
+		hlog.CtxWarnf(ctx, "Failed to send email to %s (attempt %d/%d), retrying in %s: %v",
+			emailMsg.To, attempt, w.maxAttempts, delay, sendErr)
+		_ = msg.NakWithDelay(delay)
 		return
 	}
 
-	fmt.Printf("Email sent successfully to %s\n", emailMsg.To)
+	hlog.CtxErrorf(ctx, "Failed to send email to %s after %d attempts: %v", emailMsg.To, attempt, sendErr)
+	if err := w.deadLetter(&emailMsg, attempt, sendErr); err != nil {
+		// Keep the message on the queue rather than losing it
+		hlog.CtxErrorf(ctx, "Failed to dead-letter email to %s: %v", emailMsg.To, err)
+		_ = msg.NakWithDelay(w.maxRetryBackoff)
+		return
+	}
+	_ = msg.Term()
 }
 
-// sendEmail sends an actual email via SMTP
-func (w *EmailWorker) sendEmail(msg *EmailMessage) error {
-	// Generate email body from template
-	body, err := w.renderTemplate(msg.Type, msg.Data)
+// deadLetter moves an email that failed every attempt to the dead letter stream
+func (w *EmailWorker) deadLetter(msg *EmailMessage, attempts int, sendErr error) error {
+	data, err := json.Marshal(&DeadLetterEmail{
+		FailedAt: time.Now(),
+		Error:    sendErr.Error(),
+		Message:  *msg,
+		Attempts: attempts,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
 	}
 
-	// Prepare email
-	from := w.cfg.From
-	to := msg.To
-	subject := msg.Subject
-
-	message := fmt.Sprintf("From: %s\r\n", from) +
-		fmt.Sprintf("To: %s\r\n", to) +
-		fmt.Sprintf("Subject: %s\r\n", subject) +
-		"MIME-Version: 1.0\r\n" +
-		"Content-Type: text/html; charset=UTF-8\r\n" +
-		"\r\n" +
-		body
-
-	// Send via SMTP
-	addr := fmt.Sprintf("%s:%d", w.cfg.SMTPHost, w.cfg.SMTPPort)
-
-	// For development (MailHog), we don't need authentication
-	if w.cfg.SMTPUser == "" && w.cfg.SMTPPassword == "" {
-		// Connect without auth
-		c, dialErr := smtp.Dial(addr)
-		if dialErr != nil {
-			return fmt.Errorf("failed to connect to SMTP server: %w", dialErr)
-		}
-		defer c.Close()
-
-		if mailErr := c.Mail(from); mailErr != nil {
-			return fmt.Errorf("failed to set sender: %w", mailErr)
-		}
+	if _, err := w.js.Publish(EmailDeadLetterSubject, data); err != nil {
+		return fmt.Errorf("failed to publish dead letter: %w", err)
+	}
+	return nil
+}
 
-		if rcptErr := c.Rcpt(to); rcptErr != nil {
-			return fmt.Errorf("failed to set recipient: %w", rcptErr)
-		}
+// emailRetryConfig parses the retry backoff settings, falling back to defaults
+func emailRetryConfig(cfg *config.EmailConfig) (backoff, maxBackoff time.Duration, err error) {
+	backoff, maxBackoff = defaultEmailRetryBackoff, defaultEmailMaxRetryBackoff
 
-		wc, dataErr := c.Data()
-		if dataErr != nil {
-			return fmt.Errorf("failed to create data writer: %w", dataErr)
+	if cfg.RetryBackoff != "" {
+		if backoff, err = cfg.GetRetryBackoffDuration(); err != nil {
+			return 0, 0, fmt.Errorf("invalid email retry backoff: %w", err)
 		}
-		defer wc.Close()
-
-		if _, writeErr := wc.Write([]byte(message)); writeErr != nil {
-			return fmt.Errorf("failed to write message: %w", writeErr)
+	}
+	if cfg.MaxRetryBackoff != "" {
+		if maxBackoff, err = cfg.GetMaxRetryBackoffDuration(); err != nil {
+			return 0, 0, fmt.Errorf("invalid email max retry backoff: %w", err)
 		}
-
-		return nil
 	}
-
-	// For production with authentication
-	auth := smtp.PlainAuth("", w.cfg.SMTPUser, w.cfg.SMTPPassword, w.cfg.SMTPHost)
-	err = smtp.SendMail(addr, auth, from, []string{to}, []byte(message))
-	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	if backoff <= 0 || maxBackoff < backoff {
+		return 0, 0, fmt.Errorf("email retry backoff must be positive and not exceed the max retry backoff")
 	}
 
-	return nil
+	return backoff, maxBackoff, nil
 }
 
-// renderTemplate renders an email template
-func (w *EmailWorker) renderTemplate(templateType string, data map[string]interface{}) (string, error) {
-	templates := map[string]string{
-		"welcome": `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body>
-    <h1>Welcome to HertzBoard, {{.name}}!</h1>
-    <p>We're excited to have you on board.</p>
-    <p>Get started by creating your first workspace and start collaborating!</p>
-</body>
-</html>
-`,
-		"password_reset": `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body>
-    <h1>Reset your password</h1>
-    <p>Hello {{.name}},</p>
-    <p>You requested to reset your password. Click the link below to continue:</p>
-    <p><a href="{{.reset_url}}?token={{.token}}">Reset Password</a></p>
-    <p>This link will expire in 1 hour.</p>
-    <p>If you didn't request this, you can safely ignore this email.</p>
-</body>
-</html>
-`,
-		"email_verification": `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body>
-    <h1>Verify your email</h1>
-    <p>Hello {{.name}},</p>
-    <p>Please verify your email address by clicking the link below:</p>
-    <p><a href="{{.verify_url}}?token={{.token}}">Verify Email</a></p>
-</body>
-</html>
-`,
-		"workspace_invite": `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body>
-    <h1>You've been invited to {{.workspace_name}}</h1>
-    <p>{{.inviter_name}} has invited you to collaborate on {{.workspace_name}}.</p>
-    <p><a href="{{.invite_url}}">Accept Invitation</a></p>
-</body>
-</html>
-`,
-	}
-
-	tmplStr, exists := templates[templateType]
-	if !exists {
-		return "", fmt.Errorf("template not found: %s", templateType)
-	}
-
-	tmpl, err := template.New(templateType).Parse(tmplStr)
+// sendEmail renders an email and delivers it through the configured provider
+func (w *EmailWorker) sendEmail(ctx context.Context, msg *EmailMessage) error {
+	// Generate email body from template
+	html, text, err := w.templates.Render(msg.Type, msg.Data)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
-	}
+	ctx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+	defer cancel()
 
-	return buf.String(), nil
+	return w.sender.Send(ctx, &OutgoingEmail{
+		From:    w.cfg.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		HTML:    html,
+		Text:    text,
+	})
 }
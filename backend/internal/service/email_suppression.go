@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// emailSuppressionTimeout bounds the suppression lookup before an email is queued
+const emailSuppressionTimeout = 5 * time.Second
+
+// ErrEmailSuppressionNotFound is returned when lifting a suppression of an
+// address that isn't suppressed
+var ErrEmailSuppressionNotFound = errors.New("email suppression not found")
+
+// normalizeEmail returns the form addresses are suppressed under
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// feedbackSuppression returns the suppression a bounce or complaint calls
+// for, nil for deliveries and temporary bounces
+func feedbackSuppression(f *EmailFeedback) *models.EmailSuppression {
+	var reason string
+	switch {
+	case f.Type == EmailFeedbackBounced && f.Permanent:
+		reason = models.EmailSuppressionBounced
+	case f.Type == EmailFeedbackComplained:
+		reason = models.EmailSuppressionComplained
+	default:
+		return nil
+	}
+
+	return &models.EmailSuppression{
+		Email:    normalizeEmail(f.Email),
+		Reason:   reason,
+		Provider: f.Provider,
+		Detail:   f.Reason,
+	}
+}
+
+// isSuppressed reports whether emails to the address are suppressed. Lookup
+// failures are logged and let the email through.
+func (s *EmailService) isSuppressed(to string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), emailSuppressionTimeout)
+	defer cancel()
+
+	suppression, err := s.suppressionRepo.GetByEmail(ctx, normalizeEmail(to))
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to check email suppression of %s: %v", to, err)
+		return false
+	}
+	return suppression != nil
+}
+
+// GetSuppressions returns the suppressions of the given addresses, keyed by
+// their normalized form
+func (s *EmailService) GetSuppressions(ctx context.Context, emails []string) (map[string]*models.EmailSuppression, error) {
+	normalized := make([]string, len(emails))
+	for i, email := range emails {
+		normalized[i] = normalizeEmail(email)
+	}
+	return s.suppressionRepo.GetByEmails(ctx, normalized)
+}
+
+// ListSuppressions returns suppressed addresses, most recently updated first
+func (s *EmailService) ListSuppressions(ctx context.Context, limit, offset int) ([]models.EmailSuppression, error) {
+	return s.suppressionRepo.List(ctx, limit, offset)
+}
+
+// Unsuppress lifts the suppression of an address, e.g. after its owner fixed
+// their mailbox
+func (s *EmailService) Unsuppress(ctx context.Context, email string) error {
+	deleted, err := s.suppressionRepo.Delete(ctx, normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrEmailSuppressionNotFound
+	}
+
+	hlog.CtxInfof(ctx, "Lifted email suppression of %s", email)
+	return nil
+}
+
+// suppress records the suppression a bounce or complaint calls for
+func (s *EmailService) suppress(ctx context.Context, f *EmailFeedback) error {
+	suppression := feedbackSuppression(f)
+	if suppression == nil || suppression.Email == "" {
+		return nil
+	}
+
+	if err := s.suppressionRepo.Upsert(ctx, suppression); err != nil {
+		return fmt.Errorf("failed to suppress %s: %w", suppression.Email, err)
+	}
+
+	hlog.CtxInfof(ctx, "Suppressed emails to %s after %s", suppression.Email, suppression.Reason)
+	return nil
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+//go:embed templates/email
+var embeddedEmailTemplates embed.FS
+
+const (
+	emailTemplatesRoot  = "templates/email"
+	emailLayoutTemplate = "layout"
+	emailLayoutsDir     = "layouts"
+	emailPartialsDir    = "partials"
+	emailHTMLExt        = ".html"
+	emailTextExt        = ".txt"
+)
+
+// EmailTemplates renders emails from the embedded templates. Every email type
+// has an HTML file and an optional plain-text alternative that define the
+// "title" and "content" blocks used by the layout of the same format.
+// Partials are shared by all emails.
+//
+// Files in the override directory replace embedded files with the same path,
+// so operators can restyle single emails without rebuilding.
+type EmailTemplates struct {
+	files  fs.FS
+	html   map[string]*htmltemplate.Template
+	text   map[string]*texttemplate.Template
+	mu     sync.RWMutex
+	reload bool
+}
+
+// NewEmailTemplates loads the email templates. With reload set, templates are
+// read again for every email, which is meant for development.
+func NewEmailTemplates(overrideDir string, reload bool) (*EmailTemplates, error) {
+	embedded, err := fs.Sub(embeddedEmailTemplates, emailTemplatesRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded email templates: %w", err)
+	}
+
+	files := embedded
+	if overrideDir != "" {
+		files = &overlayFS{upper: os.DirFS(overrideDir), lower: embedded}
+	}
+
+	t := &EmailTemplates{files: files, reload: reload}
+	// Fail on startup rather than on the first email
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Render returns the HTML body and the plain-text alternative of an email.
+// The text is empty when the email type has no text template.
+func (t *EmailTemplates) Render(templateType string, data map[string]interface{}) (html, text string, err error) {
+	if t.reload {
+		if err := t.load(); err != nil {
+			return "", "", err
+		}
+	}
+
+	t.mu.RLock()
+	htmlTmpl, exists := t.html[templateType]
+	textTmpl := t.text[templateType]
+	t.mu.RUnlock()
+
+	if !exists {
+		return "", "", fmt.Errorf("template not found: %s", templateType)
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTmpl.ExecuteTemplate(&buf, emailLayoutTemplate, data); err != nil {
+		return "", "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	html = buf.String()
+
+	if textTmpl != nil {
+		buf.Reset()
+		if err := textTmpl.ExecuteTemplate(&buf, emailLayoutTemplate, data); err != nil {
+			return "", "", fmt.Errorf("failed to execute text template: %w", err)
+		}
+		text = buf.String()
+	}
+
+	return html, text, nil
+}
+
+// load parses all templates and swaps them in
+func (t *EmailTemplates) load() error {
+	html, err := t.loadHTML()
+	if err != nil {
+		return err
+	}
+	text, err := t.loadText()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.html = html
+	t.text = text
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *EmailTemplates) loadHTML() (map[string]*htmltemplate.Template, error) {
+	root := htmltemplate.New(emailLayoutTemplate).Funcs(htmltemplate.FuncMap{"dict": templateDict})
+	err := t.parseShared(emailHTMLExt, func(name, content string) error {
+		tmpl := root
+		if name != emailLayoutTemplate {
+			tmpl = root.New(name)
+		}
+		_, err := tmpl.Parse(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*htmltemplate.Template)
+	err = t.parseEmails(emailHTMLExt, func(emailType, content string) error {
+		tmpl, err := root.Clone()
+		if err == nil {
+			_, err = tmpl.New(emailType).Parse(content)
+		}
+		templates[emailType] = tmpl
+		return err
+	})
+	return templates, err
+}
+
+func (t *EmailTemplates) loadText() (map[string]*texttemplate.Template, error) {
+	root := texttemplate.New(emailLayoutTemplate).Funcs(texttemplate.FuncMap{"dict": templateDict})
+	err := t.parseShared(emailTextExt, func(name, content string) error {
+		tmpl := root
+		if name != emailLayoutTemplate {
+			tmpl = root.New(name)
+		}
+		_, err := tmpl.Parse(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*texttemplate.Template)
+	err = t.parseEmails(emailTextExt, func(emailType, content string) error {
+		tmpl, err := root.Clone()
+		if err == nil {
+			_, err = tmpl.New(emailType).Parse(content)
+		}
+		templates[emailType] = tmpl
+		return err
+	})
+	return templates, err
+}
+
+// parseShared parses the layout and the partials of a format. The layout is
+// parsed as the root template so executing it renders the whole email.
+func (t *EmailTemplates) parseShared(ext string, parse func(name, content string) error) error {
+	layout := path.Join(emailLayoutsDir, "base"+ext)
+	content, err := fs.ReadFile(t.files, layout)
+	if err != nil {
+		return fmt.Errorf("failed to read email layout: %w", err)
+	}
+	if err := parse(emailLayoutTemplate, string(content)); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", layout, err)
+	}
+
+	partials, err := fs.Glob(t.files, path.Join(emailPartialsDir, "*"+ext))
+	if err != nil {
+		return fmt.Errorf("failed to list email partials: %w", err)
+	}
+	for _, name := range partials {
+		content, err := fs.ReadFile(t.files, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := parse(name, string(content)); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseEmails parses every email template of a format, named after the file
+func (t *EmailTemplates) parseEmails(ext string, parse func(emailType, content string) error) error {
+	names, err := fs.Glob(t.files, "*"+ext)
+	if err != nil {
+		return fmt.Errorf("failed to list email templates: %w", err)
+	}
+	for _, name := range names {
+		content, err := fs.ReadFile(t.files, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := parse(strings.TrimSuffix(name, ext), string(content)); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// templateDict builds a map from key/value pairs, so partials can take
+// several arguments: {{template "button" dict "url" .url "label" "Open"}}
+func templateDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict needs key/value pairs")
+	}
+	dict := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings")
+		}
+		dict[key] = pairs[i+1]
+	}
+	return dict, nil
+}
+
+// overlayFS serves files from upper and falls back to lower. Directory
+// listings are merged.
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	return o.lower.Open(name)
+}
+
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries := make(map[string]fs.DirEntry)
+	found := false
+	for _, fsys := range []fs.FS{o.lower, o.upper} {
+		dir, err := fs.ReadDir(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range dir {
+			entries[entry.Name()] = entry
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+// EmailNotVerifiedCode is the error code of requests blocked by the email
+// verification policy, clients use it to prompt for verification
+const EmailNotVerifiedCode = "email_not_verified"
+
+// ErrEmailNotVerified is returned for actions that require a verified email
+var ErrEmailNotVerified = errors.New("verify your email address with the link in the verification email to continue")
+
+// EmailVerificationPolicy restricts actions that reach people outside the
+// account, such as invites, public sharing and uploads, to users with a
+// verified email address
+type EmailVerificationPolicy struct {
+	userRepo *repository.UserRepository
+	required atomic.Bool
+}
+
+// NewEmailVerificationPolicy creates a new policy. When required is false
+// every user passes.
+func NewEmailVerificationPolicy(userRepo *repository.UserRepository, required bool) *EmailVerificationPolicy {
+	p := &EmailVerificationPolicy{userRepo: userRepo}
+	p.required.Store(required)
+	return p
+}
+
+// SetRequired turns the policy on or off, e.g. after a config reload
+func (p *EmailVerificationPolicy) SetRequired(required bool) {
+	p.required.Store(required)
+}
+
+// Check returns ErrEmailNotVerified if the policy is enabled and the user
+// hasn't verified their email. The user is read from the database because
+// verification can happen after the access token was issued.
+func (p *EmailVerificationPolicy) Check(ctx context.Context, userID uuid.UUID) error {
+	if p == nil || !p.required.Load() {
+		return nil
+	}
+
+	user, err := p.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	embedTokenPrefix           = "hbe_"
+	embedTokenBytes            = 24
+	embedTokenDisplayLength    = 12
+	maxEmbedTokensPerWorkspace = 20
+	maxEmbedTokenNameLength    = 100
+)
+
+var (
+	// ErrEmbedTokenNotFound is returned for unknown embed tokens
+	ErrEmbedTokenNotFound = errors.New("embed token not found")
+	// ErrEmbedTokenLimitReached is returned when a workspace has too many
+	// embed tokens
+	ErrEmbedTokenLimitReached = errors.New("embed token limit reached")
+	// ErrInvalidEmbedToken is returned for unknown and expired tokens and
+	// tokens whose creator left the workspace
+	ErrInvalidEmbedToken = errors.New("invalid embed token")
+)
+
+// EmbedService manages embed tokens and serves the boards they give
+// read-only access to
+type EmbedService struct {
+	embedRepo     *repository.EmbedTokenRepository
+	workspaceRepo *repository.WorkspaceRepository
+	canvasService *CanvasService
+	ipAllowlist   *IPAllowlistService
+}
+
+// NewEmbedService creates a new embed service
+func NewEmbedService(
+	embedRepo *repository.EmbedTokenRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	canvasService *CanvasService,
+	ipAllowlist *IPAllowlistService,
+) *EmbedService {
+	return &EmbedService{
+		embedRepo:     embedRepo,
+		workspaceRepo: workspaceRepo,
+		canvasService: canvasService,
+		ipAllowlist:   ipAllowlist,
+	}
+}
+
+// CreateEmbedToken creates an embed token of a workspace and returns it
+// with the token
+func (s *EmbedService) CreateEmbedToken(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.CreateEmbedTokenRequest,
+) (*models.EmbedTokenWithSecret, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxEmbedTokenNameLength {
+		return nil, fmt.Errorf("name is required and must be at most %d characters", maxEmbedTokenNameLength)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	existing, err := s.embedRepo.ListEmbedTokens(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxEmbedTokensPerWorkspace {
+		return nil, ErrEmbedTokenLimitReached
+	}
+
+	b := make([]byte, embedTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate embed token: %w", err)
+	}
+	secret := embedTokenPrefix + hex.EncodeToString(b)
+
+	token := &models.EmbedToken{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		CreatedBy:   userID,
+		Name:        name,
+		Prefix:      secret[:embedTokenDisplayLength],
+		TokenHash:   hashAPIKey(secret),
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	if err := s.embedRepo.CreateEmbedToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &models.EmbedTokenWithSecret{EmbedToken: *token, Token: secret}, nil
+}
+
+// ListEmbedTokens returns the embed tokens of a workspace
+func (s *EmbedService) ListEmbedTokens(ctx context.Context, workspaceID uuid.UUID) ([]models.EmbedToken, error) {
+	return s.embedRepo.ListEmbedTokens(ctx, workspaceID)
+}
+
+// DeleteEmbedToken revokes an embed token
+func (s *EmbedService) DeleteEmbedToken(ctx context.Context, workspaceID, tokenID uuid.UUID) error {
+	deleted, err := s.embedRepo.DeleteEmbedToken(ctx, workspaceID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrEmbedTokenNotFound
+	}
+	return nil
+}
+
+// GetBoard returns the board an embed token gives access to. The IP
+// allowlist of the workspace applies to embeds too, so viewers outside of
+// it get ErrIPNotAllowed.
+func (s *EmbedService) GetBoard(ctx context.Context, secret, clientIP string) (*models.EmbedBoard, error) {
+	token, err := s.authenticate(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, token.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, ErrInvalidEmbedToken
+	}
+
+	if err := s.ipAllowlist.CheckAccess(ctx, workspace.ID, uuid.Nil, clientIP); err != nil {
+		return nil, err
+	}
+	restricted, err := s.ipAllowlist.Restricted(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := s.canvasService.GetWorkspaceElements(ctx, token.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.EmbedBoard{
+		WorkspaceID: workspace.ID,
+		Name:        workspace.Name,
+		Elements:    make([]models.EmbedElement, len(elements)),
+		Restricted:  restricted,
+	}
+	for i := range elements {
+		board.Elements[i] = models.EmbedElement{
+			ID:          elements[i].ID,
+			ParentID:    elements[i].ParentID,
+			ElementType: elements[i].ElementType,
+			ElementData: elements[i].ElementData,
+			ZIndex:      elements[i].ZIndex,
+		}
+	}
+
+	return board, nil
+}
+
+// authenticate returns the embed token of a request. Expired tokens and
+// tokens whose creator is no longer a member of the workspace are refused.
+func (s *EmbedService) authenticate(ctx context.Context, secret string) (*models.EmbedToken, error) {
+	if !strings.HasPrefix(secret, embedTokenPrefix) {
+		return nil, ErrInvalidEmbedToken
+	}
+
+	token, err := s.embedRepo.GetEmbedTokenByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || (token.ExpiresAt != nil && token.ExpiresAt.Before(time.Now())) {
+		return nil, ErrInvalidEmbedToken
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, token.WorkspaceID, token.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrInvalidEmbedToken
+	}
+
+	if err := s.embedRepo.MarkEmbedTokenUsed(ctx, token.ID); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to record use of embed token %s: %v", token.ID, err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+// EventSubjectPrefix is the NATS subject prefix domain events are published
+// under, e.g. events.snapshot.created. Subscribe to "events.>" for all of them.
+const EventSubjectPrefix = "events."
+
+// EventPublisher publishes domain events to NATS through the transactional
+// outbox, the outbox relay delivers them at least once
+type EventPublisher struct {
+	outbox *repository.OutboxRepository
+}
+
+// NewEventPublisher creates a new event publisher
+func NewEventPublisher(outbox *repository.OutboxRepository) *EventPublisher {
+	return &EventPublisher{outbox: outbox}
+}
+
+// Publish publishes an event of the given type on its own. A nil actor marks
+// a system action. The trace of ctx is carried to the consumers. Events of
+// changes written in a transaction use event instead, so they are only
+// published when it commits.
+func (p *EventPublisher) Publish(
+	ctx context.Context,
+	eventType string,
+	workspaceID uuid.UUID,
+	actorID *uuid.UUID,
+	data interface{},
+) error {
+	msg, err := newEventMessage(ctx, eventType, workspaceID, actorID, data)
+	if err != nil {
+		return err
+	}
+
+	if err := p.outbox.Enqueue(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
+
+// event returns the outbox message of an event for a repository to write in
+// the transaction of the change. Like emit it only logs failures, a nil
+// publisher or an event that can't be encoded returns nil.
+func (p *EventPublisher) event(
+	ctx context.Context,
+	eventType string,
+	workspaceID uuid.UUID,
+	actorID *uuid.UUID,
+	data interface{},
+) *models.OutboxMessage {
+	if p == nil {
+		return nil
+	}
+	msg, err := newEventMessage(ctx, eventType, workspaceID, actorID, data)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to encode %s event for workspace %s: %v", eventType, workspaceID, err)
+		return nil
+	}
+	return msg
+}
+
+// emit publishes an event and only logs failures, so the action that caused
+// the event doesn't fail when the outbox can't be written. A nil publisher
+// drops events.
+func (p *EventPublisher) emit(
+	ctx context.Context,
+	eventType string,
+	workspaceID uuid.UUID,
+	actorID *uuid.UUID,
+	data interface{},
+) {
+	if p == nil {
+		return
+	}
+	if err := p.Publish(ctx, eventType, workspaceID, actorID, data); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to publish %s event for workspace %s: %v", eventType, workspaceID, err)
+	}
+}
+
+func newEventMessage(
+	ctx context.Context,
+	eventType string,
+	workspaceID uuid.UUID,
+	actorID *uuid.UUID,
+	data interface{},
+) (*models.OutboxMessage, error) {
+	event := &models.Event{
+		ID:          uuid.New(),
+		Type:        eventType,
+		WorkspaceID: workspaceID,
+		ActorID:     actorID,
+		OccurredAt:  time.Now(),
+		Data:        data,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return newOutboxMessage(ctx, EventSubjectPrefix+eventType, payload, false), nil
+}
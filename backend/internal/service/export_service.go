@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	// BoardExportSubject is the NATS subject export jobs are published to
+	BoardExportSubject = "exports.board"
+
+	// maxListedExports is how many of the latest exports are listed
+	maxListedExports = 20
+	// exportDownloadExpiry is how long the download link of an export works
+	exportDownloadExpiry = time.Hour
+	exportCacheControl   = "private, max-age=3600"
+)
+
+// ErrExportNotFound is returned for unknown exports
+var ErrExportNotFound = errors.New("export not found")
+
+// exportFileTypes are the extension and content type of the documents of
+// each format
+var exportFileTypes = map[string]struct{ extension, contentType string }{
+	models.ExportFormatMarkdown:       {".md", "text/markdown; charset=utf-8"},
+	models.ExportFormatConfluence:     {".txt", "text/plain; charset=utf-8"},
+	models.ExportFormatOperationsCSV:  {".csv", "text/csv; charset=utf-8"},
+	models.ExportFormatOperationsJSON: {".json", "application/json"},
+}
+
+// documentFormats are the formats of board documents, reportFormats those of
+// operation reports. Each are only listed and served by their own endpoints.
+var (
+	documentFormats = []string{models.ExportFormatMarkdown, models.ExportFormatConfluence}
+	reportFormats   = []string{models.ExportFormatOperationsCSV, models.ExportFormatOperationsJSON}
+)
+
+// ExportService exports boards as documents and their operation history as
+// reports. Exports are rendered in the background by the ExportWorker,
+// documents are stored with the assets and reports in the exports bucket.
+type ExportService struct {
+	exportRepo       *repository.ExportRepository
+	canvasService    *CanvasService
+	workspaceService *WorkspaceService
+	storage          ObjectStorage
+	nats             *nats.Conn
+	metering         *MeteringService
+	operationRepo    *repository.OperationRepository
+	reports          ObjectStorage
+}
+
+// NewExportService creates a new export service
+func NewExportService(
+	exportRepo *repository.ExportRepository,
+	canvasService *CanvasService,
+	workspaceService *WorkspaceService,
+	storage ObjectStorage,
+	nc *nats.Conn,
+	metering *MeteringService,
+	operationRepo *repository.OperationRepository,
+	reports ObjectStorage,
+) *ExportService {
+	return &ExportService{
+		exportRepo:       exportRepo,
+		canvasService:    canvasService,
+		workspaceService: workspaceService,
+		storage:          storage,
+		nats:             nc,
+		metering:         metering,
+		operationRepo:    operationRepo,
+		reports:          reports,
+	}
+}
+
+// CreateExport queues an export of a board
+func (s *ExportService) CreateExport(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.CreateBoardExportRequest,
+) (*models.BoardExport, error) {
+	if !slices.Contains(documentFormats, req.Format) {
+		return nil, fmt.Errorf("format must be %s or %s", models.ExportFormatMarkdown, models.ExportFormatConfluence)
+	}
+
+	export := &models.BoardExport{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		RequestedBy: &userID,
+		Format:      req.Format,
+		Status:      models.ExportStatusPending,
+	}
+	if err := s.queue(ctx, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// queue creates an export and queues it for rendering
+func (s *ExportService) queue(ctx context.Context, export *models.BoardExport) error {
+	if err := s.exportRepo.CreateExport(ctx, export); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&models.BoardExportJob{ExportID: export.ID, WorkspaceID: export.WorkspaceID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal export job: %w", err)
+	}
+	if err := s.nats.PublishMsg(tracing.NewMsg(ctx, BoardExportSubject, data)); err != nil {
+		// The export would never be rendered
+		s.fail(ctx, export.ID, "failed to queue export")
+		return fmt.Errorf("failed to publish export job: %w", err)
+	}
+	return nil
+}
+
+// GetExport returns an export of a board, with its download link once
+// completed
+func (s *ExportService) GetExport(ctx context.Context, workspaceID, id uuid.UUID) (*models.BoardExport, error) {
+	return s.getExport(ctx, workspaceID, id, documentFormats)
+}
+
+// getExport returns an export in formats with its download link once
+// completed
+func (s *ExportService) getExport(
+	ctx context.Context,
+	workspaceID, id uuid.UUID,
+	formats []string,
+) (*models.BoardExport, error) {
+	export, err := s.exportRepo.GetExport(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || !slices.Contains(formats, export.Format) {
+		return nil, ErrExportNotFound
+	}
+
+	if export.Status == models.ExportStatusCompleted && export.ObjectKey != nil {
+		url, err := s.storageFor(export.Format).PresignGet(ctx, *export.ObjectKey, exportDownloadExpiry, exportCacheControl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate download URL: %w", err)
+		}
+		export.DownloadURL = url
+	}
+
+	return export, nil
+}
+
+// ListExports returns the latest exports of a board
+func (s *ExportService) ListExports(ctx context.Context, workspaceID uuid.UUID) ([]models.BoardExport, error) {
+	return s.exportRepo.ListExports(ctx, workspaceID, documentFormats, maxListedExports)
+}
+
+// DeleteExport deletes an export and its document
+func (s *ExportService) DeleteExport(ctx context.Context, workspaceID, id uuid.UUID) error {
+	return s.deleteExport(ctx, workspaceID, id, documentFormats)
+}
+
+// deleteExport deletes an export in formats and its file
+func (s *ExportService) deleteExport(ctx context.Context, workspaceID, id uuid.UUID, formats []string) error {
+	export, err := s.exportRepo.DeleteExport(ctx, workspaceID, id, formats)
+	if err != nil {
+		return err
+	}
+	if export == nil {
+		return ErrExportNotFound
+	}
+
+	if export.ObjectKey != nil {
+		if err := s.storageFor(export.Format).Remove(ctx, *export.ObjectKey); err != nil {
+			hlog.CtxWarnf(ctx, "Failed to remove document of export %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// render renders a queued export and stores its document
+func (s *ExportService) render(ctx context.Context, job *models.BoardExportJob) error {
+	picked, err := s.exportRepo.MarkProcessing(ctx, job.ExportID)
+	if err != nil {
+		return err
+	}
+	if !picked {
+		// Deleted, or redelivered after another worker took it
+		return nil
+	}
+
+	export, err := s.exportRepo.GetExport(ctx, job.WorkspaceID, job.ExportID)
+	if err != nil {
+		return err
+	}
+	if export == nil {
+		return nil
+	}
+	if slices.Contains(reportFormats, export.Format) {
+		return s.renderReport(ctx, export)
+	}
+
+	document, err := s.renderDocument(ctx, export)
+	if err != nil {
+		s.fail(ctx, export.ID, "failed to render the board")
+		return err
+	}
+
+	fileType := exportFileTypes[export.Format]
+	key := fmt.Sprintf("%s/exports/%s%s", export.WorkspaceID, export.ID, fileType.extension)
+	size := int64(len(document))
+	if err := s.storage.Put(ctx, key, strings.NewReader(document), size, fileType.contentType); err != nil {
+		s.fail(ctx, export.ID, "failed to store the document")
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+
+	if err := s.exportRepo.MarkCompleted(ctx, export.ID, key, size); err != nil {
+		return err
+	}
+	s.metering.RecordExport(export.WorkspaceID)
+	return nil
+}
+
+// renderDocument renders the current content of the board of an export
+func (s *ExportService) renderDocument(ctx context.Context, export *models.BoardExport) (string, error) {
+	workspace, err := s.workspaceService.GetWorkspace(ctx, export.WorkspaceID)
+	if err != nil {
+		return "", err
+	}
+
+	elements, err := s.canvasService.GetWorkspaceElements(ctx, export.WorkspaceID)
+	if err != nil {
+		return "", err
+	}
+
+	return renderBoardExport(workspace.Name, elements, export.Format)
+}
+
+// storageFor returns the storage of the files of an export format
+func (s *ExportService) storageFor(format string) ObjectStorage {
+	if slices.Contains(reportFormats, format) {
+		return s.reports
+	}
+	return s.storage
+}
+
+// fail marks an export as failed with a reason shown to the user, also
+// when rendering ran out of time
+func (s *ExportService) fail(ctx context.Context, id uuid.UUID, reason string) {
+	if err := s.exportRepo.MarkFailed(context.WithoutCancel(ctx), id, reason); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to mark export %s as failed: %v", id, err)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const exportRenderTimeout = 2 * time.Minute
+
+// ExportWorker renders queued board exports
+type ExportWorker struct {
+	exportService *ExportService
+	sub           *nats.Subscription
+}
+
+// NewExportWorker creates a new export worker
+func NewExportWorker(nc *nats.Conn, exportService *ExportService) (*ExportWorker, error) {
+	worker := &ExportWorker{
+		exportService: exportService,
+	}
+
+	sub, err := nc.QueueSubscribe(BoardExportSubject, "board-exporters", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to export queue: %w", err)
+	}
+
+	worker.sub = sub
+	return worker, nil
+}
+
+// Close closes the export worker subscription
+func (w *ExportWorker) Close() error {
+	if w.sub != nil {
+		return w.sub.Unsubscribe()
+	}
+	return nil
+}
+
+// handleMessage processes an export job
+func (w *ExportWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "export.render")
+	defer span.End()
+
+	var job models.BoardExportJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal export job: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, exportRenderTimeout)
+	defer cancel()
+
+	if err := w.exportService.render(ctx, &job); err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to render export %s: %v", job.ExportID, err)
+		return
+	}
+
+	hlog.CtxInfof(ctx, "Rendered export %s of workspace %s", job.ExportID, job.WorkspaceID)
+}
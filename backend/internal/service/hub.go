@@ -2,53 +2,85 @@ package service
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 )
 
 const (
-	maxClientsPerRoom   = 100 // Maximum clients allowed in a room
+	maxClientsPerRoom = 100 // Maximum clients allowed in a room
+	// maxAnonymousPerRoom is the stricter limit for anonymous viewers of public rooms
+	maxAnonymousPerRoom = 20
 	roomCleanupInterval = 5 * time.Minute
 	// channelBufferSize is the buffer size for broadcast and other channels
 	channelBufferSize = 256
+	// presenceSweepInterval is how often rooms check for stale clients
+	presenceSweepInterval = 30 * time.Second
+	// stalePresenceTimeout expires clients that have not pinged for this long
+	stalePresenceTimeout = 2 * time.Minute
+	// replayLimit caps the missed messages replayed to a rejoining client,
+	// below its send buffer. Clients that missed more have to resync.
+	replayLimit = 128
 )
 
+// RoomBroadcaster delivers messages to the clients of a workspace room. The
+// Hub does it through its rooms and the broker, the api-gateway can also go
+// through the internal gRPC API of the ws-server.
+type RoomBroadcaster interface {
+	BroadcastToRoom(workspaceID uuid.UUID, msg *models.WSMessage, excludeClientID uuid.UUID)
+}
+
 // Hub maintains the set of active rooms and clients
 type Hub struct {
 	// Rooms indexed by workspace ID
 	rooms map[uuid.UUID]*models.Room
 
-	// Redis client for pub/sub
-	redis *redis.Client
+	// Broker for cross-instance fan-out
+	broker Broker
+
+	// Tracks the users connected to any instance, nil to skip tracking
+	online *OnlineUsers
+
+	// Records the time clients spend in rooms, nil to skip analytics
+	analytics *AnalyticsService
+
+	// Captures the messages of rooms that record a session, nil to skip
+	// recording
+	recorder *SessionRecorder
 
 	// Context for Redis operations
 	ctx context.Context
 
 	// Mutex for rooms map
 	mu sync.RWMutex
+
+	// Identifies this instance in broker messages
+	instanceID uuid.UUID
 }
 
-// NewHub creates a new Hub
-func NewHub(redisClient *redis.Client) *Hub {
+// NewHub creates a new Hub. online may be nil if no service needs to know
+// whether users are connected, analytics if presence isn't recorded and
+// recorder if sessions aren't.
+func NewHub(broker Broker, online *OnlineUsers, analytics *AnalyticsService, recorder *SessionRecorder) *Hub {
 	hub := &Hub{
-		rooms: make(map[uuid.UUID]*models.Room),
-		redis: redisClient,
-		ctx:   context.Background(),
+		rooms:      make(map[uuid.UUID]*models.Room),
+		broker:     broker,
+		online:     online,
+		analytics:  analytics,
+		recorder:   recorder,
+		ctx:        context.Background(),
+		instanceID: uuid.New(),
 	}
 
 	// Start room cleanup goroutine
 	go hub.cleanupEmptyRooms()
 
-	// Start Redis subscription
-	go hub.subscribeToRedis()
+	// Start broker subscription
+	go hub.subscribeToBroker()
 
 	return hub
 }
@@ -67,15 +99,18 @@ func (h *Hub) Register(client *models.Client) {
 			WorkspaceID: workspaceID,
 			Clients:     make(map[uuid.UUID]*models.Client),
 			Broadcast:   make(chan *models.WSMessage, channelBufferSize),
+			Direct:      make(chan *models.UserMessage, channelBufferSize),
 			Register:    make(chan *models.Client),
 			Unregister:  make(chan *models.Client),
+			Disconnect:  make(chan *models.DisconnectRequest),
+			Replay:      make(chan *models.ReplayResult),
 		}
 		h.rooms[workspaceID] = room
 
 		// Start room goroutine
 		go h.runRoom(room)
 
-		log.Printf("Created new room for workspace %s", workspaceID)
+		hlog.Infof("Created new room for workspace %s", workspaceID)
 	}
 
 	// Check room capacity
@@ -84,6 +119,11 @@ func (h *Hub) Register(client *models.Client) {
 		return
 	}
 
+	if client.Anonymous && countAnonymousClients(room) >= maxAnonymousPerRoom {
+		h.sendErrorToClient(client, "anonymous_limit_reached", "Room has reached maximum anonymous viewers")
+		return
+	}
+
 	// Register client to room
 	room.Register <- client
 }
@@ -99,35 +139,105 @@ func (h *Hub) Unregister(client *models.Client) {
 	}
 }
 
-// BroadcastToRoom broadcasts a message to all clients in a room except the sender
+// BroadcastToRoom broadcasts a message to all clients in a room except the
+// sender. It is published first, so local clients get the same stream
+// sequence as the clients of other instances.
 func (h *Hub) BroadcastToRoom(workspaceID uuid.UUID, msg *models.WSMessage, excludeClientID uuid.UUID) {
+	// Publish to broker for other server instances
+	seq := h.publishToBroker(workspaceID, msg, excludeClientID)
+
 	h.mu.RLock()
 	room, exists := h.rooms[workspaceID]
 	h.mu.RUnlock()
 
 	if exists {
-		// Add exclude client ID to message metadata
 		msgCopy := *msg
+		msgCopy.Seq = seq
 		room.Broadcast <- &msgCopy
 	}
 
-	// Publish to Redis for other server instances
-	h.publishToRedis(workspaceID, msg, excludeClientID)
+	// Recorded once, by the instance the message comes from
+	h.recorder.Capture(workspaceID, msg)
+}
+
+// SendToUser sends a message to every client of a user, on this and other
+// server instances, whatever room they joined
+func (h *Hub) SendToUser(userID uuid.UUID, msg *models.WSMessage) {
+	h.sendToLocalUser(userID, msg)
+
+	brokerMsg := &BrokerMessage{
+		UserID:     userID,
+		Message:    msg,
+		InstanceID: h.instanceID,
+	}
+	if err := h.broker.Publish(h.ctx, brokerMsg); err != nil {
+		hlog.Errorf("Failed to publish user message: %v", err)
+	}
+}
+
+// BroadcastToAll sends a message to every client of every room, on this and
+// other server instances
+func (h *Hub) BroadcastToAll(msg *models.WSMessage) {
+	h.broadcastToLocalRooms(msg)
+	h.publishToBroker(uuid.Nil, msg, uuid.Nil)
+}
+
+// broadcastToLocalRooms hands a message to every room of this instance
+func (h *Hub) broadcastToLocalRooms(msg *models.WSMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, room := range h.rooms {
+		select {
+		case room.Broadcast <- msg:
+		default:
+			hlog.Warnf("Room %s broadcast buffer full, dropping %s message", room.WorkspaceID, msg.Type)
+		}
+	}
+}
+
+// sendToLocalUser hands a user message to every room of this instance. The
+// rooms own their clients, so they pick out the ones of the user.
+func (h *Hub) sendToLocalUser(userID uuid.UUID, msg *models.WSMessage) {
+	userMsg := &models.UserMessage{Message: msg, UserID: userID}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, room := range h.rooms {
+		select {
+		case room.Direct <- userMsg:
+		default:
+			hlog.Warnf("Room %s direct buffer full, dropping message for user %s", room.WorkspaceID, userID)
+		}
+	}
 }
 
 // runRoom manages a single room
 func (h *Hub) runRoom(room *models.Room) {
+	sweepTicker := time.NewTicker(presenceSweepInterval)
+	defer sweepTicker.Stop()
+
 	for {
 		select {
 		case client := <-room.Register:
 			// Add client to room
+			client.JoinedAt = time.Now()
 			room.Clients[client.ID] = client
+			if !client.Anonymous {
+				h.markOnline(client.UserID, client.ID)
+			}
 
-			log.Printf("Client %s joined room %s (%d total clients)",
+			hlog.Infof("Client %s joined room %s (%d total clients)",
 				client.UserID, room.WorkspaceID, len(room.Clients))
 
-			// Send list of existing users to new client
-			h.sendExistingPresences(client, room)
+			// Negotiated clients get a single ack with the participant list,
+			// legacy clients a burst of user_joined messages
+			if client.JoinAck != nil {
+				h.sendJoinAck(client, room)
+			} else {
+				h.sendExistingPresences(client, room)
+			}
 
 			// Broadcast user_joined to other clients
 			joinMsg := &models.WSMessage{
@@ -138,38 +248,120 @@ func (h *Hub) runRoom(room *models.Room) {
 					UserID:    client.UserID,
 					UserName:  client.UserName,
 					UserColor: client.UserColor,
+					Anonymous: client.Anonymous,
+					Bot:       client.Bot,
 				},
 			}
 			h.broadcastToRoomClients(room, joinMsg, client.ID)
+			h.recorder.Capture(room.WorkspaceID, joinMsg)
+
+			if client.ReplayAfter > 0 {
+				h.fetchReplay(room, client)
+			}
 
 		case client := <-room.Unregister:
 			if _, ok := room.Clients[client.ID]; ok {
-				// Remove client from room
-				delete(room.Clients, client.ID)
-				close(client.Send)
+				h.removeClient(room, client)
 
-				log.Printf("Client %s left room %s (%d remaining clients)",
+				hlog.Infof("Client %s left room %s (%d remaining clients)",
 					client.UserID, room.WorkspaceID, len(room.Clients))
 
-				// Broadcast user_left to other clients
-				leaveMsg := &models.WSMessage{
-					Type:      models.MessageTypeUserLeft,
-					UserID:    client.UserID,
-					Timestamp: time.Now(),
-					Payload: models.UserLeftPayload{
-						UserID: client.UserID,
-					},
-				}
-				h.broadcastToRoomClients(room, leaveMsg, uuid.Nil)
-
 				// If room is empty, it will be cleaned up by cleanupEmptyRooms
 			}
 
+		case req := <-room.Disconnect:
+			req.Done <- h.disconnectUserClients(room, req)
+
 		case message := <-room.Broadcast:
 			// Broadcast message to all clients in room
 			h.broadcastToRoomClients(room, message, uuid.Nil)
+
+		case userMsg := <-room.Direct:
+			h.sendToUserClients(room, userMsg)
+
+		case replay := <-room.Replay:
+			h.sendReplay(room, replay)
+
+		case <-sweepTicker.C:
+			h.expireStalePresences(room)
+		}
+	}
+}
+
+// expireStalePresences removes clients that stopped pinging without closing
+// their connection, so their cursors do not linger for other users. The
+// remaining clients are kept online.
+func (h *Hub) expireStalePresences(room *models.Room) {
+	cutoff := time.Now().Add(-stalePresenceTimeout)
+	live := make(map[uuid.UUID][]uuid.UUID)
+
+	for clientID, client := range room.Clients {
+		if client.LastPing().After(cutoff) {
+			if !client.Anonymous {
+				live[client.UserID] = append(live[client.UserID], clientID)
+			}
+			continue
+		}
+
+		h.removeClient(room, client)
+
+		hlog.Warnf("Expired stale presence of client %s in room %s (last ping %s)",
+			client.UserID, room.WorkspaceID, client.LastPing().Format(time.RFC3339))
+	}
+
+	for userID, clientIDs := range live {
+		h.markOnline(userID, clientIDs...)
+	}
+}
+
+// removeClient closes the connection of a client, records its presence
+// and tells the rest of the room that the user left
+func (h *Hub) removeClient(room *models.Room, client *models.Client) {
+	delete(room.Clients, client.ID)
+	close(client.Send)
+	h.markOffline(client)
+	h.analytics.TrackPresence(client)
+
+	leaveMsg := &models.WSMessage{
+		Type:      models.MessageTypeUserLeft,
+		UserID:    client.UserID,
+		Timestamp: time.Now(),
+		Payload: models.UserLeftPayload{
+			UserID: client.UserID,
+		},
+	}
+	h.broadcastToRoomClients(room, leaveMsg, uuid.Nil)
+	h.recorder.Capture(room.WorkspaceID, leaveMsg)
+}
+
+// disconnectUserClients closes the connections of one user in a room. The
+// clients get an error message with the reason first, if their buffer has
+// room for it.
+func (h *Hub) disconnectUserClients(room *models.Room, req *models.DisconnectRequest) int {
+	disconnected := 0
+	for _, client := range room.Clients {
+		if client.UserID != req.UserID {
+			continue
 		}
+
+		select {
+		case client.Send <- &models.WSMessage{
+			Type:      models.MessageTypeError,
+			Timestamp: time.Now(),
+			Payload: models.ErrorPayload{
+				Code:    "disconnected",
+				Message: req.Reason,
+			},
+		}:
+		default:
+		}
+
+		h.removeClient(room, client)
+		disconnected++
+
+		hlog.Infof("Disconnected client %s of user %s from room %s", client.ID, client.UserID, room.WorkspaceID)
 	}
+	return disconnected
 }
 
 // broadcastToRoomClients sends a message to all clients in a room except excluded one
@@ -185,11 +377,150 @@ func (h *Hub) broadcastToRoomClients(room *models.Room, msg *models.WSMessage, e
 			// Client's send buffer is full, close the connection
 			close(client.Send)
 			delete(room.Clients, clientID)
-			log.Printf("Client %s send buffer full, closing connection", client.UserID)
+			h.markOffline(client)
+			hlog.Warnf("Client %s send buffer full, closing connection", client.UserID)
 		}
 	}
 }
 
+// fetchReplay reads the room messages a rejoining client missed in the
+// background and hands them back to the room. Brokers that don't keep
+// messages have nothing to replay.
+func (h *Hub) fetchReplay(room *models.Room, client *models.Client) {
+	replayBroker, ok := h.broker.(ReplayBroker)
+	if !ok {
+		return
+	}
+
+	clientID, afterSeq := client.ID, client.ReplayAfter
+	go func() {
+		brokerMsgs, complete, err := replayBroker.Replay(h.ctx, room.WorkspaceID, afterSeq, replayLimit)
+		if err != nil {
+			hlog.Errorf("Failed to replay room %s after seq %d: %v", room.WorkspaceID, afterSeq, err)
+			complete = false
+		}
+
+		result := &models.ReplayResult{ClientID: clientID, Complete: complete}
+		for _, brokerMsg := range brokerMsgs {
+			if !isReplayed(brokerMsg.Message) {
+				continue
+			}
+			msg := *brokerMsg.Message
+			msg.Seq = brokerMsg.Seq
+			result.Messages = append(result.Messages, &msg)
+		}
+		room.Replay <- result
+	}()
+}
+
+// isReplayed reports whether a missed message is replayed. Presence is
+// sent with the join, so the cursors and joins in between are skipped.
+func isReplayed(msg *models.WSMessage) bool {
+	if msg == nil {
+		return false
+	}
+	switch msg.Type {
+	case models.MessageTypeCursorMove, models.MessageTypeSelectionChange,
+		models.MessageTypePresenceUpdate, models.MessageTypeUserJoined, models.MessageTypeUserLeft:
+		return false
+	}
+	return true
+}
+
+// sendReplay sends the missed messages to a client that is still in the
+// room, or tells it to resync when they are not all available
+func (h *Hub) sendReplay(room *models.Room, replay *models.ReplayResult) {
+	client, ok := room.Clients[replay.ClientID]
+	if !ok {
+		return
+	}
+
+	if !replay.Complete {
+		select {
+		case client.Send <- &models.WSMessage{
+			Type:      models.MessageTypeError,
+			Timestamp: time.Now(),
+			Payload: models.ErrorPayload{
+				Code:    "resync_required",
+				Message: "Missed messages are no longer available, reload the board",
+			},
+		}:
+		default:
+		}
+		return
+	}
+
+	for _, msg := range replay.Messages {
+		select {
+		case client.Send <- msg:
+		default:
+			close(client.Send)
+			delete(room.Clients, client.ID)
+			h.markOffline(client)
+			hlog.Warnf("Client %s send buffer full during replay, closing connection", client.UserID)
+			return
+		}
+	}
+}
+
+// sendToUserClients sends a message to the clients of one user in a room
+func (h *Hub) sendToUserClients(room *models.Room, userMsg *models.UserMessage) {
+	for clientID, client := range room.Clients {
+		if client.UserID != userMsg.UserID {
+			continue
+		}
+
+		select {
+		case client.Send <- userMsg.Message:
+		default:
+			close(client.Send)
+			delete(room.Clients, clientID)
+			h.markOffline(client)
+			hlog.Warnf("Client %s send buffer full, closing connection", client.UserID)
+		}
+	}
+}
+
+// IsUserOnline reports whether a user has a realtime connection on any
+// instance. Always false without online tracking.
+func (h *Hub) IsUserOnline(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if h.online == nil {
+		return false, nil
+	}
+	return h.online.IsOnline(ctx, userID)
+}
+
+// markOnline records connections of a user in the background, so rooms
+// don't wait for Redis. Anonymous viewers aren't tracked.
+func (h *Hub) markOnline(userID uuid.UUID, clientIDs ...uuid.UUID) {
+	if h.online == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(h.ctx, onlineUserTimeout)
+		defer cancel()
+		if err := h.online.Touch(ctx, userID, clientIDs...); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to track online user %s: %v", userID, err)
+		}
+	}()
+}
+
+// markOffline removes a closed connection in the background
+func (h *Hub) markOffline(client *models.Client) {
+	if h.online == nil || client.Anonymous {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(h.ctx, onlineUserTimeout)
+		defer cancel()
+		if err := h.online.Remove(ctx, client.UserID, client.ID); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to untrack online user %s: %v", client.UserID, err)
+		}
+	}()
+}
+
 // sendExistingPresences sends the list of existing users to a newly joined client
 func (h *Hub) sendExistingPresences(client *models.Client, room *models.Room) {
 	for _, existingClient := range room.Clients {
@@ -206,6 +537,8 @@ func (h *Hub) sendExistingPresences(client *models.Client, room *models.Room) {
 				UserID:    existingClient.UserID,
 				UserName:  existingClient.UserName,
 				UserColor: existingClient.UserColor,
+				Anonymous: existingClient.Anonymous,
+				Bot:       existingClient.Bot,
 			},
 		}
 		client.Send <- msg
@@ -225,6 +558,39 @@ func (h *Hub) sendExistingPresences(client *models.Client, room *models.Room) {
 	}
 }
 
+// sendJoinAck completes the pending join_ack with the current participants and sends it
+func (h *Hub) sendJoinAck(client *models.Client, room *models.Room) {
+	ack := client.JoinAck
+	client.JoinAck = nil
+
+	payload, ok := ack.Payload.(*models.JoinAckPayload)
+	if !ok {
+		hlog.Errorf("Invalid join_ack payload for client %s", client.ID)
+		return
+	}
+
+	payload.Participants = make([]models.UserPresence, 0, len(room.Clients))
+	for _, existingClient := range room.Clients {
+		if existingClient.ID == client.ID || existingClient.Presence == nil {
+			continue
+		}
+		payload.Participants = append(payload.Participants, *existingClient.Presence)
+	}
+
+	client.Send <- ack
+}
+
+// countAnonymousClients returns the number of anonymous clients in a room
+func countAnonymousClients(room *models.Room) int {
+	count := 0
+	for _, client := range room.Clients {
+		if client.Anonymous {
+			count++
+		}
+	}
+	return count
+}
+
 // sendErrorToClient sends an error message to a client
 func (h *Hub) sendErrorToClient(client *models.Client, code, message string) {
 	client.Send <- &models.WSMessage{
@@ -247,7 +613,7 @@ func (h *Hub) cleanupEmptyRooms() {
 		for workspaceID, room := range h.rooms {
 			if len(room.Clients) == 0 {
 				delete(h.rooms, workspaceID)
-				log.Printf("Cleaned up empty room %s", workspaceID)
+				hlog.Infof("Cleaned up empty room %s", workspaceID)
 			}
 		}
 		h.mu.Unlock()
@@ -280,59 +646,96 @@ func (h *Hub) GetAllRoomStats() map[uuid.UUID]int {
 	return stats
 }
 
-// Redis Pub/Sub methods for scaling across multiple instances
+// DisconnectUser closes the connections of a user to this instance, in one
+// room or in all rooms when workspaceID is uuid.Nil. Returns the number of
+// closed connections.
+func (h *Hub) DisconnectUser(workspaceID, userID uuid.UUID, reason string) int {
+	h.mu.RLock()
+	rooms := make([]*models.Room, 0, len(h.rooms))
+	for id, room := range h.rooms {
+		if workspaceID == uuid.Nil || id == workspaceID {
+			rooms = append(rooms, room)
+		}
+	}
+	h.mu.RUnlock()
+
+	disconnected := 0
+	for _, room := range rooms {
+		req := &models.DisconnectRequest{
+			Done:   make(chan int, 1),
+			Reason: reason,
+			UserID: userID,
+		}
+		room.Disconnect <- req
+		disconnected += <-req.Done
+	}
+	return disconnected
+}
 
-type RedisMessage struct {
-	WorkspaceID     uuid.UUID         `json:"workspace_id"`
-	ExcludeClientID uuid.UUID         `json:"exclude_client_id"`
-	Message         *models.WSMessage `json:"message"`
+// InstanceID identifies this hub in broker messages. Rooms and their
+// statistics are local to the instance.
+func (h *Hub) InstanceID() uuid.UUID {
+	return h.instanceID
 }
 
-// publishToRedis publishes a message to Redis for other server instances
-func (h *Hub) publishToRedis(workspaceID uuid.UUID, msg *models.WSMessage, excludeClientID uuid.UUID) {
-	redisMsg := RedisMessage{
+// Broker methods for scaling across multiple instances
+
+// publishToBroker publishes a message for other server instances and
+// returns its stream sequence, 0 if the broker doesn't keep messages
+func (h *Hub) publishToBroker(workspaceID uuid.UUID, msg *models.WSMessage, excludeClientID uuid.UUID) uint64 {
+	brokerMsg := &BrokerMessage{
 		WorkspaceID:     workspaceID,
 		Message:         msg,
 		ExcludeClientID: excludeClientID,
+		InstanceID:      h.instanceID,
 	}
 
-	data, err := json.Marshal(redisMsg)
-	if err != nil {
-		log.Printf("Failed to marshal Redis message: %v", err)
-		return
+	if err := h.broker.Publish(h.ctx, brokerMsg); err != nil {
+		hlog.Errorf("Failed to publish hub message: %v", err)
 	}
+	return brokerMsg.Seq
+}
 
-	channel := fmt.Sprintf("workspace:%s", workspaceID)
-	err = h.redis.Publish(h.ctx, channel, data).Err()
-	if err != nil {
-		log.Printf("Failed to publish to Redis: %v", err)
+// subscribeToBroker subscribes to workspace and user messages from other instances
+func (h *Hub) subscribeToBroker() {
+	if err := h.broker.Subscribe(h.ctx, h.handleBrokerMessage); err != nil {
+		hlog.Errorf("Failed to subscribe to hub broker: %v", err)
 	}
 }
 
-// subscribeToRedis subscribes to Redis channels for workspace updates
-func (h *Hub) subscribeToRedis() {
-	pubsub := h.redis.PSubscribe(h.ctx, "workspace:*")
-	defer pubsub.Close()
+// handleBrokerMessage forwards a message from another instance to the local
+// room, or to the clients of a user for user messages. It runs on the broker
+// goroutine, so it only hands messages to the rooms, which own their clients.
+// Client IDs are per instance, so ExcludeClientID can't match here.
+func (h *Hub) handleBrokerMessage(brokerMsg *BrokerMessage) {
+	// Messages from this instance were already delivered locally
+	if brokerMsg.InstanceID == h.instanceID {
+		return
+	}
 
-	ch := pubsub.Channel()
+	if brokerMsg.UserID != uuid.Nil {
+		h.sendToLocalUser(brokerMsg.UserID, brokerMsg.Message)
+		return
+	}
 
-	log.Println("Started Redis subscription for workspace channels")
+	if brokerMsg.WorkspaceID == uuid.Nil {
+		h.broadcastToLocalRooms(brokerMsg.Message)
+		return
+	}
 
-	for msg := range ch {
-		var redisMsg RedisMessage
-		err := json.Unmarshal([]byte(msg.Payload), &redisMsg)
-		if err != nil {
-			log.Printf("Failed to unmarshal Redis message: %v", err)
-			continue
-		}
+	h.mu.RLock()
+	room, exists := h.rooms[brokerMsg.WorkspaceID]
+	h.mu.RUnlock()
 
-		// Forward message to local room clients
-		h.mu.RLock()
-		room, exists := h.rooms[redisMsg.WorkspaceID]
-		h.mu.RUnlock()
+	if !exists || brokerMsg.Message == nil {
+		return
+	}
 
-		if exists {
-			h.broadcastToRoomClients(room, redisMsg.Message, redisMsg.ExcludeClientID)
-		}
+	msg := *brokerMsg.Message
+	msg.Seq = brokerMsg.Seq
+	select {
+	case room.Broadcast <- &msg:
+	default:
+		hlog.Warnf("Room %s broadcast buffer full, dropping %s message", room.WorkspaceID, msg.Type)
 	}
 }
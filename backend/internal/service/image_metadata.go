@@ -0,0 +1,223 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+const (
+	// jpegReencodeQuality is used when the orientation has to be baked into the pixels
+	jpegReencodeQuality = 92
+
+	jpegMarkerPrefix = 0xFF
+	jpegMarkerSOI    = 0xD8
+	jpegMarkerEOI    = 0xD9
+	jpegMarkerSOS    = 0xDA
+	jpegMarkerAPP1   = 0xE1
+	jpegMarkerAPP13  = 0xED
+	jpegMarkerCOM    = 0xFE
+	jpegMarkerRST0   = 0xD0
+	jpegMarkerRST7   = 0xD7
+	jpegMarkerTEM    = 0x01
+
+	jpegMinSize      = 4
+	jpegLengthSize   = 2
+	tiffByteOrderLen = 2
+
+	exifOrientationTag = 0x0112
+	exifIFDCountSize   = 2
+	exifIFDEntrySize   = 12
+	exifIFDValueOffset = 8
+	exifIFDOffsetPos   = 4
+	exifTIFFHeaderSize = 8
+)
+
+// EXIF orientations
+const (
+	orientationNormal     = 1
+	orientationMirrorH    = 2
+	orientationRotate180  = 3
+	orientationMirrorV    = 4
+	orientationTranspose  = 5
+	orientationRotate90   = 6
+	orientationTransverse = 7
+	orientationRotate270  = 8
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// stripJPEGMetadata removes EXIF, XMP, IPTC and comment segments from a JPEG.
+// If the EXIF orientation is not the default one the rotation is applied to
+// the pixels first so the image keeps displaying the right way up.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	orientation := orientationNormal
+	stripped, err := filterJPEGSegments(data, func(marker byte, payload []byte) bool {
+		if marker == jpegMarkerAPP1 && bytes.HasPrefix(payload, exifHeader) {
+			orientation = exifOrientation(payload[len(exifHeader):])
+		}
+		return marker != jpegMarkerAPP1 && marker != jpegMarkerAPP13 && marker != jpegMarkerCOM
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if orientation == orientationNormal {
+		return stripped, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, applyOrientation(img, orientation), &jpeg.Options{Quality: jpegReencodeQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// filterJPEGSegments copies a JPEG, keeping only the header segments accepted
+// by keep. Everything from the start of scan onwards is copied unchanged.
+func filterJPEGSegments(data []byte, keep func(marker byte, payload []byte) bool) ([]byte, error) {
+	if len(data) < jpegMinSize || data[0] != jpegMarkerPrefix || data[1] != jpegMarkerSOI {
+		return nil, fmt.Errorf("not a JPEG file")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != jpegMarkerPrefix {
+			return nil, fmt.Errorf("invalid JPEG marker at offset %d", pos)
+		}
+
+		// Skip fill bytes
+		for pos < len(data) && data[pos] == jpegMarkerPrefix {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, fmt.Errorf("truncated JPEG file")
+		}
+		marker := data[pos]
+		pos++
+
+		// Standalone markers carry no length
+		if marker == jpegMarkerTEM || (marker >= jpegMarkerRST0 && marker <= jpegMarkerRST7) || marker == jpegMarkerEOI {
+			out = append(out, jpegMarkerPrefix, marker)
+			if marker == jpegMarkerEOI {
+				return out, nil
+			}
+			continue
+		}
+
+		if pos+jpegLengthSize > len(data) {
+			return nil, fmt.Errorf("truncated JPEG segment")
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < jpegLengthSize || pos+length > len(data) {
+			return nil, fmt.Errorf("invalid JPEG segment length")
+		}
+
+		if marker == jpegMarkerSOS {
+			out = append(out, jpegMarkerPrefix, marker)
+			return append(out, data[pos:]...), nil
+		}
+
+		if keep(marker, data[pos+jpegLengthSize:pos+length]) {
+			out = append(out, jpegMarkerPrefix, marker)
+			out = append(out, data[pos:pos+length]...)
+		}
+		pos += length
+	}
+
+	return out, nil
+}
+
+// exifOrientation reads the orientation tag from a TIFF-formatted EXIF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < exifTIFFHeaderSize {
+		return orientationNormal
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:tiffByteOrderLen]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationNormal
+	}
+
+	ifd := int(order.Uint32(tiff[exifIFDOffsetPos:]))
+	if ifd < exifTIFFHeaderSize || ifd+exifIFDCountSize > len(tiff) {
+		return orientationNormal
+	}
+
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + exifIFDCountSize + i*exifIFDEntrySize
+		if entry+exifIFDEntrySize > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			value := int(order.Uint16(tiff[entry+exifIFDValueOffset:]))
+			if value < orientationNormal || value > orientationRotate270 {
+				return orientationNormal
+			}
+			return value
+		}
+	}
+
+	return orientationNormal
+}
+
+// applyOrientation transforms the pixels according to an EXIF orientation
+func applyOrientation(src image.Image, orientation int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= orientationTranspose {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	if orientation == orientationNormal {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case orientationMirrorH:
+				sx, sy = w-1-x, y
+			case orientationRotate180:
+				sx, sy = w-1-x, h-1-y
+			case orientationMirrorV:
+				sx, sy = x, h-1-y
+			case orientationTranspose:
+				sx, sy = y, x
+			case orientationRotate90:
+				sx, sy = y, h-1-x
+			case orientationTransverse:
+				sx, sy = w-1-y, h-1-x
+			default: // orientationRotate270
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
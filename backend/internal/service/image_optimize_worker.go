@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	imageOptimizeTimeout = 3 * time.Minute
+
+	webpQuality = 80
+	avifCRF     = 32
+)
+
+// ResponsiveImageWidths are the widths variants are generated at. Widths not
+// smaller than the original are replaced by a single full-size variant.
+var ResponsiveImageWidths = []int{320, 640, 1280, 1920}
+
+// imageVariantFormats maps an optimized format to its content type and ffmpeg encoder arguments
+var imageVariantFormats = map[string]struct {
+	contentType string
+	args        []string
+}{
+	models.ImageFormatWebP: {
+		contentType: "image/webp",
+		args:        []string{"-c:v", "libwebp", "-quality", strconv.Itoa(webpQuality)},
+	},
+	models.ImageFormatAVIF: {
+		contentType: "image/avif",
+		args:        []string{"-c:v", "libaom-av1", "-still-picture", "1", "-crf", strconv.Itoa(avifCRF)},
+	},
+}
+
+// ImageOptimizeWorker generates WebP/AVIF variants of uploaded images
+type ImageOptimizeWorker struct {
+	assetService *AssetService
+	nats         *nats.Conn
+	sub          *nats.Subscription
+}
+
+// NewImageOptimizeWorker creates a new image optimization worker
+func NewImageOptimizeWorker(nc *nats.Conn, assetService *AssetService) (*ImageOptimizeWorker, error) {
+	worker := &ImageOptimizeWorker{
+		assetService: assetService,
+		nats:         nc,
+	}
+
+	// Subscribe to optimization queue
+	sub, err := nc.QueueSubscribe(ImageOptimizeSubject, "image-optimizers", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to image optimize queue: %w", err)
+	}
+
+	worker.sub = sub
+	return worker, nil
+}
+
+// Close closes the optimization worker subscription
+func (w *ImageOptimizeWorker) Close() error {
+	if w.sub != nil {
+		return w.sub.Unsubscribe()
+	}
+	return nil
+}
+
+// handleMessage processes an optimization job
+func (w *ImageOptimizeWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "asset.optimize")
+	defer span.End()
+
+	var job models.ImageOptimizeJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal image optimize job: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageOptimizeTimeout)
+	defer cancel()
+
+	variants, err := w.optimize(ctx, &job)
+	if err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to optimize image %s: %v", job.AssetID, err)
+		return
+	}
+
+	if err := w.assetService.assetRepo.UpdateAssetVariants(ctx, job.AssetID, variants); err != nil {
+		w.removeVariants(ctx, variants)
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to store variants for image %s: %v", job.AssetID, err)
+		return
+	}
+
+	hlog.CtxInfof(ctx, "Generated %d variants for image %s", len(variants), job.AssetID)
+}
+
+// optimize encodes every format/width combination and uploads the results
+func (w *ImageOptimizeWorker) optimize(ctx context.Context, job *models.ImageOptimizeJob) (models.AssetVariants, error) {
+	data, err := w.assetService.readObject(ctx, job.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dims, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "image-optimize-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input"+filepath.Ext(job.ObjectKey))
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write image: %w", err)
+	}
+
+	variants := make(models.AssetVariants)
+	for _, width := range variantWidths(dims.Width) {
+		height := dims.Height * width / dims.Width
+
+		for format, encoder := range imageVariantFormats {
+			output := filepath.Join(dir, models.VariantKey(format, width)+"."+format)
+			if err := encodeVariant(ctx, input, output, width, encoder.args); err != nil {
+				w.removeVariants(ctx, variants)
+				return nil, err
+			}
+
+			variant, err := w.uploadVariant(ctx, job, output, format, encoder.contentType)
+			if err != nil {
+				w.removeVariants(ctx, variants)
+				return nil, err
+			}
+
+			variant.Width = width
+			variant.Height = height
+			variants[models.VariantKey(format, width)] = *variant
+		}
+	}
+
+	return variants, nil
+}
+
+// uploadVariant stores an encoded variant next to the original
+func (w *ImageOptimizeWorker) uploadVariant(
+	ctx context.Context,
+	job *models.ImageOptimizeJob,
+	path, format, contentType string,
+) (*models.AssetVariant, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is built from our own temp dir
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variant: %w", err)
+	}
+
+	objectName := newObjectName(job.WorkspaceID, "."+format)
+	size := int64(len(data))
+	if err := w.assetService.uploadFile(ctx, objectName, data, size, contentType); err != nil {
+		return nil, err
+	}
+
+	return &models.AssetVariant{
+		URL:    w.assetService.getObjectURL(objectName),
+		Format: format,
+		Size:   size,
+	}, nil
+}
+
+// removeVariants deletes already uploaded variants after a failure
+func (w *ImageOptimizeWorker) removeVariants(ctx context.Context, variants models.AssetVariants) {
+	for _, variant := range variants {
+		w.assetService.cleanupUploadedFiles(ctx, w.assetService.extractObjectName(variant.URL), nil)
+	}
+}
+
+// variantWidths returns the responsive widths that make sense for an image,
+// always including the original width
+func variantWidths(originalWidth int) []int {
+	widths := make([]int, 0, len(ResponsiveImageWidths)+1)
+	for _, width := range ResponsiveImageWidths {
+		if width < originalWidth {
+			widths = append(widths, width)
+		}
+	}
+	return append(widths, originalWidth)
+}
+
+// encodeVariant resizes and encodes an image with ffmpeg
+func encodeVariant(ctx context.Context, input, output string, width int, encoderArgs []string) error {
+	args := []string{"-v", "error", "-i", input, "-vf", fmt.Sprintf("scale=%d:-2", width)}
+	args = append(args, encoderArgs...)
+	args = append(args, "-frames:v", "1", "-y", output)
+
+	//nolint:gosec // arguments are fixed, the files live in our temp dir
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to encode variant: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
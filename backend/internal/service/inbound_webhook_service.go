@@ -0,0 +1,471 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	// InboundWebhookSource marks elements created by inbound webhooks in
+	// realtime messages
+	InboundWebhookSource = "inbound_webhook"
+
+	maxInboundWebhooksPerWorkspace = 20
+	maxInboundWebhookNameLength    = 100
+	maxInboundColumnLength         = 100
+	maxInboundTextLength           = 5000
+	inboundWebhookTokenBytes       = 32
+	defaultInboundColumn           = "Inbox"
+	defaultInboundNoteColor        = "#fde68a"
+
+	// Layout of the notes in a column, in canvas units
+	inboundNoteSize      = 200
+	inboundHeadingWidth  = 200
+	inboundHeadingHeight = 40
+	inboundHeadingFont   = 24
+	inboundNoteFont      = 16
+	inboundNoteGap       = 20
+	inboundColumnGap     = 80
+)
+
+var (
+	// ErrInboundWebhookNotFound is returned for unknown inbound webhooks
+	ErrInboundWebhookNotFound = errors.New("inbound webhook not found")
+	// ErrInboundWebhookLimitReached is returned when a workspace has too many inbound webhooks
+	ErrInboundWebhookLimitReached = errors.New("inbound webhook limit reached")
+	// ErrInboundWebhookUnauthorized is returned for unknown or disabled
+	// webhooks and wrong tokens alike, so callers can't probe for IDs
+	ErrInboundWebhookUnauthorized = errors.New("invalid inbound webhook or token")
+	// ErrInvalidInboundPayload is returned for posts without usable text,
+	// column or color
+	ErrInvalidInboundPayload = errors.New("invalid inbound webhook payload")
+	// ErrInboundWebhookForbidden is returned when the creator of a webhook
+	// can no longer edit the board
+	ErrInboundWebhookForbidden = errors.New("the creator of this webhook can no longer edit the board")
+)
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// InboundWebhookPath returns the API path an inbound webhook is posted to
+func InboundWebhookPath(id uuid.UUID) string {
+	return "/api/v1/hooks/" + id.String()
+}
+
+// InboundWebhookService manages inbound webhooks and turns the text posted
+// to them into sticky notes. A note goes under the text element of the
+// board that reads like its column, below the notes already there. A
+// heading for the column is added next to the board when there is none.
+type InboundWebhookService struct {
+	webhookRepo      *repository.InboundWebhookRepository
+	canvasService    *CanvasService
+	workspaceService *WorkspaceService
+	rooms            RoomBroadcaster
+}
+
+// NewInboundWebhookService creates a new inbound webhook service. rooms
+// shows the new notes to connected clients right away.
+func NewInboundWebhookService(
+	webhookRepo *repository.InboundWebhookRepository,
+	canvasService *CanvasService,
+	workspaceService *WorkspaceService,
+	rooms RoomBroadcaster,
+) *InboundWebhookService {
+	return &InboundWebhookService{
+		webhookRepo:      webhookRepo,
+		canvasService:    canvasService,
+		workspaceService: workspaceService,
+		rooms:            rooms,
+	}
+}
+
+// CreateInboundWebhook creates an inbound webhook and returns it with its token
+func (s *InboundWebhookService) CreateInboundWebhook(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.CreateInboundWebhookRequest,
+) (*models.InboundWebhookWithToken, error) {
+	if req.DefaultColumn == "" {
+		req.DefaultColumn = defaultInboundColumn
+	}
+	name, column, err := validateInboundWebhook(req.Name, req.DefaultColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhookRepo.ListInboundWebhooks(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxInboundWebhooksPerWorkspace {
+		return nil, ErrInboundWebhookLimitReached
+	}
+
+	token, hash, err := generateInboundWebhookToken()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.InboundWebhook{
+		ID:            uuid.New(),
+		WorkspaceID:   workspaceID,
+		Name:          name,
+		TokenHash:     hash,
+		DefaultColumn: column,
+		Active:        true,
+		CreatedBy:     userID,
+	}
+
+	if err := s.webhookRepo.CreateInboundWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	webhook.Path = InboundWebhookPath(webhook.ID)
+	return &models.InboundWebhookWithToken{InboundWebhook: *webhook, Token: token}, nil
+}
+
+// ListInboundWebhooks returns the inbound webhooks of a workspace
+func (s *InboundWebhookService) ListInboundWebhooks(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) ([]models.InboundWebhook, error) {
+	webhooks, err := s.webhookRepo.ListInboundWebhooks(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Path = InboundWebhookPath(webhooks[i].ID)
+	}
+	return webhooks, nil
+}
+
+// GetInboundWebhook returns an inbound webhook of a workspace
+func (s *InboundWebhookService) GetInboundWebhook(
+	ctx context.Context,
+	workspaceID, webhookID uuid.UUID,
+) (*models.InboundWebhook, error) {
+	webhook, err := s.webhookRepo.GetInboundWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrInboundWebhookNotFound
+	}
+	webhook.Path = InboundWebhookPath(webhook.ID)
+	return webhook, nil
+}
+
+// UpdateInboundWebhook changes an inbound webhook. The token is only
+// included in the result when it was rotated.
+func (s *InboundWebhookService) UpdateInboundWebhook(
+	ctx context.Context,
+	workspaceID, webhookID uuid.UUID,
+	req *models.UpdateInboundWebhookRequest,
+) (*models.InboundWebhookWithToken, error) {
+	webhook, err := s.GetInboundWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		webhook.Name = *req.Name
+	}
+	if req.DefaultColumn != nil {
+		webhook.DefaultColumn = *req.DefaultColumn
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	webhook.Name, webhook.DefaultColumn, err = validateInboundWebhook(webhook.Name, webhook.DefaultColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.InboundWebhookWithToken{}
+	if req.RotateToken {
+		token, hash, err := generateInboundWebhookToken()
+		if err != nil {
+			return nil, err
+		}
+		webhook.TokenHash = hash
+		result.Token = token
+	}
+
+	if err := s.webhookRepo.UpdateInboundWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	result.InboundWebhook = *webhook
+	return result, nil
+}
+
+// DeleteInboundWebhook deletes an inbound webhook
+func (s *InboundWebhookService) DeleteInboundWebhook(ctx context.Context, workspaceID, webhookID uuid.UUID) error {
+	deleted, err := s.webhookRepo.DeleteInboundWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInboundWebhookNotFound
+	}
+	return nil
+}
+
+// Receive authenticates a post to an inbound webhook and creates its sticky
+// note on the board, as the user that created the webhook
+func (s *InboundWebhookService) Receive(
+	ctx context.Context,
+	webhookID uuid.UUID,
+	token string,
+	payload *models.InboundWebhookPayload,
+) (*models.InboundWebhookResult, error) {
+	webhook, err := s.webhookRepo.GetInboundWebhookByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil || !webhook.Active || !inboundTokenMatches(token, webhook.TokenHash) {
+		return nil, ErrInboundWebhookUnauthorized
+	}
+
+	if err := s.workspaceService.CheckPermission(
+		ctx, webhook.WorkspaceID, webhook.CreatedBy, models.WorkspaceRoleEditor,
+	); err != nil {
+		return nil, ErrInboundWebhookForbidden
+	}
+
+	text := strings.TrimSpace(payload.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxInboundTextLength {
+		return nil, fmt.Errorf("%w: text is required and must be at most %d characters",
+			ErrInvalidInboundPayload, maxInboundTextLength)
+	}
+	column := strings.TrimSpace(payload.Column)
+	if column == "" {
+		column = webhook.DefaultColumn
+	}
+	if utf8.RuneCountInString(column) > maxInboundColumnLength {
+		return nil, fmt.Errorf("%w: column must be at most %d characters", ErrInvalidInboundPayload, maxInboundColumnLength)
+	}
+	color := payload.Color
+	if color == "" {
+		color = defaultInboundNoteColor
+	}
+	if !hexColorPattern.MatchString(color) {
+		return nil, fmt.Errorf("%w: color must be a hex color like %s", ErrInvalidInboundPayload, defaultInboundNoteColor)
+	}
+
+	existing, err := s.canvasService.GetWorkspaceElements(ctx, webhook.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := placeInboundNote(existing, column, text, color)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := s.canvasService.BatchCreateElements(
+		ctx, webhook.WorkspaceID, webhook.CreatedBy, models.BatchCreateRequest{Elements: requests},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.rooms != nil {
+		responses := make([]models.ElementResponse, len(elements))
+		for i := range elements {
+			responses[i] = elements[i].ToResponse()
+		}
+		s.rooms.BroadcastToRoom(webhook.WorkspaceID, &models.WSMessage{
+			Type:      models.MessageTypeElementsAdded,
+			UserID:    webhook.CreatedBy,
+			Timestamp: time.Now(),
+			Payload: models.ElementsAddedPayload{
+				Elements: responses,
+				Source:   InboundWebhookSource,
+			},
+		}, uuid.Nil)
+	}
+
+	if err := s.webhookRepo.MarkInboundWebhookUsed(ctx, webhook.ID); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to record use of inbound webhook %s: %v", webhook.ID, err)
+	}
+
+	// The note is always the last element, after a new heading
+	return &models.InboundWebhookResult{
+		Column:    column,
+		ElementID: elements[len(elements)-1].ID,
+	}, nil
+}
+
+// placeInboundNote returns the sticky note to create at the bottom of the
+// column, preceded by the column heading when the board has none
+func placeInboundNote(
+	elements []models.CanvasElement,
+	column, text, color string,
+) ([]models.CreateElementRequest, error) {
+	var requests []models.CreateElementRequest
+	zIndex := 0
+	for i := range elements {
+		zIndex = max(zIndex, elements[i].ZIndex+1)
+	}
+
+	heading := findColumnHeading(elements, column)
+	var columnX, nextY float64
+	if heading != nil {
+		position, size := heading.ElementData.Bounds()
+		columnX = position.X
+		nextY = position.Y + max(size.Height, inboundHeadingHeight) + inboundNoteGap
+
+		// Below the lowest note that already sits in the column
+		for i := range elements {
+			if elements[i].ElementType != models.ElementTypeSticky {
+				continue
+			}
+			notePosition, noteSize := elements[i].ElementData.Bounds()
+			inColumn := notePosition.X > columnX-inboundNoteSize/2 && notePosition.X < columnX+inboundNoteSize/2
+			if inColumn && notePosition.Y >= position.Y {
+				nextY = max(nextY, notePosition.Y+noteSize.Height+inboundNoteGap)
+			}
+		}
+	} else {
+		// A new column to the right of everything on the board
+		var top float64
+		for i := range elements {
+			position, size := elements[i].ElementData.Bounds()
+			if i == 0 || position.X+size.Width+inboundColumnGap > columnX {
+				columnX = position.X + size.Width + inboundColumnGap
+			}
+			if i == 0 || position.Y < top {
+				top = position.Y
+			}
+		}
+
+		headingData, err := toElementData(models.TextElementData{
+			Content:   "<h2>" + html.EscapeString(column) + "</h2>",
+			PlainText: column,
+			BaseElementData: models.BaseElementData{
+				Position: models.Position{X: columnX, Y: top},
+				Size:     models.Size{Width: inboundHeadingWidth, Height: inboundHeadingHeight},
+				Style:    models.Style{FontSize: inboundHeadingFont, FontWeight: "bold"},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, models.CreateElementRequest{
+			ElementType: models.ElementTypeText,
+			ElementData: headingData,
+			ZIndex:      zIndex,
+		})
+		zIndex++
+		nextY = top + inboundHeadingHeight + inboundNoteGap
+	}
+
+	noteData, err := toElementData(models.StickyNoteData{
+		Content: text,
+		Color:   color,
+		BaseElementData: models.BaseElementData{
+			Position: models.Position{X: columnX, Y: nextY},
+			Size:     models.Size{Width: inboundNoteSize, Height: inboundNoteSize},
+			Style:    models.Style{FontSize: inboundNoteFont},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(requests, models.CreateElementRequest{
+		ElementType: models.ElementTypeSticky,
+		ElementData: noteData,
+		ZIndex:      zIndex,
+	}), nil
+}
+
+// toElementData converts typed element data to the generic map stored in
+// element_data
+func toElementData(data any) (models.ElementData, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal element data: %w", err)
+	}
+
+	var result models.ElementData
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal element data: %w", err)
+	}
+	return result, nil
+}
+
+// findColumnHeading returns the topmost text element whose text is the
+// column name, ignoring case
+func findColumnHeading(elements []models.CanvasElement, column string) *models.CanvasElement {
+	var heading *models.CanvasElement
+	for i := range elements {
+		if elements[i].ElementType != models.ElementTypeText {
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(elementPlainText(&elements[i])), column) {
+			continue
+		}
+
+		position, _ := elements[i].ElementData.Bounds()
+		if heading == nil {
+			heading = &elements[i]
+			continue
+		}
+		if headingPosition, _ := heading.ElementData.Bounds(); position.Y < headingPosition.Y {
+			heading = &elements[i]
+		}
+	}
+	return heading
+}
+
+func validateInboundWebhook(name, column string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxInboundWebhookNameLength {
+		return "", "", fmt.Errorf("name is required and must be at most %d characters", maxInboundWebhookNameLength)
+	}
+	column = strings.TrimSpace(column)
+	if column == "" || utf8.RuneCountInString(column) > maxInboundColumnLength {
+		return "", "", fmt.Errorf("default_column is required and must be at most %d characters", maxInboundColumnLength)
+	}
+	return name, column, nil
+}
+
+// generateInboundWebhookToken returns a new token and the hash stored for it
+func generateInboundWebhookToken() (token, hash string, err error) {
+	b := make([]byte, inboundWebhookTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate inbound webhook token: %w", err)
+	}
+	token = "ibh_" + hex.EncodeToString(b)
+	return token, hashInboundWebhookToken(token), nil
+}
+
+func hashInboundWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func inboundTokenMatches(token, hash string) bool {
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashInboundWebhookToken(token)), []byte(hash)) == 1
+}
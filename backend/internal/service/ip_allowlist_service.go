@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	maxIPRangesPerWorkspace  = 100
+	maxIPRangeDescriptionLen = 255
+	ipAllowlistCacheTTL      = 30 * time.Second
+	ipBlockedAuditInterval   = time.Minute
+	maxIPBlockedAuditEntries = 10000
+)
+
+var (
+	// ErrIPNotAllowed is returned when a workspace is accessed from outside
+	// its allowlist
+	ErrIPNotAllowed = errors.New("access to this workspace is restricted to approved networks, connect from one of them or ask the owner to add yours")
+	// ErrInvalidIPAllowlist is returned for allowlists that can't be saved
+	ErrInvalidIPAllowlist = errors.New("invalid IP allowlist")
+	// ErrAllowlistExcludesCaller is returned when an allowlist update would
+	// lock out the owner who makes it
+	ErrAllowlistExcludesCaller = errors.New("the allowlist must include the address you are connecting from")
+)
+
+type cachedAllowlist struct {
+	expiresAt time.Time
+	prefixes  []netip.Prefix
+}
+
+type blockedAttempt struct {
+	workspaceID uuid.UUID
+	userID      uuid.UUID
+	ip          string
+}
+
+// IPAllowlistService restricts workspaces to the networks their owners
+// allow. Allowlists are cached per instance for ipAllowlistCacheTTL, so a
+// change takes that long to reach the other instances.
+type IPAllowlistService struct {
+	workspaceRepo *repository.WorkspaceRepository
+	audit         *AuditService
+
+	mu      sync.Mutex
+	cache   map[uuid.UUID]cachedAllowlist
+	blocked map[blockedAttempt]time.Time
+}
+
+// NewIPAllowlistService creates a new IP allowlist service
+func NewIPAllowlistService(workspaceRepo *repository.WorkspaceRepository, audit *AuditService) *IPAllowlistService {
+	return &IPAllowlistService{
+		workspaceRepo: workspaceRepo,
+		audit:         audit,
+		cache:         make(map[uuid.UUID]cachedAllowlist),
+		blocked:       make(map[blockedAttempt]time.Time),
+	}
+}
+
+// GetAllowlist returns the allowlist of a workspace and the address the
+// caller is seen from
+func (s *IPAllowlistService) GetAllowlist(ctx context.Context, workspaceID uuid.UUID, clientIP string) (*models.IPAllowlistResponse, error) {
+	ranges, err := s.workspaceRepo.ListIPRanges(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.IPAllowlistResponse{Ranges: ranges, ClientIP: clientIP}, nil
+}
+
+// UpdateAllowlist replaces the allowlist of a workspace. The owner making
+// the change must stay inside it.
+func (s *IPAllowlistService) UpdateAllowlist(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	clientIP string,
+	req *models.UpdateIPAllowlistRequest,
+) (*models.IPAllowlistResponse, error) {
+	if len(req.Ranges) > maxIPRangesPerWorkspace {
+		return nil, fmt.Errorf("%w: at most %d ranges are allowed", ErrInvalidIPAllowlist, maxIPRangesPerWorkspace)
+	}
+
+	ranges := make([]models.IPRangeInput, 0, len(req.Ranges))
+	prefixes := make([]netip.Prefix, 0, len(req.Ranges))
+	seen := make(map[netip.Prefix]bool, len(req.Ranges))
+	for _, input := range req.Ranges {
+		prefix, err := parseIPRange(input.CIDR)
+		if err != nil {
+			return nil, err
+		}
+		description := strings.TrimSpace(input.Description)
+		if utf8.RuneCountInString(description) > maxIPRangeDescriptionLen {
+			return nil, fmt.Errorf("%w: descriptions must be at most %d characters", ErrInvalidIPAllowlist, maxIPRangeDescriptionLen)
+		}
+		if seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+		ranges = append(ranges, models.IPRangeInput{CIDR: prefix.String(), Description: description})
+	}
+
+	if len(prefixes) > 0 && !ipAllowed(prefixes, clientIP) {
+		return nil, ErrAllowlistExcludesCaller
+	}
+
+	if err := s.workspaceRepo.ReplaceIPRanges(ctx, workspaceID, userID, ranges); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, workspaceID)
+	s.mu.Unlock()
+
+	cidrs := make([]string, len(ranges))
+	for i := range ranges {
+		cidrs[i] = ranges[i].CIDR
+	}
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditIPAllowlistUpdated,
+		WorkspaceID: &workspaceID,
+		TargetType:  "workspace",
+		TargetID:    workspaceID.String(),
+		Metadata:    map[string]interface{}{"ranges": cidrs},
+	})
+
+	return s.GetAllowlist(ctx, workspaceID, clientIP)
+}
+
+// CheckAccess returns ErrIPNotAllowed when a workspace has an allowlist
+// that clientIP is outside of. Blocked attempts are audited, once a minute
+// per workspace, user and address. userID is uuid.Nil for anonymous
+// visitors.
+func (s *IPAllowlistService) CheckAccess(ctx context.Context, workspaceID, userID uuid.UUID, clientIP string) error {
+	allowlists, err := s.allowlists(ctx, []uuid.UUID{workspaceID})
+	if err != nil {
+		return err
+	}
+	return s.checkPrefixes(ctx, workspaceID, userID, clientIP, allowlists[workspaceID])
+}
+
+// Restricted reports whether a workspace has an IP allowlist
+func (s *IPAllowlistService) Restricted(ctx context.Context, workspaceID uuid.UUID) (bool, error) {
+	allowlists, err := s.allowlists(ctx, []uuid.UUID{workspaceID})
+	if err != nil {
+		return false, err
+	}
+	return len(allowlists[workspaceID]) > 0, nil
+}
+
+// CheckAccessMany is CheckAccess for several workspaces, whose allowlists
+// are loaded with one query. The result tells for each workspace whether
+// clientIP may access it.
+func (s *IPAllowlistService) CheckAccessMany(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+	userID uuid.UUID,
+	clientIP string,
+) (map[uuid.UUID]bool, error) {
+	allowlists, err := s.allowlists(ctx, workspaceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[uuid.UUID]bool, len(workspaceIDs))
+	for _, workspaceID := range workspaceIDs {
+		allowed[workspaceID] = s.checkPrefixes(ctx, workspaceID, userID, clientIP, allowlists[workspaceID]) == nil
+	}
+	return allowed, nil
+}
+
+// checkPrefixes returns ErrIPNotAllowed when clientIP is outside of a
+// non-empty allowlist and audits the attempt
+func (s *IPAllowlistService) checkPrefixes(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	clientIP string,
+	prefixes []netip.Prefix,
+) error {
+	if len(prefixes) == 0 || ipAllowed(prefixes, clientIP) {
+		return nil
+	}
+
+	if s.shouldAuditBlocked(blockedAttempt{workspaceID: workspaceID, userID: userID, ip: clientIP}) {
+		entry := &AuditEntry{
+			Action:      models.AuditWorkspaceAccessBlocked,
+			WorkspaceID: &workspaceID,
+			TargetType:  "workspace",
+			TargetID:    workspaceID.String(),
+			Metadata:    map[string]interface{}{"ip_address": clientIP},
+		}
+		if userID != uuid.Nil {
+			entry.Actor = &userID
+		}
+		s.audit.Record(ctx, entry)
+	}
+
+	return ErrIPNotAllowed
+}
+
+// allowlists returns the allowlists of workspaces, loading the ones that
+// aren't cached with one query
+func (s *IPAllowlistService) allowlists(ctx context.Context, workspaceIDs []uuid.UUID) (map[uuid.UUID][]netip.Prefix, error) {
+	now := time.Now()
+	prefixes := make(map[uuid.UUID][]netip.Prefix, len(workspaceIDs))
+	var missing []uuid.UUID
+
+	s.mu.Lock()
+	for _, workspaceID := range workspaceIDs {
+		if cached, ok := s.cache[workspaceID]; ok && now.Before(cached.expiresAt) {
+			prefixes[workspaceID] = cached.prefixes
+		} else {
+			missing = append(missing, workspaceID)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(missing) == 0 {
+		return prefixes, nil
+	}
+
+	cidrs, err := s.workspaceRepo.ListIPRangesByWorkspaces(ctx, missing)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to load the IP allowlists of %d workspaces: %v", len(missing), err)
+		return nil, err
+	}
+
+	loaded := make(map[uuid.UUID][]netip.Prefix, len(missing))
+	for _, workspaceID := range missing {
+		workspacePrefixes := make([]netip.Prefix, 0, len(cidrs[workspaceID]))
+		for _, cidr := range cidrs[workspaceID] {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				hlog.CtxWarnf(ctx, "Ignoring invalid IP range %q of workspace %s", cidr, workspaceID)
+				continue
+			}
+			workspacePrefixes = append(workspacePrefixes, prefix)
+		}
+		loaded[workspaceID] = workspacePrefixes
+		prefixes[workspaceID] = workspacePrefixes
+	}
+
+	s.mu.Lock()
+	for workspaceID, workspacePrefixes := range loaded {
+		s.cache[workspaceID] = cachedAllowlist{prefixes: workspacePrefixes, expiresAt: now.Add(ipAllowlistCacheTTL)}
+	}
+	s.mu.Unlock()
+
+	return prefixes, nil
+}
+
+// shouldAuditBlocked reports whether a blocked attempt wasn't audited in
+// the last ipBlockedAuditInterval, so a retrying client can't flood the log
+func (s *IPAllowlistService) shouldAuditBlocked(attempt blockedAttempt) bool {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.blocked[attempt]; ok && now.Sub(last) < ipBlockedAuditInterval {
+		return false
+	}
+	if len(s.blocked) >= maxIPBlockedAuditEntries {
+		for key, last := range s.blocked {
+			if now.Sub(last) >= ipBlockedAuditInterval {
+				delete(s.blocked, key)
+			}
+		}
+	}
+	s.blocked[attempt] = now
+	return true
+}
+
+// parseIPRange parses a CIDR range or a single address
+func parseIPRange(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%w: %q is not an IP address or CIDR range", ErrInvalidIPAllowlist, value)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: %q is not an IP address or CIDR range", ErrInvalidIPAllowlist, value)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ipAllowed reports whether ip is inside one of the prefixes. Addresses
+// that can't be parsed are outside.
+func ipAllowed(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
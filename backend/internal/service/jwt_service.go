@@ -19,9 +19,10 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// JWTService handles JWT token operations
+// JWTService handles JWT token operations. Tokens are signed with the
+// current secret and accepted with the previous one after a rotation.
 type JWTService struct {
-	secret               string
+	cfg                  *config.JWTConfig
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
 }
@@ -39,7 +40,7 @@ func NewJWTService(cfg *config.JWTConfig) (*JWTService, error) {
 	}
 
 	return &JWTService{
-		secret:               cfg.Secret,
+		cfg:                  cfg,
 		accessTokenDuration:  accessDuration,
 		refreshTokenDuration: refreshDuration,
 	}, nil
@@ -67,7 +68,7 @@ func (s *JWTService) GenerateAccessToken(userID uuid.UUID, email string, usernam
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.secret))
+	tokenString, err := token.SignedString([]byte(s.cfg.CurrentSecret()))
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to sign access token: %w", err)
 	}
@@ -90,7 +91,11 @@ func (s *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(s.secret), nil
+		keys := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(s.cfg.CurrentSecret())}}
+		if previous := s.cfg.PreviousSecret(); previous != "" {
+			keys.Keys = append(keys.Keys, []byte(previous))
+		}
+		return keys, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const defaultLDAPTimeout = 5 * time.Second
+
+var (
+	// ErrLDAPUserNotFound is returned when the directory has no user for a
+	// login
+	ErrLDAPUserNotFound = errors.New("user not found in directory")
+	// ErrLDAPInvalidCredentials is returned when the directory refuses the
+	// password of a user
+	ErrLDAPInvalidCredentials = errors.New("invalid credentials")
+)
+
+// LDAPIdentity is a user the directory authenticated
+type LDAPIdentity struct {
+	// Roles are the workspace roles the groups of the user grant
+	Roles map[uuid.UUID]models.WorkspaceRole
+	DN    string
+	Email string
+	Name  string
+}
+
+// ldapGroupRole is a parsed group role of the config
+type ldapGroupRole struct {
+	group       *ldap.DN
+	role        models.WorkspaceRole
+	workspaceID uuid.UUID
+}
+
+// LDAPAuthenticator checks passwords against an LDAP or Active Directory
+// server. It searches the user with the service account and binds as the
+// user with the password on a fresh connection for every login.
+type LDAPAuthenticator struct {
+	cfg        *config.LDAPConfig
+	groupRoles []ldapGroupRole
+	timeout    time.Duration
+}
+
+// NewLDAPAuthenticator creates an LDAP authenticator. The group roles of
+// the config are validated here so typos fail at startup.
+func NewLDAPAuthenticator(cfg *config.LDAPConfig) (*LDAPAuthenticator, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, fmt.Errorf("ldap url and base_dn are required")
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, fmt.Errorf("ldap user_filter must contain %%s for the login")
+	}
+
+	timeout := defaultLDAPTimeout
+	if cfg.Timeout != "" {
+		parsed, err := cfg.GetTimeoutDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid ldap timeout: %w", err)
+		}
+		timeout = parsed
+	}
+
+	groupRoles := make([]ldapGroupRole, 0, len(cfg.GroupRoles))
+	for _, groupRole := range cfg.GroupRoles {
+		group, err := ldap.ParseDN(groupRole.GroupDN)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ldap group %q: %w", groupRole.GroupDN, err)
+		}
+		workspaceID, err := uuid.Parse(groupRole.WorkspaceID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace of ldap group %q: %w", groupRole.GroupDN, err)
+		}
+		role := models.WorkspaceRole(groupRole.Role)
+		if role != models.WorkspaceRoleEditor && role != models.WorkspaceRoleViewer {
+			return nil, fmt.Errorf("role of ldap group %q must be editor or viewer", groupRole.GroupDN)
+		}
+		groupRoles = append(groupRoles, ldapGroupRole{group: group, workspaceID: workspaceID, role: role})
+	}
+
+	return &LDAPAuthenticator{
+		cfg:        cfg,
+		groupRoles: groupRoles,
+		timeout:    timeout,
+	}, nil
+}
+
+// LocalFallback reports whether users that aren't in the directory may log
+// in with a local password
+func (a *LDAPAuthenticator) LocalFallback() bool {
+	return a.cfg.LocalFallback
+}
+
+// Authenticate checks the password of the user the login resolves to and
+// returns their identity
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, login, password string) (*LDAPIdentity, error) {
+	// An empty password would be an unauthenticated bind, which servers
+	// accept without checking anything
+	if password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind ldap service account: %w", err)
+		}
+	}
+
+	emailAttribute := ldapAttribute(a.cfg.EmailAttribute, "mail")
+	nameAttribute := ldapAttribute(a.cfg.NameAttribute, "displayName")
+	groupAttribute := ldapAttribute(a.cfg.GroupAttribute, "memberOf")
+
+	search := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(a.timeout.Seconds()), false,
+		strings.ReplaceAll(a.cfg.UserFilter, "%s", ldap.EscapeFilter(login)),
+		[]string{emailAttribute, nameAttribute, groupAttribute},
+		nil,
+	)
+	result, err := conn.Search(search)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search ldap user: %w", err)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrLDAPUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("ldap user filter matches several entries for %s", login)
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind ldap user: %w", err)
+	}
+
+	email := entry.GetEqualFoldAttributeValue(emailAttribute)
+	if email == "" {
+		email = login
+	}
+	name := entry.GetEqualFoldAttributeValue(nameAttribute)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	return &LDAPIdentity{
+		DN:    entry.DN,
+		Email: email,
+		Name:  name,
+		Roles: a.rolesOf(entry.GetEqualFoldAttributeValues(groupAttribute)),
+	}, nil
+}
+
+func (a *LDAPAuthenticator) dial(ctx context.Context) (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: a.cfg.InsecureSkipVerify, //nolint:gosec // opt-in for self-signed directory certificates
+		MinVersion:         tls.VersionTLS12,
+	}
+	dialer := &net.Dialer{Timeout: a.timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	conn.SetTimeout(a.timeout)
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start ldap tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// rolesOf returns the workspace roles the groups grant, the highest role
+// per workspace
+func (a *LDAPAuthenticator) rolesOf(groupDNs []string) map[uuid.UUID]models.WorkspaceRole {
+	roles := make(map[uuid.UUID]models.WorkspaceRole)
+	for _, groupDN := range groupDNs {
+		group, err := ldap.ParseDN(groupDN)
+		if err != nil {
+			continue
+		}
+		for _, groupRole := range a.groupRoles {
+			if !groupRole.group.EqualFold(group) {
+				continue
+			}
+			if roles[groupRole.workspaceID] != models.WorkspaceRoleEditor {
+				roles[groupRole.workspaceID] = groupRole.role
+			}
+		}
+	}
+	return roles
+}
+
+func ldapAttribute(attribute, fallback string) string {
+	if attribute == "" {
+		return fallback
+	}
+	return attribute
+}
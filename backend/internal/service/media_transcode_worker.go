@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	mediaTranscodeTimeout = 2 * time.Minute
+
+	// posterFrameOffset is where the poster frame is taken from, clamped for short clips
+	posterFrameOffset = time.Second
+	posterFrameDivide = 2
+	posterSeekDigits  = 3
+)
+
+// MediaTranscodeWorker extracts metadata and a poster frame from uploaded videos
+type MediaTranscodeWorker struct {
+	assetService *AssetService
+	nats         *nats.Conn
+	sub          *nats.Subscription
+}
+
+// mediaProbe is the subset of ffprobe JSON output we use
+type mediaProbe struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"streams"`
+}
+
+// NewMediaTranscodeWorker creates a new media transcode worker
+func NewMediaTranscodeWorker(nc *nats.Conn, assetService *AssetService) (*MediaTranscodeWorker, error) {
+	worker := &MediaTranscodeWorker{
+		assetService: assetService,
+		nats:         nc,
+	}
+
+	// Subscribe to transcode queue
+	sub, err := nc.QueueSubscribe(MediaTranscodeSubject, "media-transcoders", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to media transcode queue: %w", err)
+	}
+
+	worker.sub = sub
+	return worker, nil
+}
+
+// Close closes the transcode worker subscription
+func (w *MediaTranscodeWorker) Close() error {
+	if w.sub != nil {
+		return w.sub.Unsubscribe()
+	}
+	return nil
+}
+
+// handleMessage processes a transcode job
+func (w *MediaTranscodeWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "asset.transcode")
+	defer span.End()
+
+	var job models.MediaTranscodeJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal media transcode job: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mediaTranscodeTimeout)
+	defer cancel()
+
+	if err := w.process(ctx, &job); err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to process video %s: %v", job.AssetID, err)
+		_ = w.assetService.assetRepo.UpdateAssetStatus(context.Background(), job.AssetID, models.AssetStatusFailed, nil)
+		return
+	}
+
+	hlog.CtxInfof(ctx, "Processed video %s", job.AssetID)
+}
+
+// process probes the video, uploads its poster frame thumbnail and stores the metadata
+func (w *MediaTranscodeWorker) process(ctx context.Context, job *models.MediaTranscodeJob) error {
+	asset, err := w.assetService.assetRepo.GetAssetByID(ctx, job.AssetID)
+	if err != nil {
+		return fmt.Errorf("asset not found: %w", err)
+	}
+
+	data, err := w.assetService.readObject(ctx, job.ObjectKey)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "media-transcode-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input"+filepath.Ext(job.ObjectKey))
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write video: %w", err)
+	}
+
+	probe, err := probeMedia(ctx, input)
+	if err != nil {
+		return err
+	}
+	if len(probe.Streams) == 0 {
+		return fmt.Errorf("file has no video stream")
+	}
+
+	width := probe.Streams[0].Width
+	height := probe.Streams[0].Height
+	asset.Width = &width
+	asset.Height = &height
+
+	var duration time.Duration
+	if seconds, parseErr := strconv.ParseFloat(probe.Format.Duration, 64); parseErr == nil {
+		duration = time.Duration(seconds * float64(time.Second))
+		durationMs := int(duration.Milliseconds())
+		asset.DurationMs = &durationMs
+	}
+
+	poster, err := extractPosterFrame(ctx, dir, input, duration)
+	if err != nil {
+		return err
+	}
+
+	thumbnailURL, err := w.assetService.createAndUploadThumbnail(ctx, poster, "jpeg", ".jpg", job.WorkspaceID, "image/jpeg")
+	if err != nil {
+		return err
+	}
+
+	asset.ThumbnailURL = thumbnailURL
+	asset.Status = models.AssetStatusReady
+
+	if err := w.assetService.assetRepo.UpdateAssetMedia(ctx, asset); err != nil {
+		w.assetService.cleanupUploadedFiles(ctx, w.assetService.extractObjectName(*thumbnailURL), nil)
+		return err
+	}
+
+	return nil
+}
+
+// probeMedia reads stream dimensions and duration with ffprobe
+func probeMedia(ctx context.Context, input string) (*mediaProbe, error) {
+	//nolint:gosec // arguments are fixed, the input file lives in our temp dir
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		input,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe video: %w", err)
+	}
+
+	var probe mediaProbe
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse probe output: %w", err)
+	}
+
+	return &probe, nil
+}
+
+// extractPosterFrame grabs a single frame from the video as a JPEG image
+func extractPosterFrame(ctx context.Context, dir, input string, duration time.Duration) (image.Image, error) {
+	offset := posterFrameOffset
+	if duration > 0 && duration < posterFrameOffset*posterFrameDivide {
+		offset = duration / posterFrameDivide
+	}
+
+	output := filepath.Join(dir, "poster.jpg")
+
+	//nolint:gosec // arguments are fixed, the files live in our temp dir
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-ss", strconv.FormatFloat(offset.Seconds(), 'f', posterSeekDigits, 64),
+		"-i", input,
+		"-frames:v", "1",
+		"-y", output,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to extract poster frame: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	data, err := os.ReadFile(output) //nolint:gosec // path is built from our own temp dir
+	if err != nil {
+		return nil, fmt.Errorf("failed to read poster frame: %w", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode poster frame: %w", err)
+	}
+
+	return img, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	// DefaultUsageMonths is how many months of usage are returned when none are asked for
+	DefaultUsageMonths = 12
+	// MaxUsageMonths is the longest usage history returned
+	MaxUsageMonths = 36
+
+	meteringRecordTimeout = 5 * time.Second
+	// usageReportBatchSize is how many closed months are reported to billing per run
+	usageReportBatchSize = 100
+)
+
+type editorKey struct {
+	workspaceID uuid.UUID
+	userID      uuid.UUID
+}
+
+// MeteringService aggregates the billable usage of workspaces per calendar
+// month: active editors, peak storage and rendered exports. Recording is
+// fire and forget like analytics, it never fails or slows down the caller.
+// A nil service meters nothing.
+type MeteringService struct {
+	usageRepo *repository.UsageRepository
+	billing   *BillingService
+
+	// Editors recorded by this instance in the current month, so each is
+	// written once per instance
+	mu      sync.Mutex
+	period  time.Time
+	editors map[editorKey]bool
+}
+
+// NewMeteringService creates a new metering service. Without billing usage
+// is still metered but no overage is reported.
+func NewMeteringService(usageRepo *repository.UsageRepository, billing *BillingService) *MeteringService {
+	return &MeteringService{
+		usageRepo: usageRepo,
+		billing:   billing,
+		editors:   make(map[editorKey]bool),
+	}
+}
+
+// usagePeriod returns the month t falls in
+func usagePeriod(t time.Time) time.Time {
+	year, month, _ := t.UTC().Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// RecordEditor counts a user who changed a board as an active editor of the
+// workspace this month
+func (s *MeteringService) RecordEditor(workspaceID, userID uuid.UUID) {
+	if s == nil || userID == uuid.Nil {
+		return
+	}
+
+	period := usagePeriod(time.Now())
+	key := editorKey{workspaceID: workspaceID, userID: userID}
+
+	s.mu.Lock()
+	if !s.period.Equal(period) {
+		s.period = period
+		s.editors = make(map[editorKey]bool)
+	}
+	if s.editors[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.editors[key] = true
+	s.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), meteringRecordTimeout)
+		defer cancel()
+
+		if err := s.usageRepo.RecordActiveEditor(ctx, workspaceID, userID, period); err != nil {
+			hlog.CtxWarnf(ctx, "Failed to meter editor %s of workspace %s: %v", userID, workspaceID, err)
+			// Recorded again on the next change
+			s.mu.Lock()
+			if s.period.Equal(period) {
+				delete(s.editors, key)
+			}
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// RecordExport counts an export of a board rendered by the server
+func (s *MeteringService) RecordExport(workspaceID uuid.UUID) {
+	if s == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), meteringRecordTimeout)
+		defer cancel()
+
+		if err := s.usageRepo.IncrementExports(ctx, workspaceID, usagePeriod(time.Now())); err != nil {
+			hlog.CtxWarnf(ctx, "Failed to meter export of workspace %s: %v", workspaceID, err)
+		}
+	}()
+}
+
+// GetUsage returns the last months of usage of a workspace, newest first.
+// With billing enabled each month carries its overage under the current
+// plan of the owner.
+func (s *MeteringService) GetUsage(ctx context.Context, workspaceID uuid.UUID, months int) (*models.UsageResponse, error) {
+	if months <= 0 {
+		months = DefaultUsageMonths
+	}
+	months = min(months, MaxUsageMonths)
+
+	periods, err := s.usageRepo.ListUsage(ctx, workspaceID, months)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.UsageResponse{Periods: periods}
+	if s.billing == nil {
+		return response, nil
+	}
+
+	entitlements, err := s.billing.WorkspaceEntitlements(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	response.Entitlements = entitlements
+	for i := range response.Periods {
+		response.Periods[i].Overage = s.billing.Overage(entitlements, &response.Periods[i])
+	}
+
+	return response, nil
+}
+
+// Aggregate samples the storage of every workspace into the current month
+// and reports the months that closed since the last run to billing. A
+// month that fails to report is retried on the next run.
+func (s *MeteringService) Aggregate(ctx context.Context) error {
+	now := time.Now()
+	sampled, err := s.usageRepo.SampleStorage(ctx, usagePeriod(now))
+	if err != nil {
+		return err
+	}
+	hlog.CtxDebugf(ctx, "Sampled storage usage of %d workspaces", sampled)
+
+	if s.billing == nil {
+		return nil
+	}
+
+	closed, err := s.usageRepo.ListUnreportedUsage(ctx, usagePeriod(now), usageReportBatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range closed {
+		usage := &closed[i]
+		if err := s.billing.ReportUsage(ctx, usage); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to report usage of workspace %s for %s: %v",
+				usage.WorkspaceID, usage.PeriodStart.Format("2006-01"), err)
+			continue
+		}
+		if err := s.usageRepo.MarkReported(ctx, usage.WorkspaceID, usage.PeriodStart); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
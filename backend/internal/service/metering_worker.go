@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const meteringAggregateTimeout = 10 * time.Minute
+
+// MeteringWorker periodically samples storage usage and reports the
+// overage of closed months to billing
+type MeteringWorker struct {
+	metering *MeteringService
+	done     chan struct{}
+	trigger  chan struct{}
+	interval time.Duration
+}
+
+// NewMeteringWorker creates and starts a new metering worker
+func NewMeteringWorker(metering *MeteringService, interval time.Duration) (*MeteringWorker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	worker := &MeteringWorker{
+		metering: metering,
+		done:     make(chan struct{}),
+		trigger:  make(chan struct{}, 1),
+		interval: interval,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the metering worker
+func (w *MeteringWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger samples usage and reports closed months now. It returns false if
+// a run is already pending.
+func (w *MeteringWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *MeteringWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.aggregate()
+		case <-w.trigger:
+			w.aggregate()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *MeteringWorker) aggregate() {
+	ctx, cancel := context.WithTimeout(context.Background(), meteringAggregateTimeout)
+	defer cancel()
+
+	if err := w.metering.Aggregate(ctx); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to aggregate usage: %v", err)
+	}
+}
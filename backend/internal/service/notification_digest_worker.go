@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const notificationDigestTimeout = 5 * time.Minute
+
+// NotificationDigestWorker periodically emails users the notifications they
+// haven't read in the app
+type NotificationDigestWorker struct {
+	notificationService *NotificationService
+	done                chan struct{}
+	trigger             chan struct{}
+	interval            time.Duration
+	delay               time.Duration
+}
+
+// NewNotificationDigestWorker creates and starts a new digest worker.
+// Notifications are emailed once they have been unread for delay.
+func NewNotificationDigestWorker(
+	notificationService *NotificationService,
+	interval, delay time.Duration,
+) (*NotificationDigestWorker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	worker := &NotificationDigestWorker{
+		notificationService: notificationService,
+		done:                make(chan struct{}),
+		trigger:             make(chan struct{}, 1),
+		interval:            interval,
+		delay:               delay,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the digest worker
+func (w *NotificationDigestWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger sends due digests now instead of at the next tick. It returns
+// false if a run is already pending.
+func (w *NotificationDigestWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// run sends digests on every tick until the worker is closed
+func (w *NotificationDigestWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.send()
+		case <-w.trigger:
+			w.send()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *NotificationDigestWorker) send() {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationDigestTimeout)
+	defer cancel()
+
+	sent, err := w.notificationService.SendDigests(ctx, w.delay)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to send notification digests: %v", err)
+	}
+
+	if sent > 0 {
+		hlog.CtxInfof(ctx, "Sent %d notification digests", sent)
+	}
+}
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	// maxMentionsPerElement caps the users notified by one piece of content
+	maxMentionsPerElement = 20
+	mentionExcerptLength  = 140
+	// notificationDigestBatch is the number of notifications emailed per digest run
+	notificationDigestBatch = 500
+)
+
+var (
+	// ErrNotificationNotFound is returned for unknown notifications
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrInvalidDigestFrequency is returned for unknown digest frequencies
+	ErrInvalidDigestFrequency = errors.New("digest frequency must be frequent, daily, weekly or off")
+)
+
+// mentionPattern matches @username not preceded by a word character, so
+// email addresses aren't taken as mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// NotificationService stores mentions and notifies the mentioned users in
+// the app, over WebSocket and with a periodic email digest
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	userRepo         *repository.UserRepository
+	emailService     *EmailService
+	hub              *Hub
+	webPush          *WebPushService
+	frontendURL      string
+}
+
+// NewNotificationService creates a new notification service. frontendURL is
+// used for deep links to elements.
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	userRepo *repository.UserRepository,
+	emailService *EmailService,
+	hub *Hub,
+	webPush *WebPushService,
+	frontendURL string,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		emailService:     emailService,
+		hub:              hub,
+		webPush:          webPush,
+		frontendURL:      frontendURL,
+	}
+}
+
+// ParseMentions returns the lowercase usernames mentioned in content, without
+// duplicates and in order of appearance
+func ParseMentions(content string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(content, -1)
+	seen := make(map[string]bool, len(matches))
+	usernames := make([]string, 0, len(matches))
+
+	for _, match := range matches {
+		// A trailing dot or dash ends the sentence, it isn't part of the username
+		username := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if !models.ValidUsername(username) || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentionsPerElement {
+			break
+		}
+	}
+
+	return usernames
+}
+
+// SyncMentions updates the mentions of an element after its content changed
+// and notifies the members that were newly mentioned, instantly if they are
+// online and with a browser push notification if they aren't. Usernames that
+// aren't members of the workspace and self-mentions are ignored.
+func (s *NotificationService) SyncMentions(
+	ctx context.Context,
+	workspaceID, elementID, authorID uuid.UUID,
+	content string,
+) error {
+	userIDs := []uuid.UUID{}
+	if usernames := ParseMentions(content); len(usernames) > 0 {
+		members, err := s.notificationRepo.GetMembersByUsernames(ctx, workspaceID, usernames)
+		if err != nil {
+			return err
+		}
+		for i := range members {
+			if members[i].ID != authorID {
+				userIDs = append(userIDs, members[i].ID)
+			}
+		}
+	}
+
+	added, err := s.notificationRepo.ReplaceMentions(ctx, workspaceID, elementID, authorID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	authorName := "Someone"
+	author, err := s.userRepo.GetByID(ctx, authorID)
+	if err != nil {
+		return err
+	}
+	if author != nil && author.Name != "" {
+		authorName = author.Name
+	}
+
+	excerpt := mentionExcerpt(content)
+	link := elementURL(s.frontendURL, workspaceID, elementID)
+	for _, userID := range added {
+		notification := &models.Notification{
+			ID:          uuid.New(),
+			UserID:      userID,
+			WorkspaceID: workspaceID,
+			Type:        models.NotificationTypeMention,
+			ActorID:     &authorID,
+			ElementID:   &elementID,
+			Data: map[string]interface{}{
+				"excerpt": excerpt,
+				"url":     link,
+			},
+		}
+		if err := s.notify(ctx, notification, &models.PushMessage{
+			Title: authorName + " mentioned you",
+			Body:  excerpt,
+			URL:   link,
+			Tag:   "mention-" + elementID.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NotifyAccessRequest notifies the owners of a workspace that a user asks to
+// join it. A nil service notifies no one.
+func (s *NotificationService) NotifyAccessRequest(
+	ctx context.Context,
+	workspace *models.Workspace,
+	request *models.WorkspaceAccessRequest,
+	ownerIDs []uuid.UUID,
+) error {
+	if s == nil {
+		return nil
+	}
+
+	requesterName := "Someone"
+	if request.User != nil && request.User.Name != "" {
+		requesterName = request.User.Name
+	}
+
+	link := workspaceURL(s.frontendURL, workspace.ID)
+	for _, ownerID := range ownerIDs {
+		notification := &models.Notification{
+			ID:          uuid.New(),
+			UserID:      ownerID,
+			WorkspaceID: workspace.ID,
+			Type:        models.NotificationTypeAccessRequest,
+			ActorID:     &request.UserID,
+			Data: map[string]interface{}{
+				"request_id": request.ID,
+				"role":       request.Role,
+				"url":        link,
+			},
+		}
+		if err := s.notify(ctx, notification, &models.PushMessage{
+			Title: "Access request for " + workspace.Name,
+			Body:  fmt.Sprintf("%s asks to join as %s", requesterName, request.Role),
+			URL:   link,
+			Tag:   "access-request-" + request.ID.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NotifyAccessDecision notifies a user that their request to join a
+// workspace was approved or denied. A nil service notifies no one.
+func (s *NotificationService) NotifyAccessDecision(
+	ctx context.Context,
+	workspace *models.Workspace,
+	request *models.WorkspaceAccessRequest,
+) error {
+	if s == nil {
+		return nil
+	}
+
+	notificationType := models.NotificationTypeAccessDenied
+	title := "Access to " + workspace.Name + " denied"
+	if request.Status == models.AccessRequestApproved {
+		notificationType = models.NotificationTypeAccessApproved
+		title = "You joined " + workspace.Name + " as " + string(request.Role)
+	}
+
+	link := workspaceURL(s.frontendURL, workspace.ID)
+	return s.notify(ctx, &models.Notification{
+		ID:          uuid.New(),
+		UserID:      request.UserID,
+		WorkspaceID: workspace.ID,
+		Type:        notificationType,
+		ActorID:     request.DecidedBy,
+		Data: map[string]interface{}{
+			"request_id": request.ID,
+			"role":       request.Role,
+			"url":        link,
+		},
+	}, &models.PushMessage{
+		Title: title,
+		URL:   link,
+		Tag:   "access-request-" + request.ID.String(),
+	})
+}
+
+// notify stores a notification and sends it to its user, instantly if they
+// are online and with a browser push notification if they aren't
+func (s *NotificationService) notify(ctx context.Context, notification *models.Notification, message *models.PushMessage) error {
+	if err := s.notificationRepo.CreateNotification(ctx, notification); err != nil {
+		return err
+	}
+
+	s.push(notification)
+	s.webPush.notifyOffline(ctx, notification.UserID, message)
+	return nil
+}
+
+// syncMentions runs SyncMentions and only logs failures, so element changes
+// don't fail because of notifications. A nil service skips mentions.
+func (s *NotificationService) syncMentions(
+	ctx context.Context,
+	workspaceID, elementID, authorID uuid.UUID,
+	content string,
+) {
+	if s == nil {
+		return
+	}
+	if err := s.SyncMentions(ctx, workspaceID, elementID, authorID, content); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to sync mentions of element %s: %v", elementID, err)
+	}
+}
+
+// push sends a notification to the connected clients of its user
+func (s *NotificationService) push(notification *models.Notification) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.SendToUser(notification.UserID, &models.WSMessage{
+		Type:      models.MessageTypeNotification,
+		UserID:    notification.UserID,
+		Timestamp: time.Now(),
+		Payload:   notification,
+	})
+}
+
+// ListNotifications returns the notifications of a user, newest first, and
+// the number of unread ones
+func (s *NotificationService) ListNotifications(
+	ctx context.Context,
+	userID uuid.UUID,
+	unreadOnly bool,
+	limit, offset int,
+) (*models.NotificationListResponse, error) {
+	notifications, err := s.notificationRepo.ListNotifications(ctx, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NotificationListResponse{
+		Notifications: notifications,
+		UnreadCount:   unread,
+	}, nil
+}
+
+// MarkRead marks a notification as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	found, err := s.notificationRepo.MarkRead(ctx, userID, notificationID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all notifications of a user as read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.notificationRepo.MarkAllRead(ctx, userID)
+}
+
+// GetPreferences returns the notification preferences of a user, the
+// defaults if the user never changed them
+func (s *NotificationService) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	prefs, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = models.DefaultNotificationPreferences(userID)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences changes the notification preferences set in the request
+func (s *NotificationService) UpdatePreferences(
+	ctx context.Context,
+	userID uuid.UUID,
+	req *models.UpdateNotificationPreferencesRequest,
+) (*models.NotificationPreferences, error) {
+	if req.DigestFrequency != nil && !models.ValidDigestFrequency(*req.DigestFrequency) {
+		return nil, ErrInvalidDigestFrequency
+	}
+
+	prefs, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EmailOnInvite != nil {
+		prefs.EmailOnInvite = *req.EmailOnInvite
+	}
+	if req.EmailOnMention != nil {
+		prefs.EmailOnMention = *req.EmailOnMention
+	}
+	if req.DigestFrequency != nil {
+		prefs.DigestFrequency = *req.DigestFrequency
+	}
+	if req.MuteWebhooks != nil {
+		prefs.MuteWebhooks = *req.MuteWebhooks
+	}
+
+	if err := s.notificationRepo.UpsertPreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// SendDigests emails every user one digest of the notifications that have
+// been unread for longer than delay. Returns the number of digests sent.
+func (s *NotificationService) SendDigests(ctx context.Context, delay time.Duration) (int, error) {
+	notifications, err := s.notificationRepo.GetDigestNotifications(ctx, time.Now().Add(-delay), notificationDigestBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	byUser := make(map[uuid.UUID][]models.NotificationWithContext)
+	var order []uuid.UUID
+	for i := range notifications {
+		userID := notifications[i].UserID
+		if _, ok := byUser[userID]; !ok {
+			order = append(order, userID)
+		}
+		byUser[userID] = append(byUser[userID], notifications[i])
+	}
+
+	sent := 0
+	for _, userID := range order {
+		ok, err := s.sendDigest(ctx, userID, byUser[userID])
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// sendDigest emails the notifications of one user and marks them as emailed.
+// Mentions are left out of the email if the user turned them off, they are
+// still marked so they aren't picked up again.
+func (s *NotificationService) sendDigest(
+	ctx context.Context,
+	userID uuid.UUID,
+	notifications []models.NotificationWithContext,
+) (bool, error) {
+	prefs, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	ids := make([]uuid.UUID, len(notifications))
+	mentions := make([]map[string]interface{}, 0, len(notifications))
+	for i := range notifications {
+		n := &notifications[i]
+		ids[i] = n.ID
+		if n.Type != models.NotificationTypeMention || !prefs.EmailOnMention {
+			continue
+		}
+
+		actorName := "Someone"
+		if n.ActorName != nil {
+			actorName = *n.ActorName
+		}
+		mentions = append(mentions, map[string]interface{}{
+			"actor_name":     actorName,
+			"workspace_name": n.WorkspaceName,
+			"excerpt":        n.Data["excerpt"],
+			"url":            n.Data["url"],
+		})
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	sent := false
+	if user != nil && !user.IsBot && len(mentions) > 0 {
+		if err := s.emailService.SendMentionDigest(user.Email, user.Name, mentions); err != nil {
+			return false, err
+		}
+		if err := s.notificationRepo.MarkDigestSent(ctx, userID); err != nil {
+			return false, err
+		}
+		sent = true
+	}
+
+	return sent, s.notificationRepo.MarkEmailed(ctx, ids)
+}
+
+// mentionExcerpt returns the content as a single line of at most
+// mentionExcerptLength characters
+func mentionExcerpt(content string) string {
+	excerpt := strings.TrimSpace(whitespacePattern.ReplaceAllString(content, " "))
+	if utf8.RuneCountInString(excerpt) <= mentionExcerptLength {
+		return excerpt
+	}
+	runes := []rune(excerpt)
+	return string(runes[:mentionExcerptLength-1]) + "…"
+}
+
+// workspaceURL returns the frontend link of a workspace, empty when the
+// frontend URL isn't configured
+func workspaceURL(frontendURL string, workspaceID uuid.UUID) string {
+	if frontendURL == "" {
+		return ""
+	}
+	return strings.TrimRight(frontendURL, "/") + "/workspace/" + workspaceID.String()
+}
+
+// elementURL returns the frontend link that opens a board at an element
+func elementURL(frontendURL string, workspaceID, elementID uuid.UUID) string {
+	link := workspaceURL(frontendURL, workspaceID)
+	if link == "" {
+		return ""
+	}
+	return link + "?element=" + elementID.String()
+}
@@ -21,6 +21,8 @@ type OAuthService struct {
 	jwtService *JWTService
 	googleCfg  *oauth2.Config
 	githubCfg  *oauth2.Config
+	google     *config.OAuthProviderConfig
+	github     *config.OAuthProviderConfig
 }
 
 // NewOAuthService creates a new OAuth service
@@ -53,9 +55,19 @@ func NewOAuthService(
 		jwtService: jwtService,
 		googleCfg:  googleCfg,
 		githubCfg:  githubCfg,
+		google:     &cfg.Google,
+		github:     &cfg.GitHub,
 	}
 }
 
+// withCurrentSecret returns a copy of oauthCfg with the current client
+// secret of provider, which changes when the secret is rotated
+func withCurrentSecret(oauthCfg *oauth2.Config, provider *config.OAuthProviderConfig) *oauth2.Config {
+	current := *oauthCfg
+	current.ClientSecret = provider.CurrentClientSecret()
+	return &current
+}
+
 // GetGoogleAuthURL returns the Google OAuth authorization URL
 func (s *OAuthService) GetGoogleAuthURL(state string) string {
 	return s.googleCfg.AuthCodeURL(state, oauth2.AccessTypeOffline)
@@ -69,13 +81,14 @@ func (s *OAuthService) GetGitHubAuthURL(state string) string {
 // GoogleCallback handles Google OAuth callback
 func (s *OAuthService) GoogleCallback(ctx context.Context, code string) (*models.AuthResponse, error) {
 	// Exchange code for token
-	token, err := s.googleCfg.Exchange(ctx, code)
+	googleCfg := withCurrentSecret(s.googleCfg, s.google)
+	token, err := googleCfg.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
 
 	// Get user info from Google
-	client := s.googleCfg.Client(ctx, token)
+	client := googleCfg.Client(ctx, token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user info: %w", err)
@@ -105,13 +118,14 @@ func (s *OAuthService) GoogleCallback(ctx context.Context, code string) (*models
 // GitHubCallback handles GitHub OAuth callback
 func (s *OAuthService) GitHubCallback(ctx context.Context, code string) (*models.AuthResponse, error) {
 	// Exchange code for token
-	token, err := s.githubCfg.Exchange(ctx, code)
+	githubCfg := withCurrentSecret(s.githubCfg, s.github)
+	token, err := githubCfg.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
 	}
 
 	// Get user info from GitHub
-	client := s.githubCfg.Client(ctx, token)
+	client := githubCfg.Client(ctx, token)
 
 	// Get user profile
 	resp, err := client.Get("https://api.github.com/user")
@@ -210,6 +224,9 @@ func (s *OAuthService) findOrCreateUser(
 			return nil, fmt.Errorf("failed to create user: %w", createErr)
 		}
 	}
+	if !user.Active() {
+		return nil, ErrUserDeactivated
+	}
 
 	// Generate tokens
 	accessToken, expiresAt, err := s.jwtService.GenerateAccessToken(user.ID, user.Email)
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// OCR providers
+const (
+	OCRProviderNone      = ""
+	OCRProviderTesseract = "tesseract"
+	OCRProviderGoogle    = "google"
+)
+
+const (
+	defaultOCRTimeout = time.Minute
+	googleVisionURL   = "https://vision.googleapis.com/v1/images:annotate"
+	ocrMaxErrorBody   = 512
+)
+
+// OCRProvider recognizes the text in an image
+type OCRProvider interface {
+	Recognize(ctx context.Context, image []byte) (string, error)
+}
+
+// NewOCRProvider creates the provider selected by upload.ocr config, nil
+// when OCR is disabled
+func NewOCRProvider(cfg *config.OCRConfig) (OCRProvider, error) {
+	timeout := defaultOCRTimeout
+	if cfg.Timeout != "" {
+		parsed, err := cfg.GetTimeoutDuration()
+		if err != nil {
+			return nil, fmt.Errorf("invalid OCR timeout: %w", err)
+		}
+		timeout = parsed
+	}
+
+	switch cfg.Provider {
+	case OCRProviderNone:
+		return nil, nil
+	case OCRProviderTesseract:
+		return &TesseractOCR{command: cfg.Command, languages: cfg.Languages, timeout: timeout}, nil
+	case OCRProviderGoogle:
+		return &GoogleVisionOCR{
+			apiKey:    cfg.APIKey,
+			languages: cfg.Languages,
+			client:    &http.Client{Timeout: timeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR provider: %s", cfg.Provider)
+	}
+}
+
+// TesseractOCR recognizes text with the local tesseract binary
+type TesseractOCR struct {
+	command   string
+	languages []string
+	timeout   time.Duration
+}
+
+// Recognize pipes the image through tesseract
+func (p *TesseractOCR) Recognize(ctx context.Context, image []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	args := []string{"stdin", "stdout"}
+	if len(p.languages) > 0 {
+		args = append(args, "-l", strings.Join(p.languages, "+"))
+	}
+
+	var stdout, stderr bytes.Buffer
+	//nolint:gosec // the command comes from config, the image is passed on stdin
+	cmd := exec.CommandContext(ctx, p.command, args...)
+	cmd.Stdin = bytes.NewReader(image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// GoogleVisionOCR recognizes text with the Google Cloud Vision API
+type GoogleVisionOCR struct {
+	client    *http.Client
+	apiKey    string
+	languages []string
+}
+
+type visionRequest struct {
+	Requests []visionImageRequest `json:"requests"`
+}
+
+type visionImageRequest struct {
+	Image        visionImage         `json:"image"`
+	ImageContext *visionImageContext `json:"imageContext,omitempty"`
+	Features     []visionFeature     `json:"features"`
+}
+
+type visionImage struct {
+	Content string `json:"content"`
+}
+
+type visionFeature struct {
+	Type string `json:"type"`
+}
+
+type visionImageContext struct {
+	LanguageHints []string `json:"languageHints"`
+}
+
+type visionResponse struct {
+	Responses []struct {
+		FullTextAnnotation *struct {
+			Text string `json:"text"`
+		} `json:"fullTextAnnotation"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"responses"`
+}
+
+// Recognize sends the image to the Vision API for document text detection
+func (p *GoogleVisionOCR) Recognize(ctx context.Context, image []byte) (string, error) {
+	imageReq := visionImageRequest{
+		Image:    visionImage{Content: base64.StdEncoding.EncodeToString(image)},
+		Features: []visionFeature{{Type: "DOCUMENT_TEXT_DETECTION"}},
+	}
+	if len(p.languages) > 0 {
+		imageReq.ImageContext = &visionImageContext{LanguageHints: p.languages}
+	}
+
+	body, err := json.Marshal(visionRequest{Requests: []visionImageRequest{imageReq}})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal vision request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleVisionURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call vision API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, ocrMaxErrorBody))
+		return "", fmt.Errorf("vision API responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var result visionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode vision response: %w", err)
+	}
+	if len(result.Responses) == 0 {
+		return "", nil
+	}
+	if result.Responses[0].Error != nil {
+		return "", fmt.Errorf("vision API failed: %s", result.Responses[0].Error.Message)
+	}
+	if result.Responses[0].FullTextAnnotation == nil {
+		return "", nil
+	}
+
+	return result.Responses[0].FullTextAnnotation.Text, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	onlineUserKey = "online_user:%s"
+	// onlineUserTTL is how long a connection counts as online without being
+	// refreshed, longer than the presence sweep that refreshes it
+	onlineUserTTL = 3 * presenceSweepInterval
+	// onlineUserTimeout bounds the Redis calls of the hub
+	onlineUserTimeout = 2 * time.Second
+)
+
+// OnlineUsers tracks which users have a realtime connection on any server
+// instance. Every user has a sorted set of client IDs scored by the time the
+// connection stops counting, so crashed instances don't leave users online.
+type OnlineUsers struct {
+	redis *redis.Client
+}
+
+// NewOnlineUsers creates a new online user tracker
+func NewOnlineUsers(redisClient *redis.Client) *OnlineUsers {
+	return &OnlineUsers{redis: redisClient}
+}
+
+// Touch marks connections of a user as online for onlineUserTTL
+func (o *OnlineUsers) Touch(ctx context.Context, userID uuid.UUID, clientIDs ...uuid.UUID) error {
+	key := fmt.Sprintf(onlineUserKey, userID)
+	expiresAt := float64(time.Now().Add(onlineUserTTL).Unix())
+
+	members := make([]redis.Z, len(clientIDs))
+	for i, clientID := range clientIDs {
+		members[i] = redis.Z{Score: expiresAt, Member: clientID.String()}
+	}
+
+	pipe := o.redis.TxPipeline()
+	pipe.ZAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, onlineUserTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to mark user online: %w", err)
+	}
+	return nil
+}
+
+// Remove marks a connection of a user as closed
+func (o *OnlineUsers) Remove(ctx context.Context, userID, clientID uuid.UUID) error {
+	if err := o.redis.ZRem(ctx, fmt.Sprintf(onlineUserKey, userID), clientID.String()).Err(); err != nil {
+		return fmt.Errorf("failed to mark user offline: %w", err)
+	}
+	return nil
+}
+
+// IsOnline reports whether a user has a connection that hasn't expired
+func (o *OnlineUsers) IsOnline(ctx context.Context, userID uuid.UUID) (bool, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	count, err := o.redis.ZCount(ctx, fmt.Sprintf(onlineUserKey, userID), now, "+inf").Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check if user is online: %w", err)
+	}
+	return count > 0, nil
+}
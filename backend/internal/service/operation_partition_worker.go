@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const operationPartitionTimeout = 10 * time.Minute
+
+// OperationPartitionWorker maintains the monthly partitions of the operation
+// log. It creates the partitions of the coming months before operations
+// need them and drops the operations older than the retention.
+type OperationPartitionWorker struct {
+	operationRepo   *repository.OperationRepository
+	done            chan struct{}
+	trigger         chan struct{}
+	interval        time.Duration
+	partitionsAhead int
+	retention       time.Duration
+}
+
+// NewOperationPartitionWorker creates and starts a new operation partition
+// worker. A zero retention keeps operations forever.
+func NewOperationPartitionWorker(
+	operationRepo *repository.OperationRepository,
+	interval time.Duration,
+	partitionsAhead int,
+	retention time.Duration,
+) (*OperationPartitionWorker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	if partitionsAhead < 0 {
+		return nil, fmt.Errorf("partitions ahead can't be negative")
+	}
+	if retention < 0 {
+		return nil, fmt.Errorf("retention can't be negative")
+	}
+
+	worker := &OperationPartitionWorker{
+		operationRepo:   operationRepo,
+		done:            make(chan struct{}),
+		trigger:         make(chan struct{}, 1),
+		interval:        interval,
+		partitionsAhead: partitionsAhead,
+		retention:       retention,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the partition worker
+func (w *OperationPartitionWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger maintains the partitions now instead of at the next tick. It
+// returns false if a run is already pending.
+func (w *OperationPartitionWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// run maintains the partitions right away, so a new month never starts
+// without its partition, then on every tick until the worker is closed
+func (w *OperationPartitionWorker) run() {
+	w.maintain()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.maintain()
+		case <-w.trigger:
+			w.maintain()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *OperationPartitionWorker) maintain() {
+	ctx, cancel := context.WithTimeout(context.Background(), operationPartitionTimeout)
+	defer cancel()
+
+	created, err := w.operationRepo.EnsurePartitions(ctx, w.partitionsAhead)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to create operation partitions: %v", err)
+	}
+	for _, partition := range created {
+		hlog.CtxInfof(ctx, "Created operation partition %s", partition)
+	}
+
+	if w.retention == 0 {
+		return
+	}
+
+	dropped, deleted, err := w.operationRepo.DeleteOldOperations(ctx, w.retention)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to delete expired operations: %v", err)
+	}
+	for _, partition := range dropped {
+		hlog.CtxInfof(ctx, "Dropped expired operation partition %s", partition)
+	}
+	if deleted > 0 {
+		hlog.CtxInfof(ctx, "Deleted %d expired operations", deleted)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// MaxReportOperations is how many operations a report can list, longer
+// ranges have to be split
+const MaxReportOperations = 200000
+
+// errReportTooLarge fails reports of ranges with too many operations
+var errReportTooLarge = errors.New("too many operations in the range")
+
+// operationReportHeader is the header row of CSV reports
+var operationReportHeader = []string{
+	"time", "user_id", "user_name", "user_email", "element_id", "element_type", "op_type", "lamport_timestamp",
+}
+
+// CreateOperationReport queues a report of the operations of a board created
+// in [from, to), for compliance reviews. A zero from reports the whole
+// history.
+func (s *ExportService) CreateOperationReport(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	format string,
+	from, to time.Time,
+) (*models.BoardExport, error) {
+	if format != models.ExportFormatOperationsCSV && format != models.ExportFormatOperationsJSON {
+		return nil, fmt.Errorf("format must be csv or json")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	export := &models.BoardExport{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		RequestedBy: &userID,
+		Format:      format,
+		Status:      models.ExportStatusPending,
+	}
+	if !from.IsZero() {
+		rangeFrom := from.UTC()
+		export.RangeFrom = &rangeFrom
+	}
+	rangeTo := to.UTC()
+	export.RangeTo = &rangeTo
+
+	if err := s.queue(ctx, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// GetOperationReport returns an operation report of a board, with its
+// download link once completed
+func (s *ExportService) GetOperationReport(ctx context.Context, workspaceID, id uuid.UUID) (*models.BoardExport, error) {
+	return s.getExport(ctx, workspaceID, id, reportFormats)
+}
+
+// ListOperationReports returns the latest operation reports of a board
+func (s *ExportService) ListOperationReports(ctx context.Context, workspaceID uuid.UUID) ([]models.BoardExport, error) {
+	return s.exportRepo.ListExports(ctx, workspaceID, reportFormats, maxListedExports)
+}
+
+// DeleteOperationReport deletes an operation report and its file
+func (s *ExportService) DeleteOperationReport(ctx context.Context, workspaceID, id uuid.UUID) error {
+	return s.deleteExport(ctx, workspaceID, id, reportFormats)
+}
+
+// renderReport writes the report of a queued operation report export and
+// stores it in the exports bucket
+func (s *ExportService) renderReport(ctx context.Context, export *models.BoardExport) error {
+	var from time.Time
+	if export.RangeFrom != nil {
+		from = *export.RangeFrom
+	}
+	to := time.Now().UTC()
+	if export.RangeTo != nil {
+		to = *export.RangeTo
+	}
+
+	var buf bytes.Buffer
+	err := s.writeOperationReport(ctx, &buf, export, from, to)
+	if errors.Is(err, errReportTooLarge) {
+		s.fail(ctx, export.ID, fmt.Sprintf(
+			"the range has more than %d operations, report shorter ranges", MaxReportOperations,
+		))
+		return err
+	}
+	if err != nil {
+		s.fail(ctx, export.ID, "failed to list the operations")
+		return err
+	}
+
+	fileType := exportFileTypes[export.Format]
+	key := fmt.Sprintf("%s/operations/%s%s", export.WorkspaceID, export.ID, fileType.extension)
+	size := int64(buf.Len())
+	if err := s.reports.Put(ctx, key, &buf, size, fileType.contentType); err != nil {
+		s.fail(ctx, export.ID, "failed to store the report")
+		return fmt.Errorf("failed to store operation report: %w", err)
+	}
+
+	// Reports are no board documents, they aren't metered as exports
+	return s.exportRepo.MarkCompleted(ctx, export.ID, key, size)
+}
+
+// writeOperationReport writes the operations of [from, to) in the format of
+// the export
+func (s *ExportService) writeOperationReport(
+	ctx context.Context,
+	buf *bytes.Buffer,
+	export *models.BoardExport,
+	from, to time.Time,
+) error {
+	var rows []models.OperationReportRow
+	var w *csv.Writer
+	if export.Format == models.ExportFormatOperationsCSV {
+		w = csv.NewWriter(buf)
+		if err := w.Write(operationReportHeader); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	count := 0
+	err := s.operationRepo.ForEachInReport(ctx, export.WorkspaceID, from, to, MaxReportOperations+1,
+		func(row *models.OperationReportRow) error {
+			count++
+			if count > MaxReportOperations {
+				return errReportTooLarge
+			}
+			if w == nil {
+				rows = append(rows, *row)
+				return nil
+			}
+			return w.Write([]string{
+				row.Time.UTC().Format(time.RFC3339Nano),
+				row.UserID.String(),
+				csvSafe(row.UserName),
+				csvSafe(row.UserEmail),
+				row.ElementID.String(),
+				row.ElementType,
+				row.OpType,
+				strconv.FormatInt(row.Timestamp, 10),
+			})
+		})
+	if err != nil {
+		return err
+	}
+
+	if w != nil {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return nil
+	}
+
+	if rows == nil {
+		rows = []models.OperationReportRow{}
+	}
+	report := &models.OperationReport{
+		GeneratedAt: time.Now().UTC(),
+		From:        export.RangeFrom,
+		To:          to,
+		Operations:  rows,
+		WorkspaceID: export.WorkspaceID,
+	}
+	if err := json.NewEncoder(buf).Encode(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// csvSafe keeps user supplied values from being evaluated as formulas when
+// a report is opened in a spreadsheet
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
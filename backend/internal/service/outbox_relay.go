@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	outboxPublishTimeout  = 30 * time.Second
+	outboxFlushTimeout    = 5 * time.Second
+	outboxCleanupInterval = time.Hour
+)
+
+// newOutboxMessage builds an outbox message that carries the trace of ctx.
+// jetstream messages are acked by the stream, the others go to core NATS.
+func newOutboxMessage(ctx context.Context, subject string, payload []byte, jetstream bool) *models.OutboxMessage {
+	msg := tracing.NewMsg(ctx, subject, payload)
+	return &models.OutboxMessage{
+		ID:        uuid.New(),
+		Subject:   subject,
+		Payload:   payload,
+		Headers:   msg.Header,
+		JetStream: jetstream,
+	}
+}
+
+// OutboxRelayStats is a snapshot of the relay counters
+type OutboxRelayStats struct {
+	Published uint64        // messages published since start
+	Failed    uint64        // publish attempts that failed since start
+	Pending   int64         // unpublished messages at the last poll
+	Lag       time.Duration // age of the oldest unpublished message at the last poll
+}
+
+// OutboxRelay publishes the messages of the transactional outbox to NATS.
+// It polls for committed messages, publishes them in order and marks them
+// published, so a message is delivered at least once even when NATS or the
+// relay goes down in between. JetStream messages carry their outbox ID as
+// Nats-Msg-Id, so the stream drops the duplicates of a retry.
+type OutboxRelay struct {
+	outbox    *repository.OutboxRepository
+	nats      *nats.Conn
+	js        nats.JetStreamContext
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+	done      chan struct{}
+	stopped   chan struct{}
+
+	published atomic.Uint64
+	failed    atomic.Uint64
+	pending   atomic.Int64
+	lag       atomic.Int64
+}
+
+// NewOutboxRelay creates and starts a new outbox relay. It polls every
+// interval and deletes published messages after retention.
+func NewOutboxRelay(
+	outbox *repository.OutboxRepository,
+	nc *nats.Conn,
+	interval time.Duration,
+	batchSize int,
+	retention time.Duration,
+) (*OutboxRelay, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+	if retention <= 0 {
+		return nil, fmt.Errorf("retention must be positive")
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	relay := &OutboxRelay{
+		outbox:    outbox,
+		nats:      nc,
+		js:        js,
+		interval:  interval,
+		batchSize: batchSize,
+		retention: retention,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	go relay.run()
+	return relay, nil
+}
+
+// Close stops the relay after the batch in flight
+func (r *OutboxRelay) Close() error {
+	close(r.done)
+	<-r.stopped
+	return nil
+}
+
+// Stats returns the relay counters
+func (r *OutboxRelay) Stats() OutboxRelayStats {
+	return OutboxRelayStats{
+		Published: r.published.Load(),
+		Failed:    r.failed.Load(),
+		Pending:   r.pending.Load(),
+		Lag:       time.Duration(r.lag.Load()),
+	}
+}
+
+// run relays messages on every tick until the relay is closed
+func (r *OutboxRelay) run() {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		select {
+		case <-ticker.C:
+			r.relay()
+			if time.Since(lastCleanup) >= outboxCleanupInterval {
+				r.cleanup()
+				lastCleanup = time.Now()
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// relay publishes batches until the outbox is drained or publishing fails
+func (r *OutboxRelay) relay() {
+	for {
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), outboxPublishTimeout)
+		published, err := r.outbox.PublishPending(ctx, r.batchSize, func(messages []models.OutboxMessage) (int, error) {
+			return r.publish(ctx, messages)
+		})
+		r.published.Add(uint64(published)) //nolint:gosec // never negative
+		if err != nil {
+			r.failed.Add(1)
+			hlog.CtxErrorf(ctx, "Failed to relay outbox messages: %v", err)
+		}
+		if err != nil || published < r.batchSize {
+			r.updateBacklog(ctx)
+			cancel()
+			return
+		}
+		cancel()
+	}
+}
+
+// publish publishes messages in order and returns how many were delivered.
+// Core NATS publishes are buffered, they count once the flush succeeds.
+func (r *OutboxRelay) publish(ctx context.Context, messages []models.OutboxMessage) (int, error) {
+	published := len(messages)
+	var publishErr error
+	for i := range messages {
+		msg := &nats.Msg{
+			Subject: messages[i].Subject,
+			Data:    messages[i].Payload,
+			Header:  nats.Header(messages[i].Headers),
+		}
+		if msg.Header == nil {
+			msg.Header = nats.Header{}
+		}
+
+		var err error
+		if messages[i].JetStream {
+			msg.Header.Set(nats.MsgIdHdr, messages[i].ID.String())
+			_, err = r.js.PublishMsg(msg, nats.Context(ctx))
+		} else {
+			err = r.nats.PublishMsg(msg)
+		}
+		if err != nil {
+			published = i
+			publishErr = fmt.Errorf("failed to publish %s message %s: %w", messages[i].Subject, messages[i].ID, err)
+			break
+		}
+	}
+
+	// Nothing is known to be delivered when the flush fails, the whole batch
+	// is published again
+	if err := r.nats.FlushTimeout(outboxFlushTimeout); err != nil {
+		return 0, fmt.Errorf("failed to flush NATS connection: %w", err)
+	}
+
+	return published, publishErr
+}
+
+func (r *OutboxRelay) updateBacklog(ctx context.Context) {
+	pending, lag, err := r.outbox.CountPending(ctx)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to count outbox messages: %v", err)
+		return
+	}
+
+	r.pending.Store(pending)
+	r.lag.Store(int64(lag))
+}
+
+func (r *OutboxRelay) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxPublishTimeout)
+	defer cancel()
+
+	deleted, err := r.outbox.DeletePublishedOlderThan(ctx, r.retention)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to clean up the outbox: %v", err)
+		return
+	}
+
+	if deleted > 0 {
+		hlog.CtxInfof(ctx, "Deleted %d published outbox messages", deleted)
+	}
+}
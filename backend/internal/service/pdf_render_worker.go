@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	// MaxPDFPages limits how many pages of a document are rendered
+	MaxPDFPages = 50
+
+	pdfRenderDPI     = 110
+	pdfRenderTimeout = 2 * time.Minute
+	pdfPagePrefix    = "page"
+	pdfPageType      = "image/png"
+)
+
+// PDFRenderWorker renders uploaded PDFs into one image asset per page
+type PDFRenderWorker struct {
+	assetService *AssetService
+	nats         *nats.Conn
+	sub          *nats.Subscription
+}
+
+// NewPDFRenderWorker creates a new PDF render worker
+func NewPDFRenderWorker(nc *nats.Conn, assetService *AssetService) (*PDFRenderWorker, error) {
+	worker := &PDFRenderWorker{
+		assetService: assetService,
+		nats:         nc,
+	}
+
+	// Subscribe to render queue
+	sub, err := nc.QueueSubscribe(PDFRenderSubject, "pdf-renderers", worker.handleMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to pdf render queue: %w", err)
+	}
+
+	worker.sub = sub
+	return worker, nil
+}
+
+// Close closes the render worker subscription
+func (w *PDFRenderWorker) Close() error {
+	if w.sub != nil {
+		return w.sub.Unsubscribe()
+	}
+	return nil
+}
+
+// handleMessage processes a render job
+func (w *PDFRenderWorker) handleMessage(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "asset.pdf_render")
+	defer span.End()
+
+	var job models.PDFRenderJob
+	if err := json.Unmarshal(msg.Data, &job); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal pdf render job: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pdfRenderTimeout)
+	defer cancel()
+
+	pageCount, err := w.render(ctx, &job)
+	if err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to render pdf %s: %v", job.AssetID, err)
+		_ = w.assetService.assetRepo.UpdateAssetStatus(context.Background(), job.AssetID, models.AssetStatusFailed, nil)
+		return
+	}
+
+	if err := w.assetService.assetRepo.UpdateAssetStatus(ctx, job.AssetID, models.AssetStatusReady, &pageCount); err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to update pdf %s status: %v", job.AssetID, err)
+		return
+	}
+
+	hlog.CtxInfof(ctx, "Rendered %d pages for pdf %s", pageCount, job.AssetID)
+}
+
+// render rasterizes the document and stores every page as a child asset
+func (w *PDFRenderWorker) render(ctx context.Context, job *models.PDFRenderJob) (int, error) {
+	parent, err := w.assetService.assetRepo.GetAssetByID(ctx, job.AssetID)
+	if err != nil {
+		return 0, fmt.Errorf("asset not found: %w", err)
+	}
+
+	data, err := w.assetService.readObject(ctx, job.ObjectKey)
+	if err != nil {
+		return 0, err
+	}
+
+	dir, err := os.MkdirTemp("", "pdf-render-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pages, err := rasterizePDF(ctx, dir, data)
+	if err != nil {
+		return 0, err
+	}
+	if len(pages) == 0 {
+		return 0, fmt.Errorf("document has no pages")
+	}
+
+	baseName := strings.TrimSuffix(parent.Filename, filepath.Ext(parent.Filename))
+	for i, pagePath := range pages {
+		if err := w.storePage(ctx, parent, baseName, pagePath, i+1); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(pages), nil
+}
+
+// storePage uploads a rendered page and records it as a child of the document
+func (w *PDFRenderWorker) storePage(
+	ctx context.Context,
+	parent *models.Asset,
+	baseName, pagePath string,
+	pageNumber int,
+) error {
+	pageData, err := os.ReadFile(pagePath) //nolint:gosec // path is built from our own temp dir
+	if err != nil {
+		return fmt.Errorf("failed to read rendered page: %w", err)
+	}
+
+	const ext = ".png"
+	objectName := newObjectName(parent.WorkspaceID, ext)
+	size := int64(len(pageData))
+
+	width, height, thumbnailURL, err := w.assetService.processImage(ctx, pageData, pdfPageType, true, ext, parent.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	if err := w.assetService.uploadFile(ctx, objectName, pageData, size, pdfPageType); err != nil {
+		return err
+	}
+
+	page := &models.Asset{
+		ID:            uuid.New(),
+		WorkspaceID:   parent.WorkspaceID,
+		UploadedBy:    parent.UploadedBy,
+		Filename:      fmt.Sprintf("%s - page %d.png", baseName, pageNumber),
+		ContentType:   pdfPageType,
+		Size:          size,
+		URL:           w.assetService.getObjectURL(objectName),
+		ThumbnailURL:  thumbnailURL,
+		Width:         width,
+		Height:        height,
+		ParentAssetID: &parent.ID,
+		PageNumber:    &pageNumber,
+		Status:        models.AssetStatusReady,
+		ScanStatus:    parent.ScanStatus,
+	}
+
+	if err := w.assetService.assetRepo.CreateAsset(ctx, page); err != nil {
+		w.assetService.cleanupUploadedFiles(ctx, objectName, thumbnailURL)
+		return fmt.Errorf("failed to create page asset: %w", err)
+	}
+
+	return nil
+}
+
+// rasterizePDF renders the document with pdftoppm and returns the page
+// images in page order
+func rasterizePDF(ctx context.Context, dir string, data []byte) ([]string, error) {
+	input := filepath.Join(dir, "document.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write document: %w", err)
+	}
+
+	//nolint:gosec // arguments are fixed, the input file lives in our temp dir
+	cmd := exec.CommandContext(ctx, "pdftoppm",
+		"-png",
+		"-r", strconv.Itoa(pdfRenderDPI),
+		"-l", strconv.Itoa(MaxPDFPages),
+		input,
+		filepath.Join(dir, pdfPagePrefix),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to render document: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, pdfPagePrefix+"-*.png"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rendered pages: %w", err)
+	}
+
+	// pdftoppm zero-pads page numbers, but only to the width of the last page
+	sort.Slice(pages, func(i, j int) bool {
+		return pageIndex(pages[i]) < pageIndex(pages[j])
+	})
+
+	return pages, nil
+}
+
+// pageIndex extracts the page number from a pdftoppm output file name
+func pageIndex(path string) int {
+	name := strings.TrimSuffix(filepath.Base(path), ".png")
+	n, _ := strconv.Atoi(strings.TrimPrefix(name, pdfPagePrefix+"-"))
+	return n
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+// maxPresentationSlides is how many frames a presentation can show
+const maxPresentationSlides = 200
+
+var (
+	// ErrPresentationNotRunning is returned when changing a session while
+	// none runs
+	ErrPresentationNotRunning = errors.New("no presentation is running")
+	// ErrPresentationRunning is returned when starting a session while one
+	// runs
+	ErrPresentationRunning = errors.New("a presentation is already running")
+	// ErrNotPresenter is returned to users that may not drive the session
+	ErrNotPresenter = errors.New("only the presenter can do this")
+)
+
+// PresentationService manages the slide order of boards and the sessions
+// presenting them. The presenter shows slides over WebSocket, followers get
+// every change broadcast and fetch the state again when they reconnect.
+type PresentationService struct {
+	presentationRepo *repository.PresentationRepository
+	workspaceRepo    *repository.WorkspaceRepository
+	rooms            RoomBroadcaster
+}
+
+// NewPresentationService creates a new presentation service
+func NewPresentationService(
+	presentationRepo *repository.PresentationRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	rooms RoomBroadcaster,
+) *PresentationService {
+	return &PresentationService{
+		presentationRepo: presentationRepo,
+		workspaceRepo:    workspaceRepo,
+		rooms:            rooms,
+	}
+}
+
+// Get returns the presentation of a workspace with the slide shown, empty
+// if it has no slides yet
+func (s *PresentationService) Get(ctx context.Context, workspaceID uuid.UUID) (*models.Presentation, error) {
+	p, err := s.presentationRepo.GetPresentation(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return &models.Presentation{WorkspaceID: workspaceID, SlideIDs: []uuid.UUID{}}, nil
+	}
+	return withCurrentSlide(p), nil
+}
+
+// SetSlides replaces the slide order of a workspace. Slides are frames of
+// the workspace, each shown once.
+func (s *PresentationService) SetSlides(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	req *models.SetSlidesRequest,
+) (*models.Presentation, error) {
+	slideIDs := req.SlideIDs
+	if slideIDs == nil {
+		slideIDs = []uuid.UUID{}
+	}
+	if len(slideIDs) > maxPresentationSlides {
+		return nil, fmt.Errorf("a presentation can have at most %d slides", maxPresentationSlides)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(slideIDs))
+	for _, id := range slideIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("slide %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+
+	if len(slideIDs) > 0 {
+		frames, err := s.presentationRepo.CountFrames(ctx, workspaceID, slideIDs)
+		if err != nil {
+			return nil, err
+		}
+		if frames != len(slideIDs) {
+			return nil, fmt.Errorf("slides must be frames of the workspace")
+		}
+	}
+
+	p, err := s.presentationRepo.SetSlides(ctx, workspaceID, slideIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.broadcast(workspaceID, models.PresentationActionSlidesUpdated, p), nil
+}
+
+// Start starts a session on a slide, presented by the user or the member
+// the request designates
+func (s *PresentationService) Start(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.StartPresentationRequest,
+) (*models.Presentation, error) {
+	presenterID := userID
+	if req.PresenterID != nil {
+		presenterID = *req.PresenterID
+		if err := s.requireMember(ctx, workspaceID, presenterID); err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Running() {
+		return nil, ErrPresentationRunning
+	}
+	if len(current.SlideIDs) == 0 {
+		return nil, fmt.Errorf("the presentation has no slides")
+	}
+	if req.Slide < 0 || req.Slide >= len(current.SlideIDs) {
+		return nil, fmt.Errorf("slide must be between 0 and %d", len(current.SlideIDs)-1)
+	}
+
+	p, err := s.presentationRepo.StartPresentation(ctx, workspaceID, presenterID, req.Slide)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		// Started, or the slides changed, since they were read
+		return nil, ErrPresentationRunning
+	}
+	return s.broadcast(workspaceID, models.PresentationActionStarted, p), nil
+}
+
+// Stop ends the running session. The presenter and editors can stop it.
+func (s *PresentationService) Stop(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Presentation, error) {
+	if err := s.requireController(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	p, err := s.presentationRepo.StopPresentation(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPresentationNotRunning
+	}
+	return s.broadcast(workspaceID, models.PresentationActionStopped, p), nil
+}
+
+// ChangePresenter hands the running session to another member. The
+// presenter and editors can hand it over.
+func (s *PresentationService) ChangePresenter(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.ChangePresenterRequest,
+) (*models.Presentation, error) {
+	if err := s.requireController(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, workspaceID, req.PresenterID); err != nil {
+		return nil, err
+	}
+
+	p, err := s.presentationRepo.SetPresenter(ctx, workspaceID, req.PresenterID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPresentationNotRunning
+	}
+	return s.broadcast(workspaceID, models.PresentationActionPresenterChanged, p), nil
+}
+
+// GoTo shows another slide of the session the user presents
+func (s *PresentationService) GoTo(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	slide int,
+) (*models.Presentation, error) {
+	if slide < 0 {
+		return nil, fmt.Errorf("slide must not be negative")
+	}
+
+	p, err := s.presentationRepo.SetCurrentSlide(ctx, workspaceID, userID, slide)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		current, getErr := s.Get(ctx, workspaceID)
+		switch {
+		case getErr != nil:
+			return nil, getErr
+		case !current.Running():
+			return nil, ErrPresentationNotRunning
+		case *current.PresenterID != userID:
+			return nil, ErrNotPresenter
+		default:
+			return nil, fmt.Errorf("slide must be between 0 and %d", len(current.SlideIDs)-1)
+		}
+	}
+	return s.broadcast(workspaceID, models.PresentationActionSlideChanged, p), nil
+}
+
+// requireController returns nil if the user presents the running session
+// or may edit the workspace
+func (s *PresentationService) requireController(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	current, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !current.Running() {
+		return ErrPresentationNotRunning
+	}
+	if *current.PresenterID == userID {
+		return nil
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get member: %w", err)
+	}
+	if member == nil || !hasPermission(member.Role, models.WorkspaceRoleEditor) {
+		return ErrNotPresenter
+	}
+	return nil
+}
+
+// requireMember returns an error unless the user is a member of the
+// workspace, as presenters must be
+func (s *PresentationService) requireMember(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get member: %w", err)
+	}
+	if member == nil {
+		return fmt.Errorf("the presenter must be a member of the workspace")
+	}
+	return nil
+}
+
+func (s *PresentationService) broadcast(
+	workspaceID uuid.UUID,
+	action string,
+	p *models.Presentation,
+) *models.Presentation {
+	p = withCurrentSlide(p)
+	if s.rooms != nil {
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypePresentationEvent,
+			Timestamp: time.Now(),
+			Payload:   models.PresentationEventPayload{Action: action, Presentation: *p},
+		}, uuid.Nil)
+	}
+	return p
+}
+
+// withCurrentSlide sets the frame shown by a running session
+func withCurrentSlide(p *models.Presentation) *models.Presentation {
+	p.CurrentSlideID = nil
+	if p.Running() && p.CurrentSlide < len(p.SlideIDs) {
+		p.CurrentSlideID = &p.SlideIDs[p.CurrentSlide]
+	}
+	return p
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKey = "rate_limit:%s:%d"
+
+// slidingWindowScript counts a request in the current window unless the
+// estimate of the sliding window is at the limit. The estimate weighs the
+// previous window by how much of it still overlaps the sliding window.
+// KEYS: current window, previous window. ARGV: weight, limit, window in ms.
+var slidingWindowScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local count = math.floor(previous * tonumber(ARGV[1])) + current
+if count >= tonumber(ARGV[2]) then
+	return {0, count}
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], tonumber(ARGV[3]) * 2)
+return {1, count + 1}
+`)
+
+// RateLimitResult is the state of a limit after a request was checked
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration // until the current window ends
+}
+
+// RateLimiter limits requests with a sliding window counter in Redis, so the
+// limits are shared by every instance
+type RateLimiter struct {
+	redis *redis.Client
+}
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(redisClient *redis.Client) *RateLimiter {
+	return &RateLimiter{redis: redisClient}
+}
+
+// Allow counts a request of subject, e.g. a route and client address, against
+// limit requests per window
+func (l *RateLimiter) Allow(ctx context.Context, subject string, limit int, window time.Duration) (*RateLimitResult, error) {
+	now := time.Now()
+	start := now.Truncate(window)
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+
+	keys := []string{
+		fmt.Sprintf(rateLimitKey, subject, start.Unix()),
+		fmt.Sprintf(rateLimitKey, subject, start.Add(-window).Unix()),
+	}
+	values, err := slidingWindowScript.Run(ctx, l.redis, keys,
+		strconv.FormatFloat(weight, 'f', 4, 64), limit, window.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	result := &RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: max(limit-int(values[1]), 0),
+		Reset:     window - elapsed,
+	}
+	return result, nil
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	recordingContentType = "application/gzip"
+	// recordingReadBatch is how many buffered events are read at a time
+	recordingReadBatch  = 1000
+	maxRecordingNameLen = 255
+
+	// MaxPlaybackSpeed is how much faster than recorded a session can be
+	// played back
+	MaxPlaybackSpeed = 32
+)
+
+var (
+	// ErrRecordingNotFound is returned for recordings the workspace doesn't have
+	ErrRecordingNotFound = errors.New("recording not found")
+	// ErrRecordingRunning is returned when starting a recording while one runs
+	ErrRecordingRunning = errors.New("a session is already being recorded")
+	// ErrRecordingNotRunning is returned when stopping a finished recording
+	ErrRecordingNotRunning = errors.New("the recording was already stopped")
+	// ErrRecordingNotReady is returned when playing a recording back before
+	// it was stopped
+	ErrRecordingNotReady = errors.New("the recording is still running")
+)
+
+// RecordingService records the operations and presence of board sessions
+// and plays them back. While a session is recorded SessionRecorder buffers
+// its events in Redis, stopping stores them compressed in the backup bucket.
+type RecordingService struct {
+	recordingRepo *repository.RecordingRepository
+	redis         *redis.Client
+	storage       ObjectStorage
+}
+
+// NewRecordingService creates a new recording service
+func NewRecordingService(
+	recordingRepo *repository.RecordingRepository,
+	redisClient *redis.Client,
+	storage ObjectStorage,
+) *RecordingService {
+	return &RecordingService{
+		recordingRepo: recordingRepo,
+		redis:         redisClient,
+		storage:       storage,
+	}
+}
+
+// recordingObjectKey builds the object key of the events of a recording
+func recordingObjectKey(workspaceID, recordingID uuid.UUID) string {
+	return fmt.Sprintf("recordings/%s/%s.ndjson.gz", workspaceID, recordingID)
+}
+
+// Start starts recording a session of a workspace. A recording left running
+// past MaxRecordingDuration is stopped first.
+func (s *RecordingService) Start(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.StartRecordingRequest,
+) (*models.SessionRecording, error) {
+	name := strings.TrimSpace(req.Name)
+	if len(name) > maxRecordingNameLen {
+		return nil, fmt.Errorf("name must be at most %d characters", maxRecordingNameLen)
+	}
+
+	running, err := s.recordingRepo.GetRunningRecording(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if running != nil {
+		if time.Since(running.StartedAt) < MaxRecordingDuration {
+			return nil, ErrRecordingRunning
+		}
+		if _, err := s.finish(ctx, running); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	if name == "" {
+		name = "Session " + now.Format("2006-01-02 15:04")
+	}
+	rec := &models.SessionRecording{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		StartedBy:   userID,
+		Name:        name,
+		Status:      models.RecordingStatusRecording,
+		StartedAt:   now,
+	}
+
+	created, err := s.recordingRepo.CreateRecording(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrRecordingRunning
+	}
+
+	// Capture stops by itself once the recording ran for too long
+	key := fmt.Sprintf(recordingActiveKey, workspaceID)
+	if err := s.redis.Set(ctx, key, rec.ID.String(), MaxRecordingDuration).Err(); err != nil {
+		return nil, fmt.Errorf("failed to start recording: %w", err)
+	}
+	return rec, nil
+}
+
+// Stop stops a running recording and stores its events
+func (s *RecordingService) Stop(ctx context.Context, workspaceID, recordingID uuid.UUID) (*models.SessionRecording, error) {
+	rec, err := s.Get(ctx, workspaceID, recordingID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != models.RecordingStatusRecording {
+		return nil, ErrRecordingNotRunning
+	}
+	return s.finish(ctx, rec)
+}
+
+// Get returns a recording of a workspace
+func (s *RecordingService) Get(ctx context.Context, workspaceID, recordingID uuid.UUID) (*models.SessionRecording, error) {
+	rec, err := s.recordingRepo.GetRecording(ctx, workspaceID, recordingID)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrRecordingNotFound
+	}
+	return rec, nil
+}
+
+// List returns the recordings of a workspace, latest first
+func (s *RecordingService) List(ctx context.Context, workspaceID uuid.UUID) ([]models.SessionRecording, error) {
+	return s.recordingRepo.ListRecordings(ctx, workspaceID)
+}
+
+// Play emits the events of a recording in order, waiting between them as
+// long as the session did, divided by speed. It returns when all events
+// were emitted, emit fails or ctx is done.
+func (s *RecordingService) Play(
+	ctx context.Context,
+	workspaceID, recordingID uuid.UUID,
+	speed float64,
+	emit func(*models.RecordedEvent) error,
+) error {
+	if speed < 1 || speed > MaxPlaybackSpeed {
+		return fmt.Errorf("speed must be between 1 and %d", MaxPlaybackSpeed)
+	}
+
+	rec, err := s.Get(ctx, workspaceID, recordingID)
+	if err != nil {
+		return err
+	}
+	if rec.Status != models.RecordingStatusReady || rec.StorageKey == nil {
+		return ErrRecordingNotReady
+	}
+
+	object, err := s.storage.Get(ctx, *rec.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to get recording: %w", err)
+	}
+	defer object.Close()
+
+	gz, err := gzip.NewReader(object)
+	if err != nil {
+		return fmt.Errorf("failed to decompress recording: %w", err)
+	}
+	defer gz.Close()
+
+	previous := rec.StartedAt
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	for decoder.More() {
+		var event models.RecordedEvent
+		if err := decoder.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode recorded event: %w", err)
+		}
+
+		if gap := event.At.Sub(previous); gap > 0 {
+			timer := time.NewTimer(time.Duration(float64(gap) / speed))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+			previous = event.At
+		}
+
+		if err := emit(&event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish ends capturing a recording, stores its buffered events and marks
+// it ready. The buffered events are kept if storing fails, so stopping can
+// be retried.
+func (s *RecordingService) finish(ctx context.Context, rec *models.SessionRecording) (*models.SessionRecording, error) {
+	activeKey := fmt.Sprintf(recordingActiveKey, rec.WorkspaceID)
+	if active, err := s.redis.Get(ctx, activeKey).Result(); err == nil && active == rec.ID.String() {
+		if err := s.redis.Del(ctx, activeKey).Err(); err != nil {
+			return nil, fmt.Errorf("failed to stop recording: %w", err)
+		}
+	} else if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to stop recording: %w", err)
+	}
+
+	eventsKey := fmt.Sprintf(recordingEventsKey, rec.ID)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	count := 0
+	for start := int64(0); ; start += recordingReadBatch {
+		events, err := s.redis.LRange(ctx, eventsKey, start, start+recordingReadBatch-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recorded events: %w", err)
+		}
+		for _, event := range events {
+			if _, err := gz.Write([]byte(event + "\n")); err != nil {
+				return nil, fmt.Errorf("failed to compress recording: %w", err)
+			}
+		}
+		count += len(events)
+		if len(events) < recordingReadBatch {
+			break
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress recording: %w", err)
+	}
+
+	key := recordingObjectKey(rec.WorkspaceID, rec.ID)
+	size := int64(buf.Len())
+	if err := s.storage.Put(ctx, key, &buf, size, recordingContentType); err != nil {
+		return nil, fmt.Errorf("failed to store recording: %w", err)
+	}
+
+	stoppedAt := time.Now().UTC()
+	if limit := rec.StartedAt.Add(MaxRecordingDuration); stoppedAt.After(limit) {
+		stoppedAt = limit
+	}
+	rec.StorageKey = &key
+	rec.EventCount = count
+	rec.SizeBytes = size
+	rec.DurationMs = stoppedAt.Sub(rec.StartedAt).Milliseconds()
+	rec.Truncated = count >= maxRecordingEvents
+	rec.StoppedAt = &stoppedAt
+
+	finished, err := s.recordingRepo.FinishRecording(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if finished == nil {
+		return nil, ErrRecordingNotRunning
+	}
+
+	// Events still in flight when capture stopped expire with the list
+	_ = s.redis.Del(ctx, eventsKey).Err()
+	return finished, nil
+}
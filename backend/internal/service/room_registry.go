@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	realtimeInstanceKey     = "realtime_instance:%s"
+	realtimeInstancePattern = "realtime_instance:*"
+	// realtimeInstanceTTL drops the rooms of instances that stopped reporting
+	realtimeInstanceTTL  = 3 * presenceSweepInterval
+	roomRegistryTimeout  = 5 * time.Second
+	roomRegistryScanSize = 100
+)
+
+// RoomRegistry publishes the room statistics of a hub to Redis, so the
+// rooms of all realtime instances can be listed from any of them. The gRPC
+// API only reaches the instance that serves the call.
+type RoomRegistry struct {
+	redis *redis.Client
+	hub   *Hub
+	done  chan struct{}
+}
+
+// NewRoomRegistry creates a registry and starts reporting the rooms of hub
+func NewRoomRegistry(redisClient *redis.Client, hub *Hub) *RoomRegistry {
+	registry := &RoomRegistry{
+		redis: redisClient,
+		hub:   hub,
+		done:  make(chan struct{}),
+	}
+
+	go registry.run()
+	return registry
+}
+
+// Close stops reporting and removes the rooms of this instance
+func (r *RoomRegistry) Close() error {
+	close(r.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), roomRegistryTimeout)
+	defer cancel()
+
+	if err := r.redis.Del(ctx, fmt.Sprintf(realtimeInstanceKey, r.hub.InstanceID())).Err(); err != nil {
+		return fmt.Errorf("failed to remove instance rooms: %w", err)
+	}
+	return nil
+}
+
+// run reports the rooms right away, then on every presence sweep until the
+// registry is closed
+func (r *RoomRegistry) run() {
+	r.report()
+
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *RoomRegistry) report() {
+	ctx, cancel := context.WithTimeout(context.Background(), roomRegistryTimeout)
+	defer cancel()
+
+	stats := models.InstanceRoomStats{
+		InstanceID: r.hub.InstanceID(),
+		Rooms:      r.hub.GetAllRoomStats(),
+		ReportedAt: time.Now(),
+	}
+	for _, clients := range stats.Rooms {
+		stats.Clients += clients
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to encode room stats: %v", err)
+		return
+	}
+
+	key := fmt.Sprintf(realtimeInstanceKey, stats.InstanceID)
+	if err := r.redis.Set(ctx, key, data, realtimeInstanceTTL).Err(); err != nil {
+		hlog.CtxWarnf(ctx, "Failed to report room stats: %v", err)
+	}
+}
+
+// List returns the rooms of every instance that reported recently. Stats
+// are up to one presence sweep old.
+func (r *RoomRegistry) List(ctx context.Context) (*models.RealtimeRoomsResponse, error) {
+	var keys []string
+	iter := r.redis.Scan(ctx, 0, realtimeInstancePattern, roomRegistryScanSize).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list realtime instances: %w", err)
+	}
+
+	response := &models.RealtimeRoomsResponse{
+		Instances: []models.InstanceRoomStats{},
+		Rooms:     make(map[uuid.UUID]int),
+	}
+	if len(keys) == 0 {
+		return response, nil
+	}
+
+	values, err := r.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance rooms: %w", err)
+	}
+
+	for _, value := range values {
+		// Keys may expire between the scan and the read
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var stats models.InstanceRoomStats
+		if err := json.Unmarshal([]byte(data), &stats); err != nil {
+			return nil, fmt.Errorf("failed to decode instance rooms: %w", err)
+		}
+
+		response.Instances = append(response.Instances, stats)
+		response.Clients += stats.Clients
+		for workspaceID, clients := range stats.Rooms {
+			response.Rooms[workspaceID] += clients
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,1054 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	defaultSCIMPageSize = 100
+	maxSCIMPageSize     = 200
+
+	// scimProvider is the provider of users created by the identity provider
+	scimProvider = "scim"
+)
+
+// SCIM error types of RFC 7644 section 3.12
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeUniqueness    = "uniqueness"
+	scimTypeInvalidSyntax = "invalidSyntax"
+)
+
+var (
+	// ErrSCIMGroupNotFound is returned by the admin mapping endpoints for
+	// unknown SCIM groups
+	ErrSCIMGroupNotFound = errors.New("SCIM group not found")
+	// ErrSCIMWorkspaceNotFound is returned when a group is mapped to an
+	// unknown workspace
+	ErrSCIMWorkspaceNotFound = errors.New("workspace not found")
+	// ErrSCIMGroupWorkspaceNotFound is returned when a group isn't mapped
+	// to the workspace
+	ErrSCIMGroupWorkspaceNotFound = errors.New("SCIM group is not mapped to the workspace")
+	// ErrInvalidSCIMRole is returned for roles groups can't grant, groups
+	// never make their members owners
+	ErrInvalidSCIMRole = errors.New("role must be editor or viewer")
+)
+
+// scimFilterPattern matches the `attribute eq "value"` filters identity
+// providers send to look up existing users and groups
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9.]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// scimMemberPathPattern matches the members[value eq "id"] path Azure AD
+// uses to remove single members
+var scimMemberPathPattern = regexp.MustCompile(`(?i)^members\[value\s+eq\s+("(?:[^"\\]|\\.)*")\]$`)
+
+// SCIMError is an error answered with a SCIM error response
+type SCIMError struct {
+	Type   string // scimType of the response, empty for none
+	Detail string
+	Status int
+}
+
+func (e *SCIMError) Error() string {
+	return e.Detail
+}
+
+func newSCIMError(status int, scimType, format string, args ...any) *SCIMError {
+	return &SCIMError{Status: status, Type: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+// SCIMService provisions users and groups from an identity provider
+// through SCIM 2.0. Users are matched by email address, which is their
+// userName. Groups grant their members roles in the workspaces they are
+// mapped to by an admin, and those memberships follow the groups.
+type SCIMService struct {
+	scimRepo      *repository.SCIMRepository
+	userRepo      *repository.UserRepository
+	workspaceRepo *repository.WorkspaceRepository
+	audit         *AuditService
+}
+
+// NewSCIMService creates a new SCIM service
+func NewSCIMService(
+	scimRepo *repository.SCIMRepository,
+	userRepo *repository.UserRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	audit *AuditService,
+) *SCIMService {
+	return &SCIMService{
+		scimRepo:      scimRepo,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+		audit:         audit,
+	}
+}
+
+// ListUsers returns a page of users. startIndex is 1-based, count below
+// zero uses the default page size.
+func (s *SCIMService) ListUsers(ctx context.Context, filter string, startIndex, count int) (*models.SCIMListResponse, error) {
+	parsed, err := parseSCIMFilter(filter, "id", "username", "emails.value", "externalid")
+	if err != nil {
+		return nil, err
+	}
+	startIndex, count = scimPage(startIndex, count)
+
+	users, total, err := s.scimRepo.ListUsers(ctx, parsed, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*models.SCIMUserResource, len(users))
+	for i := range users {
+		resources[i] = scimUserResource(&users[i])
+	}
+	return scimListResponse(resources, total, startIndex), nil
+}
+
+// GetUser returns a user
+func (s *SCIMService) GetUser(ctx context.Context, id string) (*models.SCIMUserResource, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return scimUserResource(user), nil
+}
+
+// CreateUser creates a user. Users get no password and sign in through
+// OAuth with the same email address, or whatever the deployment fronts the
+// identity provider with.
+func (s *SCIMService) CreateUser(ctx context.Context, resource *models.SCIMUserResource) (*models.SCIMUserResource, error) {
+	changes := scimUserChangesFromResource(resource)
+	if changes.email == nil || !strings.Contains(*changes.email, "@") {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "userName must be an email address")
+	}
+	if err := s.checkEmailAvailable(ctx, *changes.email, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:          *changes.email,
+		Name:           changes.resolveName(""),
+		Provider:       scimProvider,
+		SCIMExternalID: changes.externalID,
+		EmailVerified:  true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if changes.active != nil && !*changes.active {
+		if _, err := s.scimRepo.SetUserActive(ctx, user, false); err != nil {
+			return nil, err
+		}
+	}
+
+	s.recordUser(ctx, models.AuditSCIMUserCreated, user)
+	return scimUserResource(user), nil
+}
+
+// ReplaceUser replaces the attributes of a user
+func (s *SCIMService) ReplaceUser(
+	ctx context.Context,
+	id string,
+	resource *models.SCIMUserResource,
+) (*models.SCIMUserResource, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := scimUserChangesFromResource(resource)
+	if changes.externalID == nil {
+		// PUT replaces the resource, a missing externalId clears it
+		changes.clearExternalID = true
+	}
+	if err := s.applyUserChanges(ctx, user, changes); err != nil {
+		return nil, err
+	}
+
+	return scimUserResource(user), nil
+}
+
+// PatchUser applies a SCIM PATCH request to a user. It accepts the forms
+// Okta and Azure AD send: operations with or without a path, any case of
+// op, and active as a boolean or a "True"/"False" string. Attributes that
+// aren't stored are ignored.
+func (s *SCIMService) PatchUser(ctx context.Context, id string, req *models.SCIMPatchRequest) (*models.SCIMUserResource, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &scimUserChanges{}
+	for _, operation := range req.Operations {
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case "add", "replace":
+			if operation.Path == "" {
+				var values map[string]json.RawMessage
+				if err := json.Unmarshal(operation.Value, &values); err != nil {
+					return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "value must be an object without a path")
+				}
+				for attribute, value := range values {
+					if err := changes.set(attribute, value); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+			if err := changes.set(operation.Path, operation.Value); err != nil {
+				return nil, err
+			}
+		case "remove":
+			if scimAttribute(operation.Path, models.SCIMSchemaUser) == "externalid" {
+				changes.clearExternalID = true
+			}
+		default:
+			return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "unsupported op %q", operation.Op)
+		}
+	}
+
+	if err := s.applyUserChanges(ctx, user, changes); err != nil {
+		return nil, err
+	}
+
+	return scimUserResource(user), nil
+}
+
+// DeleteUser deactivates a user. Users own workspaces and content, so they
+// are never deleted through SCIM.
+func (s *SCIMService) DeleteUser(ctx context.Context, id string) error {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.setUserActive(ctx, user, false); err != nil {
+		return err
+	}
+
+	s.recordUser(ctx, models.AuditSCIMUserDeleted, user)
+	return nil
+}
+
+// ListGroups returns a page of groups. startIndex is 1-based, count below
+// zero uses the default page size. Members are left out when
+// withMembers is false.
+func (s *SCIMService) ListGroups(
+	ctx context.Context,
+	filter string,
+	startIndex, count int,
+	withMembers bool,
+) (*models.SCIMListResponse, error) {
+	parsed, err := parseSCIMFilter(filter, "id", "displayname", "externalid")
+	if err != nil {
+		return nil, err
+	}
+	startIndex, count = scimPage(startIndex, count)
+
+	groups, total, err := s.scimRepo.ListGroups(ctx, parsed, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[uuid.UUID][]models.SCIMGroupMember
+	if withMembers && len(groups) > 0 {
+		members, err = s.scimRepo.ListGroupMembers(ctx, scimGroupIDs(groups))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resources := make([]*models.SCIMGroupResource, len(groups))
+	for i := range groups {
+		resources[i] = scimGroupResource(&groups[i], members[groups[i].ID], withMembers)
+	}
+	return scimListResponse(resources, total, startIndex), nil
+}
+
+// GetGroup returns a group
+func (s *SCIMService) GetGroup(ctx context.Context, id string, withMembers bool) (*models.SCIMGroupResource, error) {
+	group, err := s.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupResource(ctx, group, withMembers)
+}
+
+// CreateGroup creates a group with its members
+func (s *SCIMService) CreateGroup(ctx context.Context, resource *models.SCIMGroupResource) (*models.SCIMGroupResource, error) {
+	displayName := strings.TrimSpace(resource.DisplayName)
+	if displayName == "" {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+	}
+	if err := s.checkGroupNameAvailable(ctx, displayName, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	group := &models.SCIMGroup{
+		ID:          uuid.New(),
+		DisplayName: displayName,
+		ExternalID:  optionalString(resource.ExternalID),
+	}
+	if err := s.scimRepo.CreateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	if err := s.setGroupMembers(ctx, group, nil, scimMemberIDs(resource.Members)); err != nil {
+		return nil, err
+	}
+	s.recordGroup(ctx, models.AuditSCIMGroupChanged, group)
+
+	return s.groupResource(ctx, group, true)
+}
+
+// ReplaceGroup replaces the name and members of a group
+func (s *SCIMService) ReplaceGroup(
+	ctx context.Context,
+	id string,
+	resource *models.SCIMGroupResource,
+) (*models.SCIMGroupResource, error) {
+	group, err := s.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := strings.TrimSpace(resource.DisplayName)
+	if displayName == "" {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+	}
+	if err := s.renameGroup(ctx, group, displayName, optionalString(resource.ExternalID)); err != nil {
+		return nil, err
+	}
+
+	current, err := s.groupMemberIDs(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setGroupMembers(ctx, group, current, scimMemberIDs(resource.Members)); err != nil {
+		return nil, err
+	}
+	s.recordGroup(ctx, models.AuditSCIMGroupChanged, group)
+
+	return s.groupResource(ctx, group, true)
+}
+
+// PatchGroup applies a SCIM PATCH request to a group. Members are added,
+// removed or replaced with the members path, single members are removed
+// with members[value eq "id"] as well.
+func (s *SCIMService) PatchGroup(ctx context.Context, id string, req *models.SCIMPatchRequest) error {
+	group, err := s.getGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.groupMemberIDs(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+	members := slices.Clone(current)
+	displayName, externalID := group.DisplayName, group.ExternalID
+
+	for _, operation := range req.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax, "unsupported op %q", operation.Op)
+		}
+
+		values := map[string]json.RawMessage{}
+		if operation.Path == "" {
+			if op == "remove" {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "remove requires a path")
+			}
+			if err := json.Unmarshal(operation.Value, &values); err != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "value must be an object without a path")
+			}
+		} else if match := scimMemberPathPattern.FindStringSubmatch(operation.Path); match != nil {
+			if op != "remove" {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "members can only be removed by value")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(match[1]), &value); err != nil {
+				return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "invalid member path")
+			}
+			members = removeSCIMMembers(members, scimMemberIDs([]models.SCIMMemberRef{{Value: value}}))
+			continue
+		} else {
+			values[operation.Path] = operation.Value
+		}
+
+		for path, value := range values {
+			switch scimAttribute(path, models.SCIMSchemaGroup) {
+			case "members":
+				var refs []models.SCIMMemberRef
+				if len(value) > 0 {
+					if err := json.Unmarshal(value, &refs); err != nil {
+						return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "members must be a list of references")
+					}
+				}
+				ids := scimMemberIDs(refs)
+				switch {
+				case op == "replace":
+					members = ids
+				case op == "remove" && len(value) == 0:
+					members = nil
+				case op == "remove":
+					members = removeSCIMMembers(members, ids)
+				default:
+					members = append(members, ids...)
+				}
+			case "displayname":
+				if op == "remove" {
+					return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName is required")
+				}
+				var name string
+				if err := json.Unmarshal(value, &name); err != nil || strings.TrimSpace(name) == "" {
+					return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "displayName must be a string")
+				}
+				displayName = strings.TrimSpace(name)
+			case "externalid":
+				externalID = nil
+				if op != "remove" {
+					if err := setSCIMString(value, func(s string) { externalID = optionalString(s) }); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	if err := s.renameGroup(ctx, group, displayName, externalID); err != nil {
+		return err
+	}
+	if err := s.setGroupMembers(ctx, group, current, members); err != nil {
+		return err
+	}
+
+	s.recordGroup(ctx, models.AuditSCIMGroupChanged, group)
+	return nil
+}
+
+// DeleteGroup deletes a group. Its members lose the memberships it granted.
+func (s *SCIMService) DeleteGroup(ctx context.Context, id string) error {
+	group, err := s.getGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	members, err := s.groupMemberIDs(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.scimRepo.DeleteGroup(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return scimNotFound("Group", id)
+	}
+
+	if err := s.scimRepo.SyncMemberships(ctx, members); err != nil {
+		return err
+	}
+
+	s.recordGroup(ctx, models.AuditSCIMGroupDeleted, group)
+	return nil
+}
+
+// ListGroupWorkspaces returns SCIM groups with the workspaces they are
+// mapped to, for admins
+func (s *SCIMService) ListGroupWorkspaces(ctx context.Context, limit, offset int) ([]models.SCIMGroupWithWorkspaces, error) {
+	groups, _, err := s.scimRepo.ListGroups(ctx, nil, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return []models.SCIMGroupWithWorkspaces{}, nil
+	}
+
+	groupIDs := scimGroupIDs(groups)
+	members, err := s.scimRepo.ListGroupMembers(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := s.scimRepo.ListGroupWorkspaces(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SCIMGroupWithWorkspaces, len(groups))
+	for i := range groups {
+		workspaces := mappings[groups[i].ID]
+		if workspaces == nil {
+			workspaces = []models.SCIMGroupWorkspace{}
+		}
+		result[i] = models.SCIMGroupWithWorkspaces{
+			SCIMGroup:   groups[i],
+			MemberCount: len(members[groups[i].ID]),
+			Workspaces:  workspaces,
+		}
+	}
+	return result, nil
+}
+
+// SetGroupWorkspace maps a SCIM group to a workspace role, or changes the
+// role of an existing mapping, and updates the memberships of its members
+func (s *SCIMService) SetGroupWorkspace(
+	ctx context.Context,
+	groupID, workspaceID uuid.UUID,
+	role models.WorkspaceRole,
+) (*models.SCIMGroupWorkspace, error) {
+	if role != models.WorkspaceRoleEditor && role != models.WorkspaceRoleViewer {
+		return nil, ErrInvalidSCIMRole
+	}
+
+	group, err := s.scimRepo.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrSCIMGroupNotFound
+	}
+
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, ErrSCIMWorkspaceNotFound
+	}
+
+	mapping := &models.SCIMGroupWorkspace{
+		GroupID:       groupID,
+		WorkspaceID:   workspaceID,
+		WorkspaceName: workspace.Name,
+		Role:          role,
+	}
+	if err := s.scimRepo.SetGroupWorkspace(ctx, mapping); err != nil {
+		return nil, err
+	}
+
+	if err := s.syncGroupMembers(ctx, groupID); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// DeleteGroupWorkspace removes the mapping of a SCIM group to a workspace.
+// Members keep memberships that weren't granted by SCIM.
+func (s *SCIMService) DeleteGroupWorkspace(ctx context.Context, groupID, workspaceID uuid.UUID) error {
+	deleted, err := s.scimRepo.DeleteGroupWorkspace(ctx, groupID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSCIMGroupWorkspaceNotFound
+	}
+
+	return s.syncGroupMembers(ctx, groupID)
+}
+
+func (s *SCIMService) getUser(ctx context.Context, id string) (*models.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, scimNotFound("User", id)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Bots belong to their workspace, not to the identity provider
+	if user == nil || user.IsBot {
+		return nil, scimNotFound("User", id)
+	}
+	return user, nil
+}
+
+func (s *SCIMService) getGroup(ctx context.Context, id string) (*models.SCIMGroup, error) {
+	groupID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, scimNotFound("Group", id)
+	}
+
+	group, err := s.scimRepo.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, scimNotFound("Group", id)
+	}
+	return group, nil
+}
+
+// applyUserChanges stores changed attributes of a user and activates or
+// deactivates them
+func (s *SCIMService) applyUserChanges(ctx context.Context, user *models.User, changes *scimUserChanges) error {
+	if changes.email != nil && !strings.EqualFold(*changes.email, user.Email) {
+		if !strings.Contains(*changes.email, "@") {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "userName must be an email address")
+		}
+		if err := s.checkEmailAvailable(ctx, *changes.email, user.ID); err != nil {
+			return err
+		}
+		user.Email = *changes.email
+	}
+	user.Name = changes.resolveName(user.Name)
+	switch {
+	case changes.externalID != nil:
+		user.SCIMExternalID = changes.externalID
+	case changes.clearExternalID:
+		user.SCIMExternalID = nil
+	}
+
+	if err := s.scimRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	if changes.active != nil {
+		if err := s.setUserActive(ctx, user, *changes.active); err != nil {
+			return err
+		}
+	}
+
+	s.recordUser(ctx, models.AuditSCIMUserUpdated, user)
+	return nil
+}
+
+func (s *SCIMService) recordUser(ctx context.Context, action string, user *models.User) {
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Metadata:   map[string]interface{}{"email": user.Email, "active": user.Active()},
+	})
+}
+
+func (s *SCIMService) recordGroup(ctx context.Context, action string, group *models.SCIMGroup) {
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     action,
+		TargetType: "scim_group",
+		TargetID:   group.ID.String(),
+		Metadata:   map[string]interface{}{"display_name": group.DisplayName},
+	})
+}
+
+// setUserActive activates or deactivates a user. Deactivated users lose the
+// memberships their groups granted, activated users get them back.
+func (s *SCIMService) setUserActive(ctx context.Context, user *models.User, active bool) error {
+	changed, err := s.scimRepo.SetUserActive(ctx, user, active)
+	if err != nil || !changed {
+		return err
+	}
+	return s.scimRepo.SyncMemberships(ctx, []uuid.UUID{user.ID})
+}
+
+func (s *SCIMService) checkEmailAvailable(ctx context.Context, email string, userID uuid.UUID) error {
+	users, _, err := s.scimRepo.ListUsers(ctx, &models.SCIMFilter{Attribute: "username", Value: email}, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 && users[0].ID != userID {
+		return newSCIMError(http.StatusConflict, scimTypeUniqueness, "a user with userName %s already exists", email)
+	}
+	return nil
+}
+
+func (s *SCIMService) checkGroupNameAvailable(ctx context.Context, displayName string, groupID uuid.UUID) error {
+	existing, err := s.scimRepo.GetGroupByDisplayName(ctx, displayName)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != groupID {
+		return newSCIMError(http.StatusConflict, scimTypeUniqueness, "a group with displayName %s already exists", displayName)
+	}
+	return nil
+}
+
+func (s *SCIMService) renameGroup(ctx context.Context, group *models.SCIMGroup, displayName string, externalID *string) error {
+	if displayName == group.DisplayName && equalOptionalStrings(externalID, group.ExternalID) {
+		return nil
+	}
+	if !strings.EqualFold(displayName, group.DisplayName) {
+		if err := s.checkGroupNameAvailable(ctx, displayName, group.ID); err != nil {
+			return err
+		}
+	}
+
+	group.DisplayName = displayName
+	group.ExternalID = externalID
+	return s.scimRepo.UpdateGroup(ctx, group)
+}
+
+// setGroupMembers changes the members of a group from current to desired
+// and syncs the memberships of everyone who joined or left
+func (s *SCIMService) setGroupMembers(ctx context.Context, group *models.SCIMGroup, current, desired []uuid.UUID) error {
+	added := removeSCIMMembers(desired, current)
+	removed := removeSCIMMembers(current, desired)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	if len(added) > 0 {
+		if err := s.scimRepo.AddGroupMembers(ctx, group.ID, added); err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		if err := s.scimRepo.RemoveGroupMembers(ctx, group.ID, removed); err != nil {
+			return err
+		}
+	}
+	if err := s.scimRepo.TouchGroup(ctx, group); err != nil {
+		return err
+	}
+
+	return s.scimRepo.SyncMemberships(ctx, append(added, removed...))
+}
+
+func (s *SCIMService) syncGroupMembers(ctx context.Context, groupID uuid.UUID) error {
+	members, err := s.groupMemberIDs(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	return s.scimRepo.SyncMemberships(ctx, members)
+}
+
+func (s *SCIMService) groupMemberIDs(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	members, err := s.scimRepo.ListGroupMembers(ctx, []uuid.UUID{groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(members[groupID]))
+	for i, member := range members[groupID] {
+		ids[i] = member.UserID
+	}
+	return ids, nil
+}
+
+func (s *SCIMService) groupResource(
+	ctx context.Context,
+	group *models.SCIMGroup,
+	withMembers bool,
+) (*models.SCIMGroupResource, error) {
+	var members []models.SCIMGroupMember
+	if withMembers {
+		byGroup, err := s.scimRepo.ListGroupMembers(ctx, []uuid.UUID{group.ID})
+		if err != nil {
+			return nil, err
+		}
+		members = byGroup[group.ID]
+	}
+	return scimGroupResource(group, members, withMembers), nil
+}
+
+// scimUserChanges collects the attributes a request sets on a user. Unset
+// attributes are left alone.
+type scimUserChanges struct {
+	email           *string
+	displayName     *string
+	externalID      *string
+	active          *bool
+	name            models.SCIMName
+	clearExternalID bool
+}
+
+func scimUserChangesFromResource(resource *models.SCIMUserResource) *scimUserChanges {
+	changes := &scimUserChanges{
+		active:     resource.Active,
+		externalID: optionalString(resource.ExternalID),
+	}
+	if email := scimResourceEmail(resource); email != "" {
+		changes.email = &email
+	}
+	if resource.DisplayName != "" {
+		changes.displayName = &resource.DisplayName
+	}
+	if resource.Name != nil {
+		changes.name = *resource.Name
+	}
+	return changes
+}
+
+// set records the value of an attribute path of a PATCH operation
+func (c *scimUserChanges) set(path string, value json.RawMessage) error {
+	attribute := scimAttribute(path, models.SCIMSchemaUser)
+	switch {
+	case attribute == "active":
+		active, err := scimBool(value)
+		if err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "active must be a boolean")
+		}
+		c.active = &active
+	case attribute == "name":
+		if err := json.Unmarshal(value, &c.name); err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "name must be an object")
+		}
+	case attribute == "emails":
+		var emails []models.SCIMEmail
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "emails must be a list")
+		}
+		if email := primarySCIMEmail(emails); email != "" {
+			c.email = &email
+		}
+	case attribute == "username", strings.HasPrefix(attribute, "emails[") && strings.HasSuffix(attribute, ".value"):
+		return setSCIMString(value, func(s string) { c.email = &s })
+	case attribute == "displayname":
+		return setSCIMString(value, func(s string) { c.displayName = &s })
+	case attribute == "externalid":
+		return setSCIMString(value, func(s string) { c.externalID = optionalString(s) })
+	case attribute == "name.formatted":
+		return setSCIMString(value, func(s string) { c.name.Formatted = s })
+	case attribute == "name.givenname":
+		return setSCIMString(value, func(s string) { c.name.GivenName = s })
+	case attribute == "name.familyname":
+		return setSCIMString(value, func(s string) { c.name.FamilyName = s })
+	}
+	return nil
+}
+
+// setSCIMString decodes a string attribute value and passes it to set
+func setSCIMString(value json.RawMessage, set func(string)) error {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "value must be a string")
+	}
+	set(s)
+	return nil
+}
+
+// resolveName picks the name of a user: displayName, the formatted name,
+// the given and family name, the current name or the local part of the
+// email address, in that order
+func (c *scimUserChanges) resolveName(current string) string {
+	if c.displayName != nil && strings.TrimSpace(*c.displayName) != "" {
+		return strings.TrimSpace(*c.displayName)
+	}
+	if formatted := strings.TrimSpace(c.name.Formatted); formatted != "" {
+		return formatted
+	}
+	if parts := strings.TrimSpace(c.name.GivenName + " " + c.name.FamilyName); parts != "" {
+		return parts
+	}
+	if current != "" {
+		return current
+	}
+	if c.email != nil {
+		local, _, _ := strings.Cut(*c.email, "@")
+		return local
+	}
+	return ""
+}
+
+func scimUserResource(user *models.User) *models.SCIMUserResource {
+	active := user.Active()
+	resource := &models.SCIMUserResource{
+		Schemas:     []string{models.SCIMSchemaUser},
+		ID:          user.ID.String(),
+		UserName:    user.Email,
+		DisplayName: user.Name,
+		Name:        &models.SCIMName{Formatted: user.Name},
+		Emails:      []models.SCIMEmail{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &models.SCIMMeta{
+			ResourceType: "User",
+			Created:      &user.CreatedAt,
+			LastModified: &user.UpdatedAt,
+		},
+	}
+	if user.SCIMExternalID != nil {
+		resource.ExternalID = *user.SCIMExternalID
+	}
+	return resource
+}
+
+func scimGroupResource(
+	group *models.SCIMGroup,
+	members []models.SCIMGroupMember,
+	withMembers bool,
+) *models.SCIMGroupResource {
+	resource := &models.SCIMGroupResource{
+		Schemas:     []string{models.SCIMSchemaGroup},
+		ID:          group.ID.String(),
+		DisplayName: group.DisplayName,
+		Meta: &models.SCIMMeta{
+			ResourceType: "Group",
+			Created:      &group.CreatedAt,
+			LastModified: &group.UpdatedAt,
+		},
+	}
+	if group.ExternalID != nil {
+		resource.ExternalID = *group.ExternalID
+	}
+	if withMembers {
+		resource.Members = make([]models.SCIMMemberRef, len(members))
+		for i, member := range members {
+			resource.Members[i] = models.SCIMMemberRef{Value: member.UserID.String(), Display: member.Email}
+		}
+	}
+	return resource
+}
+
+func scimListResponse[T any](resources []T, total, startIndex int) *models.SCIMListResponse {
+	return &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// parseSCIMFilter parses an `attribute eq "value"` filter on one of the
+// allowed lowercase attributes, nil for an empty filter
+func parseSCIMFilter(filter string, allowed ...string) (*models.SCIMFilter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+
+	match := scimFilterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "only attribute eq \"value\" filters are supported")
+	}
+
+	attribute := strings.ToLower(match[1])
+	if !slices.Contains(allowed, attribute) {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "filtering by %s is not supported", match[1])
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(match[2]), &value); err != nil {
+		return nil, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, "invalid filter value")
+	}
+
+	return &models.SCIMFilter{Attribute: attribute, Value: value}, nil
+}
+
+// scimPage clamps a 1-based start index and a page size
+func scimPage(startIndex, count int) (start, size int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = defaultSCIMPageSize
+	}
+	return startIndex, min(count, maxSCIMPageSize)
+}
+
+// scimAttribute returns the lowercase attribute of a path, without the
+// schema URN prefix of the resource
+func scimAttribute(path, schema string) string {
+	attribute := strings.ToLower(strings.TrimSpace(path))
+	return strings.TrimPrefix(attribute, strings.ToLower(schema)+":")
+}
+
+// scimBool decodes a boolean sent as JSON boolean or, as Azure AD does, as
+// a "True"/"False" string
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
+
+// scimResourceEmail returns the email address of a user resource: userName
+// when it is an email address, else the primary email
+func scimResourceEmail(resource *models.SCIMUserResource) string {
+	if userName := strings.TrimSpace(resource.UserName); strings.Contains(userName, "@") {
+		return userName
+	}
+	if email := primarySCIMEmail(resource.Emails); email != "" {
+		return email
+	}
+	return strings.TrimSpace(resource.UserName)
+}
+
+func primarySCIMEmail(emails []models.SCIMEmail) string {
+	for _, email := range emails {
+		if email.Primary {
+			return strings.TrimSpace(email.Value)
+		}
+	}
+	if len(emails) > 0 {
+		return strings.TrimSpace(emails[0].Value)
+	}
+	return ""
+}
+
+// scimMemberIDs returns the user IDs of member references, skipping values
+// that aren't user IDs
+func scimMemberIDs(refs []models.SCIMMemberRef) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(refs))
+	for _, ref := range refs {
+		if id, err := uuid.Parse(ref.Value); err == nil && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// removeSCIMMembers returns the IDs of from that aren't in ids
+func removeSCIMMembers(from, ids []uuid.UUID) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(from))
+	for _, id := range from {
+		if !slices.Contains(ids, id) && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func scimGroupIDs(groups []models.SCIMGroup) []uuid.UUID {
+	ids := make([]uuid.UUID, len(groups))
+	for i := range groups {
+		ids[i] = groups[i].ID
+	}
+	return ids
+}
+
+func scimNotFound(resourceType, id string) *SCIMError {
+	return newSCIMError(http.StatusNotFound, "", "%s %s not found", resourceType, id)
+}
+
+func optionalString(s string) *string {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	return &s
+}
+
+func equalOptionalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 50
+	maxSearchQueryRunes = 200
+
+	// searchCandidates is how many comments and assets are ranked at most,
+	// the most recent matches
+	searchCandidates = 200
+
+	searchTitleRunes   = 80
+	searchSnippetRunes = 160
+	// searchSnippetLead is how much text a snippet shows before the match
+	searchSnippetLead = 40
+	// searchLengthNorm is the text length at which the shortness bonus halves
+	searchLengthNorm = 200
+	// searchMatchFloor is the score of matches found by the database that
+	// the terms alone don't explain, such as phrases of full-text search
+	searchMatchFloor = 0.1
+	// searchFilenameWeight ranks asset filenames above recognized text
+	searchFilenameWeight = 1.5
+)
+
+// SearchService searches elements, comments and assets of a workspace at
+// once. Element content may be encrypted at rest, so elements are matched
+// after decryption rather than in the database.
+type SearchService struct {
+	canvasService *CanvasService
+	commentRepo   *repository.CommentRepository
+	assetRepo     *repository.AssetRepository
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(
+	canvasService *CanvasService,
+	commentRepo *repository.CommentRepository,
+	assetRepo *repository.AssetRepository,
+) *SearchService {
+	return &SearchService{
+		canvasService: canvasService,
+		commentRepo:   commentRepo,
+		assetRepo:     assetRepo,
+	}
+}
+
+// Search returns a page of the matches of a query in a workspace, ranked
+// together whatever their type
+func (s *SearchService) Search(ctx context.Context, workspaceID uuid.UUID, req *models.SearchRequest) (*models.SearchResponse, error) {
+	query := strings.Join(strings.Fields(req.Query), " ")
+	if query == "" {
+		return nil, fmt.Errorf("q is required")
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryRunes {
+		return nil, fmt.Errorf("q must be at most %d characters", maxSearchQueryRunes)
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+	offset := max(req.Offset, 0)
+
+	wants := func(t models.SearchResultType) bool {
+		return len(req.Types) == 0 || slices.Contains(req.Types, t)
+	}
+
+	m := newSearchMatcher(query)
+	results := []models.SearchResult{}
+
+	if wants(models.SearchResultElement) {
+		elements, err := s.canvasService.GetWorkspaceElements(ctx, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m.elements(elements)...)
+	}
+
+	if wants(models.SearchResultComment) {
+		comments, err := s.commentRepo.SearchComments(ctx, workspaceID, query, searchCandidates)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m.comments(comments)...)
+	}
+
+	if wants(models.SearchResultAsset) {
+		assets, _, err := s.assetRepo.ListAssets(ctx, workspaceID, models.AssetListFilter{
+			Query: query,
+			Limit: searchCandidates,
+		})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m.assets(assets)...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].UpdatedAt.After(results[j].UpdatedAt)
+	})
+
+	total := len(results)
+	page := results[min(offset, total):min(offset+limit, total)]
+
+	return &models.SearchResponse{
+		Results: page,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}, nil
+}
+
+// searchMatcher scores texts against the terms of a query
+type searchMatcher struct {
+	phrase string
+	terms  []string
+}
+
+func newSearchMatcher(query string) *searchMatcher {
+	phrase := strings.ToLower(query)
+	return &searchMatcher{phrase: phrase, terms: strings.Fields(phrase)}
+}
+
+// score is 0 unless every term occurs in text. Terms at the start of words,
+// the whole query as a phrase and shorter texts score higher.
+func (m *searchMatcher) score(text string) float64 {
+	lower := strings.ToLower(text)
+
+	score := 0.0
+	for _, term := range m.terms {
+		if !strings.Contains(lower, term) {
+			return 0
+		}
+		score++
+		if matchesWordStart(lower, term) {
+			score += 0.5
+		}
+	}
+	score /= float64(len(m.terms))
+
+	if len(m.terms) > 1 && matchesWordStart(lower, m.phrase) {
+		score++
+	}
+	if strings.TrimSpace(lower) == m.phrase {
+		score++
+	}
+
+	return score + 1/(1+float64(len(lower))/searchLengthNorm)
+}
+
+// snippet returns the part of text around the first match, on one line
+func (m *searchMatcher) snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	lower := strings.ToLower(text)
+
+	// strings.ToLower maps rune by rune, so rune offsets carry over
+	start := 0
+	for _, term := range m.terms {
+		if i := strings.Index(lower, term); i >= 0 {
+			start = max(utf8.RuneCountInString(lower[:i])-searchSnippetLead, 0)
+			break
+		}
+	}
+
+	runes := []rune(text)
+	end := min(start+searchSnippetRunes, len(runes))
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func (m *searchMatcher) elements(elements []models.CanvasElement) []models.SearchResult {
+	var results []models.SearchResult
+	for i := range elements {
+		element := &elements[i]
+		text := elementSearchText(element)
+		score := m.score(text)
+		if score == 0 {
+			continue
+		}
+
+		title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		results = append(results, models.SearchResult{
+			Type:        models.SearchResultElement,
+			ID:          element.ID,
+			ElementID:   &element.ID,
+			ElementType: element.ElementType,
+			Title:       truncateRunes(strings.TrimSpace(title), searchTitleRunes),
+			Snippet:     m.snippet(text),
+			Score:       score,
+			UpdatedAt:   element.UpdatedAt,
+		})
+	}
+	return results
+}
+
+func (m *searchMatcher) comments(matches []models.CommentMatch) []models.SearchResult {
+	results := make([]models.SearchResult, 0, len(matches))
+	for i := range matches {
+		comment := &matches[i].Comment
+
+		threadID := comment.ID
+		if comment.ParentID != nil {
+			threadID = *comment.ParentID
+		}
+		title := ""
+		if comment.AuthorName != nil {
+			title = *comment.AuthorName
+		}
+		updatedAt := comment.CreatedAt
+		if comment.EditedAt != nil {
+			updatedAt = *comment.EditedAt
+		}
+
+		results = append(results, models.SearchResult{
+			Type:      models.SearchResultComment,
+			ID:        comment.ID,
+			ThreadID:  &threadID,
+			ElementID: matches[i].ThreadElementID,
+			Title:     title,
+			Snippet:   m.snippet(comment.Body),
+			Score:     max(m.score(comment.Body), searchMatchFloor),
+			UpdatedAt: updatedAt,
+		})
+	}
+	return results
+}
+
+func (m *searchMatcher) assets(assets []models.Asset) []models.SearchResult {
+	results := make([]models.SearchResult, 0, len(assets))
+	for i := range assets {
+		asset := &assets[i]
+
+		score := m.score(asset.Filename) * searchFilenameWeight
+		snippet := ""
+		if asset.OCRText != nil {
+			if ocrScore := m.score(*asset.OCRText); ocrScore > 0 {
+				snippet = m.snippet(*asset.OCRText)
+				score = max(score, ocrScore)
+			}
+		}
+
+		results = append(results, models.SearchResult{
+			Type:        models.SearchResultAsset,
+			ID:          asset.ID,
+			ContentType: asset.ContentType,
+			Title:       asset.Filename,
+			Snippet:     snippet,
+			Score:       max(score, searchMatchFloor),
+			UpdatedAt:   asset.CreatedAt,
+		})
+	}
+	return results
+}
+
+// elementSearchText returns the text of an element that search looks into:
+// the text of text elements and sticky notes, list items and labels
+func elementSearchText(element *models.CanvasElement) string {
+	parts := []string{elementPlainText(element)}
+
+	if element.ElementType == models.ElementTypeList {
+		items, _ := element.ElementData["items"].([]interface{})
+		for _, raw := range items {
+			item, _ := raw.(map[string]interface{})
+			if content, _ := item["content"].(string); content != "" {
+				parts = append(parts, content)
+			}
+		}
+	}
+	if label, _ := element.ElementData["label"].(string); label != "" {
+		parts = append(parts, label)
+	}
+
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// matchesWordStart reports whether term occurs in text at the start of a word
+func matchesWordStart(text, term string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		i += offset
+		if i == 0 {
+			return true
+		}
+		previous, _ := utf8.DecodeLastRuneInString(text[:i])
+		if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+			return true
+		}
+		offset = i + len(term)
+	}
+}
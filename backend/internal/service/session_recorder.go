@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	recordingActiveKey = "recording:active:%s"
+	recordingEventsKey = "recording:%s:events"
+
+	// MaxRecordingDuration is how long a session can be recorded. Capture
+	// stops after it, the events so far are kept until the recording is
+	// stopped.
+	MaxRecordingDuration = 4 * time.Hour
+	// maxRecordingEvents is how many events a recording keeps
+	maxRecordingEvents = 200000
+	// recordingEventsTTL drops the events of recordings nobody stopped
+	recordingEventsTTL = MaxRecordingDuration + 24*time.Hour
+
+	// recordingLookupTTL is how long a room is assumed to record, or not,
+	// before Redis is asked again
+	recordingLookupTTL = 2 * time.Second
+	recordingQueueSize = 4096
+	recordingBatchSize = 200
+	recordingFlushTick = 200 * time.Millisecond
+)
+
+// recordedMessageTypes are the realtime messages a recording keeps, the
+// operations and the presence of the session
+var recordedMessageTypes = map[models.MessageType]bool{
+	models.MessageTypeOperation:       true,
+	models.MessageTypeBatch:           true,
+	models.MessageTypeCursorMove:      true,
+	models.MessageTypeSelectionChange: true,
+	models.MessageTypeUserJoined:      true,
+	models.MessageTypeUserLeft:        true,
+	models.MessageTypePresenceUpdate:  true,
+}
+
+type recordingLookup struct {
+	until       time.Time
+	recordingID string
+}
+
+type capturedMessage struct {
+	at          time.Time
+	msg         *models.WSMessage
+	workspaceID uuid.UUID
+}
+
+type capturedEvent struct {
+	recordingID string
+	data        []byte
+}
+
+// SessionRecorder captures the room messages of boards that record a
+// session. Every instance captures the messages its own clients send, in
+// batches to a Redis list per recording, so recording never slows a room
+// down: Capture only queues a message, and a worker looks up whether its
+// board records and encodes it. A nil recorder captures nothing.
+type SessionRecorder struct {
+	redis    *redis.Client
+	messages chan capturedMessage
+	events   chan capturedEvent
+	done     chan struct{}
+	stopped  chan struct{}
+
+	mu      sync.Mutex
+	lookups map[uuid.UUID]recordingLookup
+}
+
+// NewSessionRecorder creates and starts a new session recorder
+func NewSessionRecorder(redisClient *redis.Client) *SessionRecorder {
+	recorder := &SessionRecorder{
+		redis:    redisClient,
+		messages: make(chan capturedMessage, recordingQueueSize),
+		events:   make(chan capturedEvent, recordingQueueSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		lookups:  make(map[uuid.UUID]recordingLookup),
+	}
+
+	go recorder.capture()
+	go recorder.run()
+	return recorder
+}
+
+// Close stops capturing and writes the events captured so far
+func (r *SessionRecorder) Close() error {
+	close(r.done)
+	<-r.stopped
+	return nil
+}
+
+// Capture records a room message if its board records a session. It never
+// blocks, so rooms can call it; messages are dropped when Redis falls
+// behind. Messages must not be modified after they were captured.
+func (r *SessionRecorder) Capture(workspaceID uuid.UUID, msg *models.WSMessage) {
+	if r == nil || !recordedMessageTypes[msg.Type] {
+		return
+	}
+
+	select {
+	case r.messages <- capturedMessage{workspaceID: workspaceID, msg: msg, at: time.Now().UTC()}:
+	default:
+	}
+}
+
+// capture turns the captured messages of boards that record into events
+// for run, until the recorder is closed. Messages still queued then are
+// only kept for boards whose lookup is cached, so closing doesn't wait for
+// Redis once per board. It closes events when done.
+func (r *SessionRecorder) capture() {
+	defer close(r.events)
+
+	for {
+		select {
+		case captured := <-r.messages:
+			r.encode(captured, r.activeRecording(captured.workspaceID))
+		case <-r.done:
+			for {
+				select {
+				case captured := <-r.messages:
+					if recordingID, ok := r.cachedRecording(captured.workspaceID); ok {
+						r.encode(captured, recordingID)
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// encode queues the event of a captured message for its recording, if any
+func (r *SessionRecorder) encode(captured capturedMessage, recordingID string) {
+	if recordingID == "" {
+		return
+	}
+
+	payload, err := json.Marshal(captured.msg.Payload)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(&models.RecordedEvent{
+		At:      captured.at,
+		Type:    captured.msg.Type,
+		UserID:  captured.msg.UserID,
+		Payload: payload,
+	})
+	if err != nil {
+		return
+	}
+
+	select {
+	case r.events <- capturedEvent{recordingID: recordingID, data: data}:
+	default:
+	}
+}
+
+// activeRecording returns the ID of the recording of a workspace, empty if
+// it records nothing. Only the capture worker calls it, since it may wait
+// for Redis.
+func (r *SessionRecorder) activeRecording(workspaceID uuid.UUID) string {
+	if recordingID, ok := r.cachedRecording(workspaceID); ok {
+		return recordingID
+	}
+	now := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	recordingID, err := r.redis.Get(ctx, fmt.Sprintf(recordingActiveKey, workspaceID)).Result()
+	if err != nil && err != redis.Nil {
+		hlog.Warnf("Failed to check session recording of workspace %s: %v", workspaceID, err)
+	}
+
+	r.mu.Lock()
+	r.lookups[workspaceID] = recordingLookup{recordingID: recordingID, until: now.Add(recordingLookupTTL)}
+	r.mu.Unlock()
+	return recordingID
+}
+
+// cachedRecording returns the recording of a workspace if its lookup is
+// cached and hasn't expired
+func (r *SessionRecorder) cachedRecording(workspaceID uuid.UUID) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lookup, ok := r.lookups[workspaceID]
+	if !ok || time.Now().After(lookup.until) {
+		return "", false
+	}
+	return lookup.recordingID, true
+}
+
+// forget drops cached lookups that expired, so rooms that closed don't
+// stay in memory
+func (r *SessionRecorder) forget() {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for workspaceID, lookup := range r.lookups {
+		if now.After(lookup.until) {
+			delete(r.lookups, workspaceID)
+		}
+	}
+}
+
+// run writes captured events in batches, on a full batch and on every tick,
+// until capture closed events
+func (r *SessionRecorder) run() {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(recordingFlushTick)
+	defer ticker.Stop()
+
+	batch := make(map[string][]interface{})
+	buffered := 0
+	flush := func() {
+		if buffered > 0 {
+			r.write(batch)
+			batch = make(map[string][]interface{})
+			buffered = 0
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch[event.recordingID] = append(batch[event.recordingID], event.data)
+			buffered++
+			if buffered >= recordingBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			r.forget()
+		}
+	}
+}
+
+// write appends batches of events to their recordings. Recordings that
+// reached the limit are trimmed back to it.
+func (r *SessionRecorder) write(batch map[string][]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipe := r.redis.Pipeline()
+	lengths := make(map[string]*redis.IntCmd, len(batch))
+	for recordingID, events := range batch {
+		key := fmt.Sprintf(recordingEventsKey, recordingID)
+		lengths[recordingID] = pipe.RPush(ctx, key, events...)
+		pipe.Expire(ctx, key, recordingEventsTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		hlog.Warnf("Failed to write recorded events: %v", err)
+		return
+	}
+
+	for recordingID, length := range lengths {
+		if length.Val() > maxRecordingEvents {
+			key := fmt.Sprintf(recordingEventsKey, recordingID)
+			if err := r.redis.LTrim(ctx, key, 0, maxRecordingEvents-1).Err(); err != nil {
+				hlog.Warnf("Failed to trim recording %s: %v", recordingID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// maxWorkspaceNameLength matches the workspaces.name column
+const maxWorkspaceNameLength = 255
+
+// RestoreToNewWorkspace branches a board: it creates a new private workspace
+// owned by the user with the elements of the snapshot. Referenced assets are
+// copied, so the branch stays intact when the source board changes.
+func (s *SnapshotService) RestoreToNewWorkspace(
+	ctx context.Context,
+	workspaceID, userID, snapshotID uuid.UUID,
+	name string,
+) (*models.Workspace, error) {
+	snapshot, err := s.snapshotRepo.GetSnapshotByID(ctx, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not found: %w", err)
+	}
+
+	if snapshot.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("snapshot does not belong to workspace")
+	}
+
+	source, err := s.workspaceRepo.GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	if name == "" {
+		name = branchWorkspaceName(source.Name, snapshot)
+	}
+	if utf8.RuneCountInString(name) > maxWorkspaceNameLength {
+		return nil, fmt.Errorf("name must be at most %d characters", maxWorkspaceNameLength)
+	}
+
+	workspace := &models.Workspace{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: source.Description,
+		OwnerID:     userID,
+		IsPublic:    false, // Branches are private like duplicates
+		Settings:    source.Settings,
+	}
+
+	elements, err := s.readSnapshotElements(ctx, workspace.ID, userID, snapshot, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.workspaceRepo.CreateWorkspace(ctx, workspace); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	s.copyElementAssets(ctx, workspaceID, workspace.ID, userID, elements)
+
+	if len(elements) > 0 {
+		if err := s.canvasRepo.BatchCreateElements(ctx, elements); err != nil {
+			// Don't leave a half-populated board behind
+			_ = s.workspaceRepo.SoftDeleteWorkspace(ctx, workspace.ID)
+			return nil, fmt.Errorf("failed to create elements: %w", err)
+		}
+	}
+
+	s.emitSnapshotEvent(ctx, models.EventSnapshotRestored, &userID, snapshot, models.SnapshotEventPayload{
+		Mode:           models.SnapshotRestoreNewWorkspace,
+		NewWorkspaceID: &workspace.ID,
+		ElementCount:   len(elements),
+	})
+
+	return workspace, nil
+}
+
+// branchWorkspaceName names a branch after the snapshot it was created from
+func branchWorkspaceName(sourceName string, snapshot *models.CanvasSnapshot) string {
+	name := fmt.Sprintf("%s (version %d)", sourceName, snapshot.Version)
+	if snapshot.Name != nil {
+		name = fmt.Sprintf("%s (%s)", sourceName, *snapshot.Name)
+	}
+	if runes := []rune(name); len(runes) > maxWorkspaceNameLength {
+		name = string(runes[:maxWorkspaceNameLength])
+	}
+	return name
+}
+
+// copyElementAssets copies the assets referenced by elements into the target
+// workspace and points the elements at the copies. Each asset is copied once;
+// assets that are gone or can't be copied keep their old reference.
+func (s *SnapshotService) copyElementAssets(
+	ctx context.Context,
+	sourceWorkspaceID, targetWorkspaceID, userID uuid.UUID,
+	elements []models.CanvasElement,
+) {
+	if s.assetService == nil {
+		return
+	}
+
+	copies := make(map[uuid.UUID]uuid.UUID)
+	for i := range elements {
+		assetID, ok := elements[i].ElementData.AssetID()
+		if !ok {
+			continue
+		}
+
+		copyID, done := copies[assetID]
+		if !done {
+			asset, err := s.assetService.GetWorkspaceAsset(ctx, sourceWorkspaceID, assetID)
+			if err == nil {
+				var copied *models.Asset
+				copied, err = s.assetService.CopyAsset(ctx, asset, targetWorkspaceID, userID)
+				if err == nil {
+					copyID = copied.ID
+				}
+			}
+			if err != nil {
+				hlog.CtxErrorf(ctx, "Failed to copy asset %s to workspace %s: %v", assetID, targetWorkspaceID, err)
+			}
+			copies[assetID] = copyID
+		}
+
+		if copyID != uuid.Nil {
+			elements[i].ElementData["asset_id"] = copyID.String()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+// snapshotMessageTypes maps snapshot events to the WebSocket messages
+// connected clients receive
+var snapshotMessageTypes = map[string]models.MessageType{
+	models.EventSnapshotCreated:  models.MessageTypeSnapshotCreated,
+	models.EventSnapshotRestored: models.MessageTypeSnapshotRestored,
+	models.EventSnapshotDeleted:  models.MessageTypeSnapshotDeleted,
+}
+
+// emitSnapshotEvent tells connected clients and integrations about a change
+// in the version history of a workspace. A nil actor marks a system action.
+func (s *SnapshotService) emitSnapshotEvent(
+	ctx context.Context,
+	eventType string,
+	actorID *uuid.UUID,
+	snapshot *models.CanvasSnapshot,
+	payload models.SnapshotEventPayload,
+) {
+	payload.Snapshot = snapshot.ToResponse()
+
+	if s.rooms != nil {
+		msg := &models.WSMessage{
+			Type:      snapshotMessageTypes[eventType],
+			Timestamp: time.Now(),
+			Payload:   payload,
+		}
+		if actorID != nil {
+			msg.UserID = *actorID
+		}
+		s.rooms.BroadcastToRoom(snapshot.WorkspaceID, msg, uuid.Nil)
+	}
+
+	if s.events != nil {
+		if err := s.events.Publish(ctx, eventType, snapshot.WorkspaceID, actorID, payload); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to publish %s event for snapshot %s: %v", eventType, snapshot.ID, err)
+		}
+	}
+}
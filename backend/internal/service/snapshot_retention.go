@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	// DefaultSnapshotRetentionCount is how many snapshots workspaces without a
+	// policy keep
+	DefaultSnapshotRetentionCount = 100
+
+	maxSnapshotRetentionCount = 1000
+	maxSnapshotRetentionDays  = 3650
+	maxSnapshotKeepDaily      = 366
+	maxSnapshotKeepWeekly     = 520
+
+	// retentionWorkspaceBatchSize is how many workspaces EnforceRetention loads at a time
+	retentionWorkspaceBatchSize = 100
+
+	daysPerWeek = 7
+)
+
+// defaultRetentionPolicy keeps the latest snapshots only
+func defaultRetentionPolicy(workspaceID uuid.UUID) *models.SnapshotRetentionPolicy {
+	return &models.SnapshotRetentionPolicy{
+		WorkspaceID: workspaceID,
+		MaxCount:    DefaultSnapshotRetentionCount,
+	}
+}
+
+// GetRetentionPolicy returns the retention policy of a workspace, or the
+// default one when the owner has not configured it. The count is capped to
+// the history depth of the owner's plan.
+func (s *SnapshotService) GetRetentionPolicy(ctx context.Context, workspaceID uuid.UUID) (*models.SnapshotRetentionPolicy, error) {
+	policy, err := s.snapshotRepo.GetRetentionPolicy(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = defaultRetentionPolicy(workspaceID)
+	}
+
+	maxSnapshots, err := s.planSnapshotLimit(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if maxSnapshots > 0 && policy.MaxCount > maxSnapshots {
+		policy.MaxCount = maxSnapshots
+	}
+
+	return policy, nil
+}
+
+// planSnapshotLimit returns how many snapshots the plan of the workspace
+// owner keeps, 0 when unlimited or billing is disabled
+func (s *SnapshotService) planSnapshotLimit(ctx context.Context, workspaceID uuid.UUID) (int, error) {
+	if s.billing == nil {
+		return 0, nil
+	}
+
+	entitlements, err := s.billing.WorkspaceEntitlements(ctx, workspaceID)
+	if err != nil {
+		return 0, err
+	}
+	return entitlements.MaxSnapshots, nil
+}
+
+// UpdateRetentionPolicy replaces the retention policy of a workspace. It is
+// applied by the next cleanup run.
+func (s *SnapshotService) UpdateRetentionPolicy(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.UpdateSnapshotRetentionRequest,
+) (*models.SnapshotRetentionPolicy, error) {
+	if err := validateRetentionRequest(req); err != nil {
+		return nil, err
+	}
+
+	maxSnapshots, err := s.planSnapshotLimit(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if maxSnapshots > 0 && req.MaxCount > maxSnapshots {
+		return nil, fmt.Errorf("%w: the plan keeps at most %d snapshots", ErrPlanLimitReached, maxSnapshots)
+	}
+
+	policy := &models.SnapshotRetentionPolicy{
+		WorkspaceID: workspaceID,
+		MaxCount:    req.MaxCount,
+		MaxAgeDays:  req.MaxAgeDays,
+		KeepDaily:   req.KeepDaily,
+		KeepWeekly:  req.KeepWeekly,
+		UpdatedBy:   &userID,
+	}
+
+	if err := s.snapshotRepo.UpsertRetentionPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func validateRetentionRequest(req *models.UpdateSnapshotRetentionRequest) error {
+	if req.MaxCount < 1 || req.MaxCount > maxSnapshotRetentionCount {
+		return fmt.Errorf("max_count must be between 1 and %d", maxSnapshotRetentionCount)
+	}
+	if req.MaxAgeDays != nil && (*req.MaxAgeDays < 1 || *req.MaxAgeDays > maxSnapshotRetentionDays) {
+		return fmt.Errorf("max_age_days must be between 1 and %d", maxSnapshotRetentionDays)
+	}
+	if req.KeepDaily < 0 || req.KeepDaily > maxSnapshotKeepDaily {
+		return fmt.Errorf("keep_daily must be between 0 and %d", maxSnapshotKeepDaily)
+	}
+	if req.KeepWeekly < 0 || req.KeepWeekly > maxSnapshotKeepWeekly {
+		return fmt.Errorf("keep_weekly must be between 0 and %d", maxSnapshotKeepWeekly)
+	}
+	return nil
+}
+
+// ApplyRetention deletes the snapshots of a workspace its retention policy
+// no longer keeps and returns how many were deleted
+func (s *SnapshotService) ApplyRetention(ctx context.Context, workspaceID uuid.UUID) (int, error) {
+	policy, err := s.GetRetentionPolicy(ctx, workspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshots, err := s.snapshotRepo.GetSnapshotsForRetention(ctx, workspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := expiredSnapshots(policy, snapshots, time.Now())
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(expired))
+	for i := range expired {
+		ids[i] = expired[i].ID
+	}
+
+	keys, err := s.snapshotRepo.DeleteSnapshots(ctx, workspaceID, ids)
+	if err != nil {
+		return 0, err
+	}
+	s.removeSnapshotData(ctx, keys...)
+
+	for i := range expired {
+		s.emitSnapshotEvent(ctx, models.EventSnapshotDeleted, nil, &expired[i], models.SnapshotEventPayload{})
+	}
+
+	return len(expired), nil
+}
+
+// EnforceRetention applies the retention policy of every workspace that has
+// snapshots and returns how many snapshots were deleted. Failing workspaces
+// are logged and skipped.
+func (s *SnapshotService) EnforceRetention(ctx context.Context) (int, error) {
+	total := 0
+	after := uuid.Nil
+	for {
+		workspaceIDs, err := s.snapshotRepo.GetSnapshotWorkspaces(ctx, after, retentionWorkspaceBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		for _, workspaceID := range workspaceIDs {
+			deleted, err := s.ApplyRetention(ctx, workspaceID)
+			if err != nil {
+				hlog.CtxErrorf(ctx, "Failed to apply snapshot retention to workspace %s: %v", workspaceID, err)
+				continue
+			}
+			total += deleted
+		}
+
+		if len(workspaceIDs) < retentionWorkspaceBatchSize {
+			return total, nil
+		}
+		after = workspaceIDs[len(workspaceIDs)-1]
+	}
+}
+
+// expiredSnapshots returns the snapshots the policy doesn't keep.
+// Snapshots must be ordered newest first. Pinned snapshots don't count
+// towards max_count, and the newest snapshot is always kept so the board
+// can still be restored.
+func expiredSnapshots(
+	policy *models.SnapshotRetentionPolicy,
+	snapshots []models.CanvasSnapshot,
+	now time.Time,
+) []models.CanvasSnapshot {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	keep := map[uuid.UUID]bool{snapshots[0].ID: true}
+
+	var cutoff time.Time
+	if policy.MaxAgeDays != nil {
+		cutoff = now.AddDate(0, 0, -*policy.MaxAgeDays)
+	}
+
+	recent := 0
+	for i := range snapshots {
+		if snapshots[i].Pinned {
+			keep[snapshots[i].ID] = true
+			continue
+		}
+		if recent < policy.MaxCount && (cutoff.IsZero() || snapshots[i].CreatedAt.After(cutoff)) {
+			keep[snapshots[i].ID] = true
+		}
+		recent++
+	}
+
+	days := make(map[string]bool, policy.KeepDaily)
+	for i := 0; i < policy.KeepDaily; i++ {
+		days[snapshotDay(now.AddDate(0, 0, -i))] = true
+	}
+	keepNewestPerPeriod(snapshots, days, snapshotDay, keep)
+
+	weeks := make(map[string]bool, policy.KeepWeekly)
+	for i := 0; i < policy.KeepWeekly; i++ {
+		weeks[snapshotWeek(now.AddDate(0, 0, -i*daysPerWeek))] = true
+	}
+	keepNewestPerPeriod(snapshots, weeks, snapshotWeek, keep)
+
+	var expired []models.CanvasSnapshot
+	for i := range snapshots {
+		if !keep[snapshots[i].ID] {
+			expired = append(expired, snapshots[i])
+		}
+	}
+	return expired
+}
+
+// keepNewestPerPeriod marks the newest snapshot of each of the given periods
+func keepNewestPerPeriod(
+	snapshots []models.CanvasSnapshot,
+	periods map[string]bool,
+	periodOf func(time.Time) string,
+	keep map[uuid.UUID]bool,
+) {
+	if len(periods) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool, len(periods))
+	for i := range snapshots {
+		period := periodOf(snapshots[i].CreatedAt)
+		if !periods[period] || seen[period] {
+			continue
+		}
+		seen[period] = true
+		keep[snapshots[i].ID] = true
+	}
+}
+
+// snapshotDay returns the UTC calendar day of t
+func snapshotDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// snapshotWeek returns the ISO week of t in UTC
+func snapshotWeek(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
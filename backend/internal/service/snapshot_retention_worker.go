@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const snapshotRetentionTimeout = 10 * time.Minute
+
+// SnapshotRetentionWorker periodically applies the retention policies of all
+// workspaces, so snapshots also expire on boards nobody edits anymore
+type SnapshotRetentionWorker struct {
+	snapshotService *SnapshotService
+	done            chan struct{}
+	trigger         chan struct{}
+	interval        time.Duration
+}
+
+// NewSnapshotRetentionWorker creates and starts a new snapshot retention worker
+func NewSnapshotRetentionWorker(snapshotService *SnapshotService, interval time.Duration) (*SnapshotRetentionWorker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	worker := &SnapshotRetentionWorker{
+		snapshotService: snapshotService,
+		done:            make(chan struct{}),
+		trigger:         make(chan struct{}, 1),
+		interval:        interval,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the retention worker
+func (w *SnapshotRetentionWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger applies retention now instead of at the next tick. It returns
+// false if a run is already pending.
+func (w *SnapshotRetentionWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// run applies retention on every tick until the worker is closed
+func (w *SnapshotRetentionWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.cleanup()
+		case <-w.trigger:
+			w.cleanup()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *SnapshotRetentionWorker) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotRetentionTimeout)
+	defer cancel()
+
+	deleted, err := w.snapshotService.EnforceRetention(ctx)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to apply snapshot retention: %v", err)
+	}
+
+	if deleted > 0 {
+		hlog.CtxInfof(ctx, "Deleted %d expired snapshots", deleted)
+	}
+}
@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -12,37 +18,85 @@ import (
 )
 
 const (
-	MaxSnapshotsPerWorkspace = 100 // Keep only the latest 100 snapshots
+	maxSnapshotNameLength = 255
+	maxSnapshotTags       = 20
+	maxSnapshotTagLength  = 50
 )
 
+// snapshotTagPattern allows tags such as "sprint-12-final" or "v1.2"
+var snapshotTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
 type SnapshotService struct {
 	snapshotRepo  *repository.SnapshotRepository
 	canvasRepo    *repository.CanvasRepository
 	workspaceRepo *repository.WorkspaceRepository
+	cacheService  *CanvasCacheService
+	rooms         RoomBroadcaster
+	assetService  *AssetService
+	events        *EventPublisher
+	storage       ObjectStorage
+	billing       *BillingService
 }
 
 func NewSnapshotService(
 	snapshotRepo *repository.SnapshotRepository,
 	canvasRepo *repository.CanvasRepository,
 	workspaceRepo *repository.WorkspaceRepository,
+	cacheService *CanvasCacheService,
+	rooms RoomBroadcaster,
+	assetService *AssetService,
+	events *EventPublisher,
+	storage ObjectStorage,
+	billing *BillingService,
 ) *SnapshotService {
 	return &SnapshotService{
 		snapshotRepo:  snapshotRepo,
 		canvasRepo:    canvasRepo,
 		workspaceRepo: workspaceRepo,
+		cacheService:  cacheService,
+		rooms:         rooms,
+		assetService:  assetService,
+		events:        events,
+		storage:       storage,
+		billing:       billing,
 	}
 }
 
-// CreateSnapshot creates a new snapshot of the current canvas state
+// CreateSnapshot creates a new snapshot of the current canvas state. When the
+// board has not changed since the latest snapshot and the request carries no
+// name, tags or pin, the latest snapshot is returned instead and created is false.
 func (s *SnapshotService) CreateSnapshot(
 	ctx context.Context,
 	workspaceID, userID uuid.UUID,
-	description *string,
-) (*models.CanvasSnapshot, error) {
+	req *models.CreateSnapshotRequest,
+) (snapshot *models.CanvasSnapshot, created bool, err error) {
+	name, err := normalizeSnapshotName(req.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	tags, err := normalizeSnapshotTags(req.Tags)
+	if err != nil {
+		return nil, false, err
+	}
+
 	// Get all current elements
 	elements, err := s.canvasRepo.GetElementsByWorkspace(ctx, workspaceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get workspace elements: %w", err)
+		return nil, false, fmt.Errorf("failed to get workspace elements: %w", err)
+	}
+
+	contentHash, err := snapshotContentHash(elements)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Labelled snapshots are always created, so users can mark a state that
+	// matches the previous snapshot
+	if name == nil && len(tags) == 0 && !req.Pinned {
+		latest, latestErr := s.snapshotRepo.GetLatestSnapshot(ctx, workspaceID)
+		if latestErr == nil && latest.ContentHash != nil && *latest.ContentHash == contentHash {
+			return latest, false, nil
+		}
 	}
 
 	// Serialize elements to snapshot data
@@ -70,23 +124,35 @@ func (s *SnapshotService) CreateSnapshot(
 	}
 
 	// Create snapshot
-	snapshot := &models.CanvasSnapshot{
+	snapshot = &models.CanvasSnapshot{
 		ID:           uuid.New(),
 		WorkspaceID:  workspaceID,
-		Description:  description,
+		Name:         name,
+		Description:  req.Description,
+		Tags:         tags,
+		Pinned:       req.Pinned,
+		ContentHash:  &contentHash,
 		SnapshotData: snapshotData,
 		ElementCount: len(elements),
 		CreatedBy:    userID,
 	}
 
+	// The payload goes to object storage, Postgres only keeps metadata
+	if err := s.storeSnapshotData(ctx, snapshot); err != nil {
+		return nil, false, err
+	}
+
 	if err := s.snapshotRepo.CreateSnapshot(ctx, snapshot); err != nil {
-		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+		s.removeSnapshotData(ctx, *snapshot.StorageKey)
+		return nil, false, fmt.Errorf("failed to create snapshot: %w", err)
 	}
 
+	s.emitSnapshotEvent(ctx, models.EventSnapshotCreated, &userID, snapshot, models.SnapshotEventPayload{})
+
 	// Cleanup old snapshots
 	go s.cleanupOldSnapshots(context.Background(), workspaceID)
 
-	return snapshot, nil
+	return snapshot, true, nil
 }
 
 // GetSnapshot retrieves a snapshot by ID
@@ -96,6 +162,10 @@ func (s *SnapshotService) GetSnapshot(ctx context.Context, id uuid.UUID) (*model
 		return nil, fmt.Errorf("failed to get snapshot: %w", err)
 	}
 
+	if err := s.loadSnapshotData(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
 	return snapshot, nil
 }
 
@@ -106,6 +176,10 @@ func (s *SnapshotService) GetSnapshotByVersion(ctx context.Context, workspaceID
 		return nil, fmt.Errorf("failed to get snapshot: %w", err)
 	}
 
+	if err := s.loadSnapshotData(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
 	return snapshot, nil
 }
 
@@ -114,21 +188,15 @@ const (
 	maxSnapshotLimit     = 100
 )
 
-// ListSnapshots retrieves all snapshots for a workspace
+// ListSnapshots retrieves snapshots of a workspace, optionally filtered by tag or pinned flag
 func (s *SnapshotService) ListSnapshots(
 	ctx context.Context,
 	workspaceID uuid.UUID,
-	limit, offset int,
+	filter models.SnapshotListFilter,
 ) ([]models.CanvasSnapshot, int, error) {
-	// Set default limit
-	if limit <= 0 {
-		limit = defaultSnapshotLimit
-	}
-	if limit > maxSnapshotLimit {
-		limit = maxSnapshotLimit
-	}
+	filter = normalizeSnapshotFilter(filter)
 
-	snapshots, total, err := s.snapshotRepo.ListSnapshots(ctx, workspaceID, limit, offset)
+	snapshots, total, err := s.snapshotRepo.ListSnapshots(ctx, workspaceID, filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
 	}
@@ -136,6 +204,37 @@ func (s *SnapshotService) ListSnapshots(
 	return snapshots, total, nil
 }
 
+// ListSnapshotsByWorkspaces retrieves a page of snapshots of each of several
+// workspaces with the same filter
+func (s *SnapshotService) ListSnapshotsByWorkspaces(
+	ctx context.Context,
+	workspaceIDs []uuid.UUID,
+	filter models.SnapshotListFilter,
+) (map[uuid.UUID]*models.SnapshotPage, error) {
+	pages, err := s.snapshotRepo.ListSnapshotsByWorkspaces(ctx, workspaceIDs, normalizeSnapshotFilter(filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	return pages, nil
+}
+
+// normalizeSnapshotFilter applies the default and maximum page size and
+// lowercases the tag
+func normalizeSnapshotFilter(filter models.SnapshotListFilter) models.SnapshotListFilter {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSnapshotLimit
+	}
+	if filter.Limit > maxSnapshotLimit {
+		filter.Limit = maxSnapshotLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Tag = strings.ToLower(strings.TrimSpace(filter.Tag))
+	return filter
+}
+
 // RestoreSnapshot restores the canvas to a specific snapshot version
 func (s *SnapshotService) RestoreSnapshot(
 	ctx context.Context,
@@ -152,79 +251,260 @@ func (s *SnapshotService) RestoreSnapshot(
 		return fmt.Errorf("snapshot does not belong to workspace")
 	}
 
-	// Create backup before restoring
-	if err := s.createBackupSnapshot(ctx, workspaceID, userID, snapshot.Version); err != nil {
+	// Parse first so an unreadable snapshot leaves the board untouched
+	restoredElements, err := s.readSnapshotElements(ctx, workspaceID, userID, snapshot, nil)
+	if err != nil {
 		return err
 	}
 
-	// Delete current elements
-	if err := s.deleteCurrentElements(ctx, workspaceID); err != nil {
+	// Create backup before restoring
+	backup, err := s.createBackupSnapshot(ctx, workspaceID, userID, snapshot.Version)
+	if err != nil {
 		return err
 	}
 
-	// Restore elements from snapshot
-	return s.restoreElementsFromSnapshot(ctx, workspaceID, userID, snapshot)
-}
+	// Replace the board atomically, a failure keeps the current elements
+	if err := s.canvasRepo.ReplaceWorkspaceElements(ctx, workspaceID, restoredElements); err != nil {
+		return fmt.Errorf("failed to restore elements: %w", err)
+	}
 
-func (s *SnapshotService) createBackupSnapshot(ctx context.Context, workspaceID, userID uuid.UUID, version int) error {
-	desc := fmt.Sprintf("Auto-backup before restoring to version %d", version)
-	if _, err := s.CreateSnapshot(ctx, workspaceID, userID, &desc); err != nil {
-		return fmt.Errorf("failed to create backup snapshot: %w", err)
+	if s.cacheService != nil {
+		_ = s.cacheService.InvalidateWorkspaceElements(ctx, workspaceID)
+	}
+
+	// Connected clients must drop their local state and reload the board
+	if s.rooms != nil {
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypeBoardReloaded,
+			UserID:    userID,
+			Timestamp: time.Now(),
+			Payload: models.BoardReloadedPayload{
+				SnapshotID:       snapshot.ID,
+				Version:          snapshot.Version,
+				BackupSnapshotID: backup.ID,
+				ElementCount:     len(restoredElements),
+			},
+		}, uuid.Nil)
 	}
+
+	s.emitSnapshotEvent(ctx, models.EventSnapshotRestored, &userID, snapshot, models.SnapshotEventPayload{
+		Mode:             models.SnapshotRestoreBoard,
+		BackupSnapshotID: &backup.ID,
+		ElementCount:     len(restoredElements),
+	})
+
 	return nil
 }
 
-func (s *SnapshotService) deleteCurrentElements(ctx context.Context, workspaceID uuid.UUID) error {
-	currentElements, err := s.canvasRepo.GetElementsByWorkspace(ctx, workspaceID)
+// RestoreElements copies some elements of a snapshot back into the live board
+// as new elements, leaving everything else untouched. Elements that are not in
+// the snapshot are ignored.
+func (s *SnapshotService) RestoreElements(
+	ctx context.Context,
+	workspaceID, userID, snapshotID uuid.UUID,
+	elementIDs []uuid.UUID,
+) ([]models.CanvasElement, error) {
+	if len(elementIDs) == 0 {
+		return nil, fmt.Errorf("no elements to restore")
+	}
+	if len(elementIDs) > maxBatchSize {
+		return nil, fmt.Errorf("cannot restore more than %d elements at once", maxBatchSize)
+	}
+
+	snapshot, err := s.snapshotRepo.GetSnapshotByID(ctx, snapshotID)
 	if err != nil {
-		return fmt.Errorf("failed to get current elements: %w", err)
+		return nil, fmt.Errorf("snapshot not found: %w", err)
 	}
 
-	if len(currentElements) > 0 {
-		ids := make([]uuid.UUID, len(currentElements))
-		for i := range currentElements {
-			ids[i] = currentElements[i].ID
+	if snapshot.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("snapshot does not belong to workspace")
+	}
+
+	only := make(map[uuid.UUID]bool, len(elementIDs))
+	for _, id := range elementIDs {
+		only[id] = true
+	}
+
+	elements, err := s.readSnapshotElements(ctx, workspaceID, userID, snapshot, only)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("elements not found in snapshot")
+	}
+
+	// Keep a parent outside the selection only if it is still on the board
+	restored := make(map[uuid.UUID]bool, len(elements))
+	for i := range elements {
+		restored[elements[i].ID] = true
+	}
+	for i := range elements {
+		parentID := elements[i].ParentID
+		if parentID == nil || restored[*parentID] {
+			continue
 		}
-		if err := s.canvasRepo.BatchDeleteElements(ctx, ids); err != nil {
-			return fmt.Errorf("failed to delete current elements: %w", err)
+		parent, err := s.canvasRepo.GetElementByID(ctx, *parentID)
+		if err != nil || parent.WorkspaceID != workspaceID {
+			elements[i].ParentID = nil
 		}
 	}
-	return nil
+
+	if err := s.canvasRepo.BatchCreateElements(ctx, elements); err != nil {
+		return nil, fmt.Errorf("failed to restore elements: %w", err)
+	}
+
+	if s.cacheService != nil {
+		_ = s.cacheService.InvalidateWorkspaceElements(ctx, workspaceID)
+	}
+
+	if s.rooms != nil {
+		responses := make([]models.ElementResponse, len(elements))
+		for i := range elements {
+			responses[i] = elements[i].ToResponse()
+		}
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypeElementsRestored,
+			UserID:    userID,
+			Timestamp: time.Now(),
+			Payload: models.ElementsRestoredPayload{
+				SnapshotID: snapshot.ID,
+				Version:    snapshot.Version,
+				Elements:   responses,
+			},
+		}, uuid.Nil)
+	}
+
+	s.emitSnapshotEvent(ctx, models.EventSnapshotRestored, &userID, snapshot, models.SnapshotEventPayload{
+		Mode:         models.SnapshotRestoreElements,
+		ElementCount: len(elements),
+	})
+
+	return elements, nil
 }
 
-func (s *SnapshotService) restoreElementsFromSnapshot(
+func (s *SnapshotService) createBackupSnapshot(
 	ctx context.Context,
 	workspaceID, userID uuid.UUID,
-	snapshot *models.CanvasSnapshot,
-) error {
-	elementsData, ok := snapshot.SnapshotData["elements"].([]interface{})
-	if !ok {
-		return fmt.Errorf("invalid snapshot data format")
+	version int,
+) (*models.CanvasSnapshot, error) {
+	desc := fmt.Sprintf("Auto-backup before restoring to version %d", version)
+	backup, _, err := s.CreateSnapshot(ctx, workspaceID, userID, &models.CreateSnapshotRequest{Description: &desc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup snapshot: %w", err)
 	}
+	return backup, nil
+}
 
+// readSnapshotElements parses the elements of a snapshot into new elements
+// of the workspace. Malformed elements are skipped. When only is set, just the
+// elements with those original IDs are read and parents outside the selection
+// keep their original ID, otherwise such parents are cleared.
+func (s *SnapshotService) readSnapshotElements(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	snapshot *models.CanvasSnapshot,
+	only map[uuid.UUID]bool,
+) ([]models.CanvasElement, error) {
 	var restoredElements []models.CanvasElement
-	for _, elemData := range elementsData {
+	newIDs := make(map[uuid.UUID]uuid.UUID)
+	err := s.forEachSnapshotElement(ctx, snapshot, func(elemData map[string]interface{}) error {
+		oldID, idErr := uuid.Parse(fmt.Sprintf("%v", elemData["id"]))
+		if only != nil && (idErr != nil || !only[oldID]) {
+			return nil
+		}
+
 		element, err := s.parseSnapshotElement(elemData, workspaceID, userID)
 		if err != nil {
-			continue
+			return nil
+		}
+		if idErr == nil {
+			newIDs[oldID] = element.ID
 		}
 		restoredElements = append(restoredElements, element)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if len(restoredElements) > 0 {
-		if err := s.canvasRepo.BatchCreateElements(ctx, restoredElements); err != nil {
-			return fmt.Errorf("failed to restore elements: %w", err)
+	// Elements get new IDs, so groups and connectors must point at the new ones
+	for i := range restoredElements {
+		remapElementReferences(restoredElements[i].ElementData, newIDs)
+		if restoredElements[i].ParentID == nil {
+			continue
+		}
+		if parentID, ok := newIDs[*restoredElements[i].ParentID]; ok {
+			restoredElements[i].ParentID = &parentID
+		} else if only == nil {
+			restoredElements[i].ParentID = nil
+		}
+	}
+
+	return orderParentsFirst(restoredElements), nil
+}
+
+// remapElementReferences rewrites the connector ends and group children stored
+// in element data to the new element IDs
+func remapElementReferences(data models.ElementData, newIDs map[uuid.UUID]uuid.UUID) {
+	remap := func(value interface{}) interface{} {
+		raw, ok := value.(string)
+		if !ok {
+			return value
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return value
+		}
+		if newID, ok := newIDs[id]; ok {
+			return newID.String()
+		}
+		return value
+	}
+
+	for _, key := range []string{"start_element_id", "end_element_id"} {
+		if value, ok := data[key]; ok {
+			data[key] = remap(value)
+		}
+	}
+
+	if children, ok := data["child_ids"].([]interface{}); ok {
+		for i := range children {
+			children[i] = remap(children[i])
 		}
 	}
-	return nil
 }
 
-func (s *SnapshotService) parseSnapshotElement(elemData interface{}, workspaceID, userID uuid.UUID) (models.CanvasElement, error) {
-	elemMap, ok := elemData.(map[string]interface{})
-	if !ok {
-		return models.CanvasElement{}, fmt.Errorf("invalid element format")
+// orderParentsFirst sorts elements so every parent is inserted before its
+// children, as required by the parent_id foreign key
+func orderParentsFirst(elements []models.CanvasElement) []models.CanvasElement {
+	parents := make(map[uuid.UUID]*uuid.UUID, len(elements))
+	for i := range elements {
+		parents[elements[i].ID] = elements[i].ParentID
+	}
+
+	depth := func(id uuid.UUID) int {
+		d := 0
+		for parent := parents[id]; parent != nil && d < len(elements); parent = parents[*parent] {
+			d++
+		}
+		return d
 	}
 
+	depths := make(map[uuid.UUID]int, len(elements))
+	for i := range elements {
+		depths[elements[i].ID] = depth(elements[i].ID)
+	}
+
+	sort.SliceStable(elements, func(i, j int) bool {
+		return depths[elements[i].ID] < depths[elements[j].ID]
+	})
+	return elements
+}
+
+func (s *SnapshotService) parseSnapshotElement(
+	elemMap map[string]interface{},
+	workspaceID, userID uuid.UUID,
+) (models.CanvasElement, error) {
 	elementDataJSON, err := json.Marshal(elemMap["element_data"])
 	if err != nil {
 		return models.CanvasElement{}, err
@@ -262,8 +542,54 @@ func (s *SnapshotService) parseSnapshotElement(elemData interface{}, workspaceID
 	}, nil
 }
 
+// UpdateSnapshot renames, tags, pins or unpins a snapshot
+func (s *SnapshotService) UpdateSnapshot(
+	ctx context.Context,
+	workspaceID, snapshotID uuid.UUID,
+	req *models.UpdateSnapshotRequest,
+) (*models.CanvasSnapshot, error) {
+	snapshot, err := s.snapshotRepo.GetSnapshotByID(ctx, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not found: %w", err)
+	}
+
+	if snapshot.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("snapshot does not belong to workspace")
+	}
+
+	// Apply updates
+	if req.Name != nil {
+		name, err := normalizeSnapshotName(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Name = name
+	}
+	if req.Description != nil {
+		snapshot.Description = req.Description
+	}
+	if req.Tags != nil {
+		tags, err := normalizeSnapshotTags(req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Tags = tags
+	}
+	if req.Pinned != nil {
+		snapshot.Pinned = *req.Pinned
+	}
+
+	if err := s.snapshotRepo.UpdateSnapshot(ctx, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to update snapshot: %w", err)
+	}
+
+	// The payload is not part of the metadata response
+	snapshot.SnapshotData = nil
+	return snapshot, nil
+}
+
 // DeleteSnapshot deletes a specific snapshot
-func (s *SnapshotService) DeleteSnapshot(ctx context.Context, workspaceID, snapshotID uuid.UUID) error {
+func (s *SnapshotService) DeleteSnapshot(ctx context.Context, workspaceID, userID, snapshotID uuid.UUID) error {
 	// Verify snapshot belongs to workspace
 	snapshot, err := s.snapshotRepo.GetSnapshotByID(ctx, snapshotID)
 	if err != nil {
@@ -274,6 +600,10 @@ func (s *SnapshotService) DeleteSnapshot(ctx context.Context, workspaceID, snaps
 		return fmt.Errorf("snapshot does not belong to workspace")
 	}
 
+	if snapshot.Pinned {
+		return fmt.Errorf("snapshot is pinned, unpin it before deleting")
+	}
+
 	// Don't allow deleting the only snapshot
 	count, err := s.snapshotRepo.GetSnapshotCount(ctx, workspaceID)
 	if err != nil {
@@ -288,21 +618,98 @@ func (s *SnapshotService) DeleteSnapshot(ctx context.Context, workspaceID, snaps
 		return fmt.Errorf("failed to delete snapshot: %w", err)
 	}
 
+	if snapshot.StorageKey != nil {
+		s.removeSnapshotData(ctx, *snapshot.StorageKey)
+	}
+
+	s.emitSnapshotEvent(ctx, models.EventSnapshotDeleted, &userID, snapshot, models.SnapshotEventPayload{})
+
 	return nil
 }
 
 // Auto-create snapshot on significant changes (helper for future use)
 func (s *SnapshotService) AutoCreateSnapshot(ctx context.Context, workspaceID, userID uuid.UUID, changeDescription string) error {
 	description := fmt.Sprintf("Auto: %s", changeDescription)
-	_, err := s.CreateSnapshot(ctx, workspaceID, userID, &description)
+	_, _, err := s.CreateSnapshot(ctx, workspaceID, userID, &models.CreateSnapshotRequest{Description: &description})
 	return err
 }
 
 // Private helper functions
 
+// snapshotContentHash hashes what a snapshot restores: element IDs, types,
+// data, order and grouping. Timestamps are left out.
+func snapshotContentHash(elements []models.CanvasElement) (string, error) {
+	type hashedElement struct {
+		ParentID    *uuid.UUID         `json:"parent_id"`
+		ElementData models.ElementData `json:"element_data"`
+		ElementType models.ElementType `json:"element_type"`
+		ZIndex      int                `json:"z_index"`
+		ID          uuid.UUID          `json:"id"`
+	}
+
+	hashed := make([]hashedElement, len(elements))
+	for i := range elements {
+		hashed[i] = hashedElement{
+			ID:          elements[i].ID,
+			ElementType: elements[i].ElementType,
+			ElementData: elements[i].ElementData,
+			ZIndex:      elements[i].ZIndex,
+			ParentID:    elements[i].ParentID,
+		}
+	}
+	sort.Slice(hashed, func(i, j int) bool {
+		return hashed[i].ID.String() < hashed[j].ID.String()
+	})
+
+	// Map keys are encoded sorted, so equal boards produce equal output
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(hashed); err != nil {
+		return "", fmt.Errorf("failed to hash snapshot content: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// normalizeSnapshotName trims a snapshot name, an empty name clears it
+func normalizeSnapshotName(name *string) (*string, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	trimmed := strings.TrimSpace(*name)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if len(trimmed) > maxSnapshotNameLength {
+		return nil, fmt.Errorf("snapshot name must be at most %d characters", maxSnapshotNameLength)
+	}
+	return &trimmed, nil
+}
+
+// normalizeSnapshotTags lowercases, validates and deduplicates tags
+func normalizeSnapshotTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxSnapshotTagLength || !snapshotTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to %d letters, digits, dots, dashes or underscores", tag, maxSnapshotTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxSnapshotTags {
+		return nil, fmt.Errorf("a snapshot can have at most %d tags", maxSnapshotTags)
+	}
+	return normalized, nil
+}
+
 func (s *SnapshotService) cleanupOldSnapshots(ctx context.Context, workspaceID uuid.UUID) {
-	// Keep only the latest N snapshots
-	_ = s.snapshotRepo.DeleteOldSnapshots(ctx, workspaceID, MaxSnapshotsPerWorkspace)
-	// Errors are intentionally ignored - cleanup is best-effort
-	// In production, use proper logging
+	// Errors are intentionally ignored - cleanup is best-effort and the
+	// retention worker retries later
+	_, _ = s.ApplyRetention(ctx, workspaceID)
 }
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	snapshotContentType = "application/gzip"
+
+	// inlineSnapshotBatchSize is how many legacy snapshots are moved at a time
+	inlineSnapshotBatchSize = 50
+)
+
+// snapshotObjectKey builds the object key of a snapshot payload
+func snapshotObjectKey(workspaceID, snapshotID uuid.UUID) string {
+	return fmt.Sprintf("snapshots/%s/%s.json.gz", workspaceID, snapshotID)
+}
+
+// storeSnapshotData uploads the snapshot payload as gzipped JSON and records
+// its location on the snapshot
+func (s *SnapshotService) storeSnapshotData(ctx context.Context, snapshot *models.CanvasSnapshot) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(snapshot.SnapshotData); err != nil {
+		return fmt.Errorf("failed to encode snapshot data: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot data: %w", err)
+	}
+
+	key := snapshotObjectKey(snapshot.WorkspaceID, snapshot.ID)
+	size := int64(buf.Len())
+	if err := s.storage.Put(ctx, key, &buf, size, snapshotContentType); err != nil {
+		return fmt.Errorf("failed to store snapshot data: %w", err)
+	}
+
+	snapshot.StorageKey = &key
+	snapshot.CompressedSize = size
+	return nil
+}
+
+// openSnapshotData returns a reader over the decompressed JSON payload
+func (s *SnapshotService) openSnapshotData(ctx context.Context, snapshot *models.CanvasSnapshot) (io.ReadCloser, error) {
+	object, err := s.storage.Get(ctx, *snapshot.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot data: %w", err)
+	}
+
+	gz, err := gzip.NewReader(object)
+	if err != nil {
+		object.Close()
+		return nil, fmt.Errorf("failed to decompress snapshot data: %w", err)
+	}
+
+	return &snapshotDataReader{Reader: gz, object: object}, nil
+}
+
+// snapshotDataReader closes both the gzip stream and the underlying object
+type snapshotDataReader struct {
+	*gzip.Reader
+	object io.Closer
+}
+
+func (r *snapshotDataReader) Close() error {
+	_ = r.Reader.Close()
+	return r.object.Close()
+}
+
+// loadSnapshotData fills SnapshotData of a snapshot stored in object storage
+func (s *SnapshotService) loadSnapshotData(ctx context.Context, snapshot *models.CanvasSnapshot) error {
+	if snapshot.StorageKey == nil {
+		return nil
+	}
+
+	data, err := s.openSnapshotData(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	var snapshotData models.ElementData
+	if err := json.NewDecoder(data).Decode(&snapshotData); err != nil {
+		return fmt.Errorf("failed to decode snapshot data: %w", err)
+	}
+
+	snapshot.SnapshotData = snapshotData
+	return nil
+}
+
+// forEachSnapshotElement calls fn for every serialized element of a snapshot.
+// Stored payloads are decoded as a stream, so large boards are never held in
+// memory as a whole.
+func (s *SnapshotService) forEachSnapshotElement(
+	ctx context.Context,
+	snapshot *models.CanvasSnapshot,
+	fn func(elem map[string]interface{}) error,
+) error {
+	if snapshot.StorageKey == nil {
+		elementsData, ok := snapshot.SnapshotData["elements"].([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid snapshot data format")
+		}
+		for _, elemData := range elementsData {
+			elemMap, ok := elemData.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := fn(elemMap); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	data, err := s.openSnapshotData(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	return decodeSnapshotElements(json.NewDecoder(data), fn)
+}
+
+// decodeSnapshotElements walks the top-level payload object and decodes the
+// "elements" array one element at a time
+func decodeSnapshotElements(dec *json.Decoder, fn func(elem map[string]interface{}) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	found := false
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to decode snapshot data: %w", err)
+		}
+
+		if key, _ := token.(string); key != "elements" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fmt.Errorf("failed to decode snapshot data: %w", err)
+			}
+			continue
+		}
+
+		found = true
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var elem map[string]interface{}
+			if err := dec.Decode(&elem); err != nil {
+				return fmt.Errorf("failed to decode snapshot element: %w", err)
+			}
+			if err := fn(elem); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("invalid snapshot data format")
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode snapshot data: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("invalid snapshot data format")
+	}
+	return nil
+}
+
+// removeSnapshotData deletes stored payloads, failures only leave garbage behind
+func (s *SnapshotService) removeSnapshotData(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.storage.Remove(ctx, key); err != nil {
+			hlog.CtxErrorf(ctx, "Failed to remove snapshot data %s: %v", key, err)
+		}
+	}
+}
+
+// MigrateInlineSnapshots moves payloads of snapshots created before object
+// storage was used out of Postgres and returns how many were moved
+func (s *SnapshotService) MigrateInlineSnapshots(ctx context.Context) (int, error) {
+	moved := 0
+	for {
+		snapshots, err := s.snapshotRepo.GetInlineSnapshots(ctx, inlineSnapshotBatchSize)
+		if err != nil {
+			return moved, err
+		}
+		if len(snapshots) == 0 {
+			return moved, nil
+		}
+
+		for i := range snapshots {
+			if err := s.storeSnapshotData(ctx, &snapshots[i]); err != nil {
+				return moved, err
+			}
+			err := s.snapshotRepo.SetSnapshotStorage(ctx, snapshots[i].ID, *snapshots[i].StorageKey, snapshots[i].CompressedSize)
+			if err != nil {
+				s.removeSnapshotData(ctx, *snapshots[i].StorageKey)
+				return moved, err
+			}
+			moved++
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	unsplashAPIURL = "https://api.unsplash.com"
+	giphyAPIURL    = "https://api.giphy.com/v1"
+
+	stockAPITimeout     = 10 * time.Second
+	stockDefaultPerPage = 20
+	stockMaxPerPage     = 50
+	stockMaxQueryLength = 200
+	stockMaxErrorBody   = 512
+)
+
+var (
+	// ErrUnknownStockProvider is returned for providers we don't integrate with
+	ErrUnknownStockProvider = errors.New("unknown stock media provider")
+	// ErrStockProviderDisabled is returned when a provider has no API key configured
+	ErrStockProviderDisabled = errors.New("stock media provider is not configured")
+)
+
+// StockMediaService proxies searches to Unsplash and GIPHY so API keys stay
+// on the server, and imports chosen items through the from-url pipeline
+type StockMediaService struct {
+	assetService *AssetService
+	httpClient   *http.Client
+	cfg          *config.IntegrationsConfig
+}
+
+// NewStockMediaService creates a new stock media service
+func NewStockMediaService(cfg *config.IntegrationsConfig, assetService *AssetService) *StockMediaService {
+	return &StockMediaService{
+		assetService: assetService,
+		httpClient:   &http.Client{Timeout: stockAPITimeout},
+		cfg:          cfg,
+	}
+}
+
+// Search queries a provider and returns one page of results
+func (s *StockMediaService) Search(
+	ctx context.Context,
+	provider, query string,
+	page, perPage int,
+) (*models.StockSearchResponse, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if len(query) > stockMaxQueryLength {
+		return nil, fmt.Errorf("query is too long")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > stockMaxPerPage {
+		perPage = stockDefaultPerPage
+	}
+
+	if err := s.checkProvider(provider); err != nil {
+		return nil, err
+	}
+
+	var result *models.StockSearchResponse
+	var err error
+	switch provider {
+	case models.StockProviderUnsplash:
+		result, err = s.searchUnsplash(ctx, query, page, perPage)
+	default:
+		result, err = s.searchGiphy(ctx, query, page, perPage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Page = page
+	result.PerPage = perPage
+	return result, nil
+}
+
+// Insert imports a stock media item into a workspace with its attribution.
+// The item is looked up by ID so clients can't use this to import arbitrary URLs.
+func (s *StockMediaService) Insert(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	provider, itemID string,
+) (*models.Asset, error) {
+	if itemID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if err := s.checkProvider(provider); err != nil {
+		return nil, err
+	}
+
+	var downloadURL, filename string
+	var attribution *models.AssetAttribution
+	switch provider {
+	case models.StockProviderUnsplash:
+		photo, err := s.getUnsplashPhoto(ctx, itemID)
+		if err != nil {
+			return nil, err
+		}
+		// Unsplash guidelines require reporting every download
+		s.trackUnsplashDownload(ctx, photo.Links.DownloadLocation)
+
+		downloadURL = photo.URLs.Regular
+		filename = "unsplash-" + photo.ID + ".jpg"
+		attribution = s.unsplashAttribution(photo)
+	default:
+		gif, err := s.getGiphyGIF(ctx, itemID)
+		if err != nil {
+			return nil, err
+		}
+
+		downloadURL = gif.Images.Downsized.URL
+		filename = "giphy-" + gif.ID + ".gif"
+		attribution = giphyAttribution(gif)
+	}
+
+	if downloadURL == "" {
+		return nil, fmt.Errorf("item has no downloadable image")
+	}
+
+	return s.assetService.importRemoteAsset(ctx, workspaceID, userID, downloadURL, filename, attribution)
+}
+
+// checkProvider verifies that a provider is known and has an API key
+func (s *StockMediaService) checkProvider(provider string) error {
+	var key string
+	switch provider {
+	case models.StockProviderUnsplash:
+		key = s.cfg.Unsplash.AccessKey
+	case models.StockProviderGiphy:
+		key = s.cfg.Giphy.APIKey
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownStockProvider, provider)
+	}
+
+	if key == "" {
+		return fmt.Errorf("%w: %s", ErrStockProviderDisabled, provider)
+	}
+	return nil
+}
+
+// getJSON performs a GET request against a provider API and decodes the response
+func (s *StockMediaService) getJSON(ctx context.Context, endpoint string, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, stockMaxErrorBody))
+		return fmt.Errorf("provider responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode provider response: %w", err)
+	}
+
+	return nil
+}
+
+// Unsplash
+
+type unsplashUser struct {
+	Name  string `json:"name"`
+	Links struct {
+		HTML string `json:"html"`
+	} `json:"links"`
+}
+
+type unsplashPhoto struct {
+	ID             string       `json:"id"`
+	Description    string       `json:"description"`
+	AltDescription string       `json:"alt_description"`
+	User           unsplashUser `json:"user"`
+	URLs           struct {
+		Regular string `json:"regular"`
+		Small   string `json:"small"`
+	} `json:"urls"`
+	Links struct {
+		HTML             string `json:"html"`
+		DownloadLocation string `json:"download_location"`
+	} `json:"links"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (s *StockMediaService) unsplashHeader() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Client-ID "+s.cfg.Unsplash.AccessKey)
+	header.Set("Accept-Version", "v1")
+	return header
+}
+
+func (s *StockMediaService) searchUnsplash(ctx context.Context, query string, page, perPage int) (*models.StockSearchResponse, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("page", strconv.Itoa(page))
+	params.Set("per_page", strconv.Itoa(perPage))
+
+	var body struct {
+		Results []unsplashPhoto `json:"results"`
+		Total   int             `json:"total"`
+	}
+	if err := s.getJSON(ctx, unsplashAPIURL+"/search/photos?"+params.Encode(), s.unsplashHeader(), &body); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.StockMediaItem, 0, len(body.Results))
+	for i := range body.Results {
+		photo := &body.Results[i]
+		title := photo.Description
+		if title == "" {
+			title = photo.AltDescription
+		}
+		items = append(items, models.StockMediaItem{
+			ID:          photo.ID,
+			Provider:    models.StockProviderUnsplash,
+			Title:       title,
+			PreviewURL:  photo.URLs.Small,
+			Width:       photo.Width,
+			Height:      photo.Height,
+			Attribution: *s.unsplashAttribution(photo),
+		})
+	}
+
+	return &models.StockSearchResponse{Items: items, Total: body.Total}, nil
+}
+
+func (s *StockMediaService) getUnsplashPhoto(ctx context.Context, id string) (*unsplashPhoto, error) {
+	var photo unsplashPhoto
+	endpoint := unsplashAPIURL + "/photos/" + url.PathEscape(id)
+	if err := s.getJSON(ctx, endpoint, s.unsplashHeader(), &photo); err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// trackUnsplashDownload notifies Unsplash that a photo was used. Failures
+// are only logged, they must not block the import.
+func (s *StockMediaService) trackUnsplashDownload(ctx context.Context, downloadLocation string) {
+	if downloadLocation == "" {
+		return
+	}
+
+	var ignored struct{}
+	if err := s.getJSON(ctx, downloadLocation, s.unsplashHeader(), &ignored); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to track unsplash download: %v", err)
+	}
+}
+
+// unsplashAttribution links back to the photo and author with the referral
+// parameters required by the Unsplash API guidelines
+func (s *StockMediaService) unsplashAttribution(photo *unsplashPhoto) *models.AssetAttribution {
+	return &models.AssetAttribution{
+		Provider:   models.StockProviderUnsplash,
+		SourceID:   photo.ID,
+		SourceURL:  s.withReferral(photo.Links.HTML),
+		AuthorName: photo.User.Name,
+		AuthorURL:  s.withReferral(photo.User.Links.HTML),
+	}
+}
+
+func (s *StockMediaService) withReferral(link string) string {
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	params := u.Query()
+	params.Set("utm_source", s.cfg.Unsplash.AppName)
+	params.Set("utm_medium", "referral")
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+// GIPHY
+
+type giphyImage struct {
+	URL    string `json:"url"`
+	Width  string `json:"width"`
+	Height string `json:"height"`
+}
+
+type giphyGIF struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	User     *struct {
+		DisplayName string `json:"display_name"`
+		ProfileURL  string `json:"profile_url"`
+	} `json:"user"`
+	Images struct {
+		FixedWidth giphyImage `json:"fixed_width"`
+		Downsized  giphyImage `json:"downsized"`
+	} `json:"images"`
+}
+
+func (s *StockMediaService) searchGiphy(ctx context.Context, query string, page, perPage int) (*models.StockSearchResponse, error) {
+	params := url.Values{}
+	params.Set("api_key", s.cfg.Giphy.APIKey)
+	params.Set("q", query)
+	params.Set("limit", strconv.Itoa(perPage))
+	params.Set("offset", strconv.Itoa((page-1)*perPage))
+	if s.cfg.Giphy.Rating != "" {
+		params.Set("rating", s.cfg.Giphy.Rating)
+	}
+
+	var body struct {
+		Data       []giphyGIF `json:"data"`
+		Pagination struct {
+			TotalCount int `json:"total_count"`
+		} `json:"pagination"`
+	}
+	if err := s.getJSON(ctx, giphyAPIURL+"/gifs/search?"+params.Encode(), nil, &body); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.StockMediaItem, 0, len(body.Data))
+	for i := range body.Data {
+		gif := &body.Data[i]
+		width, _ := strconv.Atoi(gif.Images.Downsized.Width)
+		height, _ := strconv.Atoi(gif.Images.Downsized.Height)
+		items = append(items, models.StockMediaItem{
+			ID:          gif.ID,
+			Provider:    models.StockProviderGiphy,
+			Title:       gif.Title,
+			PreviewURL:  gif.Images.FixedWidth.URL,
+			Width:       width,
+			Height:      height,
+			Attribution: *giphyAttribution(gif),
+		})
+	}
+
+	return &models.StockSearchResponse{Items: items, Total: body.Pagination.TotalCount}, nil
+}
+
+func (s *StockMediaService) getGiphyGIF(ctx context.Context, id string) (*giphyGIF, error) {
+	params := url.Values{}
+	params.Set("api_key", s.cfg.Giphy.APIKey)
+
+	var body struct {
+		Data giphyGIF `json:"data"`
+	}
+	endpoint := giphyAPIURL + "/gifs/" + url.PathEscape(id) + "?" + params.Encode()
+	if err := s.getJSON(ctx, endpoint, nil, &body); err != nil {
+		return nil, err
+	}
+	return &body.Data, nil
+}
+
+func giphyAttribution(gif *giphyGIF) *models.AssetAttribution {
+	attribution := &models.AssetAttribution{
+		Provider:   models.StockProviderGiphy,
+		SourceID:   gif.ID,
+		SourceURL:  gif.URL,
+		AuthorName: gif.Username,
+	}
+	if gif.User != nil {
+		if gif.User.DisplayName != "" {
+			attribution.AuthorName = gif.User.DisplayName
+		}
+		attribution.AuthorURL = gif.User.ProfileURL
+	}
+	return attribution
+}
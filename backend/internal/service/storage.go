@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// Object storage providers
+const (
+	StorageProviderMinIO      = "minio"
+	StorageProviderS3         = "s3"
+	StorageProviderGCS        = "gcs"
+	StorageProviderFilesystem = "filesystem"
+)
+
+const (
+	// defaultAssetBucket is the bucket assets have always been stored in
+	defaultAssetBucket  = "hertz-board-assets"
+	defaultBackupBucket = "hertz-board-backups"
+	defaultExportBucket = "hertz-board-exports"
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	ContentType string
+	Size        int64
+}
+
+// ObjectStorage stores asset files under keys such as
+// "<workspace>/<yyyy>/<mm>/<uuid>.png"
+type ObjectStorage interface {
+	// Put stores an object, replacing any existing one with the same key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens an object for reading
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Stat returns the size and content type of an object
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+
+	// Copy duplicates an object server-side
+	Copy(ctx context.Context, srcKey, dstKey string) error
+
+	// Remove deletes an object. Removing a missing object is not an error.
+	Remove(ctx context.Context, key string) error
+
+	// PresignGet returns a time-limited download URL
+	PresignGet(ctx context.Context, key string, expiry time.Duration, cacheControl string) (string, error)
+
+	// PresignPut returns a time-limited URL the client can PUT the object
+	// to. The upload must be sent with contentType as its Content-Type.
+	PresignPut(ctx context.Context, key string, expiry time.Duration, contentType string) (string, error)
+
+	// ObjectURL returns the canonical URL stored in asset records
+	ObjectURL(key string) string
+
+	// ObjectKey extracts the key from a URL returned by ObjectURL
+	ObjectKey(objectURL string) string
+}
+
+// NewObjectStorage creates the asset storage backend selected by storage.provider config
+func NewObjectStorage(cfg *config.StorageConfig, minioCfg *config.MinIOConfig) (ObjectStorage, error) {
+	return newObjectStorage(cfg, minioCfg, bucketOrDefault(cfg.Bucket, defaultAssetBucket))
+}
+
+// NewBackupStorage creates the storage backend for snapshots and other
+// backups. It uses the same provider as assets with a separate bucket.
+func NewBackupStorage(cfg *config.StorageConfig, minioCfg *config.MinIOConfig) (ObjectStorage, error) {
+	bucket := cfg.BackupBucket
+	if bucket == "" && (cfg.Provider == "" || cfg.Provider == StorageProviderMinIO) {
+		bucket = minioCfg.BucketBackups
+	}
+	return newObjectStorage(cfg, minioCfg, bucketOrDefault(bucket, defaultBackupBucket))
+}
+
+// NewExportStorage creates the storage backend for reports generated for
+// download. It uses the same provider as assets with a separate bucket.
+func NewExportStorage(cfg *config.StorageConfig, minioCfg *config.MinIOConfig) (ObjectStorage, error) {
+	bucket := cfg.ExportBucket
+	if bucket == "" && (cfg.Provider == "" || cfg.Provider == StorageProviderMinIO) {
+		bucket = minioCfg.BucketExports
+	}
+	return newObjectStorage(cfg, minioCfg, bucketOrDefault(bucket, defaultExportBucket))
+}
+
+func newObjectStorage(cfg *config.StorageConfig, minioCfg *config.MinIOConfig, bucket string) (ObjectStorage, error) {
+	switch cfg.Provider {
+	case "", StorageProviderMinIO:
+		return NewS3Storage(&S3StorageOptions{
+			Endpoint:     minioCfg.Endpoint,
+			AccessKey:    minioCfg.AccessKey,
+			SecretKey:    minioCfg.SecretKey,
+			Bucket:       bucket,
+			UseSSL:       minioCfg.UseSSL,
+			CreateBucket: true,
+			PrivateACL:   true,
+		})
+	case StorageProviderS3, StorageProviderGCS:
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = defaultStorageEndpoint(cfg.Provider)
+		}
+		// GCS is accessed through its S3-compatible XML API with HMAC keys
+		return NewS3Storage(&S3StorageOptions{
+			Endpoint:  endpoint,
+			Region:    cfg.Region,
+			AccessKey: cfg.AccessKey,
+			SecretKey: cfg.SecretKey,
+			Bucket:    bucket,
+			UseSSL:    true,
+		})
+	case StorageProviderFilesystem:
+		return NewFilesystemStorage(cfg.Path, bucket, cfg.PublicURL, cfg.SigningKey)
+	default:
+		return nil, fmt.Errorf("unknown storage provider: %s", cfg.Provider)
+	}
+}
+
+func bucketOrDefault(bucket, fallback string) string {
+	if bucket == "" {
+		return fallback
+	}
+	return bucket
+}
+
+func defaultStorageEndpoint(provider string) string {
+	if provider == StorageProviderGCS {
+		return "storage.googleapis.com"
+	}
+	return "s3.amazonaws.com"
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	filesystemURLScheme = "file://"
+	filesystemMetaExt   = ".meta"
+	filesystemDirPerm   = 0o750
+	filesystemFilePerm  = 0o640
+
+	// FilesystemStorageRoute is where the API serves filesystem objects
+	FilesystemStorageRoute = "/api/v1/storage"
+)
+
+// ErrInvalidSignature is returned when a storage URL signature is missing,
+// wrong or expired
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// FilesystemStorage stores objects on local disk. Presigned URLs point at the
+// API, which verifies the HMAC signature and serves or accepts the file.
+type FilesystemStorage struct {
+	root       string
+	bucket     string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewFilesystemStorage creates the bucket directory under root
+func NewFilesystemStorage(root, bucket, publicURL, signingKey string) (*FilesystemStorage, error) {
+	if root == "" {
+		return nil, fmt.Errorf("storage path is required for filesystem storage")
+	}
+	if signingKey == "" {
+		return nil, fmt.Errorf("storage signing key is required for filesystem storage")
+	}
+
+	dir := filepath.Join(root, bucket)
+	if err := os.MkdirAll(dir, filesystemDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &FilesystemStorage{
+		root:       dir,
+		bucket:     bucket,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+// path resolves a key inside the bucket directory
+func (s *FilesystemStorage) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid object key")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes an object and its content type to disk
+func (s *FilesystemStorage) Put(_ context.Context, key string, r io.Reader, size int64, contentType string) error {
+	objectPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), filesystemDirPerm); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create object: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	src := r
+	if size >= 0 {
+		src = io.LimitReader(r, size+1)
+	}
+	written, err := io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("object size mismatch: expected %d bytes, got %d", size, written)
+	}
+
+	if err := os.WriteFile(objectPath+filesystemMetaExt, []byte(contentType), filesystemFilePerm); err != nil {
+		return fmt.Errorf("failed to write object metadata: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), objectPath); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	return nil
+}
+
+// Get opens an object for reading
+func (s *FilesystemStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	objectPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return file, nil
+}
+
+// Stat returns the size and content type of an object
+func (s *FilesystemStorage) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	objectPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	contentType := "application/octet-stream"
+	if meta, err := os.ReadFile(objectPath + filesystemMetaExt); err == nil && len(meta) > 0 {
+		contentType = string(meta)
+	}
+
+	return &ObjectInfo{ContentType: contentType, Size: fi.Size()}, nil
+}
+
+// Copy duplicates an object and its metadata
+func (s *FilesystemStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	info, err := s.Stat(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+
+	src, err := s.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return s.Put(ctx, dstKey, src, info.Size, info.ContentType)
+}
+
+// Remove deletes an object and its metadata
+func (s *FilesystemStorage) Remove(_ context.Context, key string) error {
+	objectPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(objectPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove object: %w", err)
+	}
+	_ = os.Remove(objectPath + filesystemMetaExt)
+	return nil
+}
+
+// PresignGet returns a signed API URL for downloading an object. The
+// Cache-Control of the response is signed with it, like S3 signs
+// response-cache-control.
+func (s *FilesystemStorage) PresignGet(_ context.Context, key string, expiry time.Duration, cacheControl string) (string, error) {
+	params := s.sign("GET", key, expiry, cacheControl)
+	if cacheControl != "" {
+		params.Set("cache_control", cacheControl)
+	}
+	return s.signedURL(key, params), nil
+}
+
+// PresignPut returns a signed API URL for uploading an object of a content
+// type
+func (s *FilesystemStorage) PresignPut(_ context.Context, key string, expiry time.Duration, contentType string) (string, error) {
+	params := s.sign("PUT", key, expiry, contentType)
+	params.Set("content_type", contentType)
+	return s.signedURL(key, params), nil
+}
+
+// ObjectURL returns the URL stored in asset records
+func (s *FilesystemStorage) ObjectURL(key string) string {
+	return filesystemURLScheme + s.bucket + "/" + key
+}
+
+// ObjectKey extracts the key from a stored URL
+func (s *FilesystemStorage) ObjectKey(objectURL string) string {
+	return keyAfterBucket(objectURL, s.bucket)
+}
+
+// Verify checks the signature of a presigned request. header is the signed
+// header value of the request, the Cache-Control of a GET or the
+// Content-Type of a PUT.
+func (s *FilesystemStorage) Verify(method, key, expires, header, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+
+	expected := s.signature(method, key, expiresAt, header)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *FilesystemStorage) sign(method, key string, expiry time.Duration, header string) url.Values {
+	expiresAt := time.Now().Add(expiry).Unix()
+
+	params := make(url.Values)
+	params.Set("expires", strconv.FormatInt(expiresAt, 10))
+	params.Set("signature", s.signature(method, key, expiresAt, header))
+	return params
+}
+
+func (s *FilesystemStorage) signature(method, key string, expiresAt int64, header string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%d\n%s", method, key, expiresAt, header)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *FilesystemStorage) signedURL(key string, params url.Values) string {
+	return s.publicURL + FilesystemStorageRoute + "/" + key + "?" + params.Encode()
+}
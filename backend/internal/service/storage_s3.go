@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+// S3StorageOptions configures an S3-compatible storage backend
+type S3StorageOptions struct {
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	UseSSL    bool
+
+	// CreateBucket creates the bucket when it does not exist
+	CreateBucket bool
+
+	// PrivateACL clears the bucket policy so objects are only reachable
+	// through presigned URLs. Only supported by MinIO.
+	PrivateACL bool
+}
+
+// S3Storage stores objects in MinIO, Amazon S3 or any S3-compatible service
+type S3Storage struct {
+	client   *minio.Client
+	bucket   string
+	endpoint string
+	scheme   string
+}
+
+// NewS3Storage connects to the service and makes sure the bucket exists
+func NewS3Storage(opts *S3StorageOptions) (*S3Storage, error) {
+	transport, err := minio.DefaultTransport(opts.UseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage transport: %w", err)
+	}
+
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure:    opts.UseSSL,
+		Region:    opts.Region,
+		Transport: tracing.NewTransport(transport),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, opts.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+
+	if !exists {
+		if !opts.CreateBucket {
+			return nil, fmt.Errorf("bucket %s does not exist", opts.Bucket)
+		}
+		err = client.MakeBucket(ctx, opts.Bucket, minio.MakeBucketOptions{Region: opts.Region})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+	}
+
+	// Assets are private, clients fetch them through presigned URLs.
+	// Clearing the policy also revokes public read on existing buckets.
+	if opts.PrivateACL {
+		if err := client.SetBucketPolicy(ctx, opts.Bucket, ""); err != nil {
+			return nil, fmt.Errorf("failed to set bucket policy: %w", err)
+		}
+	}
+
+	scheme := "http"
+	if opts.UseSSL {
+		scheme = "https"
+	}
+
+	return &S3Storage{
+		client:   client,
+		bucket:   opts.Bucket,
+		endpoint: opts.Endpoint,
+		scheme:   scheme,
+	}, nil
+}
+
+// Put uploads an object
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+	return nil
+}
+
+// Get opens an object for reading
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return object, nil
+}
+
+// Stat returns the size and content type of an object
+func (s *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return &ObjectInfo{ContentType: info.ContentType, Size: info.Size}, nil
+}
+
+// Copy duplicates an object without downloading it
+func (s *S3Storage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucket, Object: dstKey},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: srcKey},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes an object
+func (s *S3Storage) Remove(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove object: %w", err)
+	}
+	return nil
+}
+
+// PresignGet returns a presigned download URL
+func (s *S3Storage) PresignGet(ctx context.Context, key string, expiry time.Duration, cacheControl string) (string, error) {
+	params := make(url.Values)
+	if cacheControl != "" {
+		params.Set("response-cache-control", cacheControl)
+	}
+
+	signed, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign download: %w", err)
+	}
+	return signed.String(), nil
+}
+
+// PresignPut returns a presigned upload URL. The Content-Type header is
+// signed, the store rejects uploads of another type.
+func (s *S3Storage) PresignPut(ctx context.Context, key string, expiry time.Duration, contentType string) (string, error) {
+	headers := http.Header{"Content-Type": []string{contentType}}
+	signed, err := s.client.PresignHeader(ctx, http.MethodPut, s.bucket, key, expiry, nil, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign upload: %w", err)
+	}
+	return signed.String(), nil
+}
+
+// ObjectURL returns the path-style URL of an object
+func (s *S3Storage) ObjectURL(key string) string {
+	return fmt.Sprintf("%s://%s/%s/%s", s.scheme, s.endpoint, s.bucket, key)
+}
+
+// ObjectKey extracts the key from a path-style object URL
+func (s *S3Storage) ObjectKey(objectURL string) string {
+	return keyAfterBucket(objectURL, s.bucket)
+}
+
+// keyAfterBucket returns the part of a stored URL following the bucket name
+func keyAfterBucket(objectURL, bucket string) string {
+	const urlParts = 2
+	parts := strings.SplitN(objectURL, bucket+"/", urlParts)
+	if len(parts) == urlParts {
+		return parts[1]
+	}
+	return objectURL
+}
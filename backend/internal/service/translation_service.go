@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	// TranslationSource marks translated copies in realtime messages
+	TranslationSource = "translation"
+
+	// translationBatchSize is how many texts are sent to the backend per call
+	translationBatchSize = 50
+	translationColumnGap = 80
+)
+
+// languageCodePattern matches language codes such as de, pt-BR or zh-Hans
+var languageCodePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,4})?$`)
+
+var (
+	// ErrNoTranslatableText is returned when none of the elements has text
+	ErrNoTranslatableText = errors.New("none of the elements has text to translate")
+	// ErrTranslationForbidden is returned when a viewer asks for translated
+	// copies
+	ErrTranslationForbidden = errors.New("only editors can add translated copies to the board")
+)
+
+// TranslationService translates the text of elements, and adds translated
+// copies of them to the board on request
+type TranslationService struct {
+	translator       Translator
+	canvasService    *CanvasService
+	workspaceService *WorkspaceService
+	rooms            RoomBroadcaster
+	maxElements      int
+}
+
+// NewTranslationService creates a translation service with the backend
+// selected by config
+func NewTranslationService(
+	cfg *config.TranslationConfig,
+	aiCfg *config.AIConfig,
+	canvasService *CanvasService,
+	workspaceService *WorkspaceService,
+	rooms RoomBroadcaster,
+) (*TranslationService, error) {
+	translator, err := NewTranslator(cfg, aiCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TranslationService{
+		translator:       translator,
+		canvasService:    canvasService,
+		workspaceService: workspaceService,
+		rooms:            rooms,
+		maxElements:      cfg.MaxElements,
+	}, nil
+}
+
+// translationSegment is a text to translate and the element it belongs to
+type translationSegment struct {
+	text    string
+	element int // index in the translations
+	item    int // index of the list item, -1 for the content
+}
+
+// Translate translates the text elements, sticky notes and lists among the
+// requested elements. Other elements are skipped.
+func (s *TranslationService) Translate(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.TranslateElementsRequest,
+) (*models.TranslateElementsResponse, error) {
+	if len(req.ElementIDs) == 0 || len(req.ElementIDs) > s.maxElements {
+		return nil, fmt.Errorf("element_ids must list between 1 and %d elements", s.maxElements)
+	}
+	if !languageCodePattern.MatchString(req.TargetLanguage) {
+		return nil, fmt.Errorf("target_language must be a language code such as de, fr or pt-BR")
+	}
+	if req.Create {
+		if err := s.workspaceService.CheckPermission(ctx, workspaceID, userID, models.WorkspaceRoleEditor); err != nil {
+			return nil, ErrTranslationForbidden
+		}
+	}
+
+	board, err := s.canvasService.GetWorkspaceElements(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.CanvasElement, len(board))
+	for i := range board {
+		byID[board[i].ID] = &board[i]
+	}
+
+	// Rich text keeps its markup, everything else is plain
+	var elements []*models.CanvasElement
+	var richText, plainText []translationSegment
+	translations := []models.ElementTranslation{}
+	for _, id := range req.ElementIDs {
+		element, ok := byID[id]
+		if !ok {
+			continue
+		}
+
+		index := len(translations)
+		switch element.ElementType {
+		case models.ElementTypeText:
+			if content, _ := element.ElementData["content"].(string); strings.TrimSpace(content) != "" {
+				richText = append(richText, translationSegment{text: content, element: index, item: -1})
+			} else if text := strings.TrimSpace(elementPlainText(element)); text != "" {
+				plainText = append(plainText, translationSegment{text: text, element: index, item: -1})
+			} else {
+				continue
+			}
+		case models.ElementTypeSticky:
+			text := strings.TrimSpace(elementPlainText(element))
+			if text == "" {
+				continue
+			}
+			plainText = append(plainText, translationSegment{text: text, element: index, item: -1})
+		case models.ElementTypeList:
+			items, _ := element.ElementData["items"].([]interface{})
+			segments := 0
+			for i, raw := range items {
+				item, _ := raw.(map[string]interface{})
+				if content, _ := item["content"].(string); strings.TrimSpace(content) != "" {
+					plainText = append(plainText, translationSegment{text: content, element: index, item: i})
+					segments++
+				}
+			}
+			if segments == 0 {
+				continue
+			}
+		default:
+			continue
+		}
+
+		translation := models.ElementTranslation{ElementID: element.ID, ElementType: element.ElementType}
+		if element.ElementType == models.ElementTypeList {
+			items, _ := element.ElementData["items"].([]interface{})
+			translation.Items = make([]string, len(items))
+		}
+		translations = append(translations, translation)
+		elements = append(elements, element)
+	}
+	if len(translations) == 0 {
+		return nil, ErrNoTranslatableText
+	}
+
+	if err := s.translateSegments(ctx, translations, richText, req.TargetLanguage, true); err != nil {
+		return nil, err
+	}
+	if err := s.translateSegments(ctx, translations, plainText, req.TargetLanguage, false); err != nil {
+		return nil, err
+	}
+
+	resp := &models.TranslateElementsResponse{Translations: translations}
+	if req.Create {
+		resp.Elements, err = s.createCopies(ctx, workspaceID, userID, board, elements, translations, req.TargetLanguage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// translateSegments translates segments in batches and stores the results
+// in their translations
+func (s *TranslationService) translateSegments(
+	ctx context.Context,
+	translations []models.ElementTranslation,
+	segments []translationSegment,
+	target string,
+	html bool,
+) error {
+	for start := 0; start < len(segments); start += translationBatchSize {
+		batch := segments[start:min(start+translationBatchSize, len(segments))]
+		texts := make([]string, len(batch))
+		for i := range batch {
+			texts[i] = batch[i].text
+		}
+
+		translated, err := s.translator.Translate(ctx, texts, target, html)
+		if err != nil {
+			return err
+		}
+
+		for i, segment := range batch {
+			translation := &translations[segment.element]
+			if segment.item >= 0 {
+				translation.Items[segment.item] = translated[i].Text
+			} else {
+				translation.Content = translated[i].Text
+			}
+			if translation.SourceLanguage == "" {
+				translation.SourceLanguage = translated[i].SourceLanguage
+			}
+		}
+	}
+	return nil
+}
+
+// createCopies adds a translated copy of each element to the board. The
+// copies keep their layout, moved to the right of everything on the board.
+func (s *TranslationService) createCopies(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	board []models.CanvasElement,
+	elements []*models.CanvasElement,
+	translations []models.ElementTranslation,
+	target string,
+) ([]models.ElementResponse, error) {
+	var right, left float64
+	zIndex := 0
+	for i := range board {
+		position, size := board[i].ElementData.Bounds()
+		right = max(right, position.X+size.Width)
+		zIndex = max(zIndex, board[i].ZIndex+1)
+	}
+	for i, element := range elements {
+		position, _ := element.ElementData.Bounds()
+		if i == 0 || position.X < left {
+			left = position.X
+		}
+	}
+	offset := right + translationColumnGap - left
+
+	requests := make([]models.CreateElementRequest, len(elements))
+	for i, element := range elements {
+		data := make(models.ElementData, len(element.ElementData)+2)
+		for key, value := range element.ElementData {
+			data[key] = value
+		}
+		delete(data, models.AIGeneratedKey)
+
+		position, _ := element.ElementData.Bounds()
+		data["position"] = map[string]interface{}{"x": position.X + offset, "y": position.Y}
+		data[models.TranslatedFromKey] = element.ID.String()
+		data[models.TranslationLanguageKey] = target
+		applyTranslation(data, element.ElementType, &translations[i])
+
+		requests[i] = models.CreateElementRequest{
+			ElementType: element.ElementType,
+			ElementData: data,
+			ZIndex:      zIndex + i,
+		}
+	}
+
+	created, err := s.canvasService.BatchCreateElements(
+		ctx, workspaceID, userID, models.BatchCreateRequest{Elements: requests},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.ElementResponse, len(created))
+	for i := range created {
+		responses[i] = created[i].ToResponse()
+	}
+
+	if s.rooms != nil {
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypeElementsAdded,
+			UserID:    userID,
+			Timestamp: time.Now(),
+			Payload: models.ElementsAddedPayload{
+				Elements: responses,
+				Source:   TranslationSource,
+			},
+		}, uuid.Nil)
+	}
+
+	return responses, nil
+}
+
+// applyTranslation replaces the text in the data of a copy with its
+// translation
+func applyTranslation(data models.ElementData, elementType models.ElementType, translation *models.ElementTranslation) {
+	switch elementType {
+	case models.ElementTypeText:
+		content, _ := data["content"].(string)
+		if strings.TrimSpace(content) != "" {
+			data["content"] = translation.Content
+			data["plain_text"] = html.UnescapeString(htmlTagPattern.ReplaceAllString(translation.Content, ""))
+		} else {
+			data["content"] = "<p>" + html.EscapeString(translation.Content) + "</p>"
+			data["plain_text"] = translation.Content
+		}
+	case models.ElementTypeSticky:
+		data["content"] = translation.Content
+	case models.ElementTypeList:
+		items, _ := data["items"].([]interface{})
+		translated := make([]interface{}, len(items))
+		for i, raw := range items {
+			original, _ := raw.(map[string]interface{})
+			item := make(map[string]interface{}, len(original))
+			for key, value := range original {
+				item[key] = value
+			}
+			item["id"] = uuid.New().String()
+			if translation.Items[i] != "" {
+				item["content"] = translation.Items[i]
+			}
+			translated[i] = item
+		}
+		data["items"] = translated
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// Translation providers
+const (
+	TranslationProviderDeepL  = "deepl"
+	TranslationProviderGoogle = "google"
+	TranslationProviderAI     = "ai"
+)
+
+const (
+	translationTimeout      = 30 * time.Second
+	translationMaxErrorBody = 512
+
+	deeplURL           = "https://api.deepl.com"
+	deeplFreeURL       = "https://api-free.deepl.com"
+	deeplFreeKeySuffix = ":fx"
+	googleTranslateURL = "https://translation.googleapis.com/language/translate/v2"
+)
+
+var (
+	// ErrTranslationProvider is returned when the translation backend fails
+	// or replies with something unusable
+	ErrTranslationProvider = errors.New("translation provider failed")
+	// ErrTranslationRejected is returned when the translation backend refuses
+	// a request, usually for a language it doesn't support
+	ErrTranslationRejected = errors.New("translation provider rejected the request")
+)
+
+// TranslatedText is a translation and the language detected in its source
+type TranslatedText struct {
+	Text           string
+	SourceLanguage string // empty when the backend doesn't detect it
+}
+
+// Translator translates texts with a translation backend
+type Translator interface {
+	// Translate translates texts to a language, in order. With html the
+	// texts are HTML whose markup is kept.
+	Translate(ctx context.Context, texts []string, target string, html bool) ([]TranslatedText, error)
+}
+
+// NewTranslator creates the translator selected by translation.provider
+// config. The ai provider translates with the language model of aiCfg.
+func NewTranslator(cfg *config.TranslationConfig, aiCfg *config.AIConfig) (Translator, error) {
+	client := &http.Client{Timeout: translationTimeout}
+
+	switch cfg.Provider {
+	case TranslationProviderDeepL:
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = deeplURL
+			if strings.HasSuffix(cfg.APIKey, deeplFreeKeySuffix) {
+				baseURL = deeplFreeURL
+			}
+		}
+		return &DeepLTranslator{client: client, apiKey: cfg.APIKey, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+	case TranslationProviderGoogle:
+		return &GoogleTranslator{client: client, apiKey: cfg.APIKey}, nil
+	case TranslationProviderAI:
+		provider, err := NewAIProvider(aiCfg)
+		if err != nil {
+			return nil, err
+		}
+		return &AITranslator{provider: provider}, nil
+	default:
+		return nil, fmt.Errorf("unknown translation provider: %s", cfg.Provider)
+	}
+}
+
+// DeepLTranslator translates with the DeepL API
+type DeepLTranslator struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+type deeplRequest struct {
+	TargetLang  string   `json:"target_lang"`
+	TagHandling string   `json:"tag_handling,omitempty"`
+	Text        []string `json:"text"`
+}
+
+type deeplResponse struct {
+	Translations []struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	} `json:"translations"`
+}
+
+// Translate translates texts with one request
+func (t *DeepLTranslator) Translate(ctx context.Context, texts []string, target string, html bool) ([]TranslatedText, error) {
+	in := deeplRequest{Text: texts, TargetLang: strings.ToUpper(target)}
+	if html {
+		in.TagHandling = "html"
+	}
+
+	var out deeplResponse
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + t.apiKey}
+	if err := callTranslationAPI(ctx, t.client, t.baseURL+"/v2/translate", headers, in, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Translations) != len(texts) {
+		return nil, fmt.Errorf("%w: got %d translations for %d texts", ErrTranslationProvider, len(out.Translations), len(texts))
+	}
+
+	translated := make([]TranslatedText, len(texts))
+	for i, translation := range out.Translations {
+		translated[i] = TranslatedText{
+			Text:           translation.Text,
+			SourceLanguage: strings.ToLower(translation.DetectedSourceLanguage),
+		}
+	}
+	return translated, nil
+}
+
+// GoogleTranslator translates with the Google Cloud Translation API
+type GoogleTranslator struct {
+	client *http.Client
+	apiKey string
+}
+
+type googleTranslateRequest struct {
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	Q      []string `json:"q"`
+}
+
+type googleTranslateResponse struct {
+	Data struct {
+		Translations []struct {
+			TranslatedText         string `json:"translatedText"`
+			DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+		} `json:"translations"`
+	} `json:"data"`
+}
+
+// Translate translates texts with one request
+func (t *GoogleTranslator) Translate(ctx context.Context, texts []string, target string, html bool) ([]TranslatedText, error) {
+	in := googleTranslateRequest{Q: texts, Target: target, Format: "text"}
+	if html {
+		in.Format = "html"
+	}
+
+	var out googleTranslateResponse
+	headers := map[string]string{"X-Goog-Api-Key": t.apiKey}
+	if err := callTranslationAPI(ctx, t.client, googleTranslateURL, headers, in, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Data.Translations) != len(texts) {
+		return nil, fmt.Errorf("%w: got %d translations for %d texts",
+			ErrTranslationProvider, len(out.Data.Translations), len(texts))
+	}
+
+	translated := make([]TranslatedText, len(texts))
+	for i, translation := range out.Data.Translations {
+		translated[i] = TranslatedText{
+			Text:           translation.TranslatedText,
+			SourceLanguage: translation.DetectedSourceLanguage,
+		}
+	}
+	return translated, nil
+}
+
+// AITranslator translates with the language model of AI assistance
+type AITranslator struct {
+	provider AIProvider
+}
+
+// Translate asks the model for the translations of the texts as JSON
+func (t *AITranslator) Translate(ctx context.Context, texts []string, target string, html bool) ([]TranslatedText, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal texts: %w", err)
+	}
+
+	system := "You translate the texts of a collaborative whiteboard, given as a JSON array, to the language " +
+		"with the code " + target + ". "
+	if html {
+		system += "The texts are HTML, keep the tags and translate the text between them only. "
+	}
+	system += "Reply with JSON only, one translation per text in the same order, in the form " +
+		`{"translations":["First text","Second text"]}`
+
+	reply, err := t.provider.Complete(ctx, &AIPrompt{System: system, User: string(input)})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTranslationProvider, err)
+	}
+
+	var parsed struct {
+		Translations []string `json:"translations"`
+	}
+	if err := decodeAIJSON(reply, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTranslationProvider, err)
+	}
+	if len(parsed.Translations) != len(texts) {
+		return nil, fmt.Errorf("%w: got %d translations for %d texts",
+			ErrTranslationProvider, len(parsed.Translations), len(texts))
+	}
+
+	translated := make([]TranslatedText, len(texts))
+	for i, text := range parsed.Translations {
+		translated[i] = TranslatedText{Text: text}
+	}
+	return translated, nil
+}
+
+// callTranslationAPI posts a JSON request to a translation API and decodes
+// its response
+func callTranslationAPI(ctx context.Context, client *http.Client, url string, headers map[string]string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal translation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTranslationProvider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, translationMaxErrorBody))
+		reason := ErrTranslationProvider
+		if resp.StatusCode == http.StatusBadRequest {
+			reason = ErrTranslationRejected
+		}
+		return fmt.Errorf("%w: responded with status %d: %s", reason, resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: failed to decode response: %w", ErrTranslationProvider, err)
+	}
+	return nil
+}
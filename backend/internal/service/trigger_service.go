@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	defaultTriggerPageSize = 50
+	maxTriggerPageSize     = 100
+)
+
+var (
+	// ErrUnknownTrigger is returned for trigger keys other than the ones in
+	// models.TriggerEvents
+	ErrUnknownTrigger = errors.New("unknown trigger")
+	// ErrInvalidTriggerCursor is returned for cursors that weren't returned
+	// by a trigger
+	ErrInvalidTriggerCursor = errors.New("invalid cursor")
+	// ErrTriggerSubscriptionNotFound is returned for unknown REST hook subscriptions
+	ErrTriggerSubscriptionNotFound = errors.New("subscription not found")
+	// ErrTriggerForbidden is returned when the user of an API key may not
+	// manage REST hooks
+	ErrTriggerForbidden = errors.New("only workspace owners can subscribe to triggers")
+)
+
+// TriggerService serves the triggers of automation platforms such as Zapier
+// and Make. Polling triggers list new items newest first with a stable
+// cursor, and REST hook subscriptions are outgoing webhooks in the rest_hook
+// format, which receive the same items as the polling triggers.
+type TriggerService struct {
+	canvasRepo       *repository.CanvasRepository
+	commentRepo      *repository.CommentRepository
+	workspaceRepo    *repository.WorkspaceRepository
+	userRepo         *repository.UserRepository
+	workspaceService *WorkspaceService
+	webhookService   *WebhookService
+	frontendURL      string
+}
+
+// NewTriggerService creates a new trigger service. frontendURL is used for
+// the links of items.
+func NewTriggerService(
+	canvasRepo *repository.CanvasRepository,
+	commentRepo *repository.CommentRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	userRepo *repository.UserRepository,
+	workspaceService *WorkspaceService,
+	webhookService *WebhookService,
+	frontendURL string,
+) *TriggerService {
+	return &TriggerService{
+		canvasRepo:       canvasRepo,
+		commentRepo:      commentRepo,
+		workspaceRepo:    workspaceRepo,
+		userRepo:         userRepo,
+		workspaceService: workspaceService,
+		webhookService:   webhookService,
+		frontendURL:      frontendURL,
+	}
+}
+
+// Account describes the workspace and user an API key acts as
+func (s *TriggerService) Account(ctx context.Context, key *models.APIKey) (*models.AutomationAccount, error) {
+	workspace, err := s.workspaceRepo.GetWorkspaceByID(ctx, key.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &models.AutomationAccount{
+		WorkspaceID:   workspace.ID,
+		WorkspaceName: workspace.Name,
+		UserID:        user.ID,
+		UserName:      user.Name,
+		UserEmail:     user.Email,
+	}, nil
+}
+
+// PollNewElements lists the elements of a workspace newest first. The
+// returned cursor continues below the last item, empty on the last page.
+func (s *TriggerService) PollNewElements(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	cursor string,
+	limit int,
+) ([]models.ElementTriggerItem, string, error) {
+	after, err := decodeTriggerCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit = triggerPageSize(limit)
+
+	elements, err := s.canvasRepo.ListElementsPage(ctx, workspaceID, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]models.ElementTriggerItem, len(elements))
+	for i := range elements {
+		items[i] = elementTriggerItem(&elements[i], s.frontendURL)
+	}
+
+	next := ""
+	if len(elements) == limit {
+		last := elements[len(elements)-1]
+		next = encodeTriggerCursor(last.CreatedAt, last.ID)
+	}
+	return items, next, nil
+}
+
+// PollNewComments lists the comments of a workspace, threads and replies,
+// newest first. The returned cursor continues below the last item, empty on
+// the last page.
+func (s *TriggerService) PollNewComments(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	cursor string,
+	limit int,
+) ([]models.CommentTriggerItem, string, error) {
+	after, err := decodeTriggerCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit = triggerPageSize(limit)
+
+	comments, err := s.commentRepo.ListCommentsPage(ctx, workspaceID, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]models.CommentTriggerItem, len(comments))
+	for i := range comments {
+		items[i] = commentTriggerItem(&comments[i], s.frontendURL)
+	}
+
+	next := ""
+	if len(comments) == limit {
+		last := comments[len(comments)-1]
+		next = encodeTriggerCursor(last.CreatedAt, last.ID)
+	}
+	return items, next, nil
+}
+
+// PollNewMembers lists the members of a workspace, most recently joined
+// first. The returned cursor continues below the last item, empty on the
+// last page.
+func (s *TriggerService) PollNewMembers(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	cursor string,
+	limit int,
+) ([]models.MemberTriggerItem, string, error) {
+	after, err := decodeTriggerCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit = triggerPageSize(limit)
+
+	members, err := s.workspaceRepo.ListMembersPage(ctx, workspaceID, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]models.MemberTriggerItem, len(members))
+	for i := range members {
+		items[i] = memberTriggerItem(&members[i])
+	}
+
+	next := ""
+	if len(members) == limit {
+		last := members[len(members)-1]
+		next = encodeTriggerCursor(last.JoinedAt, last.ID)
+	}
+	return items, next, nil
+}
+
+// Subscribe registers a REST hook for a trigger. Only owners manage
+// webhooks, so the user of the API key must still own the workspace.
+func (s *TriggerService) Subscribe(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.TriggerSubscriptionRequest,
+) (*models.TriggerSubscription, error) {
+	eventType, ok := models.TriggerEvents[req.Event]
+	if !ok {
+		return nil, ErrUnknownTrigger
+	}
+
+	if err := s.workspaceService.CheckPermission(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return nil, ErrTriggerForbidden
+	}
+
+	webhook, err := s.webhookService.CreateWebhook(ctx, workspaceID, userID, &models.CreateWebhookRequest{
+		URL:    req.TargetURL,
+		Format: models.WebhookFormatRESTHook,
+		Events: []string{eventType},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TriggerSubscription{
+		ID:        webhook.ID,
+		TargetURL: webhook.URL,
+		Event:     req.Event,
+		CreatedAt: webhook.CreatedAt,
+	}, nil
+}
+
+// Unsubscribe deletes a REST hook. Other webhooks of the workspace can't
+// be deleted this way.
+func (s *TriggerService) Unsubscribe(ctx context.Context, workspaceID, userID, subscriptionID uuid.UUID) error {
+	if err := s.workspaceService.CheckPermission(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return ErrTriggerForbidden
+	}
+
+	webhook, err := s.webhookService.GetWebhook(ctx, workspaceID, subscriptionID)
+	if errors.Is(err, ErrWebhookNotFound) || (err == nil && webhook.Format != models.WebhookFormatRESTHook) {
+		return ErrTriggerSubscriptionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.webhookService.DeleteWebhook(ctx, workspaceID, subscriptionID)
+}
+
+// renderRESTHookPayload renders an event as the JSON array of the trigger
+// items it created, so REST hooks receive what the polling trigger lists.
+// Items that are gone by the time of the delivery are left out.
+func (s *WebhookService) renderRESTHookPayload(ctx context.Context, delivery *models.WebhookDelivery) ([]byte, error) {
+	var event struct {
+		Data        json.RawMessage `json:"data"`
+		Type        string          `json:"type"`
+		WorkspaceID uuid.UUID       `json:"workspace_id"`
+	}
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	var items any
+	switch event.Type {
+	case models.EventElementCreated:
+		var data models.ElementEventPayload
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode event data: %w", err)
+		}
+		elements, err := s.canvasRepo.GetElementsByIDs(ctx, data.ElementIDs)
+		if err != nil {
+			return nil, err
+		}
+		elementItems := make([]models.ElementTriggerItem, len(elements))
+		for i := range elements {
+			elementItems[i] = elementTriggerItem(&elements[i], s.frontendURL)
+		}
+		items = elementItems
+
+	case models.EventMemberJoined:
+		var data models.MemberEventPayload
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode event data: %w", err)
+		}
+		memberItems := []models.MemberTriggerItem{}
+		member, err := s.workspaceRepo.GetMember(ctx, event.WorkspaceID, data.UserID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.userRepo.GetByID(ctx, data.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil && user != nil {
+			memberItems = append(memberItems, memberTriggerItem(&models.WorkspaceMemberWithUser{
+				WorkspaceMember: *member,
+				User:            *user,
+			}))
+		}
+		items = memberItems
+
+	default:
+		// Test deliveries and events without a trigger are sent as they are
+		return delivery.Payload, nil
+	}
+
+	payload, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trigger items: %w", err)
+	}
+	return payload, nil
+}
+
+func elementTriggerItem(element *models.CanvasElement, frontendURL string) models.ElementTriggerItem {
+	return models.ElementTriggerItem{
+		ID:          element.ID,
+		WorkspaceID: element.WorkspaceID,
+		ElementType: element.ElementType,
+		ElementData: element.ElementData,
+		Text:        elementPlainText(element),
+		URL:         elementURL(frontendURL, element.WorkspaceID, element.ID),
+		CreatedBy:   element.CreatedBy,
+		CreatedAt:   element.CreatedAt,
+	}
+}
+
+func commentTriggerItem(comment *models.CommentMatch, frontendURL string) models.CommentTriggerItem {
+	link := workspaceURL(frontendURL, comment.WorkspaceID)
+	if comment.ThreadElementID != nil {
+		link = elementURL(frontendURL, comment.WorkspaceID, *comment.ThreadElementID)
+	}
+	return models.CommentTriggerItem{
+		ID:          comment.ID,
+		WorkspaceID: comment.WorkspaceID,
+		ElementID:   comment.ThreadElementID,
+		ParentID:    comment.ParentID,
+		AuthorID:    comment.AuthorID,
+		AuthorName:  comment.AuthorName,
+		Body:        comment.Body,
+		URL:         link,
+		CreatedAt:   comment.CreatedAt,
+	}
+}
+
+func memberTriggerItem(member *models.WorkspaceMemberWithUser) models.MemberTriggerItem {
+	return models.MemberTriggerItem{
+		ID:          member.ID,
+		WorkspaceID: member.WorkspaceID,
+		UserID:      member.UserID,
+		Role:        member.Role,
+		Name:        member.User.Name,
+		Email:       member.User.Email,
+		Username:    member.User.Username,
+		AvatarURL:   member.User.AvatarURL,
+		JoinedAt:    member.JoinedAt,
+	}
+}
+
+func triggerPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultTriggerPageSize
+	}
+	return min(limit, maxTriggerPageSize)
+}
+
+// encodeTriggerCursor encodes the position of an item as
+// base64url("<unix microseconds>.<id>"). PostgreSQL timestamps have
+// microsecond precision, so the position round-trips exactly.
+func encodeTriggerCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + "." + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeTriggerCursor decodes a cursor of encodeTriggerCursor, nil for the
+// first page
+func decodeTriggerCursor(cursor string) (*models.TriggerCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidTriggerCursor
+	}
+	micros, rawID, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, ErrInvalidTriggerCursor
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidTriggerCursor
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, ErrInvalidTriggerCursor
+	}
+
+	return &models.TriggerCursor{CreatedAt: time.UnixMicro(usec).UTC(), ID: id}, nil
+}
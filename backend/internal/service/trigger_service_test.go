@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestTriggerCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 14, 15, 9, 26, 535897000, time.UTC)
+	id := uuid.New()
+
+	cursor, err := decodeTriggerCursor(encodeTriggerCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("decodeTriggerCursor: %v", err)
+	}
+	if !cursor.CreatedAt.Equal(createdAt) || cursor.ID != id {
+		t.Errorf("got cursor (%v, %v), want (%v, %v)", cursor.CreatedAt, cursor.ID, createdAt, id)
+	}
+
+	if cursor, err := decodeTriggerCursor(""); err != nil || cursor != nil {
+		t.Errorf("empty cursor: got (%v, %v), want first page", cursor, err)
+	}
+	if _, err := decodeTriggerCursor("not a cursor"); err == nil {
+		t.Error("invalid cursor: got no error")
+	}
+}
+
+func TestCommentTriggerItem(t *testing.T) {
+	workspaceID := uuid.New()
+	elementID := uuid.New()
+	threadID := uuid.New()
+	authorID := uuid.New()
+	authorName := "Ada"
+
+	tests := []struct {
+		name    string
+		comment models.CommentMatch
+		url     string
+	}{
+		{
+			name: "board thread",
+			comment: models.CommentMatch{Comment: models.Comment{
+				ID: threadID, WorkspaceID: workspaceID, Body: "On the board",
+			}},
+			url: "https://board.example/workspace/" + workspaceID.String(),
+		},
+		{
+			name: "reply in an element thread",
+			comment: models.CommentMatch{
+				Comment: models.Comment{
+					ID: uuid.New(), WorkspaceID: workspaceID, ParentID: &threadID,
+					AuthorID: &authorID, AuthorName: &authorName, Body: "A reply",
+				},
+				ThreadElementID: &elementID,
+			},
+			url: "https://board.example/workspace/" + workspaceID.String() + "?element=" + elementID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := commentTriggerItem(&tt.comment, "https://board.example/")
+			if item.ID != tt.comment.ID || item.WorkspaceID != workspaceID || item.Body != tt.comment.Body {
+				t.Errorf("got item %+v for comment %+v", item, tt.comment.Comment)
+			}
+			if item.ElementID != tt.comment.ThreadElementID || item.ParentID != tt.comment.ParentID {
+				t.Errorf("got element %v, parent %v", item.ElementID, item.ParentID)
+			}
+			if item.URL != tt.url {
+				t.Errorf("got URL %q, want %q", item.URL, tt.url)
+			}
+		})
+	}
+
+	comment := models.CommentMatch{Comment: models.Comment{ID: threadID, WorkspaceID: workspaceID}}
+	if item := commentTriggerItem(&comment, ""); item.URL != "" {
+		t.Errorf("got URL %q without a frontend URL, want none", item.URL)
+	}
+}
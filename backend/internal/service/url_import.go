@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+)
+
+const (
+	remoteFetchTimeout   = 20 * time.Second
+	remoteDialTimeout    = 5 * time.Second
+	remoteMaxRedirects   = 3
+	remoteUserAgent      = "HertzBoard-ImageImporter/1.0"
+	remoteDefaultName    = "image"
+	remoteMaxFilenameLen = 255
+)
+
+// ErrBlockedAddress is returned when a remote URL resolves to a private or
+// otherwise non-public address
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// remoteImageExtensions maps importable content types to file extensions
+var remoteImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// newRemoteClient builds an HTTP client that refuses to connect to
+// loopback, private, link-local and other internal addresses. The check runs
+// on the resolved IP of every connection, so redirects and DNS rebinding
+// can't be used to reach internal services.
+func newRemoteClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: remoteDialTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   remoteDialTimeout,
+		ResponseHeaderTimeout: remoteFetchTimeout,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   remoteFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= remoteMaxRedirects {
+				return fmt.Errorf("too many redirects")
+			}
+			return validateRemoteURL(req.URL)
+		},
+	}
+}
+
+// isPublicIP reports whether the address is routable on the public internet
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast()
+}
+
+// validateRemoteURL checks the scheme and host of a URL before fetching it
+func validateRemoteURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("only http and https URLs are supported")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("URL has no host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("URLs with credentials are not supported")
+	}
+	return nil
+}
+
+// ImportAssetFromURL downloads a remote image and stores it like a regular upload
+func (s *AssetService) ImportAssetFromURL(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.ImportAssetRequest,
+) (*models.Asset, error) {
+	return s.importRemoteAsset(ctx, workspaceID, userID, req.URL, req.Filename, nil)
+}
+
+// importRemoteAsset fetches a remote image and runs it through the upload pipeline
+func (s *AssetService) importRemoteAsset(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	rawURL, filename string,
+	attribution *models.AssetAttribution,
+) (*models.Asset, error) {
+	remoteURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := validateRemoteURL(remoteURL); err != nil {
+		return nil, err
+	}
+
+	data, contentType, err := s.fetchRemoteImage(ctx, remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if filename == "" {
+		filename = remoteFilename(remoteURL, contentType)
+	}
+
+	return s.uploadAsset(ctx, workspaceID, userID, filename, contentType, int64(len(data)), bytes.NewReader(data), attribution)
+}
+
+// fetchRemoteImage downloads at most MaxImageSize bytes and detects the
+// content type from the data itself rather than trusting the remote server
+func (s *AssetService) fetchRemoteImage(ctx context.Context, remoteURL *url.URL) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteFetchTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, remoteURL.String(), http.NoBody)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("User-Agent", remoteUserAgent)
+	httpReq.Header.Set("Accept", "image/*")
+
+	resp, err := s.remoteClient.Do(httpReq)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("remote server responded with status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > MaxImageSize {
+		return nil, "", fmt.Errorf("%w: maximum allowed size is %d bytes", ErrFileTooLarge, MaxImageSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxImageSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read remote file: %w", err)
+	}
+	if len(data) > MaxImageSize {
+		return nil, "", fmt.Errorf("%w: maximum allowed size is %d bytes", ErrFileTooLarge, MaxImageSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := remoteImageExtensions[contentType]; !ok {
+		return nil, "", fmt.Errorf("unsupported file type: %s", contentType)
+	}
+
+	return data, contentType, nil
+}
+
+// remoteFilename derives a file name from the URL path, making sure the
+// extension matches the detected content type
+func remoteFilename(remoteURL *url.URL, contentType string) string {
+	name := path.Base(remoteURL.Path)
+	if name == "." || name == "/" {
+		name = remoteDefaultName
+	}
+
+	if ext := path.Ext(name); mime.TypeByExtension(strings.ToLower(ext)) != contentType {
+		name = strings.TrimSuffix(name, ext) + remoteImageExtensions[contentType]
+	}
+
+	if len(name) > remoteMaxFilenameLen {
+		name = name[len(name)-remoteMaxFilenameLen:]
+	}
+	return name
+}
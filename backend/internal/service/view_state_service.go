@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	viewStateKey = "view_state:%s:%s"
+	// viewStateDirtyKey is the set of user:workspace pairs to persist
+	viewStateDirtyKey = "view_state:dirty"
+	// viewStateTTL drops states that stopped changing from Redis, long after
+	// they were persisted
+	viewStateTTL = 7 * 24 * time.Hour
+	// viewStateFlushBatch is how many states are persisted per batch
+	viewStateFlushBatch = 100
+	viewStateTimeout    = 2 * time.Minute
+
+	minViewStateZoom         = 0.01
+	maxViewStateZoom         = 100
+	maxViewStateFrames       = 500
+	maxViewStateLayers       = 100
+	maxViewStateLayerNameLen = 100
+
+	// ViewStateFlushInterval is how often changed view states are persisted
+	ViewStateFlushInterval = time.Minute
+)
+
+// ViewStateService keeps where users left off on boards. Clients save the
+// viewport often, so states are kept in Redis and persisted to PostgreSQL
+// periodically by ViewStateWorker. States Redis no longer has are read back
+// from PostgreSQL.
+type ViewStateService struct {
+	redis         *redis.Client
+	viewStateRepo *repository.ViewStateRepository
+}
+
+// NewViewStateService creates a new view state service
+func NewViewStateService(redisClient *redis.Client, viewStateRepo *repository.ViewStateRepository) *ViewStateService {
+	return &ViewStateService{redis: redisClient, viewStateRepo: viewStateRepo}
+}
+
+// Get returns the view state of a user on a workspace, the default view if
+// they never saved one
+func (s *ViewStateService) Get(ctx context.Context, userID, workspaceID uuid.UUID) (*models.ViewState, error) {
+	key := fmt.Sprintf(viewStateKey, userID, workspaceID)
+
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err == nil {
+		var state models.ViewState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal view state: %w", err)
+		}
+		return &state, nil
+	}
+	if err != redis.Nil {
+		return nil, fmt.Errorf("failed to get view state: %w", err)
+	}
+
+	state, err := s.viewStateRepo.GetViewState(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return defaultViewState(), nil
+	}
+
+	// Cached again so the next saves change the persisted state
+	if data, err := json.Marshal(state); err == nil {
+		if err := s.redis.Set(ctx, key, data, viewStateTTL).Err(); err != nil {
+			hlog.CtxWarnf(ctx, "Failed to cache view state: %v", err)
+		}
+	}
+	return state, nil
+}
+
+// Update changes the view state of a user on a workspace. Fields the request
+// leaves out keep their value.
+func (s *ViewStateService) Update(
+	ctx context.Context,
+	userID, workspaceID uuid.UUID,
+	req *models.UpdateViewStateRequest,
+) (*models.ViewState, error) {
+	if err := validateViewState(req); err != nil {
+		return nil, err
+	}
+
+	state, err := s.Get(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Viewport != nil {
+		state.Viewport = *req.Viewport
+	}
+	if req.CollapsedFrameIDs != nil {
+		state.CollapsedFrameIDs = uniqueIDs(*req.CollapsedFrameIDs)
+	}
+	if req.HiddenLayers != nil {
+		state.HiddenLayers = *req.HiddenLayers
+	}
+	state.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal view state: %w", err)
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, fmt.Sprintf(viewStateKey, userID, workspaceID), data, viewStateTTL)
+	pipe.SAdd(ctx, viewStateDirtyKey, userID.String()+":"+workspaceID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to save view state: %w", err)
+	}
+	return state, nil
+}
+
+// Flush persists the view states changed since the last flush. Returns how
+// many were persisted.
+func (s *ViewStateService) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		members, err := s.redis.SPopN(ctx, viewStateDirtyKey, viewStateFlushBatch).Result()
+		if err != nil {
+			return total, fmt.Errorf("failed to pop changed view states: %w", err)
+		}
+
+		for _, member := range members {
+			if err := s.persist(ctx, member); err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		if len(members) < viewStateFlushBatch {
+			return total, nil
+		}
+	}
+}
+
+// persist writes a state from Redis to the database. It is marked dirty
+// again if that fails, so it is retried on the next flush.
+func (s *ViewStateService) persist(ctx context.Context, member string) error {
+	userPart, workspacePart, _ := strings.Cut(member, ":")
+	userID, userErr := uuid.Parse(userPart)
+	workspaceID, workspaceErr := uuid.Parse(workspacePart)
+	if userErr != nil || workspaceErr != nil {
+		return nil
+	}
+
+	err := s.writeState(ctx, userID, workspaceID)
+	if err == nil {
+		return nil
+	}
+
+	if retryErr := s.redis.SAdd(ctx, viewStateDirtyKey, member).Err(); retryErr != nil {
+		hlog.CtxWarnf(ctx, "Failed to requeue view state %s: %v", member, retryErr)
+	}
+	return err
+}
+
+func (s *ViewStateService) writeState(ctx context.Context, userID, workspaceID uuid.UUID) error {
+	data, err := s.redis.Get(ctx, fmt.Sprintf(viewStateKey, userID, workspaceID)).Bytes()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read view state: %w", err)
+	}
+
+	var state models.ViewState
+	if err := json.Unmarshal(data, &state); err != nil {
+		hlog.CtxWarnf(ctx, "Dropping unreadable view state of user %s: %v", userID, err)
+		return nil
+	}
+	return s.viewStateRepo.UpsertViewState(ctx, userID, workspaceID, &state)
+}
+
+func validateViewState(req *models.UpdateViewStateRequest) error {
+	if v := req.Viewport; v != nil {
+		if math.IsNaN(v.X) || math.IsInf(v.X, 0) || math.IsNaN(v.Y) || math.IsInf(v.Y, 0) {
+			return fmt.Errorf("viewport center must be finite")
+		}
+		if !(v.Zoom >= minViewStateZoom && v.Zoom <= maxViewStateZoom) {
+			return fmt.Errorf("zoom must be between %g and %g", minViewStateZoom, float64(maxViewStateZoom))
+		}
+	}
+	if req.CollapsedFrameIDs != nil && len(*req.CollapsedFrameIDs) > maxViewStateFrames {
+		return fmt.Errorf("at most %d frames can be collapsed", maxViewStateFrames)
+	}
+	if req.HiddenLayers != nil {
+		if len(*req.HiddenLayers) > maxViewStateLayers {
+			return fmt.Errorf("at most %d layers can be hidden", maxViewStateLayers)
+		}
+		for _, layer := range *req.HiddenLayers {
+			if layer == "" || len(layer) > maxViewStateLayerNameLen {
+				return fmt.Errorf("layer names must have 1 to %d characters", maxViewStateLayerNameLen)
+			}
+		}
+	}
+	return nil
+}
+
+func defaultViewState() *models.ViewState {
+	return &models.ViewState{
+		CollapsedFrameIDs: []uuid.UUID{},
+		HiddenLayers:      []string{},
+		Viewport:          models.Viewport{Zoom: 1},
+	}
+}
+
+// uniqueIDs returns ids without duplicates, in order
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// ViewStateWorker periodically persists the changed view states
+type ViewStateWorker struct {
+	viewStates *ViewStateService
+	done       chan struct{}
+	trigger    chan struct{}
+	interval   time.Duration
+}
+
+// NewViewStateWorker creates and starts a new view state worker
+func NewViewStateWorker(viewStates *ViewStateService, interval time.Duration) (*ViewStateWorker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	worker := &ViewStateWorker{
+		viewStates: viewStates,
+		done:       make(chan struct{}),
+		trigger:    make(chan struct{}, 1),
+		interval:   interval,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the view state worker. Changed states are persisted by the
+// next instance to run.
+func (w *ViewStateWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger persists changed view states now instead of at the next tick. It
+// returns false if a flush is already pending.
+func (w *ViewStateWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *ViewStateWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.trigger:
+			w.flush()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *ViewStateWorker) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), viewStateTimeout)
+	defer cancel()
+
+	count, err := w.viewStates.Flush(ctx)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to persist view states: %v", err)
+	}
+	if count > 0 {
+		hlog.CtxDebugf(ctx, "Persisted %d view states", count)
+	}
+}
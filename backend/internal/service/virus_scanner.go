@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// Antivirus providers
+const (
+	AntivirusProviderNone   = ""
+	AntivirusProviderClamAV = "clamav"
+)
+
+const (
+	defaultScanTimeout = 30 * time.Second
+
+	// clamdChunkSize is the size of each INSTREAM chunk sent to clamd
+	clamdChunkSize = 64 * 1024
+)
+
+// ScanResult is the outcome of scanning a file
+type ScanResult struct {
+	Signature string
+	Infected  bool
+	Skipped   bool
+}
+
+// VirusScanner scans file contents for malware
+type VirusScanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+// NewVirusScanner creates the scanner for the configured provider
+func NewVirusScanner(cfg *config.AntivirusConfig) (VirusScanner, error) {
+	switch cfg.Provider {
+	case AntivirusProviderNone:
+		return NoopScanner{}, nil
+	case AntivirusProviderClamAV:
+		timeout := defaultScanTimeout
+		if cfg.Timeout != "" {
+			parsed, err := cfg.GetTimeoutDuration()
+			if err != nil {
+				return nil, fmt.Errorf("invalid antivirus timeout: %w", err)
+			}
+			timeout = parsed
+		}
+		return &ClamAVScanner{address: cfg.Address, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown antivirus provider: %s", cfg.Provider)
+	}
+}
+
+// NoopScanner skips scanning, used when no antivirus is configured
+type NoopScanner struct{}
+
+// Scan reports every file as skipped
+func (NoopScanner) Scan(_ context.Context, _ io.Reader) (*ScanResult, error) {
+	return &ScanResult{Skipped: true}, nil
+}
+
+// ClamAVScanner scans files with a clamd daemon over TCP
+type ClamAVScanner struct {
+	address string
+	timeout time.Duration
+}
+
+// Scan streams the file to clamd using the INSTREAM command
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set clamd deadline: %w", err)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to start clamd stream: %w", err)
+	}
+
+	// Each chunk is prefixed with its length, a zero length ends the stream
+	buf := make([]byte, clamdChunkSize)
+	var size [4]byte
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n)) //nolint:gosec // n is bounded by clamdChunkSize
+			if _, err := conn.Write(size[:]); err != nil {
+				return nil, fmt.Errorf("failed to write to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, fmt.Errorf("failed to write to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return nil, fmt.Errorf("failed to finish clamd stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply parses replies like "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseClamdReply(reply string) (*ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case result == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(result, "FOUND"):
+		return &ScanResult{
+			Infected:  true,
+			Signature: strings.TrimSpace(strings.TrimSuffix(result, "FOUND")),
+		}, nil
+	default:
+		return nil, fmt.Errorf("clamd error: %s", result)
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	// webPushTTL is how long push services keep a message for an offline browser
+	webPushTTL             = 24 * time.Hour
+	webPushTimeout         = 30 * time.Second
+	webPushMaxErrorBody    = 1024
+	maxPushUserAgentLength = 255
+)
+
+var (
+	// ErrWebPushDisabled is returned when no VAPID key is configured
+	ErrWebPushDisabled = errors.New("web push is not configured")
+	// ErrInvalidPushSubscription is returned for subscriptions with a bad
+	// endpoint or keys
+	ErrInvalidPushSubscription = errors.New("invalid push subscription")
+	// ErrPushSubscriptionNotFound is returned when removing an unknown subscription
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+)
+
+// WebPushService sends browser push notifications to users that have no
+// realtime connection, so they learn about mentions and invites with the app
+// closed
+type WebPushService struct {
+	subscriptionRepo *repository.PushSubscriptionRepository
+	hub              *Hub
+	vapidKey         *ecdsa.PrivateKey
+	httpClient       *http.Client
+	vapidPublicKey   string
+	subject          string
+}
+
+// NewWebPushService creates a new web push service. Web push is disabled
+// without a VAPID private key.
+func NewWebPushService(
+	cfg *config.WebPushConfig,
+	subscriptionRepo *repository.PushSubscriptionRepository,
+	hub *Hub,
+) (*WebPushService, error) {
+	s := &WebPushService{
+		subscriptionRepo: subscriptionRepo,
+		hub:              hub,
+		httpClient:       newRemoteClient(),
+		subject:          cfg.Subject,
+	}
+	if cfg.VAPIDPrivateKey == "" {
+		return s, nil
+	}
+
+	raw, err := decodeBase64URL(cfg.VAPIDPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	publicKey, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	s.vapidKey = key
+	s.vapidPublicKey = base64.RawURLEncoding.EncodeToString(publicKey)
+	return s, nil
+}
+
+// Enabled reports whether a VAPID key is configured
+func (s *WebPushService) Enabled() bool {
+	return s != nil && s.vapidKey != nil
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with, the
+// applicationServerKey of PushManager.subscribe
+func (s *WebPushService) PublicKey() (string, error) {
+	if !s.Enabled() {
+		return "", ErrWebPushDisabled
+	}
+	return s.vapidPublicKey, nil
+}
+
+// Subscribe stores the push subscription of a browser. Subscribing an
+// endpoint again updates its keys and owner.
+func (s *WebPushService) Subscribe(
+	ctx context.Context,
+	userID uuid.UUID,
+	req *models.RegisterPushSubscriptionRequest,
+	userAgent string,
+) (*models.PushSubscription, error) {
+	if !s.Enabled() {
+		return nil, ErrWebPushDisabled
+	}
+
+	endpoint, err := url.Parse(req.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: endpoint must be an https URL", ErrInvalidPushSubscription)
+	}
+	if p256dh, err := decodeBase64URL(req.Keys.P256dh); err != nil || len(p256dh) != pushPublicKeySize {
+		return nil, fmt.Errorf("%w: p256dh must be an uncompressed P-256 key", ErrInvalidPushSubscription)
+	}
+	if auth, err := decodeBase64URL(req.Keys.Auth); err != nil || len(auth) != pushAuthSecretSize {
+		return nil, fmt.Errorf("%w: auth must be a 16 byte secret", ErrInvalidPushSubscription)
+	}
+
+	if len(userAgent) > maxPushUserAgentLength {
+		userAgent = userAgent[:maxPushUserAgentLength]
+	}
+
+	sub := &models.PushSubscription{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: userAgent,
+	}
+	if err := s.subscriptionRepo.Upsert(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// Unsubscribe removes the push subscription of an endpoint
+func (s *WebPushService) Unsubscribe(ctx context.Context, userID uuid.UUID, endpoint string) error {
+	if !s.Enabled() {
+		return ErrWebPushDisabled
+	}
+
+	deleted, err := s.subscriptionRepo.Delete(ctx, userID, endpoint)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// NotifyOffline sends a push notification to every browser of a user, unless
+// the user is connected and already got the realtime notification
+func (s *WebPushService) NotifyOffline(ctx context.Context, userID uuid.UUID, msg *models.PushMessage) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	if s.hub != nil {
+		online, err := s.hub.IsUserOnline(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if online {
+			return nil
+		}
+	}
+
+	subscriptions, err := s.subscriptionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal push message: %w", err)
+	}
+
+	var errs []error
+	for i := range subscriptions {
+		if err := s.deliver(ctx, &subscriptions[i], payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notifyOffline runs NotifyOffline in the background and only logs failures,
+// push services can be slow and shouldn't hold up the request. A nil or
+// disabled service skips the push.
+func (s *WebPushService) notifyOffline(ctx context.Context, userID uuid.UUID, msg *models.PushMessage) {
+	if !s.Enabled() {
+		return
+	}
+
+	// The push outlives the request but stays part of its trace
+	ctx = tracing.Detach(ctx)
+
+	go func() {
+		ctx, span := tracing.Start(ctx, "webpush.notify", tracing.SpanKindProducer)
+		defer span.End()
+
+		ctx, cancel := context.WithTimeout(ctx, webPushTimeout)
+		defer cancel()
+
+		if err := s.NotifyOffline(ctx, userID, msg); err != nil {
+			span.RecordError(err)
+			hlog.CtxErrorf(ctx, "Failed to send push notification to user %s: %v", userID, err)
+		}
+	}()
+}
+
+// deliver sends a payload to one subscription. Subscriptions the push service
+// no longer knows are removed.
+func (s *WebPushService) deliver(ctx context.Context, sub *models.PushSubscription, payload []byte) error {
+	body, err := encryptPushPayload(sub.P256dh, sub.Auth, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt push message: %w", err)
+	}
+
+	authorization, err := s.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(webPushTTL.Seconds())))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// The browser unsubscribed or the subscription expired
+		return s.subscriptionRepo.DeleteByID(ctx, sub.ID)
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, webPushMaxErrorBody))
+		return fmt.Errorf("push service returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return s.subscriptionRepo.MarkUsed(ctx, sub.ID)
+}
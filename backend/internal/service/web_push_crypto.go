@@ -0,0 +1,155 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"crypto/hkdf"
+)
+
+const (
+	// vapidTokenExpiry is the lifetime of VAPID tokens, push services reject
+	// tokens valid for more than 24 hours
+	vapidTokenExpiry = 12 * time.Hour
+
+	// Sizes defined by RFC 8291
+	pushAuthSecretSize = 16
+	pushPublicKeySize  = 65
+	pushSaltSize       = 16
+	pushKeySize        = 16
+	pushNonceSize      = 12
+	pushIKMSize        = 32
+	pushRecordSize     = 4096
+	p256ScalarSize     = 32
+)
+
+// decodeBase64URL decodes base64url with or without padding, browsers and
+// key generators use both
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// vapidAuthorization returns the Authorization header value that identifies
+// this server to the push service of the endpoint (RFC 8292)
+func (s *WebPushService) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenExpiry).Unix(),
+		"sub": s.subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal VAPID claims: %w", err)
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signingInput))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.vapidKey, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	// JWS uses the fixed size r || s encoding instead of ASN.1
+	signature := make([]byte, 2*p256ScalarSize)
+	r.FillBytes(signature[:p256ScalarSize])
+	sig.FillBytes(signature[p256ScalarSize:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return "vapid t=" + token + ", k=" + s.vapidPublicKey, nil
+}
+
+// encryptPushPayload encrypts a payload for a browser subscription with the
+// aes128gcm content encoding (RFC 8291, RFC 8188)
+func encryptPushPayload(p256dh, authSecret string, plaintext []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(p256dh)
+	if err != nil || len(uaPublic) != pushPublicKeySize {
+		return nil, ErrInvalidPushSubscription
+	}
+	auth, err := decodeBase64URL(authSecret)
+	if err != nil || len(auth) != pushAuthSecretSize {
+		return nil, ErrInvalidPushSubscription
+	}
+
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, ErrInvalidPushSubscription
+	}
+
+	// Every message uses a new ephemeral key and salt
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate push key: %w", err)
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	sharedSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive push secret: %w", err)
+	}
+
+	salt := make([]byte, pushSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate push salt: %w", err)
+	}
+
+	// IKM = HKDF(auth, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public)
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, auth, keyInfo, pushIKMSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive push key: %w", err)
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive push key: %w", err)
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", pushKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive push key: %w", err)
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", pushNonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive push nonce: %w", err)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create push cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create push cipher: %w", err)
+	}
+
+	// A single record, ended by the 0x02 padding delimiter
+	record := append(append([]byte{}, plaintext...), 0x02)
+	if len(record)+gcm.Overhead() > pushRecordSize {
+		return nil, fmt.Errorf("push payload too large: %d bytes", len(plaintext))
+	}
+
+	// Header: salt || record size || key ID length || key ID (as_public)
+	body := make([]byte, 0, pushSaltSize+4+1+len(asPublic)+len(record)+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, pushRecordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+	body = gcm.Seal(body, nonce, record, nil)
+
+	return body, nil
+}
@@ -0,0 +1,508 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	// WebhookDeliverySubject is the subject webhook deliveries are queued on
+	WebhookDeliverySubject = "webhooks.deliveries"
+
+	webhookStreamName = "WEBHOOK_DELIVERIES"
+
+	// Headers sent with every webhook request
+	webhookEventHeader     = "X-HertzBoard-Event"
+	webhookDeliveryHeader  = "X-HertzBoard-Delivery"
+	webhookSignatureHeader = "X-HertzBoard-Signature"
+	webhookUserAgent       = "HertzBoard-Webhooks/1.0"
+
+	maxWebhooksPerWorkspace = 20
+	maxWebhookURLLength     = 2048
+	webhookSecretBytes      = 32
+	webhookMaxErrorLength   = 512
+)
+
+var (
+	// ErrWebhookNotFound is returned for unknown webhooks
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrWebhookLimitReached is returned when a workspace has too many webhooks
+	ErrWebhookLimitReached = errors.New("webhook limit reached")
+)
+
+// WebhookService manages workspace webhooks and delivers events to them.
+// Events are matched to webhooks by the dispatcher, and every match is
+// queued as a delivery on JetStream so it's retried until it succeeds.
+//
+// Webhooks in a chat format get the event rendered as a chat message
+// instead, see ChatFormatter. REST hooks of automation platforms get the
+// trigger items of the event, see TriggerService. Users can keep their activity out of chat
+// messages with the mute_webhooks notification preference.
+type WebhookService struct {
+	webhookRepo      *repository.WebhookRepository
+	workspaceRepo    *repository.WorkspaceRepository
+	canvasRepo       *repository.CanvasRepository
+	userRepo         *repository.UserRepository
+	notificationRepo *repository.NotificationRepository
+	js               nats.JetStreamContext
+	httpClient       *http.Client
+	frontendURL      string
+}
+
+// NewWebhookService creates a webhook service and ensures the delivery stream
+// exists. frontendURL is used for links in chat messages.
+func NewWebhookService(
+	webhookRepo *repository.WebhookRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	canvasRepo *repository.CanvasRepository,
+	userRepo *repository.UserRepository,
+	notificationRepo *repository.NotificationRepository,
+	nc *nats.Conn,
+	frontendURL string,
+) (*WebhookService, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	if err := ensureStream(js, &nats.StreamConfig{
+		Name:      webhookStreamName,
+		Subjects:  []string{WebhookDeliverySubject},
+		Storage:   nats.FileStorage,
+		Retention: nats.WorkQueuePolicy,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &WebhookService{
+		webhookRepo:      webhookRepo,
+		workspaceRepo:    workspaceRepo,
+		canvasRepo:       canvasRepo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		js:               js,
+		// Webhook URLs are user supplied, so internal addresses are refused
+		httpClient:  newRemoteClient(),
+		frontendURL: frontendURL,
+	}, nil
+}
+
+// CreateWebhook registers a webhook and returns it with its signing secret
+func (s *WebhookService) CreateWebhook(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.CreateWebhookRequest,
+) (*models.WebhookWithSecret, error) {
+	if req.Format == "" {
+		req.Format = models.WebhookFormatJSON
+	}
+
+	events, err := validateWebhook(req.URL, req.Format, req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhookRepo.ListWebhooks(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerWorkspace {
+		return nil, ErrWebhookLimitReached
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		URL:         req.URL,
+		Format:      req.Format,
+		Secret:      secret,
+		Events:      events,
+		Active:      true,
+		CreatedBy:   &userID,
+	}
+
+	if err := s.webhookRepo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookWithSecret{Webhook: *webhook, Secret: secret}, nil
+}
+
+// ListWebhooks returns the webhooks of a workspace
+func (s *WebhookService) ListWebhooks(ctx context.Context, workspaceID uuid.UUID) ([]models.Webhook, error) {
+	return s.webhookRepo.ListWebhooks(ctx, workspaceID)
+}
+
+// GetWebhook returns a webhook of a workspace
+func (s *WebhookService) GetWebhook(ctx context.Context, workspaceID, webhookID uuid.UUID) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.GetWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook changes a webhook. The secret is only included in the
+// result when it was rotated.
+func (s *WebhookService) UpdateWebhook(
+	ctx context.Context,
+	workspaceID, webhookID uuid.UUID,
+	req *models.UpdateWebhookRequest,
+) (*models.WebhookWithSecret, error) {
+	webhook, err := s.GetWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Format != nil {
+		webhook.Format = *req.Format
+	}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	events, err := validateWebhook(webhook.URL, webhook.Format, webhook.Events)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = events
+
+	result := &models.WebhookWithSecret{}
+	if req.RotateSecret {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+		result.Secret = secret
+	}
+
+	if err := s.webhookRepo.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	result.Webhook = *webhook
+	return result, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, workspaceID, webhookID uuid.UUID) error {
+	deleted, err := s.webhookRepo.DeleteWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first
+func (s *WebhookService) ListDeliveries(
+	ctx context.Context,
+	workspaceID, webhookID uuid.UUID,
+	limit, offset int,
+) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, workspaceID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, webhookID, limit, offset)
+}
+
+// TestWebhook sends a webhook.test event right away and returns the logged
+// delivery, so owners can check their endpoint and signature verification.
+// Failed test deliveries are not retried.
+func (s *WebhookService) TestWebhook(
+	ctx context.Context,
+	workspaceID, webhookID, userID uuid.UUID,
+) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &models.Event{
+		ID:          uuid.New(),
+		Type:        models.EventWebhookTest,
+		WorkspaceID: workspaceID,
+		ActorID:     &userID,
+		OccurredAt:  time.Now(),
+		Data:        map[string]interface{}{"webhook_id": webhook.ID},
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	delivery, err := s.createDelivery(ctx, webhook, event, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = models.WebhookDeliveryFailed
+	if s.attemptDelivery(ctx, webhook, delivery) {
+		delivery.Status = models.WebhookDeliverySucceeded
+	}
+	if err := s.webhookRepo.UpdateDeliveryAttempt(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// DispatchEvent queues a delivery of the event for every active webhook of
+// its workspace that subscribed to the event type. Chat webhooks are skipped
+// if the actor muted webhooks.
+func (s *WebhookService) DispatchEvent(ctx context.Context, event *models.Event, payload []byte) error {
+	webhooks, err := s.webhookRepo.GetActiveWebhooks(ctx, event.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	muted, err := s.actorMutedWebhooks(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	for i := range webhooks {
+		if !webhooks[i].Subscribed(event.Type) {
+			continue
+		}
+		if _, chat := chatFormatters[webhooks[i].Format]; chat && muted {
+			continue
+		}
+
+		delivery, err := s.createDelivery(ctx, &webhooks[i], event, payload)
+		if err != nil {
+			return err
+		}
+
+		msg := tracing.NewMsg(ctx, WebhookDeliverySubject, []byte(delivery.ID.String()))
+		if _, err := s.js.PublishMsg(msg); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// actorMutedWebhooks reports whether the user who caused an event keeps
+// their activity out of chat webhooks
+func (s *WebhookService) actorMutedWebhooks(ctx context.Context, event *models.Event) (bool, error) {
+	if event.ActorID == nil {
+		return false, nil
+	}
+
+	prefs, err := s.notificationRepo.GetPreferences(ctx, *event.ActorID)
+	if err != nil {
+		return false, err
+	}
+	return prefs != nil && prefs.MuteWebhooks, nil
+}
+
+// Deliver makes one attempt of a queued delivery and records the outcome.
+// It returns whether the delivery is finished, either because it succeeded or
+// because there is nothing left to deliver.
+func (s *WebhookService) Deliver(ctx context.Context, deliveryID uuid.UUID, finalAttempt bool) (bool, error) {
+	delivery, err := s.webhookRepo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return false, err
+	}
+	if delivery == nil || delivery.Status != models.WebhookDeliveryPending {
+		return true, nil
+	}
+
+	webhook, err := s.webhookRepo.GetWebhookByID(ctx, delivery.WebhookID)
+	if err != nil {
+		return false, err
+	}
+	if webhook == nil || !webhook.Active {
+		msg := "webhook was deleted or disabled"
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = &msg
+		return true, s.webhookRepo.UpdateDeliveryAttempt(ctx, delivery)
+	}
+
+	succeeded := s.attemptDelivery(ctx, webhook, delivery)
+	switch {
+	case succeeded:
+		delivery.Status = models.WebhookDeliverySucceeded
+	case finalAttempt:
+		delivery.Status = models.WebhookDeliveryFailed
+	}
+
+	if err := s.webhookRepo.UpdateDeliveryAttempt(ctx, delivery); err != nil {
+		return false, err
+	}
+
+	return succeeded, nil
+}
+
+func (s *WebhookService) createDelivery(
+	ctx context.Context,
+	webhook *models.Webhook,
+	event *models.Event,
+	payload []byte,
+) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   payload,
+		Status:    models.WebhookDeliveryPending,
+	}
+
+	if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// attemptDelivery posts the payload to the webhook and updates the attempt
+// count, response status and error of the delivery. Any 2xx response counts
+// as delivered. Chat webhooks get the payload rendered as a chat message,
+// REST hooks the trigger items of the event.
+func (s *WebhookService) attemptDelivery(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) bool {
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+	delivery.Error = nil
+
+	fail := func(err error) bool {
+		msg := err.Error()
+		if len(msg) > webhookMaxErrorLength {
+			msg = msg[:webhookMaxErrorLength]
+		}
+		delivery.Error = &msg
+		return false
+	}
+
+	body := []byte(delivery.Payload)
+	if formatter, ok := chatFormatters[webhook.Format]; ok {
+		rendered, err := s.renderChatPayload(ctx, formatter, delivery)
+		if err != nil {
+			return fail(err)
+		}
+		body = rendered
+	}
+	if webhook.Format == models.WebhookFormatRESTHook {
+		rendered, err := s.renderRESTHookPayload(ctx, delivery)
+		if err != nil {
+			return fail(err)
+		}
+		body = rendered
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(fmt.Errorf("failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(webhook.Secret, time.Now(), body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookMaxErrorLength))
+
+	status := resp.StatusCode
+	delivery.ResponseStatus = &status
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return fail(fmt.Errorf("endpoint responded with status %d", status))
+	}
+
+	now := time.Now()
+	delivery.DeliveredAt = &now
+	return true
+}
+
+// signWebhookPayload returns the signature header value
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">". Receivers
+// should recompute it with the webhook secret and reject old timestamps.
+func signWebhookPayload(secret string, now time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmacSHA256([]byte(secret), timestamp+"."+string(payload))
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac)
+}
+
+// validateWebhook checks the URL, format and event filter of a webhook and
+// returns the deduplicated events
+func validateWebhook(rawURL, format string, events []string) ([]string, error) {
+	_, chat := chatFormatters[format]
+	if !chat && format != models.WebhookFormatJSON && format != models.WebhookFormatRESTHook {
+		return nil, fmt.Errorf("unknown webhook format: %s", format)
+	}
+
+	if rawURL == "" || len(rawURL) > maxWebhookURLLength {
+		return nil, fmt.Errorf("url is required and must be at most %d characters", maxWebhookURLLength)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if err := validateRemoteURL(u); err != nil {
+		return nil, err
+	}
+	// Chat providers only issue HTTPS webhook URLs
+	if chat && u.Scheme != "https" {
+		return nil, errors.New("chat webhook urls must use https")
+	}
+
+	seen := make(map[string]bool, len(events))
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !models.ValidEventType(event) {
+			return nil, fmt.Errorf("unknown event type: %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+
+	return unique, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/tracing"
+)
+
+const (
+	webhookDispatcherQueue = "webhook-dispatchers"
+	webhookConsumerName    = "webhook-workers"
+
+	webhookDispatchTimeout = 10 * time.Second
+	webhookDeliveryTimeout = 30 * time.Second
+	webhookAckWait         = time.Minute
+	webhookMaxAttempts     = 8
+	webhookRetryBackoff    = 30 * time.Second
+	webhookMaxRetryBackoff = time.Hour
+)
+
+// WebhookWorker matches domain events to webhooks and delivers the queued
+// deliveries. Failed deliveries are retried with exponential backoff.
+type WebhookWorker struct {
+	webhookService *WebhookService
+	js             nats.JetStreamContext
+	eventSub       *nats.Subscription
+	deliverySub    *nats.Subscription
+}
+
+// NewWebhookWorker creates and starts a webhook worker
+func NewWebhookWorker(nc *nats.Conn, webhookService *WebhookService) (*WebhookWorker, error) {
+	worker := &WebhookWorker{
+		webhookService: webhookService,
+		js:             webhookService.js,
+	}
+
+	eventSub, err := nc.QueueSubscribe(EventSubjectPrefix+">", webhookDispatcherQueue, worker.handleEvent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+	worker.eventSub = eventSub
+
+	deliverySub, err := worker.js.QueueSubscribe(WebhookDeliverySubject, webhookConsumerName, worker.handleDelivery,
+		nats.Durable(webhookConsumerName),
+		nats.BindStream(webhookStreamName),
+		nats.ManualAck(),
+		nats.AckWait(webhookAckWait),
+	)
+	if err != nil {
+		_ = eventSub.Unsubscribe()
+		return nil, fmt.Errorf("failed to subscribe to webhook deliveries: %w", err)
+	}
+	worker.deliverySub = deliverySub
+
+	return worker, nil
+}
+
+// Close stops the webhook worker subscriptions
+func (w *WebhookWorker) Close() error {
+	if err := w.eventSub.Unsubscribe(); err != nil {
+		return err
+	}
+	return w.deliverySub.Unsubscribe()
+}
+
+// handleEvent queues deliveries for a domain event
+func (w *WebhookWorker) handleEvent(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "webhook.dispatch")
+	defer span.End()
+
+	var event models.Event
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to unmarshal event: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookDispatchTimeout)
+	defer cancel()
+
+	if err := w.webhookService.DispatchEvent(ctx, &event, msg.Data); err != nil {
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to dispatch %s event to webhooks: %v", event.Type, err)
+	}
+}
+
+// handleDelivery makes one delivery attempt and schedules a retry on failure
+func (w *WebhookWorker) handleDelivery(msg *nats.Msg) {
+	ctx, span := tracing.StartConsumer(msg, "webhook.deliver")
+	defer span.End()
+
+	deliveryID, err := uuid.Parse(string(msg.Data))
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Invalid webhook delivery ID: %v", err)
+		_ = msg.Term()
+		return
+	}
+
+	attempt := 1
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		attempt = int(meta.NumDelivered)
+	}
+	finalAttempt := attempt >= webhookMaxAttempts
+
+	ctx, cancel := context.WithTimeout(ctx, webhookDeliveryTimeout)
+	defer cancel()
+
+	done, err := w.webhookService.Deliver(ctx, deliveryID, finalAttempt)
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		hlog.CtxErrorf(ctx, "Failed to process webhook delivery %s: %v", deliveryID, err)
+		_ = msg.NakWithDelay(retryDelay(attempt, webhookRetryBackoff, webhookMaxRetryBackoff))
+	case done:
+		_ = msg.Ack()
+	case finalAttempt:
+		hlog.CtxErrorf(ctx, "Webhook delivery %s failed after %d attempts", deliveryID, attempt)
+		_ = msg.Term()
+	default:
+		_ = msg.NakWithDelay(retryDelay(attempt, webhookRetryBackoff, webhookMaxRetryBackoff))
+	}
+}
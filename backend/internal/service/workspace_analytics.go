@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	// DefaultAnalyticsDays is the range of workspace analytics when none is asked for
+	DefaultAnalyticsDays = 30
+	// MaxAnalyticsDays is the longest range of workspace analytics
+	MaxAnalyticsDays = 365
+
+	// DefaultHeatmapDays is the range of heatmaps when none is asked for
+	DefaultHeatmapDays = 7
+	// DefaultHeatmapResolution is the number of cells along the longer side
+	// of heatmaps when none is asked for, MaxHeatmapResolution the most
+	DefaultHeatmapResolution = 64
+	MaxHeatmapResolution     = 256
+
+	analyticsDateFormat = "2006-01-02"
+	hoursPerDay         = 24
+	// minHeatmapCellSize keeps heatmaps of tiny areas from cells smaller
+	// than a board unit
+	minHeatmapCellSize = 1.0
+)
+
+// ErrAnalyticsDisabled is returned when ClickHouse isn't configured
+var ErrAnalyticsDisabled = errors.New("analytics are not enabled")
+
+// WorkspaceAnalyticsService reports the engagement of workspaces, activity
+// from the analytics events in ClickHouse and element counts from PostgreSQL.
+// A nil service means analytics are disabled.
+type WorkspaceAnalyticsService struct {
+	analyticsRepo *repository.AnalyticsRepository
+	canvasRepo    *repository.CanvasRepository
+}
+
+// NewWorkspaceAnalyticsService creates a new workspace analytics service
+func NewWorkspaceAnalyticsService(
+	analyticsRepo *repository.AnalyticsRepository,
+	canvasRepo *repository.CanvasRepository,
+) *WorkspaceAnalyticsService {
+	return &WorkspaceAnalyticsService{
+		analyticsRepo: analyticsRepo,
+		canvasRepo:    canvasRepo,
+	}
+}
+
+// GetWorkspaceAnalytics returns the engagement of a workspace over the last
+// days UTC days, today included. Every day and hour of the range is present,
+// with zero counts where nothing happened.
+func (s *WorkspaceAnalyticsService) GetWorkspaceAnalytics(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	days int,
+) (*models.WorkspaceAnalytics, error) {
+	if s == nil {
+		return nil, ErrAnalyticsDisabled
+	}
+	if days <= 0 {
+		days = DefaultAnalyticsDays
+	}
+	days = min(days, MaxAnalyticsDays)
+
+	to := time.Now().UTC().Truncate(hoursPerDay*time.Hour).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+
+	active, err := s.analyticsRepo.GetDailyActiveUsers(ctx, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	edits, err := s.analyticsRepo.GetDailyEdits(ctx, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	hourly, err := s.analyticsRepo.GetHourlyEdits(ctx, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	growth, err := s.canvasRepo.GetElementGrowth(ctx, workspaceID, from, to.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.analyticsRepo.GetDailyViews(ctx, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	viewers, err := s.analyticsRepo.GetDailyUniqueViewers(ctx, workspaceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busiest := make([]models.HourlyCount, hoursPerDay)
+	for hour := range busiest {
+		busiest[hour].Hour = hour
+	}
+	for _, count := range hourly {
+		if count.Hour >= 0 && count.Hour < hoursPerDay {
+			busiest[count.Hour].Count = count.Count
+		}
+	}
+
+	return &models.WorkspaceAnalytics{
+		From:          from,
+		To:            to,
+		DailyActive:   fillDays(from, days, active),
+		Edits:         fillDays(from, days, edits),
+		BusiestHours:  busiest,
+		ElementGrowth: fillDays(from, days, growth),
+		Views:         fillDays(from, days, views),
+		UniqueViewers: fillDays(from, days, viewers),
+	}, nil
+}
+
+// GetHeatmap returns the density of the cursor samples of a workspace in
+// [from, to) on a grid of square cells, resolution of them along the longer
+// side of the area the samples fell in. An empty kind covers cursor moves
+// and selections.
+func (s *WorkspaceAnalyticsService) GetHeatmap(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	from, to time.Time,
+	kind string,
+	resolution int,
+) (*models.Heatmap, error) {
+	if s == nil {
+		return nil, ErrAnalyticsDisabled
+	}
+	if resolution <= 0 {
+		resolution = DefaultHeatmapResolution
+	}
+	resolution = min(resolution, MaxHeatmapResolution)
+
+	heatmap := &models.Heatmap{
+		From:     from,
+		To:       to,
+		Kind:     kind,
+		CellSize: minHeatmapCellSize,
+		Cells:    [][]int64{},
+	}
+
+	bounds, err := s.analyticsRepo.GetCursorBounds(ctx, workspaceID, from, to, kind)
+	if err != nil {
+		return nil, err
+	}
+	if bounds == nil {
+		return heatmap, nil
+	}
+
+	width := bounds.MaxX - bounds.MinX
+	height := bounds.MaxY - bounds.MinY
+	cellSize := math.Max(math.Max(width, height)/float64(resolution), minHeatmapCellSize)
+	// Samples on the far edges fall in the last cells
+	columns := min(int(width/cellSize)+1, resolution)
+	rows := min(int(height/cellSize)+1, resolution)
+
+	cells, err := s.analyticsRepo.GetCursorDensity(
+		ctx, workspaceID, from, to, kind, bounds.MinX, bounds.MinY, cellSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	grid := make([][]int64, rows)
+	for row := range grid {
+		grid[row] = make([]int64, columns)
+	}
+	for _, cell := range cells {
+		column := max(0, min(cell.Column, columns-1))
+		row := max(0, min(cell.Row, rows-1))
+		grid[row][column] += cell.Count
+		heatmap.MaxCount = max(heatmap.MaxCount, grid[row][column])
+	}
+
+	heatmap.OriginX = bounds.MinX
+	heatmap.OriginY = bounds.MinY
+	heatmap.CellSize = cellSize
+	heatmap.Columns = columns
+	heatmap.Rows = rows
+	heatmap.Samples = bounds.Samples
+	heatmap.Cells = grid
+	return heatmap, nil
+}
+
+// fillDays returns one count per day starting at from, zero for the days
+// missing in counts
+func fillDays(from time.Time, days int, counts []models.DailyCount) []models.DailyCount {
+	byDate := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		byDate[count.Date] = count.Count
+	}
+
+	series := make([]models.DailyCount, days)
+	for i := range series {
+		date := from.AddDate(0, 0, i).Format(analyticsDateFormat)
+		series[i] = models.DailyCount{Date: date, Count: byDate[date]}
+	}
+	return series
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	// healthLargestElements is how many of the largest elements are listed
+	healthLargestElements = 10
+	// healthMaxGroupDepth stops following group nesting, deeper is reported
+	// as this deep
+	healthMaxGroupDepth = 64
+
+	// Thresholds past which recommendations are made. Trash is only worth
+	// purging once it is at least a quarter of the live elements.
+	healthElements               = 20000
+	healthOperations             = 100000
+	healthOperationsPerElement   = 50
+	healthTrashedElements        = 1000
+	healthTrashedElementsDivisor = 4
+	healthLargeElementBytes      = 256 << 10
+	healthGroupDepth             = 8
+	healthSnapshots              = 100
+
+	bytesPerMiB = 1 << 20
+)
+
+// WorkspaceHealthService reports how much data workspaces keep and what
+// owners can do to keep giant boards performant. Sizes are read from the
+// replica, they may lag a little behind.
+type WorkspaceHealthService struct {
+	workspaceRepo *repository.WorkspaceRepository
+	canvasRepo    *repository.CanvasRepository
+	snapshotRepo  *repository.SnapshotRepository
+}
+
+// NewWorkspaceHealthService creates a new workspace health service
+func NewWorkspaceHealthService(
+	workspaceRepo *repository.WorkspaceRepository,
+	canvasRepo *repository.CanvasRepository,
+	snapshotRepo *repository.SnapshotRepository,
+) *WorkspaceHealthService {
+	return &WorkspaceHealthService{
+		workspaceRepo: workspaceRepo,
+		canvasRepo:    canvasRepo,
+		snapshotRepo:  snapshotRepo,
+	}
+}
+
+// GetHealth returns the sizes of the data of a workspace, its largest
+// elements, how deep its groups nest and recommendations
+func (s *WorkspaceHealthService) GetHealth(ctx context.Context, workspaceID uuid.UUID) (*models.WorkspaceHealth, error) {
+	sizes, err := s.workspaceRepo.GetWorkspaceSizes(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	largest, err := s.canvasRepo.GetLargestElements(ctx, workspaceID, healthLargestElements)
+	if err != nil {
+		return nil, err
+	}
+	depth, deepest, err := s.canvasRepo.GetMaxGroupDepth(ctx, workspaceID, healthMaxGroupDepth)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.snapshotRepo.GetRetentionPolicy(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &models.WorkspaceHealth{
+		GeneratedAt:      time.Now().UTC(),
+		Sizes:            *sizes,
+		LargestElements:  largest,
+		DeepestElementID: deepest,
+		MaxGroupDepth:    depth,
+	}
+	health.Recommendations = recommendHealth(health, policy != nil)
+	return health, nil
+}
+
+// recommendHealth returns what to do about the data of a workspace that
+// slows its board down, most impactful first
+func recommendHealth(health *models.WorkspaceHealth, hasRetention bool) []models.HealthRecommendation {
+	sizes := health.Sizes
+	recommendations := []models.HealthRecommendation{}
+	add := func(code, format string, args ...interface{}) {
+		recommendations = append(recommendations, models.HealthRecommendation{
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if sizes.Elements.Count > healthElements {
+		add(models.HealthSplitBoard,
+			"The board has %d elements. Split it into several boards to keep it responsive.",
+			sizes.Elements.Count)
+	}
+	if sizes.Operations.Count > healthOperations &&
+		sizes.Operations.Count > healthOperationsPerElement*max(sizes.Elements.Count, 1) {
+		add(models.HealthCompactOperations,
+			"%d operations (%s) are stored for %d elements. Save a snapshot and compact the operation history "+
+				"to speed up sync and replay.",
+			sizes.Operations.Count, formatMiB(sizes.Operations.Bytes), sizes.Elements.Count)
+	}
+	if sizes.TrashedElements.Count > healthTrashedElements &&
+		sizes.TrashedElements.Count*healthTrashedElementsDivisor >= sizes.Elements.Count {
+		add(models.HealthPurgeTrash,
+			"%d deleted elements (%s) are kept so they can be restored. Purge the ones no longer needed.",
+			sizes.TrashedElements.Count, formatMiB(sizes.TrashedElements.Bytes))
+	}
+
+	large := 0
+	for _, element := range health.LargestElements {
+		if element.Bytes > healthLargeElementBytes {
+			large++
+		}
+	}
+	if large > 0 {
+		add(models.HealthSplitLargeElements,
+			"%d of the largest elements are over %d KiB. Move large content such as embedded images to assets.",
+			large, healthLargeElementBytes>>10)
+	}
+
+	if health.MaxGroupDepth > healthGroupDepth {
+		add(models.HealthFlattenGroups,
+			"Groups nest %d levels deep. Flatten them to make selecting and moving elements faster.",
+			health.MaxGroupDepth)
+	}
+	if sizes.Snapshots.Count > healthSnapshots && !hasRetention {
+		add(models.HealthSnapshotRetention,
+			"%d snapshots (%s) are kept. Set a snapshot retention policy to prune old ones.",
+			sizes.Snapshots.Count, formatMiB(sizes.Snapshots.Bytes))
+	}
+
+	return recommendations
+}
+
+// formatMiB formats a size in MiB for recommendations
+func formatMiB(bytes int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/bytesPerMiB)
+}
@@ -2,30 +2,108 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bifshteksex/hertz-board/internal/models"
 	"github.com/bifshteksex/hertz-board/internal/repository"
 
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/google/uuid"
 )
 
+// Mention autocomplete sizes
+const (
+	defaultMemberSuggestions = 10
+	maxMemberSuggestions     = 25
+)
+
+// maxAccessRequestMessage is the longest note users can add to an access
+// request, in characters
+const maxAccessRequestMessage = 500
+
+// maxContributors is how many contributors of a board are returned
+const maxContributors = 200
+
+var (
+	// ErrWorkspaceNotFound is returned for unknown and deleted workspaces
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	// ErrWorkspaceAccessDenied is returned to users that aren't members of a
+	// workspace they have no public access to
+	ErrWorkspaceAccessDenied = errors.New("access denied")
+	// ErrInviteNotFound is returned for unknown invitations and those
+	// addressed to someone else
+	ErrInviteNotFound = errors.New("invitation not found")
+	// ErrInviteLinkNotFound is returned for unknown invite links
+	ErrInviteLinkNotFound = errors.New("invite link not found")
+	// ErrAlreadyMember is returned when users ask to join a workspace twice
+	ErrAlreadyMember = errors.New("you are already a member of this workspace")
+	// ErrAccessRequestNotFound is returned for unknown access requests
+	ErrAccessRequestNotFound = errors.New("access request not found")
+	// ErrAccessRequestPending is returned when a user already waits for an
+	// answer to a request
+	ErrAccessRequestPending = errors.New("access was already requested")
+	// ErrAccessRequestDecided is returned when approving or denying a
+	// request that was already answered
+	ErrAccessRequestDecided = errors.New("access request was already answered")
+	// ErrInvalidPublicRole is returned for public roles other than viewer
+	// and commenter
+	ErrInvalidPublicRole = errors.New("public role must be viewer or commenter")
+	// ErrDuplicateNotAllowed is returned to non-members duplicating a public
+	// workspace whose owner doesn't allow it
+	ErrDuplicateNotAllowed = errors.New("duplicating this workspace is not allowed")
+	// ErrInvalidFreezeRole is returned for freeze roles other than owner and
+	// editor
+	ErrInvalidFreezeRole = errors.New("freeze role must be owner or editor")
+	// ErrFreezeForbidden is returned to members whose role may not freeze
+	// the board
+	ErrFreezeForbidden = errors.New("your role can't freeze or unfreeze this board")
+	// ErrBoardFrozen is returned for changes to a frozen board
+	ErrBoardFrozen = errors.New("the board is frozen")
+)
+
 type WorkspaceService struct {
 	workspaceRepo *repository.WorkspaceRepository
 	userRepo      *repository.UserRepository
 	emailService  *EmailService
+	events        *EventPublisher
+	webPush       *WebPushService
+	billing       *BillingService
+	audit         *AuditService
+	visits        *WorkspaceVisits
+	notifications *NotificationService
+	rooms         RoomBroadcaster
+	views         *WorkspaceViews
 }
 
 func NewWorkspaceService(
 	workspaceRepo *repository.WorkspaceRepository,
 	userRepo *repository.UserRepository,
 	emailService *EmailService,
+	events *EventPublisher,
+	webPush *WebPushService,
+	billing *BillingService,
+	audit *AuditService,
+	visits *WorkspaceVisits,
+	notifications *NotificationService,
+	rooms RoomBroadcaster,
+	views *WorkspaceViews,
 ) *WorkspaceService {
 	return &WorkspaceService{
 		workspaceRepo: workspaceRepo,
 		userRepo:      userRepo,
 		emailService:  emailService,
+		events:        events,
+		webPush:       webPush,
+		billing:       billing,
+		audit:         audit,
+		visits:        visits,
+		notifications: notifications,
+		rooms:         rooms,
+		views:         views,
 	}
 }
 
@@ -65,7 +143,7 @@ func (s *WorkspaceService) GetWorkspace(ctx context.Context, id uuid.UUID) (*mod
 	}
 
 	if workspace == nil {
-		return nil, fmt.Errorf("workspace not found")
+		return nil, ErrWorkspaceNotFound
 	}
 
 	return workspace, nil
@@ -86,7 +164,7 @@ func (s *WorkspaceService) GetWorkspaceWithRole(ctx context.Context, workspaceID
 	if member == nil {
 		// Check if workspace is public
 		if !workspace.IsPublic {
-			return nil, fmt.Errorf("access denied")
+			return nil, ErrWorkspaceAccessDenied
 		}
 		// Public workspace, viewer role
 		return &models.WorkspaceWithRole{
@@ -101,11 +179,20 @@ func (s *WorkspaceService) GetWorkspaceWithRole(ctx context.Context, workspaceID
 		return nil, fmt.Errorf("failed to get owner: %w", err)
 	}
 
-	return &models.WorkspaceWithRole{
+	withRole := &models.WorkspaceWithRole{
 		Workspace: *workspace,
 		UserRole:  member.Role,
 		Owner:     owner,
-	}, nil
+	}
+	if member.Role == models.WorkspaceRoleOwner {
+		counts, err := s.workspaceRepo.GetViewCounts(ctx, []uuid.UUID{workspaceID})
+		if err != nil {
+			return nil, err
+		}
+		views := counts[workspaceID]
+		withRole.Views = &views
+	}
+	return withRole, nil
 }
 
 // UpdateWorkspace updates workspace information
@@ -135,6 +222,17 @@ func (s *WorkspaceService) UpdateWorkspace(
 	if req.Settings != nil {
 		workspace.Settings = req.Settings
 	}
+	if req.PublicAccess != nil {
+		if err := applyPublicAccess(&workspace.PublicAccess, req.PublicAccess); err != nil {
+			return nil, err
+		}
+	}
+	if req.FreezeRole != nil {
+		if *req.FreezeRole != models.WorkspaceRoleOwner && *req.FreezeRole != models.WorkspaceRoleEditor {
+			return nil, ErrInvalidFreezeRole
+		}
+		workspace.FreezeRole = *req.FreezeRole
+	}
 
 	if err := s.workspaceRepo.UpdateWorkspace(ctx, workspace); err != nil {
 		return nil, fmt.Errorf("failed to update workspace: %w", err)
@@ -149,6 +247,13 @@ func (s *WorkspaceService) DeleteWorkspace(ctx context.Context, workspaceID uuid
 		return fmt.Errorf("failed to delete workspace: %w", err)
 	}
 
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditWorkspaceDeleted,
+		WorkspaceID: &workspaceID,
+		TargetType:  "workspace",
+		TargetID:    workspaceID.String(),
+	})
+
 	return nil
 }
 
@@ -158,6 +263,14 @@ func (s *WorkspaceService) ListUserWorkspaces(
 	userID uuid.UUID,
 	filter models.WorkspaceListFilter,
 ) (*models.WorkspaceListResponse, error) {
+	if filter.Recent && s.visits != nil {
+		// Visits are persisted periodically, the latest ones may still be
+		// buffered
+		if err := s.visits.FlushUser(ctx, userID); err != nil {
+			hlog.CtxWarnf(ctx, "Failed to persist workspace visits of user %s: %v", userID, err)
+		}
+	}
+
 	workspaces, total, err := s.workspaceRepo.ListWorkspacesByUser(ctx, userID, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list workspaces: %w", err)
@@ -172,26 +285,21 @@ func (s *WorkspaceService) ListUserWorkspaces(
 	}
 
 	for i := range workspaces {
-		// Get owner info
-		owner, err := s.userRepo.GetByID(ctx, workspaces[i].OwnerID)
-		if err != nil {
-			continue // Skip on error
-		}
-
 		wsResp := models.WorkspaceResponse{
-			ID:           workspaces[i].ID,
-			Name:         workspaces[i].Name,
-			Description:  workspaces[i].Description,
-			OwnerID:      workspaces[i].OwnerID,
-			ThumbnailURL: workspaces[i].ThumbnailURL,
-			IsPublic:     workspaces[i].IsPublic,
-			Settings:     workspaces[i].Settings,
-			CreatedAt:    workspaces[i].CreatedAt,
-			UpdatedAt:    workspaces[i].UpdatedAt,
-			UserRole:     &workspaces[i].UserRole,
+			ID:            workspaces[i].ID,
+			Name:          workspaces[i].Name,
+			Description:   workspaces[i].Description,
+			OwnerID:       workspaces[i].OwnerID,
+			ThumbnailURL:  workspaces[i].ThumbnailURL,
+			IsPublic:      workspaces[i].IsPublic,
+			Settings:      workspaces[i].Settings,
+			CreatedAt:     workspaces[i].CreatedAt,
+			UpdatedAt:     workspaces[i].UpdatedAt,
+			UserRole:      &workspaces[i].UserRole,
+			LastVisitedAt: workspaces[i].LastVisitedAt,
 		}
 
-		if owner != nil {
+		if owner := workspaces[i].Owner; owner != nil {
 			wsResp.Owner = &models.UserResponse{
 				ID:        owner.ID,
 				Email:     owner.Email,
@@ -203,9 +311,56 @@ func (s *WorkspaceService) ListUserWorkspaces(
 		response.Workspaces = append(response.Workspaces, wsResp)
 	}
 
+	if err := s.addViewCounts(ctx, response.Workspaces); err != nil {
+		return nil, err
+	}
+
 	return response, nil
 }
 
+// addViewCounts sets the view counts of the workspaces the user owns
+func (s *WorkspaceService) addViewCounts(ctx context.Context, workspaces []models.WorkspaceResponse) error {
+	owned := make([]uuid.UUID, 0, len(workspaces))
+	for i := range workspaces {
+		if role := workspaces[i].UserRole; role != nil && *role == models.WorkspaceRoleOwner {
+			owned = append(owned, workspaces[i].ID)
+		}
+	}
+	if len(owned) == 0 {
+		return nil
+	}
+
+	counts, err := s.workspaceRepo.GetViewCounts(ctx, owned)
+	if err != nil {
+		return err
+	}
+	for i := range workspaces {
+		if role := workspaces[i].UserRole; role != nil && *role == models.WorkspaceRoleOwner {
+			views := counts[workspaces[i].ID]
+			workspaces[i].Views = &views
+		}
+	}
+	return nil
+}
+
+// RecordVisit records that a user opened a workspace, for listings by last
+// visit. It does nothing when visits aren't tracked.
+func (s *WorkspaceService) RecordVisit(ctx context.Context, userID, workspaceID uuid.UUID) error {
+	if s.visits == nil {
+		return nil
+	}
+	return s.visits.Record(ctx, userID, workspaceID)
+}
+
+// RecordView counts a view of a workspace. It does nothing when views
+// aren't counted.
+func (s *WorkspaceService) RecordView(ctx context.Context, view *models.WorkspaceView) error {
+	if s.views == nil {
+		return nil
+	}
+	return s.views.Record(ctx, view)
+}
+
 // DuplicateWorkspace creates a copy of a workspace
 func (s *WorkspaceService) DuplicateWorkspace(
 	ctx context.Context,
@@ -218,6 +373,16 @@ func (s *WorkspaceService) DuplicateWorkspace(
 		return nil, err
 	}
 
+	// Members may always duplicate, visitors of public workspaces only if
+	// the owner allows it
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	if member == nil && !original.PublicAccess.AllowDuplicate {
+		return nil, ErrDuplicateNotAllowed
+	}
+
 	// Use provided name or default to original name + (Copy)
 	name := newName
 	if name == "" {
@@ -243,9 +408,134 @@ func (s *WorkspaceService) DuplicateWorkspace(
 	return newWorkspace, nil
 }
 
+// --- Board Freeze ---
+
+// FreezeBoard makes a board read-only for everyone until it is unfrozen.
+// Freezing a frozen board is a no-op.
+func (s *WorkspaceService) FreezeBoard(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Workspace, error) {
+	if err := s.checkCanFreeze(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.workspaceRepo.FreezeWorkspace(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.afterFreeze(ctx, workspaceID, userID, changed, models.AuditBoardFrozen)
+}
+
+// UnfreezeBoard makes a frozen board editable again. Unfreezing a board that
+// isn't frozen is a no-op.
+func (s *WorkspaceService) UnfreezeBoard(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Workspace, error) {
+	if err := s.checkCanFreeze(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.workspaceRepo.UnfreezeWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.afterFreeze(ctx, workspaceID, userID, changed, models.AuditBoardUnfrozen)
+}
+
+// CheckNotFrozen returns ErrBoardFrozen if the board is frozen
+func (s *WorkspaceService) CheckNotFrozen(ctx context.Context, workspaceID uuid.UUID) error {
+	workspace, err := s.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.FrozenAt != nil {
+		return ErrBoardFrozen
+	}
+	return nil
+}
+
+// checkCanFreeze returns nil if the role of the user may freeze the board,
+// as configured by the owner
+func (s *WorkspaceService) checkCanFreeze(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	workspace, err := s.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get member: %w", err)
+	}
+	if member == nil {
+		return ErrWorkspaceAccessDenied
+	}
+	if !hasPermission(member.Role, workspace.FreezeRole) {
+		return ErrFreezeForbidden
+	}
+	return nil
+}
+
+// afterFreeze audits and broadcasts a change of the freeze state and returns
+// the workspace
+func (s *WorkspaceService) afterFreeze(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	changed bool,
+	action string,
+) (*models.Workspace, error) {
+	workspace, err := s.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return workspace, nil
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      action,
+		WorkspaceID: &workspaceID,
+		TargetType:  "workspace",
+		TargetID:    workspaceID.String(),
+	})
+
+	if s.rooms != nil {
+		s.rooms.BroadcastToRoom(workspaceID, &models.WSMessage{
+			Type:      models.MessageTypeBoardFrozen,
+			UserID:    userID,
+			Timestamp: time.Now(),
+			Payload: models.BoardFrozenPayload{
+				Frozen:   workspace.FrozenAt != nil,
+				FrozenAt: workspace.FrozenAt,
+				FrozenBy: workspace.FrozenBy,
+			},
+		}, uuid.Nil)
+	}
+
+	return workspace, nil
+}
+
 // --- Member Management ---
 
 // GetMembers retrieves all members of a workspace
+// GetWorkspaceStats returns usage statistics of a workspace
+func (s *WorkspaceService) GetWorkspaceStats(ctx context.Context, workspaceID uuid.UUID) (*models.WorkspaceStats, error) {
+	stats, err := s.workspaceRepo.GetWorkspaceStats(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace stats: %w", err)
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("workspace not found")
+	}
+
+	return stats, nil
+}
+
+// GetContributors returns who created and edited the elements of a
+// workspace, or of one of its elements, most contributions first
+func (s *WorkspaceService) GetContributors(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+	elementID *uuid.UUID,
+) ([]models.Contributor, error) {
+	return s.workspaceRepo.GetContributors(ctx, workspaceID, elementID, maxContributors)
+}
+
 func (s *WorkspaceService) GetMembers(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceMemberResponse, error) {
 	members, err := s.workspaceRepo.ListMembers(ctx, workspaceID)
 	if err != nil {
@@ -270,6 +560,26 @@ func (s *WorkspaceService) GetMembers(ctx context.Context, workspaceID uuid.UUID
 	return response, nil
 }
 
+// SuggestMembers returns the members matching a mention being typed, for
+// autocomplete. The user asking isn't suggested.
+func (s *WorkspaceService) SuggestMembers(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	query string,
+	limit int,
+) ([]models.MemberSuggestion, error) {
+	if limit <= 0 || limit > maxMemberSuggestions {
+		limit = defaultMemberSuggestions
+	}
+	query = strings.TrimPrefix(strings.TrimSpace(query), "@")
+
+	suggestions, err := s.workspaceRepo.SuggestMembers(ctx, workspaceID, userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest members: %w", err)
+	}
+	return suggestions, nil
+}
+
 // UpdateMemberRole updates a member's role
 func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, workspaceID, memberUserID uuid.UUID, role models.WorkspaceRole) error {
 	// Prevent changing owner role
@@ -286,6 +596,14 @@ func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, workspaceID, me
 		return fmt.Errorf("failed to update member role: %w", err)
 	}
 
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditMemberRoleChanged,
+		WorkspaceID: &workspaceID,
+		TargetType:  "user",
+		TargetID:    memberUserID.String(),
+		Metadata:    map[string]interface{}{"role": role},
+	})
+
 	return nil
 }
 
@@ -305,6 +623,13 @@ func (s *WorkspaceService) RemoveMember(ctx context.Context, workspaceID, member
 		return fmt.Errorf("failed to remove member: %w", err)
 	}
 
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditMemberRemoved,
+		WorkspaceID: &workspaceID,
+		TargetType:  "user",
+		TargetID:    memberUserID.String(),
+	})
+
 	return nil
 }
 
@@ -331,6 +656,16 @@ func (s *WorkspaceService) CreateInvite(
 		return nil, fmt.Errorf("invitation already sent to this email")
 	}
 
+	// Pending invitations count against the member cap, so they can't be
+	// used to exceed it once accepted
+	if err := s.checkMemberLimit(ctx, workspaceID, true); err != nil {
+		return nil, err
+	}
+
+	if err := s.emailService.AllowInvite(ctx, workspaceID, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Generate invite token
 	token := uuid.New().String()
 	tokenHash := hashToken(token)
@@ -349,18 +684,35 @@ func (s *WorkspaceService) CreateInvite(
 		return nil, fmt.Errorf("failed to create invite: %w", err)
 	}
 
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditInviteCreated,
+		WorkspaceID: &workspaceID,
+		TargetType:  "invite",
+		TargetID:    invite.ID.String(),
+		Metadata:    map[string]interface{}{"email": req.Email, "role": req.Role},
+	})
+
 	// Get workspace details for email
 	workspace, _ := s.GetWorkspace(ctx, workspaceID)
 	creator, _ := s.userRepo.GetByID(ctx, createdBy)
 
-	// Send invitation email
-	if workspace != nil && creator != nil {
-		_ = s.emailService.SendWorkspaceInvite(req.Email, workspace.Name, creator.Name, token)
-	}
-
 	// Build invite URL (frontend route)
 	inviteURL := fmt.Sprintf("/workspace/invite?token=%s", token)
 
+	// Send invitation email, and a push notification if the user has an account
+	if workspace != nil && creator != nil {
+		_ = s.emailService.SendWorkspaceInvite(ctx, req.Email, workspace.Name, creator.Name, token)
+
+		if user != nil {
+			s.webPush.notifyOffline(ctx, user.ID, &models.PushMessage{
+				Title: "Invitation to " + workspace.Name,
+				Body:  fmt.Sprintf("%s invited you to join %s as %s", creator.Name, workspace.Name, req.Role),
+				URL:   inviteURL,
+				Tag:   "invite-" + invite.ID.String(),
+			})
+		}
+	}
+
 	return &models.InviteTokenResponse{
 		Token:     token,
 		ExpiresAt: invite.ExpiresAt,
@@ -381,6 +733,91 @@ func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string, userI
 		return nil, fmt.Errorf("invalid or expired invitation")
 	}
 
+	return s.acceptInvite(ctx, invite, userID)
+}
+
+// ListUserInvites retrieves the pending invitations addressed to the
+// verified email of a user
+func (s *WorkspaceService) ListUserInvites(ctx context.Context, userID uuid.UUID) ([]models.UserInviteResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	return s.workspaceRepo.ListInvitesByEmail(ctx, user.Email)
+}
+
+// AcceptUserInvite accepts an invitation addressed to the email of a user by
+// its ID, as an alternative to the emailed token
+func (s *WorkspaceService) AcceptUserInvite(ctx context.Context, inviteID, userID uuid.UUID) (*models.Workspace, error) {
+	invite, err := s.userInvite(ctx, inviteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.acceptInvite(ctx, invite, userID)
+}
+
+// DeclineUserInvite turns down an invitation addressed to the email of a
+// user. The invitation is deleted, so the workspace can invite them again.
+func (s *WorkspaceService) DeclineUserInvite(ctx context.Context, inviteID, userID uuid.UUID) error {
+	invite, err := s.userInvite(ctx, inviteID, userID)
+	if err != nil {
+		return err
+	}
+	if invite.AcceptedAt != nil {
+		return fmt.Errorf("invitation already accepted")
+	}
+
+	if err := s.workspaceRepo.RevokeInvite(ctx, invite.ID); err != nil {
+		return fmt.Errorf("failed to decline invite: %w", err)
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditInviteDeclined,
+		WorkspaceID: &invite.WorkspaceID,
+		TargetType:  "invite",
+		TargetID:    invite.ID.String(),
+	})
+
+	return nil
+}
+
+// userInvite retrieves an invitation by ID, ErrInviteNotFound unless it is
+// addressed to the email of the user. Without the emailed token only owning
+// the address proves the invitation is theirs, so the email must be verified
+// whether or not auth.require_verified_email is set.
+func (s *WorkspaceService) userInvite(ctx context.Context, inviteID, userID uuid.UUID) (*models.WorkspaceInvite, error) {
+	invite, err := s.workspaceRepo.GetInviteByID(ctx, inviteID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	if invite == nil || !strings.EqualFold(user.Email, invite.Email) {
+		return nil, ErrInviteNotFound
+	}
+
+	return invite, nil
+}
+
+// acceptInvite adds a user as a member of the workspace of an invitation
+// addressed to them
+func (s *WorkspaceService) acceptInvite(
+	ctx context.Context,
+	invite *models.WorkspaceInvite,
+	userID uuid.UUID,
+) (*models.Workspace, error) {
 	// Check if already accepted
 	if invite.AcceptedAt != nil {
 		return nil, fmt.Errorf("invitation already accepted")
@@ -397,7 +834,7 @@ func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string, userI
 		return nil, fmt.Errorf("user not found")
 	}
 
-	if user.Email != invite.Email {
+	if !strings.EqualFold(user.Email, invite.Email) {
 		return nil, fmt.Errorf("invitation email does not match your account")
 	}
 
@@ -407,6 +844,10 @@ func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string, userI
 		return nil, fmt.Errorf("you are already a member of this workspace")
 	}
 
+	if err := s.checkMemberLimit(ctx, invite.WorkspaceID, false); err != nil {
+		return nil, err
+	}
+
 	// Add user as member
 	newMember := &models.WorkspaceMember{
 		ID:          uuid.New(),
@@ -416,14 +857,22 @@ func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string, userI
 		InvitedBy:   &invite.CreatedBy,
 	}
 
-	if addErr := s.workspaceRepo.AddMember(ctx, newMember); addErr != nil {
-		return nil, fmt.Errorf("failed to add member: %w", addErr)
+	// The invite is marked as accepted and the join event written with the member
+	joined := s.events.event(ctx, models.EventMemberJoined, invite.WorkspaceID, &userID, models.MemberEventPayload{
+		Role:   invite.Role,
+		UserID: userID,
+	})
+	if acceptErr := s.workspaceRepo.AcceptInvite(ctx, invite.ID, newMember, joined); acceptErr != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", acceptErr)
 	}
 
-	// Mark invite as accepted
-	if markErr := s.workspaceRepo.MarkInviteAsAccepted(ctx, invite.ID, userID); markErr != nil {
-		return nil, fmt.Errorf("failed to mark invite as accepted: %w", markErr)
-	}
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditInviteAccepted,
+		WorkspaceID: &invite.WorkspaceID,
+		TargetType:  "invite",
+		TargetID:    invite.ID.String(),
+		Metadata:    map[string]interface{}{"role": invite.Role},
+	})
 
 	// Get workspace
 	workspace, err := s.GetWorkspace(ctx, invite.WorkspaceID)
@@ -434,6 +883,42 @@ func (s *WorkspaceService) AcceptInvite(ctx context.Context, token string, userI
 	return workspace, nil
 }
 
+// checkMemberLimit returns ErrPlanLimitReached when a workspace can't take
+// another member under the plan of its owner, optionally counting pending
+// invitations as members
+func (s *WorkspaceService) checkMemberLimit(ctx context.Context, workspaceID uuid.UUID, withInvites bool) error {
+	if s.billing == nil {
+		return nil
+	}
+
+	entitlements, err := s.billing.WorkspaceEntitlements(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if entitlements.MaxMembers <= 0 {
+		return nil
+	}
+
+	stats, err := s.workspaceRepo.GetWorkspaceStats(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		return fmt.Errorf("workspace not found")
+	}
+
+	members := stats.MemberCount
+	if withInvites {
+		invites, err := s.workspaceRepo.ListPendingInvites(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		members += len(invites)
+	}
+
+	return checkLimit(entitlements, "members", members, 1, entitlements.MaxMembers)
+}
+
 // GetPendingInvites retrieves all pending invitations for a workspace
 func (s *WorkspaceService) GetPendingInvites(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceInviteResponse, error) {
 	invites, err := s.workspaceRepo.ListPendingInvites(ctx, workspaceID)
@@ -441,6 +926,16 @@ func (s *WorkspaceService) GetPendingInvites(ctx context.Context, workspaceID uu
 		return nil, fmt.Errorf("failed to get pending invites: %w", err)
 	}
 
+	// Owners see which invitation emails were never sent
+	emails := make([]string, len(invites))
+	for i := range invites {
+		emails[i] = invites[i].Email
+	}
+	suppressions, err := s.emailService.GetSuppressions(ctx, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email suppressions: %w", err)
+	}
+
 	response := make([]models.WorkspaceInviteResponse, 0, len(invites))
 	for i := range invites {
 		// Get creator info
@@ -461,6 +956,7 @@ func (s *WorkspaceService) GetPendingInvites(ctx context.Context, workspaceID uu
 				Name:      creator.Name,
 				AvatarURL: creator.AvatarURL,
 			},
+			Suppression: suppressions[normalizeEmail(invites[i].Email)],
 		})
 	}
 
@@ -473,9 +969,378 @@ func (s *WorkspaceService) RevokeInvite(ctx context.Context, inviteID uuid.UUID)
 		return fmt.Errorf("failed to revoke invite: %w", err)
 	}
 
+	s.audit.Record(ctx, &AuditEntry{
+		Action:     models.AuditInviteRevoked,
+		TargetType: "invite",
+		TargetID:   inviteID.String(),
+	})
+
 	return nil
 }
 
+// --- Invite links ---
+
+// CreateInviteLink creates a shareable link that lets any authenticated user
+// join a workspace with a role, optionally a limited number of times or
+// until it expires
+func (s *WorkspaceService) CreateInviteLink(
+	ctx context.Context,
+	workspaceID, createdBy uuid.UUID,
+	req *models.CreateInviteLinkRequest,
+) (*models.InviteLinkTokenResponse, error) {
+	if req.Role != models.WorkspaceRoleEditor && req.Role != models.WorkspaceRoleViewer {
+		return nil, fmt.Errorf("role must be editor or viewer")
+	}
+	if req.MaxUses != nil && *req.MaxUses <= 0 {
+		return nil, fmt.Errorf("max_uses must be positive")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	token := uuid.New().String()
+	link := &models.WorkspaceInviteLink{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		Role:        req.Role,
+		TokenHash:   hashToken(token),
+		MaxUses:     req.MaxUses,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   &createdBy,
+	}
+	if link.ExpiresAt != nil {
+		expiresAt := link.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := s.workspaceRepo.CreateInviteLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditInviteLinkCreated,
+		WorkspaceID: &workspaceID,
+		TargetType:  "invite_link",
+		TargetID:    link.ID.String(),
+		Metadata:    map[string]interface{}{"role": link.Role, "max_uses": link.MaxUses, "expires_at": link.ExpiresAt},
+	})
+
+	return &models.InviteLinkTokenResponse{
+		Link:      link,
+		Token:     token,
+		InviteURL: fmt.Sprintf("/workspace/join?token=%s", token),
+	}, nil
+}
+
+// ListInviteLinks retrieves the invite links of a workspace that weren't
+// revoked
+func (s *WorkspaceService) ListInviteLinks(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceInviteLink, error) {
+	return s.workspaceRepo.ListInviteLinks(ctx, workspaceID)
+}
+
+// ListInviteLinkRedemptions retrieves the users that joined a workspace
+// through one of its invite links
+func (s *WorkspaceService) ListInviteLinkRedemptions(
+	ctx context.Context,
+	workspaceID, linkID uuid.UUID,
+) ([]models.InviteLinkRedemption, error) {
+	link, err := s.workspaceRepo.GetInviteLink(ctx, workspaceID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrInviteLinkNotFound
+	}
+
+	return s.workspaceRepo.ListInviteLinkRedemptions(ctx, linkID)
+}
+
+// RevokeInviteLink stops an invite link from working. Members that joined
+// through it stay.
+func (s *WorkspaceService) RevokeInviteLink(ctx context.Context, workspaceID, linkID uuid.UUID) error {
+	revoked, err := s.workspaceRepo.RevokeInviteLink(ctx, workspaceID, linkID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInviteLinkNotFound
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditInviteLinkRevoked,
+		WorkspaceID: &workspaceID,
+		TargetType:  "invite_link",
+		TargetID:    linkID.String(),
+	})
+
+	return nil
+}
+
+// RedeemInviteLink adds a user as a member of the workspace of an invite
+// link, with the role of the link
+func (s *WorkspaceService) RedeemInviteLink(ctx context.Context, token string, userID uuid.UUID) (*models.Workspace, error) {
+	link, err := s.workspaceRepo.GetInviteLinkByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.RevokedAt != nil {
+		return nil, fmt.Errorf("invalid or revoked invite link")
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return nil, fmt.Errorf("invite link has expired")
+	}
+	if link.MaxUses != nil && link.UseCount >= *link.MaxUses {
+		return nil, fmt.Errorf("invite link has been used up")
+	}
+
+	workspace, err := s.GetWorkspace(ctx, link.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, link.WorkspaceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	if member != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	if err := s.checkMemberLimit(ctx, link.WorkspaceID, false); err != nil {
+		return nil, err
+	}
+
+	newMember := &models.WorkspaceMember{
+		ID:          uuid.New(),
+		WorkspaceID: link.WorkspaceID,
+		UserID:      userID,
+		Role:        link.Role,
+		InvitedBy:   link.CreatedBy,
+	}
+
+	// The use is counted and the join event written with the member
+	joined := s.events.event(ctx, models.EventMemberJoined, link.WorkspaceID, &userID, models.MemberEventPayload{
+		Role:   link.Role,
+		UserID: userID,
+	})
+	redeemed, err := s.workspaceRepo.RedeemInviteLink(ctx, link.ID, newMember, joined)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem invite link: %w", err)
+	}
+	if !redeemed {
+		return nil, fmt.Errorf("invite link is no longer valid")
+	}
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditInviteLinkRedeemed,
+		WorkspaceID: &link.WorkspaceID,
+		TargetType:  "invite_link",
+		TargetID:    link.ID.String(),
+		Metadata:    map[string]interface{}{"role": link.Role},
+	})
+
+	return workspace, nil
+}
+
+// --- Access requests ---
+
+// RequestAccess asks the owner of a workspace to let a user join it, and
+// notifies them
+func (s *WorkspaceService) RequestAccess(
+	ctx context.Context,
+	workspaceID, userID uuid.UUID,
+	req *models.RequestAccessRequest,
+) (*models.WorkspaceAccessRequest, error) {
+	workspace, err := s.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	if member != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.WorkspaceRoleViewer
+	}
+	if role != models.WorkspaceRoleEditor && role != models.WorkspaceRoleViewer {
+		return nil, fmt.Errorf("role must be editor or viewer")
+	}
+
+	request := &models.WorkspaceAccessRequest{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Role:        role,
+	}
+	if message := strings.TrimSpace(req.Message); message != "" {
+		if utf8.RuneCountInString(message) > maxAccessRequestMessage {
+			return nil, fmt.Errorf("message must be at most %d characters", maxAccessRequestMessage)
+		}
+		request.Message = &message
+	}
+
+	created, err := s.workspaceRepo.CreateAccessRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAccessRequestPending
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user != nil {
+		request.User = &models.UserResponse{ID: user.ID, Email: user.Email, Name: user.Name, AvatarURL: user.AvatarURL}
+	}
+
+	// The owner is the one who answers requests
+	if err := s.notifications.NotifyAccessRequest(ctx, workspace, request, []uuid.UUID{workspace.OwnerID}); err != nil {
+		hlog.CtxErrorf(ctx, "Failed to notify owners of access request %s: %v", request.ID, err)
+	}
+
+	return request, nil
+}
+
+// ListAccessRequests retrieves the pending access requests of a workspace
+func (s *WorkspaceService) ListAccessRequests(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceAccessRequest, error) {
+	return s.workspaceRepo.ListPendingAccessRequests(ctx, workspaceID)
+}
+
+// ApproveAccessRequest adds the user of a pending access request as a
+// member, with the role asked for unless req overrides it
+func (s *WorkspaceService) ApproveAccessRequest(
+	ctx context.Context,
+	workspaceID, requestID, ownerID uuid.UUID,
+	req *models.ApproveAccessRequest,
+) (*models.WorkspaceAccessRequest, error) {
+	request, err := s.pendingAccessRequest(ctx, workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := request.Role
+	if req.Role != "" {
+		role = req.Role
+	}
+	if role != models.WorkspaceRoleEditor && role != models.WorkspaceRoleViewer {
+		return nil, fmt.Errorf("role must be editor or viewer")
+	}
+
+	if err := s.checkMemberLimit(ctx, workspaceID, false); err != nil {
+		return nil, err
+	}
+
+	member := &models.WorkspaceMember{
+		ID:          uuid.New(),
+		WorkspaceID: workspaceID,
+		UserID:      request.UserID,
+		Role:        role,
+		InvitedBy:   &ownerID,
+	}
+
+	// The request is marked as approved and the join event written with the member
+	joined := s.events.event(ctx, models.EventMemberJoined, workspaceID, &request.UserID, models.MemberEventPayload{
+		Role:   role,
+		UserID: request.UserID,
+	})
+	approved, err := s.workspaceRepo.ApproveAccessRequest(ctx, requestID, ownerID, member, joined)
+	if err != nil {
+		return nil, err
+	}
+	if !approved {
+		return nil, ErrAccessRequestDecided
+	}
+
+	now := time.Now()
+	request.Status = models.AccessRequestApproved
+	request.Role = role
+	request.DecidedBy = &ownerID
+	request.DecidedAt = &now
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditAccessRequestApproved,
+		WorkspaceID: &workspaceID,
+		TargetType:  "user",
+		TargetID:    request.UserID.String(),
+		Metadata:    map[string]interface{}{"request_id": requestID, "role": role},
+	})
+	s.notifyAccessDecision(ctx, request)
+
+	return request, nil
+}
+
+// DenyAccessRequest turns down a pending access request
+func (s *WorkspaceService) DenyAccessRequest(
+	ctx context.Context,
+	workspaceID, requestID, ownerID uuid.UUID,
+) (*models.WorkspaceAccessRequest, error) {
+	request, err := s.pendingAccessRequest(ctx, workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := s.workspaceRepo.DenyAccessRequest(ctx, requestID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !denied {
+		return nil, ErrAccessRequestDecided
+	}
+
+	now := time.Now()
+	request.Status = models.AccessRequestDenied
+	request.DecidedBy = &ownerID
+	request.DecidedAt = &now
+
+	s.audit.Record(ctx, &AuditEntry{
+		Action:      models.AuditAccessRequestDenied,
+		WorkspaceID: &workspaceID,
+		TargetType:  "user",
+		TargetID:    request.UserID.String(),
+		Metadata:    map[string]interface{}{"request_id": requestID},
+	})
+	s.notifyAccessDecision(ctx, request)
+
+	return request, nil
+}
+
+func (s *WorkspaceService) pendingAccessRequest(
+	ctx context.Context,
+	workspaceID, requestID uuid.UUID,
+) (*models.WorkspaceAccessRequest, error) {
+	request, err := s.workspaceRepo.GetAccessRequest(ctx, workspaceID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrAccessRequestNotFound
+	}
+	if request.Status != models.AccessRequestPending {
+		return nil, ErrAccessRequestDecided
+	}
+	return request, nil
+}
+
+// notifyAccessDecision tells the user of a request about the answer, only
+// logging failures since the decision is already stored
+func (s *WorkspaceService) notifyAccessDecision(ctx context.Context, request *models.WorkspaceAccessRequest) {
+	workspace, err := s.GetWorkspace(ctx, request.WorkspaceID)
+	if err == nil {
+		err = s.notifications.NotifyAccessDecision(ctx, workspace, request)
+	}
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to notify the user of access request %s: %v", request.ID, err)
+	}
+}
+
 // --- Permissions ---
 
 // CheckPermission checks if user has required permission level
@@ -495,7 +1360,7 @@ func (s *WorkspaceService) CheckPermission(ctx context.Context, workspaceID, use
 		if workspace.IsPublic && requiredRole == models.WorkspaceRoleViewer {
 			return nil // Allow public view
 		}
-		return fmt.Errorf("access denied")
+		return ErrWorkspaceAccessDenied
 	}
 
 	// Check role hierarchy: owner > editor > viewer
@@ -506,6 +1371,30 @@ func (s *WorkspaceService) CheckPermission(ctx context.Context, workspaceID, use
 	return nil
 }
 
+// GetUserRole returns the user's role in a workspace.
+// Non-members get viewer access to public workspaces, commenting is decided
+// by the public access settings.
+func (s *WorkspaceService) GetUserRole(ctx context.Context, workspaceID, userID uuid.UUID) (models.WorkspaceRole, error) {
+	workspace, err := s.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return "", err
+	}
+
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get member: %w", err)
+	}
+
+	if member == nil {
+		if workspace.IsPublic {
+			return models.WorkspaceRoleViewer, nil
+		}
+		return "", ErrWorkspaceAccessDenied
+	}
+
+	return member.Role, nil
+}
+
 // IsOwner checks if user is the owner of workspace
 func (s *WorkspaceService) IsOwner(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error) {
 	workspace, err := s.GetWorkspace(ctx, workspaceID)
@@ -518,6 +1407,25 @@ func (s *WorkspaceService) IsOwner(ctx context.Context, workspaceID, userID uuid
 
 // --- Helpers ---
 
+// applyPublicAccess changes the public access settings given in req
+func applyPublicAccess(access *models.PublicAccess, req *models.UpdatePublicAccessRequest) error {
+	if req.Role != nil {
+		switch *req.Role {
+		case models.PublicRoleViewer, models.PublicRoleCommenter:
+			access.Role = *req.Role
+		default:
+			return ErrInvalidPublicRole
+		}
+	}
+	if req.AllowDuplicate != nil {
+		access.AllowDuplicate = *req.AllowDuplicate
+	}
+	if req.AllowAnonymous != nil {
+		access.AllowAnonymous = *req.AllowAnonymous
+	}
+	return nil
+}
+
 func hasPermission(userRole, requiredRole models.WorkspaceRole) bool {
 	roleHierarchy := map[models.WorkspaceRole]int{
 		models.WorkspaceRoleViewer: 1,
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	workspaceViewSeenKey = "workspace_views:seen:%s:%s"
+	// workspaceViewVisit is how long a visit lasts. Reopening a board within
+	// it isn't counted as another view.
+	workspaceViewVisit = 30 * time.Minute
+)
+
+// WorkspaceViews counts the views of workspaces by members and visitors of
+// public workspaces. Counts are kept in the database for the owner, every
+// counted view also goes to the analytics pipeline for view trends.
+type WorkspaceViews struct {
+	redis         *redis.Client
+	workspaceRepo *repository.WorkspaceRepository
+	analytics     *AnalyticsService
+}
+
+// NewWorkspaceViews creates a new view counter
+func NewWorkspaceViews(
+	redisClient *redis.Client,
+	workspaceRepo *repository.WorkspaceRepository,
+	analytics *AnalyticsService,
+) *WorkspaceViews {
+	return &WorkspaceViews{redis: redisClient, workspaceRepo: workspaceRepo, analytics: analytics}
+}
+
+// Record counts a view unless the viewer already viewed the workspace
+// during this visit
+func (v *WorkspaceViews) Record(ctx context.Context, view *models.WorkspaceView) error {
+	viewer := viewerKey(view)
+
+	seenKey := fmt.Sprintf(workspaceViewSeenKey, view.WorkspaceID, viewer)
+	fresh, err := v.redis.SetNX(ctx, seenKey, 1, workspaceViewVisit).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check workspace view: %w", err)
+	}
+	if !fresh {
+		return nil
+	}
+
+	first, err := v.workspaceRepo.RecordView(ctx, view.WorkspaceID, viewer)
+	if err != nil {
+		return err
+	}
+
+	member := false
+	if view.UserID != nil {
+		m, err := v.workspaceRepo.GetMember(ctx, view.WorkspaceID, *view.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get member: %w", err)
+		}
+		member = m != nil
+	}
+
+	v.analytics.TrackView(&models.ViewAnalytics{
+		WorkspaceID:  view.WorkspaceID,
+		UserID:       view.UserID,
+		Viewer:       viewer,
+		ReferrerHost: referrerHost(view.Referrer),
+		Anonymous:    view.UserID == nil,
+		Member:       member,
+		FirstView:    first,
+	})
+	return nil
+}
+
+// viewerKey identifies signed in users by ID and anonymous visitors by a
+// hash of their address and browser, which isn't stored itself
+func viewerKey(view *models.WorkspaceView) string {
+	if view.UserID != nil {
+		return "u:" + view.UserID.String()
+	}
+	sum := sha256.Sum256([]byte(view.IP + "\x00" + view.UserAgent))
+	return "a:" + hex.EncodeToString(sum[:16])
+}
+
+// referrerHost returns the host of the page that linked to the board, empty
+// if there was none
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
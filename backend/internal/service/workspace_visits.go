@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bifshteksex/hertz-board/internal/models"
+	"github.com/bifshteksex/hertz-board/internal/repository"
+)
+
+const (
+	workspaceVisitsKey = "workspace_visits:%s"
+	// workspaceVisitsDirtyKey is the set of users with visits to persist
+	workspaceVisitsDirtyKey = "workspace_visits:dirty"
+	// workspaceVisitsBuffered is how many recent visits of a user Redis keeps
+	workspaceVisitsBuffered = 50
+	// workspaceVisitsTTL drops the buffer of users that stopped visiting,
+	// long after their visits were persisted
+	workspaceVisitsTTL = 7 * 24 * time.Hour
+	// workspaceVisitsFlushBatch is how many users are persisted per batch
+	workspaceVisitsFlushBatch = 100
+	workspaceVisitsTimeout    = 2 * time.Minute
+
+	// WorkspaceVisitFlushInterval is how often buffered visits are persisted
+	WorkspaceVisitFlushInterval = time.Minute
+)
+
+// WorkspaceVisits records when users open workspaces. Opening a board is
+// frequent, so visits are buffered in a sorted set per user in Redis and
+// persisted periodically by WorkspaceVisitWorker.
+type WorkspaceVisits struct {
+	redis         *redis.Client
+	workspaceRepo *repository.WorkspaceRepository
+}
+
+// NewWorkspaceVisits creates a new visit recorder
+func NewWorkspaceVisits(redisClient *redis.Client, workspaceRepo *repository.WorkspaceRepository) *WorkspaceVisits {
+	return &WorkspaceVisits{redis: redisClient, workspaceRepo: workspaceRepo}
+}
+
+// Record buffers a visit of a user to a workspace
+func (v *WorkspaceVisits) Record(ctx context.Context, userID, workspaceID uuid.UUID) error {
+	key := fmt.Sprintf(workspaceVisitsKey, userID)
+
+	pipe := v.redis.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().UnixMilli()), Member: workspaceID.String()})
+	pipe.ZRemRangeByRank(ctx, key, 0, -workspaceVisitsBuffered-1)
+	pipe.Expire(ctx, key, workspaceVisitsTTL)
+	pipe.SAdd(ctx, workspaceVisitsDirtyKey, userID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record workspace visit: %w", err)
+	}
+	return nil
+}
+
+// FlushUser persists the buffered visits of one user now, so that listings
+// by last visit include the latest ones
+func (v *WorkspaceVisits) FlushUser(ctx context.Context, userID uuid.UUID) error {
+	removed, err := v.redis.SRem(ctx, workspaceVisitsDirtyKey, userID.String()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check buffered workspace visits: %w", err)
+	}
+	if removed == 0 {
+		return nil
+	}
+	return v.persist(ctx, userID)
+}
+
+// Flush persists the buffered visits of all users with new visits. Returns
+// how many users were flushed.
+func (v *WorkspaceVisits) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		members, err := v.redis.SPopN(ctx, workspaceVisitsDirtyKey, workspaceVisitsFlushBatch).Result()
+		if err != nil {
+			return total, fmt.Errorf("failed to pop users with workspace visits: %w", err)
+		}
+
+		for _, member := range members {
+			userID, parseErr := uuid.Parse(member)
+			if parseErr != nil {
+				continue
+			}
+			if err := v.persist(ctx, userID); err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		if len(members) < workspaceVisitsFlushBatch {
+			return total, nil
+		}
+	}
+}
+
+// persist writes the buffer of a user to the database. The user is marked
+// dirty again if that fails, so the visits are retried on the next flush.
+func (v *WorkspaceVisits) persist(ctx context.Context, userID uuid.UUID) error {
+	err := v.writeBuffer(ctx, userID)
+	if err == nil {
+		return nil
+	}
+
+	if retryErr := v.redis.SAdd(ctx, workspaceVisitsDirtyKey, userID.String()).Err(); retryErr != nil {
+		hlog.CtxWarnf(ctx, "Failed to requeue workspace visits of user %s: %v", userID, retryErr)
+	}
+	return err
+}
+
+func (v *WorkspaceVisits) writeBuffer(ctx context.Context, userID uuid.UUID) error {
+	buffered, err := v.redis.ZRangeWithScores(ctx, fmt.Sprintf(workspaceVisitsKey, userID), 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read buffered workspace visits: %w", err)
+	}
+
+	visits := make([]models.WorkspaceVisit, 0, len(buffered))
+	for _, z := range buffered {
+		member, _ := z.Member.(string)
+		workspaceID, parseErr := uuid.Parse(member)
+		if parseErr != nil {
+			continue
+		}
+		visits = append(visits, models.WorkspaceVisit{
+			UserID:      userID,
+			WorkspaceID: workspaceID,
+			VisitedAt:   time.UnixMilli(int64(z.Score)),
+		})
+	}
+
+	return v.workspaceRepo.UpsertVisits(ctx, userID, visits)
+}
+
+// WorkspaceVisitWorker periodically persists the buffered workspace visits
+type WorkspaceVisitWorker struct {
+	visits   *WorkspaceVisits
+	done     chan struct{}
+	trigger  chan struct{}
+	interval time.Duration
+}
+
+// NewWorkspaceVisitWorker creates and starts a new visit worker
+func NewWorkspaceVisitWorker(visits *WorkspaceVisits, interval time.Duration) (*WorkspaceVisitWorker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	worker := &WorkspaceVisitWorker{
+		visits:   visits,
+		done:     make(chan struct{}),
+		trigger:  make(chan struct{}, 1),
+		interval: interval,
+	}
+
+	go worker.run()
+	return worker, nil
+}
+
+// Close stops the visit worker. Visits still buffered are persisted by the
+// next instance to run.
+func (w *WorkspaceVisitWorker) Close() error {
+	close(w.done)
+	return nil
+}
+
+// Trigger persists buffered visits now instead of at the next tick. It
+// returns false if a flush is already pending.
+func (w *WorkspaceVisitWorker) Trigger() bool {
+	select {
+	case w.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *WorkspaceVisitWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.trigger:
+			w.flush()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *WorkspaceVisitWorker) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), workspaceVisitsTimeout)
+	defer cancel()
+
+	count, err := w.visits.Flush(ctx)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "Failed to persist workspace visits: %v", err)
+	}
+	if count > 0 {
+		hlog.CtxDebugf(ctx, "Persisted workspace visits of %d users", count)
+	}
+}
@@ -0,0 +1,265 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	exportQueueSize     = 4096
+	exportBatchSize     = 512
+	exportInterval      = 5 * time.Second
+	exportTimeout       = 10 * time.Second
+	exportMaxErrorBody  = 512
+	instrumentationName = "github.com/bifshteksex/hertz-board"
+
+	otlpStatusError = 2
+)
+
+// exporter batches finished spans and posts them as OTLP/HTTP JSON
+type exporter struct {
+	client      *http.Client
+	queue       chan *Span
+	done        chan struct{}
+	endpoint    string
+	serviceName string
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+func newExporter(endpoint, serviceName string) *exporter {
+	e := &exporter{
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, exportQueueSize),
+		done:        make(chan struct{}),
+		endpoint:    endpoint,
+		serviceName: serviceName,
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// enqueue hands a span to the export loop. Spans are dropped when the
+// collector can't keep up, tracing must not slow down requests.
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+	}
+}
+
+// shutdown exports the queued spans and stops the export loop
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+
+	finished := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			hlog.Errorf("Failed to export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) == exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+					if len(batch) == exportBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *exporter) export(spans []*Span) error {
+	body, err := json.Marshal(e.buildRequest(spans))
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send spans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, exportMaxErrorBody))
+		return fmt.Errorf("collector returned status %d: %s", resp.StatusCode, string(msg))
+	}
+
+	return nil
+}
+
+// OTLP/HTTP JSON request, see opentelemetry-proto trace/v1/trace.proto
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	Status            *otlpStatus     `json:"status,omitempty"`
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Kind              SpanKind        `json:"kind"`
+}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+type otlpAttribute struct {
+	Value otlpValue `json:"value"`
+	Key   string    `json:"key"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *exporter) buildRequest(spans []*Span) *otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           span.sc.TraceID.String(),
+			SpanID:            span.sc.SpanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		}
+		if span.parent != (SpanID{}) {
+			s.ParentSpanID = span.parent.String()
+		}
+		for key, value := range span.attributes {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: key, Value: toOTLPValue(value)})
+		}
+		if span.failed {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: span.errMessage}
+		}
+		span.mu.Unlock()
+
+		out = append(out, s)
+	}
+
+	serviceName := e.serviceName
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: &serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: instrumentationName},
+				Spans: out,
+			}},
+		}},
+	}
+}
+
+func toOTLPValue(value interface{}) otlpValue {
+	switch v := value.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return otlpValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpValue{IntValue: &s}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	default:
+		s := fmt.Sprintf("%v", v)
+		return otlpValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Transport records a span for every outgoing request that runs within a
+// trace and passes the trace context on, e.g. for the MinIO client
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base, http.DefaultTransport when nil
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := StartChild(req.Context(), "HTTP "+req.Method, SpanKindClient)
+	if span == nil {
+		return t.Base.RoundTrip(req)
+	}
+	defer span.End()
+
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("server.address", req.URL.Host)
+
+	// RoundTrippers must not modify the request
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header.Set)
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatusError(fmt.Sprintf("HTTP %d", resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NewMsg returns a NATS message that carries the trace context of ctx in its
+// headers, so consumers continue the trace
+func NewMsg(ctx context.Context, subject string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	Inject(ctx, msg.Header.Set)
+	return msg
+}
+
+// StartConsumer creates the span of a job received from NATS, continuing
+// the trace of the publisher when the message carries one
+func StartConsumer(msg *nats.Msg, name string) (context.Context, *Span) {
+	ctx := context.Background()
+	if msg.Header != nil {
+		ctx = Extract(ctx, msg.Header.Get(TraceparentHeader))
+	}
+
+	ctx, span := Start(ctx, name, SpanKindConsumer)
+	span.SetAttribute("messaging.system", "nats")
+	span.SetAttribute("messaging.destination", msg.Subject)
+	return ctx, span
+}
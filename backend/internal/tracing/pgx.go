@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxStatementLength caps the SQL recorded on query spans
+const maxStatementLength = 2048
+
+type querySpanKey struct{}
+
+// QueryTracer records a span for every query that runs within a trace. Set
+// it as the Tracer of the pgx connection config.
+type QueryTracer struct{}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, span := StartChild(ctx, "postgres "+sqlOperation(data.SQL), SpanKindClient)
+	if span == nil {
+		return ctx
+	}
+
+	statement := data.SQL
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength]
+	}
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.statement", statement)
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span, ok := ctx.Value(querySpanKey{}).(*Span)
+	if !ok {
+		return
+	}
+
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+	}
+	span.SetAttribute("db.rows_affected", data.CommandTag.RowsAffected())
+	span.End()
+}
+
+// sqlOperation returns the first keyword of a statement, such as SELECT
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header
+const TraceparentHeader = "traceparent"
+
+const (
+	traceparentVersion = "00"
+	traceparentParts   = 4
+	flagSampled        = 0x01
+)
+
+// Inject writes the trace context of ctx with set, e.g. into HTTP or NATS
+// headers. Nothing is written outside of a trace.
+func Inject(ctx context.Context, set func(key, value string)) {
+	sc := parentSpanContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	set(TraceparentHeader, traceparentVersion+"-"+sc.TraceID.String()+"-"+sc.SpanID.String()+"-"+flags)
+}
+
+// Extract returns a context that continues the trace in the traceparent
+// value, ctx unchanged when the value is missing or malformed
+func Extract(ctx context.Context, traceparent string) context.Context {
+	sc, ok := parseTraceparent(traceparent)
+	if !ok {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+func parseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != traceparentParts || parts[0] != traceparentVersion {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(sc.TraceID) {
+		return SpanContext{}, false
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(sc.SpanID) {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return SpanContext{}, false
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&flagSampled != 0
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
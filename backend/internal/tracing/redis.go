@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook records a span for every Redis command and pipeline that runs
+// within a trace. Add it with client.AddHook.
+type RedisHook struct{}
+
+// DialHook implements redis.Hook
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook implements redis.Hook
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := StartChild(ctx, "redis "+cmd.Name(), SpanKindClient)
+		if span == nil {
+			return next(ctx, cmd)
+		}
+		defer span.End()
+
+		span.SetAttribute("db.system", "redis")
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := StartChild(ctx, "redis pipeline", SpanKindClient)
+		if span == nil {
+			return next(ctx, cmds)
+		}
+		defer span.End()
+
+		span.SetAttribute("db.system", "redis")
+		span.SetAttribute("db.redis.commands", len(cmds))
+		err := next(ctx, cmds)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,269 @@
+// Package tracing records spans of requests, queries and background jobs and
+// exports them over OTLP/HTTP to Jaeger or any OpenTelemetry collector.
+// Trace context crosses process boundaries in W3C traceparent headers.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/bifshteksex/hertz-board/internal/config"
+)
+
+// SpanKind describes the role of a span in a trace, values match OTLP
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// SpanContext is the part of a span that is propagated to other processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether the span context has a trace and span ID
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Span is a timed operation. All methods are safe on a nil span, which is
+// what Start returns when tracing is disabled.
+type Span struct {
+	start      time.Time
+	end        time.Time
+	tracer     *Tracer
+	attributes map[string]interface{}
+	name       string
+	errMessage string
+	sc         SpanContext
+	parent     SpanID
+	kind       SpanKind
+	mu         sync.Mutex
+	failed     bool
+}
+
+// SpanContext returns the IDs of the span, zero for a nil span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute records a key/value pair on the span. Values are strings,
+// bools, integers or floats, anything else is formatted with %v.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.errMessage = err.Error()
+}
+
+// SetStatusError marks the span as failed without an error value, e.g. for
+// HTTP responses with a 5xx status
+func (s *Span) SetStatusError(message string) {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.errMessage = message
+}
+
+// End finishes the span and queues it for export
+func (s *Span) End() {
+	if s == nil || !s.sc.Sampled {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.exporter.enqueue(s)
+}
+
+// Tracer creates spans and hands finished ones to the exporter
+type Tracer struct {
+	exporter    *exporter
+	serviceName string
+	sampleRatio float64
+}
+
+type spanContextKey struct{}
+
+type remoteContextKey struct{}
+
+var (
+	globalMu sync.RWMutex
+	global   *Tracer
+)
+
+// Init sets up the process wide tracer from the config. It returns a
+// shutdown function that flushes queued spans, and does nothing when
+// tracing is disabled.
+func Init(cfg *config.TracingConfig, serviceName string) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("tracing endpoint is required")
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	tracer := &Tracer{
+		serviceName: serviceName,
+		sampleRatio: ratio,
+	}
+	tracer.exporter = newExporter(cfg.Endpoint, serviceName)
+
+	globalMu.Lock()
+	global = tracer
+	globalMu.Unlock()
+
+	return tracer.exporter.shutdown, nil
+}
+
+func currentTracer() *Tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return global
+}
+
+// Enabled reports whether spans are recorded
+func Enabled() bool {
+	return currentTracer() != nil
+}
+
+// Start creates a span as a child of the span or remote span context in ctx,
+// or a new trace without either. Callers must End the span.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	tracer := currentTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:     tracer,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+
+	if parent := parentSpanContext(ctx); parent.IsValid() {
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		_, _ = rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = tracer.sample()
+	}
+	_, _ = rand.Read(span.sc.SpanID[:])
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// StartChild creates a span only when ctx already belongs to a trace, so
+// queries of startup code and tickers don't each become a trace of their own
+func StartChild(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if !parentSpanContext(ctx).IsValid() {
+		return ctx, nil
+	}
+	return Start(ctx, name, kind)
+}
+
+// SpanFromContext returns the current span, nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// TraceIDFromContext returns the trace ID of the current span, empty
+// without one. It correlates logs with traces.
+func TraceIDFromContext(ctx context.Context) string {
+	sc := parentSpanContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID.String()
+}
+
+// Detach returns a context for work that outlives the request, such as
+// goroutines started by a handler. It keeps the trace but not the
+// cancellation of ctx.
+func Detach(ctx context.Context) context.Context {
+	sc := parentSpanContext(ctx)
+	if !sc.IsValid() {
+		return context.Background()
+	}
+	return ContextWithRemoteSpanContext(context.Background(), sc)
+}
+
+// ContextWithRemoteSpanContext returns a context whose spans continue a
+// trace started in another process
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteContextKey{}, sc)
+}
+
+func parentSpanContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteContextKey{}).(SpanContext)
+	return sc
+}
+
+func (t *Tracer) sample() bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return false
+	}
+	return float64(n.Int64())/math.MaxInt64 < t.sampleRatio
+}
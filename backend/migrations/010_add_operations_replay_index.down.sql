@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_operations_workspace_created_at;
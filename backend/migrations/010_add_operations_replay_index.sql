@@ -0,0 +1,2 @@
+-- Index for replaying workspace operations in wall-clock order
+CREATE INDEX IF NOT EXISTS idx_operations_workspace_created_at ON operations(workspace_id, created_at, timestamp);
@@ -0,0 +1,8 @@
+-- Rendered pages stay behind as standalone image assets
+DROP INDEX IF EXISTS idx_assets_parent_asset_id;
+
+ALTER TABLE assets
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS page_count,
+    DROP COLUMN IF EXISTS page_number,
+    DROP COLUMN IF EXISTS parent_asset_id;
@@ -0,0 +1,17 @@
+-- Migration: Multi-page assets
+-- Documents such as PDFs are stored as a parent asset with one image asset per page
+
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS parent_asset_id UUID REFERENCES assets(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS page_number INTEGER,
+    ADD COLUMN IF NOT EXISTS page_count INTEGER,
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'ready'
+        CHECK (status IN ('processing', 'ready', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_assets_parent_asset_id ON assets(parent_asset_id, page_number)
+    WHERE parent_asset_id IS NOT NULL;
+
+COMMENT ON COLUMN assets.parent_asset_id IS 'Parent document asset for rendered pages';
+COMMENT ON COLUMN assets.page_number IS 'Page number within the parent document (1-based)';
+COMMENT ON COLUMN assets.page_count IS 'Number of rendered pages (for documents only)';
+COMMENT ON COLUMN assets.status IS 'Processing status: processing, ready or failed';
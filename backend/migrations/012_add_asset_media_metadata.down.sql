@@ -0,0 +1,4 @@
+ALTER TABLE assets
+    DROP COLUMN IF EXISTS duration_ms;
+
+COMMENT ON COLUMN canvas_elements.element_type IS 'Type of element: text, shape, image, drawing, sticky, list, connector, group';
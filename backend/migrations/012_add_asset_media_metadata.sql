@@ -0,0 +1,8 @@
+-- Migration: Media metadata for video and animated assets
+-- The poster frame of a video is stored as its thumbnail
+
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS duration_ms INTEGER;
+
+COMMENT ON COLUMN assets.duration_ms IS 'Playback duration in milliseconds (for videos and animated GIFs only)';
+COMMENT ON COLUMN canvas_elements.element_type IS 'Type of element: text, shape, image, video, drawing, sticky, list, connector, group';
@@ -0,0 +1,7 @@
+-- Migration: Optimized image variants
+-- WebP/AVIF renditions at responsive widths, keyed by "<format>_<width>"
+
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+COMMENT ON COLUMN assets.variants IS 'Optimized renditions of an image, keyed by format and width (e.g. webp_640)';
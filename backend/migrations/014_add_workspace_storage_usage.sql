@@ -0,0 +1,14 @@
+-- Migration: Workspace storage usage
+-- Cumulative size of live assets, maintained by the assets repository
+
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS storage_used_bytes BIGINT NOT NULL DEFAULT 0;
+
+UPDATE workspaces w
+SET storage_used_bytes = COALESCE((
+    SELECT SUM(a.size)
+    FROM assets a
+    WHERE a.workspace_id = w.id AND a.deleted_at IS NULL
+), 0);
+
+COMMENT ON COLUMN workspaces.storage_used_bytes IS 'Total size of non-deleted assets in bytes';
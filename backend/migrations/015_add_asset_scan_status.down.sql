@@ -0,0 +1,10 @@
+-- Without a scan status quarantined assets would be served again, so they
+-- are soft-deleted first
+UPDATE assets SET deleted_at = NOW()
+WHERE scan_status = 'infected' AND deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_assets_infected;
+
+ALTER TABLE assets
+    DROP COLUMN IF EXISTS scan_signature,
+    DROP COLUMN IF EXISTS scan_status;
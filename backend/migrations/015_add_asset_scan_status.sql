@@ -0,0 +1,16 @@
+-- Migration: Antivirus scan status for assets
+-- Infected assets are quarantined: hidden from listings and not downloadable
+
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20) NOT NULL DEFAULT 'skipped'
+        CHECK (scan_status IN ('pending', 'clean', 'infected', 'skipped')),
+    ADD COLUMN IF NOT EXISTS scan_signature VARCHAR(255);
+
+-- Existing assets were uploaded before scanning, new ones start as pending
+ALTER TABLE assets ALTER COLUMN scan_status SET DEFAULT 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_assets_infected ON assets(workspace_id)
+    WHERE scan_status = 'infected';
+
+COMMENT ON COLUMN assets.scan_status IS 'Antivirus scan result: pending, clean, infected or skipped';
+COMMENT ON COLUMN assets.scan_signature IS 'Name of the detected signature for infected assets';
@@ -0,0 +1,7 @@
+-- Migration: Attribution for assets imported from stock media providers
+-- Unsplash and GIPHY require crediting the author wherever the media is shown
+
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS attribution JSONB;
+
+COMMENT ON COLUMN assets.attribution IS 'Source provider, author and links of imported stock media';
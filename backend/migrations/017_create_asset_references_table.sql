@@ -0,0 +1,23 @@
+-- Migration: Track which canvas elements reference each asset
+-- Maintained by the canvas repository on element create, update and delete
+
+CREATE TABLE IF NOT EXISTS asset_references (
+    asset_id UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    element_id UUID NOT NULL REFERENCES canvas_elements(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (asset_id, element_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_references_element_id ON asset_references(element_id);
+CREATE INDEX IF NOT EXISTS idx_asset_references_workspace_id ON asset_references(workspace_id);
+
+-- Backfill from existing elements
+INSERT INTO asset_references (asset_id, element_id, workspace_id)
+SELECT a.id, ce.id, ce.workspace_id
+FROM canvas_elements ce
+JOIN assets a ON a.id::text = ce.element_data->>'asset_id' AND a.workspace_id = ce.workspace_id
+WHERE ce.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE asset_references IS 'Canvas elements that display an asset, used for safe deletion and orphan cleanup';
@@ -0,0 +1,21 @@
+-- Payloads already moved to object storage can't be read back in SQL, so
+-- the rollback stops here while such snapshots exist
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM canvas_snapshots WHERE snapshot_data IS NULL) THEN
+        RAISE EXCEPTION 'canvas_snapshots has payloads in object storage, copy them back into snapshot_data before reverting';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_canvas_snapshots_inline;
+
+CREATE INDEX IF NOT EXISTS idx_canvas_snapshots_data_gin ON canvas_snapshots USING GIN (snapshot_data);
+
+ALTER TABLE canvas_snapshots
+    ALTER COLUMN snapshot_data SET NOT NULL;
+
+ALTER TABLE canvas_snapshots
+    DROP COLUMN IF EXISTS compressed_size,
+    DROP COLUMN IF EXISTS storage_key;
+
+COMMENT ON COLUMN canvas_snapshots.snapshot_data IS 'Complete serialized canvas state (all elements)';
@@ -0,0 +1,22 @@
+-- Migration: Store snapshot payloads in object storage
+-- New snapshots keep only metadata here, the gzipped canvas state lives in the
+-- backups bucket. Existing rows keep snapshot_data until they are moved.
+
+ALTER TABLE canvas_snapshots
+    ADD COLUMN IF NOT EXISTS storage_key TEXT,
+    ADD COLUMN IF NOT EXISTS compressed_size BIGINT;
+
+ALTER TABLE canvas_snapshots
+    ALTER COLUMN snapshot_data DROP NOT NULL;
+
+-- The payload is no longer queried in place
+DROP INDEX IF EXISTS idx_canvas_snapshots_data_gin;
+
+-- Speeds up moving the remaining inline payloads
+CREATE INDEX IF NOT EXISTS idx_canvas_snapshots_inline
+    ON canvas_snapshots(created_at)
+    WHERE storage_key IS NULL;
+
+COMMENT ON COLUMN canvas_snapshots.snapshot_data IS 'Inline canvas state of snapshots not yet moved to object storage';
+COMMENT ON COLUMN canvas_snapshots.storage_key IS 'Object key of the gzipped JSON canvas state in the backups bucket';
+COMMENT ON COLUMN canvas_snapshots.compressed_size IS 'Size of the stored payload in bytes';
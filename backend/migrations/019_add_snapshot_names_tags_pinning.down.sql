@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_canvas_snapshots_pinned;
+DROP INDEX IF EXISTS idx_canvas_snapshots_tags;
+
+ALTER TABLE canvas_snapshots
+    DROP COLUMN IF EXISTS pinned,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS name;
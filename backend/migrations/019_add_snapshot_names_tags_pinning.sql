@@ -0,0 +1,14 @@
+-- Migration: Named, tagged and pinned snapshots
+-- Pinned snapshots are never removed by the automatic cleanup
+
+ALTER TABLE canvas_snapshots
+    ADD COLUMN IF NOT EXISTS name VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_canvas_snapshots_tags ON canvas_snapshots USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_canvas_snapshots_pinned ON canvas_snapshots(workspace_id) WHERE pinned;
+
+COMMENT ON COLUMN canvas_snapshots.name IS 'Optional user-facing name of the snapshot';
+COMMENT ON COLUMN canvas_snapshots.tags IS 'Labels such as sprint-12-final, used for filtering';
+COMMENT ON COLUMN canvas_snapshots.pinned IS 'Pinned snapshots are kept by retention cleanup';
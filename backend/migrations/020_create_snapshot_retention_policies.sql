@@ -0,0 +1,18 @@
+-- Migration: Per-workspace snapshot retention policies
+-- Workspaces without a row use the default policy (keep the latest 100)
+
+CREATE TABLE IF NOT EXISTS snapshot_retention_policies (
+    workspace_id UUID PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    max_count INTEGER NOT NULL DEFAULT 100 CHECK (max_count > 0),
+    max_age_days INTEGER CHECK (max_age_days > 0),
+    keep_daily INTEGER NOT NULL DEFAULT 0 CHECK (keep_daily >= 0),
+    keep_weekly INTEGER NOT NULL DEFAULT 0 CHECK (keep_weekly >= 0),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE snapshot_retention_policies IS 'Which snapshots the cleanup job keeps, pinned snapshots are always kept';
+COMMENT ON COLUMN snapshot_retention_policies.max_count IS 'Number of latest snapshots to keep';
+COMMENT ON COLUMN snapshot_retention_policies.max_age_days IS 'Snapshots older than this are removed unless kept by a tier, NULL keeps them';
+COMMENT ON COLUMN snapshot_retention_policies.keep_daily IS 'Number of past days for which the newest snapshot of the day is kept';
+COMMENT ON COLUMN snapshot_retention_policies.keep_weekly IS 'Number of past weeks for which the newest snapshot of the week is kept';
@@ -0,0 +1,2 @@
+ALTER TABLE canvas_snapshots
+    DROP COLUMN IF EXISTS content_hash;
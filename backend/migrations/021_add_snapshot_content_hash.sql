@@ -0,0 +1,7 @@
+-- Migration: Content hash of snapshots
+-- Lets snapshot creation skip boards that did not change since the last snapshot
+
+ALTER TABLE canvas_snapshots
+    ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+COMMENT ON COLUMN canvas_snapshots.content_hash IS 'Hex SHA-256 of the serialized elements, NULL for snapshots created before hashing';
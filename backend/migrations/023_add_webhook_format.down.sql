@@ -0,0 +1,7 @@
+-- Chat webhooks would get signed JSON payloads they can't read, so they
+-- are deactivated
+UPDATE webhooks SET active = FALSE, updated_at = NOW()
+WHERE format <> 'json';
+
+ALTER TABLE webhooks
+    DROP COLUMN IF EXISTS format;
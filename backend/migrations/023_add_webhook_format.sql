@@ -0,0 +1,6 @@
+-- Migration: Payload format of webhooks for chat integrations such as Microsoft Teams
+
+ALTER TABLE webhooks
+    ADD COLUMN IF NOT EXISTS format VARCHAR(20) NOT NULL DEFAULT 'json' CHECK (format IN ('json', 'teams'));
+
+COMMENT ON COLUMN webhooks.format IS 'json for signed event payloads, or the chat provider the events are rendered for';
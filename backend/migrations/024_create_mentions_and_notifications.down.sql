@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS mentions;
+
+DROP TRIGGER IF EXISTS set_users_username ON users;
+DROP FUNCTION IF EXISTS set_default_username();
+DROP FUNCTION IF EXISTS generate_username(TEXT);
+
+DROP INDEX IF EXISTS idx_users_username;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS username;
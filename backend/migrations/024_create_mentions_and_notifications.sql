@@ -0,0 +1,94 @@
+-- Migration: Usernames for @mentions, mention records and in-app notifications
+
+-- Usernames are derived from the email address and made unique with a
+-- numeric suffix, e.g. alice, alice2
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(50);
+
+CREATE OR REPLACE FUNCTION generate_username(user_email TEXT)
+RETURNS TEXT AS $$
+DECLARE
+    base TEXT;
+    candidate TEXT;
+    suffix INTEGER := 1;
+BEGIN
+    base := lower(regexp_replace(split_part(user_email, '@', 1), '[^a-zA-Z0-9_.-]', '', 'g'));
+    base := left(trim(BOTH '.-' FROM base), 40);
+    IF length(base) < 2 THEN
+        base := 'user';
+    END IF;
+
+    candidate := base;
+    WHILE EXISTS (SELECT 1 FROM users WHERE lower(username) = candidate) LOOP
+        suffix := suffix + 1;
+        candidate := base || suffix;
+    END LOOP;
+
+    RETURN candidate;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    u RECORD;
+BEGIN
+    FOR u IN SELECT id, email FROM users WHERE username IS NULL ORDER BY created_at LOOP
+        UPDATE users SET username = generate_username(u.email) WHERE id = u.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(lower(username));
+
+CREATE OR REPLACE FUNCTION set_default_username()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.username IS NULL THEN
+        NEW.username := generate_username(NEW.email);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS set_users_username ON users;
+CREATE TRIGGER set_users_username
+    BEFORE INSERT ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION set_default_username();
+
+-- Element IDs may reference canvas_elements or elements, so there is no
+-- foreign key on them
+CREATE TABLE IF NOT EXISTS mentions (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    element_id UUID NOT NULL,
+    mentioned_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (element_id, mentioned_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions(mentioned_user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    element_id UUID,
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP,
+    emailed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_digest ON notifications(created_at)
+    WHERE read_at IS NULL AND emailed_at IS NULL;
+
+COMMENT ON COLUMN users.username IS 'Unique handle used for @mentions, compared case-insensitively';
+COMMENT ON TABLE mentions IS 'Users currently mentioned in element content';
+COMMENT ON TABLE notifications IS 'In-app notifications of users';
+COMMENT ON COLUMN notifications.data IS 'Type specific details such as the mention excerpt';
+COMMENT ON COLUMN notifications.emailed_at IS 'When the notification was included in an email digest';
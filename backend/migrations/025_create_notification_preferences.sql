@@ -0,0 +1,18 @@
+-- Migration: Per-user notification preferences
+
+-- Users without a row use the defaults
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_on_invite BOOLEAN NOT NULL DEFAULT TRUE,
+    email_on_mention BOOLEAN NOT NULL DEFAULT TRUE,
+    digest_frequency VARCHAR(20) NOT NULL DEFAULT 'frequent'
+        CHECK (digest_frequency IN ('frequent', 'daily', 'weekly', 'off')),
+    mute_webhooks BOOLEAN NOT NULL DEFAULT FALSE,
+    last_digest_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE notification_preferences IS 'Notification settings of users, defaults apply without a row';
+COMMENT ON COLUMN notification_preferences.digest_frequency IS 'frequent sends on every digest run, off never emails digests';
+COMMENT ON COLUMN notification_preferences.mute_webhooks IS 'Keep the activity of the user out of chat webhook messages';
+COMMENT ON COLUMN notification_preferences.last_digest_at IS 'When the user was last sent a digest, for daily and weekly digests';
@@ -0,0 +1,18 @@
+-- Migration: Email addresses suppressed after hard bounces and complaints
+
+-- Addresses are stored lowercase. A later bounce or complaint updates the
+-- existing row.
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    email VARCHAR(255) PRIMARY KEY,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('bounced', 'complained')),
+    provider VARCHAR(20) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_suppressions_updated ON email_suppressions(updated_at DESC);
+
+COMMENT ON TABLE email_suppressions IS 'Email addresses no email is sent to, recorded from provider feedback webhooks';
+COMMENT ON COLUMN email_suppressions.reason IS 'bounced for hard bounces, complained for spam complaints';
+COMMENT ON COLUMN email_suppressions.detail IS 'Bounce diagnostic or complaint type reported by the provider';
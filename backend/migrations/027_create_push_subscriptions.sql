@@ -0,0 +1,19 @@
+-- Migration: Browser push subscriptions for web push notifications
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh VARCHAR(255) NOT NULL,
+    auth VARCHAR(255) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_id);
+
+COMMENT ON TABLE push_subscriptions IS 'Push service endpoints of the browsers of users';
+COMMENT ON COLUMN push_subscriptions.endpoint IS 'Push service URL, a browser re-subscribing moves it to the new user';
+COMMENT ON COLUMN push_subscriptions.p256dh IS 'Browser ECDH public key the payload is encrypted for, base64url';
+COMMENT ON COLUMN push_subscriptions.auth IS 'Browser authentication secret, base64url';
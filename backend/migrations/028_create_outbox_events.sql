@@ -0,0 +1,26 @@
+-- Migration: Transactional outbox of NATS messages
+
+-- Rows are written in the transaction of the change that caused them and
+-- published by the outbox relay once committed. A message is published at
+-- least once, consumers must tolerate duplicates.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    jetstream BOOLEAN NOT NULL DEFAULT false,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(seq) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+COMMENT ON TABLE outbox_events IS 'Messages waiting to be published to NATS by the outbox relay';
+COMMENT ON COLUMN outbox_events.seq IS 'Write order, messages are published in it';
+COMMENT ON COLUMN outbox_events.headers IS 'NATS headers, carries the trace context of the writer';
+COMMENT ON COLUMN outbox_events.jetstream IS 'Publish to JetStream and wait for the ack instead of core NATS';
+COMMENT ON COLUMN outbox_events.published_at IS 'NULL until published, published rows are deleted after the retention';
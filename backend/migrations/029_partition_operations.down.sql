@@ -0,0 +1,37 @@
+-- Turns operations back into a plain table, keeping the rows of the
+-- partitions that still exist. Should an id appear twice, only its first
+-- row is kept.
+
+ALTER TABLE operations RENAME TO operations_partitioned;
+ALTER INDEX operations_pkey RENAME TO operations_partitioned_pkey;
+
+CREATE TABLE operations (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    element_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    op_type VARCHAR(20) NOT NULL CHECK (op_type IN ('create', 'update', 'delete', 'move')),
+    data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    timestamp BIGINT NOT NULL, -- Lamport timestamp
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO operations SELECT * FROM operations_partitioned ON CONFLICT (id) DO NOTHING;
+
+DROP TABLE operations_partitioned;
+DROP FUNCTION IF EXISTS drop_operations_partitions(TIMESTAMP);
+DROP FUNCTION IF EXISTS ensure_operations_partitions(INTEGER);
+DROP FUNCTION IF EXISTS create_operations_partition(DATE);
+
+CREATE INDEX idx_operations_workspace_id ON operations(workspace_id);
+CREATE INDEX idx_operations_element_id ON operations(element_id);
+CREATE INDEX idx_operations_user_id ON operations(user_id);
+CREATE INDEX idx_operations_timestamp ON operations(timestamp);
+CREATE INDEX idx_operations_workspace_timestamp ON operations(workspace_id, timestamp);
+CREATE INDEX idx_operations_created_at ON operations(created_at);
+CREATE INDEX idx_operations_sync ON operations(workspace_id, user_id, timestamp);
+CREATE INDEX idx_operations_workspace_created_at ON operations(workspace_id, created_at, timestamp);
+
+COMMENT ON TABLE operations IS 'Stores CRDT operations for real-time synchronization';
+COMMENT ON COLUMN operations.timestamp IS 'Lamport timestamp for operation ordering';
+COMMENT ON COLUMN operations.data IS 'Operation-specific data (element properties for create/update)';
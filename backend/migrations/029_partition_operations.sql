@@ -0,0 +1,145 @@
+-- Migration: Partition operations by month and workspace
+
+-- operations grows with every edit of every board. It becomes a table
+-- partitioned by created_at with one partition per month, so old months
+-- are dropped as a whole instead of deleted row by row, and queries with
+-- a created_at range only read the months they need. Every month is split
+-- further into 8 partitions by hash of workspace_id, so the queries of a
+-- board, which all filter on its workspace, read an eighth of a month. Rows
+-- outside of the existing months land in operations_default and move to
+-- their month when its partition is created.
+--
+-- Partitions are managed like pg_partman does it, with the functions below:
+-- ensure_operations_partitions creates the current and upcoming months,
+-- drop_operations_partitions drops the months before a cutoff. The
+-- api-gateway calls both periodically.
+--
+-- A primary key of a partitioned table must contain the partition keys, so
+-- it becomes (id, created_at, workspace_id). The database no longer rejects
+-- an id that exists in another month or workspace. That is safe because
+-- nothing depends on id alone: no foreign key or ON CONFLICT refers to it,
+-- and operations are read by workspace, never looked up by id by itself.
+-- The only writer, OperationRepository.Create, inserts every operation once
+-- with an ID the server draws with uuid.New(), never one sent by a client,
+-- so a duplicate would take a collision of 122 random bits.
+
+ALTER TABLE operations RENAME TO operations_unpartitioned;
+ALTER INDEX operations_pkey RENAME TO operations_unpartitioned_pkey;
+
+CREATE TABLE operations (
+    id UUID NOT NULL,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    element_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    op_type VARCHAR(20) NOT NULL CHECK (op_type IN ('create', 'update', 'delete', 'move')),
+    data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    timestamp BIGINT NOT NULL, -- Lamport timestamp
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at, workspace_id)
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE operations_default PARTITION OF operations DEFAULT;
+
+-- create_operations_partition creates the partition of the month of target,
+-- with its partitions by workspace, and moves the rows of that month out of
+-- the default partition. It returns the name of the new partition, NULL
+-- when it already exists.
+CREATE OR REPLACE FUNCTION create_operations_partition(target DATE) RETURNS TEXT AS $$
+DECLARE
+    buckets CONSTANT INTEGER := 8;
+    start_at TIMESTAMP := date_trunc('month', target::timestamp);
+    end_at TIMESTAMP := date_trunc('month', target::timestamp) + INTERVAL '1 month';
+    partition_name TEXT := 'operations_p' || to_char(date_trunc('month', target::timestamp), 'YYYYMM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN NULL;
+    END IF;
+
+    EXECUTE format(
+        'CREATE TABLE %I (LIKE operations INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY HASH (workspace_id)',
+        partition_name
+    );
+    FOR bucket IN 0..buckets - 1 LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF %I FOR VALUES WITH (MODULUS %s, REMAINDER %s)',
+            partition_name || '_h' || bucket, partition_name, buckets, bucket
+        );
+    END LOOP;
+
+    EXECUTE format(
+        'WITH moved AS (DELETE FROM operations_default WHERE created_at >= $1 AND created_at < $2 RETURNING *) '
+        'INSERT INTO %I SELECT * FROM moved',
+        partition_name
+    ) USING start_at, end_at;
+    EXECUTE format(
+        'ALTER TABLE operations ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+        partition_name, start_at, end_at
+    );
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- ensure_operations_partitions creates the partitions of the current month
+-- and of the months_ahead months after it that don't exist yet, and returns
+-- their names
+CREATE OR REPLACE FUNCTION ensure_operations_partitions(months_ahead INTEGER) RETURNS SETOF TEXT AS $$
+DECLARE
+    created TEXT;
+BEGIN
+    FOR i IN 0..months_ahead LOOP
+        created := create_operations_partition((date_trunc('month', NOW()::timestamp) + i * INTERVAL '1 month')::date);
+        IF created IS NOT NULL THEN
+            RETURN NEXT created;
+        END IF;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- drop_operations_partitions drops the monthly partitions, with their
+-- partitions by workspace, that end before cutoff and returns their names.
+-- Rows of the default partition are left for a DELETE.
+CREATE OR REPLACE FUNCTION drop_operations_partitions(cutoff TIMESTAMP) RETURNS SETOF TEXT AS $$
+DECLARE
+    partition_name TEXT;
+BEGIN
+    FOR partition_name IN
+        SELECT c.relname
+        FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'operations'::regclass
+          AND c.relname ~ '^operations_p[0-9]{6}$'
+        ORDER BY c.relname
+    LOOP
+        IF to_date(substr(partition_name, 13), 'YYYYMM') + INTERVAL '1 month' <= cutoff THEN
+            EXECUTE format('DROP TABLE %I', partition_name);
+            RETURN NEXT partition_name;
+        END IF;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Partitions for every month with operations, the current one and the next
+-- two, then the existing rows are copied over
+SELECT create_operations_partition(month::date)
+FROM generate_series(
+    date_trunc('month', LEAST((SELECT MIN(created_at) FROM operations_unpartitioned), NOW()::timestamp)),
+    date_trunc('month', NOW()::timestamp) + INTERVAL '2 months',
+    INTERVAL '1 month'
+) AS month;
+
+INSERT INTO operations SELECT * FROM operations_unpartitioned;
+
+DROP TABLE operations_unpartitioned;
+
+-- Indexes are created on every partition. created_at alone needs none, it
+-- selects the partitions.
+CREATE INDEX idx_operations_workspace_timestamp ON operations(workspace_id, timestamp);
+CREATE INDEX idx_operations_workspace_created_at ON operations(workspace_id, created_at, timestamp);
+CREATE INDEX idx_operations_element_id ON operations(element_id);
+CREATE INDEX idx_operations_user_id ON operations(user_id);
+CREATE INDEX idx_operations_sync ON operations(workspace_id, user_id, timestamp);
+
+COMMENT ON TABLE operations IS 'Stores CRDT operations for real-time synchronization, partitioned by month of created_at and hash of workspace_id';
+COMMENT ON COLUMN operations.timestamp IS 'Lamport timestamp for operation ordering';
+COMMENT ON COLUMN operations.data IS 'Operation-specific data (element properties for create/update)';
@@ -0,0 +1,23 @@
+-- Migration: Inbound workspace webhooks that create sticky notes
+
+-- Only the SHA-256 hash of the token is stored, the token itself is shown
+-- once. Notes are created as the user that created the webhook, so the
+-- webhook goes away with them.
+CREATE TABLE IF NOT EXISTS inbound_webhooks (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    default_column VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_webhooks_workspace ON inbound_webhooks(workspace_id);
+
+COMMENT ON TABLE inbound_webhooks IS 'Workspace URLs that create sticky notes from posted text';
+COMMENT ON COLUMN inbound_webhooks.token_hash IS 'Hex SHA-256 of the secret token callers authenticate with';
+COMMENT ON COLUMN inbound_webhooks.default_column IS 'Column notes go to when the payload names none';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_workspace_members_workspace_joined;
+DROP INDEX IF EXISTS idx_canvas_elements_workspace_created;
+DROP TABLE IF EXISTS api_keys;
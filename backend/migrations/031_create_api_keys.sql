@@ -0,0 +1,26 @@
+-- Migration: Workspace API keys for automation platforms such as Zapier and Make
+
+-- A key acts as the user that created it, within one workspace. Only the
+-- SHA-256 hash of the key is stored, the key itself is shown once.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_workspace ON api_keys(workspace_id);
+
+-- Newest first keyset pagination of the polling triggers
+CREATE INDEX IF NOT EXISTS idx_canvas_elements_workspace_created
+    ON canvas_elements(workspace_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_workspace_members_workspace_joined
+    ON workspace_members(workspace_id, joined_at DESC, id DESC);
+
+COMMENT ON TABLE api_keys IS 'Keys automation platforms use to poll triggers and subscribe REST hooks';
+COMMENT ON COLUMN api_keys.prefix IS 'First characters of the key, shown so users can tell keys apart';
+COMMENT ON COLUMN api_keys.key_hash IS 'Hex SHA-256 of the key';
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS scim_group_workspaces;
+DROP TABLE IF EXISTS scim_group_members;
+DROP TABLE IF EXISTS scim_groups;
+
+ALTER TABLE workspace_members DROP COLUMN IF EXISTS scim_managed;
+
+DROP INDEX IF EXISTS idx_users_scim_external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS scim_external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
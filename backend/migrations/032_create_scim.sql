@@ -0,0 +1,47 @@
+-- Migration: SCIM 2.0 provisioning of users and groups
+
+-- Deactivated users can't log in, their API keys and inbound webhooks stop
+-- working. Users are never deleted by SCIM, they own boards and content.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS scim_external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_scim_external_id ON users(scim_external_id)
+    WHERE scim_external_id IS NOT NULL;
+
+-- Memberships granted by SCIM groups are kept in sync with the groups,
+-- memberships added by invites are left alone
+ALTER TABLE workspace_members ADD COLUMN IF NOT EXISTS scim_managed BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id UUID PRIMARY KEY,
+    display_name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scim_groups_display_name ON scim_groups(lower(display_name));
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_group_members_user ON scim_group_members(user_id);
+
+CREATE TABLE IF NOT EXISTS scim_group_workspaces (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, workspace_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_group_workspaces_workspace ON scim_group_workspaces(workspace_id);
+
+COMMENT ON COLUMN users.deactivated_at IS 'When the identity provider deactivated the user, NULL while active';
+COMMENT ON COLUMN users.scim_external_id IS 'ID of the user at the identity provider';
+COMMENT ON COLUMN workspace_members.scim_managed IS 'Membership granted by a SCIM group mapping';
+COMMENT ON TABLE scim_groups IS 'Groups provisioned by the identity provider';
+COMMENT ON TABLE scim_group_workspaces IS 'Workspace roles granted to the members of a SCIM group';
@@ -0,0 +1 @@
+ALTER TABLE workspace_members DROP COLUMN IF EXISTS ldap_managed;
@@ -0,0 +1,7 @@
+-- Migration: Workspace memberships granted by LDAP groups
+
+-- Memberships granted by the LDAP group roles are updated at every LDAP
+-- login, memberships added by invites are left alone
+ALTER TABLE workspace_members ADD COLUMN IF NOT EXISTS ldap_managed BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN workspace_members.ldap_managed IS 'Membership granted by an LDAP group role';
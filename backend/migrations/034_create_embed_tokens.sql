@@ -0,0 +1,22 @@
+-- Migration: Embed tokens for read-only boards in iframes
+
+-- A token gives viewer access to one workspace through the embed API. It
+-- stops working when it expires or its creator leaves the workspace. Only
+-- the SHA-256 hash of the token is stored, the token itself is shown once.
+CREATE TABLE IF NOT EXISTS embed_tokens (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_embed_tokens_workspace ON embed_tokens(workspace_id);
+
+COMMENT ON TABLE embed_tokens IS 'Tokens of read-only board embeds in wikis and docs';
+COMMENT ON COLUMN embed_tokens.prefix IS 'First characters of the token, shown so users can tell tokens apart';
+COMMENT ON COLUMN embed_tokens.token_hash IS 'Hex SHA-256 of the token';
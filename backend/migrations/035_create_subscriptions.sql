@@ -0,0 +1,28 @@
+-- Migration: Billing plans and Stripe subscriptions
+
+-- The plan of a user applies to the workspaces they own. Users without a
+-- row are on the free plan. The row is created when the user first checks
+-- out and updated from Stripe webhooks.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    plan VARCHAR(20) NOT NULL DEFAULT 'free',
+    status VARCHAR(30) NOT NULL,
+    stripe_customer_id VARCHAR(255) NOT NULL UNIQUE,
+    stripe_subscription_id VARCHAR(255) UNIQUE,
+    current_period_end TIMESTAMP,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_subscriptions_plan CHECK (plan IN ('free', 'pro', 'team'))
+);
+
+-- Stripe retries webhooks and may deliver an event more than once
+CREATE TABLE IF NOT EXISTS stripe_events (
+    id VARCHAR(255) PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE subscriptions IS 'Stripe subscriptions that put users on a paid plan';
+COMMENT ON COLUMN subscriptions.status IS 'Stripe subscription status, e.g. active, past_due or canceled';
+COMMENT ON TABLE stripe_events IS 'IDs of processed Stripe webhook events';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS usage_active_editors;
+DROP TABLE IF EXISTS usage_periods;
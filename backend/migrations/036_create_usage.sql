@@ -0,0 +1,28 @@
+-- Migration: Monthly usage metering of workspaces
+
+-- One row per workspace and calendar month (UTC). Active editors are counted
+-- from usage_active_editors, storage is the highest sampled usage.
+CREATE TABLE IF NOT EXISTS usage_periods (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    active_editors INTEGER NOT NULL DEFAULT 0,
+    storage_peak_bytes BIGINT NOT NULL DEFAULT 0,
+    exports_rendered INTEGER NOT NULL DEFAULT 0,
+    reported_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, period_start)
+);
+
+-- Closed months waiting to be reported to billing
+CREATE INDEX IF NOT EXISTS idx_usage_periods_unreported ON usage_periods(period_start) WHERE reported_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS usage_active_editors (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (workspace_id, period_start, user_id)
+);
+
+COMMENT ON TABLE usage_periods IS 'Billable usage of workspaces per month';
+COMMENT ON COLUMN usage_periods.reported_at IS 'When overage of the closed month was reported to billing';
+COMMENT ON TABLE usage_active_editors IS 'Users who changed a board of the workspace in the month';
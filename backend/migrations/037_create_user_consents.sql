@@ -0,0 +1,17 @@
+-- Migration: Consent to the terms of service and privacy policy
+
+-- One row per user, document and accepted version, kept as evidence of
+-- consent. Users accept again when the configured version changes.
+CREATE TABLE IF NOT EXISTS user_consents (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document VARCHAR(20) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(45),
+    accepted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_user_consents_document CHECK (document IN ('terms', 'privacy')),
+    CONSTRAINT uq_user_consents_version UNIQUE (user_id, document, version)
+);
+
+COMMENT ON TABLE user_consents IS 'Accepted versions of the terms of service and privacy policy';
+COMMENT ON COLUMN user_consents.ip_address IS 'Client IP the consent was given from';
@@ -0,0 +1,34 @@
+-- Migration: Audit log and its export to SIEM sinks
+
+-- Append only. Actors and targets have no foreign keys, so the log outlives
+-- the users and workspaces it mentions.
+CREATE TABLE IF NOT EXISTS audit_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL DEFAULT gen_random_uuid(),
+    action VARCHAR(100) NOT NULL,
+    actor_type VARCHAR(20) NOT NULL CHECK (actor_type IN ('user', 'api_key', 'scim', 'system')),
+    actor_id UUID,
+    workspace_id UUID,
+    target_type VARCHAR(50),
+    target_id VARCHAR(255),
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(100),
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_workspace ON audit_events(workspace_id, seq) WHERE workspace_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, seq) WHERE actor_id IS NOT NULL;
+
+-- Position of each sink in the log
+CREATE TABLE IF NOT EXISTS audit_export_cursors (
+    sink VARCHAR(100) PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE audit_events IS 'Security relevant actions, shipped to the configured SIEM sinks';
+COMMENT ON COLUMN audit_events.seq IS 'Log order, sinks ship events in it';
+COMMENT ON COLUMN audit_events.target_id IS 'ID of the changed resource, text so SCIM and job names fit';
+COMMENT ON TABLE audit_export_cursors IS 'Last event shipped to each sink';
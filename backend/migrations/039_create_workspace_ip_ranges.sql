@@ -0,0 +1,16 @@
+-- Migration: Per-workspace IP allowlists
+
+-- A workspace with ranges only accepts requests and realtime joins from
+-- them. A workspace without ranges is reachable from anywhere.
+CREATE TABLE IF NOT EXISTS workspace_ip_ranges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    cidr CIDR NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (workspace_id, cidr)
+);
+
+COMMENT ON TABLE workspace_ip_ranges IS 'Networks a workspace may be accessed from, any when empty';
+COMMENT ON COLUMN workspace_ip_ranges.description IS 'Label for the owner, e.g. the office the range belongs to';
@@ -0,0 +1,15 @@
+-- Migration: Per-workspace data keys for content encryption
+
+-- Element and snapshot content is encrypted with the data key of its
+-- workspace. The key is only stored wrapped by the master key, which lives
+-- in the configuration or a KMS, never in the database.
+CREATE TABLE IF NOT EXISTS workspace_data_keys (
+    workspace_id UUID PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    master_key_id VARCHAR(255) NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE workspace_data_keys IS 'Data keys of encrypted workspace content, wrapped by a master key';
+COMMENT ON COLUMN workspace_data_keys.master_key_id IS 'Master key the data key is wrapped with, rewrapped lazily after rotation';
@@ -0,0 +1,6 @@
+-- Audit events of bots are kept, the restored check only applies to new ones
+ALTER TABLE audit_events DROP CONSTRAINT IF EXISTS audit_events_actor_type_check;
+ALTER TABLE audit_events ADD CONSTRAINT audit_events_actor_type_check
+    CHECK (actor_type IN ('user', 'api_key', 'scim', 'system')) NOT VALID;
+DROP TABLE IF EXISTS bot_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,34 @@
+-- Migration: Bot accounts of CI pipelines and alert integrations
+
+-- A bot is a user without a password that is a member of the one workspace
+-- it was created in, so its changes are attributed like anyone else's
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Bots authenticate with tokens limited to the scopes they were given.
+-- Only the SHA-256 hash of a token is stored, the token itself is shown once.
+CREATE TABLE IF NOT EXISTS bot_tokens (
+    id UUID PRIMARY KEY,
+    bot_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    element_types TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_tokens_bot ON bot_tokens(bot_id);
+
+-- Actions taken with bot tokens are audited as the bot
+ALTER TABLE audit_events DROP CONSTRAINT IF EXISTS audit_events_actor_type_check;
+ALTER TABLE audit_events ADD CONSTRAINT audit_events_actor_type_check
+    CHECK (actor_type IN ('user', 'api_key', 'scim', 'system', 'bot'));
+
+COMMENT ON COLUMN users.is_bot IS 'Bot account of a workspace, authenticates with bot tokens only';
+COMMENT ON TABLE bot_tokens IS 'Scoped tokens bot accounts call the API with';
+COMMENT ON COLUMN bot_tokens.scopes IS 'Operations the token may perform, e.g. elements:create';
+COMMENT ON COLUMN bot_tokens.element_types IS 'Element types the token may create, empty for any';
@@ -0,0 +1,21 @@
+-- Migration: Board exports
+
+-- Documents rendered from a board by the export worker. The file is kept in
+-- object storage until the export is deleted.
+CREATE TABLE IF NOT EXISTS board_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    format VARCHAR(20) NOT NULL CHECK (format IN ('markdown', 'confluence')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    object_key TEXT,
+    size_bytes BIGINT,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_board_exports_workspace ON board_exports(workspace_id, created_at DESC);
+
+COMMENT ON TABLE board_exports IS 'Documents exported from boards, rendered in the background';
+COMMENT ON COLUMN board_exports.object_key IS 'Key of the rendered document in asset storage, set once completed';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_assets_search;
+
+ALTER TABLE assets
+    DROP COLUMN IF EXISTS search_vector,
+    DROP COLUMN IF EXISTS ocr_text;
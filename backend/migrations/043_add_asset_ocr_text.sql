@@ -0,0 +1,14 @@
+-- Migration: Text recognized in image assets
+-- Photos of whiteboards and documents become searchable by the text in them
+
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS ocr_text TEXT,
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', filename), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(ocr_text, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_assets_search ON assets USING GIN(search_vector);
+
+COMMENT ON COLUMN assets.ocr_text IS 'Text recognized in the image, NULL until OCR ran or when it is disabled';
+COMMENT ON COLUMN assets.search_vector IS 'Full-text index of the filename and recognized text';
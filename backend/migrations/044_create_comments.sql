@@ -0,0 +1,26 @@
+-- Migration: Comments on boards and their elements
+
+-- A thread is a root comment and its replies. Only threads are resolved,
+-- replies follow the state of their thread.
+CREATE TABLE IF NOT EXISTS comments (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    element_id UUID REFERENCES canvas_elements(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    resolved_at TIMESTAMP,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    edited_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (parent_id IS NULL OR (element_id IS NULL AND resolved_at IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_threads ON comments(workspace_id, created_at) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_element ON comments(element_id) WHERE element_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_replies ON comments(parent_id, created_at) WHERE parent_id IS NOT NULL;
+
+COMMENT ON TABLE comments IS 'Comment threads on boards and elements and their replies';
+COMMENT ON COLUMN comments.element_id IS 'Element a thread is about, NULL for the board; NULL for replies';
+COMMENT ON COLUMN comments.parent_id IS 'Thread a reply belongs to, NULL for threads';
+COMMENT ON COLUMN comments.resolved_at IS 'When the thread was resolved, NULL while it is open';
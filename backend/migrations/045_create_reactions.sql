@@ -0,0 +1,18 @@
+-- Migration: Emoji reactions
+
+-- Reactions are shared by everything that can be reacted to, told apart by
+-- target_type. A user reacts at most once with each emoji to a target.
+CREATE TABLE IF NOT EXISTS reactions (
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('comment', 'element')),
+    target_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(64) NOT NULL,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (target_type, target_id, user_id, emoji)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions(target_type, target_id, created_at);
+
+COMMENT ON TABLE reactions IS 'Emoji reactions of users to comments and elements';
+COMMENT ON COLUMN reactions.target_id IS 'Comment or element reacted to, removed along with it by the application';
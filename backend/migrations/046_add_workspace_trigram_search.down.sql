@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_workspaces_description_trgm;
+DROP INDEX IF EXISTS idx_workspaces_name_trgm;
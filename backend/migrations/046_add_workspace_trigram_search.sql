@@ -0,0 +1,10 @@
+-- Migration: Fuzzy search on workspace names and descriptions
+
+-- Trigram matching finds boards despite typos and reordered words, e.g.
+-- "retro spirnt 12" for "Retro sprint 12".
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_workspaces_name_trgm
+    ON workspaces USING GIN (name gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_workspaces_description_trgm
+    ON workspaces USING GIN (description gin_trgm_ops) WHERE deleted_at IS NULL;
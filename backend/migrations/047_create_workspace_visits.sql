@@ -0,0 +1,14 @@
+-- Migration: Last visit of users to workspaces
+
+-- Visits are buffered in Redis and written here periodically, so a row
+-- may lag behind the latest visit by the flush interval.
+CREATE TABLE IF NOT EXISTS workspace_visits (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    visited_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, workspace_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_visits_recent ON workspace_visits(user_id, visited_at DESC);
+
+COMMENT ON TABLE workspace_visits IS 'When each user last opened each workspace';
@@ -0,0 +1,21 @@
+-- Migration: Requests of users to join private workspaces
+
+CREATE TABLE IF NOT EXISTS workspace_access_requests (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    message TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied')),
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A user has at most one pending request per workspace
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_access_requests_pending
+    ON workspace_access_requests(workspace_id, user_id) WHERE status = 'pending';
+
+COMMENT ON TABLE workspace_access_requests IS 'Requests of users to join workspaces they have no access to';
+COMMENT ON COLUMN workspace_access_requests.role IS 'Role asked for; owners may grant another one on approval';
+COMMENT ON COLUMN workspace_access_requests.decided_by IS 'Owner who approved or denied the request';
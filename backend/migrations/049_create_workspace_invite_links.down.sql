@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS workspace_invite_link_redemptions;
+DROP TABLE IF EXISTS workspace_invite_links;
@@ -0,0 +1,28 @@
+-- Migration: Shareable invite links any signed-in user can redeem
+
+CREATE TABLE IF NOT EXISTS workspace_invite_links (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    token_hash VARCHAR(255) UNIQUE NOT NULL,
+    max_uses INTEGER CHECK (max_uses > 0),
+    use_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invite_links_workspace ON workspace_invite_links(workspace_id, created_at);
+
+CREATE TABLE IF NOT EXISTS workspace_invite_link_redemptions (
+    link_id UUID NOT NULL REFERENCES workspace_invite_links(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redeemed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (link_id, user_id)
+);
+
+COMMENT ON TABLE workspace_invite_links IS 'Invite links with a role that any authenticated user can redeem';
+COMMENT ON COLUMN workspace_invite_links.max_uses IS 'Redemptions allowed, NULL for unlimited';
+COMMENT ON COLUMN workspace_invite_links.expires_at IS 'When the link stops working, NULL if it never expires';
+COMMENT ON TABLE workspace_invite_link_redemptions IS 'Users that joined a workspace through an invite link';
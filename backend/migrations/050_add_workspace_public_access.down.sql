@@ -0,0 +1,4 @@
+ALTER TABLE workspaces
+    DROP COLUMN IF EXISTS public_allow_anonymous,
+    DROP COLUMN IF EXISTS public_allow_duplicate,
+    DROP COLUMN IF EXISTS public_role;
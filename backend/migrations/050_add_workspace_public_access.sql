@@ -0,0 +1,13 @@
+-- Migration: Public access settings of workspaces
+-- What visitors of a public workspace that aren't members may do. The
+-- defaults keep the former behavior: anyone may view and duplicate.
+
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS public_role VARCHAR(20) NOT NULL DEFAULT 'viewer'
+        CHECK (public_role IN ('viewer', 'commenter')),
+    ADD COLUMN IF NOT EXISTS public_allow_duplicate BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS public_allow_anonymous BOOLEAN NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN workspaces.public_role IS 'Role of non-members on the public workspace: viewer or commenter';
+COMMENT ON COLUMN workspaces.public_allow_duplicate IS 'Whether non-members may duplicate the public workspace';
+COMMENT ON COLUMN workspaces.public_allow_anonymous IS 'Whether the public workspace may be viewed without signing in';
@@ -0,0 +1,3 @@
+COMMENT ON COLUMN canvas_elements.element_type IS 'Type of element: text, shape, image, video, drawing, sticky, list, connector, group';
+
+DROP TABLE IF EXISTS workspace_presentations;
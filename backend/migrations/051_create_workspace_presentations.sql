@@ -0,0 +1,20 @@
+-- Migration: Presentations of workspaces
+-- The slide order of a board and the session presenting it. Both servers
+-- read the session, so followers connected to either see the same slide.
+
+CREATE TABLE IF NOT EXISTS workspace_presentations (
+    workspace_id UUID PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    slide_ids UUID[] NOT NULL DEFAULT '{}',
+    presenter_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    current_slide INTEGER NOT NULL DEFAULT 0 CHECK (current_slide >= 0),
+    started_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE workspace_presentations IS 'Slide order and running presentation session of each workspace';
+COMMENT ON COLUMN workspace_presentations.slide_ids IS 'Frame elements shown as slides, in order';
+COMMENT ON COLUMN workspace_presentations.presenter_id IS 'User advancing the slides, NULL when no session runs';
+COMMENT ON COLUMN workspace_presentations.current_slide IS 'Index in slide_ids of the slide shown';
+COMMENT ON COLUMN workspace_presentations.started_at IS 'When the running session started, NULL when none runs';
+
+COMMENT ON COLUMN canvas_elements.element_type IS 'Type of element: text, shape, image, video, drawing, sticky, list, connector, group, frame';
@@ -0,0 +1,4 @@
+ALTER TABLE workspaces
+    DROP COLUMN IF EXISTS freeze_role,
+    DROP COLUMN IF EXISTS frozen_by,
+    DROP COLUMN IF EXISTS frozen_at;
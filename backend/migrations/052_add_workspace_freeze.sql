@@ -0,0 +1,13 @@
+-- Migration: Board freeze
+-- A frozen board is read-only for everyone until it is unfrozen, e.g. for
+-- "pens down" moments in workshops
+
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS frozen_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS frozen_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS freeze_role VARCHAR(20) NOT NULL DEFAULT 'editor'
+        CHECK (freeze_role IN ('owner', 'editor'));
+
+COMMENT ON COLUMN workspaces.frozen_at IS 'When the board was made read-only, NULL unless frozen';
+COMMENT ON COLUMN workspaces.frozen_by IS 'User that froze the board';
+COMMENT ON COLUMN workspaces.freeze_role IS 'Least role that may freeze and unfreeze the board: owner or editor';
@@ -0,0 +1,17 @@
+-- Migration: View counts of workspaces
+-- One row per viewer of a workspace, signed in users by ID and anonymous
+-- visitors by a hash of their network and browser. A viewer counts one view
+-- per visit, reopening the board within a visit isn't counted again.
+
+CREATE TABLE IF NOT EXISTS workspace_views (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    viewer_key VARCHAR(64) NOT NULL,
+    view_count BIGINT NOT NULL DEFAULT 1 CHECK (view_count > 0),
+    first_viewed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_viewed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, viewer_key)
+);
+
+COMMENT ON TABLE workspace_views IS 'Views of each workspace by viewer, for unique and total view counts';
+COMMENT ON COLUMN workspace_views.viewer_key IS 'u:<user ID> for signed in users, a:<hash> for anonymous visitors';
+COMMENT ON COLUMN workspace_views.view_count IS 'Visits of the viewer to the workspace';
@@ -0,0 +1,18 @@
+-- Migration: View states of users on workspaces
+-- Where each user left off on a board. The state lives in Redis while it
+-- changes and is persisted here periodically, for devices opening the board
+-- after Redis dropped it.
+
+CREATE TABLE IF NOT EXISTS workspace_view_states (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    state JSONB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, workspace_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_view_states_workspace ON workspace_view_states(workspace_id);
+
+COMMENT ON TABLE workspace_view_states IS 'Last viewport, collapsed frames and hidden layers of each user on each workspace';
+COMMENT ON COLUMN workspace_view_states.state IS 'Viewport, collapsed_frame_ids and hidden_layers as saved by the client';
+COMMENT ON COLUMN workspace_view_states.updated_at IS 'When the state was saved, older saves never overwrite newer ones';
@@ -0,0 +1,29 @@
+-- Migration: Session recordings of workspaces
+-- Operations and presence of a board between a start and a stop. Events are
+-- buffered in Redis while recording, the finished recording is stored as
+-- gzipped NDJSON in the backup bucket.
+
+CREATE TABLE IF NOT EXISTS session_recordings (
+    id UUID PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    started_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'recording' CHECK (status IN ('recording', 'ready')),
+    storage_key TEXT,
+    event_count INTEGER NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_recordings_workspace ON session_recordings(workspace_id, started_at DESC);
+
+-- A board records one session at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_recordings_running
+    ON session_recordings(workspace_id) WHERE status = 'recording';
+
+COMMENT ON TABLE session_recordings IS 'Recorded operations and presence of workspace sessions, for playback';
+COMMENT ON COLUMN session_recordings.storage_key IS 'Key of the gzipped NDJSON events in the backup bucket, set once stopped';
+COMMENT ON COLUMN session_recordings.truncated IS 'Whether events past the recording limit were dropped';
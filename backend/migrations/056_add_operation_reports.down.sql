@@ -0,0 +1,6 @@
+DELETE FROM board_exports WHERE format IN ('operations_csv', 'operations_json');
+ALTER TABLE board_exports DROP CONSTRAINT IF EXISTS board_exports_format_check;
+ALTER TABLE board_exports ADD CONSTRAINT board_exports_format_check
+    CHECK (format IN ('markdown', 'confluence'));
+ALTER TABLE board_exports DROP COLUMN IF EXISTS range_to;
+ALTER TABLE board_exports DROP COLUMN IF EXISTS range_from;
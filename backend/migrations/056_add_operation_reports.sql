@@ -0,0 +1,13 @@
+-- Migration: Operation history reports
+
+-- Reports of the operations of a time range go through the export worker
+-- like board documents, stored in the exports bucket
+ALTER TABLE board_exports ADD COLUMN IF NOT EXISTS range_from TIMESTAMP;
+ALTER TABLE board_exports ADD COLUMN IF NOT EXISTS range_to TIMESTAMP;
+
+ALTER TABLE board_exports DROP CONSTRAINT IF EXISTS board_exports_format_check;
+ALTER TABLE board_exports ADD CONSTRAINT board_exports_format_check
+    CHECK (format IN ('markdown', 'confluence', 'operations_csv', 'operations_json'));
+
+COMMENT ON COLUMN board_exports.range_from IS 'Start of the operations of a report, NULL for the whole history';
+COMMENT ON COLUMN board_exports.range_to IS 'End of the operations of a report, excluded';
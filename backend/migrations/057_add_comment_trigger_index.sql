@@ -0,0 +1,6 @@
+-- Migration: Index of the new-comment trigger
+
+-- The trigger pages through all comments of a workspace, threads and
+-- replies, newest first
+CREATE INDEX IF NOT EXISTS idx_comments_workspace_created
+    ON comments(workspace_id, created_at DESC, id DESC);
@@ -0,0 +1,45 @@
+-- Migration: Analytics tables, written in batches by the analytics worker
+
+CREATE TABLE IF NOT EXISTS element_operations (
+    time DateTime64(3, 'UTC'),
+    workspace_id UUID,
+    user_id UUID,
+    element_id UUID,
+    op_type LowCardinality(String)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(time)
+ORDER BY (workspace_id, time);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    started_at DateTime64(3, 'UTC'),
+    ended_at DateTime64(3, 'UTC'),
+    duration_ms UInt64,
+    user_id UUID,
+    anonymous Bool,
+    transport LowCardinality(String)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(started_at)
+ORDER BY (user_id, started_at);
+
+CREATE TABLE IF NOT EXISTS presence (
+    joined_at DateTime64(3, 'UTC'),
+    left_at DateTime64(3, 'UTC'),
+    duration_ms UInt64,
+    workspace_id UUID,
+    user_id UUID,
+    anonymous Bool
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(joined_at)
+ORDER BY (workspace_id, joined_at);
+
+CREATE TABLE IF NOT EXISTS api_requests (
+    time DateTime64(3, 'UTC'),
+    method LowCardinality(String),
+    route LowCardinality(String),
+    status UInt16,
+    duration_ms Float64,
+    user_id Nullable(UUID)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(time)
+ORDER BY (route, time)
+TTL toDateTime(time) + INTERVAL 90 DAY;
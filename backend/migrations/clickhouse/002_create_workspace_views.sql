@@ -0,0 +1,14 @@
+-- Migration: Views of workspaces, for view trends
+
+CREATE TABLE IF NOT EXISTS workspace_views (
+    time DateTime64(3, 'UTC'),
+    workspace_id UUID,
+    viewer String,
+    user_id Nullable(UUID),
+    anonymous Bool,
+    member Bool,
+    first_view Bool,
+    referrer_host LowCardinality(String)
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(time)
+ORDER BY (workspace_id, time);
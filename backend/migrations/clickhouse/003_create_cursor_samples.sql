@@ -0,0 +1,13 @@
+-- Migration: Sampled cursor positions on boards, for attention heatmaps
+
+CREATE TABLE IF NOT EXISTS cursor_samples (
+    time DateTime64(3, 'UTC'),
+    workspace_id UUID,
+    user_id UUID,
+    anonymous Bool,
+    kind LowCardinality(String),
+    x Float64,
+    y Float64
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(time)
+ORDER BY (workspace_id, time);
@@ -43,6 +43,7 @@ export default {
 		confirmPasswordPlaceholder: 'Confirm password',
 		signingIn: 'Signing in...',
 		creatingAccount: 'Creating account...',
+		acceptTerms: 'I accept the terms of service and privacy policy',
 		googleButton: 'Google',
 		githubButton: 'GitHub',
 		resetTitle: 'Reset your password',
@@ -43,6 +43,7 @@ export default {
 		confirmPasswordPlaceholder: 'Подтвердите пароль',
 		signingIn: 'Вход...',
 		creatingAccount: 'Создание аккаунта...',
+		acceptTerms: 'Я принимаю условия использования и политику конфиденциальности',
 		googleButton: 'Google',
 		githubButton: 'GitHub',
 		resetTitle: 'Сброс пароля',
@@ -43,6 +43,7 @@ export default {
 		confirmPasswordPlaceholder: '确认密码',
 		signingIn: '登录中...',
 		creatingAccount: '创建账户中...',
+		acceptTerms: '我接受服务条款和隐私政策',
 		googleButton: 'Google',
 		githubButton: 'GitHub',
 		resetTitle: '重置您的密码',
@@ -25,7 +25,26 @@ import type {
 	UpdateElementRequest,
 	Asset,
 	Snapshot,
-	CreateSnapshotRequest
+	CreateSnapshotRequest,
+	UpdateSnapshotRequest,
+	SnapshotRetentionPolicy,
+	UpdateSnapshotRetentionRequest,
+	Webhook,
+	WebhookWithSecret,
+	WebhookDelivery,
+	CreateWebhookRequest,
+	UpdateWebhookRequest,
+	PaginationParams,
+	NotificationListResponse,
+	NotificationFilters,
+	NotificationPreferences,
+	UpdateNotificationPreferencesRequest,
+	PushSubscriptionInfo,
+	WorkspaceAnalytics,
+	ConsentStatus,
+	AcceptConsentRequest,
+	IPAllowlist,
+	UpdateIPAllowlistRequest
 } from '$lib/types/api';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8080/api/v1';
@@ -207,6 +226,74 @@ export class ApiClient {
 		});
 	}
 
+	async getConsentStatus(): Promise<ConsentStatus> {
+		return this.request<ConsentStatus>('/users/me/consent');
+	}
+
+	async acceptConsent(data: AcceptConsentRequest): Promise<ConsentStatus> {
+		return this.request<ConsentStatus>('/users/me/consent', {
+			method: 'POST',
+			body: JSON.stringify(data)
+		});
+	}
+
+	// Notification endpoints
+	async listNotifications(filters?: NotificationFilters): Promise<NotificationListResponse> {
+		const params = new URLSearchParams();
+		if (filters) {
+			Object.entries(filters).forEach(([key, value]) => {
+				if (value !== undefined && value !== null) {
+					params.append(key, String(value));
+				}
+			});
+		}
+		const query = params.toString();
+		return this.request<NotificationListResponse>(`/notifications${query ? `?${query}` : ''}`);
+	}
+
+	async markNotificationRead(notificationId: string): Promise<void> {
+		return this.request(`/notifications/${notificationId}/read`, {
+			method: 'POST'
+		});
+	}
+
+	async markAllNotificationsRead(): Promise<{ marked: number }> {
+		return this.request<{ marked: number }>('/notifications/read-all', {
+			method: 'POST'
+		});
+	}
+
+	async getNotificationPreferences(): Promise<NotificationPreferences> {
+		return this.request<NotificationPreferences>('/notifications/preferences');
+	}
+
+	async updateNotificationPreferences(
+		data: UpdateNotificationPreferencesRequest
+	): Promise<NotificationPreferences> {
+		return this.request<NotificationPreferences>('/notifications/preferences', {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		});
+	}
+
+	async getPushPublicKey(): Promise<{ public_key: string }> {
+		return this.request<{ public_key: string }>('/notifications/push/public-key');
+	}
+
+	async registerPushSubscription(subscription: PushSubscriptionJSON): Promise<PushSubscriptionInfo> {
+		return this.request<PushSubscriptionInfo>('/notifications/push/subscriptions', {
+			method: 'POST',
+			body: JSON.stringify(subscription)
+		});
+	}
+
+	async unregisterPushSubscription(endpoint: string): Promise<{ message: string }> {
+		return this.request<{ message: string }>('/notifications/push/subscriptions', {
+			method: 'DELETE',
+			body: JSON.stringify({ endpoint })
+		});
+	}
+
 	// Workspace endpoints
 	async listWorkspaces(filters?: WorkspaceFilters): Promise<WorkspaceListResponse> {
 		const params = new URLSearchParams();
@@ -256,6 +343,11 @@ export class ApiClient {
 		return response.workspace;
 	}
 
+	async getWorkspaceAnalytics(id: string, days?: number): Promise<WorkspaceAnalytics> {
+		const query = days ? `?days=${days}` : '';
+		return this.request<WorkspaceAnalytics>(`/workspaces/${id}/analytics${query}`);
+	}
+
 	// Workspace members
 	async listMembers(workspaceId: string): Promise<WorkspaceMember[]> {
 		return this.request<WorkspaceMember[]>(`/workspaces/${workspaceId}/members`);
@@ -316,6 +408,18 @@ export class ApiClient {
 		});
 	}
 
+	// Network restrictions
+	async getIPAllowlist(workspaceId: string): Promise<IPAllowlist> {
+		return this.request<IPAllowlist>(`/workspaces/${workspaceId}/ip-allowlist`);
+	}
+
+	async updateIPAllowlist(workspaceId: string, data: UpdateIPAllowlistRequest): Promise<IPAllowlist> {
+		return this.request<IPAllowlist>(`/workspaces/${workspaceId}/ip-allowlist`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		});
+	}
+
 	// Canvas elements
 	async listElements(workspaceId: string): Promise<CanvasElement[]> {
 		return this.request<CanvasElement[]>(`/workspaces/${workspaceId}/elements`);
@@ -384,8 +488,9 @@ export class ApiClient {
 		return this.request<Asset[]>(`/workspaces/${workspaceId}/assets`);
 	}
 
-	async deleteAsset(workspaceId: string, assetId: string): Promise<void> {
-		return this.request(`/workspaces/${workspaceId}/assets/${assetId}`, {
+	async deleteAsset(workspaceId: string, assetId: string, force = false): Promise<void> {
+		const query = force ? '?force=true' : '';
+		return this.request(`/workspaces/${workspaceId}/assets/${assetId}${query}`, {
 			method: 'DELETE'
 		});
 	}
@@ -402,17 +507,127 @@ export class ApiClient {
 		});
 	}
 
+	async updateSnapshot(
+		workspaceId: string,
+		snapshotId: string,
+		data: UpdateSnapshotRequest
+	): Promise<Snapshot> {
+		return this.request<Snapshot>(`/workspaces/${workspaceId}/snapshots/${snapshotId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		});
+	}
+
 	async restoreSnapshot(workspaceId: string, snapshotId: string): Promise<void> {
 		return this.request(`/workspaces/${workspaceId}/snapshots/${snapshotId}/restore`, {
 			method: 'POST'
 		});
 	}
 
+	async restoreSnapshotElements(
+		workspaceId: string,
+		snapshotId: string,
+		elementIds: string[]
+	): Promise<CanvasElement[]> {
+		const response = await this.request<{ elements: CanvasElement[]; total: number }>(
+			`/workspaces/${workspaceId}/snapshots/${snapshotId}/restore-elements`,
+			{
+				method: 'POST',
+				body: JSON.stringify({ element_ids: elementIds })
+			}
+		);
+		return response.elements;
+	}
+
+	async restoreSnapshotToNew(
+		workspaceId: string,
+		snapshotId: string,
+		name?: string
+	): Promise<Workspace> {
+		const response = await this.request<{ workspace: Workspace }>(
+			`/workspaces/${workspaceId}/snapshots/${snapshotId}/restore-to-new`,
+			{
+				method: 'POST',
+				body: JSON.stringify({ name })
+			}
+		);
+		return response.workspace;
+	}
+
+	async getSnapshotRetention(workspaceId: string): Promise<SnapshotRetentionPolicy> {
+		return this.request<SnapshotRetentionPolicy>(`/workspaces/${workspaceId}/snapshots/retention`);
+	}
+
+	async updateSnapshotRetention(
+		workspaceId: string,
+		data: UpdateSnapshotRetentionRequest
+	): Promise<SnapshotRetentionPolicy> {
+		return this.request<SnapshotRetentionPolicy>(`/workspaces/${workspaceId}/snapshots/retention`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		});
+	}
+
 	async deleteSnapshot(workspaceId: string, snapshotId: string): Promise<void> {
 		return this.request(`/workspaces/${workspaceId}/snapshots/${snapshotId}`, {
 			method: 'DELETE'
 		});
 	}
+
+	// Webhook endpoints
+	async getWebhooks(workspaceId: string): Promise<{ webhooks: Webhook[]; event_types: string[] }> {
+		return this.request(`/workspaces/${workspaceId}/webhooks`);
+	}
+
+	async createWebhook(workspaceId: string, data: CreateWebhookRequest): Promise<WebhookWithSecret> {
+		return this.request<WebhookWithSecret>(`/workspaces/${workspaceId}/webhooks`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		});
+	}
+
+	async updateWebhook(
+		workspaceId: string,
+		webhookId: string,
+		data: UpdateWebhookRequest
+	): Promise<WebhookWithSecret> {
+		return this.request<WebhookWithSecret>(`/workspaces/${workspaceId}/webhooks/${webhookId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		});
+	}
+
+	async deleteWebhook(workspaceId: string, webhookId: string): Promise<void> {
+		return this.request(`/workspaces/${workspaceId}/webhooks/${webhookId}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async getWebhookDeliveries(
+		workspaceId: string,
+		webhookId: string,
+		params?: PaginationParams
+	): Promise<WebhookDelivery[]> {
+		const searchParams = new URLSearchParams();
+		if (params) {
+			Object.entries(params).forEach(([key, value]) => {
+				if (value !== undefined && value !== null) {
+					searchParams.append(key, String(value));
+				}
+			});
+		}
+		const query = searchParams.toString();
+		const response = await this.request<{ deliveries: WebhookDelivery[] }>(
+			`/workspaces/${workspaceId}/webhooks/${webhookId}/deliveries${query ? `?${query}` : ''}`
+		);
+		return response.deliveries;
+	}
+
+	async testWebhook(workspaceId: string, webhookId: string): Promise<WebhookDelivery> {
+		return this.request<WebhookDelivery>(`/workspaces/${workspaceId}/webhooks/${webhookId}/test`, {
+			method: 'POST'
+		});
+	}
 }
 
 // Singleton instance
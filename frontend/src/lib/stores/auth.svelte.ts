@@ -59,10 +59,10 @@ class AuthStore {
 		}
 	}
 
-	async register(email: string, password: string, name: string) {
+	async register(email: string, password: string, name: string, acceptTerms: boolean) {
 		this._isLoading = true;
 		try {
-			const response = await api.register({ email, password, name });
+			const response = await api.register({ email, password, name, accept_terms: acceptTerms });
 			this._user = response.user;
 			return response;
 		} finally {
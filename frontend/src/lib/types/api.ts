@@ -4,9 +4,11 @@ export interface User {
 	id: string;
 	email: string;
 	name: string;
+	username: string;
 	avatar_url?: string;
-	provider: 'email' | 'google' | 'github';
+	provider: 'email' | 'google' | 'github' | 'bot';
 	email_verified: boolean;
+	is_bot?: boolean;
 	created_at: string;
 	updated_at: string;
 }
@@ -62,6 +64,18 @@ export interface WorkspaceInvitation {
 	status: 'pending' | 'accepted' | 'declined' | 'expired';
 	expires_at: string;
 	created_at: string;
+	suppression?: EmailSuppression;
+}
+
+// Set on invitations whose emails aren't sent because the address bounced
+// or its owner reported an email as spam
+export interface EmailSuppression {
+	email: string;
+	reason: 'bounced' | 'complained';
+	provider: string;
+	detail?: string;
+	created_at: string;
+	updated_at: string;
 }
 
 export interface WorkspaceListResponse {
@@ -81,6 +95,7 @@ export type ElementType =
 	| 'arrow'
 	| 'sticky'
 	| 'image'
+	| 'video'
 	| 'freehand'
 	| 'list'
 	| 'connector';
@@ -129,6 +144,8 @@ export interface CanvasElement {
 	parent_id?: string;
 	group_id?: string;
 	image_url?: string;
+	video_url?: string;
+	poster_url?: string;
 	path_data?: string;
 	connector_data?: ConnectorData;
 	created_by?: string;
@@ -145,15 +162,21 @@ export interface Asset {
 	mime_type: string;
 	url: string;
 	thumbnail_url?: string;
+	ocr_text?: string; // text recognized in images when OCR is enabled
 	uploaded_by: string;
+	usage_count?: number;
+	used_by?: string[];
 	created_at: string;
 }
 
 export interface Snapshot {
 	id: string;
 	workspace_id: string;
-	name: string;
+	version: number;
+	name?: string;
 	description?: string;
+	tags: string[];
+	pinned: boolean;
 	element_count: number;
 	created_by: string;
 	created_at: string;
@@ -164,6 +187,7 @@ export interface RegisterRequest {
 	email: string;
 	password: string;
 	name: string;
+	accept_terms?: boolean;
 }
 
 export interface LoginRequest {
@@ -177,6 +201,7 @@ export interface RefreshTokenRequest {
 
 export interface UpdateProfileRequest {
 	name?: string;
+	username?: string;
 	avatar_url?: string;
 }
 
@@ -218,6 +243,23 @@ export interface SetPublicAccessRequest {
 	is_public: boolean;
 }
 
+export interface IPRange {
+	id: string;
+	cidr: string;
+	description: string;
+	created_by?: string;
+	created_at: string;
+}
+
+export interface IPAllowlist {
+	ranges: IPRange[];
+	client_ip: string;
+}
+
+export interface UpdateIPAllowlistRequest {
+	ranges: { cidr: string; description?: string }[];
+}
+
 export interface CreateElementRequest {
 	type: ElementType;
 	content: string;
@@ -244,8 +286,170 @@ export interface UpdateElementRequest {
 }
 
 export interface CreateSnapshotRequest {
-	name: string;
+	name?: string;
 	description?: string;
+	tags?: string[];
+	pinned?: boolean;
+}
+
+export interface UpdateSnapshotRequest {
+	name?: string;
+	description?: string;
+	tags?: string[];
+	pinned?: boolean;
+}
+
+export interface SnapshotRetentionPolicy {
+	workspace_id: string;
+	max_count: number;
+	max_age_days?: number;
+	keep_daily: number;
+	keep_weekly: number;
+	updated_by?: string;
+	updated_at?: string;
+}
+
+export interface UpdateSnapshotRetentionRequest {
+	max_count: number;
+	max_age_days?: number;
+	keep_daily: number;
+	keep_weekly: number;
+}
+
+// Webhook Types
+export type WebhookFormat = 'json' | 'teams';
+
+export interface Webhook {
+	id: string;
+	workspace_id: string;
+	url: string;
+	format: WebhookFormat;
+	events: string[];
+	active: boolean;
+	created_by?: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export interface WebhookWithSecret extends Webhook {
+	secret?: string;
+}
+
+export interface WebhookDelivery {
+	id: string;
+	webhook_id: string;
+	event_id: string;
+	event_type: string;
+	payload: unknown;
+	status: 'pending' | 'succeeded' | 'failed';
+	attempts: number;
+	response_status?: number;
+	error?: string;
+	created_at: string;
+	delivered_at?: string;
+}
+
+export interface CreateWebhookRequest {
+	url: string;
+	format?: WebhookFormat;
+	events: string[];
+}
+
+export interface UpdateWebhookRequest {
+	url?: string;
+	format?: WebhookFormat;
+	active?: boolean;
+	events?: string[];
+	rotate_secret?: boolean;
+}
+
+// Notification Types
+export interface Notification {
+	id: string;
+	user_id: string;
+	workspace_id: string;
+	workspace_name: string;
+	type: 'mention';
+	actor_id?: string;
+	actor_name?: string;
+	element_id?: string;
+	data: { excerpt?: string; url?: string };
+	read_at?: string;
+	created_at: string;
+}
+
+export interface NotificationListResponse {
+	notifications: Notification[];
+	unread_count: number;
+}
+
+export interface NotificationFilters extends PaginationParams {
+	unread?: boolean;
+}
+
+export type DigestFrequency = 'frequent' | 'daily' | 'weekly' | 'off';
+
+export interface NotificationPreferences {
+	email_on_invite: boolean;
+	email_on_mention: boolean;
+	digest_frequency: DigestFrequency;
+	mute_webhooks: boolean;
+	updated_at: string;
+}
+
+export interface UpdateNotificationPreferencesRequest {
+	email_on_invite?: boolean;
+	email_on_mention?: boolean;
+	digest_frequency?: DigestFrequency;
+	mute_webhooks?: boolean;
+}
+
+export interface PushSubscriptionInfo {
+	id: string;
+	user_id: string;
+	endpoint: string;
+	user_agent: string;
+	created_at: string;
+	last_used_at?: string;
+}
+
+// Analytics Types
+export interface DailyCount {
+	date: string; // YYYY-MM-DD, UTC
+	count: number;
+}
+
+export interface HourlyCount {
+	hour: number; // 0-23, UTC
+	count: number;
+}
+
+export interface WorkspaceAnalytics {
+	from: string;
+	to: string;
+	daily_active_collaborators: DailyCount[];
+	edits: DailyCount[];
+	busiest_hours: HourlyCount[];
+	element_growth: DailyCount[];
+}
+
+export interface ConsentDocumentStatus {
+	document: 'terms' | 'privacy';
+	current_version: string;
+	url?: string;
+	accepted_version?: string;
+	accepted_at?: string;
+	required: boolean;
+}
+
+export interface ConsentStatus {
+	consent_required: boolean;
+	documents: ConsentDocumentStatus[];
+}
+
+export interface AcceptConsentRequest {
+	terms_version: string;
+	privacy_version: string;
 }
 
 // Error Types